@@ -0,0 +1,21 @@
+// Package v1alpha1 contains the API types of the Windows Machine Config Operator, in the
+// windowsmachineconfig.openshift.io group
+// +kubebuilder:object:generate=true
+// +groupName=windowsmachineconfig.openshift.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the API types
+	GroupVersion = schema.GroupVersion{Group: "windowsmachineconfig.openshift.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the API types with a scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the API types to the given scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
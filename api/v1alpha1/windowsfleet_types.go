@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WindowsFleetName is the name of the single WindowsFleet maintained by WMCO
+const WindowsFleetName = "cluster"
+
+// BlockingError is an error preventing Windows Machines from becoming ready nodes
+type BlockingError struct {
+	// Message is the error
+	Message string `json:"message"`
+	// Count is the number of Machines blocked by the error
+	Count int `json:"count"`
+	// Machines are the names of the Machines blocked by the error
+	Machines []string `json:"machines,omitempty"`
+}
+
+// WindowsFleetStatus is the observed state of the Windows Machines managed by WMCO
+type WindowsFleetStatus struct {
+	// Version is the current WMCO version, whose payload the Windows nodes are to be configured with
+	Version string `json:"version,omitempty"`
+	// Total is the number of Windows Machines managed by WMCO
+	Total int `json:"total"`
+	// Ready is the number of configured Windows nodes reported as ready by their kubelet
+	Ready int `json:"ready"`
+	// Configuring is the number of Windows Machines being provisioned, configured or deleted
+	Configuring int `json:"configuring"`
+	// NotReady is the number of configured Windows nodes not reported as ready by their kubelet
+	NotReady int `json:"notReady"`
+	// Failed is the number of Windows Machines which failed to provision or whose last configuration failed
+	Failed int `json:"failed"`
+	// Current is the number of Windows nodes configured by the current WMCO version
+	Current int `json:"current"`
+	// BlockingErrors are the most frequent errors of the failed Windows Machines, most frequent first
+	BlockingErrors []BlockingError `json:"blockingErrors,omitempty"`
+	// LastChangeTime is the time at which the status last changed
+	LastChangeTime *meta.Time `json:"lastChangeTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.total`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.ready`
+// +kubebuilder:printcolumn:name="Configuring",type=integer,JSONPath=`.status.configuring`
+// +kubebuilder:printcolumn:name="Not Ready",type=integer,JSONPath=`.status.notReady`
+// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+// +kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.current`
+
+// WindowsFleet reports the health of the Windows nodes of the cluster at a glance. WMCO maintains a single
+// WindowsFleet, named cluster, which is read only, the status being maintained by WMCO.
+type WindowsFleet struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	Status WindowsFleetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WindowsFleetList is a list of WindowsFleets
+type WindowsFleetList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []WindowsFleet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WindowsFleet{}, &WindowsFleetList{})
+}
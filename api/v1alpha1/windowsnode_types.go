@@ -0,0 +1,124 @@
+package v1alpha1
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of a WindowsNode
+const (
+	// ReachableCondition indicates that WMCO could connect to the instance when it last tried to
+	ReachableCondition = "Reachable"
+	// PayloadCurrentCondition indicates that the instance was configured by the current WMCO version
+	PayloadCurrentCondition = "PayloadCurrent"
+	// ServicesRunningCondition indicates that the Kubernetes services of the instance are running and its kubelet
+	// reports the node as ready
+	ServicesRunningCondition = "ServicesRunning"
+	// NetworkReadyCondition indicates that the hybrid overlay has set up the network of the node
+	NetworkReadyCondition = "NetworkReady"
+	// NetworkPrerequisitesMetCondition indicates that the cluster network meets the prerequisites of Windows nodes,
+	// which are checked before configuring the instance
+	NetworkPrerequisitesMetCondition = "NetworkPrerequisitesMet"
+	// ClockSynchronizedCondition indicates that the clock of the instance is within the clock skew threshold of the
+	// operator clock
+	ClockSynchronizedCondition = "ClockSynchronized"
+	// SmokeTestPassedCondition indicates that a test pod ran on the node once it was configured, and was reachable
+	// across the pod network
+	SmokeTestPassedCondition = "SmokeTestPassed"
+	// ArtifactsReachableCondition indicates that the instance can reach the sources of the artifacts it retrieves over
+	// the network, such as the Machine Config Server and the image registries, which are checked before configuring it
+	ArtifactsReachableCondition = "ArtifactsReachable"
+	// NICValidCondition indicates that the network interface configuration of an Azure instance has none of the
+	// problems known to break the hybrid overlay, such as accelerated networking, which is checked before configuring it
+	NICValidCondition = "NICValid"
+	// PreflightPassedCondition indicates that the first instance of a new pool is reachable over SSH and meets the OS
+	// build, disk space and Windows feature requirements, which are checked before configuring it
+	PreflightPassedCondition = "PreflightPassed"
+	// ExcludedCondition indicates that the instance is excluded from the management of WMCO by an administrator, and
+	// is neither configured nor remediated
+	ExcludedCondition = "Excluded"
+)
+
+// Outcomes of a configuration attempt
+const (
+	// ConfigurationSucceeded is the outcome of a configuration which completed
+	ConfigurationSucceeded = "Succeeded"
+	// ConfigurationFailed is the outcome of a configuration which failed
+	ConfigurationFailed = "Failed"
+	// ConfigurationCancelled is the outcome of a configuration cancelled as its Machine started being deleted
+	ConfigurationCancelled = "Cancelled"
+)
+
+// ConfigurationStepRecord records the completion of a configuration step
+type ConfigurationStepRecord struct {
+	// Name is the name of the step, such as PayloadTransferred
+	Name string `json:"name"`
+	// Duration is the time taken by the step, since the completion of the previous step or the start of the
+	// configuration
+	Duration meta.Duration `json:"duration"`
+}
+
+// ConfigurationAttempt records an attempt to configure the instance
+type ConfigurationAttempt struct {
+	// StartTime is the time at which the configuration started
+	StartTime meta.Time `json:"startTime"`
+	// Duration is the time taken by the configuration
+	Duration meta.Duration `json:"duration"`
+	// Version is the WMCO version which ran the configuration
+	Version string `json:"version,omitempty"`
+	// Outcome is Succeeded, Failed or Cancelled
+	Outcome string `json:"outcome"`
+	// Steps are the configuration steps completed, in order
+	Steps []ConfigurationStepRecord `json:"steps,omitempty"`
+	// Error is the error of a failed configuration
+	Error string `json:"error,omitempty"`
+}
+
+// WindowsNodeStatus is the observed state of a Windows instance managed by WMCO
+type WindowsNodeStatus struct {
+	// MachineName is the name of the Machine backing the instance
+	MachineName string `json:"machineName,omitempty"`
+	// InstanceID is the cloud provider ID of the instance
+	InstanceID string `json:"instanceID,omitempty"`
+	// NodeName is the name of the node of the instance, once it is registered
+	NodeName string `json:"nodeName,omitempty"`
+	// Version is the WMCO version which configured the instance
+	Version string `json:"version,omitempty"`
+	// LastConfigurationTime is the time at which the instance was last configured successfully
+	LastConfigurationTime *meta.Time `json:"lastConfigurationTime,omitempty"`
+	// LastError is the error of the last failed configuration of the instance, cleared once it is configured
+	LastError string `json:"lastError,omitempty"`
+	// Conditions are the NetworkPrerequisitesMet, PreflightPassed, ArtifactsReachable, NICValid, Reachable,
+	// PayloadCurrent, ServicesRunning, NetworkReady, ClockSynchronized, SmokeTestPassed and Excluded conditions of the
+	// instance
+	Conditions []meta.Condition `json:"conditions,omitempty"`
+	// ConfigurationAttempts is the journal of the most recent attempts to configure the instance, oldest first
+	ConfigurationAttempts []ConfigurationAttempt `json:"configurationAttempts,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.nodeName`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Last Configured",type=date,JSONPath=`.status.lastConfigurationTime`
+
+// WindowsNode reports the status of a Windows instance managed by WMCO. It is named after the Machine backing the
+// instance and is read only, the status being maintained by WMCO.
+type WindowsNode struct {
+	meta.TypeMeta   `json:",inline"`
+	meta.ObjectMeta `json:"metadata,omitempty"`
+
+	Status WindowsNodeStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WindowsNodeList is a list of WindowsNodes
+type WindowsNodeList struct {
+	meta.TypeMeta `json:",inline"`
+	meta.ListMeta `json:"metadata,omitempty"`
+	Items         []WindowsNode `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WindowsNode{}, &WindowsNodeList{})
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// WindowsAntivirusMisconfigured is the node condition which is True when Microsoft Defender Antivirus runs on a
+	// Windows node with its real-time protection disabled, signatures older than antivirusSignatureMaxAge, or without
+	// the exclusions of the container runtime and Kubernetes components, whose absence slows down the containers
+	WindowsAntivirusMisconfigured core.NodeConditionType = "WindowsAntivirusMisconfigured"
+	// AntivirusCheckedAnnotation holds the time at which the antivirus status of the node was last checked
+	AntivirusCheckedAnnotation = "windowsmachineconfig.openshift.io/antivirus-checked"
+
+	// antivirusCheckPeriod is the interval at which the antivirus status of configured Windows nodes is checked
+	antivirusCheckPeriod = time.Hour
+	// antivirusSignatureMaxAge is the age above which the antivirus signatures are outdated
+	antivirusSignatureMaxAge = 7 * 24 * time.Hour
+)
+
+var (
+	// antivirusRealTimeProtection is 1 when the real-time protection of Microsoft Defender Antivirus is enabled on each
+	// Windows Machine, else 0
+	antivirusRealTimeProtection = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_machine_antivirus_realtime_protection",
+		Help: "1 when the real-time protection of Microsoft Defender Antivirus is enabled on the Windows Machine, " +
+			"else 0",
+	}, []string{"machine"})
+	// antivirusSignatureAge is the age of the antivirus signatures of each Windows Machine running Defender
+	antivirusSignatureAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_machine_antivirus_signature_age_seconds",
+		Help: "Time elapsed since the Microsoft Defender Antivirus signatures of the Windows Machine were updated",
+	}, []string{"machine"})
+	// antivirusMissingExclusions is the number of required antivirus exclusions missing on each Windows Machine
+	antivirusMissingExclusions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_machine_antivirus_missing_exclusions",
+		Help: "Number of the paths and processes of the container runtime and Kubernetes components missing from " +
+			"the Microsoft Defender Antivirus exclusions of the Windows Machine",
+	}, []string{"machine"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(antivirusRealTimeProtection, antivirusSignatureAge, antivirusMissingExclusions)
+}
+
+// reconcileAntivirus checks the status of Microsoft Defender Antivirus on the VM backing the given Machine, recording
+// it in the antivirus metrics and in the WindowsAntivirusMisconfigured condition of its node, as a misconfigured
+// antivirus is a frequent cause of the performance problems of Windows nodes. Third-party antiviruses are not
+// inspected. The status is checked once every antivirusCheckPeriod.
+func (r *machineReconciliation) reconcileAntivirus(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.config.ProblemDetection || r.config.DryRun {
+		return ctrl.Result{}, nil
+	}
+	if remaining := checkRemaining(node, AntivirusCheckedAnnotation, antivirusCheckPeriod, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	antivirus, err := vm.Antivirus()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get antivirus status of node %s", node.GetName())
+	}
+	deleteAntivirusMetrics(machine.GetName())
+	if antivirus.Defender {
+		realTime := 0.0
+		if antivirus.RealTimeProtection {
+			realTime = 1
+		}
+		antivirusRealTimeProtection.WithLabelValues(machine.GetName()).Set(realTime)
+		antivirusSignatureAge.WithLabelValues(machine.GetName()).Set(time.Since(antivirus.SignaturesUpdated).Seconds())
+		antivirusMissingExclusions.WithLabelValues(machine.GetName()).Set(float64(len(antivirus.MissingExclusions)))
+	}
+	if err := r.setNodeConditions(ctx, machine, node.GetName(), antivirusCondition(antivirus, time.Now())); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.recordCheck(ctx, node.GetName(), AntivirusCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: antivirusCheckPeriod}, nil
+}
+
+// antivirusCondition returns the WindowsAntivirusMisconfigured node condition reporting the given antivirus status at
+// the given time
+func antivirusCondition(antivirus *windows.Antivirus, now time.Time) core.NodeCondition {
+	condition := core.NodeCondition{Type: WindowsAntivirusMisconfigured, Status: core.ConditionFalse,
+		Reason: "AntivirusConfigured", Message: "Microsoft Defender Antivirus is configured for Windows nodes"}
+	if !antivirus.Defender {
+		condition.Reason = "DefenderNotRunning"
+		condition.Message = "Microsoft Defender Antivirus is not running, third-party antiviruses are not inspected"
+		return condition
+	}
+	var problems []string
+	if !antivirus.RealTimeProtection {
+		problems = append(problems, "real-time protection is disabled")
+	}
+	if age := now.Sub(antivirus.SignaturesUpdated); age > antivirusSignatureMaxAge {
+		problems = append(problems, fmt.Sprintf("signatures were last updated %s ago", age.Round(time.Hour)))
+	}
+	if len(antivirus.MissingExclusions) > 0 {
+		problems = append(problems, "exclusions are missing: "+strings.Join(antivirus.MissingExclusions, ", "))
+	}
+	if len(problems) > 0 {
+		condition.Status = core.ConditionTrue
+		condition.Reason = "AntivirusMisconfigured"
+		condition.Message = "Microsoft Defender Antivirus " + strings.Join(problems, "; ")
+	}
+	return condition
+}
+
+// deleteAntivirusMetrics removes the antivirus metrics of the Machine with the given name
+func deleteAntivirusMetrics(machineName string) {
+	antivirusRealTimeProtection.DeleteLabelValues(machineName)
+	antivirusSignatureAge.DeleteLabelValues(machineName)
+	antivirusMissingExclusions.DeleteLabelValues(machineName)
+}
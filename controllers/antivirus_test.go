@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+func TestAntivirusCondition(t *testing.T) {
+	now := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		antivirus *windows.Antivirus
+		expected  core.ConditionStatus
+		reason    string
+	}{
+		{
+			name:      "not running",
+			antivirus: &windows.Antivirus{},
+			expected:  core.ConditionFalse,
+			reason:    "DefenderNotRunning",
+		},
+		{
+			name: "configured",
+			antivirus: &windows.Antivirus{Defender: true, RealTimeProtection: true,
+				SignaturesUpdated: now.Add(-24 * time.Hour)},
+			expected: core.ConditionFalse,
+			reason:   "AntivirusConfigured",
+		},
+		{
+			name: "outdated signatures",
+			antivirus: &windows.Antivirus{Defender: true, RealTimeProtection: true,
+				SignaturesUpdated: now.Add(-30 * 24 * time.Hour)},
+			expected: core.ConditionTrue,
+			reason:   "AntivirusMisconfigured",
+		},
+		{
+			name: "missing exclusions",
+			antivirus: &windows.Antivirus{Defender: true, RealTimeProtection: true, SignaturesUpdated: now,
+				MissingExclusions: []string{"kubelet.exe"}},
+			expected: core.ConditionTrue,
+			reason:   "AntivirusMisconfigured",
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			condition := antivirusCondition(test.antivirus, now)
+			assert.Equal(t, WindowsAntivirusMisconfigured, condition.Type)
+			assert.Equal(t, test.expected, condition.Status)
+			assert.Equal(t, test.reason, condition.Reason)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	oconfig "github.com/openshift/api/config/v1"
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// requiredImages returns the images pulled by the node of a Windows Machine for the pods WMCO runs on it: the pause
+// image, the images to pre-pull and the smoke test image
+func (r *machineReconciliation) requiredImages() []string {
+	images := []string{windows.DefaultPauseImage}
+	if r.config.PauseImage != "" {
+		images[0] = r.config.PauseImage
+	}
+	images = append(images, r.config.PrePullImages...)
+	if r.config.SmokeTest {
+		images = append(images, r.config.SmokeTestImage)
+	}
+	return images
+}
+
+// clusterProxy returns the HTTPS proxy configured cluster-wide, through which the artifact sources not excluded by its
+// noProxy list are reached, or nil if the cluster has none
+func (r *machineReconciliation) clusterProxy(ctx context.Context) (*httpproxy.Config, error) {
+	proxy := &oconfig.Proxy{}
+	if err := r.apiReader.Get(ctx, kubeTypes.NamespacedName{Name: "cluster"}, proxy); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "unable to get the cluster proxy")
+	}
+	if proxy.Status.HTTPSProxy == "" {
+		return nil, nil
+	}
+	return &httpproxy.Config{HTTPSProxy: proxy.Status.HTTPSProxy, NoProxy: proxy.Status.NoProxy}, nil
+}
+
+// checkArtifacts records whether the VM of the given Machine can reach the sources of the artifacts it retrieves over
+// the network, through the cluster proxy if any, in the WindowsNode of the Machine. An unreachable source is reported
+// without preventing the configuration, as the sources may be reached through means the check does not know of, such
+// as a proxy configured in the Windows image. A VM which cannot be connected to is not checked, so that the connection
+// failure is reported by the configuration of the Machine.
+func (r *machineReconciliation) checkArtifacts(ctx context.Context, machine *mapi.Machine) error {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	proxy, err := r.clusterProxy(ctx)
+	if err != nil {
+		return err
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		log.V(1).Info("unable to connect to check the artifact sources", "error", err.Error())
+		return nil
+	}
+	unreachable, err := vm.UnreachableArtifacts(r.requiredImages(), proxy)
+	if err != nil {
+		log.Error(err, "unable to check the artifact sources")
+		return nil
+	}
+	condition := meta.Condition{Type: wmcoapi.ArtifactsReachableCondition, Status: meta.ConditionTrue,
+		Reason: "Reachable", Message: "The instance can reach the sources of the artifacts it retrieves"}
+	if len(unreachable) > 0 {
+		condition.Status = meta.ConditionFalse
+		condition.Reason = "Unreachable"
+		condition.Message = "Unreachable from the instance: " + strings.Join(unreachable, "; ")
+		log.Info("artifact sources are unreachable, the configuration may fail", "unreachable", unreachable)
+		r.recorder.Eventf(machine, core.EventTypeWarning, "ArtifactsUnreachable",
+			"The configuration of Machine %s may fail, unreachable from the instance: %s", machine.GetName(),
+			strings.Join(unreachable, "; "))
+	}
+	return r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		apimeta.SetStatusCondition(&status.Conditions, condition)
+	})
+}
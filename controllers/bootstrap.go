@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// BootstrapCredentialsCheckedAnnotation holds the time at which the expiry of the bootstrap kubeconfig of the node
+	// was last checked
+	BootstrapCredentialsCheckedAnnotation = "windowsmachineconfig.openshift.io/bootstrap-credentials-checked"
+	// bootstrapCredentialsCheckPeriod is the interval at which the expiry of the bootstrap kubeconfigs of configured
+	// Windows nodes is checked
+	bootstrapCredentialsCheckPeriod = 24 * time.Hour
+	// bootstrapCredentialsRenewalThreshold is the time before their expiry from which the credentials of a bootstrap
+	// kubeconfig are renewed, leaving kubelet able to request a client certificate if its current one expires. It is
+	// much longer than the check period, so that the credentials are renewed in time.
+	bootstrapCredentialsRenewalThreshold = 30 * 24 * time.Hour
+)
+
+// bootstrapCredentialsExpiry is the expiry of the credentials of the bootstrap kubeconfig of each Windows Machine
+var bootstrapCredentialsExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "windows_machine_bootstrap_credentials_expiry_timestamp_seconds",
+	Help: "Expiry of the credentials of the bootstrap kubeconfig of the Windows Machine, in seconds since the epoch",
+}, []string{"machine"})
+
+func init() {
+	metrics.Registry.MustRegister(bootstrapCredentialsExpiry)
+}
+
+// reconcileBootstrapCredentials checks the bootstrap kubeconfig of the VM backing the given Machine once every
+// bootstrapCredentialsCheckPeriod, renewing it if needed
+func (r *machineReconciliation) reconcileBootstrapCredentials(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if remaining := checkRemaining(node, BootstrapCredentialsCheckedAnnotation, bootstrapCredentialsCheckPeriod,
+		time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	if err := r.renewBootstrapCredentials(machine, node); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.recordCheck(ctx, node.GetName(), BootstrapCredentialsCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: bootstrapCredentialsCheckPeriod}, nil
+}
+
+// renewBootstrapCredentials renews the bootstrap kubeconfig of the VM backing the given Machine from the current
+// worker ignition once its credentials are within bootstrapCredentialsRenewalThreshold of their expiry, so that the
+// kubelet of a long-lived node can still request a client certificate after its current one expired. A warning event
+// is emitted if the renewed credentials still expire soon, as the worker ignition then holds outdated credentials.
+func (r *machineReconciliation) renewBootstrapCredentials(machine *mapi.Machine, node *core.Node) error {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return err
+	}
+	expiry, err := vm.BootstrapCredentialsExpiry()
+	if err != nil {
+		return err
+	}
+	if expiry.IsZero() {
+		bootstrapCredentialsExpiry.DeleteLabelValues(machine.GetName())
+		return nil
+	}
+	bootstrapCredentialsExpiry.WithLabelValues(machine.GetName()).Set(float64(expiry.Unix()))
+	if time.Until(expiry) > bootstrapCredentialsRenewalThreshold {
+		return nil
+	}
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "BootstrapCredentialsRenewal",
+			"Machine %s node %s bootstrap kubeconfig expiring at %s would be renewed", machine.GetName(),
+			node.GetName(), expiry.UTC().Format(time.RFC3339))
+		return nil
+	}
+
+	if err := vm.RenewBootstrapCredentials(); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "BootstrapCredentialsRenewalFailure",
+			"Machine %s node %s bootstrap kubeconfig expiring at %s could not be renewed", machine.GetName(),
+			node.GetName(), expiry.UTC().Format(time.RFC3339))
+		return errors.Wrapf(err, "unable to renew bootstrap kubeconfig of node %s", node.GetName())
+	}
+	renewed, err := vm.BootstrapCredentialsExpiry()
+	if err != nil {
+		return err
+	}
+	if renewed.IsZero() {
+		bootstrapCredentialsExpiry.DeleteLabelValues(machine.GetName())
+	} else {
+		bootstrapCredentialsExpiry.WithLabelValues(machine.GetName()).Set(float64(renewed.Unix()))
+	}
+	if !renewed.IsZero() && time.Until(renewed) <= bootstrapCredentialsRenewalThreshold {
+		log.Info("worker ignition holds expiring bootstrap credentials", "node", node.GetName(), "expiry", renewed)
+		r.recorder.Eventf(machine, core.EventTypeWarning, "BootstrapCredentialsExpiring",
+			"Machine %s node %s bootstrap kubeconfig expires at %s, and the worker ignition holds no newer "+
+				"credentials", machine.GetName(), node.GetName(), renewed.UTC().Format(time.RFC3339))
+		return nil
+	}
+	log.Info("bootstrap kubeconfig renewed", "node", node.GetName(), "expiry", renewed)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "BootstrapCredentialsRenewed",
+		"Machine %s node %s bootstrap kubeconfig renewed", machine.GetName(), node.GetName())
+	return nil
+}
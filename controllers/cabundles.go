@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// CABundlesCheckedAnnotation holds the time at which the CA bundles of the node were last compared with the ones of
+	// the worker ignition
+	CABundlesCheckedAnnotation = "windowsmachineconfig.openshift.io/ca-bundles-checked"
+	// CABundlesUpdatingAnnotation is applied to a node while the services reading its CA bundles are restarted to pick
+	// up the updated CA bundles
+	CABundlesUpdatingAnnotation = "windowsmachineconfig.openshift.io/ca-bundles-updating"
+	// caBundleCheckPeriod is the interval at which the CA bundles of configured Windows nodes are compared with the
+	// ones of the worker ignition. The rotations of the cluster CAs overlap the old and new CAs for much longer.
+	caBundleCheckPeriod = time.Hour
+)
+
+func init() {
+	disruptionAnnotations = append(disruptionAnnotations, CABundlesUpdatingAnnotation)
+}
+
+// reconcileCABundles updates the CA bundles of the node of the given Machine once the kubelet client CA or the API
+// server CA of the cluster rotated, as published in the worker ignition, so that the node keeps trusting the API
+// server and being trusted by it without being recreated. When the update restarts services, it is rolled out as a
+// node disruption, during maintenance windows and to at most maxUnhealthyCount nodes at a time.
+func (r *machineReconciliation) reconcileCABundles(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	_, updating := node.Annotations[CABundlesUpdatingAnnotation]
+	if remaining := checkRemaining(node, CABundlesCheckedAnnotation, caBundleCheckPeriod, time.Now()); remaining > 0 &&
+		!updating {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	drift, err := vm.CABundleDrift()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	services := windows.CABundleServices(drift)
+	if len(drift) > 0 && r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "CABundleUpdate",
+			"Machine %s node %s CA bundles would be updated, restarting the services %s: %s", machine.GetName(),
+			node.GetName(), strings.Join(services, ", "), strings.Join(drift, ", "))
+	}
+	if len(drift) > 0 && !r.config.DryRun {
+		if len(services) > 0 && !updating {
+			var result ctrl.Result
+			if node, result, err = r.startNodeDisruption(ctx, machine, node.DeepCopy(), CABundlesUpdatingAnnotation,
+				"CA bundle update"); err != nil || !result.IsZero() {
+				return result, err
+			}
+		}
+		updated, err := vm.UpdateCABundles()
+		if err != nil {
+			r.recorder.Eventf(machine, core.EventTypeWarning, "CABundleUpdateFailure",
+				"Machine %s node %s CA bundles could not be updated", machine.GetName(), node.GetName())
+			return ctrl.Result{}, errors.Wrapf(err, "unable to update CA bundles of node %s", node.GetName())
+		}
+		log.Info("CA bundles updated", "node", node.GetName(), "files", updated, "restarted", services)
+		r.recorder.Eventf(machine, core.EventTypeNormal, "CABundlesUpdated",
+			"Machine %s node %s CA bundles updated after a rotation of the cluster CAs: %s", machine.GetName(),
+			node.GetName(), strings.Join(updated, ", "))
+	}
+	if _, present := node.Annotations[CABundlesUpdatingAnnotation]; present {
+		if err := r.finishNodeDisruption(ctx, node.GetName(), CABundlesUpdatingAnnotation); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.recordCheck(ctx, node.GetName(), CABundlesCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: caBundleCheckPeriod}, nil
+}
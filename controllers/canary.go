@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/retry"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+const (
+	// CanaryVerifiedAnnotation is applied to the canary node once it has passed health verification. The value is the
+	// operator version the node was verified with.
+	CanaryVerifiedAnnotation = "windowsmachineconfig.openshift.io/canary-verified"
+	// CanaryFailedAnnotation is applied to the canary node once it has failed health verification. The value
+	// identifies the verification attempt, made by an operator version with a test pod image, so that a failure does
+	// not hold the upgrade once either changes. Removing the annotation retries the verification.
+	CanaryFailedAnnotation = "windowsmachineconfig.openshift.io/canary-failed"
+	// canaryPodPrefix is the prefix of the name of the test pod scheduled on the canary node
+	canaryPodPrefix = "windows-canary-"
+	// canaryPodImage is the image used by the canary test pod, unless a pause image is configured
+	canaryPodImage = windows.DefaultPauseImage
+	// canaryRequeueDelay is the time to wait before checking on the canary again
+	canaryRequeueDelay = time.Minute
+)
+
+// canaryState describes the progress of a canary upgrade
+type canaryState int
+
+const (
+	// canaryNotStarted indicates that no node has been upgraded yet and no upgrade is in progress, so the machine being
+	// reconciled can become the canary
+	canaryNotStarted canaryState = iota
+	// canaryInProgress indicates that the canary is being upgraded or verified
+	canaryInProgress
+	// canaryFailed indicates that the canary node failed health verification and the upgrade has been aborted, until
+	// the verification is retried
+	canaryFailed
+	// canaryVerified indicates that the canary node passed health verification and the rest of the fleet can proceed
+	canaryVerified
+)
+
+// getCanaryState returns the state of the canary upgrade for the current operator version. The canary is the last
+// Windows node configured by the current operator version. Until it has been verified as healthy no other outdated
+// Windows machine is allowed to be deleted.
+func (r *machineReconciliation) getCanaryState(ctx context.Context) (canaryState, error) {
+	nodes, err := r.k8sclientset.CoreV1().Nodes().List(ctx, meta.ListOptions{LabelSelector: nodeconfig.WindowsOSLabel})
+	if err != nil {
+		return canaryInProgress, errors.Wrap(err, "error listing Windows nodes")
+	}
+
+	canary, verified := selectCanary(nodes.Items, version.Get())
+	if verified {
+		return canaryVerified, nil
+	}
+	if canary != nil {
+		return r.verifyCanary(ctx, canary)
+	}
+
+	// No node is running the current version yet, check if a machine is already being upgraded
+	machines, err := listWindowsMachines(ctx, r.client)
+	if err != nil {
+		return canaryInProgress, err
+	}
+	for _, machine := range machines {
+		if !r.config.ManagesMachine(machine.GetLabels()) {
+			continue
+		}
+		if !machine.GetDeletionTimestamp().IsZero() || !r.isWindowsMachineHealthy(&machine) {
+			return canaryInProgress, nil
+		}
+	}
+	return canaryNotStarted, nil
+}
+
+// selectCanary returns the canary among the given Windows nodes, which is the last node configured by the given
+// operator version, or nil if there is none. It also returns true if a node configured by that version has been
+// verified, in which case the canary upgrade is complete.
+func selectCanary(nodes []core.Node, operatorVersion string) (*core.Node, bool) {
+	var canary *core.Node
+	for i, node := range nodes {
+		if node.Annotations[nodeconfig.VersionAnnotation] != operatorVersion {
+			continue
+		}
+		if node.Annotations[CanaryVerifiedAnnotation] == operatorVersion {
+			return nil, true
+		}
+		canary = &nodes[i]
+	}
+	return canary, false
+}
+
+// verifyCanary verifies that the given canary node is Ready and that a test pod can be scheduled onto it. The node
+// is annotated with CanaryVerifiedAnnotation once verification succeeds, and with CanaryFailedAnnotation once it
+// fails. The test pod is deleted in both cases.
+func (r *machineReconciliation) verifyCanary(ctx context.Context, node *core.Node) (canaryState, error) {
+	image := canaryPodImage
+	if r.config.PauseImage != "" {
+		image = r.config.PauseImage
+	}
+	attempt := canaryAttempt(version.Get(), image)
+	podName := canaryPodPrefix + node.GetName()
+	pods := r.k8sclientset.CoreV1().Pods(r.watchNamespace)
+
+	var pod *core.Pod
+	if node.Annotations[CanaryFailedAnnotation] != attempt && isNodeReady(node) {
+		var err error
+		if pod, err = pods.Get(ctx, podName, meta.GetOptions{}); err != nil {
+			if !k8sapierrors.IsNotFound(err) {
+				return canaryInProgress, errors.Wrapf(err, "error getting canary pod %s", podName)
+			}
+			pod = nil
+		}
+		// A test pod left by a verification with another image is replaced
+		if pod != nil && pod.Spec.Containers[0].Image != image {
+			r.log.Info("replacing canary test pod", "node", node.GetName(), "image", image)
+			if err := pods.Delete(ctx, podName, meta.DeleteOptions{}); err != nil && !k8sapierrors.IsNotFound(err) {
+				return canaryInProgress, errors.Wrapf(err, "error deleting canary pod %s", podName)
+			}
+			return canaryInProgress, nil
+		}
+	}
+
+	state, reason := evaluateCanary(node, pod, attempt, time.Now())
+	switch state {
+	case canaryInProgress:
+		if pod == nil && isNodeReady(node) {
+			r.log.Info("scheduling canary test pod", "node", node.GetName())
+			if _, err := pods.Create(ctx, newCanaryPod(podName, node.GetName(), image),
+				meta.CreateOptions{}); err != nil {
+				return canaryInProgress, errors.Wrapf(err, "error creating canary pod %s", podName)
+			}
+		}
+		return canaryInProgress, nil
+	case canaryFailed:
+		// The failure has already been recorded against this attempt
+		if reason == "" {
+			return canaryFailed, nil
+		}
+	}
+
+	// The test pod is not needed anymore, the canary node has been verified or has failed verification
+	if err := pods.Delete(ctx, podName, meta.DeleteOptions{}); err != nil && !k8sapierrors.IsNotFound(err) {
+		return canaryInProgress, errors.Wrapf(err, "error deleting canary pod %s", podName)
+	}
+	if state == canaryFailed {
+		node.Annotations[CanaryFailedAnnotation] = attempt
+	} else {
+		node.Annotations[CanaryVerifiedAnnotation] = version.Get()
+		delete(node.Annotations, CanaryFailedAnnotation)
+	}
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return canaryInProgress, errors.Wrapf(err, "error annotating canary node %s", node.GetName())
+	}
+	if state == canaryFailed {
+		r.log.Info("canary node failed verification", "node", node.GetName(), "version", version.Get(),
+			"reason", reason)
+		r.recorder.Eventf(node, core.EventTypeWarning, "CanaryUpgradeFailed",
+			"%s, upgrade of remaining Windows nodes aborted", reason)
+		return canaryFailed, nil
+	}
+	r.log.Info("canary node verified", "node", node.GetName(), "version", version.Get())
+	r.recorder.Eventf(node, core.EventTypeNormal, "CanaryUpgradeVerified",
+		"canary node %s verified, proceeding with the upgrade of remaining Windows nodes", node.GetName())
+	return canaryVerified, nil
+}
+
+// canaryAttempt returns the value of CanaryFailedAnnotation identifying a verification of the canary by the given
+// operator version with a test pod running the given image. A failure is only held against the same attempt, so that
+// the verification is retried once the operator is upgraded or the pause image is changed.
+func canaryAttempt(operatorVersion, image string) string {
+	return operatorVersion + "," + image
+}
+
+// evaluateCanary returns the state of the verification of the given canary node at the given time, given its test
+// pod, nil if it does not exist, and the current verification attempt. The test pod is only considered once the node
+// is Ready. A newly detected failure is returned along with its reason, which is empty if the failure has already
+// been recorded against the attempt.
+func evaluateCanary(node *core.Node, pod *core.Pod, attempt string, now time.Time) (canaryState, string) {
+	if node.Annotations[CanaryFailedAnnotation] == attempt {
+		return canaryFailed, ""
+	}
+	if !isNodeReady(node) {
+		if now.Sub(node.GetCreationTimestamp().Time) > retry.Timeout {
+			return canaryFailed, fmt.Sprintf("canary node %s is not Ready", node.GetName())
+		}
+		return canaryInProgress, ""
+	}
+	if pod == nil {
+		return canaryInProgress, ""
+	}
+	if !isPodScheduled(pod) {
+		if now.Sub(pod.GetCreationTimestamp().Time) < retry.Timeout {
+			return canaryInProgress, ""
+		}
+		return canaryFailed, fmt.Sprintf("canary test pod could not be scheduled on node %s", node.GetName())
+	}
+	return canaryVerified, ""
+}
+
+// newCanaryPod returns a test pod running the given image, which must be scheduled onto the given node
+func newCanaryPod(name, nodeName, image string) *core.Pod {
+	return &core.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app": "windows-canary"},
+		},
+		Spec: core.PodSpec{
+			Containers: []core.Container{{
+				Name:  "pause",
+				Image: image,
+			}},
+			NodeSelector: map[string]string{
+				core.LabelHostname: nodeName,
+				core.LabelOSStable: "windows",
+			},
+			Tolerations: []core.Toleration{{
+				Operator: core.TolerationOpExists,
+			}},
+		},
+	}
+}
+
+// isNodeReady returns true if the given node has the Ready condition set to true
+func isNodeReady(node *core.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == core.NodeReady {
+			return condition.Status == core.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isPodScheduled returns true if the given pod has been scheduled onto a node
+func isPodScheduled(pod *core.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == core.PodScheduled {
+			return condition.Status == core.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/retry"
+)
+
+func TestIsNodeReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []core.NodeCondition
+		expected   bool
+	}{
+		{
+			name:       "no conditions",
+			conditions: nil,
+			expected:   false,
+		},
+		{
+			name:       "ready",
+			conditions: []core.NodeCondition{{Type: core.NodeReady, Status: core.ConditionTrue}},
+			expected:   true,
+		},
+		{
+			name: "not ready",
+			conditions: []core.NodeCondition{
+				{Type: core.NodeMemoryPressure, Status: core.ConditionTrue},
+				{Type: core.NodeReady, Status: core.ConditionFalse},
+			},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &core.Node{Status: core.NodeStatus{Conditions: test.conditions}}
+			assert.Equal(t, test.expected, isNodeReady(node))
+		})
+	}
+}
+
+func TestNewCanaryPod(t *testing.T) {
+	pod := newCanaryPod(canaryPodPrefix+"node", "node", canaryPodImage)
+	assert.Equal(t, canaryPodImage, pod.Spec.Containers[0].Image)
+	assert.Equal(t, "node", pod.Spec.NodeSelector[core.LabelHostname])
+	assert.Equal(t, "windows", pod.Spec.NodeSelector[core.LabelOSStable])
+	assert.False(t, isPodScheduled(pod))
+
+	pod.Status.Conditions = []core.PodCondition{{Type: core.PodScheduled, Status: core.ConditionTrue}}
+	assert.True(t, isPodScheduled(pod))
+}
+
+func TestSelectCanary(t *testing.T) {
+	node := func(name string, annotations map[string]string) core.Node {
+		return core.Node{ObjectMeta: meta.ObjectMeta{Name: name, Annotations: annotations}}
+	}
+	tests := []struct {
+		name             string
+		nodes            []core.Node
+		expectedCanary   string
+		expectedVerified bool
+	}{
+		{
+			name:  "no node of the current version",
+			nodes: []core.Node{node("old", map[string]string{nodeconfig.VersionAnnotation: "1.0.0"})},
+		},
+		{
+			name: "last node of the current version",
+			nodes: []core.Node{
+				node("first", map[string]string{nodeconfig.VersionAnnotation: "2.0.0"}),
+				node("old", map[string]string{nodeconfig.VersionAnnotation: "1.0.0"}),
+				node("last", map[string]string{nodeconfig.VersionAnnotation: "2.0.0"}),
+				node("unconfigured", nil),
+			},
+			expectedCanary: "last",
+		},
+		{
+			name: "verified by a previous version",
+			nodes: []core.Node{node("canary", map[string]string{nodeconfig.VersionAnnotation: "2.0.0",
+				CanaryVerifiedAnnotation: "1.0.0"})},
+			expectedCanary: "canary",
+		},
+		{
+			name: "verified",
+			nodes: []core.Node{
+				node("canary", map[string]string{nodeconfig.VersionAnnotation: "2.0.0"}),
+				node("verified", map[string]string{nodeconfig.VersionAnnotation: "2.0.0",
+					CanaryVerifiedAnnotation: "2.0.0"}),
+			},
+			expectedVerified: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			canary, verified := selectCanary(test.nodes, "2.0.0")
+			assert.Equal(t, test.expectedVerified, verified)
+			if test.expectedCanary == "" {
+				assert.Nil(t, canary)
+				return
+			}
+			if assert.NotNil(t, canary) {
+				assert.Equal(t, test.expectedCanary, canary.GetName())
+			}
+		})
+	}
+}
+
+func TestEvaluateCanary(t *testing.T) {
+	now := time.Now()
+	attempt := canaryAttempt("2.0.0", canaryPodImage)
+	ready := []core.NodeCondition{{Type: core.NodeReady, Status: core.ConditionTrue}}
+	scheduled := []core.PodCondition{{Type: core.PodScheduled, Status: core.ConditionTrue}}
+	tests := []struct {
+		name           string
+		nodeAge        time.Duration
+		nodeConditions []core.NodeCondition
+		failedAttempt  string
+		podAge         time.Duration
+		podConditions  []core.PodCondition
+		noPod          bool
+		expectedState  canaryState
+		expectedReason bool
+	}{
+		{
+			name:          "node not Ready yet",
+			nodeAge:       time.Minute,
+			noPod:         true,
+			expectedState: canaryInProgress,
+		},
+		{
+			name:           "node not Ready in time",
+			nodeAge:        retry.Timeout + time.Minute,
+			noPod:          true,
+			expectedState:  canaryFailed,
+			expectedReason: true,
+		},
+		{
+			name:           "test pod to be created",
+			nodeAge:        time.Minute,
+			nodeConditions: ready,
+			noPod:          true,
+			expectedState:  canaryInProgress,
+		},
+		{
+			name:           "test pod not scheduled yet",
+			nodeAge:        time.Minute,
+			nodeConditions: ready,
+			podAge:         time.Minute,
+			expectedState:  canaryInProgress,
+		},
+		{
+			name:           "test pod not scheduled in time",
+			nodeAge:        retry.Timeout + time.Minute,
+			nodeConditions: ready,
+			podAge:         retry.Timeout + time.Second,
+			expectedState:  canaryFailed,
+			expectedReason: true,
+		},
+		{
+			name:           "test pod scheduled",
+			nodeAge:        time.Minute,
+			nodeConditions: ready,
+			podAge:         time.Minute,
+			podConditions:  scheduled,
+			expectedState:  canaryVerified,
+		},
+		{
+			name:          "failure recorded",
+			nodeAge:       retry.Timeout + time.Minute,
+			failedAttempt: attempt,
+			noPod:         true,
+			expectedState: canaryFailed,
+		},
+		{
+			name:           "failure recorded against another image is retried",
+			nodeAge:        retry.Timeout + time.Minute,
+			nodeConditions: ready,
+			failedAttempt:  canaryAttempt("2.0.0", "registry.example.com/pause:3.5"),
+			noPod:          true,
+			expectedState:  canaryInProgress,
+		},
+		{
+			name:           "failure recorded against a previous version is retried",
+			nodeAge:        retry.Timeout + time.Minute,
+			nodeConditions: ready,
+			failedAttempt:  canaryAttempt("1.0.0", canaryPodImage),
+			podAge:         time.Minute,
+			podConditions:  scheduled,
+			expectedState:  canaryVerified,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &core.Node{
+				ObjectMeta: meta.ObjectMeta{Name: "canary", Annotations: map[string]string{},
+					CreationTimestamp: meta.NewTime(now.Add(-test.nodeAge))},
+				Status: core.NodeStatus{Conditions: test.nodeConditions},
+			}
+			if test.failedAttempt != "" {
+				node.Annotations[CanaryFailedAnnotation] = test.failedAttempt
+			}
+			var pod *core.Pod
+			if !test.noPod {
+				pod = newCanaryPod(canaryPodPrefix+"canary", "canary", canaryPodImage)
+				pod.CreationTimestamp = meta.NewTime(now.Add(-test.podAge))
+				pod.Status.Conditions = test.podConditions
+			}
+			state, reason := evaluateCanary(node, pod, attempt, now)
+			assert.Equal(t, test.expectedState, state)
+			assert.Equal(t, test.expectedReason, reason != "")
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// CertificateExpiring is the node condition which is True when certificates of the Kubernetes components of a
+	// Windows node are about to expire, after which the node would silently drop off the cluster
+	CertificateExpiring core.NodeConditionType = "CertificateExpiring"
+	// CertificatesCheckedAnnotation holds the time at which the certificates of the node were last read
+	CertificatesCheckedAnnotation = "windowsmachineconfig.openshift.io/certificates-checked"
+
+	// certExpiryCheckPeriod is the interval at which the certificates of configured Windows nodes are checked
+	certExpiryCheckPeriod = time.Hour
+	// certExpiryWarningPeriod is the longest time before its expiry a certificate is reported as expiring
+	certExpiryWarningPeriod = 30 * 24 * time.Hour
+	// certExpiryWarningFraction is the fraction of its lifetime left at which a certificate is reported as expiring.
+	// Short-lived certificates rotated by kubelet, by 90% of their lifetime, are only reported once their rotation is
+	// overdue.
+	certExpiryWarningFraction = 0.1
+)
+
+var (
+	// certificateExpiry is the expiry of each certificate of the Kubernetes components of each Windows Machine
+	certificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_machine_certificate_expiry_timestamp_seconds",
+		Help: "Expiry of the certificate of a Kubernetes component of the Windows Machine, in seconds since the epoch",
+	}, []string{"machine", "certificate"})
+	// certificateExpiring is 1 for each certificate of the Kubernetes components of each Windows Machine which is about
+	// to expire, else 0
+	certificateExpiring = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_machine_certificate_expiring",
+		Help: "1 when the certificate of a Kubernetes component of the Windows Machine is about to expire, else 0",
+	}, []string{"machine", "certificate"})
+)
+
+var (
+	// certificatesExported holds the names of the Machines whose certificate metrics were exported since the operator
+	// started
+	certificatesExported = make(map[string]bool)
+	// certificatesExportedMutex guards certificatesExported
+	certificatesExportedMutex sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(certificateExpiry, certificateExpiring)
+}
+
+// reconcileCertificateExpiry reads the certificates of the Kubernetes components of the VM backing the given Machine,
+// recording their expiry in the certificate metrics, and the ones about to expire in the CertificateExpiring
+// condition of its node. The certificates are read once every certExpiryCheckPeriod, and on the first reconciliation
+// since the operator started, so that the metrics alerted on are not missing until the period elapses.
+func (r *machineReconciliation) reconcileCertificateExpiry(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if remaining := checkRemaining(node, CertificatesCheckedAnnotation, certExpiryCheckPeriod,
+		time.Now()); remaining > 0 && certificateMetricsExported(machine.GetName()) {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	certificates, err := vm.Certificates()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to read certificates of node %s", node.GetName())
+	}
+	now := time.Now()
+	var expiring []string
+	for name, validity := range certificates {
+		certificateExpiry.WithLabelValues(machine.GetName(), name).Set(float64(validity.NotAfter.Unix()))
+		value := 0.0
+		if isCertificateExpiring(validity, now) {
+			value = 1
+			expiring = append(expiring, fmt.Sprintf("%s expires at %s", name,
+				validity.NotAfter.UTC().Format(time.RFC3339)))
+		}
+		certificateExpiring.WithLabelValues(machine.GetName(), name).Set(value)
+	}
+	setCertificateMetricsExported(machine.GetName(), true)
+	if r.config.ProblemDetection && !r.config.DryRun {
+		if err := r.setCertificateCondition(ctx, machine, node, expiring); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.recordCheck(ctx, node.GetName(), CertificatesCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: certExpiryCheckPeriod}, nil
+}
+
+// setCertificateCondition lists the given certificates about to expire in the CertificateExpiring condition of the
+// given node
+func (r *machineReconciliation) setCertificateCondition(ctx context.Context, machine *mapi.Machine, node *core.Node,
+	expiring []string) error {
+	condition := core.NodeCondition{Type: CertificateExpiring, Status: core.ConditionFalse, Reason: "Valid",
+		Message: "No certificate is about to expire"}
+	if len(expiring) > 0 {
+		sort.Strings(expiring)
+		condition = core.NodeCondition{Type: CertificateExpiring, Status: core.ConditionTrue, Reason: "Expiring",
+			Message: "Certificates are about to expire: " + strings.Join(expiring, ", ")}
+	}
+	return r.setNodeConditions(ctx, machine, node.GetName(), condition)
+}
+
+// isCertificateExpiring returns true if the certificate with the given validity period expires, at the given time,
+// within the warning period or within the warning fraction of its lifetime, whichever is shorter
+func isCertificateExpiring(validity *windows.CertificateValidity, now time.Time) bool {
+	warning := time.Duration(float64(validity.NotAfter.Sub(validity.NotBefore)) * certExpiryWarningFraction)
+	if warning > certExpiryWarningPeriod {
+		warning = certExpiryWarningPeriod
+	}
+	return validity.NotAfter.Sub(now) < warning
+}
+
+// certificateMetricsExported returns true if the certificate metrics of the Machine with the given name were exported
+// since the operator started
+func certificateMetricsExported(machineName string) bool {
+	certificatesExportedMutex.Lock()
+	defer certificatesExportedMutex.Unlock()
+	return certificatesExported[machineName]
+}
+
+// setCertificateMetricsExported records whether the certificate metrics of the Machine with the given name are
+// exported
+func setCertificateMetricsExported(machineName string, exported bool) {
+	certificatesExportedMutex.Lock()
+	defer certificatesExportedMutex.Unlock()
+	if exported {
+		certificatesExported[machineName] = true
+		return
+	}
+	delete(certificatesExported, machineName)
+}
+
+// deleteCertificateMetrics deletes the certificate metrics of the given Machine
+func deleteCertificateMetrics(machineName string) {
+	setCertificateMetricsExported(machineName, false)
+	for _, name := range []string{windows.CertificateKubeletClient, windows.CertificateKubeletServing,
+		windows.CertificateKubeletCA} {
+		certificateExpiry.DeleteLabelValues(machineName, name)
+		certificateExpiring.DeleteLabelValues(machineName, name)
+	}
+}
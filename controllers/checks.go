@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+)
+
+// checkRemaining returns the time left at the given time until the periodic check, with the given period, whose last
+// run is recorded in the given annotation of the given node is due again. Zero is returned if the check is due, which
+// it is if it never ran. The checks run over SSH are gated this way as the Machines are reconciled much more often than
+// the checks must run, whenever the shortest of the requeue delays of all checks elapses or an object they watch
+// changes.
+func checkRemaining(node *core.Node, annotation string, period time.Duration, now time.Time) time.Duration {
+	checked, err := time.Parse(time.RFC3339, node.GetAnnotations()[annotation])
+	if err != nil {
+		return 0
+	}
+	if remaining := checked.Add(period).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// recordCheck records the current time in the given annotations of the node with the given name, as the last run of
+// the periodic checks they track
+func (r *WindowsMachineReconciler) recordCheck(ctx context.Context, nodeName string, annotations ...string) error {
+	checked := make(map[string]string)
+	for _, annotation := range annotations {
+		checked[annotation] = time.Now().UTC().Format(time.RFC3339)
+	}
+	return apply.NodeAnnotations(ctx, r.k8sclientset, nodeName, checked)
+}
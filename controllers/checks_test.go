@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckRemaining(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        time.Duration
+	}{
+		{
+			name: "never checked",
+			want: 0,
+		},
+		{
+			name:        "invalid time",
+			annotations: map[string]string{"checked": "yesterday"},
+			want:        0,
+		},
+		{
+			name:        "checked recently",
+			annotations: map[string]string{"checked": "2021-06-01T11:45:00Z"},
+			want:        45 * time.Minute,
+		},
+		{
+			name:        "check due",
+			annotations: map[string]string{"checked": "2021-06-01T10:00:00Z"},
+			want:        0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node", Annotations: test.annotations}}
+			assert.Equal(t, test.want, checkRemaining(node, "checked", time.Hour, now))
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+)
+
+const (
+	// ClockSkewCheckedAnnotation holds the time at which the clock of the node was last compared with the operator
+	// clock
+	ClockSkewCheckedAnnotation = "windowsmachineconfig.openshift.io/clock-skew-checked"
+
+	// clockSkewCheckPeriod is the interval at which the clocks of configured Windows nodes are compared with the
+	// operator clock
+	clockSkewCheckPeriod = 10 * time.Minute
+)
+
+// clockSkewSeconds is the offset of the clock of each Windows Machine from the operator clock, as last measured
+var clockSkewSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "windows_machine_clock_skew_seconds",
+	Help: "Offset of the clock of the Windows Machine from the clock of the operator, positive when ahead",
+}, []string{"machine"})
+
+func init() {
+	metrics.Registry.MustRegister(clockSkewSeconds)
+}
+
+// reconcileClockSkew compares the clock of the VM backing the given Machine with the operator clock, recording the
+// skew in the clock skew metric and in the ClockSynchronized condition of its WindowsNode. A warning event is emitted
+// when the skew starts exceeding the threshold, before it breaks the validation of certificates and tokens.
+func (r *machineReconciliation) reconcileClockSkew(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if r.config.ClockSkewThreshold == 0 || r.config.DryRun {
+		return ctrl.Result{}, nil
+	}
+	if remaining := checkRemaining(node, ClockSkewCheckedAnnotation, clockSkewCheckPeriod, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	skew, err := vm.ClockSkew()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	clockSkewSeconds.WithLabelValues(machine.GetName()).Set(skew.Seconds())
+
+	skew = skew.Round(time.Millisecond)
+	condition := meta.Condition{Type: wmcoapi.ClockSynchronizedCondition, Status: meta.ConditionTrue,
+		Reason: "WithinThreshold", Message: fmt.Sprintf("The clock of the instance is %s off the operator clock", skew)}
+	exceeded := skew > r.config.ClockSkewThreshold || skew < -r.config.ClockSkewThreshold
+	if exceeded {
+		condition.Status = meta.ConditionFalse
+		condition.Reason = "SkewExceeded"
+		condition.Message = fmt.Sprintf("The clock of the instance is %s off the operator clock, exceeding %s",
+			skew, r.config.ClockSkewThreshold)
+	}
+	newlyExceeded := false
+	if err := r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		newlyExceeded = exceeded && !apimeta.IsStatusConditionFalse(status.Conditions,
+			wmcoapi.ClockSynchronizedCondition)
+		apimeta.SetStatusCondition(&status.Conditions, condition)
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	if newlyExceeded {
+		r.log.Info("clock skew exceeds threshold", "machine", machine.GetName(), "node", node.GetName(),
+			"skew", skew)
+		r.recorder.Eventf(machine, core.EventTypeWarning, "ClockSkewDetected",
+			"Machine %s node %s clock is %s off the operator clock, exceeding %s", machine.GetName(), node.GetName(),
+			skew, r.config.ClockSkewThreshold)
+	}
+	if err := r.recordCheck(ctx, node.GetName(), ClockSkewCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: clockSkewCheckPeriod}, nil
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// clusterVersionName is the name of the ClusterVersion object reporting the progress of cluster upgrades
+	clusterVersionName = "version"
+	// clusterUpgradeDelay is the delay after which disruptive operations held by a cluster upgrade are attempted again,
+	// in case the end of the upgrade is missed
+	clusterUpgradeDelay = 15 * time.Minute
+)
+
+// isClusterUpgrading returns true if the ClusterVersion reports that a cluster upgrade is progressing
+func (r *WindowsMachineReconciler) isClusterUpgrading(ctx context.Context) (bool, error) {
+	clusterVersion := &oconfig.ClusterVersion{}
+	if err := r.client.Get(ctx, kubeTypes.NamespacedName{Name: clusterVersionName}, clusterVersion); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "unable to get ClusterVersion %s", clusterVersionName)
+	}
+	return isProgressing(clusterVersion), nil
+}
+
+// isProgressing returns true if the given ClusterVersion has the Progressing condition set to true
+func isProgressing(clusterVersion *oconfig.ClusterVersion) bool {
+	for _, condition := range clusterVersion.Status.Conditions {
+		if condition.Type == oconfig.OperatorProgressing {
+			return condition.Status == oconfig.ConditionTrue
+		}
+	}
+	return false
+}
+
+// clusterUpgradeCompletedPredicate passes the updates of the ClusterVersion ending a cluster upgrade, so that the
+// disruptive operations held during the upgrade are resumed
+func clusterUpgradeCompletedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldVersion, ok := e.ObjectOld.(*oconfig.ClusterVersion)
+			if !ok || e.ObjectNew.GetName() != clusterVersionName {
+				return false
+			}
+			return isProgressing(oldVersion) && !isProgressing(e.ObjectNew.(*oconfig.ClusterVersion))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
+}
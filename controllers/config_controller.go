@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/logging"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+)
+
+// NewNamespacedCache returns a cache restricted to the given namespace, added to the given manager. The manager
+// cache is cluster scoped, so objects such as ConfigMaps in the operator namespace are watched through this cache to
+// avoid requiring cluster wide permissions.
+func NewNamespacedCache(mgr manager.Manager, namespace string) (cache.Cache, error) {
+	namespacedCache, err := cache.New(mgr.GetConfig(), cache.Options{Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(), Namespace: namespace})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating namespaced cache")
+	}
+	if err := mgr.Add(namespacedCache); err != nil {
+		return nil, errors.Wrap(err, "error adding namespaced cache to the manager")
+	}
+	return namespacedCache, nil
+}
+
+// isOperatorConfigMap returns true if the given object is the operator configuration ConfigMap
+func isOperatorConfigMap(obj client.Object, namespace string) bool {
+	return obj.GetName() == operatorconfig.ConfigMapName && obj.GetNamespace() == namespace
+}
+
+// ConfigReconciler is used to create a controller which validates the operator configuration ConfigMap and applies
+// the settings which are not read by the other controllers at the start of each reconciliation, such as the log levels
+type ConfigReconciler struct {
+	k8sclientset *kubernetes.Clientset
+	log          logr.Logger
+	recorder     record.EventRecorder
+	// watchNamespace is the namespace the operator is watching as defined by the operator CSV
+	watchNamespace  string
+	namespacedCache cache.Cache
+	defaultConfig   operatorconfig.Config
+	// logLevels are the levels of the operator logs
+	logLevels *logging.Levels
+}
+
+// NewConfigReconciler returns a pointer to a ConfigReconciler
+func NewConfigReconciler(mgr manager.Manager, watchNamespace string, namespacedCache cache.Cache,
+	defaultConfig operatorconfig.Config, logLevels *logging.Levels) (*ConfigReconciler, error) {
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes clientset")
+	}
+	return &ConfigReconciler{
+		k8sclientset:    clientset,
+		log:             ctrl.Log.WithName("controller").WithName("config"),
+		recorder:        newThrottledRecorder(mgr.GetEventRecorderFor("config")),
+		watchNamespace:  watchNamespace,
+		namespacedCache: namespacedCache,
+		defaultConfig:   defaultConfig,
+		logLevels:       logLevels,
+	}, nil
+}
+
+// SetupWithManager sets up a new operator configuration controller with the given options
+func (r *ConfigReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
+	configPredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isOperatorConfigMap(e.Object, r.watchNamespace)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isOperatorConfigMap(e.ObjectNew, r.watchNamespace)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isOperatorConfigMap(e.Object, r.watchNamespace)
+		},
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("config").
+		Watches(source.NewKindWithCache(&core.ConfigMap{}, r.namespacedCache), &handler.EnqueueRequestForObject{},
+			builder.WithPredicates(configPredicate)).
+		WithOptions(options).
+		Complete(r)
+}
+
+// Reconcile validates the operator configuration and applies the log levels
+func (r *ConfigReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	config, err := operatorconfig.Load(ctx, r.k8sclientset, r.watchNamespace, r.defaultConfig)
+	if err != nil {
+		r.log.Error(err, "invalid operator configuration")
+		cm, getErr := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Get(ctx, operatorconfig.ConfigMapName,
+			meta.GetOptions{})
+		if getErr != nil {
+			if k8sapierrors.IsNotFound(getErr) {
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, errors.Wrapf(getErr, "error getting %s ConfigMap", operatorconfig.ConfigMapName)
+		}
+		r.recorder.Eventf(cm, core.EventTypeWarning, "InvalidConfiguration", "%v", err)
+		// The configuration will be reconciled again once it is changed
+		return ctrl.Result{}, nil
+	}
+
+	var controllerLevels map[string]zapcore.Level
+	for controller, level := range config.ControllerLogLevels {
+		if controllerLevels == nil {
+			controllerLevels = make(map[string]zapcore.Level)
+		}
+		controllerLevels[controller] = zapLevel(level)
+	}
+	if r.logLevels.Set(zapLevel(config.LogLevel), controllerLevels) {
+		r.log.Info("log levels changed", "logLevel", config.LogLevel, "controllerLogLevels",
+			config.ControllerLogLevels)
+	}
+	r.log.V(1).Info("operator configuration loaded", "config", config)
+	return ctrl.Result{}, nil
+}
+
+// zapLevel returns the zap level corresponding to the given log level
+func zapLevel(level operatorconfig.LogLevel) zapcore.Level {
+	if level == operatorconfig.LogLevelDebug {
+		return uberzap.DebugLevel
+	}
+	return uberzap.InfoLevel
+}
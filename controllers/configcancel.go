@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// deletionCheckInterval is the interval at which a Machine being configured is checked for deletion
+const deletionCheckInterval = 10 * time.Second
+
+// cancelOnDeletion calls the given cancel function once the given Machine starts being deleted, such as when its
+// MachineSet is scaled down, so that the configuration of a VM about to be terminated is aborted instead of failing
+// once the VM is gone. The Machine is read from the cache every deletionCheckInterval, until the given context is
+// done.
+func (r *WindowsMachineReconciler) cancelOnDeletion(ctx context.Context, machine *mapi.Machine, cancel func()) {
+	name := kubeTypes.NamespacedName{Namespace: machine.GetNamespace(), Name: machine.GetName()}
+	// The error returned once the context is done is ignored, as the configuration completed
+	_ = wait.PollUntil(deletionCheckInterval, func() (bool, error) {
+		current := &mapi.Machine{}
+		if err := r.client.Get(ctx, name, current); err != nil {
+			if k8sapierrors.IsNotFound(err) {
+				cancel()
+				return true, nil
+			}
+			r.log.V(1).Error(err, "unable to check machine deletion", "machine", name)
+			return false, nil
+		}
+		if isMachineDeleting(current) {
+			r.log.Info("machine deleting, cancelling configuration", "machine", name)
+			cancel()
+			return true, nil
+		}
+		return false, nil
+	}, ctx.Done())
+}
+
+// isMachineDeleting returns true if the given Machine is being deleted
+func isMachineDeleting(machine *mapi.Machine) bool {
+	return !machine.GetDeletionTimestamp().IsZero() ||
+		(machine.Status.Phase != nil && *machine.Status.Phase == "Deleting")
+}
+
+// isCancellation returns true if the given error stems from the cancellation of the operations on a VM, or of the
+// context they were run with
+func isCancellation(err error) bool {
+	return errors.Is(err, windows.ErrCancelled) || errors.Is(err, context.Canceled)
+}
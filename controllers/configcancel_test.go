@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsMachineDeleting(t *testing.T) {
+	phase := func(p string) *string { return &p }
+	now := meta.Now()
+	tests := []struct {
+		name    string
+		machine mapi.Machine
+		want    bool
+	}{
+		{name: "provisioned", machine: mapi.Machine{Status: mapi.MachineStatus{Phase: phase("Provisioned")}}},
+		{name: "no phase", machine: mapi.Machine{}},
+		{name: "deleting phase", machine: mapi.Machine{Status: mapi.MachineStatus{Phase: phase("Deleting")}},
+			want: true},
+		{name: "deletion timestamp", machine: mapi.Machine{ObjectMeta: meta.ObjectMeta{DeletionTimestamp: &now},
+			Status: mapi.MachineStatus{Phase: phase("Provisioned")}}, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, isMachineDeleting(&test.machine))
+		})
+	}
+}
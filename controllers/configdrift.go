@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+const (
+	// ConfigDriftCheckedAnnotation holds the time at which the observed configuration of the node was last compared
+	// with its desired configuration
+	ConfigDriftCheckedAnnotation = "windowsmachineconfig.openshift.io/config-drift-checked"
+	// PayloadCheckedAnnotation holds the time at which the payload files of the node were last hashed
+	PayloadCheckedAnnotation = "windowsmachineconfig.openshift.io/payload-checked"
+	// configDriftCheckPeriod is the interval at which the observed configuration of configured Windows nodes is
+	// compared with their desired configuration
+	configDriftCheckPeriod = time.Hour
+	// payloadCheckPeriod is the interval at which the payload files of configured Windows nodes are hashed, as part of
+	// the configuration drift check. Hashing the binaries of the Kubernetes components loads the VM, and they are only
+	// replaced by upgrades.
+	payloadCheckPeriod = 24 * time.Hour
+)
+
+// Reasons for which the configuration of a Windows node diverges from its desired configuration, as counted in the
+// configuration drift metric
+const (
+	// driftReasonVersion is the reason of the nodes configured by another operator version
+	driftReasonVersion = "version"
+	// driftReasonPayload is the reason of the nodes with payload files missing or with a different content
+	driftReasonPayload = "payload"
+	// driftReasonServices is the reason of the nodes with required services which are not running
+	driftReasonServices = "services"
+	// driftReasonHost is the reason of the nodes with other resources, such as directories, registry values or
+	// firewall rules, which drifted
+	driftReasonHost = "host"
+	// driftReasonAny counts the nodes which drifted for any reason
+	driftReasonAny = "any"
+)
+
+// driftReasons are the reasons counted in the configuration drift metric
+var driftReasons = []string{driftReasonVersion, driftReasonPayload, driftReasonServices, driftReasonHost,
+	driftReasonAny}
+
+var (
+	// configDriftedNodes is the number of Windows nodes whose observed configuration diverges from the desired one
+	configDriftedNodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_nodes_config_drifted",
+		Help: "Number of Windows nodes whose observed configuration diverges from the desired one, by reason",
+	}, []string{"reason"})
+	// configDrift holds the reasons for which the configuration of each Windows Machine last diverged, keyed by
+	// Machine name
+	configDrift = make(map[string][]string)
+	// payloadDrift holds whether the payload files of each Windows Machine differed from the payload when they were
+	// last hashed, keyed by Machine name
+	payloadDrift = make(map[string]bool)
+	// configDriftMutex guards configDrift and payloadDrift
+	configDriftMutex sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(configDriftedNodes)
+	for _, reason := range driftReasons {
+		configDriftedNodes.WithLabelValues(reason).Set(0)
+	}
+}
+
+// reconcileConfigDrift compares the observed configuration of the node of the given Machine, its version annotation,
+// payload files, required services and managed host resources, with its desired configuration, and records the
+// reasons of any divergence in the configuration drift metric, so that a drifting fleet or a stalled upgrade can be
+// alerted on. Drift is only reported, as it is corrected by the upgrade and host settings reconciliations. The payload
+// files are only hashed once every payloadCheckPeriod, their last state being reported in between.
+func (r *machineReconciliation) reconcileConfigDrift(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	now := time.Now()
+	if remaining := checkRemaining(node, ConfigDriftCheckedAnnotation, configDriftCheckPeriod, now); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	// The payload is hashed on the first check since the operator started, as the last state is not persisted
+	previousPayloadDrift, hashed := lastPayloadDrift(machine.GetName())
+	withPayload := !hashed || checkRemaining(node, PayloadCheckedAnnotation, payloadCheckPeriod, now) == 0
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	drift, err := vm.ConfigurationDrift(withPayload)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	reasons := classifyDrift(node.GetAnnotations()[nodeconfig.VersionAnnotation], desiredVersion(r.config), drift,
+		!withPayload && previousPayloadDrift)
+	checked := []string{ConfigDriftCheckedAnnotation}
+	if withPayload {
+		payloadDrifted := false
+		for _, reason := range reasons {
+			payloadDrifted = payloadDrifted || reason == driftReasonPayload
+		}
+		setPayloadDrift(machine.GetName(), payloadDrifted)
+		checked = append(checked, PayloadCheckedAnnotation)
+	}
+	if newlyDrifted := setConfigDrift(machine.GetName(), reasons); newlyDrifted {
+		log.Info("configuration drift detected", "node", node.GetName(), "reasons", reasons, "drift", drift)
+		r.recorder.Eventf(machine, core.EventTypeWarning, "ConfigurationDrift",
+			"Machine %s node %s configuration diverges from the desired one: %s", machine.GetName(),
+			node.GetName(), strings.Join(reasons, ", "))
+	}
+	if err := r.recordCheck(ctx, node.GetName(), checked...); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: configDriftCheckPeriod}, nil
+}
+
+// classifyDrift returns the reasons for which a node with the given version annotation, and whose resources drifted
+// as reported by ConfigurationDrift, diverges from its desired configuration with the given desired version. The
+// payload reason is also returned if payloadDrifted is set, for the payload files not hashed by ConfigurationDrift.
+func classifyDrift(nodeVersion, desiredVersion string, drift []string, payloadDrifted bool) []string {
+	found := make(map[string]bool)
+	if nodeVersion != desiredVersion {
+		found[driftReasonVersion] = true
+	}
+	found[driftReasonPayload] = payloadDrifted
+	for _, change := range drift {
+		switch {
+		case strings.HasPrefix(change, "file "):
+			found[driftReasonPayload] = true
+		case strings.HasPrefix(change, "stopped "):
+			found[driftReasonServices] = true
+		default:
+			found[driftReasonHost] = true
+		}
+	}
+	var reasons []string
+	for _, reason := range driftReasons {
+		if found[reason] {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// setConfigDrift records the drift reasons of the Machine with the given name, no reasons meaning that it does not
+// drift, and updates the configuration drift metric. It returns true if the Machine did not drift before.
+func setConfigDrift(machineName string, reasons []string) bool {
+	configDriftMutex.Lock()
+	defer configDriftMutex.Unlock()
+	_, drifted := configDrift[machineName]
+	if len(reasons) == 0 {
+		delete(configDrift, machineName)
+	} else {
+		configDrift[machineName] = reasons
+	}
+	updateConfigDriftMetric()
+	return !drifted && len(reasons) > 0
+}
+
+// lastPayloadDrift returns whether the payload files of the Machine with the given name differed from the payload when
+// they were last hashed, and whether they were hashed since the operator started
+func lastPayloadDrift(machineName string) (bool, bool) {
+	configDriftMutex.Lock()
+	defer configDriftMutex.Unlock()
+	drifted, hashed := payloadDrift[machineName]
+	return drifted, hashed
+}
+
+// setPayloadDrift records whether the payload files of the Machine with the given name differ from the payload
+func setPayloadDrift(machineName string, drifted bool) {
+	configDriftMutex.Lock()
+	defer configDriftMutex.Unlock()
+	payloadDrift[machineName] = drifted
+}
+
+// deleteConfigDrift forgets the drift of the Machine with the given name
+func deleteConfigDrift(machineName string) {
+	setConfigDrift(machineName, nil)
+	configDriftMutex.Lock()
+	defer configDriftMutex.Unlock()
+	delete(payloadDrift, machineName)
+}
+
+// updateConfigDriftMetric counts the drifted Machines by reason. configDriftMutex must be held.
+func updateConfigDriftMetric() {
+	counts := make(map[string]int)
+	for _, reasons := range configDrift {
+		for _, reason := range reasons {
+			counts[reason]++
+		}
+		counts[driftReasonAny]++
+	}
+	for _, reason := range driftReasons {
+		configDriftedNodes.WithLabelValues(reason).Set(float64(counts[reason]))
+	}
+}
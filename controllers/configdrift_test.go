@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+// TestClassifyDrift tests the classifyDrift function
+func TestClassifyDrift(t *testing.T) {
+	assert.Nil(t, classifyDrift(version.Get(), version.Get(), nil, false))
+	assert.Equal(t, []string{driftReasonVersion}, classifyDrift(version.Get()+"-previous", version.Get(), nil,
+		false))
+	assert.Equal(t, []string{driftReasonPayload, driftReasonServices, driftReasonHost},
+		classifyDrift(version.Get(), version.Get(), []string{"stopped kubelet", "file C:\\k\\kubelet.exe",
+			"firewall WMCO-kubelet", "file C:\\k\\kube-proxy.exe"}, false))
+	assert.Equal(t, []string{driftReasonPayload, driftReasonServices},
+		classifyDrift(version.Get(), version.Get(), []string{"stopped kubelet"}, true))
+}
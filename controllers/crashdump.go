@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// CrashDumpsAnnotation holds the comma separated crash dumps already reported for the node's VM
+const CrashDumpsAnnotation = "windowsmachineconfig.openshift.io/crash-dumps"
+
+// reportCrashDumps emits an event on the given Machine for each crash dump written on its VM since the dumps were last
+// reported, and records the reported dumps on the node with the given name
+func (r *WindowsMachineReconciler) reportCrashDumps(ctx context.Context, machine *mapi.Machine, nodeName string,
+	dumps []windows.CrashDump) error {
+	// The node is read again as it may have been updated earlier in the reconciliation
+	node, err := r.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error getting node %s", nodeName)
+	}
+	reported := make(map[string]bool)
+	for _, dump := range strings.Split(node.Annotations[CrashDumpsAnnotation], ",") {
+		reported[dump] = true
+	}
+	var current []string
+	for _, dump := range dumps {
+		current = append(current, dump.String())
+		if reported[dump.String()] {
+			continue
+		}
+		r.recorder.Eventf(machine, core.EventTypeWarning, "CrashDumpWritten",
+			"Machine %s crash dump %s written at %s, retrievable from node %s at crash-dumps/%s through the "+
+				"kubelet logs endpoint", machine.GetName(), dump.Name, dump.Time.UTC().Format(time.RFC3339),
+			node.GetName(), dump.Name)
+	}
+
+	annotation := strings.Join(current, ",")
+	if node.Annotations[CrashDumpsAnnotation] == annotation {
+		return nil
+	}
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[CrashDumpsAnnotation] = annotation
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return errors.Wrapf(err, "error annotating node %s", node.GetName())
+	}
+	return nil
+}
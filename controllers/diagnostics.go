@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DiagnosticsAnnotation is the annotation which, applied to a Windows Machine, triggers the collection of the
+	// diagnostics of its VM. It is removed once the diagnostics have been collected.
+	DiagnosticsAnnotation = "windowsmachineconfig.openshift.io/collect-diagnostics"
+	// DiagnosticsLabel is the label applied to the ConfigMaps holding the diagnostics of Windows Machines, whose value
+	// is the Machine name
+	DiagnosticsLabel = "windowsmachineconfig.openshift.io/diagnostics"
+	// diagnosticsCollectedAnnotation holds the time at which the diagnostics of a ConfigMap were collected
+	diagnosticsCollectedAnnotation = "windowsmachineconfig.openshift.io/diagnostics-collected"
+	// diagnosticsConfigMapPrefix is the prefix of the name of the ConfigMaps holding the diagnostics of Windows Machines
+	diagnosticsConfigMapPrefix = "windows-diagnostics-"
+	// diagnosticsMaxBytes is the size the diagnostics of a ConfigMap are truncated to, below the 1 MiB size limit of
+	// objects to leave room for the metadata and the encoding of the ConfigMap
+	diagnosticsMaxBytes = 900 * 1024
+	// diagnosticsTruncatedMarker starts the diagnostics whose beginning was dropped to fit the ConfigMap
+	diagnosticsTruncatedMarker = "[truncated]\n"
+)
+
+// reconcileDiagnostics collects the diagnostics of the VM backing the given Machine into a ConfigMap in the operator
+// namespace, if requested through the diagnostics annotation, and removes the annotation
+func (r *machineReconciliation) reconcileDiagnostics(ctx context.Context, machine *mapi.Machine) error {
+	if _, present := machine.GetAnnotations()[DiagnosticsAnnotation]; !present {
+		return nil
+	}
+	if r.config.DryRun {
+		log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+		reportDryRun(r.recorder, log, machine, "DiagnosticsCollection",
+			"Machine %s diagnostics would be collected", machine.GetName())
+	} else if err := r.collectDiagnostics(ctx, machine); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "DiagnosticsCollectionFailure",
+			"Machine %s diagnostics could not be collected", machine.GetName())
+		return err
+	}
+
+	delete(machine.Annotations, DiagnosticsAnnotation)
+	if err := r.client.Update(ctx, machine); err != nil {
+		return errors.Wrapf(err, "unable to remove %s annotation from machine %s", DiagnosticsAnnotation,
+			machine.GetName())
+	}
+	return nil
+}
+
+// collectDiagnostics connects to the VM backing the given Machine and stores its diagnostics in the Machine's
+// diagnostics ConfigMap, replacing the previously collected ones
+func (r *machineReconciliation) collectDiagnostics(ctx context.Context, machine *mapi.Machine) error {
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return err
+	}
+
+	cm := &core.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{
+			Name:        diagnosticsConfigMapPrefix + machine.GetName(),
+			Namespace:   r.watchNamespace,
+			Labels:      map[string]string{DiagnosticsLabel: machine.GetName()},
+			Annotations: map[string]string{diagnosticsCollectedAnnotation: time.Now().UTC().Format(time.RFC3339)},
+		},
+		Data: truncateDiagnostics(vm.CollectDiagnostics(), diagnosticsMaxBytes),
+	}
+	configMaps := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace)
+	if _, err := configMaps.Create(ctx, cm, meta.CreateOptions{}); err != nil {
+		if !k8sapierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "error creating %s ConfigMap", cm.GetName())
+		}
+		if _, err := configMaps.Update(ctx, cm, meta.UpdateOptions{}); err != nil {
+			return errors.Wrapf(err, "error updating %s ConfigMap", cm.GetName())
+		}
+	}
+	r.recorder.Eventf(machine, core.EventTypeNormal, "DiagnosticsCollected",
+		"Machine %s diagnostics collected in ConfigMap %s", machine.GetName(), cm.GetName())
+	return nil
+}
+
+// truncateDiagnostics returns the given diagnostics, keyed by file name, truncated to the given total size. The size is
+// shared between the diagnostics, the space left by the smaller ones going to the larger ones, and the end of each
+// truncated diagnostic is kept, as it holds the most recent logs.
+func truncateDiagnostics(diagnostics map[string]string, maxBytes int) map[string]string {
+	names := make([]string, 0, len(diagnostics))
+	for name := range diagnostics {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i])+len(diagnostics[names[i]]) < len(names[j])+len(diagnostics[names[j]])
+	})
+	truncated := make(map[string]string, len(diagnostics))
+	remaining := maxBytes
+	for i, name := range names {
+		// The file names are part of the size of the ConfigMap as well
+		budget := remaining/(len(names)-i) - len(name)
+		value := diagnostics[name]
+		if len(value) > budget {
+			start := len(value) - budget + len(diagnosticsTruncatedMarker)
+			if start > len(value) {
+				start = len(value)
+			}
+			// The values of a ConfigMap are UTF-8 strings, they are cut at the start of a character
+			for start < len(value) && !utf8.RuneStart(value[start]) {
+				start++
+			}
+			value = diagnosticsTruncatedMarker + value[start:]
+		}
+		truncated[name] = value
+		remaining -= len(name) + len(value)
+	}
+	return truncated
+}
+
+// removeDiagnostics deletes the diagnostics ConfigMap of the given Machine, if it exists
+func (r *WindowsMachineReconciler) removeDiagnostics(ctx context.Context, machineName string) error {
+	err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Delete(ctx, diagnosticsConfigMapPrefix+machineName,
+		meta.DeleteOptions{})
+	if err != nil && !k8sapierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "error deleting %s ConfigMap", diagnosticsConfigMapPrefix+machineName)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateDiagnostics(t *testing.T) {
+	diagnostics := map[string]string{"services.txt": "kubelet Running", "kubelet.log": strings.Repeat("a", 200) + "end",
+		"hns.txt": strings.Repeat("é", 100)}
+	truncated := truncateDiagnostics(diagnostics, 200)
+
+	size := 0
+	for name, value := range truncated {
+		size += len(name) + len(value)
+		assert.True(t, utf8.ValidString(value), name)
+	}
+	assert.LessOrEqual(t, size, 200)
+	// The small diagnostics are kept whole, the others are truncated from their beginning
+	assert.Equal(t, "kubelet Running", truncated["services.txt"])
+	assert.True(t, strings.HasPrefix(truncated["kubelet.log"], diagnosticsTruncatedMarker))
+	assert.True(t, strings.HasSuffix(truncated["kubelet.log"], "aend"))
+	assert.True(t, strings.HasPrefix(truncated["hns.txt"], diagnosticsTruncatedMarker+"é"))
+
+	assert.Equal(t, diagnostics, truncateDiagnostics(diagnostics, diagnosticsMaxBytes))
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+// mirrorPodAnnotation is the annotation present on mirror pods, which represent static pods on the API server
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// drainNode cordons the given node and evicts the pods running on it. Returns true once no pods remain to be evicted.
+func (r *WindowsMachineReconciler) drainNode(ctx context.Context, node *core.Node) (bool, error) {
+	if !node.Spec.Unschedulable {
+		node.Spec.Unschedulable = true
+		if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+			meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+			return false, errors.Wrapf(err, "error cordoning node %s", node.GetName())
+		}
+		r.log.Info("cordoned node", "node", node.GetName())
+	}
+	return r.evictPods(ctx, node)
+}
+
+// evictPods evicts the evictable pods running on the given node. Returns true once no pods remain to be evicted.
+func (r *WindowsMachineReconciler) evictPods(ctx context.Context, node *core.Node) (bool, error) {
+	pods, err := r.k8sclientset.CoreV1().Pods("").List(ctx, meta.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.GetName()).String()})
+	if err != nil {
+		return false, errors.Wrapf(err, "error listing pods on node %s", node.GetName())
+	}
+	drained := true
+	for _, pod := range pods.Items {
+		if !isEvictable(&pod) {
+			continue
+		}
+		drained = false
+		eviction := &policy.Eviction{ObjectMeta: meta.ObjectMeta{Name: pod.GetName(), Namespace: pod.GetNamespace()}}
+		err := r.k8sclientset.PolicyV1beta1().Evictions(pod.GetNamespace()).Evict(ctx, eviction)
+		if err != nil {
+			if k8sapierrors.IsNotFound(err) {
+				continue
+			}
+			if k8sapierrors.IsTooManyRequests(err) {
+				// The eviction is blocked by a PodDisruptionBudget, it will be retried
+				r.log.V(1).Info("pod eviction blocked", "pod", pod.GetNamespace()+"/"+pod.GetName())
+				continue
+			}
+			return false, errors.Wrapf(err, "error evicting pod %s/%s", pod.GetNamespace(), pod.GetName())
+		}
+	}
+	return drained, nil
+}
+
+// isEvictable returns true if the given pod must be evicted when draining its node. Pods managed by DaemonSets, mirror
+// pods and pods which are terminating or have completed are not evicted.
+func isEvictable(pod *core.Pod) bool {
+	if !pod.GetDeletionTimestamp().IsZero() {
+		return false
+	}
+	if pod.Status.Phase == core.PodSucceeded || pod.Status.Phase == core.PodFailed {
+		return false
+	}
+	if _, present := pod.GetAnnotations()[mirrorPodAnnotation]; present {
+		return false
+	}
+	for _, owner := range pod.GetOwnerReferences() {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// disruptionAnnotations are the annotations applied to the nodes being drained by WMCO for a disruptive operation.
+// The value of each annotation is "cordoned" if the node was cordoned by an administrator before the operation.
+var disruptionAnnotations = []string{KubeletReconfiguringAnnotation}
+
+// startNodeDisruption applies the given disruption annotation to the given node, during maintenance windows and once
+// fewer than maxUnhealthyCount other nodes are unavailable, returning the annotated node. A non-zero result is
+// returned when the operation, described by the given description, must wait.
+func (r *machineReconciliation) startNodeDisruption(ctx context.Context, machine *mapi.Machine, node *core.Node,
+	annotation, description string) (*core.Node, ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	delay, err := r.getMaintenanceWindowDelay(ctx, machine)
+	if err != nil {
+		return nil, ctrl.Result{}, errors.Wrap(err, "unable to determine maintenance window")
+	}
+	if delay > 0 {
+		log.Info(description+" pending until the next maintenance window", "delay", delay)
+		return nil, ctrl.Result{RequeueAfter: delay}, nil
+	}
+	return r.reserveNodeDisruption(ctx, machine, node, annotation, description)
+}
+
+// reserveNodeDisruption applies the given disruption annotation to the given node once fewer than maxUnhealthyCount
+// other nodes are unavailable, regardless of the maintenance windows, returning the annotated node. A non-zero result
+// is returned when the operation, described by the given description, must wait.
+func (r *machineReconciliation) reserveNodeDisruption(ctx context.Context, machine *mapi.Machine, node *core.Node,
+	annotation, description string) (*core.Node, ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	// Concurrent reconciliations must not start disrupting more than maxUnhealthyCount nodes
+	r.fleet.Lock()
+	defer r.fleet.Unlock()
+	unavailable, err := r.getUnavailableNodeCount(ctx, node.GetName())
+	if err != nil {
+		return nil, ctrl.Result{}, err
+	}
+	if unavailable >= int(r.config.MaxUnhealthyCount) {
+		log.Info(description+" waiting for other nodes to become available",
+			"maxUnhealthyCount", r.config.MaxUnhealthyCount)
+		return nil, ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+	if node.Spec.Unschedulable {
+		// The node was cordoned by an administrator, it must not be uncordoned once the operation completes
+		node.Annotations[annotation] = "cordoned"
+	} else {
+		node.Annotations[annotation] = ""
+	}
+	annotated, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager})
+	if err != nil {
+		return nil, ctrl.Result{}, errors.Wrapf(err, "error annotating node %s", node.GetName())
+	}
+	return annotated, ctrl.Result{}, nil
+}
+
+// finishNodeDisruption removes the given disruption annotation from the node with the given name, uncordoning the
+// node unless it was cordoned by an administrator before the operation
+func (r *WindowsMachineReconciler) finishNodeDisruption(ctx context.Context, nodeName, annotation string) error {
+	node, err := r.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "could not get node %s", nodeName)
+	}
+	if node.Annotations[annotation] != "cordoned" {
+		node.Spec.Unschedulable = false
+	}
+	delete(node.Annotations, annotation)
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return errors.Wrapf(err, "error uncordoning node %s", nodeName)
+	}
+	return nil
+}
+
+// getUnavailableNodeCount returns the number of configured Windows nodes, other than the given node, which are not
+// Ready or are being disrupted by WMCO
+func (r *WindowsMachineReconciler) getUnavailableNodeCount(ctx context.Context, nodeName string) (int, error) {
+	nodes, err := r.k8sclientset.CoreV1().Nodes().List(ctx, meta.ListOptions{LabelSelector: nodeconfig.WindowsOSLabel})
+	if err != nil {
+		return 0, errors.Wrap(err, "error listing Windows nodes")
+	}
+	unavailable := 0
+	for _, node := range nodes.Items {
+		if node.GetName() == nodeName {
+			continue
+		}
+		if _, present := node.Annotations[nodeconfig.VersionAnnotation]; !present {
+			continue
+		}
+		if isNodeDisrupted(&node) || !isNodeReady(&node) {
+			unavailable++
+		}
+	}
+	return unavailable, nil
+}
+
+// isNodeDisrupted returns true if the given node has any of the disruption annotations
+func isNodeDisrupted(node *core.Node) bool {
+	for _, annotation := range disruptionAnnotations {
+		if _, present := node.Annotations[annotation]; present {
+			return true
+		}
+	}
+	return false
+}
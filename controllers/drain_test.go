@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestIsEvictable tests the isEvictable function
+func TestIsEvictable(t *testing.T) {
+	now := meta.Now()
+	tests := []struct {
+		name string
+		pod  *core.Pod
+		want bool
+	}{
+		{
+			name: "running pod",
+			pod:  &core.Pod{Status: core.PodStatus{Phase: core.PodRunning}},
+			want: true,
+		},
+		{
+			name: "terminating pod",
+			pod:  &core.Pod{ObjectMeta: meta.ObjectMeta{DeletionTimestamp: &now}},
+			want: false,
+		},
+		{
+			name: "completed pod",
+			pod:  &core.Pod{Status: core.PodStatus{Phase: core.PodSucceeded}},
+			want: false,
+		},
+		{
+			name: "mirror pod",
+			pod:  &core.Pod{ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{mirrorPodAnnotation: ""}}},
+			want: false,
+		},
+		{
+			name: "DaemonSet pod",
+			pod: &core.Pod{ObjectMeta: meta.ObjectMeta{
+				OwnerReferences: []meta.OwnerReference{{Kind: "DaemonSet", Name: "exporter"}}}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isEvictable(tt.pod))
+		})
+	}
+}
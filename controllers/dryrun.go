@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// dryRunReasonPrefix prefixes the reasons of the events reporting the actions which would be taken outside of dry-run
+// mode
+const dryRunReasonPrefix = "DryRun"
+
+// reportDryRun reports, through an event on the given object and the given logger, an action which is not taken as
+// the operator is in dry-run mode
+func reportDryRun(recorder record.EventRecorder, log logr.Logger, object runtime.Object, reason, messageFmt string,
+	args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	log.Info("dry run: "+message, "action", reason)
+	recorder.Event(object, core.EventTypeNormal, dryRunReasonPrefix+reason, message)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// eventThrottleWindow is the time during which the repetitions of a warning event on an object are counted instead of
+// being recorded
+const eventThrottleWindow = 10 * time.Minute
+
+// eventKey identifies the repetitions of a warning event on an object
+type eventKey struct {
+	uid     kubeTypes.UID
+	reason  string
+	message string
+}
+
+// eventRepetitions tracks the repetitions of a warning event
+type eventRepetitions struct {
+	// count is the number of times the event was seen since first
+	count int
+	// first is the time at which the event was first seen
+	first time.Time
+	// last is the time at which the event was last seen
+	last time.Time
+	// recorded is the time at which the event was last recorded
+	recorded time.Time
+}
+
+// throttledRecorder is an event recorder aggregating the repetitions of identical warning events on an object, such as
+// the failures of the reconciliations of a Machine retried during an outage. The first occurrence of a warning event
+// is recorded, the repetitions within eventThrottleWindow of the last recording are only counted, and the next
+// repetition after the window is recorded with the number of times the event was seen and when it was first seen.
+// Warning events not seen for eventThrottleWindow are forgotten. Normal events are always recorded.
+type throttledRecorder struct {
+	record.EventRecorder
+	// now returns the current time
+	now func() time.Time
+	// lock protects seen, as the recorder is shared by concurrent reconciliations
+	lock sync.Mutex
+	seen map[eventKey]*eventRepetitions
+}
+
+// newThrottledRecorder returns a throttledRecorder recording the events through the given recorder
+func newThrottledRecorder(recorder record.EventRecorder) *throttledRecorder {
+	return &throttledRecorder{EventRecorder: recorder, now: time.Now, seen: make(map[eventKey]*eventRepetitions)}
+}
+
+// Event records the given event, unless it is the repetition of a warning event recorded within eventThrottleWindow
+func (r *throttledRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	if eventType == core.EventTypeWarning {
+		var throttled bool
+		if message, throttled = r.throttle(object, reason, message); throttled {
+			return
+		}
+	}
+	r.EventRecorder.Event(object, eventType, reason, message)
+}
+
+// Eventf records the given event with a formatted message, unless it is the repetition of a warning event recorded
+// within eventThrottleWindow
+func (r *throttledRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// throttle counts the given warning event, returning the message to record, or true if the event must not be recorded
+func (r *throttledRecorder) throttle(object runtime.Object, reason, message string) (string, bool) {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return message, false
+	}
+	key := eventKey{uid: accessor.GetUID(), reason: reason, message: message}
+	now := r.now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// Forget the events which stopped repeating, so that the map does not grow with the objects deleted
+	for k, repetitions := range r.seen {
+		if now.Sub(repetitions.last) >= eventThrottleWindow {
+			delete(r.seen, k)
+		}
+	}
+	repetitions, found := r.seen[key]
+	if !found {
+		r.seen[key] = &eventRepetitions{count: 1, first: now, last: now, recorded: now}
+		return message, false
+	}
+	repetitions.count++
+	repetitions.last = now
+	if now.Sub(repetitions.recorded) < eventThrottleWindow {
+		return "", true
+	}
+	repetitions.recorded = now
+	return fmt.Sprintf("%s (seen %d times since %s)", message, repetitions.count,
+		repetitions.first.UTC().Format(time.RFC3339)), false
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestThrottledRecorder(t *testing.T) {
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	machine := &core.Node{ObjectMeta: meta.ObjectMeta{UID: "machine"}}
+	other := &core.Node{ObjectMeta: meta.ObjectMeta{UID: "other"}}
+	tests := []struct {
+		name     string
+		object   *core.Node
+		elapsed  time.Duration
+		typ      string
+		message  string
+		expected string
+	}{
+		{
+			name:     "first warning recorded",
+			object:   machine,
+			typ:      core.EventTypeWarning,
+			message:  "failure",
+			expected: "Warning Failure failure",
+		},
+		{
+			name:    "repeated warning throttled",
+			object:  machine,
+			elapsed: time.Minute,
+			typ:     core.EventTypeWarning,
+			message: "failure",
+		},
+		{
+			name:     "warning with another message recorded",
+			object:   machine,
+			elapsed:  2 * time.Minute,
+			typ:      core.EventTypeWarning,
+			message:  "other failure",
+			expected: "Warning Failure other failure",
+		},
+		{
+			name:     "warning on another object recorded",
+			object:   other,
+			elapsed:  3 * time.Minute,
+			typ:      core.EventTypeWarning,
+			message:  "failure",
+			expected: "Warning Failure failure",
+		},
+		{
+			name:     "repeated normal event recorded",
+			object:   machine,
+			elapsed:  4 * time.Minute,
+			typ:      core.EventTypeNormal,
+			message:  "failure",
+			expected: "Normal Failure failure",
+		},
+		{
+			name:     "repeated warning recorded with count after window",
+			object:   machine,
+			elapsed:  10 * time.Minute,
+			typ:      core.EventTypeWarning,
+			message:  "failure",
+			expected: "Warning Failure failure (seen 3 times since 2021-06-01T10:00:00Z)",
+		},
+		{
+			name:    "repeated warning throttled again",
+			object:  machine,
+			elapsed: 15 * time.Minute,
+			typ:     core.EventTypeWarning,
+			message: "failure",
+		},
+		{
+			name:     "warning recorded again once forgotten",
+			object:   machine,
+			elapsed:  time.Hour,
+			typ:      core.EventTypeWarning,
+			message:  "failure",
+			expected: "Warning Failure failure",
+		},
+	}
+	fake := record.NewFakeRecorder(len(tests))
+	recorder := newThrottledRecorder(fake)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder.now = func() time.Time { return start.Add(test.elapsed) }
+			recorder.Eventf(test.object, test.typ, "Failure", "%s", test.message)
+			select {
+			case event := <-fake.Events:
+				assert.Equal(t, test.expected, event)
+			default:
+				assert.Empty(t, test.expected)
+			}
+		})
+	}
+}
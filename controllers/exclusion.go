@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+)
+
+// ExcludedAnnotation is applied by administrators to a Windows Machine, or to its node, to quarantine the node for
+// investigation: WMCO neither configures nor remediates it, and does not delete its Machine when it was configured by
+// another operator version. Its value, optional, is the reason for the exclusion.
+const ExcludedAnnotation = "windowsmachineconfig.openshift.io/excluded"
+
+// reconcileExclusion records in the Excluded condition of the WindowsNode of the given Machine whether the Machine, or
+// its node, is excluded from the management of WMCO, returning true if it is
+func (r *WindowsMachineReconciler) reconcileExclusion(ctx context.Context, machine *mapi.Machine) (bool, error) {
+	node, err := r.getMachineNode(ctx, machine)
+	if err != nil {
+		return false, err
+	}
+	reason, excluded := exclusionReason(machine, node)
+	condition := meta.Condition{Type: wmcoapi.ExcludedCondition, Status: meta.ConditionFalse, Reason: "Managed",
+		Message: "The instance is managed by WMCO"}
+	if excluded {
+		condition.Status = meta.ConditionTrue
+		condition.Reason = "ExcludedByAnnotation"
+		condition.Message = "The instance is excluded from the management of WMCO: " + reason
+	}
+	if err := r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		apimeta.SetStatusCondition(&status.Conditions, condition)
+	}); err != nil {
+		return false, err
+	}
+	return excluded, nil
+}
+
+// getMachineNode returns the node of the given Machine, nil if it is not registered
+func (r *WindowsMachineReconciler) getMachineNode(ctx context.Context, machine *mapi.Machine) (*core.Node, error) {
+	if machine.Status.NodeRef == nil {
+		return nil, nil
+	}
+	node := &core.Node{}
+	if err := r.client.Get(ctx, kubeTypes.NamespacedName{Name: machine.Status.NodeRef.Name}, node); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "could not get node associated with machine %s", machine.GetName())
+	}
+	return node, nil
+}
+
+// exclusionReason returns the reason the given Machine, or its node which is nil if it is not registered, is excluded
+// from the management of WMCO, and whether it is excluded
+func exclusionReason(machine *mapi.Machine, node *core.Node) (string, bool) {
+	if reason, present := machine.GetAnnotations()[ExcludedAnnotation]; present {
+		if reason == "" {
+			reason = fmt.Sprintf("Machine %s has the %s annotation", machine.GetName(), ExcludedAnnotation)
+		}
+		return reason, true
+	}
+	if node == nil {
+		return "", false
+	}
+	if reason, present := node.GetAnnotations()[ExcludedAnnotation]; present {
+		if reason == "" {
+			reason = fmt.Sprintf("node %s has the %s annotation", node.GetName(), ExcludedAnnotation)
+		}
+		return reason, true
+	}
+	return "", false
+}
+
+// isExcluded returns true if the given Machine, or its node which is nil if it is not registered, is excluded from the
+// management of WMCO
+func isExcluded(machine *mapi.Machine, node *core.Node) bool {
+	_, excluded := exclusionReason(machine, node)
+	return excluded
+}
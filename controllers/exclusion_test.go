@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExclusionReason(t *testing.T) {
+	tests := []struct {
+		name               string
+		machineAnnotations map[string]string
+		node               *core.Node
+		wantReason         string
+		wantExcluded       bool
+	}{
+		{
+			name: "not excluded",
+			node: &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node"}},
+		},
+		{
+			name: "not registered",
+		},
+		{
+			name:               "machine excluded with reason",
+			machineAnnotations: map[string]string{ExcludedAnnotation: "investigating crash"},
+			wantReason:         "investigating crash",
+			wantExcluded:       true,
+		},
+		{
+			name:               "machine excluded without reason",
+			machineAnnotations: map[string]string{ExcludedAnnotation: ""},
+			wantReason:         "Machine machine has the " + ExcludedAnnotation + " annotation",
+			wantExcluded:       true,
+		},
+		{
+			name: "node excluded without reason",
+			node: &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node",
+				Annotations: map[string]string{ExcludedAnnotation: ""}}},
+			wantReason:   "node node has the " + ExcludedAnnotation + " annotation",
+			wantExcluded: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			machine := &mapi.Machine{ObjectMeta: meta.ObjectMeta{Name: "machine",
+				Annotations: test.machineAnnotations}}
+			reason, excluded := exclusionReason(machine, test.node)
+			assert.Equal(t, test.wantReason, reason)
+			assert.Equal(t, test.wantExcluded, excluded)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+const (
+	// fleetStatusPeriod is the interval at which the status of the WindowsFleet is published
+	fleetStatusPeriod = time.Minute
+	// maxBlockingErrors is the number of most frequent errors reported in the status of the WindowsFleet
+	maxBlockingErrors = 5
+)
+
+// States of a Windows Machine, as counted in the status of the WindowsFleet
+const (
+	// fleetStateReady is the state of the Machines whose configured node is ready
+	fleetStateReady = "ready"
+	// fleetStateConfiguring is the state of the Machines being provisioned, configured or deleted
+	fleetStateConfiguring = "configuring"
+	// fleetStateNotReady is the state of the Machines whose configured node is not ready
+	fleetStateNotReady = "notReady"
+	// fleetStateFailed is the state of the Machines which failed to provision or whose last configuration failed
+	fleetStateFailed = "failed"
+)
+
+// ReportFleetStatus periodically publishes the health of the Windows Machines in the status of the WindowsFleet. It
+// returns once the given context is done.
+func (r *WindowsMachineReconciler) ReportFleetStatus(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.reportFleetStatus(ctx); err != nil {
+			r.log.Error(err, "unable to report the Windows fleet status")
+		}
+	}, fleetStatusPeriod)
+	return nil
+}
+
+// reportFleetStatus summarizes the state of the managed Windows Machines and publishes it
+func (r *WindowsMachineReconciler) reportFleetStatus(ctx context.Context) error {
+	config, err := operatorconfig.Load(ctx, r.k8sclientset, r.watchNamespace, r.defaultConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to load operator configuration")
+	}
+	managed, err := r.getManagedMachines(ctx, config)
+	if err != nil {
+		return err
+	}
+	// The WindowsNodes are read from the API server, as they are not watched
+	windowsNodes := &wmcoapi.WindowsNodeList{}
+	if err := r.apiReader.List(ctx, windowsNodes, client.InNamespace(r.watchNamespace)); err != nil {
+		return errors.Wrap(err, "error listing WindowsNodes")
+	}
+	lastErrors := make(map[string]string, len(windowsNodes.Items))
+	for _, windowsNode := range windowsNodes.Items {
+		lastErrors[windowsNode.GetName()] = windowsNode.Status.LastError
+	}
+	return r.publishFleetStatus(ctx, fleetStatus(managed, lastErrors))
+}
+
+// fleetStatus returns the status of the WindowsFleet summarizing the given managed Machines, given the errors of
+// their last failed configuration, keyed by Machine name
+func fleetStatus(managed []managedMachine, lastErrors map[string]string) wmcoapi.WindowsFleetStatus {
+	status := wmcoapi.WindowsFleetStatus{Version: version.Get(), Total: len(managed)}
+	blocked := make(map[string][]string)
+	for _, m := range managed {
+		state, blockingErr := machineFleetState(m.machine, m.node, lastErrors[m.machine.GetName()])
+		switch state {
+		case fleetStateReady:
+			status.Ready++
+		case fleetStateConfiguring:
+			status.Configuring++
+		case fleetStateNotReady:
+			status.NotReady++
+		case fleetStateFailed:
+			status.Failed++
+			blocked[blockingErr] = append(blocked[blockingErr], m.machine.GetName())
+		}
+		if m.node == nil {
+			continue
+		}
+		if nodeVersion, present := m.node.Annotations[nodeconfig.VersionAnnotation]; present &&
+			nodeVersion == status.Version {
+			status.Current++
+		}
+	}
+	status.BlockingErrors = topBlockingErrors(blocked)
+	return status
+}
+
+// machineFleetState returns the state of the given Windows Machine, with the given node and error of its last failed
+// configuration, along with the error blocking it when it failed
+func machineFleetState(machine *mapi.Machine, node *core.Node, lastError string) (string, string) {
+	switch {
+	case machine.Status.Phase != nil && *machine.Status.Phase == "Failed":
+		if machine.Status.ErrorMessage != nil && *machine.Status.ErrorMessage != "" {
+			return fleetStateFailed, *machine.Status.ErrorMessage
+		}
+		return fleetStateFailed, "Machine failed to provision"
+	case !machine.GetDeletionTimestamp().IsZero():
+		return fleetStateConfiguring, ""
+	case lastError != "":
+		return fleetStateFailed, lastError
+	case node == nil:
+		return fleetStateConfiguring, ""
+	}
+	if _, present := node.Annotations[nodeconfig.VersionAnnotation]; !present {
+		return fleetStateConfiguring, ""
+	}
+	if isNodeReady(node) {
+		return fleetStateReady, ""
+	}
+	return fleetStateNotReady, ""
+}
+
+// topBlockingErrors returns the most frequent of the given errors, given with the names of the Machines they block,
+// most frequent first
+func topBlockingErrors(blocked map[string][]string) []wmcoapi.BlockingError {
+	var blockingErrors []wmcoapi.BlockingError
+	for message, machines := range blocked {
+		sort.Strings(machines)
+		blockingErrors = append(blockingErrors, wmcoapi.BlockingError{Message: message, Count: len(machines),
+			Machines: machines})
+	}
+	sort.Slice(blockingErrors, func(i, j int) bool {
+		if blockingErrors[i].Count != blockingErrors[j].Count {
+			return blockingErrors[i].Count > blockingErrors[j].Count
+		}
+		return blockingErrors[i].Message < blockingErrors[j].Message
+	})
+	if len(blockingErrors) > maxBlockingErrors {
+		blockingErrors = blockingErrors[:maxBlockingErrors]
+	}
+	return blockingErrors
+}
+
+// publishFleetStatus writes the given status to the WindowsFleet, creating it if needed. The status is only updated
+// when it changes, recording the time of the change.
+func (r *WindowsMachineReconciler) publishFleetStatus(ctx context.Context, status wmcoapi.WindowsFleetStatus) error {
+	fleet := &wmcoapi.WindowsFleet{}
+	// The WindowsFleet is read from the API server, as it is not watched
+	err := r.apiReader.Get(ctx, kubeTypes.NamespacedName{Name: wmcoapi.WindowsFleetName}, fleet)
+	if err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error getting WindowsFleet %s", wmcoapi.WindowsFleetName)
+		}
+		fleet = &wmcoapi.WindowsFleet{ObjectMeta: meta.ObjectMeta{Name: wmcoapi.WindowsFleetName}}
+		if err := r.client.Create(ctx, fleet); err != nil {
+			return errors.Wrapf(err, "error creating WindowsFleet %s", wmcoapi.WindowsFleetName)
+		}
+	}
+	status.LastChangeTime = fleet.Status.LastChangeTime
+	if equality.Semantic.DeepEqual(status, fleet.Status) {
+		return nil
+	}
+	now := meta.Now()
+	status.LastChangeTime = &now
+	fleet.Status = status
+	if err := r.client.Status().Update(ctx, fleet); err != nil {
+		return errors.Wrapf(err, "error updating status of WindowsFleet %s", wmcoapi.WindowsFleetName)
+	}
+	r.log.Info("Windows fleet status", "version", status.Version, "total", status.Total, "ready", status.Ready,
+		"configuring", status.Configuring, "notReady", status.NotReady, "failed", status.Failed,
+		"current", status.Current)
+	return nil
+}
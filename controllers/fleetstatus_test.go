@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+func TestFleetStatus(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	machine := func(name, phase string) *mapi.Machine {
+		return &mapi.Machine{ObjectMeta: meta.ObjectMeta{Name: name}, Status: mapi.MachineStatus{Phase: ptr(phase)}}
+	}
+	node := func(version string, ready bool) *core.Node {
+		status := core.ConditionFalse
+		if ready {
+			status = core.ConditionTrue
+		}
+		return &core.Node{
+			ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{nodeconfig.VersionAnnotation: version}},
+			Status:     core.NodeStatus{Conditions: []core.NodeCondition{{Type: core.NodeReady, Status: status}}},
+		}
+	}
+	failed := machine("failed", "Failed")
+	failed.Status.ErrorMessage = ptr("instance type unavailable")
+	managed := []managedMachine{
+		{machine: machine("ready", "Running"), node: node(version.Get(), true)},
+		{machine: machine("outdated", "Running"), node: node("old", true)},
+		{machine: machine("not-ready", "Running"), node: node(version.Get(), false)},
+		{machine: machine("provisioning", "Provisioning")},
+		{machine: machine("configuring", "Running"), node: &core.Node{}},
+		{machine: machine("unreachable-1", "Running"), node: &core.Node{}},
+		{machine: machine("unreachable-2", "Running")},
+		{machine: failed},
+	}
+	lastErrors := map[string]string{"unreachable-1": "unable to connect", "unreachable-2": "unable to connect",
+		"ready": ""}
+
+	status := fleetStatus(managed, lastErrors)
+	assert.Equal(t, wmcoapi.WindowsFleetStatus{
+		Version:     version.Get(),
+		Total:       8,
+		Ready:       2,
+		Configuring: 2,
+		NotReady:    1,
+		Failed:      3,
+		Current:     2,
+		BlockingErrors: []wmcoapi.BlockingError{
+			{Message: "unable to connect", Count: 2, Machines: []string{"unreachable-1", "unreachable-2"}},
+			{Message: "instance type unavailable", Count: 1, Machines: []string{"failed"}},
+		},
+	}, status)
+}
+
+func TestTopBlockingErrors(t *testing.T) {
+	blocked := map[string][]string{
+		"a": {"m1"}, "b": {"m2", "m3"}, "c": {"m4"}, "d": {"m5"}, "e": {"m6"}, "f": {"m7"},
+	}
+	blockingErrors := topBlockingErrors(blocked)
+	assert.Len(t, blockingErrors, maxBlockingErrors)
+	assert.Equal(t, "b", blockingErrors[0].Message)
+	assert.Equal(t, "a", blockingErrors[1].Message)
+	assert.Equal(t, "e", blockingErrors[4].Message)
+	assert.Empty(t, topBlockingErrors(nil))
+}
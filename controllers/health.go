@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
+)
+
+// cacheSyncTimeout is the time the cache sync readiness check waits for the caches to be synced
+const cacheSyncTimeout = time.Second
+
+// PrivateKeyCheck returns a readiness check which fails if the private key secret is missing from the given namespace
+func PrivateKeyCheck(c client.Client, namespace string) healthz.Checker {
+	return func(_ *http.Request) error {
+		_, err := secrets.GetPrivateKey(kubeTypes.NamespacedName{Namespace: namespace,
+			Name: secrets.PrivateKeySecret}, c)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get secret %s", secrets.PrivateKeySecret)
+		}
+		return nil
+	}
+}
+
+// CacheSyncCheck returns a readiness check which fails until the given caches are started and synced
+func CacheSyncCheck(caches ...cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), cacheSyncTimeout)
+		defer cancel()
+		for _, c := range caches {
+			if !c.WaitForCacheSync(ctx) {
+				return errors.New("caches not synced")
+			}
+		}
+		return nil
+	}
+}
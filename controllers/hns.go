@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// hnsCheckPeriod is the interval at which the HNS networks of configured Windows nodes are checked. It is shorter
+	// than the host settings resync period as a node with broken pod networking still reports Ready.
+	hnsCheckPeriod = 10 * time.Minute
+	// hnsRepairBackoff is the delay after a failed repair of the HNS networks of a node before it is attempted again
+	hnsRepairBackoff = time.Hour
+	// HNSCheckedAnnotation holds the time at which the HNS networks of the node were last checked
+	HNSCheckedAnnotation = "windowsmachineconfig.openshift.io/hns-checked"
+	// NetworkingUnhealthyAnnotation is applied to a node cordoned and drained by WMCO because its HNS networks are
+	// broken. The node is uncordoned once its networking is healthy again.
+	NetworkingUnhealthyAnnotation = "windowsmachineconfig.openshift.io/networking-unhealthy"
+	// HNSRepairFailedAnnotation holds the time at which the last repair of the HNS networks of the node failed
+	HNSRepairFailedAnnotation = "windowsmachineconfig.openshift.io/hns-repair-failed"
+)
+
+func init() {
+	disruptionAnnotations = append(disruptionAnnotations, NetworkingUnhealthyAnnotation)
+}
+
+// reconcileHNSNetworks checks the HNS networks backing the pod networking of the node of the given Machine once every
+// hnsCheckPeriod, and repairs them if they are missing or corrupted and the repair is enabled. The node is cordoned and
+// drained for the repair, as any other disruption, within the maxUnhealthyCount budget, so that its pods are
+// recreated with working networking. A failed repair is not attempted again before hnsRepairBackoff has elapsed.
+func (r *machineReconciliation) reconcileHNSNetworks(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	if r.config.DryRun {
+		action := "reported"
+		if r.config.HNSNetworkRepair {
+			action = "the node drained and the networks recreated along with kube-proxy"
+		}
+		reportDryRun(r.recorder, log, machine, "HNSNetworkRepair",
+			"Machine %s node %s HNS networks would be checked and, if broken, %s", machine.GetName(),
+			node.GetName(), action)
+		return ctrl.Result{}, nil
+	}
+	if failed, err := time.Parse(time.RFC3339, node.Annotations[HNSRepairFailedAnnotation]); err == nil &&
+		time.Since(failed) < hnsRepairBackoff {
+		return ctrl.Result{RequeueAfter: hnsRepairBackoff - time.Since(failed)}, nil
+	}
+	// A repair in progress is driven to completion without waiting for the next check
+	_, repairing := node.Annotations[NetworkingUnhealthyAnnotation]
+	if remaining := checkRemaining(node, HNSCheckedAnnotation, hnsCheckPeriod, time.Now()); remaining > 0 &&
+		!repairing {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	problems, err := vm.CheckHNSNetworks()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to check HNS networks of node %s", node.GetName())
+	}
+	if len(problems) == 0 {
+		if err := r.uncordonHealthyNode(ctx, machine, node); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.setHNSRepairFailed(ctx, node.GetName(), false); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.reportHNSNetworks(ctx, machine, node, core.ConditionFalse, "Healthy",
+			"The HNS networks are healthy"); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.recordHNSCheck(ctx, node.GetName(), hnsCheckPeriod)
+	}
+	log.Info("pod networking broken", "node", node.GetName(), "problems", problems)
+	r.recorder.Eventf(machine, core.EventTypeWarning, "HNSNetworkCorrupted",
+		"Machine %s node %s pod networking is broken: %s", machine.GetName(), node.GetName(),
+		strings.Join(problems, "; "))
+	if !r.config.HNSNetworkRepair {
+		// A repair started before it was disabled is abandoned, releasing the node
+		if repairing {
+			if err := r.finishNodeDisruption(ctx, node.GetName(), NetworkingUnhealthyAnnotation); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		if err := r.reportHNSNetworks(ctx, machine, node, core.ConditionTrue, "Broken",
+			"The HNS networks are broken: "+strings.Join(problems, "; ")); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.recordHNSCheck(ctx, node.GetName(), hnsCheckPeriod)
+	}
+	if !repairing {
+		var result ctrl.Result
+		if node, result, err = r.startNodeDisruption(ctx, machine, node.DeepCopy(), NetworkingUnhealthyAnnotation,
+			"HNS network repair"); err != nil || !result.IsZero() {
+			return result, err
+		}
+		r.recorder.Eventf(machine, core.EventTypeWarning, "NetworkingUnhealthyCordon",
+			"Machine %s node %s is being drained to repair its HNS networks", machine.GetName(), node.GetName())
+	}
+	drained, err := r.drainNode(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to drain node %s", node.GetName())
+	}
+	if !drained {
+		log.Info("waiting for node to be drained", "node", node.GetName())
+		return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+
+	repair := func() error {
+		defer windows.CancelOnDone(ctx, vm)()
+		return vm.RepairHNSNetworks(node.GetName(), node.Annotations[nodeconfig.HybridOverlaySubnet])
+	}
+	if err := repair(); err != nil {
+		log.Error(err, "unable to repair HNS networks", "node", node.GetName(), "backoff", hnsRepairBackoff)
+		r.recorder.Eventf(machine, core.EventTypeWarning, "HNSNetworkRepairFailure",
+			"Machine %s node %s HNS networks could not be repaired, retrying in %s: %v", machine.GetName(),
+			node.GetName(), hnsRepairBackoff, err)
+		if err := r.setHNSRepairFailed(ctx, node.GetName(), true); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.reportHNSNetworks(ctx, machine, node, core.ConditionTrue, "RepairFailed",
+			"The HNS networks are broken and could not be repaired: "+strings.Join(problems, "; ")); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.recordHNSCheck(ctx, node.GetName(), hnsRepairBackoff)
+	}
+	log.Info("pod networking repaired", "node", node.GetName())
+	if err := r.uncordonHealthyNode(ctx, machine, node); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.setHNSRepairFailed(ctx, node.GetName(), false); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.recorder.Eventf(machine, core.EventTypeNormal, "HNSNetworkRepaired",
+		"Machine %s node %s HNS networks and kube-proxy recreated", machine.GetName(), node.GetName())
+	if err := r.reportHNSNetworks(ctx, machine, node, core.ConditionFalse, "Repaired",
+		"The HNS networks were broken and have been repaired: "+strings.Join(problems, "; ")); err != nil {
+		return ctrl.Result{}, err
+	}
+	return r.recordHNSCheck(ctx, node.GetName(), hnsCheckPeriod)
+}
+
+// recordHNSCheck records the current time as the last check of the HNS networks of the node with the given name,
+// requeuing the Machine once the given delay has elapsed
+func (r *machineReconciliation) recordHNSCheck(ctx context.Context, nodeName string,
+	requeue time.Duration) (ctrl.Result, error) {
+	if err := r.recordCheck(ctx, nodeName, HNSCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// reportHNSNetworks records the state of the HNS networks of the given node in its WindowsHNSNetworksDegraded
+// condition, if problem detection is enabled
+func (r *machineReconciliation) reportHNSNetworks(ctx context.Context, machine *mapi.Machine, node *core.Node,
+	status core.ConditionStatus, reason, message string) error {
+	if !r.config.ProblemDetection {
+		return nil
+	}
+	return r.setNodeConditions(ctx, machine, node.GetName(), core.NodeCondition{Type: WindowsHNSNetworksDegraded,
+		Status: status, Reason: reason, Message: message})
+}
+
+// setHNSRepairFailed records the current time in the HNSRepairFailedAnnotation of the node with the given name if
+// failed is true, and removes the annotation otherwise
+func (r *WindowsMachineReconciler) setHNSRepairFailed(ctx context.Context, nodeName string, failed bool) error {
+	node, err := r.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "could not get node %s", nodeName)
+	}
+	if _, present := node.Annotations[HNSRepairFailedAnnotation]; !present && !failed {
+		return nil
+	}
+	if failed {
+		node.Annotations[HNSRepairFailedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	} else {
+		delete(node.Annotations, HNSRepairFailedAnnotation)
+	}
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return errors.Wrapf(err, "error annotating node %s", nodeName)
+	}
+	return nil
+}
+
+// uncordonHealthyNode uncordons the given node if it was cordoned for the repair of its HNS networks, now that its
+// networking is healthy, unless it was cordoned by an administrator beforehand
+func (r *WindowsMachineReconciler) uncordonHealthyNode(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) error {
+	if _, present := node.Annotations[NetworkingUnhealthyAnnotation]; !present {
+		return nil
+	}
+	if err := r.finishNodeDisruption(ctx, node.GetName(), NetworkingUnhealthyAnnotation); err != nil {
+		return err
+	}
+	r.log.Info("uncordoned node with healthy networking", "node", node.GetName())
+	r.recorder.Eventf(machine, core.EventTypeNormal, "NetworkingHealthyUncordon",
+		"Machine %s node %s networking is healthy again", machine.GetName(), node.GetName())
+	return nil
+}
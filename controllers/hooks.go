@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// configurationHooks returns the hook scripts held by the ConfigMap named by the configurationHooks setting, run on
+// the Windows VMs before and after their configuration, along with their expected checksums. The configuration of the
+// VMs fails while the ConfigMap is missing or invalid, so that no VM is configured without the site-specific
+// customization.
+func (r *machineReconciliation) configurationHooks(ctx context.Context) (windows.ConfigurationHooks, error) {
+	if r.config.ConfigurationHooks == "" {
+		return windows.ConfigurationHooks{}, nil
+	}
+	cm, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Get(ctx, r.config.ConfigurationHooks,
+		meta.GetOptions{})
+	if err != nil {
+		return windows.ConfigurationHooks{}, errors.Wrapf(err, "unable to get configuration hooks ConfigMap %s",
+			r.config.ConfigurationHooks)
+	}
+	hooks := windows.ConfigurationHooks{
+		PreConfigure:        cm.Data[windows.PreConfigureHook],
+		PreConfigureSHA256:  hookChecksum(cm.Data, windows.PreConfigureHook),
+		PostConfigure:       cm.Data[windows.PostConfigureHook],
+		PostConfigureSHA256: hookChecksum(cm.Data, windows.PostConfigureHook),
+	}
+	if err := validateHook(windows.PreConfigureHook, hooks.PreConfigure, hooks.PreConfigureSHA256); err != nil {
+		return windows.ConfigurationHooks{}, errors.Wrapf(err, "invalid configuration hooks ConfigMap %s",
+			r.config.ConfigurationHooks)
+	}
+	if err := validateHook(windows.PostConfigureHook, hooks.PostConfigure, hooks.PostConfigureSHA256); err != nil {
+		return windows.ConfigurationHooks{}, errors.Wrapf(err, "invalid configuration hooks ConfigMap %s",
+			r.config.ConfigurationHooks)
+	}
+	return hooks, nil
+}
+
+// hookChecksum returns the expected checksum of the hook script with the given name, held by the given ConfigMap data
+func hookChecksum(data map[string]string, name string) string {
+	return strings.ToLower(strings.TrimSpace(data[name+windows.HookChecksumSuffix]))
+}
+
+// validateHook returns an error if the given hook script is not empty and its checksum is not the given expected one
+func validateHook(name, script, checksum string) error {
+	if script == "" {
+		return nil
+	}
+	if checksum == "" {
+		return errors.Errorf("hook %s has no %s checksum", name, name+windows.HookChecksumSuffix)
+	}
+	if actual := windows.HookChecksum(script); actual != checksum {
+		return errors.Errorf("hook %s has checksum %s instead of the expected %s", name, actual, checksum)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+func TestValidateHook(t *testing.T) {
+	script := "Write-Output configured"
+	assert.NoError(t, validateHook(windows.PreConfigureHook, "", ""))
+	assert.NoError(t, validateHook(windows.PreConfigureHook, script, windows.HookChecksum(script)))
+	assert.Error(t, validateHook(windows.PreConfigureHook, script, ""))
+	assert.Error(t, validateHook(windows.PreConfigureHook, script, windows.HookChecksum("Write-Output other")))
+}
+
+func TestHookChecksum(t *testing.T) {
+	data := map[string]string{windows.PostConfigureHook + windows.HookChecksumSuffix: " ABCDEF\n"}
+	assert.Equal(t, "abcdef", hookChecksum(data, windows.PostConfigureHook))
+	assert.Equal(t, "", hookChecksum(data, windows.PreConfigureHook))
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// HostSettingsCheckedAnnotation holds the time at which the managed host settings of the node were last corrected
+	HostSettingsCheckedAnnotation = "windowsmachineconfig.openshift.io/host-settings-checked"
+
+	// hostResyncPeriod is the interval at which the managed host settings of configured Windows nodes, such as the
+	// firewall rules, hardening, crash dump, event log export, overlay MTU, DNS, time synchronization and shutdown hook
+	// settings, are checked for drift, and new crash dumps are reported
+	hostResyncPeriod = time.Hour
+)
+
+// reconcileHostSettings corrects the managed host settings of the VM backing the given configured Machine and reports
+// new crash dumps, once every hostResyncPeriod, so that drift keeps being corrected
+func (r *machineReconciliation) reconcileHostSettings(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.managesHostSettings() {
+		return ctrl.Result{}, nil
+	}
+	if r.config.DryRun {
+		log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+		reportDryRun(r.recorder, log, machine, "HostSettingsCorrection",
+			"Machine %s host settings, such as firewall rules, hardening, DNS and time synchronization, would be "+
+				"checked and corrected", machine.GetName())
+		return ctrl.Result{}, nil
+	}
+	if remaining := checkRemaining(node, HostSettingsCheckedAnnotation, hostResyncPeriod, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := vm.EnsureHostSettings(); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "HostSettingsFailure",
+			"Machine %s host settings, such as firewall rules, hardening, DNS or time synchronization, could not be "+
+				"corrected", machine.GetName())
+		return ctrl.Result{}, err
+	}
+	if r.config.CrashDumps {
+		dumps, err := vm.CrashDumps()
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.reportCrashDumps(ctx, machine, node.GetName(), dumps); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.recordCheck(ctx, node.GetName(), HostSettingsCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: hostResyncPeriod}, nil
+}
+
+// managesHostSettings returns true if any of the host settings corrected by reconcileHostSettings is managed
+func (r *machineReconciliation) managesHostSettings() bool {
+	return r.config.ManageFirewallRules || r.config.HardenNodes || r.config.CrashDumps || r.config.EventLogs ||
+		r.hostSettings().OverlayMTU > 0 || len(r.config.DNSServers) > 0 || len(r.config.DNSSuffixSearchList) > 0 ||
+		len(r.config.NTPServers) > 0 || r.config.ShutdownGracePeriod > 0
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	oconfig "github.com/openshift/api/config/v1"
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	core "k8s.io/api/core/v1"
+)
+
+var (
+	// instanceTypeRegex splits an AWS instance type into its class, generation, attributes and size
+	instanceTypeRegex = regexp.MustCompile(`^([a-z]+)(\d+)([a-z-]*)\.([a-z0-9]+)$`)
+	// nonENAFamilies are the AWS instance families without Elastic Network Adapter support, which the Windows AMIs
+	// rely on for networking
+	nonENAFamilies = map[string]bool{"t1": true, "t2": true, "m1": true, "m2": true, "m3": true, "m4": true,
+		"c1": true, "c3": true, "c4": true, "r3": true, "i2": true, "d2": true, "g2": true, "hs1": true, "cr1": true,
+		"cc2": true}
+	// undersizedSizes are the AWS instance sizes with fewer than 2 vCPUs or less than 8 GiB of memory in every family
+	undersizedSizes = map[string]bool{"nano": true, "micro": true, "small": true, "medium": true}
+)
+
+// awsProviderSpec holds the fields of the provider spec of AWS Machines describing their instances
+type awsProviderSpec struct {
+	// InstanceType is the type of the instance
+	InstanceType string `json:"instanceType,omitempty"`
+}
+
+// instanceTypeProblems returns the reasons the given AWS instance type is unsupported or undersized for a Windows
+// node: it is an Arm instance type, which Windows Server does not run on, it does not support the Elastic Network
+// Adapter, or it has fewer than 2 vCPUs or less than 8 GiB of memory. Unknown instance types are not reported.
+func instanceTypeProblems(instanceType string) []string {
+	match := instanceTypeRegex.FindStringSubmatch(instanceType)
+	if match == nil {
+		return nil
+	}
+	class, family, attributes, size := match[1], match[1]+match[2], match[3], match[4]
+	var problems []string
+	if family == "a1" || strings.Contains(attributes, "g") {
+		problems = append(problems, "it is an Arm instance type, which Windows Server does not run on")
+	}
+	if nonENAFamilies[family] {
+		problems = append(problems, "it does not support the Elastic Network Adapter")
+	}
+	// Compute optimized large instances have 2 vCPUs and 4 GiB of memory
+	if undersizedSizes[size] || (class == "c" && size == "large") {
+		problems = append(problems, "it has fewer than 2 vCPUs or less than 8 GiB of memory")
+	}
+	return problems
+}
+
+// checkInstanceType reports through a warning event on the given AWS Machine if its instance type is unsupported or
+// undersized for a Windows node. The Machine is configured regardless.
+func (r *WindowsMachineReconciler) checkInstanceType(machine *mapi.Machine) {
+	if r.platform != oconfig.AWSPlatformType || machine.Spec.ProviderSpec.Value == nil {
+		return
+	}
+	spec := &awsProviderSpec{}
+	if err := json.Unmarshal(machine.Spec.ProviderSpec.Value.Raw, spec); err != nil {
+		return
+	}
+	problems := instanceTypeProblems(spec.InstanceType)
+	if len(problems) == 0 {
+		return
+	}
+	message := fmt.Sprintf("instance type %s is not recommended for Windows nodes: %s", spec.InstanceType,
+		strings.Join(problems, "; "))
+	r.log.Info("machine instance type not recommended", "machine", machine.GetName(), "instanceType",
+		spec.InstanceType, "problems", problems)
+	r.recorder.Eventf(machine, core.EventTypeWarning, "InstanceTypeUnsupported", "Machine %s %s", machine.GetName(),
+		message)
+}
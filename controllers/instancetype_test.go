@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceTypeProblems(t *testing.T) {
+	tests := []struct {
+		instanceType string
+		expected     []string
+	}{
+		{instanceType: "m5a.large", expected: nil},
+		{instanceType: "g4dn.xlarge", expected: nil},
+		{instanceType: "c5.xlarge", expected: nil},
+		{instanceType: "unknown", expected: nil},
+		{
+			instanceType: "c5.large",
+			expected:     []string{"it has fewer than 2 vCPUs or less than 8 GiB of memory"},
+		},
+		{
+			instanceType: "m6g.xlarge",
+			expected:     []string{"it is an Arm instance type, which Windows Server does not run on"},
+		},
+		{
+			instanceType: "t2.micro",
+			expected: []string{"it does not support the Elastic Network Adapter",
+				"it has fewer than 2 vCPUs or less than 8 GiB of memory"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.instanceType, func(t *testing.T) {
+			assert.Equal(t, test.expected, instanceTypeProblems(test.instanceType))
+		})
+	}
+}
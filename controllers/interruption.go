@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oconfig "github.com/openshift/api/config/v1"
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
+)
+
+const (
+	// interruptibleInstanceLabel is applied by the Machine API to the Machines backed by spot or preemptible instances
+	interruptibleInstanceLabel = "machine.openshift.io/interruptible-instance"
+	// InterruptedAnnotation is applied to the nodes whose instance received an interruption notice. The value is the
+	// notice, giving the action and time of the interruption.
+	InterruptedAnnotation = "windowsmachineconfig.openshift.io/interrupted"
+	// interruptionCheckPeriod is the interval at which interruptible instances are checked for interruption notices,
+	// much shorter than the two minutes and thirty seconds notices of AWS and Azure
+	interruptionCheckPeriod = 15 * time.Second
+	// interruptionDrainRetryDelay is the time after which the eviction of the pods of an interrupted node is retried
+	interruptionDrainRetryDelay = 5 * time.Second
+)
+
+// MonitorInterruptions periodically checks the spot or preemptible instances backing the configured Windows nodes for
+// an interruption notice, on AWS and Azure, Azure Stack Hub having neither spot instances nor scheduled events. The
+// node of an instance to be interrupted is annotated with InterruptedAnnotation, which triggers the reconciliation of
+// its Machine draining it. The checks run apart from the reconciliation of the Machines, so that their short interval
+// does not requeue every other check. It returns once the given context is done, and immediately on other platforms.
+func (r *WindowsMachineReconciler) MonitorInterruptions(ctx context.Context) error {
+	if (r.platform != oconfig.AWSPlatformType && r.platform != oconfig.AzurePlatformType) || r.azureStackHub {
+		return nil
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.checkInterruptions(ctx); err != nil {
+			r.log.Error(err, "unable to check for instance interruption notices")
+		}
+	}, interruptionCheckPeriod)
+	return nil
+}
+
+// checkInterruptions checks each interruptible Windows Machine managed by WMCO, whose node is configured, for an
+// interruption notice
+func (r *WindowsMachineReconciler) checkInterruptions(ctx context.Context) error {
+	machines, err := listWindowsMachines(ctx, r.client, client.HasLabels{interruptibleInstanceLabel})
+	if err != nil {
+		return err
+	}
+	if len(machines) == 0 {
+		return nil
+	}
+	config, err := operatorconfig.Load(ctx, r.k8sclientset, r.watchNamespace, r.defaultConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to load operator configuration")
+	}
+	for i := range machines {
+		machine := &machines[i]
+		if machine.Status.NodeRef == nil || !machine.GetDeletionTimestamp().IsZero() ||
+			!config.ManagesMachine(machine.GetLabels()) {
+			continue
+		}
+		// Each Machine is checked with the signers of the private key secret it is configured with
+		reconciliation := &machineReconciliation{WindowsMachineReconciler: r, config: config}
+		if err := reconciliation.checkInterruption(ctx, machine); err != nil {
+			r.log.Error(err, "unable to check for an interruption notice",
+				"windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+		}
+	}
+	return nil
+}
+
+// checkInterruption checks the instance backing the given Machine for an interruption notice, annotating its node
+// with the notice once received
+func (r *machineReconciliation) checkInterruption(ctx context.Context, machine *mapi.Machine) error {
+	node := &core.Node{}
+	if err := r.client.Get(ctx, client.ObjectKey{Name: machine.Status.NodeRef.Name}, node); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "could not get node associated with machine %s", machine.GetName())
+	}
+	if _, configured := node.Annotations[nodeconfig.VersionAnnotation]; !configured {
+		return nil
+	}
+	if _, interrupted := node.Annotations[InterruptedAnnotation]; interrupted || isExcluded(machine, node) {
+		return nil
+	}
+	if err := r.loadSigners(secrets.PrivateKeySecret); err != nil {
+		return err
+	}
+	if err := r.usePrivateKeySecret(ctx, machine); err != nil {
+		return err
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		log.V(1).Info("unable to connect to check for an interruption notice", "error", err.Error())
+		return nil
+	}
+	notice, err := vm.InterruptionNotice()
+	if err != nil || notice == "" {
+		return err
+	}
+	log.Info("instance interruption notice received", "notice", notice)
+	r.recorder.Eventf(machine, core.EventTypeWarning, "InstanceInterrupted",
+		"Machine %s instance received an interruption notice: %s", machine.GetName(), notice)
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "InterruptionDrain",
+			"Machine %s node %s would be drained before its instance is interrupted", machine.GetName(),
+			node.GetName())
+		return nil
+	}
+	node.Annotations[InterruptedAnnotation] = notice
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return errors.Wrapf(err, "error annotating node %s", node.GetName())
+	}
+	return nil
+}
+
+// reconcileInterruption drains the node of the given Machine once its instance received an interruption notice, as
+// recorded by MonitorInterruptions, so that its pods are rescheduled before the instance disappears. Returns true if
+// the instance is being interrupted, in which case nothing else must be done on the node.
+func (r *machineReconciliation) reconcileInterruption(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, bool, error) {
+	if _, interrupted := node.Annotations[InterruptedAnnotation]; !interrupted {
+		return ctrl.Result{}, false, nil
+	}
+	drained, err := r.drainNode(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, true, err
+	}
+	if !drained {
+		return ctrl.Result{RequeueAfter: interruptionDrainRetryDelay}, true, nil
+	}
+	r.log.Info("interrupted node drained", "windowsmachine", machine.GetNamespace()+"/"+machine.GetName(),
+		"node", node.GetName())
+	return ctrl.Result{}, true, nil
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"context"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+// reportIsolationCompatibility reports through a warning event on the given newly configured Machine if the OS build
+// of its VM cannot run the containers of the Windows workloads process-isolated, as recorded on its node when it was
+// configured. As the Docker runtime does not support Hyper-V isolation, these containers cannot run on the node at all.
+func (r *machineReconciliation) reportIsolationCompatibility(ctx context.Context, machine *mapi.Machine) error {
+	if len(r.config.WorkloadBuilds) == 0 || machine.Spec.ProviderID == nil {
+		return nil
+	}
+	node, err := r.getNodeByProviderID(ctx, *machine.Spec.ProviderID)
+	if err != nil {
+		return err
+	}
+	incompatible, present := node.GetAnnotations()[nodeconfig.IncompatibleBuildsAnnotation]
+	if !present {
+		return nil
+	}
+	r.recorder.Eventf(machine, core.EventTypeWarning, "ProcessIsolationUnsupported",
+		"Machine %s node %s OS build cannot run process-isolated containers of images built for Windows builds %s: "+
+			"the Windows image must be of the same build as the workloads",
+		machine.GetName(), node.GetName(), incompatible)
+	return nil
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"time"
+
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	// requeueJitterFactor is the maximum fraction of the requeue delay of a reconciliation added to it, so that the
+	// Machines requeued after the same delay, such as until the start of a maintenance window, are not all reconciled
+	// at the same time
+	requeueJitterFactor = 0.1
+	// invalidationSpread is the maximum delay of the reconciliation of each Machine invalidated by an event
+	// invalidating many Machines at once, such as a change of the operator configuration or a private key rotation,
+	// so that they are not all configured through SSH at the same time
+	invalidationSpread = time.Minute
+)
+
+// jitterRequeue returns the given result, with a random delay of up to requeueJitterFactor of its requeue delay added
+func jitterRequeue(result ctrl.Result) ctrl.Result {
+	if result.RequeueAfter > 0 {
+		result.RequeueAfter += randomDelay(time.Duration(float64(result.RequeueAfter) * requeueJitterFactor))
+	}
+	return result
+}
+
+// randomDelay returns a random delay shorter than the given maximum
+func randomDelay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(utilrand.Int63nRange(0, int64(max)))
+}
+
+// jitteredEnqueue is an event handler enqueuing the requests mapped from the object of each event. The requests are
+// delayed by a random time within invalidationSpread when the event invalidates many Machines: when several requests
+// are mapped, or when the given invalidation function, if any, returns true for the object.
+type jitteredEnqueue struct {
+	mapFunc handler.MapFunc
+	// invalidates returns true if the given object invalidates its Machine as part of an event invalidating many
+	// Machines at once
+	invalidates func(client.Object) bool
+}
+
+// enqueueWithJitter returns the jitteredEnqueue handler of the given map and invalidation functions
+func enqueueWithJitter(mapFunc handler.MapFunc, invalidates func(client.Object) bool) handler.EventHandler {
+	return &jitteredEnqueue{mapFunc: mapFunc, invalidates: invalidates}
+}
+
+// Create enqueues the requests mapped from the created object
+func (e *jitteredEnqueue) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(evt.Object, q)
+}
+
+// Update enqueues the requests mapped from the updated object
+func (e *jitteredEnqueue) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(evt.ObjectNew, q)
+}
+
+// Delete enqueues the requests mapped from the deleted object
+func (e *jitteredEnqueue) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(evt.Object, q)
+}
+
+// Generic enqueues the requests mapped from the object
+func (e *jitteredEnqueue) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(evt.Object, q)
+}
+
+// enqueue adds the requests mapped from the given object to the given queue
+func (e *jitteredEnqueue) enqueue(object client.Object, q workqueue.RateLimitingInterface) {
+	requests := e.mapFunc(object)
+	spread := len(requests) > 1 || (e.invalidates != nil && e.invalidates(object))
+	for _, request := range requests {
+		if !spread {
+			q.Add(request)
+			continue
+		}
+		q.AddAfter(request, randomDelay(invalidationSpread))
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestJitterRequeue(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ctrl.Result
+		min    time.Duration
+		max    time.Duration
+	}{
+		{
+			name:   "no requeue",
+			result: ctrl.Result{},
+			min:    0,
+			max:    0,
+		},
+		{
+			name:   "immediate requeue",
+			result: ctrl.Result{Requeue: true},
+			min:    0,
+			max:    0,
+		},
+		{
+			name:   "delayed requeue",
+			result: ctrl.Result{RequeueAfter: time.Hour},
+			min:    time.Hour,
+			max:    time.Hour + 6*time.Minute,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				result := jitterRequeue(test.result)
+				assert.Equal(t, test.result.Requeue, result.Requeue)
+				assert.GreaterOrEqual(t, int64(result.RequeueAfter), int64(test.min))
+				assert.LessOrEqual(t, int64(result.RequeueAfter), int64(test.max))
+			}
+		})
+	}
+}
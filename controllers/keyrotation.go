@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// reconcilePreviousKey switches the node of the given Machine over to the current private key in place when it was
+// configured with the previous private key held by the private key secret, so that rotating the private key does not
+// recreate the machine. The node is returned unchanged if it does not use the previous private key, else updated with
+// the hash of the current public key. Instances trusting an SSH certificate authority are not switched over, as the
+// authority is what they trust.
+func (r *machineReconciliation) reconcilePreviousKey(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (*core.Node, error) {
+	if r.previousSigner == nil || r.sshCA != nil ||
+		node.Annotations[nodeconfig.PubKeyHashAnnotation] !=
+			nodeconfig.CreatePubKeyHashAnnotation(r.previousSigner.PublicKey()) {
+		return node, nil
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "PrivateKeySwitch",
+			"Machine %s node %s would be switched over from the previous to the current private key",
+			machine.GetName(), node.GetName())
+		return node, nil
+	}
+	sshAddress, instanceID, err := r.getMachineSSHAddress(machine)
+	if err != nil {
+		return nil, err
+	}
+	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, sshAddress, instanceID, machine.GetName(),
+		r.networkCIDRs, r.vxlanPort, r.previousSigner, r.platform, r.config.SSHUser, windows.KubeletSettings{},
+		r.connectionSettings(), nil, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to machine %s with the previous private key",
+			machine.GetName())
+	}
+	defer nc.Close()
+	// The current key is authorized alongside the previous one, which is only revoked once a connection with the
+	// current key succeeded, so that a key the instance does not accept cannot lock WMCO out of it
+	if err := nc.Windows.AuthorizeKey(r.signer.PublicKey()); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "PrivateKeySwitchFailure",
+			"Machine %s node %s could not be switched over to the current private key", machine.GetName(),
+			node.GetName())
+		return nil, errors.Wrapf(err, "unable to authorize the current public key on machine %s",
+			machine.GetName())
+	}
+	vm, err := r.machineVM(machine)
+	if err == nil {
+		_, err = vm.Run("hostname", true)
+	}
+	if err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "PrivateKeySwitchFailure",
+			"Machine %s node %s does not accept the current private key, the previous one is kept",
+			machine.GetName(), node.GetName())
+		return nil, errors.Wrapf(err, "unable to connect to machine %s with the current private key",
+			machine.GetName())
+	}
+	if err := vm.RevokeKey(r.previousSigner.PublicKey()); err != nil {
+		return nil, errors.Wrapf(err, "unable to revoke the previous public key on machine %s", machine.GetName())
+	}
+	nodeName := node.GetName()
+	node, err = r.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get node %s", nodeName)
+	}
+	node.Annotations[nodeconfig.PubKeyHashAnnotation] = nodeconfig.CreatePubKeyHashAnnotation(r.signer.PublicKey())
+	if node, err = r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return nil, errors.Wrapf(err, "unable to update public key hash of node %s", nodeName)
+	}
+	log.Info("switched over to the current private key", "node", nodeName)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "PrivateKeySwitched",
+		"Machine %s node %s switched over from the previous to the current private key", machine.GetName(),
+		nodeName)
+	return node, nil
+}
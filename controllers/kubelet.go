@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+// KubeletReconfiguringAnnotation is applied to a node cordoned by WMCO while its kubelet is being reconfigured. The
+// node is uncordoned once kubelet has been reconfigured.
+const KubeletReconfiguringAnnotation = "windowsmachineconfig.openshift.io/kubelet-reconfiguring"
+
+// reconcileKubeletSettings applies the kubelet settings to the node of the given Machine, if they changed since the
+// node was configured. The change is rolled out to at most maxUnhealthyCount nodes at a time, during maintenance
+// windows, by draining each node before kubelet is restarted.
+func (r *machineReconciliation) reconcileKubeletSettings(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	ipAddress, instanceID, err := GetMachineInstance(machine, r.config.NodeIPCIDRs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	settings := r.config.KubeletSettings(ipAddress)
+	hash, err := nodeconfig.CreateKubeletConfigHash(settings)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if node.Annotations[nodeconfig.KubeletConfigHashAnnotation] == hash {
+		return ctrl.Result{}, nil
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	nodeName := node.GetName()
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "KubeletReconfiguration",
+			"Machine %s node %s would be drained and its kubelet reconfigured", machine.GetName(), nodeName)
+		return ctrl.Result{}, nil
+	}
+
+	if _, reconfiguring := node.Annotations[KubeletReconfiguringAnnotation]; !reconfiguring {
+		var result ctrl.Result
+		if node, result, err = r.startNodeDisruption(ctx, machine, node, KubeletReconfiguringAnnotation,
+			"kubelet reconfiguration"); err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+	drained, err := r.drainNode(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to drain node %s", node.GetName())
+	}
+	if !drained {
+		log.Info("waiting for node to be drained", "node", node.GetName())
+		return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+
+	sshAddress, err := r.machineSSHAddress(machine, ipAddress)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, sshAddress, instanceID, machine.GetName(),
+		r.networkCIDRs, r.vxlanPort, r.signer, r.platform, r.config.SSHUser, settings,
+		r.connectionSettings(), nil, nil)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to create new nodeconfig for machine %s", machine.GetName())
+	}
+	defer nc.Close()
+	if err := nc.ReconfigureKubelet(ctx); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "KubeletReconfigurationFailure",
+			"Machine %s kubelet reconfiguration failure", machine.GetName())
+		return ctrl.Result{}, err
+	}
+
+	if err := r.finishNodeDisruption(ctx, nodeName, KubeletReconfiguringAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("kubelet reconfigured", "node", nodeName)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "KubeletReconfigured",
+		"Machine %s kubelet reconfigured successfully", machine.GetName())
+	return ctrl.Result{}, nil
+}
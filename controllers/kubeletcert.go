@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// KubeletCertificateCheckedAnnotation holds the time at which the client certificate of the kubelet of the node was
+	// last checked
+	KubeletCertificateCheckedAnnotation = "windowsmachineconfig.openshift.io/kubelet-certificate-checked"
+	// kubeletCertCheckPeriod is the interval at which the client certificates of the kubelets of configured Windows
+	// nodes are checked
+	kubeletCertCheckPeriod = time.Hour
+	// kubeletCertRotationDeadline is the fraction of the lifetime of its client certificate after which kubelet must
+	// have rotated it. kubelet rotates it at a random point between 70% and 90% of its lifetime.
+	kubeletCertRotationDeadline = 0.92
+)
+
+// kubeletClientCertExpiry is the expiry of the client certificate of the kubelet of each Windows Machine
+var kubeletClientCertExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "windows_machine_kubelet_client_certificate_expiry_timestamp_seconds",
+	Help: "Expiry of the client certificate of the kubelet of the Windows Machine, in seconds since the epoch",
+}, []string{"machine"})
+
+func init() {
+	metrics.Registry.MustRegister(kubeletClientCertExpiry)
+}
+
+// reconcileKubeletCertificate checks the client certificate of the kubelet of the node of the given Machine once every
+// kubeletCertCheckPeriod, renewing it if its rotation is stuck
+func (r *machineReconciliation) reconcileKubeletCertificate(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if remaining := checkRemaining(node, KubeletCertificateCheckedAnnotation, kubeletCertCheckPeriod,
+		time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	held, err := r.checkKubeletCertificate(ctx, machine, node)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if held {
+		// The check is not recorded, so that the renewal is attempted once the maintenance ends
+		return ctrl.Result{RequeueAfter: nodeMaintenanceDelay}, nil
+	}
+	if err := r.recordCheck(ctx, node.GetName(), KubeletCertificateCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: kubeletCertCheckPeriod}, nil
+}
+
+// checkKubeletCertificate checks that the kubelet of the node of the given Machine rotates its client certificate,
+// recording its expiry in the kubelet client certificate expiry metric. Once the certificate outlived the rotation
+// deadline, the rotation is stuck and kubelet is restarted without its client certificate, so that it requests a new
+// one with its bootstrap credentials before it loses access to the API server. The repair is not held until a
+// maintenance window, as restarting kubelet does not disrupt the pods of the node, but it is held while the node is
+// under maintenance, in which case true is returned.
+func (r *machineReconciliation) checkKubeletCertificate(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (bool, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return false, err
+	}
+	validity, err := vm.KubeletClientCertificate()
+	if err != nil {
+		return false, err
+	}
+	kubeletClientCertExpiry.WithLabelValues(machine.GetName()).Set(float64(validity.NotAfter.Unix()))
+	if time.Now().Before(rotationDeadline(validity.NotBefore, validity.NotAfter)) {
+		return false, nil
+	}
+
+	log.Info("kubelet client certificate rotation stuck", "node", node.GetName(), "expiry", validity.NotAfter)
+	r.recorder.Eventf(machine, core.EventTypeWarning, "KubeletCertificateRotationStuck",
+		"Machine %s node %s kubelet client certificate expiring at %s was not rotated", machine.GetName(),
+		node.GetName(), validity.NotAfter.UTC().Format(time.RFC3339))
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "KubeletCertificateRenewal",
+			"Machine %s node %s kubelet would be restarted to request a new client certificate", machine.GetName(),
+			node.GetName())
+		return false, nil
+	}
+	if held, err := r.isHeldForNodeMaintenance(ctx, machine); err != nil || held {
+		return held, err
+	}
+	if err := vm.RenewKubeletClientCertificate(); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "KubeletCertificateRenewalFailure",
+			"Machine %s node %s kubelet could not be restarted to request a new client certificate",
+			machine.GetName(), node.GetName())
+		return false, errors.Wrapf(err, "unable to renew kubelet client certificate of node %s", node.GetName())
+	}
+	log.Info("kubelet restarted to request a new client certificate", "node", node.GetName())
+	r.recorder.Eventf(machine, core.EventTypeNormal, "KubeletCertificateRenewalRequested",
+		"Machine %s node %s kubelet restarted to request a new client certificate", machine.GetName(),
+		node.GetName())
+	return false, nil
+}
+
+// rotationDeadline returns the time by which kubelet must have rotated a client certificate with the given validity
+// period
+func rotationDeadline(notBefore, notAfter time.Time) time.Time {
+	lifetime := notAfter.Sub(notBefore)
+	return notBefore.Add(time.Duration(float64(lifetime) * kubeletCertRotationDeadline))
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// TestRotationDeadline tests the rotationDeadline function
+func TestRotationDeadline(t *testing.T) {
+	notBefore := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, notBefore.Add(92*time.Hour), rotationDeadline(notBefore, notBefore.Add(100*time.Hour)))
+	assert.Equal(t, notBefore, rotationDeadline(notBefore, notBefore))
+}
+
+// TestIsCertificateExpiring tests the isCertificateExpiring function
+func TestIsCertificateExpiring(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	// A 30 day kubelet certificate is expiring within its last 3 days
+	assert.False(t, isCertificateExpiring(&windows.CertificateValidity{NotBefore: now.Add(-26 * day),
+		NotAfter: now.Add(4 * day)}, now))
+	assert.True(t, isCertificateExpiring(&windows.CertificateValidity{NotBefore: now.Add(-28 * day),
+		NotAfter: now.Add(2 * day)}, now))
+	// A 10 year CA is expiring within its last 30 days
+	assert.False(t, isCertificateExpiring(&windows.CertificateValidity{NotBefore: now.Add(-3600 * day),
+		NotAfter: now.Add(60 * day)}, now))
+	assert.True(t, isCertificateExpiring(&windows.CertificateValidity{NotBefore: now.Add(-3600 * day),
+		NotAfter: now.Add(20 * day)}, now))
+	assert.True(t, isCertificateExpiring(&windows.CertificateValidity{NotBefore: now.Add(-3650 * day),
+		NotAfter: now.Add(-day)}, now))
+}
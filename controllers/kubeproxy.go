@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+// reconcileKubeProxy applies the Direct Server Return setting to the kube-proxy of the node of the given Machine, if
+// it changed since the node was configured. kube-proxy is restarted during maintenance windows, without draining the
+// node, as the load balancing policies of the services are recreated within seconds.
+func (r *machineReconciliation) reconcileKubeProxy(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if nodeconfig.IsKubeProxyDSRCurrent(node, r.config.KubeProxyDSR) {
+		return ctrl.Result{}, nil
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	dsr := strconv.FormatBool(r.config.KubeProxyDSR)
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "KubeProxyReconfiguration",
+			"Machine %s node %s kube-proxy would be restarted with Direct Server Return %s", machine.GetName(),
+			node.GetName(), dsr)
+		return ctrl.Result{}, nil
+	}
+	delay, err := r.getMaintenanceWindowDelay(ctx, machine)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to determine maintenance window")
+	}
+	if delay > 0 {
+		log.Info("kube-proxy reconfiguration pending until the next maintenance window", "delay", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	ipAddress, instanceID, err := GetMachineInstance(machine, r.config.NodeIPCIDRs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	sshAddress, err := r.machineSSHAddress(machine, ipAddress)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, sshAddress, instanceID, machine.GetName(),
+		r.networkCIDRs, r.vxlanPort, r.signer, r.platform, r.config.SSHUser, r.config.KubeletSettings(ipAddress),
+		r.hostSettings(), nil, nil)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to create new nodeconfig for machine %s", machine.GetName())
+	}
+	defer nc.Close()
+	if err := nc.ReconfigureKubeProxy(ctx); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "KubeProxyReconfigurationFailure",
+			"Machine %s kube-proxy reconfiguration failure", machine.GetName())
+		return ctrl.Result{}, err
+	}
+	log.Info("kube-proxy reconfigured", "node", node.GetName(), "dsr", dsr)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "KubeProxyReconfigured",
+		"Machine %s kube-proxy reconfigured with Direct Server Return %s", machine.GetName(), dsr)
+	return ctrl.Result{}, nil
+}
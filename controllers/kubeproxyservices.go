@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// KubeProxyServicesUnsupported is the node condition which is True when the kube-proxy of a Windows node cannot
+	// honor the traffic policy, source ranges or session affinity of some services, given the OS build of the node
+	// and whether Direct Server Return is enabled
+	KubeProxyServicesUnsupported core.NodeConditionType = "KubeProxyServicesUnsupported"
+	// KubeProxyServicesCheckedAnnotation holds the time at which the services were last checked against the
+	// kube-proxy of the node
+	KubeProxyServicesCheckedAnnotation = "windowsmachineconfig.openshift.io/kube-proxy-services-checked"
+
+	// kubeProxyServicesCheckPeriod is the interval at which the services are checked against the kube-proxy of
+	// configured Windows nodes
+	kubeProxyServicesCheckPeriod = 30 * time.Minute
+	// minDSRBuild is the OS build of Windows Server 2019, from which kube-proxy supports Direct Server Return
+	minDSRBuild = 17763
+	// minSessionAffinityBuild is the OS build of Windows Server 2022, from which kube-proxy supports the ClientIP
+	// session affinity
+	minSessionAffinityBuild = 20348
+	// maxReportedServices is the number of unsupported services listed in the condition message
+	maxReportedServices = 5
+)
+
+// reconcileKubeProxyServices checks the services of the cluster against the kube-proxy of the node of the given
+// Machine, and records the services whose features it cannot honor in the KubeProxyServicesUnsupported condition of
+// the node, as kube-proxy silently ignores them. The services of the whole cluster are listed, so the check only runs
+// once per kubeProxyServicesCheckPeriod.
+func (r *machineReconciliation) reconcileKubeProxyServices(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.config.ProblemDetection || r.config.DryRun {
+		return ctrl.Result{}, nil
+	}
+	if remaining := checkRemaining(node, KubeProxyServicesCheckedAnnotation, kubeProxyServicesCheckPeriod,
+		time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	build, err := windows.ParseBuild(node.Status.NodeInfo.KernelVersion)
+	if err != nil {
+		r.log.V(1).Info("unable to get OS build of node", "node", node.GetName(), "error", err)
+		return ctrl.Result{RequeueAfter: kubeProxyServicesCheckPeriod}, nil
+	}
+	services, err := r.k8sclientset.CoreV1().Services(meta.NamespaceAll).List(ctx, meta.ListOptions{})
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to list services")
+	}
+	dsr := nodeconfig.IsKubeProxyDSRCurrent(node, true)
+	condition := kubeProxyServicesCondition(unsupportedServiceFeatures(services.Items, build, dsr))
+	if err := r.setNodeConditions(ctx, machine, node.GetName(), condition); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.recordCheck(ctx, node.GetName(), KubeProxyServicesCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: kubeProxyServicesCheckPeriod}, nil
+}
+
+// unsupportedServiceFeatures returns the features of the given services which the kube-proxy of a Windows node with
+// the given OS build, with Direct Server Return enabled or not, cannot honor:
+// * loadBalancerSourceRanges, which are not enforced on Windows
+// * the Local external traffic policy without Direct Server Return, which does not preserve the client source IP
+// * the ClientIP session affinity before Windows Server 2022
+func unsupportedServiceFeatures(services []core.Service, build int, dsr bool) []string {
+	var unsupported []string
+	if dsr && build < minDSRBuild {
+		unsupported = append(unsupported, fmt.Sprintf("Direct Server Return requires OS build %d", minDSRBuild))
+	}
+	for _, service := range services {
+		if service.Spec.Type == core.ServiceTypeExternalName || service.Spec.ClusterIP == core.ClusterIPNone {
+			continue
+		}
+		var features []string
+		if service.Spec.Type == core.ServiceTypeLoadBalancer && len(service.Spec.LoadBalancerSourceRanges) > 0 {
+			features = append(features, "loadBalancerSourceRanges are not enforced")
+		}
+		if (service.Spec.Type == core.ServiceTypeLoadBalancer || service.Spec.Type == core.ServiceTypeNodePort) &&
+			service.Spec.ExternalTrafficPolicy == core.ServiceExternalTrafficPolicyTypeLocal && !dsr {
+			features = append(features, "the Local external traffic policy does not preserve the client source IP "+
+				"without Direct Server Return")
+		}
+		if service.Spec.SessionAffinity == core.ServiceAffinityClientIP && build < minSessionAffinityBuild {
+			features = append(features, fmt.Sprintf("the ClientIP session affinity requires OS build %d",
+				minSessionAffinityBuild))
+		}
+		if len(features) > 0 {
+			unsupported = append(unsupported, fmt.Sprintf("service %s/%s: %s", service.GetNamespace(),
+				service.GetName(), strings.Join(features, ", ")))
+		}
+	}
+	return unsupported
+}
+
+// kubeProxyServicesCondition returns the KubeProxyServicesUnsupported node condition reporting the given unsupported
+// features, of which the first maxReportedServices are listed
+func kubeProxyServicesCondition(unsupported []string) core.NodeCondition {
+	if len(unsupported) == 0 {
+		return core.NodeCondition{Type: KubeProxyServicesUnsupported, Status: core.ConditionFalse,
+			Reason: "Supported", Message: "kube-proxy supports the features of all services"}
+	}
+	listed := unsupported
+	if len(listed) > maxReportedServices {
+		listed = append(listed[:maxReportedServices:maxReportedServices],
+			fmt.Sprintf("and %d more", len(unsupported)-maxReportedServices))
+	}
+	return core.NodeCondition{Type: KubeProxyServicesUnsupported, Status: core.ConditionTrue,
+		Reason:  "UnsupportedServiceFeatures",
+		Message: "kube-proxy cannot honor some service features: " + strings.Join(listed, "; ")}
+}
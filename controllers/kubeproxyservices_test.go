@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUnsupportedServiceFeatures(t *testing.T) {
+	services := []core.Service{
+		{ObjectMeta: meta.ObjectMeta{Namespace: "a", Name: "lb"}, Spec: core.ServiceSpec{
+			Type: core.ServiceTypeLoadBalancer, ClusterIP: "172.30.0.1",
+			LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+			ExternalTrafficPolicy:    core.ServiceExternalTrafficPolicyTypeLocal}},
+		{ObjectMeta: meta.ObjectMeta{Namespace: "a", Name: "affinity"}, Spec: core.ServiceSpec{
+			Type: core.ServiceTypeClusterIP, ClusterIP: "172.30.0.2", SessionAffinity: core.ServiceAffinityClientIP}},
+		{ObjectMeta: meta.ObjectMeta{Namespace: "a", Name: "headless"}, Spec: core.ServiceSpec{
+			Type: core.ServiceTypeClusterIP, ClusterIP: core.ClusterIPNone,
+			SessionAffinity: core.ServiceAffinityClientIP}},
+		{ObjectMeta: meta.ObjectMeta{Namespace: "a", Name: "plain"}, Spec: core.ServiceSpec{
+			Type: core.ServiceTypeNodePort, ClusterIP: "172.30.0.3"}},
+	}
+
+	assert.Equal(t, []string{
+		"service a/lb: loadBalancerSourceRanges are not enforced, the Local external traffic policy does not " +
+			"preserve the client source IP without Direct Server Return",
+		"service a/affinity: the ClientIP session affinity requires OS build 20348",
+	}, unsupportedServiceFeatures(services, 17763, false))
+
+	assert.Equal(t, []string{"service a/lb: loadBalancerSourceRanges are not enforced"},
+		unsupportedServiceFeatures(services, 20348, true))
+
+	assert.Equal(t, []string{"Direct Server Return requires OS build 17763"},
+		unsupportedServiceFeatures(services[3:], 17134, true))
+}
+
+func TestKubeProxyServicesCondition(t *testing.T) {
+	condition := kubeProxyServicesCondition(nil)
+	assert.Equal(t, core.ConditionFalse, condition.Status)
+
+	condition = kubeProxyServicesCondition([]string{"1", "2", "3", "4", "5", "6", "7"})
+	assert.Equal(t, core.ConditionTrue, condition.Status)
+	assert.Equal(t, "kube-proxy cannot honor some service features: 1; 2; 3; 4; 5; and 2 more", condition.Message)
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// WindowsLicenseInvalid is the node condition which is True when the Windows installation of a node is not
+	// activated, or when its grace period or time based license, such as the license of an evaluation image, expires
+	// within licenseExpiryThreshold. Windows shuts down periodically once its license expired.
+	WindowsLicenseInvalid core.NodeConditionType = "WindowsLicenseInvalid"
+	// LicenseCheckedAnnotation holds the time at which the license status of the node was last checked
+	LicenseCheckedAnnotation = "windowsmachineconfig.openshift.io/license-checked"
+
+	// licenseCheckPeriod is the interval at which the license status of configured Windows nodes is checked
+	licenseCheckPeriod = time.Hour
+	// licenseExpiryThreshold is the time before its expiry from which a license is reported as invalid
+	licenseExpiryThreshold = 14 * 24 * time.Hour
+)
+
+var (
+	// windowsActivated is 1 when the Windows installation of each Windows Machine is activated, else 0
+	windowsActivated = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_machine_activated",
+		Help: "1 when the Windows installation of the Windows Machine is activated, else 0",
+	}, []string{"machine"})
+	// windowsLicenseRemaining is the time remaining before the license of each Windows Machine expires
+	windowsLicenseRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "windows_machine_license_remaining_seconds",
+		Help: "Time remaining before the grace period or time based license of the Windows Machine expires, " +
+			"0 if it does not expire",
+	}, []string{"machine"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(windowsActivated, windowsLicenseRemaining)
+}
+
+// reconcileLicense checks the activation status of the Windows installation of the VM backing the given Machine,
+// recording it in the license metrics and in the WindowsLicenseInvalid condition of its node, as an unlicensed
+// installation or an expired evaluation image shuts down periodically, taking its workloads with it. The status is
+// checked once every licenseCheckPeriod.
+func (r *machineReconciliation) reconcileLicense(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if r.config.DryRun {
+		return ctrl.Result{}, nil
+	}
+	if remaining := checkRemaining(node, LicenseCheckedAnnotation, licenseCheckPeriod, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	license, err := vm.License()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get license status of node %s", node.GetName())
+	}
+	activated := 0.0
+	if license.Activated() {
+		activated = 1
+	}
+	windowsActivated.WithLabelValues(machine.GetName()).Set(activated)
+	windowsLicenseRemaining.WithLabelValues(machine.GetName()).Set(license.Remaining.Seconds())
+	if err := r.setNodeConditions(ctx, machine, node.GetName(), licenseCondition(license)); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.recordCheck(ctx, node.GetName(), LicenseCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: licenseCheckPeriod}, nil
+}
+
+// licenseCondition returns the WindowsLicenseInvalid node condition reporting the given license status
+func licenseCondition(license *windows.License) core.NodeCondition {
+	condition := core.NodeCondition{Type: WindowsLicenseInvalid, Status: core.ConditionFalse, Reason: "Activated",
+		Message: fmt.Sprintf("Windows is activated: %s", license.Description)}
+	switch {
+	case !license.Activated():
+		condition.Status = core.ConditionTrue
+		condition.Reason = "NotActivated"
+		condition.Message = fmt.Sprintf("Windows is not activated, license status %s: %s", license.StatusName(),
+			license.Description)
+		if license.Remaining > 0 {
+			condition.Message += fmt.Sprintf(", grace period expires in %s", license.Remaining)
+		}
+	case license.Remaining > 0 && license.Remaining <= licenseExpiryThreshold:
+		condition.Status = core.ConditionTrue
+		condition.Reason = "LicenseExpiring"
+		condition.Message = fmt.Sprintf("Windows license expires in %s: %s", license.Remaining, license.Description)
+	}
+	return condition
+}
+
+// deleteLicenseMetrics removes the license metrics of the Machine with the given name
+func deleteLicenseMetrics(machineName string) {
+	windowsActivated.DeleteLabelValues(machineName)
+	windowsLicenseRemaining.DeleteLabelValues(machineName)
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+func TestLicenseCondition(t *testing.T) {
+	testCases := []struct {
+		name     string
+		license  *windows.License
+		expected core.ConditionStatus
+		reason   string
+	}{
+		{
+			name:     "activated",
+			license:  &windows.License{Status: 1, Description: "VOLUME_KMSCLIENT channel", Remaining: 4320 * time.Hour},
+			expected: core.ConditionFalse,
+			reason:   "Activated",
+		},
+		{
+			name:     "evaluation expiring",
+			license:  &windows.License{Status: 1, Description: "TIMEBASED_EVAL channel", Remaining: 24 * time.Hour},
+			expected: core.ConditionTrue,
+			reason:   "LicenseExpiring",
+		},
+		{
+			name:     "not activated",
+			license:  &windows.License{Status: 2, Description: "RETAIL channel", Remaining: 30 * 24 * time.Hour},
+			expected: core.ConditionTrue,
+			reason:   "NotActivated",
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			condition := licenseCondition(test.license)
+			assert.Equal(t, WindowsLicenseInvalid, condition.Type)
+			assert.Equal(t, test.expected, condition.Status)
+			assert.Equal(t, test.reason, condition.Reason)
+		})
+	}
+}
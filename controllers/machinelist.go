@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"context"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// machineAPINamespace is the namespace holding the Machines and MachineSets of the cluster
+const machineAPINamespace = "openshift-machine-api"
+
+// listWindowsMachines returns the Windows Machines of the Machine API namespace matching the given options, listed
+// through the given reader. The list is scoped by namespace and label, so that only the Windows Machines are returned
+// rather than all the Machines of the cluster.
+func listWindowsMachines(ctx context.Context, reader client.Reader, opts ...client.ListOption) ([]mapi.Machine,
+	error) {
+	opts = append([]client.ListOption{client.InNamespace(machineAPINamespace),
+		client.MatchingLabels{MachineOSLabel: "Windows"}}, opts...)
+	machines := &mapi.MachineList{}
+	if err := reader.List(ctx, machines, opts...); err != nil {
+		return nil, errors.Wrap(err, "error listing Windows machines")
+	}
+	return machines.Items, nil
+}
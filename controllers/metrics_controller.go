@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/metrics"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+// metricsDebounce is the time waited before reconciling the metrics Endpoints on node events, so that the events
+// received in a burst are handled by a single reconciliation
+const metricsDebounce = 500 * time.Millisecond
+
+// MetricsReconciler is used to create a controller which keeps the metrics Endpoints in sync with the addresses of the
+// Windows nodes. This is kept separate from the WindowsMachineReconciler, so that the metrics configuration neither
+// depends on nor blocks the configuration of Windows Machines.
+type MetricsReconciler struct {
+	log logr.Logger
+	// prometheusNodeConfig stores information required to configure Prometheus
+	prometheusNodeConfig *metrics.PrometheusNodeConfig
+	// watchNamespace is the namespace the operator is watching as defined by the operator CSV
+	watchNamespace string
+	// namespacedCache is a cache restricted to the operator namespace
+	namespacedCache cache.Cache
+}
+
+// NewMetricsReconciler returns a pointer to a MetricsReconciler
+func NewMetricsReconciler(mgr manager.Manager, watchNamespace string,
+	namespacedCache cache.Cache) (*MetricsReconciler, error) {
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes clientset")
+	}
+	pc, err := metrics.NewPrometheusNodeConfig(clientset, watchNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to initialize Prometheus configuration")
+	}
+	return &MetricsReconciler{
+		log:                  ctrl.Log.WithName("controller").WithName("metrics"),
+		prometheusNodeConfig: pc,
+		watchNamespace:       watchNamespace,
+		namespacedCache:      namespacedCache,
+	}, nil
+}
+
+// SetupWithManager sets up a new metrics controller with the given options
+func (r *MetricsReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
+	// Watch for Windows nodes being added or removed, or their addresses, schedulability or metrics scheme being
+	// changed
+	nodePredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isWindowsNode(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !isWindowsNode(e.ObjectNew) {
+				return false
+			}
+			oldNode, ok := e.ObjectOld.(*core.Node)
+			if !ok {
+				return false
+			}
+			newNode := e.ObjectNew.(*core.Node)
+			return !reflect.DeepEqual(oldNode.Status.Addresses, newNode.Status.Addresses) ||
+				oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable ||
+				oldNode.Annotations[nodeconfig.MetricsProxyAnnotation] !=
+					newNode.Annotations[nodeconfig.MetricsProxyAnnotation]
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isWindowsNode(e.Object)
+		},
+	}
+	// Watch for the metrics Endpoints being created or changed, such as when they are reverted on operator restarts
+	endpointsPredicate := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetName() == metrics.WindowsMetricsResource && object.GetNamespace() == r.watchNamespace
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("metrics").
+		Watches(source.NewKindWithCache(&core.Endpoints{}, r.namespacedCache), &handler.EnqueueRequestForObject{},
+			builder.WithPredicates(endpointsPredicate)).
+		Watches(&source.Kind{Type: &core.Node{}}, r.enqueueMetricsEndpoints(),
+			builder.WithPredicates(nodePredicate)).
+		WithOptions(options).
+		Complete(r)
+}
+
+// isWindowsNode returns true if the given object is a Windows node
+func isWindowsNode(object client.Object) bool {
+	return object.GetLabels()[core.LabelOSStable] == "windows"
+}
+
+// enqueueMetricsEndpoints returns the handler enqueuing the metrics Endpoints once metricsDebounce has elapsed, on node
+// events. The workqueue holds the request only once, so that bursts of node events result in a single reconciliation.
+func (r *MetricsReconciler) enqueueMetricsEndpoints() handler.EventHandler {
+	enqueue := func(q workqueue.RateLimitingInterface) {
+		q.AddAfter(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: r.watchNamespace,
+			Name: metrics.WindowsMetricsResource}}, metricsDebounce)
+	}
+	return handler.Funcs{
+		CreateFunc: func(_ event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q)
+		},
+		UpdateFunc: func(_ event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q)
+		},
+		DeleteFunc: func(_ event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q)
+		},
+	}
+}
+
+// Reconcile updates the metrics Endpoints with the addresses of the schedulable Windows nodes
+func (r *MetricsReconciler) Reconcile(_ context.Context, request ctrl.Request) (ctrl.Result, error) {
+	r.log.V(1).Info("reconciling", "endpoints", request.NamespacedName)
+	if err := r.prometheusNodeConfig.Configure(); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			// Monitoring is not enabled in the operator namespace, the Endpoints will be reconciled once created
+			return ctrl.Result{}, nil
+		}
+		if k8sapierrors.IsForbidden(err) {
+			// Monitoring is restricted, the Endpoints will be reconciled once the operator is allowed to write them
+			r.log.Info("Prometheus configuration skipped", "reason", err.Error())
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to configure Prometheus")
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// NetworkPolicyNotEnforced is the node condition which is True when network policies are not enforced on the pods
+	// of a Windows node, so that the pods selected by a NetworkPolicy are not silently left unrestricted
+	NetworkPolicyNotEnforced core.NodeConditionType = "NetworkPolicyNotEnforced"
+	// NetworkPolicyCheckedAnnotation holds the time at which the network policy enforcement of the node was last
+	// checked
+	NetworkPolicyCheckedAnnotation = "windowsmachineconfig.openshift.io/network-policy-checked"
+
+	// networkPolicyCheckPeriod is the interval at which the network policy enforcement of configured Windows nodes is
+	// checked
+	networkPolicyCheckPeriod = 30 * time.Minute
+)
+
+// networkPolicyEnforced is 1 when network policies are enforced on the pods of each Windows Machine, else 0
+var networkPolicyEnforced = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "windows_machine_network_policy_enforced",
+	Help: "1 when network policies are enforced on the pods of the Windows Machine, else 0",
+}, []string{"machine"})
+
+func init() {
+	metrics.Registry.MustRegister(networkPolicyEnforced)
+}
+
+// reconcileNetworkPolicy checks whether network policies can be, and are, enforced on the pods of the VM backing the
+// given Machine, recording it in the network policy metric and in the NetworkPolicyNotEnforced condition of its node.
+// The enforcement is checked once every networkPolicyCheckPeriod.
+func (r *machineReconciliation) reconcileNetworkPolicy(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.config.ProblemDetection || r.config.DryRun {
+		return ctrl.Result{}, nil
+	}
+	if remaining := checkRemaining(node, NetworkPolicyCheckedAnnotation, networkPolicyCheckPeriod,
+		time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status, err := vm.NetworkPolicyStatus()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get network policy status of node %s", node.GetName())
+	}
+	condition := networkPolicyCondition(status)
+	enforced := 0.0
+	if condition.Status == core.ConditionFalse {
+		enforced = 1
+	}
+	networkPolicyEnforced.WithLabelValues(machine.GetName()).Set(enforced)
+	if err := r.setNodeConditions(ctx, machine, node.GetName(), condition); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.recordCheck(ctx, node.GetName(), NetworkPolicyCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: networkPolicyCheckPeriod}, nil
+}
+
+// networkPolicyCondition returns the NetworkPolicyNotEnforced node condition reporting the given network policy
+// status. The condition is Unknown when the OS build and overlay network allow network policies to be enforced but the
+// node runs no pods to check them on.
+func networkPolicyCondition(status *windows.NetworkPolicyStatus) core.NodeCondition {
+	condition := core.NodeCondition{Type: NetworkPolicyNotEnforced, Status: core.ConditionTrue}
+	gaps := status.Gaps()
+	switch {
+	case !status.ACLPoliciesSupported():
+		condition.Reason = "UnsupportedOSBuild"
+	case !status.OverlayNetwork:
+		condition.Reason = "OverlayNetworkMissing"
+	case len(gaps) > 0:
+		condition.Reason = "NotProgrammed"
+	case status.PodEndpoints == 0:
+		condition.Status = core.ConditionUnknown
+		condition.Reason = "NoPods"
+		condition.Message = "No pod runs on the node to check network policy enforcement on"
+		return condition
+	default:
+		condition.Status = core.ConditionFalse
+		condition.Reason = "Enforced"
+		condition.Message = "Network policies are enforced on the pods of the node"
+		return condition
+	}
+	condition.Message = "Network policies are not enforced on the pods of the node: " + strings.Join(gaps, "; ")
+	return condition
+}
+
+// deleteNetworkPolicyMetrics removes the network policy metric of the Machine with the given name
+func deleteNetworkPolicyMetrics(machineName string) {
+	networkPolicyEnforced.DeleteLabelValues(machineName)
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+func TestNetworkPolicyCondition(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   *windows.NetworkPolicyStatus
+		expected core.ConditionStatus
+		reason   string
+	}{
+		{
+			name:     "unsupported OS build",
+			status:   &windows.NetworkPolicyStatus{Build: 17134, OverlayNetwork: true, PodEndpoints: 1},
+			expected: core.ConditionTrue,
+			reason:   "UnsupportedOSBuild",
+		},
+		{
+			name:     "overlay network missing",
+			status:   &windows.NetworkPolicyStatus{Build: 17763},
+			expected: core.ConditionTrue,
+			reason:   "OverlayNetworkMissing",
+		},
+		{
+			name:     "not programmed",
+			status:   &windows.NetworkPolicyStatus{Build: 17763, OverlayNetwork: true, PodEndpoints: 2},
+			expected: core.ConditionTrue,
+			reason:   "NotProgrammed",
+		},
+		{
+			name:     "no pods",
+			status:   &windows.NetworkPolicyStatus{Build: 17763, OverlayNetwork: true},
+			expected: core.ConditionUnknown,
+			reason:   "NoPods",
+		},
+		{
+			name: "enforced",
+			status: &windows.NetworkPolicyStatus{Build: 20348, OverlayNetwork: true, PodEndpoints: 2,
+				EnforcedEndpoints: 2},
+			expected: core.ConditionFalse,
+			reason:   "Enforced",
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			condition := networkPolicyCondition(test.status)
+			assert.Equal(t, NetworkPolicyNotEnforced, condition.Type)
+			assert.Equal(t, test.expected, condition.Status)
+			assert.Equal(t, test.reason, condition.Reason)
+		})
+	}
+}
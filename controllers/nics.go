@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	oconfig "github.com/openshift/api/config/v1"
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	core "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+)
+
+// azureProviderSpec holds the fields of the provider spec of Azure Machines describing their network interfaces
+type azureProviderSpec struct {
+	// AcceleratedNetworking enables SR-IOV on the network interface of the VM
+	AcceleratedNetworking bool `json:"acceleratedNetworking,omitempty"`
+}
+
+// providerSpecNICProblems returns the problems of the network interface configuration of the given Azure Machine
+// known to break the hybrid overlay, as requested in its provider spec
+func providerSpecNICProblems(machine *mapi.Machine) []string {
+	if machine.Spec.ProviderSpec.Value == nil {
+		return nil
+	}
+	spec := &azureProviderSpec{}
+	if err := json.Unmarshal(machine.Spec.ProviderSpec.Value.Raw, spec); err != nil {
+		return nil
+	}
+	if spec.AcceleratedNetworking {
+		return []string{"accelerated networking is enabled in the provider spec, which is known to break the " +
+			"hybrid overlay"}
+	}
+	return nil
+}
+
+// checkNICs records whether the network interface configuration of the VM of the given Azure Machine has problems
+// known to break the hybrid overlay or the VM extensions in the WindowsNode of the Machine, reporting them through a
+// warning event. The Machine is configured regardless, as they may only affect some workloads. The provider spec of
+// the Machine is checked instead of the VM when the VM cannot be checked.
+func (r *machineReconciliation) checkNICs(ctx context.Context, machine *mapi.Machine) error {
+	if r.platform != oconfig.AzurePlatformType {
+		return nil
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	problems := providerSpecNICProblems(machine)
+	checked := false
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		log.V(1).Info("unable to connect to check the network interfaces", "error", err.Error())
+	} else if vmProblems, err := vm.NetworkInterfaceProblems(); err != nil {
+		log.Error(err, "unable to check the network interfaces")
+	} else {
+		problems = vmProblems
+		checked = true
+	}
+	condition := meta.Condition{Type: wmcoapi.NICValidCondition, Status: meta.ConditionTrue,
+		Reason: "Validated", Message: "The network interface configuration of the instance has no known problem"}
+	if len(problems) > 0 {
+		condition.Status = meta.ConditionFalse
+		condition.Reason = "UnsupportedConfiguration"
+		condition.Message = strings.Join(problems, "; ")
+		log.Info("machine network interface configuration is known to cause problems", "problems", problems)
+		r.recorder.Eventf(machine, core.EventTypeWarning, "NICConfigurationUnsupported",
+			"Machine %s network interface configuration: %s", machine.GetName(), strings.Join(problems, "; "))
+	} else if !checked {
+		// The provider spec did not report any problem, but the VM could not be checked
+		return nil
+	}
+	return r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		apimeta.SetStatusCondition(&status.Conditions, condition)
+	})
+}
@@ -0,0 +1,376 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	oconfig "github.com/openshift/api/config/v1"
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+)
+
+const (
+	// machineAnnotation is the annotation applied to nodes by the Machine API, holding the namespace/name of the
+	// Machine backing the node
+	machineAnnotation = "machine.openshift.io/machine"
+	// orphanedNodeGracePeriod is the time a Windows node whose Machine no longer exists must have been NotReady for
+	// before it is deleted
+	orphanedNodeGracePeriod = 5 * time.Minute
+)
+
+// NodeReconciler is used to create a controller which keeps the WMCO owned labels, annotations and taints of Windows
+// nodes in sync with their Machines and MachineSets, and removes Windows nodes whose Machines no longer exist. This is
+// kept separate from the WindowsMachineReconciler, so that nodes are reconciled without going through the machine
+// configuration path.
+type NodeReconciler struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// k8sclientset holds the kube client that we can re-use for all kube objects other than custom resources.
+	k8sclientset *kubernetes.Clientset
+	log          logr.Logger
+	recorder     record.EventRecorder
+	// watchNamespace is the namespace the operator is watching as defined by the operator CSV
+	watchNamespace string
+	// namespacedCache is a cache restricted to the operator namespace
+	namespacedCache cache.Cache
+	// defaultConfig holds the operator configuration used for the settings missing from the configuration ConfigMap
+	defaultConfig operatorconfig.Config
+	// platform is the platform the cluster runs on, determining the format of the topology labels
+	platform oconfig.PlatformType
+}
+
+// NewNodeReconciler returns a pointer to a NodeReconciler
+func NewNodeReconciler(mgr manager.Manager, platform oconfig.PlatformType, watchNamespace string,
+	namespacedCache cache.Cache, defaultConfig operatorconfig.Config) (*NodeReconciler, error) {
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes clientset")
+	}
+	return &NodeReconciler{
+		client:          mgr.GetClient(),
+		k8sclientset:    clientset,
+		log:             ctrl.Log.WithName("controller").WithName("node"),
+		recorder:        newThrottledRecorder(mgr.GetEventRecorderFor("node")),
+		watchNamespace:  watchNamespace,
+		namespacedCache: namespacedCache,
+		defaultConfig:   defaultConfig,
+		platform:        platform,
+	}, nil
+}
+
+// SetupWithManager sets up a new Node controller with the given options
+func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
+	// Watch for the labels, annotations, taints or readiness of Windows nodes being changed, leaving out the
+	// annotations in which WMCO records the state of the nodes, which change on their own schedule
+	nodePredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isConfiguredWindowsNode(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !isConfiguredWindowsNode(e.ObjectNew) {
+				return false
+			}
+			oldNode, ok := e.ObjectOld.(*core.Node)
+			if !ok {
+				return false
+			}
+			newNode := e.ObjectNew.(*core.Node)
+			return !reflect.DeepEqual(oldNode.GetLabels(), newNode.GetLabels()) ||
+				!reflect.DeepEqual(watchedAnnotations(oldNode), watchedAnnotations(newNode)) ||
+				!reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints) ||
+				isNodeReady(oldNode) != isNodeReady(newNode)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
+	// Watch for the labels, annotations or taints of Windows Machines being changed, and for Windows Machines being
+	// deleted
+	machinePredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !isWindowsMachine(e.ObjectNew.GetLabels()) {
+				return false
+			}
+			oldMachine, ok := e.ObjectOld.(*mapi.Machine)
+			if !ok {
+				return false
+			}
+			newMachine := e.ObjectNew.(*mapi.Machine)
+			return !reflect.DeepEqual(oldMachine.Spec.ObjectMeta.Labels, newMachine.Spec.ObjectMeta.Labels) ||
+				!reflect.DeepEqual(oldMachine.Spec.ObjectMeta.Annotations, newMachine.Spec.ObjectMeta.Annotations) ||
+				!reflect.DeepEqual(oldMachine.Spec.Taints, newMachine.Spec.Taints) ||
+				!reflect.DeepEqual(nodeconfig.TopologyLabels(r.platform, oldMachine.GetLabels()),
+					nodeconfig.TopologyLabels(r.platform, newMachine.GetLabels()))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isWindowsMachine(e.Object.GetLabels())
+		},
+	}
+	// Watch for the labels, annotations or taints of the template of Windows MachineSets being changed
+	machineSetPredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !isWindowsMachineSet(e.ObjectNew) {
+				return false
+			}
+			oldMachineSet, ok := e.ObjectOld.(*mapi.MachineSet)
+			if !ok {
+				return false
+			}
+			newMachineSet := e.ObjectNew.(*mapi.MachineSet)
+			return !reflect.DeepEqual(oldMachineSet.Spec.Template.Spec.ObjectMeta.Labels,
+				newMachineSet.Spec.Template.Spec.ObjectMeta.Labels) ||
+				!reflect.DeepEqual(oldMachineSet.Spec.Template.Spec.ObjectMeta.Annotations,
+					newMachineSet.Spec.Template.Spec.ObjectMeta.Annotations) ||
+				!reflect.DeepEqual(oldMachineSet.Spec.Template.Spec.Taints, newMachineSet.Spec.Template.Spec.Taints)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
+	// Watch for the operator configuration being changed, as it defines taints applied to all Windows nodes
+	configPredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isOperatorConfigMap(e.Object, r.watchNamespace)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isOperatorConfigMap(e.ObjectNew, r.watchNamespace)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isOperatorConfigMap(e.Object, r.watchNamespace)
+		},
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&core.Node{}, builder.WithPredicates(nodePredicate)).
+		Watches(&source.Kind{Type: &mapi.Machine{}}, handler.EnqueueRequestsFromMapFunc(mapMachineToNode),
+			builder.WithPredicates(machinePredicate)).
+		Watches(&source.Kind{Type: &mapi.MachineSet{}}, handler.EnqueueRequestsFromMapFunc(r.mapMachineSetToNodes),
+			builder.WithPredicates(machineSetPredicate)).
+		Watches(source.NewKindWithCache(&core.ConfigMap{}, r.namespacedCache),
+			handler.EnqueueRequestsFromMapFunc(r.mapToConfiguredWindowsNodes), builder.WithPredicates(configPredicate)).
+		WithOptions(options).
+		Complete(r)
+}
+
+// isConfiguredWindowsNode returns true if the given object is a Windows node which has been configured by WMCO
+func isConfiguredWindowsNode(obj client.Object) bool {
+	if obj.GetLabels()[core.LabelOSStable] != "windows" {
+		return false
+	}
+	_, present := obj.GetAnnotations()[nodeconfig.VersionAnnotation]
+	return present
+}
+
+// watchedAnnotations returns the annotations of the given node the node controller reacts to. The annotations within
+// the domain of WMCO, in which it records the state of the node such as the times of its periodic checks, are left
+// out, apart from the ones telling whether the node is configured and managed by WMCO.
+func watchedAnnotations(node *core.Node) map[string]string {
+	watched := make(map[string]string)
+	for key, value := range node.GetAnnotations() {
+		if isOperatorKey(key) && key != nodeconfig.VersionAnnotation && key != ExcludedAnnotation {
+			continue
+		}
+		watched[key] = value
+	}
+	return watched
+}
+
+// mapMachineToNode maps the given Machine to its node
+func mapMachineToNode(object client.Object) []reconcile.Request {
+	machine, ok := object.(*mapi.Machine)
+	if !ok || machine.Status.NodeRef == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: machine.Status.NodeRef.Name}}}
+}
+
+// mapMachineSetToNodes maps the given MachineSet to the nodes of the Machines it owns
+func (r *NodeReconciler) mapMachineSetToNodes(object client.Object) []reconcile.Request {
+	machines := &mapi.MachineList{}
+	err := r.client.List(context.TODO(), machines, client.InNamespace(object.GetNamespace()),
+		client.MatchingLabels{machineSetLabel: object.GetName()})
+	if err != nil {
+		r.log.Error(err, "could not get a list of machines", "machineset", object.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range machines.Items {
+		requests = append(requests, mapMachineToNode(&machines.Items[i])...)
+	}
+	return requests
+}
+
+// mapToConfiguredWindowsNodes maps the given object to all Windows nodes configured by WMCO
+func (r *NodeReconciler) mapToConfiguredWindowsNodes(object client.Object) []reconcile.Request {
+	nodes := &core.NodeList{}
+	if err := r.client.List(context.TODO(), nodes, client.MatchingLabels{core.LabelOSStable: "windows"}); err != nil {
+		r.log.Error(err, "could not get a list of Windows nodes")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range nodes.Items {
+		if isConfiguredWindowsNode(&nodes.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name: nodes.Items[i].GetName()}})
+		}
+	}
+	return requests
+}
+
+// Reconcile ensures the Windows node has the labels, annotations and taints defined for its Machine, deleting the node
+// if the Machine no longer exists. Nodes excluded from the management of WMCO are left untouched. The node and the
+// operator configuration are read from the caches of the manager, an update based on a stale node failing with a
+// conflict which is retried.
+func (r *NodeReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	log := r.log.WithValues("node", request.Name)
+
+	node := &core.Node{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: request.Name}, node); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get node %s", request.Name)
+	}
+	if !isConfiguredWindowsNode(node) {
+		// The labels and taints of nodes being configured are applied by the WindowsMachineReconciler
+		return ctrl.Result{}, nil
+	}
+
+	// The Machine API links nodes to their Machines through an annotation
+	machineRef := strings.SplitN(node.GetAnnotations()[machineAnnotation], "/", 2)
+	if len(machineRef) != 2 {
+		log.V(1).Info("node is not associated with a Machine")
+		return ctrl.Result{}, nil
+	}
+	config, err := operatorconfig.LoadFromCache(ctx, r.namespacedCache, r.watchNamespace, r.defaultConfig)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to load operator configuration")
+	}
+	machine := &mapi.Machine{}
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: machineRef[0], Name: machineRef[1]}, machine)
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return r.removeOrphanedNode(ctx, node, config.DryRun)
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get Machine %s/%s", machineRef[0], machineRef[1])
+	}
+	if isExcluded(machine, node) {
+		log.V(1).Info("node excluded from management", "annotation", ExcludedAnnotation)
+		return ctrl.Result{}, nil
+	}
+	labels, taints, err := getDesiredNodeMetadata(ctx, r.client, machine, config.NodeTaints)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get labels and taints for node %s", node.GetName())
+	}
+	annotations, err := getDesiredNodeAnnotations(ctx, r.client, machine)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get annotations for node %s", node.GetName())
+	}
+	changed := nodeconfig.SyncNodeMetadata(node, labels, taints)
+	changed = nodeconfig.SyncNodeAnnotations(node, annotations) || changed
+	// The topology labels not set by the cloud provider integration are derived from the Machine, once the external
+	// cloud controller manager, which sets them, initialized the node
+	topology := nodeconfig.TopologyLabels(r.platform, machine.GetLabels())
+	if !nodeconfig.HasTaint(node.Spec.Taints, nodeconfig.CloudProviderUninitializedTaint) &&
+		nodeconfig.AddTopologyLabels(node, topology) {
+		log.Info("added topology labels", "topology", topology)
+		changed = true
+	}
+	excluded := false
+	if nodeconfig.IsEgressAssignable(node) {
+		if config.DryRun {
+			reportDryRun(r.recorder, r.log, node, "EgressIPExclusion", "Label %s would be removed from node %s, as "+
+				"Windows nodes cannot host egress IPs", nodeconfig.EgressAssignableLabel, node.GetName())
+		} else {
+			excluded = nodeconfig.ExcludeFromEgressIP(node)
+		}
+	}
+	if !changed && !excluded {
+		return ctrl.Result{}, nil
+	}
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "error updating metadata of node %s", node.GetName())
+	}
+	log.Info("synced node metadata", "labels", labels, "annotations", annotations, "taints", taints)
+	if excluded {
+		r.recorder.Eventf(node, core.EventTypeWarning, "EgressIPExcluded", "Removed label %s from node %s, as "+
+			"Windows nodes cannot host egress IPs", nodeconfig.EgressAssignableLabel, node.GetName())
+	}
+	return ctrl.Result{}, nil
+}
+
+// removeOrphanedNode deletes the given node, whose Machine no longer exists, once it has been NotReady for the grace
+// period. The deletion is only reported in dry-run mode, and nodes excluded from the management of WMCO are kept.
+func (r *NodeReconciler) removeOrphanedNode(ctx context.Context, node *core.Node, dryRun bool) (ctrl.Result, error) {
+	if _, excluded := node.GetAnnotations()[ExcludedAnnotation]; excluded {
+		r.log.Info("keeping orphaned node excluded from management", "node", node.GetName(),
+			"annotation", ExcludedAnnotation)
+		return ctrl.Result{}, nil
+	}
+	if isNodeReady(node) {
+		// The instance is still running, the node will become NotReady if it is terminated
+		return ctrl.Result{}, nil
+	}
+	if remaining := orphanedNodeGracePeriod - getNotReadyDuration(node); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	if dryRun {
+		reportDryRun(r.recorder, r.log, node, "OrphanedNodeDeletion", "Node %s would be deleted as its Machine %s no "+
+			"longer exists", node.GetName(), node.GetAnnotations()[machineAnnotation])
+		return ctrl.Result{}, nil
+	}
+	r.log.Info("deleting orphaned node", "node", node.GetName(), "machine", node.GetAnnotations()[machineAnnotation])
+	err := r.k8sclientset.CoreV1().Nodes().Delete(ctx, node.GetName(), meta.DeleteOptions{})
+	if err != nil && !k8sapierrors.IsNotFound(err) {
+		return ctrl.Result{}, errors.Wrapf(err, "error deleting orphaned node %s", node.GetName())
+	}
+	r.recorder.Eventf(node, core.EventTypeNormal, "OrphanedNodeDeleted",
+		"Deleted node %s as its Machine %s no longer exists", node.GetName(), node.GetAnnotations()[machineAnnotation])
+	return ctrl.Result{}, nil
+}
+
+// getNotReadyDuration returns the time the given node has not been Ready for
+func getNotReadyDuration(node *core.Node) time.Duration {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == core.NodeReady {
+			if condition.Status == core.ConditionTrue {
+				return 0
+			}
+			return time.Since(condition.LastTransitionTime.Time)
+		}
+	}
+	// The node has never reported its status
+	return time.Since(node.GetCreationTimestamp().Time)
+}
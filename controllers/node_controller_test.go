@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+func TestWatchedAnnotations(t *testing.T) {
+	node := &core.Node{ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{
+		machineAnnotation:                  "openshift-machine-api/windows-1",
+		nodeconfig.VersionAnnotation:       "2.0.0",
+		ExcludedAnnotation:                 "",
+		"example.com/team":                 "a",
+		ClockSkewCheckedAnnotation:         "2026-10-16T12:00:00Z",
+		nodeconfig.ManagedLabelsAnnotation: "team",
+	}}}
+	assert.Equal(t, map[string]string{machineAnnotation: "openshift-machine-api/windows-1",
+		nodeconfig.VersionAnnotation: "2.0.0", ExcludedAnnotation: "", "example.com/team": "a"},
+		watchedAnnotations(node))
+}
+
+func TestRemoveOrphanedNode(t *testing.T) {
+	notReady := func(since time.Duration) []core.NodeCondition {
+		return []core.NodeCondition{{Type: core.NodeReady, Status: core.ConditionFalse,
+			LastTransitionTime: meta.NewTime(time.Now().Add(-since))}}
+	}
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		conditions    []core.NodeCondition
+		age           time.Duration
+		dryRun        bool
+		expectRequeue bool
+		expectedEvent string
+	}{
+		{
+			name:       "ready",
+			conditions: []core.NodeCondition{{Type: core.NodeReady, Status: core.ConditionTrue}},
+			age:        time.Hour,
+		},
+		{
+			name:        "excluded",
+			annotations: map[string]string{ExcludedAnnotation: ""},
+			conditions:  notReady(time.Hour),
+			age:         time.Hour,
+		},
+		{
+			name:          "grace period not elapsed",
+			conditions:    notReady(time.Minute),
+			age:           time.Hour,
+			expectRequeue: true,
+		},
+		{
+			name:          "status never reported within the grace period",
+			age:           time.Minute,
+			expectRequeue: true,
+		},
+		{
+			name:          "dry run",
+			conditions:    notReady(time.Hour),
+			age:           time.Hour,
+			dryRun:        true,
+			expectedEvent: "Normal DryRunOrphanedNodeDeletion Node windows-node would be deleted",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			annotations := map[string]string{machineAnnotation: "openshift-machine-api/windows-1"}
+			for key, value := range test.annotations {
+				annotations[key] = value
+			}
+			node := &core.Node{
+				ObjectMeta: meta.ObjectMeta{Name: "windows-node", Annotations: annotations,
+					CreationTimestamp: meta.NewTime(time.Now().Add(-test.age))},
+				Status: core.NodeStatus{Conditions: test.conditions},
+			}
+			fake := record.NewFakeRecorder(1)
+			// The nodes which must be kept are returned before the kube client, left nil, is used
+			r := &NodeReconciler{log: ctrl.Log.WithName("test"), recorder: fake}
+			result, err := r.removeOrphanedNode(context.TODO(), node, test.dryRun)
+			require.NoError(t, err)
+			if test.expectRequeue {
+				assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= orphanedNodeGracePeriod,
+					"requeue after %s", result.RequeueAfter)
+			} else {
+				assert.Zero(t, result.RequeueAfter)
+			}
+			select {
+			case event := <-fake.Events:
+				assert.True(t, test.expectedEvent != "" && strings.HasPrefix(event, test.expectedEvent), event)
+			default:
+				assert.Empty(t, test.expectedEvent)
+			}
+		})
+	}
+}
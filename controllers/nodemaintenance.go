@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+)
+
+// nodeMaintenanceDelay is the delay after which disruptive operations held by the maintenance of a node are attempted
+// again, as the end of the maintenance is not watched
+const nodeMaintenanceDelay = 5 * time.Minute
+
+// nodeMaintenanceListGVKs are the kinds of the lists of the NodeMaintenance objects through which administrators
+// declare nodes under maintenance with the Node Maintenance Operator, in its current and legacy API groups
+var nodeMaintenanceListGVKs = []schema.GroupVersionKind{
+	{Group: "nodemaintenance.medik8s.io", Version: "v1beta1", Kind: "NodeMaintenanceList"},
+	{Group: "nodemaintenance.kubevirt.io", Version: "v1beta1", Kind: "NodeMaintenanceList"},
+}
+
+// nodeMaintenanceTaints are the keys of the taints applied by the Node Maintenance Operator to the nodes it drains, in
+// its current and legacy versions
+var nodeMaintenanceTaints = []string{"medik8s.io/drain", "kubevirt.io/drain"}
+
+// getNodeMaintenance returns a description of the maintenance the node of the given Machine is under, declared by a
+// NodeMaintenance or signaled by the taint the Node Maintenance Operator drains it with, or an empty string if the
+// node is not under maintenance
+func (r *WindowsMachineReconciler) getNodeMaintenance(ctx context.Context, machine *mapi.Machine) (string, error) {
+	if machine.Status.NodeRef == nil {
+		return "", nil
+	}
+	nodeName := machine.Status.NodeRef.Name
+	node := &core.Node{}
+	if err := r.client.Get(ctx, kubeTypes.NamespacedName{Name: nodeName}, node); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "could not get node %s", nodeName)
+	}
+	if taint := maintenanceTaint(node); taint != "" {
+		return "taint " + taint, nil
+	}
+	for _, gvk := range nodeMaintenanceListGVKs {
+		// The NodeMaintenances are read from the API server, as they are not watched
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		if err := r.apiReader.List(ctx, list); err != nil {
+			if apimeta.IsNoMatchError(err) || k8sapierrors.IsNotFound(err) {
+				// The Node Maintenance Operator is not installed
+				continue
+			}
+			return "", errors.Wrapf(err, "unable to list %s NodeMaintenances", gvk.Group)
+		}
+		if name := declaredMaintenance(list.Items, nodeName); name != "" {
+			return "NodeMaintenance " + name, nil
+		}
+	}
+	return "", nil
+}
+
+// isHeldForNodeMaintenance returns true if the node of the given Machine is under maintenance, reporting it with an
+// event. The operations disrupting the node, including the remediations which do not wait for maintenance windows,
+// are then held and attempted again after nodeMaintenanceDelay.
+func (r *WindowsMachineReconciler) isHeldForNodeMaintenance(ctx context.Context, machine *mapi.Machine) (bool, error) {
+	nodeMaintenance, err := r.getNodeMaintenance(ctx, machine)
+	if err != nil {
+		return false, err
+	}
+	if nodeMaintenance == "" {
+		return false, nil
+	}
+	r.recorder.Eventf(machine, core.EventTypeNormal, "DisruptionHeldForNodeMaintenance",
+		"Disruptive operations on Machine %s are held while its node is under maintenance: %s", machine.GetName(),
+		nodeMaintenance)
+	return true, nil
+}
+
+// maintenanceTaint returns the key of the Node Maintenance Operator taint of the given node, empty if it has none
+func maintenanceTaint(node *core.Node) string {
+	for _, taint := range node.Spec.Taints {
+		for _, key := range nodeMaintenanceTaints {
+			if taint.Key == key {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
+// declaredMaintenance returns the name of the given NodeMaintenance putting the node with the given name under
+// maintenance, empty if none does. A NodeMaintenance being deleted ends the maintenance.
+func declaredMaintenance(nodeMaintenances []unstructured.Unstructured, nodeName string) string {
+	for _, nodeMaintenance := range nodeMaintenances {
+		name, _, _ := unstructured.NestedString(nodeMaintenance.Object, "spec", "nodeName")
+		if name == nodeName && nodeMaintenance.GetDeletionTimestamp().IsZero() {
+			return nodeMaintenance.GetName()
+		}
+	}
+	return ""
+}
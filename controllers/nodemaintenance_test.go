@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMaintenanceTaint(t *testing.T) {
+	node := &core.Node{Spec: core.NodeSpec{Taints: []core.Taint{
+		{Key: "node.kubernetes.io/unschedulable", Effect: core.TaintEffectNoSchedule}}}}
+	assert.Equal(t, "", maintenanceTaint(node))
+	node.Spec.Taints = append(node.Spec.Taints, core.Taint{Key: "medik8s.io/drain", Effect: core.TaintEffectNoSchedule})
+	assert.Equal(t, "medik8s.io/drain", maintenanceTaint(node))
+}
+
+func TestDeclaredMaintenance(t *testing.T) {
+	nodeMaintenance := func(name, nodeName string, deleted bool) unstructured.Unstructured {
+		item := unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"nodeName": nodeName}}}
+		item.SetName(name)
+		if deleted {
+			now := meta.Now()
+			item.SetDeletionTimestamp(&now)
+		}
+		return item
+	}
+	items := []unstructured.Unstructured{nodeMaintenance("other", "node-b", false),
+		nodeMaintenance("ended", "node-a", true)}
+	assert.Equal(t, "", declaredMaintenance(items, "node-a"))
+	items = append(items, nodeMaintenance("maintenance", "node-a", false))
+	assert.Equal(t, "maintenance", declaredMaintenance(items, "node-a"))
+}
@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+// nodeMetadataConfigMap is the name of the ConfigMap, in the operator namespace, which holds the metadata of nodes
+// whose Machines were deleted by WMCO. The metadata is restored on the nodes replacing them.
+const nodeMetadataConfigMap = "windows-node-metadata"
+
+// azureProviderIDPrefix is the prefix of the provider IDs of Azure instances
+const azureProviderIDPrefix = "azure://"
+
+// systemDomains are the domains of label, annotation and taint keys which are managed by the system rather than added
+// by users. Keys within these domains or any of their subdomains are not preserved across machine recreation.
+var systemDomains = []string{
+	"kubernetes.io",
+	"k8s.io",
+	"k8s.ovn.org",
+	"openshift.io",
+}
+
+// operatorDomain is the domain of the annotations WMCO records the state of nodes in
+const operatorDomain = "windowsmachineconfig.openshift.io"
+
+// nodeMetadata is the user-added metadata of a node
+type nodeMetadata struct {
+	// MachineSet is the name of the MachineSet which owned the deleted Machine
+	MachineSet string `json:"machineSet"`
+	// Timestamp is the time the metadata was saved
+	Timestamp meta.Time `json:"timestamp"`
+	// Labels are the user-added labels
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are the user-added annotations
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Taints are the user-added taints
+	Taints []core.Taint `json:"taints,omitempty"`
+}
+
+// isSystemKey returns true if the given label, annotation or taint key is managed by the system
+func isSystemKey(key string) bool {
+	i := strings.Index(key, "/")
+	if i == -1 {
+		return false
+	}
+	domain := key[:i]
+	for _, systemDomain := range systemDomains {
+		if domain == systemDomain || strings.HasSuffix(domain, "."+systemDomain) {
+			return true
+		}
+	}
+	return false
+}
+
+// getUserMetadata returns the metadata on the given node which was neither added by the system nor is part of the
+// given Machine's spec or of the labels, annotations and taints managed by WMCO
+func getUserMetadata(node *core.Node, machine *mapi.Machine) *nodeMetadata {
+	metadata := &nodeMetadata{Labels: map[string]string{}, Annotations: map[string]string{}}
+	managedLabels := make(map[string]bool)
+	for _, key := range strings.Split(node.GetAnnotations()[nodeconfig.ManagedLabelsAnnotation], ",") {
+		managedLabels[key] = true
+	}
+	managedTaints := make(map[string]bool)
+	for _, key := range strings.Split(node.GetAnnotations()[nodeconfig.ManagedTaintsAnnotation], ",") {
+		managedTaints[key] = true
+	}
+	managedAnnotations := make(map[string]bool)
+	for _, key := range strings.Split(node.GetAnnotations()[nodeconfig.ManagedAnnotationsAnnotation], ",") {
+		managedAnnotations[key] = true
+	}
+	for key, value := range node.GetLabels() {
+		if _, present := machine.Spec.ObjectMeta.Labels[key]; present || managedLabels[key] || isSystemKey(key) {
+			continue
+		}
+		metadata.Labels[key] = value
+	}
+	for key, value := range node.GetAnnotations() {
+		if _, present := machine.Spec.ObjectMeta.Annotations[key]; present || managedAnnotations[key] ||
+			isSystemKey(key) {
+			continue
+		}
+		metadata.Annotations[key] = value
+	}
+	for _, taint := range node.Spec.Taints {
+		if isSystemKey(taint.Key) || nodeconfig.HasTaint(machine.Spec.Taints, taint) ||
+			managedTaints[taint.Key+":"+string(taint.Effect)] {
+			continue
+		}
+		taint.TimeAdded = nil
+		metadata.Taints = append(metadata.Taints, taint)
+	}
+	return metadata
+}
+
+// getMachineSetName returns the name of the MachineSet owning the given Machine, or an empty string if the Machine
+// is not owned by a MachineSet
+func getMachineSetName(machine *mapi.Machine) string {
+	for _, owner := range machine.GetOwnerReferences() {
+		if owner.Kind == "MachineSet" {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// getNodeMetadataConfigMap returns the ConfigMap holding saved node metadata, creating it if it does not exist
+func (r *WindowsMachineReconciler) getNodeMetadataConfigMap(ctx context.Context) (*core.ConfigMap, error) {
+	cm, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Get(ctx, nodeMetadataConfigMap, meta.GetOptions{})
+	if err == nil {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		return cm, nil
+	}
+	if !k8sapierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "error getting %s ConfigMap", nodeMetadataConfigMap)
+	}
+	cm = &core.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{Name: nodeMetadataConfigMap, Namespace: r.watchNamespace},
+		Data:       map[string]string{},
+	}
+	cm, err = r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Create(ctx, cm, meta.CreateOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating %s ConfigMap", nodeMetadataConfigMap)
+	}
+	cm.Data = map[string]string{}
+	return cm, nil
+}
+
+// saveNodeMetadata saves the user-added metadata of the given Machine's node, so that it can be restored on the node
+// replacing it once the Machine is deleted
+func (r *WindowsMachineReconciler) saveNodeMetadata(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) error {
+	machineSet := getMachineSetName(machine)
+	if machineSet == "" {
+		// There will be no replacement node to restore the metadata on
+		return nil
+	}
+	metadata := getUserMetadata(node, machine)
+	if len(metadata.Labels) == 0 && len(metadata.Annotations) == 0 && len(metadata.Taints) == 0 {
+		return nil
+	}
+	metadata.MachineSet = machineSet
+	metadata.Timestamp = meta.Now()
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling node metadata")
+	}
+
+	cm, err := r.getNodeMetadataConfigMap(ctx)
+	if err != nil {
+		return err
+	}
+	cm.Data[machine.GetName()] = string(data)
+	if _, err = r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Update(ctx, cm, meta.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "error updating %s ConfigMap", nodeMetadataConfigMap)
+	}
+	r.log.Info("saved node metadata", "machine", machine.GetName(), "node", node.GetName())
+	return nil
+}
+
+// restoreNodeMetadata applies the oldest metadata saved from a node of the same MachineSet to the node of the given
+// Machine
+func (r *WindowsMachineReconciler) restoreNodeMetadata(ctx context.Context, machine *mapi.Machine) error {
+	machineSet := getMachineSetName(machine)
+	if machineSet == "" || machine.Spec.ProviderID == nil {
+		return nil
+	}
+	cm, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Get(ctx, nodeMetadataConfigMap, meta.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "error getting %s ConfigMap", nodeMetadataConfigMap)
+	}
+
+	// Find the oldest metadata saved for the MachineSet
+	var keys []string
+	saved := make(map[string]*nodeMetadata)
+	for key, data := range cm.Data {
+		metadata := &nodeMetadata{}
+		if err := json.Unmarshal([]byte(data), metadata); err != nil {
+			r.log.Error(err, "ignoring invalid node metadata", "key", key)
+			continue
+		}
+		if metadata.MachineSet != machineSet {
+			continue
+		}
+		keys = append(keys, key)
+		saved[key] = metadata
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return saved[keys[i]].Timestamp.Before(&saved[keys[j]].Timestamp)
+	})
+	key := keys[0]
+	metadata := saved[key]
+
+	node, err := r.getNodeByProviderID(ctx, *machine.Spec.ProviderID)
+	if err != nil {
+		return err
+	}
+	// The labels and annotations are applied without taking over the ones set by other controllers, which are left
+	// untouched along with the rest of the saved metadata
+	applied, err := apply.NodeMetadata(ctx, r.k8sclientset, node.GetName(), metadata.Labels, metadata.Annotations)
+	if err != nil {
+		if !k8sapierrors.IsConflict(errors.Cause(err)) {
+			return errors.Wrapf(err, "error restoring metadata on node %s", node.GetName())
+		}
+		r.log.Info("node metadata conflicts with another field manager, not restoring it", "node", node.GetName(),
+			"source machine", key, "error", err.Error())
+		r.recorder.Eventf(machine, core.EventTypeWarning, "NodeMetadataConflict",
+			"Metadata of the node of deleted Machine %s conflicts with the metadata set by another controller: %v",
+			key, err)
+		return r.deleteSavedNodeMetadata(ctx, cm, key)
+	}
+	node = applied
+	// Taints are an atomic list, which cannot be applied without taking over the taints set by other controllers
+	var taints []core.Taint
+	for _, taint := range metadata.Taints {
+		if !nodeconfig.HasTaint(node.Spec.Taints, taint) {
+			taints = append(taints, taint)
+		}
+	}
+	if len(taints) > 0 {
+		node.Spec.Taints = append(node.Spec.Taints, taints...)
+		if _, err = r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+			meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+			return errors.Wrapf(err, "error restoring taints on node %s", node.GetName())
+		}
+	}
+
+	if err := r.deleteSavedNodeMetadata(ctx, cm, key); err != nil {
+		return err
+	}
+	r.log.Info("restored node metadata", "node", node.GetName(), "source machine", key)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "NodeMetadataRestored",
+		"Restored labels, annotations and taints of the node of deleted Machine %s on node %s", key, node.GetName())
+	return nil
+}
+
+// deleteSavedNodeMetadata removes the metadata saved with the given key from the given node metadata ConfigMap
+func (r *WindowsMachineReconciler) deleteSavedNodeMetadata(ctx context.Context, cm *core.ConfigMap,
+	key string) error {
+	delete(cm.Data, key)
+	if _, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Update(ctx, cm, meta.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "error updating %s ConfigMap", nodeMetadataConfigMap)
+	}
+	return nil
+}
+
+// getNodeByProviderID returns the Windows node with the given provider ID
+func (r *WindowsMachineReconciler) getNodeByProviderID(ctx context.Context, providerID string) (*core.Node, error) {
+	nodes, err := r.k8sclientset.CoreV1().Nodes().List(ctx, meta.ListOptions{LabelSelector: nodeconfig.WindowsOSLabel})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing Windows nodes")
+	}
+	for i, node := range nodes.Items {
+		if sameProviderID(node.Spec.ProviderID, providerID) {
+			return &nodes.Items[i], nil
+		}
+	}
+	return nil, errors.Errorf("unable to find node with provider ID %s", providerID)
+}
+
+// sameProviderID returns true if the given provider IDs identify the same instance. Azure provider IDs are Azure
+// Resource Manager resource IDs, which are case insensitive, and whose resource group is lowercased in the provider
+// ID of the nodes on some Azure environments, such as Azure Stack Hub.
+func sameProviderID(a, b string) bool {
+	if strings.HasPrefix(a, azureProviderIDPrefix) && strings.HasPrefix(b, azureProviderIDPrefix) {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// getDesiredNodeAnnotations returns the annotations which must be present on the node of the given Machine. These are
+// the annotations in the spec of the Machine and of the template of the MachineSet owning it, which takes precedence,
+// leaving out the annotations within the domain of WMCO, which records its own state in them.
+func getDesiredNodeAnnotations(ctx context.Context, c client.Client, machine *mapi.Machine) (map[string]string,
+	error) {
+	annotations := make(map[string]string)
+	for key, value := range machine.Spec.ObjectMeta.Annotations {
+		annotations[key] = value
+	}
+	if machineSetName := getMachineSetName(machine); machineSetName != "" {
+		machineSet := &mapi.MachineSet{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: machine.GetNamespace(), Name: machineSetName}, machineSet)
+		if err != nil && !k8sapierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "unable to get MachineSet %s", machineSetName)
+		}
+		for key, value := range machineSet.Spec.Template.Spec.ObjectMeta.Annotations {
+			annotations[key] = value
+		}
+	}
+	for key := range annotations {
+		if isOperatorKey(key) {
+			delete(annotations, key)
+		}
+	}
+	return annotations, nil
+}
+
+// isOperatorKey returns true if the given annotation key is within the domain of WMCO
+func isOperatorKey(key string) bool {
+	return strings.HasPrefix(key, operatorDomain+"/")
+}
+
+// getDesiredNodeMetadata returns the labels and taints which must be present on the node of the given Machine. These
+// are the labels and taints in the spec of the Machine and of the template of the MachineSet owning it, along with the
+// given taints applied to all Windows nodes and the label telling that Windows nodes cannot host egress IPs. The
+// MachineSet template takes precedence, as changes to it are not propagated to existing Machines.
+func getDesiredNodeMetadata(ctx context.Context, c client.Client, machine *mapi.Machine,
+	nodeTaints []core.Taint) (map[string]string, []core.Taint, error) {
+	labels := make(map[string]string)
+	for key, value := range machine.Spec.ObjectMeta.Labels {
+		labels[key] = value
+	}
+	labels[nodeconfig.EgressIPCapableLabel] = "false"
+	var taints []core.Taint
+	for _, taint := range append(append([]core.Taint{}, nodeTaints...), machine.Spec.Taints...) {
+		if !nodeconfig.HasTaint(taints, taint) {
+			taints = append(taints, taint)
+		}
+	}
+
+	machineSetName := getMachineSetName(machine)
+	if machineSetName == "" {
+		return labels, taints, nil
+	}
+	machineSet := &mapi.MachineSet{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: machine.GetNamespace(), Name: machineSetName}, machineSet)
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return labels, taints, nil
+		}
+		return nil, nil, errors.Wrapf(err, "unable to get MachineSet %s", machineSetName)
+	}
+	for key, value := range machineSet.Spec.Template.Spec.ObjectMeta.Labels {
+		labels[key] = value
+	}
+	for _, taint := range machineSet.Spec.Template.Spec.Taints {
+		if !nodeconfig.HasTaint(taints, taint) {
+			taints = append(taints, taint)
+		}
+	}
+	return labels, taints, nil
+}
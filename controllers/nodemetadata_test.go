@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+func TestGetUserMetadata(t *testing.T) {
+	machine := &mapi.Machine{}
+	machine.Spec.ObjectMeta.Labels = map[string]string{"from-machine-spec": ""}
+	machine.Spec.Taints = []core.Taint{{Key: "machine-taint", Effect: core.TaintEffectNoSchedule}}
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{
+			Labels: map[string]string{
+				"from-machine-spec":              "",
+				"node-role.kubernetes.io/worker": "",
+				"node.openshift.io/os_id":        "Windows",
+				"topology.kubernetes.io/zone":    "us-east-1a",
+				"example.com/team":               "payments",
+				"app":                            "iis",
+			},
+			Annotations: map[string]string{
+				"windowsmachineconfig.openshift.io/version": "3.0.0",
+				"k8s.ovn.org/hybrid-overlay-node-subnet":    "10.132.0.0/24",
+				"example.com/owner":                         "jdoe",
+				"example.com/synced":                        "from-machineset",
+				nodeconfig.ManagedAnnotationsAnnotation:     "example.com/synced",
+			},
+		},
+		Spec: core.NodeSpec{
+			Taints: []core.Taint{
+				{Key: "machine-taint", Effect: core.TaintEffectNoSchedule},
+				{Key: "node.kubernetes.io/unreachable", Effect: core.TaintEffectNoExecute},
+				{Key: "dedicated", Value: "iis", Effect: core.TaintEffectNoSchedule},
+			},
+		},
+	}
+
+	metadata := getUserMetadata(node, machine)
+	assert.Equal(t, map[string]string{"example.com/team": "payments", "app": "iis"}, metadata.Labels)
+	assert.Equal(t, map[string]string{"example.com/owner": "jdoe"}, metadata.Annotations)
+	assert.Equal(t, []core.Taint{{Key: "dedicated", Value: "iis", Effect: core.TaintEffectNoSchedule}}, metadata.Taints)
+}
+
+func TestGetDesiredNodeAnnotations(t *testing.T) {
+	machine := &mapi.Machine{}
+	machine.Spec.ObjectMeta.Annotations = map[string]string{"example.com/owner": "jdoe",
+		nodeconfig.VersionAnnotation: "1.0.0", ExcludedAnnotation: ""}
+	// The Machine is not owned by a MachineSet, so the client is not used
+	annotations, err := getDesiredNodeAnnotations(context.TODO(), nil, machine)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"example.com/owner": "jdoe"}, annotations)
+}
+
+func TestSameProviderID(t *testing.T) {
+	assert.True(t, sameProviderID("azure:///subscriptions/s/resourceGroups/Cluster-RG/providers/"+
+		"Microsoft.Compute/virtualMachines/winworker-abcde",
+		"azure:///subscriptions/s/resourcegroups/cluster-rg/providers/Microsoft.Compute/virtualMachines/"+
+			"winworker-abcde"))
+	assert.False(t, sameProviderID("azure:///subscriptions/s/resourceGroups/rg/providers/"+
+		"Microsoft.Compute/virtualMachines/a", "azure:///subscriptions/s/resourceGroups/rg/providers/"+
+		"Microsoft.Compute/virtualMachines/b"))
+	assert.False(t, sameProviderID("aws:///us-east-1a/i-0ABC", "aws:///us-east-1a/i-0abc"))
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+const (
+	// operatorConditionNameEnv is the environment variable set by OLM to the name of the OperatorCondition of the
+	// operator
+	operatorConditionNameEnv = "OPERATOR_CONDITION_NAME"
+	// upgradeableCondition is the type of the OperatorCondition condition telling OLM if the operator can be upgraded
+	upgradeableCondition = "Upgradeable"
+	// operatorConditionPeriod is the interval at which the Upgradeable condition is reported
+	operatorConditionPeriod = time.Minute
+)
+
+// operatorConditionGVK is the kind of the OLM object through which operators report their conditions to OLM
+var operatorConditionGVK = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1",
+	Kind: "OperatorCondition"}
+
+// operatorConditionSpec is the part of the OperatorCondition spec set by the operator
+type operatorConditionSpec struct {
+	Conditions []meta.Condition `json:"conditions,omitempty"`
+}
+
+// ReportUpgradeable periodically reports through the OperatorCondition of the operator that it must not be upgraded
+// by OLM while Windows nodes are being configured, recreated or pending upgrade, so that a fleet rollout is not
+// interrupted by an operator upgrade, and nodes kept within the version skew tolerance are not left behind by two. It
+// returns once the given context is done, and immediately if the operator is not managed by OLM.
+func (r *WindowsMachineReconciler) ReportUpgradeable(ctx context.Context) error {
+	name := os.Getenv(operatorConditionNameEnv)
+	if name == "" {
+		r.log.Info("operator not installed by OLM, not reporting the Upgradeable condition",
+			"env", operatorConditionNameEnv)
+		return nil
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.reportUpgradeable(ctx, name); err != nil {
+			r.log.Error(err, "unable to report the Upgradeable condition", "operatorcondition", name)
+		}
+	}, operatorConditionPeriod)
+	return nil
+}
+
+// reportUpgradeable sets the Upgradeable condition of the OperatorCondition with the given name according to the
+// Windows Machines currently being rolled out
+func (r *WindowsMachineReconciler) reportUpgradeable(ctx context.Context, name string) error {
+	config, err := operatorconfig.Load(ctx, r.k8sclientset, r.watchNamespace, r.defaultConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to load operator configuration")
+	}
+	rollingOut, err := r.getRollingOutMachines(ctx, config)
+	if err != nil {
+		return err
+	}
+	condition := meta.Condition{Type: upgradeableCondition, Status: meta.ConditionTrue, Reason: "AsExpected",
+		Message: "No Windows node is being configured, recreated or pending upgrade"}
+	if len(rollingOut) > 0 {
+		condition.Status = meta.ConditionFalse
+		condition.Reason = "WindowsNodeRollout"
+		condition.Message = fmt.Sprintf("Windows nodes are being configured, recreated or pending upgrade: %s",
+			strings.Join(rollingOut, ", "))
+	}
+	return r.setOperatorCondition(ctx, name, condition)
+}
+
+// ReportCondition sets the given informational condition in the OperatorCondition of the operator, so that it is
+// visible to cluster administrators alongside the Upgradeable condition. It is not reported if the operator is not
+// managed by OLM.
+func (r *WindowsMachineReconciler) ReportCondition(ctx context.Context, condition meta.Condition) error {
+	name := os.Getenv(operatorConditionNameEnv)
+	if name == "" {
+		return nil
+	}
+	return r.setOperatorCondition(ctx, name, condition)
+}
+
+// managedMachine is a Windows Machine managed by WMCO, along with its node, nil until it is registered
+type managedMachine struct {
+	machine *mapi.Machine
+	node    *core.Node
+}
+
+// getManagedMachines returns the Windows Machines managed with the given configuration, along with their nodes
+func (r *WindowsMachineReconciler) getManagedMachines(ctx context.Context,
+	config *operatorconfig.Config) ([]managedMachine, error) {
+	machines, err := listWindowsMachines(ctx, r.client)
+	if err != nil {
+		return nil, err
+	}
+	var managed []managedMachine
+	for i := range machines {
+		machine := &machines[i]
+		if !config.ManagesMachine(machine.GetLabels()) {
+			continue
+		}
+		var node *core.Node
+		if machine.Status.NodeRef != nil {
+			node = &core.Node{}
+			if err := r.client.Get(ctx, kubeTypes.NamespacedName{Name: machine.Status.NodeRef.Name},
+				node); err != nil {
+				if !k8sapierrors.IsNotFound(err) {
+					return nil, errors.Wrapf(err, "could not get node associated with machine %s",
+						machine.GetName())
+				}
+				node = nil
+			}
+		}
+		managed = append(managed, managedMachine{machine: machine, node: node})
+	}
+	return managed, nil
+}
+
+// getRollingOutMachines returns a description of each Windows Machine managed with the given configuration which is
+// being deleted, configured or is to be recreated with the current operator version
+func (r *WindowsMachineReconciler) getRollingOutMachines(ctx context.Context,
+	config *operatorconfig.Config) ([]string, error) {
+	managed, err := r.getManagedMachines(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	var rollingOut []string
+	for _, m := range managed {
+		if state := machineRolloutState(m.machine, m.node, config); state != "" {
+			rollingOut = append(rollingOut, fmt.Sprintf("%s (%s)", m.machine.GetName(), state))
+		}
+	}
+	sort.Strings(rollingOut)
+	return rollingOut, nil
+}
+
+// machineRolloutState returns the stage of the rollout the given Windows Machine, with the given node, is at, or an
+// empty string if the Machine is not being rolled out. Failed Machines are not rolled out until they are replaced, and
+// excluded Machines until their exclusion is lifted.
+// Nodes kept within the version skew tolerance are pending upgrade, as a further operator upgrade would take them out
+// of the tolerance.
+func machineRolloutState(machine *mapi.Machine, node *core.Node, config *operatorconfig.Config) string {
+	switch {
+	case !machine.GetDeletionTimestamp().IsZero():
+		return "deleting"
+	case isExcluded(machine, node):
+		return ""
+	case machine.Status.Phase == nil || *machine.Status.Phase == "Failed":
+		return ""
+	case *machine.Status.Phase != "Running":
+		return "provisioning"
+	case node == nil:
+		return "registering"
+	}
+	nodeVersion, present := node.Annotations[nodeconfig.VersionAnnotation]
+	if !present {
+		return "configuring"
+	}
+	if isVersionOutdated(nodeVersion, config) {
+		return "upgrading"
+	}
+	if nodeVersion != version.Get() && nodeVersion != config.PinnedVersion {
+		return "pending upgrade"
+	}
+	return ""
+}
+
+// setOperatorCondition sets the given condition in the spec of the OperatorCondition with the given name. The
+// OperatorCondition is left untouched if it already holds the condition, and ignored if it does not exist.
+func (r *WindowsMachineReconciler) setOperatorCondition(ctx context.Context, name string,
+	condition meta.Condition) error {
+	operatorCondition := &unstructured.Unstructured{}
+	operatorCondition.SetGroupVersionKind(operatorConditionGVK)
+	if err := r.client.Get(ctx, kubeTypes.NamespacedName{Namespace: r.watchNamespace, Name: name},
+		operatorCondition); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			r.log.V(1).Info("OperatorCondition not found", "operatorcondition", name)
+			return nil
+		}
+		return errors.Wrapf(err, "unable to get OperatorCondition %s", name)
+	}
+	spec := operatorConditionSpec{}
+	if rawSpec, ok := operatorCondition.Object["spec"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawSpec, &spec); err != nil {
+			return errors.Wrapf(err, "unable to read the spec of OperatorCondition %s", name)
+		}
+	}
+	if current := apimeta.FindStatusCondition(spec.Conditions, condition.Type); current != nil &&
+		current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message {
+		return nil
+	}
+	condition.ObservedGeneration = operatorCondition.GetGeneration()
+	apimeta.SetStatusCondition(&spec.Conditions, condition)
+	rawSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		return errors.Wrapf(err, "unable to convert the spec of OperatorCondition %s", name)
+	}
+	if err := unstructured.SetNestedField(operatorCondition.Object, rawSpec["conditions"], "spec",
+		"conditions"); err != nil {
+		return errors.Wrapf(err, "unable to set the conditions of OperatorCondition %s", name)
+	}
+	if err := r.client.Update(ctx, operatorCondition); err != nil {
+		return errors.Wrapf(err, "unable to update OperatorCondition %s", name)
+	}
+	r.log.Info("reported operator condition", "operatorcondition", name, "type", condition.Type,
+		"status", condition.Status, "message", condition.Message)
+	return nil
+}
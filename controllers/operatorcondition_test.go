@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+func TestMachineRolloutState(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	now := meta.Now()
+	tests := []struct {
+		name          string
+		deleting      bool
+		phase         *string
+		registered    bool
+		nodeVersion   *string
+		pinnedVersion string
+		expected      string
+	}{
+		{
+			name:     "deleting",
+			deleting: true,
+			phase:    ptr("Running"),
+			expected: "deleting",
+		},
+		{
+			name:     "failed",
+			phase:    ptr("Failed"),
+			expected: "",
+		},
+		{
+			name:     "provisioning",
+			phase:    ptr("Provisioned"),
+			expected: "provisioning",
+		},
+		{
+			name:     "not registered",
+			phase:    ptr("Running"),
+			expected: "registering",
+		},
+		{
+			name:       "configuring",
+			phase:      ptr("Running"),
+			registered: true,
+			expected:   "configuring",
+		},
+		{
+			name:        "outdated",
+			phase:       ptr("Running"),
+			registered:  true,
+			nodeVersion: ptr("old"),
+			expected:    "upgrading",
+		},
+		{
+			name:          "pinned",
+			phase:         ptr("Running"),
+			registered:    true,
+			nodeVersion:   ptr("old"),
+			pinnedVersion: "old",
+			expected:      "",
+		},
+		{
+			name:        "up to date",
+			phase:       ptr("Running"),
+			registered:  true,
+			nodeVersion: ptr(version.Get()),
+			expected:    "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			machine := &mapi.Machine{Status: mapi.MachineStatus{Phase: test.phase}}
+			if test.deleting {
+				machine.SetDeletionTimestamp(&now)
+			}
+			var node *core.Node
+			if test.registered {
+				node = &core.Node{}
+				if test.nodeVersion != nil {
+					node.SetAnnotations(map[string]string{nodeconfig.VersionAnnotation: *test.nodeVersion})
+				}
+			}
+			assert.Equal(t, test.expected, machineRolloutState(machine, node,
+				&operatorconfig.Config{PinnedVersion: test.pinnedVersion}))
+		})
+	}
+}
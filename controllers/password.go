@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+)
+
+const (
+	// CredentialsSecret is the name of the Secret, in the operator namespace, holding the current password of the user
+	// WMCO connects as on each Windows node whose password is rotated, keyed by Machine name
+	CredentialsSecret = "windows-instance-credentials"
+	// PasswordRotatedAnnotation holds the time at which the password of the node's VM was last rotated
+	PasswordRotatedAnnotation = "windowsmachineconfig.openshift.io/password-rotated"
+	// passwordLength is the length of the generated passwords
+	passwordLength = 32
+)
+
+// passwordCharacterClasses are the character classes which generated passwords contain at least one character of,
+// satisfying the Windows password complexity requirements
+var passwordCharacterClasses = []string{
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"abcdefghijklmnopqrstuvwxyz",
+	"0123456789",
+	"!#%+-.:=?@^_~",
+}
+
+// reconcilePassword rotates the password of the user WMCO connects as on the VM backing the given Machine, if the
+// rotation interval elapsed since it was last rotated, and requeues the Machine for the next rotation
+func (r *machineReconciliation) reconcilePassword(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	interval := r.config.PasswordRotationInterval
+	if interval == 0 {
+		return ctrl.Result{}, nil
+	}
+	if rotated, err := time.Parse(time.RFC3339, node.Annotations[PasswordRotatedAnnotation]); err == nil {
+		if next := rotated.Add(interval); time.Now().Before(next) {
+			return ctrl.Result{RequeueAfter: time.Until(next)}, nil
+		}
+	}
+
+	if r.config.DryRun {
+		log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+		reportDryRun(r.recorder, log, machine, "PasswordRotation", "Machine %s password would be rotated",
+			machine.GetName())
+		return ctrl.Result{}, nil
+	}
+
+	password, err := generatePassword()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// The password is stored before it is set, so that it is never lost if the rotation is interrupted
+	if err := r.storePassword(ctx, machine.GetName(), password); err != nil {
+		return ctrl.Result{}, err
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := vm.SetPassword(password); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "PasswordRotationFailure",
+			"Machine %s password could not be rotated", machine.GetName())
+		return ctrl.Result{}, err
+	}
+
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[PasswordRotatedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "error annotating node %s", node.GetName())
+	}
+	r.recorder.Eventf(machine, core.EventTypeNormal, "PasswordRotated", "Machine %s password rotated",
+		machine.GetName())
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// storePassword saves the given password of the given Machine in the credentials Secret, creating it if needed
+func (r *WindowsMachineReconciler) storePassword(ctx context.Context, machineName, password string) error {
+	secret, err := r.getCredentialsSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		// The Secret is created rather than applied, so that a concurrent creation fails instead of being overwritten
+		if _, err := r.k8sclientset.CoreV1().Secrets(r.watchNamespace).Create(ctx,
+			r.credentialsSecret(map[string][]byte{machineName: []byte(password)}, ""),
+			meta.CreateOptions{FieldManager: apply.FieldManager}); err != nil {
+			return errors.Wrapf(err, "error creating %s Secret", CredentialsSecret)
+		}
+		return nil
+	}
+	data := passwords(secret)
+	data[machineName] = []byte(password)
+	if _, err := apply.Secret(ctx, r.k8sclientset, r.credentialsSecret(data, secret.GetResourceVersion())); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getCredentialsSecret returns the credentials Secret, nil if it does not exist
+func (r *WindowsMachineReconciler) getCredentialsSecret(ctx context.Context) (*core.Secret, error) {
+	secret, err := r.k8sclientset.CoreV1().Secrets(r.watchNamespace).Get(ctx, CredentialsSecret, meta.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error getting %s Secret", CredentialsSecret)
+	}
+	return secret, nil
+}
+
+// passwords returns a copy of the passwords held by the given credentials Secret, keyed by Machine name
+func passwords(secret *core.Secret) map[string][]byte {
+	data := make(map[string][]byte, len(secret.Data))
+	for machineName, password := range secret.Data {
+		data[machineName] = password
+	}
+	return data
+}
+
+// credentialsSecret returns the credentials Secret holding the given passwords. The whole Secret is applied, as WMCO
+// owns it. The given resource version, unless empty, makes the apply fail with a Conflict error if the Secret was
+// changed since it was read, so that the passwords stored by concurrent reconciliations are not dropped.
+func (r *WindowsMachineReconciler) credentialsSecret(data map[string][]byte, resourceVersion string) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Name: CredentialsSecret, Namespace: r.watchNamespace,
+			ResourceVersion: resourceVersion},
+		Type: core.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// removePassword removes the password of the given Machine from the credentials Secret, if present
+func (r *WindowsMachineReconciler) removePassword(ctx context.Context, machineName string) error {
+	secret, err := r.getCredentialsSecret(ctx)
+	if err != nil || secret == nil {
+		return err
+	}
+	data := passwords(secret)
+	if _, present := data[machineName]; !present {
+		return nil
+	}
+	delete(data, machineName)
+	secret, err = apply.Secret(ctx, r.k8sclientset, r.credentialsSecret(data, secret.GetResourceVersion()))
+	if err != nil {
+		return err
+	}
+	if _, present := secret.Data[machineName]; !present {
+		return nil
+	}
+	// The password is kept when it is also owned by another field manager, such as a previous WMCO version which
+	// updated the Secret instead of applying it, or the creation of the Secret, so it is removed through an update
+	delete(secret.Data, machineName)
+	if _, err := r.k8sclientset.CoreV1().Secrets(r.watchNamespace).Update(ctx, secret,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return errors.Wrapf(err, "error updating %s Secret", CredentialsSecret)
+	}
+	return nil
+}
+
+// generatePassword returns a random password containing at least one character of each password character class
+func generatePassword() (string, error) {
+	alphabet := strings.Join(passwordCharacterClasses, "")
+	for {
+		password := make([]byte, passwordLength)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+			if err != nil {
+				return "", errors.Wrap(err, "error generating password")
+			}
+			password[i] = alphabet[n.Int64()]
+		}
+		if isComplex(string(password)) {
+			return string(password), nil
+		}
+	}
+}
+
+// isComplex returns true if the given password contains at least one character of each password character class
+func isComplex(password string) bool {
+	for _, class := range passwordCharacterClasses {
+		if !strings.ContainsAny(password, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// earliestRequeue returns the result requeuing soonest among the given results
+func earliestRequeue(results ...ctrl.Result) ctrl.Result {
+	earliest := ctrl.Result{}
+	for _, result := range results {
+		if result.Requeue {
+			earliest.Requeue = true
+		}
+		if result.RequeueAfter > 0 && (earliest.RequeueAfter == 0 || result.RequeueAfter < earliest.RequeueAfter) {
+			earliest.RequeueAfter = result.RequeueAfter
+		}
+	}
+	return earliest
+}
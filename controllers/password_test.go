@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// TestGeneratePassword tests the generatePassword function
+func TestGeneratePassword(t *testing.T) {
+	password, err := generatePassword()
+	require.NoError(t, err)
+	assert.Len(t, password, passwordLength)
+	assert.True(t, isComplex(password))
+
+	other, err := generatePassword()
+	require.NoError(t, err)
+	assert.NotEqual(t, password, other)
+}
+
+// TestEarliestRequeue tests the earliestRequeue function
+func TestEarliestRequeue(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []ctrl.Result
+		want    ctrl.Result
+	}{
+		{
+			name:    "no requeue",
+			results: []ctrl.Result{{}, {}},
+			want:    ctrl.Result{},
+		},
+		{
+			name:    "single requeue",
+			results: []ctrl.Result{{}, {RequeueAfter: time.Hour}},
+			want:    ctrl.Result{RequeueAfter: time.Hour},
+		},
+		{
+			name:    "earliest requeue",
+			results: []ctrl.Result{{RequeueAfter: time.Hour}, {RequeueAfter: time.Minute}},
+			want:    ctrl.Result{RequeueAfter: time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, earliestRequeue(tt.results...))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	authorization "k8s.io/api/authorization/v1"
+	rbac "k8s.io/api/rbac/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/openshift/windows-machine-config-operator/deploy"
+)
+
+// requiredPermissions returns the permissions granted to the operator by the Role and ClusterRole of deploy/role.yaml,
+// which the CSV grants as well, the permissions of the Role being granted in the given watched namespace
+func requiredPermissions(watchNamespace string) ([]authorization.ResourceAttributes, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(deploy.Role), 4096)
+	var permissions []authorization.ResourceAttributes
+	for {
+		// A ClusterRole decodes a Role as well, both holding their rules in the same fields
+		var role rbac.ClusterRole
+		if err := decoder.Decode(&role); err != nil {
+			if err == io.EOF {
+				return permissions, nil
+			}
+			return nil, errors.Wrap(err, "unable to decode the roles of the operator")
+		}
+		namespace := ""
+		switch role.Kind {
+		case "Role":
+			namespace = watchNamespace
+		case "ClusterRole":
+		default:
+			continue
+		}
+		for _, rule := range role.Rules {
+			permissions = append(permissions, ruleAttributes(namespace, rule)...)
+		}
+	}
+}
+
+// ruleAttributes returns the permissions granted by the given policy rule in the given namespace, empty for a
+// ClusterRole
+func ruleAttributes(namespace string, rule rbac.PolicyRule) []authorization.ResourceAttributes {
+	names := rule.ResourceNames
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	var attributes []authorization.ResourceAttributes
+	for _, group := range rule.APIGroups {
+		for _, resource := range rule.Resources {
+			resource, subresource := resource, ""
+			if i := strings.Index(resource, "/"); i >= 0 {
+				resource, subresource = resource[:i], resource[i+1:]
+			}
+			for _, verb := range rule.Verbs {
+				for _, name := range names {
+					attributes = append(attributes, authorization.ResourceAttributes{Namespace: namespace,
+						Group: group, Resource: resource, Subresource: subresource, Verb: verb, Name: name})
+				}
+			}
+		}
+	}
+	return attributes
+}
+
+// MissingPermissions returns a description of each permission of the operator, when watching the given namespace,
+// which is not granted to the operator service account. It lets installs not managed by OLM, whose RBAC is not
+// generated from the CSV, be diagnosed at startup rather than through failing reconciliations.
+func MissingPermissions(ctx context.Context, clientset kubernetes.Interface, watchNamespace string) ([]string, error) {
+	permissions, err := requiredPermissions(watchNamespace)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, attributes := range permissions {
+		attributes := attributes
+		review := &authorization.SelfSubjectAccessReview{
+			Spec: authorization.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+		}
+		review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review,
+			meta.CreateOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to review the permissions of the operator")
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, describePermission(attributes))
+		}
+	}
+	return missing, nil
+}
+
+// describePermission returns a description of the given permission, such as `create secrets in namespace ns`
+func describePermission(attributes authorization.ResourceAttributes) string {
+	resource := attributes.Resource
+	if attributes.Subresource != "" {
+		resource += "/" + attributes.Subresource
+	}
+	if attributes.Group != "" {
+		resource += "." + attributes.Group
+	}
+	if attributes.Name != "" {
+		resource += " named " + attributes.Name
+	}
+	if attributes.Namespace == "" {
+		return fmt.Sprintf("%s %s", attributes.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s in namespace %s", attributes.Verb, resource, attributes.Namespace)
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorization "k8s.io/api/authorization/v1"
+)
+
+func TestRequiredPermissions(t *testing.T) {
+	permissions, err := requiredPermissions("wmco")
+	require.NoError(t, err)
+	for _, expected := range []authorization.ResourceAttributes{
+		{Namespace: "wmco", Group: "windowsmachineconfig.openshift.io", Resource: "windowsnodes", Verb: "update"},
+		{Namespace: "wmco", Group: "", Resource: "services", Verb: "create"},
+		{Group: "", Resource: "nodes", Verb: "*"},
+		{Group: "", Resource: "nodes", Subresource: "status", Verb: "update"},
+		{Group: "", Resource: "services", Verb: "list"},
+		{Group: "", Resource: "secrets", Verb: "delete"},
+		{Group: "machine.openshift.io", Resource: "machinesets", Verb: "list"},
+		{Group: "windowsmachineconfig.openshift.io", Resource: "windowsfleets", Verb: "update"},
+		{Group: "", Resource: "configmaps", Verb: "get", Name: "cluster-config-v1"},
+	} {
+		assert.Contains(t, permissions, expected)
+	}
+}
+
+func TestDescribePermission(t *testing.T) {
+	assert.Equal(t, "create pods/eviction",
+		describePermission(authorization.ResourceAttributes{Resource: "pods", Subresource: "eviction",
+			Verb: "create"}))
+	assert.Equal(t, "get configmaps named cluster-config-v1",
+		describePermission(authorization.ResourceAttributes{Resource: "configmaps", Verb: "get",
+			Name: "cluster-config-v1"}))
+	assert.Equal(t, "update leases.coordination.k8s.io in namespace wmco",
+		describePermission(authorization.ResourceAttributes{Namespace: "wmco", Group: "coordination.k8s.io",
+			Resource: "leases", Verb: "update"}))
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// preflightRequeueDelay is the time after which the first Machine of a pool is validated again when it failed the
+// pre-flight validation
+const preflightRequeueDelay = 5 * time.Minute
+
+// isNewPool returns true if none of the Machines of the MachineSet owning the given Machine has a node yet, a Machine
+// without a MachineSet being a pool of its own
+func (r *WindowsMachineReconciler) isNewPool(ctx context.Context, machine *mapi.Machine) (bool, error) {
+	machineSetName := getMachineSetName(machine)
+	if machineSetName == "" {
+		return machine.Status.NodeRef == nil, nil
+	}
+	machines, err := listWindowsMachines(ctx, r.client, client.InNamespace(machine.GetNamespace()),
+		client.MatchingFields{machineSetIndex: machineSetName})
+	if err != nil {
+		return false, errors.Wrap(err, "cannot list Machines")
+	}
+	for _, m := range machines {
+		if m.Status.NodeRef != nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkPreflight validates the first Machine of a new pool before it is configured, so that a misconfigured pool
+// fails fast with clear reasons rather than partway through its configuration: the VM must be reachable over SSH, run
+// a supported OS build, have enough free space on its system drive and have the Containers feature installed. The
+// userData secret is validated before. The results are recorded in the WindowsNode of the Machine and reported through
+// warning events on the Machine and its MachineSet. It returns false if the Machine fails the validation. A VM
+// rejecting the private key passes, so that it is remediated by the configuration of the Machine.
+func (r *machineReconciliation) checkPreflight(ctx context.Context, machine *mapi.Machine) (bool, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	newPool, err := r.isNewPool(ctx, machine)
+	if err != nil {
+		return false, err
+	}
+	if !newPool {
+		return true, nil
+	}
+	var problems []string
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		var authErr *windows.AuthErr
+		if errors.As(err, &authErr) {
+			return true, nil
+		}
+		problems = append(problems, "unreachable over SSH: "+err.Error())
+	} else {
+		preflight, err := vm.Preflight()
+		if err != nil {
+			log.Error(err, "unable to run the pre-flight checks")
+			return true, nil
+		}
+		problems = preflight.Problems()
+	}
+
+	condition := meta.Condition{Type: wmcoapi.PreflightPassedCondition, Status: meta.ConditionTrue,
+		Reason: "Passed", Message: "The first instance of the pool meets the requirements of Windows nodes"}
+	if len(problems) > 0 {
+		condition.Status = meta.ConditionFalse
+		condition.Reason = "Failed"
+		condition.Message = "Pre-flight validation failed: " + strings.Join(problems, "; ")
+		log.Info("machine cannot be configured as it failed the pre-flight validation", "problems", problems)
+		r.recorder.Eventf(machine, core.EventTypeWarning, "PreflightFailed",
+			"Machine %s cannot be configured, pre-flight validation failed: %s", machine.GetName(),
+			strings.Join(problems, "; "))
+		if machineSetName := getMachineSetName(machine); machineSetName != "" {
+			machineSet := &mapi.MachineSet{}
+			if err := r.client.Get(ctx, types.NamespacedName{Namespace: machine.GetNamespace(),
+				Name: machineSetName}, machineSet); err == nil {
+				r.recorder.Eventf(machineSet, core.EventTypeWarning, "PreflightFailed",
+					"First Machine %s of the MachineSet failed the pre-flight validation: %s", machine.GetName(),
+					strings.Join(problems, "; "))
+			}
+		}
+	}
+	if err := r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		apimeta.SetStatusCondition(&status.Conditions, condition)
+	}); err != nil {
+		return false, err
+	}
+	return len(problems) == 0, nil
+}
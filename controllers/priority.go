@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+)
+
+const (
+	// ConfigurationPriorityAnnotation can be applied to a Windows Machine or MachineSet, with an integer value, so that
+	// Machines with a higher priority are configured before the Machines with a lower priority awaiting configuration.
+	// The annotation of a Machine takes precedence over the annotation of its MachineSet. Machines default to 0.
+	ConfigurationPriorityAnnotation = "windowsmachineconfig.openshift.io/configuration-priority"
+	// priorityRequeueDelay is the time to wait before checking again if a Machine whose configuration is deferred to
+	// Machines with a higher priority can be configured
+	priorityRequeueDelay = 30 * time.Second
+)
+
+// configurationPriority returns the configuration priority of the given Machine, owned by the given MachineSet, which
+// is nil if the Machine is not owned by a MachineSet
+func configurationPriority(machine *mapi.Machine, machineSet *mapi.MachineSet) (int, error) {
+	value, present := machine.GetAnnotations()[ConfigurationPriorityAnnotation]
+	if !present && machineSet != nil {
+		value, present = machineSet.GetAnnotations()[ConfigurationPriorityAnnotation]
+	}
+	if !present {
+		return 0, nil
+	}
+	priority, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.Errorf("invalid %s annotation %q: expected an integer", ConfigurationPriorityAnnotation,
+			value)
+	}
+	return priority, nil
+}
+
+// getConfigurationPriority returns the configuration priority of the given Machine
+func (r *WindowsMachineReconciler) getConfigurationPriority(ctx context.Context, machine *mapi.Machine) (int, error) {
+	machineSet, err := r.getMachineSet(ctx, machine)
+	if err != nil {
+		return 0, err
+	}
+	return configurationPriority(machine, machineSet)
+}
+
+// getHigherPriorityMachine returns the name of a Windows Machine awaiting configuration with a higher configuration
+// priority than the given Machine, or an empty string if there is none. Machines whose last configuration failed,
+// paused Machines and excluded Machines do not hold the Machines with a lower priority.
+func (r *machineReconciliation) getHigherPriorityMachine(ctx context.Context, machine *mapi.Machine) (string,
+	error) {
+	priority, err := r.getConfigurationPriority(ctx, machine)
+	if err != nil {
+		return "", err
+	}
+	machines, err := listWindowsMachines(ctx, r.client)
+	if err != nil {
+		return "", err
+	}
+	windowsNodes := &wmcoapi.WindowsNodeList{}
+	if err := r.apiReader.List(ctx, windowsNodes, client.InNamespace(r.watchNamespace)); err != nil {
+		return "", errors.Wrap(err, "error listing WindowsNodes")
+	}
+	failed := make(map[string]bool)
+	for _, windowsNode := range windowsNodes.Items {
+		failed[windowsNode.GetName()] = windowsNode.Status.LastError != ""
+	}
+	for i := range machines {
+		other := &machines[i]
+		if other.GetName() == machine.GetName() || !r.config.ManagesMachine(other.GetLabels()) ||
+			!other.GetDeletionTimestamp().IsZero() || other.Status.Phase == nil ||
+			*other.Status.Phase != "Provisioned" || failed[other.GetName()] {
+			continue
+		}
+		if paused, err := r.isPaused(ctx, other); err != nil || paused {
+			continue
+		}
+		if node, err := r.getMachineNode(ctx, other); err != nil || isExcluded(other, node) {
+			continue
+		}
+		otherPriority, err := r.getConfigurationPriority(ctx, other)
+		if err != nil {
+			// A Machine with an invalid priority is configured with the default priority
+			r.log.Error(err, "invalid configuration priority", "machine", other.GetName())
+			continue
+		}
+		if otherPriority > priority {
+			return other.GetName(), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigurationPriority(t *testing.T) {
+	annotated := func(priority string) meta.ObjectMeta {
+		return meta.ObjectMeta{Annotations: map[string]string{ConfigurationPriorityAnnotation: priority}}
+	}
+	tests := []struct {
+		name       string
+		machine    *mapi.Machine
+		machineSet *mapi.MachineSet
+		expected   int
+		wantErr    bool
+	}{
+		{
+			name:     "default",
+			machine:  &mapi.Machine{},
+			expected: 0,
+		},
+		{
+			name:       "MachineSet priority",
+			machine:    &mapi.Machine{},
+			machineSet: &mapi.MachineSet{ObjectMeta: annotated("10")},
+			expected:   10,
+		},
+		{
+			name:       "Machine priority takes precedence",
+			machine:    &mapi.Machine{ObjectMeta: annotated("-5")},
+			machineSet: &mapi.MachineSet{ObjectMeta: annotated("10")},
+			expected:   -5,
+		},
+		{
+			name:    "invalid priority",
+			machine: &mapi.Machine{ObjectMeta: annotated("high")},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			priority, err := configurationPriority(test.machine, test.machineSet)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, priority)
+		})
+	}
+}
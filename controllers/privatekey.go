@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
+	"github.com/openshift/windows-machine-config-operator/pkg/signer"
+)
+
+// PrivateKeySecretAnnotation is applied to a Windows MachineSet to configure its Machines with the private key of the
+// given Secret of the operator namespace, rather than with the private key of the cloud-private-key Secret
+const PrivateKeySecretAnnotation = "windowsmachineconfig.openshift.io/private-key-secret"
+
+// cachedSigners are the signers created from the keys held by a private key secret at a resource version
+type cachedSigners struct {
+	// resourceVersion is the resource version of the secret the signers were created from
+	resourceVersion string
+	// signer is the signer of the private key
+	signer ssh.Signer
+	// sshCA is the signer of the SSH certificate authority, nil if the secret does not hold one
+	sshCA ssh.Signer
+	// previousSigner is the signer of the previous private key, nil if the secret does not hold one
+	previousSigner ssh.Signer
+}
+
+// signerCache holds the signers created from the private key secrets, by private key secret name, shared by the
+// reconciliations of Windows Machines until the private key secrets change
+type signerCache struct {
+	sync.Mutex
+	signers map[string]*cachedSigners
+}
+
+// get returns the signers cached for the private key secret with the given name, nil if they were not created from
+// the given resource version of the secret
+func (c *signerCache) get(secretName, resourceVersion string) *cachedSigners {
+	c.Lock()
+	defer c.Unlock()
+	cached := c.signers[secretName]
+	if cached == nil || cached.resourceVersion != resourceVersion {
+		return nil
+	}
+	return cached
+}
+
+// set caches the given signers of the private key secret with the given name
+func (c *signerCache) set(secretName string, signers *cachedSigners) {
+	c.Lock()
+	defer c.Unlock()
+	if c.signers == nil {
+		c.signers = make(map[string]*cachedSigners)
+	}
+	c.signers[secretName] = signers
+}
+
+// forget drops the signers of the private key secret with the given name
+func (c *signerCache) forget(secretName string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.signers, secretName)
+}
+
+// getSigners returns the signers created from the private key secret with the given name, read from the cache of the
+// manager. The keys are only parsed again when the resource version of the secret changed since they were cached.
+func (r *WindowsMachineReconciler) getSigners(secretName string) (*cachedSigners, error) {
+	secret := &core.Secret{}
+	if err := r.client.Get(context.TODO(), kubeTypes.NamespacedName{Namespace: r.watchNamespace, Name: secretName},
+		secret); err != nil {
+		return nil, err
+	}
+	if cached := r.signerCache.get(secretName, secret.GetResourceVersion()); cached != nil {
+		return cached, nil
+	}
+	signers, err := newCachedSigners(secret)
+	if err != nil {
+		return nil, err
+	}
+	r.signerCache.set(secretName, signers)
+	return signers, nil
+}
+
+// newCachedSigners returns the signers created from the private key, SSH certificate authority and previous private
+// key held by the given private key secret
+func newCachedSigners(secret *core.Secret) (*cachedSigners, error) {
+	privateKey, ok := secret.Data[secrets.PrivateKeySecretKey]
+	if !ok {
+		return nil, errors.Errorf("%s missing '%s' secret", secret.GetName(), secrets.PrivateKeySecretKey)
+	}
+	signers := &cachedSigners{resourceVersion: secret.GetResourceVersion()}
+	var err error
+	if signers.signer, err = signer.Create(privateKey); err != nil {
+		return nil, errors.Wrap(err, "error creating signer")
+	}
+	if caKey := secret.Data[secrets.SSHCAKeySecretKey]; caKey != nil {
+		if signers.sshCA, err = signer.Create(caKey); err != nil {
+			return nil, errors.Wrap(err, "error creating SSH certificate authority signer")
+		}
+	}
+	if previousKey := secret.Data[secrets.PreviousPrivateKeySecretKey]; previousKey != nil {
+		if signers.previousSigner, err = signer.Create(previousKey); err != nil {
+			return nil, errors.Wrap(err, "error creating signer from previous private key")
+		}
+	}
+	return signers, nil
+}
+
+// loadSigners sets the signers used by the current reconciliation from the private key secret with the given name:
+// the signer of its private key, and the signers of its SSH certificate authority and previous private key if it
+// holds them
+func (r *machineReconciliation) loadSigners(secretName string) error {
+	signers, err := r.getSigners(secretName)
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "%s does not exist, please create it", secretName)
+		}
+		return errors.Wrapf(err, "unable to get signers from secret %s", secretName)
+	}
+	if r.signer != signers.signer {
+		// The VM must be connected to again with the new signers
+		r.closeVM()
+	}
+	r.signer = signers.signer
+	r.sshCA = signers.sshCA
+	r.previousSigner = signers.previousSigner
+	r.privateKeySecret = secretName
+	return nil
+}
+
+// usePrivateKeySecret switches the current reconciliation over to the signers of the private key secret given by the
+// PrivateKeySecretAnnotation of the MachineSet owning the given Machine, if any
+func (r *machineReconciliation) usePrivateKeySecret(ctx context.Context, machine *mapi.Machine) error {
+	machineSet, err := r.getMachineSet(ctx, machine)
+	if err != nil || machineSet == nil {
+		return err
+	}
+	secretName := machineSetPrivateKeySecret(machineSet)
+	if secretName == secrets.PrivateKeySecret {
+		return nil
+	}
+	if err := r.loadSigners(secretName); err != nil {
+		r.recorder.Eventf(machineSet, core.EventTypeWarning, "InvalidPrivateKeySecret",
+			"MachineSet %s private key secret %s is not usable: %v", machineSet.GetName(), secretName, err)
+		return errors.Wrapf(err, "invalid private key secret of MachineSet %s", machineSet.GetName())
+	}
+	return nil
+}
+
+// machineSetPrivateKeySecret returns the name of the private key secret the Machines of the given MachineSet are
+// configured with
+func machineSetPrivateKeySecret(machineSet *mapi.MachineSet) string {
+	if secretName := machineSet.GetAnnotations()[PrivateKeySecretAnnotation]; secretName != "" {
+		return secretName
+	}
+	return secrets.PrivateKeySecret
+}
+
+// nodePrivateKeySecrets maps the names of the nodes of the given Machines, owned by the given MachineSets, to the name
+// of the private key secret they are configured with, when it is not the cloud-private-key secret
+func nodePrivateKeySecrets(machines []mapi.Machine, machineSets []mapi.MachineSet) map[string]string {
+	machineSetSecrets := make(map[string]string)
+	for i := range machineSets {
+		if secretName := machineSetPrivateKeySecret(&machineSets[i]); secretName != secrets.PrivateKeySecret {
+			machineSetSecrets[machineSets[i].GetNamespace()+"/"+machineSets[i].GetName()] = secretName
+		}
+	}
+	nodeSecrets := make(map[string]string)
+	for i := range machines {
+		machine := &machines[i]
+		secretName, found := machineSetSecrets[machine.GetNamespace()+"/"+getMachineSetName(machine)]
+		if found && machine.Status.NodeRef != nil {
+			nodeSecrets[machine.Status.NodeRef.Name] = secretName
+		}
+	}
+	return nodeSecrets
+}
+
+// holdsPrivateKey returns true if the given object is the cloud-private-key secret, or another secret of the given
+// namespace holding a private key, which may be the private key secret of a MachineSet
+func holdsPrivateKey(obj client.Object, keyNamespace string) bool {
+	if obj.GetNamespace() != keyNamespace {
+		return false
+	}
+	if obj.GetName() == secrets.PrivateKeySecret {
+		return true
+	}
+	secret, ok := obj.(*core.Secret)
+	if !ok {
+		return false
+	}
+	_, present := secret.Data[secrets.PrivateKeySecretKey]
+	return present
+}
+
+// privateKeyHandler drops the cached signers of a private key secret when it is deleted. The signers of a changed
+// secret are recreated once its new resource version is read.
+func (r *WindowsMachineReconciler) privateKeyHandler() handler.EventHandler {
+	return handler.Funcs{
+		DeleteFunc: func(e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+			r.signerCache.forget(e.Object.GetName())
+		},
+	}
+}
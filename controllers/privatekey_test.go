@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
+)
+
+func TestSignerCache(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keySigner, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	cache := &signerCache{}
+	require.Nil(t, cache.get("cloud-private-key", "1"))
+
+	signers := &cachedSigners{resourceVersion: "1", signer: keySigner}
+	cache.set("cloud-private-key", signers)
+	require.Equal(t, signers, cache.get("cloud-private-key", "1"))
+	// The signers are not used once the secret changed
+	require.Nil(t, cache.get("cloud-private-key", "2"))
+	// The signers of other private key secrets are cached separately
+	require.Nil(t, cache.get("team-a-private-key", "1"))
+
+	cache.forget("cloud-private-key")
+	require.Nil(t, cache.get("cloud-private-key", "1"))
+}
+
+func TestNewCachedSigners(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	secret := &core.Secret{ObjectMeta: meta.ObjectMeta{Name: "cloud-private-key", ResourceVersion: "1"},
+		Data: map[string][]byte{secrets.PrivateKeySecretKey: privateKey}}
+	signers, err := newCachedSigners(secret)
+	require.NoError(t, err)
+	assert.Equal(t, "1", signers.resourceVersion)
+	assert.NotNil(t, signers.signer)
+	assert.Nil(t, signers.sshCA)
+	assert.Nil(t, signers.previousSigner)
+
+	secret.Data[secrets.SSHCAKeySecretKey] = privateKey
+	secret.Data[secrets.PreviousPrivateKeySecretKey] = privateKey
+	signers, err = newCachedSigners(secret)
+	require.NoError(t, err)
+	assert.NotNil(t, signers.sshCA)
+	assert.NotNil(t, signers.previousSigner)
+
+	secret.Data[secrets.PreviousPrivateKeySecretKey] = []byte("invalid")
+	_, err = newCachedSigners(secret)
+	assert.Error(t, err)
+
+	_, err = newCachedSigners(&core.Secret{ObjectMeta: meta.ObjectMeta{Name: "cloud-private-key"}})
+	assert.Error(t, err)
+}
+
+func TestNodePrivateKeySecrets(t *testing.T) {
+	machineSets := []mapi.MachineSet{
+		{ObjectMeta: meta.ObjectMeta{Name: "team-a", Namespace: "openshift-machine-api",
+			Annotations: map[string]string{PrivateKeySecretAnnotation: "team-a-private-key"}}},
+		{ObjectMeta: meta.ObjectMeta{Name: "shared", Namespace: "openshift-machine-api"}},
+	}
+	machine := func(name, machineSet, node string) mapi.Machine {
+		m := mapi.Machine{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "openshift-machine-api",
+			OwnerReferences: []meta.OwnerReference{{Kind: "MachineSet", Name: machineSet}}}}
+		if node != "" {
+			m.Status.NodeRef = &core.ObjectReference{Name: node}
+		}
+		return m
+	}
+	machines := []mapi.Machine{machine("team-a-1", "team-a", "node-1"), machine("team-a-2", "team-a", ""),
+		machine("shared-1", "shared", "node-2")}
+	assert.Equal(t, map[string]string{"node-1": "team-a-private-key"}, nodePrivateKeySecrets(machines, machineSets))
+}
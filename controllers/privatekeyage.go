@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
+	"github.com/openshift/windows-machine-config-operator/pkg/signer"
+)
+
+const (
+	// PrivateKeyHashAnnotation is applied to the private key secret, holding the hash of the public key of the private
+	// key last seen by WMCO, so that its rotations are detected
+	PrivateKeyHashAnnotation = "windowsmachineconfig.openshift.io/private-key-hash"
+	// PrivateKeyRotatedAnnotation is applied to the private key secret, holding the time at which the private key was
+	// last rotated
+	PrivateKeyRotatedAnnotation = "windowsmachineconfig.openshift.io/private-key-rotated"
+	// privateKeyAgeCheckPeriod is the interval at which the age of the private key is checked, and the rotation of an
+	// outdated private key reported again
+	privateKeyAgeCheckPeriod = 24 * time.Hour
+)
+
+var (
+	// privateKeyRotatedUnix is the time at which the private key was last rotated, in seconds since the epoch, zero
+	// until it is known
+	privateKeyRotatedUnix int64
+	// privateKeyAgeSeconds is the time elapsed since the private key was last rotated, computed at each scrape
+	privateKeyAgeSeconds = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "windows_private_key_age_seconds",
+		Help: "Time elapsed since the private key used to configure Windows instances was last rotated",
+	}, func() float64 {
+		rotated := atomic.LoadInt64(&privateKeyRotatedUnix)
+		if rotated == 0 {
+			return 0
+		}
+		return time.Since(time.Unix(rotated, 0)).Seconds()
+	})
+	// privateKeyRotationDue is 1 when the private key is older than the privateKeyMaxAge setting
+	privateKeyRotationDue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "windows_private_key_rotation_due",
+		Help: "1 when the private key used to configure Windows instances is older than privateKeyMaxAge, else 0",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(privateKeyAgeSeconds, privateKeyRotationDue)
+}
+
+// reconcilePrivateKeyAge records the rotations of the private key in the annotations of the private key secret with
+// the given name, and reports through a warning event, once a day, that the private key must be rotated when it is
+// older than privateKeyMaxAge. The first time a private key secret is seen, it is considered rotated when created.
+func (r *SecretReconciler) reconcilePrivateKeyAge(ctx context.Context,
+	name kubeTypes.NamespacedName) (reconcile.Result, error) {
+	secret := &core.Secret{}
+	if err := r.client.Get(ctx, name, secret); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "unable to get secret %s", name)
+	}
+	keySigner, err := signer.Create(secret.Data[secrets.PrivateKeySecretKey])
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error creating signer from private key")
+	}
+	keyHash := nodeconfig.CreatePubKeyHashAnnotation(keySigner.PublicKey())
+	rotated, err := time.Parse(time.RFC3339, secret.Annotations[PrivateKeyRotatedAnnotation])
+	if err != nil || secret.Annotations[PrivateKeyHashAnnotation] != keyHash {
+		rotated = time.Now()
+		if _, present := secret.Annotations[PrivateKeyHashAnnotation]; !present {
+			rotated = secret.GetCreationTimestamp().Time
+		}
+		// Only the annotations are applied, as the rest of the secret is owned by the user
+		if err := apply.SecretAnnotations(ctx, r.k8sclientset, name, map[string]string{
+			PrivateKeyHashAnnotation:    keyHash,
+			PrivateKeyRotatedAnnotation: rotated.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "unable to record private key rotation in secret %s", name)
+		}
+		r.log.Info("private key rotation recorded", "secret", name, "rotated", rotated.UTC())
+	}
+	atomic.StoreInt64(&privateKeyRotatedUnix, rotated.Unix())
+
+	config, err := operatorconfig.Load(ctx, r.k8sclientset, r.watchNamespace, r.defaultConfig)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "unable to load operator configuration")
+	}
+	if config.PrivateKeyMaxAge == 0 {
+		// The setting is checked again later, as changes to the configuration do not trigger reconciliations
+		privateKeyRotationDue.Set(0)
+		return reconcile.Result{RequeueAfter: privateKeyAgeCheckPeriod}, nil
+	}
+	age := time.Since(rotated)
+	if age < config.PrivateKeyMaxAge {
+		privateKeyRotationDue.Set(0)
+		return earliestRequeue(reconcile.Result{RequeueAfter: config.PrivateKeyMaxAge - age},
+			reconcile.Result{RequeueAfter: privateKeyAgeCheckPeriod}), nil
+	}
+	privateKeyRotationDue.Set(1)
+	r.log.Info("private key rotation due", "secret", name, "age", age.Round(time.Hour),
+		"maxAge", config.PrivateKeyMaxAge)
+	r.recorder.Eventf(secret, core.EventTypeWarning, "PrivateKeyRotationDue",
+		"Private key was last rotated %s ago, exceeding the maximum age of %s", age.Round(time.Hour),
+		config.PrivateKeyMaxAge)
+	return reconcile.Result{RequeueAfter: privateKeyAgeCheckPeriod}, nil
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// WindowsSystemDiskPressure is the node condition which is True when the free space of the system drive of a
+	// Windows node is below systemDiskFreeThreshold. The kubelet only reports the pressure on the drive holding its
+	// root directory and the container images.
+	WindowsSystemDiskPressure core.NodeConditionType = "WindowsSystemDiskPressure"
+	// WindowsServiceCrashLooping is the node condition which is True when a service of a Windows node required by the
+	// Kubernetes components terminated unexpectedly at least serviceCrashThreshold times within serviceCrashWindow
+	WindowsServiceCrashLooping core.NodeConditionType = "WindowsServiceCrashLooping"
+	// WindowsHNSNetworksDegraded is the node condition which is True when the HNS networks of a Windows node are
+	// broken and were not repaired
+	WindowsHNSNetworksDegraded core.NodeConditionType = "WindowsHNSNetworksDegraded"
+
+	// ProblemsCheckedAnnotation holds the time at which the problems of the node were last detected
+	ProblemsCheckedAnnotation = "windowsmachineconfig.openshift.io/problems-checked"
+
+	// problemCheckPeriod is the interval at which the problems of configured Windows nodes are detected
+	problemCheckPeriod = 10 * time.Minute
+	// systemDiskFreeThreshold is the free percentage of the system drive below which it is under pressure
+	systemDiskFreeThreshold = 10
+	// serviceCrashWindow is the look-back window of the unexpected service terminations
+	serviceCrashWindow = time.Hour
+	// serviceCrashThreshold is the number of unexpected terminations of a service within serviceCrashWindow from which
+	// it is crash looping
+	serviceCrashThreshold = 3
+)
+
+// reconcileProblems detects the Windows specific problems of the VM backing the given Machine, which the kubelet does
+// not report, and records them as conditions of its node, so that they can be acted on by a MachineHealthCheck. The
+// problems are detected once every problemCheckPeriod.
+func (r *machineReconciliation) reconcileProblems(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.config.ProblemDetection || r.config.DryRun {
+		return ctrl.Result{}, nil
+	}
+	if remaining := checkRemaining(node, ProblemsCheckedAnnotation, problemCheckPeriod, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	problems, err := vm.DetectProblems(serviceCrashWindow)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to detect problems of node %s", node.GetName())
+	}
+	if err := r.setNodeConditions(ctx, machine, node.GetName(), problemConditions(problems)...); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.recordCheck(ctx, node.GetName(), ProblemsCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: problemCheckPeriod}, nil
+}
+
+// problemConditions returns the node conditions reporting the given problems
+func problemConditions(problems *windows.Problems) []core.NodeCondition {
+	disk := core.NodeCondition{Type: WindowsSystemDiskPressure, Status: core.ConditionFalse, Reason: "SufficientFreeSpace",
+		Message: fmt.Sprintf("The system drive has %d%% free space", problems.SystemDiskFreePercent)}
+	if problems.SystemDiskFreePercent < systemDiskFreeThreshold {
+		disk.Status = core.ConditionTrue
+		disk.Reason = "InsufficientFreeSpace"
+		disk.Message = fmt.Sprintf("The system drive has %d%% free space, below %d%%",
+			problems.SystemDiskFreePercent, systemDiskFreeThreshold)
+	}
+
+	var crashLooping []string
+	for service, crashes := range problems.ServiceCrashes {
+		if crashes >= serviceCrashThreshold {
+			crashLooping = append(crashLooping, fmt.Sprintf("%s (%d)", service, crashes))
+		}
+	}
+	sort.Strings(crashLooping)
+	services := core.NodeCondition{Type: WindowsServiceCrashLooping, Status: core.ConditionFalse,
+		Reason: "ServicesStable", Message: "No service is crash looping"}
+	if len(crashLooping) > 0 {
+		services.Status = core.ConditionTrue
+		services.Reason = "ServicesCrashLooping"
+		services.Message = fmt.Sprintf("Services terminated unexpectedly at least %d times within %s: %s",
+			serviceCrashThreshold, serviceCrashWindow, strings.Join(crashLooping, ", "))
+	}
+	return []core.NodeCondition{disk, services}
+}
+
+// setNodeConditions sets the given conditions on the node with the given name, emitting a warning event on the given
+// Machine for each condition which becomes True. Only the given conditions are patched, the other conditions of the
+// node being owned by the kubelet and other controllers.
+func (r *WindowsMachineReconciler) setNodeConditions(ctx context.Context, machine *mapi.Machine, nodeName string,
+	conditions ...core.NodeCondition) error {
+	node, err := r.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "unable to get node %s", nodeName)
+	}
+	now := meta.Now()
+	var raised []core.NodeCondition
+	owned := make(map[core.NodeConditionType]bool)
+	for _, condition := range conditions {
+		if setNodeCondition(node, condition, now) {
+			raised = append(raised, condition)
+		}
+		owned[condition.Type] = true
+	}
+	var patched []core.NodeCondition
+	for _, condition := range node.Status.Conditions {
+		if owned[condition.Type] {
+			patched = append(patched, condition)
+		}
+	}
+	if err := apply.NodeConditions(ctx, r.k8sclientset, nodeName, patched); err != nil {
+		return err
+	}
+	for _, condition := range raised {
+		r.log.Info("node problem detected", "node", nodeName, "condition", condition.Type,
+			"reason", condition.Reason)
+		r.recorder.Eventf(machine, core.EventTypeWarning, string(condition.Type), "Machine %s node %s: %s",
+			machine.GetName(), nodeName, condition.Message)
+	}
+	return nil
+}
+
+// setNodeCondition sets the given condition on the given node, heartbeating it at the given time. The transition time
+// is only updated when the status of the condition changes. Returns true if the condition became True.
+func setNodeCondition(node *core.Node, condition core.NodeCondition, now meta.Time) bool {
+	condition.LastHeartbeatTime = now
+	condition.LastTransitionTime = now
+	for i, existing := range node.Status.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		node.Status.Conditions[i] = condition
+		return existing.Status != core.ConditionTrue && condition.Status == core.ConditionTrue
+	}
+	node.Status.Conditions = append(node.Status.Conditions, condition)
+	return condition.Status == core.ConditionTrue
+}
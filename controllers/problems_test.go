@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+func TestSetNodeCondition(t *testing.T) {
+	before := meta.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := meta.NewTime(before.Add(time.Hour))
+	node := &core.Node{Status: core.NodeStatus{Conditions: []core.NodeCondition{
+		{Type: core.NodeReady, Status: core.ConditionTrue},
+		{Type: WindowsSystemDiskPressure, Status: core.ConditionFalse, LastTransitionTime: before,
+			LastHeartbeatTime: before},
+	}}}
+
+	assert.False(t, setNodeCondition(node, core.NodeCondition{Type: WindowsSystemDiskPressure,
+		Status: core.ConditionFalse}, now))
+	assert.Equal(t, before, node.Status.Conditions[1].LastTransitionTime)
+	assert.Equal(t, now, node.Status.Conditions[1].LastHeartbeatTime)
+
+	assert.True(t, setNodeCondition(node, core.NodeCondition{Type: WindowsSystemDiskPressure,
+		Status: core.ConditionTrue}, now))
+	assert.Equal(t, now, node.Status.Conditions[1].LastTransitionTime)
+	assert.False(t, setNodeCondition(node, core.NodeCondition{Type: WindowsSystemDiskPressure,
+		Status: core.ConditionTrue}, now))
+
+	assert.True(t, setNodeCondition(node, core.NodeCondition{Type: WindowsServiceCrashLooping,
+		Status: core.ConditionTrue}, now))
+	assert.Len(t, node.Status.Conditions, 3)
+}
+
+func TestProblemConditions(t *testing.T) {
+	conditions := problemConditions(&windows.Problems{SystemDiskFreePercent: 25,
+		ServiceCrashes: map[string]int{"kubelet": 2}})
+	assert.Equal(t, core.ConditionFalse, conditions[0].Status)
+	assert.Equal(t, core.ConditionFalse, conditions[1].Status)
+
+	conditions = problemConditions(&windows.Problems{SystemDiskFreePercent: 5,
+		ServiceCrashes: map[string]int{"kubelet": 3, "kube-proxy": 4, "hybrid-overlay-node": 1}})
+	assert.Equal(t, core.ConditionTrue, conditions[0].Status)
+	assert.Equal(t, core.ConditionTrue, conditions[1].Status)
+	assert.Equal(t, "Services terminated unexpectedly at least 3 times within 1h0m0s: kube-proxy (4), kubelet (3)",
+		conditions[1].Message)
+}
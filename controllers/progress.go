@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+// configurationProgress reports the configuration steps completed for a Machine through events on the Machine and,
+// once it is registered, on its node
+type configurationProgress struct {
+	recorder record.EventRecorder
+	machine  *mapi.Machine
+	// lastStep is the last configuration step completed, empty if none was
+	lastStep windows.ConfigurationStep
+	// start is the time at which the configuration started
+	start time.Time
+	// lastCompletion is the time at which the last step completed, or the configuration started if none did
+	lastCompletion time.Time
+	// steps are the configuration steps completed, in order
+	steps []wmcoapi.ConfigurationStepRecord
+}
+
+// newConfigurationProgress returns the progress of a configuration of the given Machine starting now
+func newConfigurationProgress(recorder record.EventRecorder, machine *mapi.Machine) *configurationProgress {
+	now := time.Now()
+	return &configurationProgress{recorder: recorder, machine: machine, start: now, lastCompletion: now}
+}
+
+// report records the completion of the given configuration step
+func (p *configurationProgress) report(step windows.ConfigurationStep, node *core.Node) {
+	now := time.Now()
+	p.steps = append(p.steps, wmcoapi.ConfigurationStepRecord{Name: string(step),
+		Duration: meta.Duration{Duration: now.Sub(p.lastCompletion).Round(time.Second)}})
+	p.lastCompletion = now
+	p.lastStep = step
+	p.recorder.Eventf(p.machine, core.EventTypeNormal, string(step), "Machine %s configuration step %s completed",
+		p.machine.GetName(), step)
+	if node != nil {
+		p.recorder.Eventf(node, core.EventTypeNormal, string(step), "Node configuration step %s completed", step)
+	}
+}
+
+// failureMessage returns the message of the event reporting that the configuration failed, giving the last step
+// completed
+func (p *configurationProgress) failureMessage() string {
+	if p.lastStep == "" {
+		return fmt.Sprintf("Machine %s configuration failure before any step completed", p.machine.GetName())
+	}
+	return fmt.Sprintf("Machine %s configuration failure after step %s", p.machine.GetName(), p.lastStep)
+}
+
+// attempt returns the record of the configuration, ended now with the given error, if any
+func (p *configurationProgress) attempt(configErr error) wmcoapi.ConfigurationAttempt {
+	attempt := wmcoapi.ConfigurationAttempt{
+		StartTime: meta.NewTime(p.start),
+		Duration:  meta.Duration{Duration: time.Since(p.start).Round(time.Second)},
+		Version:   version.Get(),
+		Outcome:   wmcoapi.ConfigurationSucceeded,
+		Steps:     p.steps,
+	}
+	switch {
+	case isCancellation(configErr):
+		attempt.Outcome = wmcoapi.ConfigurationCancelled
+	case configErr != nil:
+		attempt.Outcome = wmcoapi.ConfigurationFailed
+		attempt.Error = configErr.Error()
+	}
+	return attempt
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// RebootRequestedAnnotation is applied by administrators to a Windows node to have WMCO reboot it safely. Its value
+	// is ignored, and it is removed once the reboot starts.
+	RebootRequestedAnnotation = "windowsmachineconfig.openshift.io/reboot-requested"
+	// RebootingAnnotation is applied to a node cordoned by WMCO while it is rebooted. The node is uncordoned once its
+	// services run and it is ready again.
+	RebootingAnnotation = "windowsmachineconfig.openshift.io/rebooting"
+)
+
+func init() {
+	disruptionAnnotations = append(disruptionAnnotations, RebootingAnnotation)
+}
+
+// reconcileReboot reboots the node of the given Machine if an administrator requested it through the reboot requested
+// annotation. The reboot waits for maintenance windows and for fewer than maxUnhealthyCount other nodes to be
+// unavailable: the node is cordoned and drained, rebooted, and uncordoned once its services run and it is ready.
+func (r *machineReconciliation) reconcileReboot(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	nodeName := node.GetName()
+	if _, rebooting := node.Annotations[RebootingAnnotation]; !rebooting {
+		if _, requested := node.Annotations[RebootRequestedAnnotation]; !requested {
+			return ctrl.Result{}, nil
+		}
+		if isNodeDisrupted(node) {
+			// Let the ongoing operation complete, the node is reconciled again once it does
+			log.Info("reboot waiting for the ongoing operation on the node to complete", "node", nodeName)
+			return ctrl.Result{}, nil
+		}
+		if r.config.DryRun {
+			reportDryRun(r.recorder, log, machine, "NodeReboot", "Machine %s node %s would be drained and rebooted",
+				machine.GetName(), nodeName)
+			return ctrl.Result{}, nil
+		}
+		// The request is removed along with the application of the rebooting annotation, so that the node is
+		// rebooted once per request
+		delete(node.Annotations, RebootRequestedAnnotation)
+		var result ctrl.Result
+		var err error
+		if node, result, err = r.startNodeDisruption(ctx, machine, node, RebootingAnnotation,
+			"reboot"); err != nil || !result.IsZero() {
+			return result, err
+		}
+		r.recorder.Eventf(machine, core.EventTypeNormal, "NodeRebootStarted",
+			"Machine %s node %s is being drained to be rebooted", machine.GetName(), nodeName)
+	}
+
+	drained, err := r.drainNode(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to drain node %s", nodeName)
+	}
+	if !drained {
+		log.Info("waiting for node to be drained", "node", nodeName)
+		return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := vm.Reboot(); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "NodeRebootFailure",
+			"Machine %s node %s could not be rebooted: %v", machine.GetName(), nodeName, err)
+		return ctrl.Result{}, err
+	}
+	if err := vm.WaitForServices(); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "services of node %s not running after reboot", nodeName)
+	}
+	if err := r.waitForNodeReady(ctx, nodeName); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.finishNodeDisruption(ctx, nodeName, RebootingAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("rebooted node", "node", nodeName)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "NodeRebooted", "Machine %s node %s was rebooted",
+		machine.GetName(), nodeName)
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// ReconfigureRequestedAnnotation is applied by administrators to a Windows node, or to its Machine, to have WMCO
+	// run the full configuration of the node again, such as after manual changes to the VM. Its value is ignored, and
+	// it is removed once the reconfiguration starts.
+	ReconfigureRequestedAnnotation = "windowsmachineconfig.openshift.io/reconfigure-requested"
+	// ReconfiguringAnnotation is applied to a node cordoned by WMCO while it is configured again. The node is
+	// uncordoned once its configuration succeeded.
+	ReconfiguringAnnotation = "windowsmachineconfig.openshift.io/reconfiguring"
+)
+
+func init() {
+	disruptionAnnotations = append(disruptionAnnotations, ReconfiguringAnnotation)
+}
+
+// reconcileReconfiguration configures the node of the given Machine again if an administrator requested it through
+// the reconfigure requested annotation of the node or of the Machine. The reconfiguration waits for maintenance
+// windows and for fewer than maxUnhealthyCount other nodes to be unavailable: the node is cordoned and drained, its VM
+// is configured as when the Machine was provisioned, and the node is uncordoned once the configuration succeeded. A
+// failed configuration is retried until it succeeds.
+func (r *machineReconciliation) reconcileReconfiguration(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	nodeName := node.GetName()
+	if _, reconfiguring := node.Annotations[ReconfiguringAnnotation]; !reconfiguring {
+		_, nodeRequested := node.Annotations[ReconfigureRequestedAnnotation]
+		_, machineRequested := machine.GetAnnotations()[ReconfigureRequestedAnnotation]
+		if !nodeRequested && !machineRequested {
+			return ctrl.Result{}, nil
+		}
+		if isNodeDisrupted(node) {
+			// Let the ongoing operation complete, the node is reconciled again once it does
+			log.Info("reconfiguration waiting for the ongoing operation on the node to complete", "node", nodeName)
+			return ctrl.Result{}, nil
+		}
+		if r.config.DryRun {
+			reportDryRun(r.recorder, log, machine, "NodeReconfiguration",
+				"Machine %s node %s would be drained and configured again", machine.GetName(), nodeName)
+			return ctrl.Result{}, nil
+		}
+		// The requests are removed along with the application of the reconfiguring annotation, so that the node is
+		// configured again once per request
+		delete(node.Annotations, ReconfigureRequestedAnnotation)
+		var result ctrl.Result
+		var err error
+		if node, result, err = r.startNodeDisruption(ctx, machine, node, ReconfiguringAnnotation,
+			"reconfiguration"); err != nil || !result.IsZero() {
+			return result, err
+		}
+		if machineRequested {
+			delete(machine.Annotations, ReconfigureRequestedAnnotation)
+			if err := r.client.Update(ctx, machine); err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "error removing reconfiguration request from machine %s",
+					machine.GetName())
+			}
+		}
+		r.recorder.Eventf(machine, core.EventTypeNormal, "NodeReconfigurationStarted",
+			"Machine %s node %s is being drained to be configured again", machine.GetName(), nodeName)
+	}
+
+	drained, err := r.drainNode(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to drain node %s", nodeName)
+	}
+	if !drained {
+		log.Info("waiting for node to be drained", "node", nodeName)
+		return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+	ipAddress, instanceID, err := GetMachineInstance(machine, r.config.NodeIPCIDRs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	sshAddress, err := r.machineSSHAddress(machine, ipAddress)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	labels, taints, err := getDesiredNodeMetadata(ctx, r.client, machine, r.config.NodeTaints)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get labels and taints for machine %s", machine.GetName())
+	}
+	log.Info("configuring node again", "node", nodeName)
+	progress := newConfigurationProgress(r.recorder, machine)
+	err = r.addWorkerNode(ctx, ipAddress, sshAddress, instanceID, machine, r.platform, labels, taints,
+		progress.report)
+	if isCancellation(err) {
+		// The reconfiguration is run again once the operator restarts, unless the Machine is being deleted
+		log.Info("reconfiguration interrupted", "lastStep", progress.lastStep)
+		return ctrl.Result{}, nil
+	}
+	if recordErr := r.recordConfiguration(ctx, machine, progress, err); recordErr != nil {
+		log.Error(recordErr, "unable to record configuration")
+	}
+	if err != nil {
+		r.recorder.Event(machine, core.EventTypeWarning, "NodeReconfigurationFailure", progress.failureMessage())
+		return ctrl.Result{}, err
+	}
+	if err := r.finishNodeDisruption(ctx, nodeName, ReconfiguringAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("node configured again", "node", nodeName)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "NodeReconfigured", "Machine %s node %s was configured again",
+		machine.GetName(), nodeName)
+	return ctrl.Result{}, nil
+}
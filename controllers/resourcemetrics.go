@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// ResourceMetricsUnavailable is the node condition which is True when the kubelet of a Windows node does not serve
+	// the CPU and memory usage of the node and its pods, which metrics-server and the HorizontalPodAutoscalers rely on
+	ResourceMetricsUnavailable core.NodeConditionType = "ResourceMetricsUnavailable"
+	// ResourceMetricsRecoveringAnnotation is applied to a node while its kubelet is restarted to recover its resource
+	// metrics
+	ResourceMetricsRecoveringAnnotation = "windowsmachineconfig.openshift.io/resource-metrics-recovering"
+	// ResourceMetricsCheckedAnnotation holds the time at which the resource metrics of the node were last checked
+	ResourceMetricsCheckedAnnotation = "windowsmachineconfig.openshift.io/resource-metrics-checked"
+
+	// resourceMetricsCheckPeriod is the interval at which the resource metrics of configured Windows nodes are checked
+	resourceMetricsCheckPeriod = 30 * time.Minute
+	// resourceMetricsRetryDelay is the time given to kubelet to collect the resource metrics again once restarted
+	resourceMetricsRetryDelay = 5 * time.Minute
+	// resourceMetricsGracePeriod is the time pods must have been running for before their usage is expected
+	resourceMetricsGracePeriod = 5 * time.Minute
+	// maxReportedPods is the number of pods missing resource metrics listed in the condition message
+	maxReportedPods = 5
+	// kubeletRestartedReason is the reason of the ResourceMetricsUnavailable condition once kubelet was restarted to
+	// recover the resource metrics
+	kubeletRestartedReason = "KubeletRestarted"
+)
+
+// resourceMetricsAvailable is 1 when the kubelet of each Windows Machine serves the resource metrics of the node and
+// its pods, else 0
+var resourceMetricsAvailable = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "windows_machine_resource_metrics_available",
+	Help: "1 when the kubelet of the Windows Machine serves the CPU and memory usage of the node and its pods, else 0",
+}, []string{"machine"})
+
+func init() {
+	metrics.Registry.MustRegister(resourceMetricsAvailable)
+	disruptionAnnotations = append(disruptionAnnotations, ResourceMetricsRecoveringAnnotation)
+}
+
+// statsSummary is the part of the summary of resource statistics served by kubelet which metrics-server reads
+type statsSummary struct {
+	Node struct {
+		CPU    *cpuStats    `json:"cpu"`
+		Memory *memoryStats `json:"memory"`
+	} `json:"node"`
+	Pods []podStats `json:"pods"`
+}
+
+// podStats are the resource statistics of a pod in the stats summary
+type podStats struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"podRef"`
+	CPU    *cpuStats    `json:"cpu"`
+	Memory *memoryStats `json:"memory"`
+}
+
+// cpuStats is the CPU usage in the stats summary
+type cpuStats struct {
+	UsageNanoCores *uint64 `json:"usageNanoCores"`
+}
+
+// memoryStats is the memory usage in the stats summary
+type memoryStats struct {
+	WorkingSetBytes *uint64 `json:"workingSetBytes"`
+}
+
+// reconcileResourceMetrics checks that the kubelet of the node of the given Machine serves the CPU and memory usage of
+// the node and its running pods through its stats summary API, recording it in the resource metrics metric and in the
+// ResourceMetricsUnavailable condition of the node. Kubelet is restarted once when the resource metrics are missing,
+// as a node disruption, and the node is reported as failed to repair if they are still missing afterwards. The check
+// runs once per resourceMetricsCheckPeriod, or resourceMetricsRetryDelay after kubelet was restarted.
+func (r *machineReconciliation) reconcileResourceMetrics(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.config.ProblemDetection || r.config.DryRun || !isNodeReady(node) {
+		return ctrl.Result{}, nil
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	// The reason the resource metrics were last reported unavailable for, empty if they were available
+	var unavailableReason string
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == ResourceMetricsUnavailable && condition.Status == core.ConditionTrue {
+			unavailableReason = condition.Reason
+		}
+	}
+	period := resourceMetricsCheckPeriod
+	if unavailableReason == kubeletRestartedReason {
+		period = resourceMetricsRetryDelay
+	}
+	_, recovering := node.Annotations[ResourceMetricsRecoveringAnnotation]
+	if remaining := checkRemaining(node, ResourceMetricsCheckedAnnotation, period, time.Now()); remaining > 0 &&
+		!recovering {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	problems, err := r.resourceMetricsProblems(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(problems) == 0 {
+		if recovering {
+			if err := r.finishNodeDisruption(ctx, node.GetName(), ResourceMetricsRecoveringAnnotation); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		resourceMetricsAvailable.WithLabelValues(machine.GetName()).Set(1)
+		if err := r.setNodeConditions(ctx, machine, node.GetName(), core.NodeCondition{
+			Type: ResourceMetricsUnavailable, Status: core.ConditionFalse, Reason: "Available",
+			Message: "kubelet serves the resource metrics of the node and its pods"}); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.recordCheck(ctx, node.GetName(), ResourceMetricsCheckedAnnotation); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: resourceMetricsCheckPeriod}, nil
+	}
+	resourceMetricsAvailable.WithLabelValues(machine.GetName()).Set(0)
+	message := "kubelet does not serve the resource metrics of the node and its pods: " + strings.Join(problems, "; ")
+	if unavailableReason == kubeletRestartedReason || unavailableReason == "RepairFailed" {
+		// Restarting kubelet did not help, restarting it again would not either
+		if err := r.setNodeConditions(ctx, machine, node.GetName(), core.NodeCondition{
+			Type: ResourceMetricsUnavailable, Status: core.ConditionTrue, Reason: "RepairFailed",
+			Message: message + ", after restarting kubelet"}); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.recordCheck(ctx, node.GetName(), ResourceMetricsCheckedAnnotation); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: resourceMetricsCheckPeriod}, nil
+	}
+	if !recovering {
+		var result ctrl.Result
+		if node, result, err = r.startNodeDisruption(ctx, machine, node.DeepCopy(),
+			ResourceMetricsRecoveringAnnotation, "resource metrics recovery"); err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+	log.Info("resource metrics unavailable, restarting kubelet", "node", node.GetName(), "problems", problems)
+	vm, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := vm.RestartKubelet(); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to restart kubelet of node %s", node.GetName())
+	}
+	if err := r.finishNodeDisruption(ctx, node.GetName(), ResourceMetricsRecoveringAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.recorder.Eventf(machine, core.EventTypeNormal, "KubeletRestarted",
+		"Machine %s node %s kubelet restarted to recover its resource metrics", machine.GetName(), node.GetName())
+	if err := r.setNodeConditions(ctx, machine, node.GetName(), core.NodeCondition{
+		Type: ResourceMetricsUnavailable, Status: core.ConditionTrue, Reason: kubeletRestartedReason,
+		Message: message + ", kubelet was restarted"}); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.recordCheck(ctx, node.GetName(), ResourceMetricsCheckedAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: resourceMetricsRetryDelay}, nil
+}
+
+// resourceMetricsProblems returns the resource metrics of the given node and its running pods missing from the stats
+// summary served by its kubelet, read through the node proxy of the API server like metrics-server does
+func (r *WindowsMachineReconciler) resourceMetricsProblems(ctx context.Context, node *core.Node) ([]string, error) {
+	data, err := r.k8sclientset.CoreV1().RESTClient().Get().Resource("nodes").Name(node.GetName()).
+		SubResource("proxy").Suffix("stats", "summary").DoRaw(ctx)
+	if err != nil {
+		if k8sapierrors.IsForbidden(err) {
+			return nil, errors.Wrapf(err, "unable to get stats summary of node %s", node.GetName())
+		}
+		return []string{"the stats summary API is unavailable: " + err.Error()}, nil
+	}
+	summary := &statsSummary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return []string{"the stats summary is invalid: " + err.Error()}, nil
+	}
+	// The pods of the node are listed from the API server rather than cached, as they are only listed once per check
+	pods, err := r.k8sclientset.CoreV1().Pods(meta.NamespaceAll).List(ctx, meta.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.GetName()).String()})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list pods of node %s", node.GetName())
+	}
+	return missingResourceMetrics(summary, pods.Items, time.Now()), nil
+}
+
+// missingResourceMetrics returns the CPU and memory usage missing from the given stats summary, for the node and for
+// the given pods of the node which are running since more than the grace period at the given time
+func missingResourceMetrics(summary *statsSummary, pods []core.Pod, now time.Time) []string {
+	var problems []string
+	if summary.Node.CPU == nil || summary.Node.CPU.UsageNanoCores == nil {
+		problems = append(problems, "the CPU usage of the node is missing")
+	}
+	if summary.Node.Memory == nil || summary.Node.Memory.WorkingSetBytes == nil {
+		problems = append(problems, "the memory usage of the node is missing")
+	}
+	served := make(map[string]bool, len(summary.Pods))
+	for _, pod := range summary.Pods {
+		served[pod.PodRef.Namespace+"/"+pod.PodRef.Name] = pod.CPU != nil && pod.CPU.UsageNanoCores != nil &&
+			pod.Memory != nil && pod.Memory.WorkingSetBytes != nil
+	}
+	var missing []string
+	for _, pod := range pods {
+		if pod.Status.Phase != core.PodRunning || pod.Status.StartTime == nil ||
+			now.Sub(pod.Status.StartTime.Time) < resourceMetricsGracePeriod {
+			continue
+		}
+		if name := pod.GetNamespace() + "/" + pod.GetName(); !served[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return problems
+	}
+	sort.Strings(missing)
+	listed := missing
+	if len(listed) > maxReportedPods {
+		listed = append(listed[:maxReportedPods:maxReportedPods],
+			fmt.Sprintf("and %d more", len(missing)-maxReportedPods))
+	}
+	return append(problems, fmt.Sprintf("the CPU or memory usage of %d running pods is missing: %s", len(missing),
+		strings.Join(listed, ", ")))
+}
+
+// deleteResourceMetricsMetrics deletes the resource metrics metric of the given Machine
+func deleteResourceMetricsMetrics(machineName string) {
+	resourceMetricsAvailable.DeleteLabelValues(machineName)
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMissingResourceMetrics(t *testing.T) {
+	now := time.Now()
+	runningPod := func(name string, started time.Duration) core.Pod {
+		startTime := meta.NewTime(now.Add(-started))
+		return core.Pod{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: name},
+			Status: core.PodStatus{Phase: core.PodRunning, StartTime: &startTime}}
+	}
+	complete := `{"node":{"cpu":{"usageNanoCores":1},"memory":{"workingSetBytes":1}},"pods":[` +
+		`{"podRef":{"namespace":"ns","name":"a"},"cpu":{"usageNanoCores":1},"memory":{"workingSetBytes":1}}]}`
+	testCases := []struct {
+		name     string
+		summary  string
+		pods     []core.Pod
+		expected []string
+	}{
+		{
+			name:    "available",
+			summary: complete,
+			pods:    []core.Pod{runningPod("a", time.Hour), runningPod("b", time.Minute)},
+		},
+		{
+			name:     "node usage missing",
+			summary:  `{"node":{"cpu":{},"memory":{"workingSetBytes":1}}}`,
+			expected: []string{"the CPU usage of the node is missing"},
+		},
+		{
+			name: "pod usage missing",
+			summary: `{"node":{"cpu":{"usageNanoCores":1},"memory":{"workingSetBytes":1}},"pods":[` +
+				`{"podRef":{"namespace":"ns","name":"a"},"cpu":{"usageNanoCores":1}}]}`,
+			pods:     []core.Pod{runningPod("a", time.Hour), runningPod("b", time.Hour)},
+			expected: []string{"the CPU or memory usage of 2 running pods is missing: ns/a, ns/b"},
+		},
+		{
+			name:    "pods not running",
+			summary: complete,
+			pods: []core.Pod{{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "c"},
+				Status: core.PodStatus{Phase: core.PodSucceeded}}},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			summary := &statsSummary{}
+			require.NoError(t, json.Unmarshal([]byte(test.summary), summary))
+			assert.Equal(t, test.expected, missingResourceMetrics(summary, test.pods, now))
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"strings"
 
 	"github.com/go-logr/logr"
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
 	core "k8s.io/api/core/v1"
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,9 +16,11 @@ import (
 	kubeTypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -24,28 +28,37 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
 	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
 	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
 	"github.com/openshift/windows-machine-config-operator/pkg/signer"
 )
 
 const (
-	userDataSecret    = "windows-user-data"
 	userDataNamespace = "openshift-machine-api"
 )
 
 // NewSecretReconciler returns a pointer to a SecretReconciler
-func NewSecretReconciler(mgr manager.Manager, watchNamespace string) *SecretReconciler {
+func NewSecretReconciler(mgr manager.Manager, watchNamespace string,
+	defaultConfig operatorconfig.Config) (*SecretReconciler, error) {
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes clientset")
+	}
 	reconciler := &SecretReconciler{
 		client:         mgr.GetClient(),
 		scheme:         mgr.GetScheme(),
 		log:            ctrl.Log.WithName("controller").WithName("secret"),
-		watchNamespace: watchNamespace}
-	return reconciler
+		k8sclientset:   clientset,
+		recorder:       newThrottledRecorder(mgr.GetEventRecorderFor("secret")),
+		watchNamespace: watchNamespace,
+		defaultConfig:  defaultConfig}
+	return reconciler, nil
 }
 
-// SetupWithManager sets up a new Secret controller
-func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+// SetupWithManager sets up a new Secret controller with the given options
+func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
 	// Check that the private key exists, if it doesn't, log a warning
 	_, err := secrets.GetPrivateKey(kubeTypes.NamespacedName{Namespace: r.watchNamespace,
 		Name: secrets.PrivateKeySecret}, mgr.GetClient())
@@ -54,16 +67,19 @@ func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}
 	privateKeyPredicate := builder.WithPredicates(predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
-			return isPrivateKeySecret(e.Object, r.watchNamespace)
+			return holdsPrivateKey(e.Object, r.watchNamespace)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
-			return isPrivateKeySecret(e.Object, r.watchNamespace)
+			return holdsPrivateKey(e.Object, r.watchNamespace)
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			// get update event only when secret data is changed
-			if isPrivateKeySecret(e.ObjectNew, r.watchNamespace) {
-				if string(e.ObjectOld.(*core.Secret).Data[secrets.PrivateKeySecretKey]) !=
-					string(e.ObjectNew.(*core.Secret).Data[secrets.PrivateKeySecretKey]) {
+			if holdsPrivateKey(e.ObjectOld, r.watchNamespace) || holdsPrivateKey(e.ObjectNew, r.watchNamespace) {
+				oldData, newData := e.ObjectOld.(*core.Secret).Data, e.ObjectNew.(*core.Secret).Data
+				if string(oldData[secrets.PrivateKeySecretKey]) != string(newData[secrets.PrivateKeySecretKey]) ||
+					string(oldData[secrets.SSHCAKeySecretKey]) != string(newData[secrets.SSHCAKeySecretKey]) ||
+					string(oldData[secrets.PreviousPrivateKeySecretKey]) !=
+						string(newData[secrets.PreviousPrivateKeySecretKey]) {
 					return true
 				}
 			}
@@ -88,21 +104,35 @@ func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			return false
 		},
 	})
+	// Watch for the private key secret of Windows MachineSets being set, changed or unset, so that the userData secret
+	// of the private key secret is generated, or deleted once no longer used
+	machineSetPredicate := builder.WithPredicates(predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isWindowsMachineSet(e.Object) && e.Object.GetAnnotations()[PrivateKeySecretAnnotation] != ""
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isWindowsMachineSet(e.Object) && e.Object.GetAnnotations()[PrivateKeySecretAnnotation] != ""
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return (isWindowsMachineSet(e.ObjectOld) || isWindowsMachineSet(e.ObjectNew)) &&
+				e.ObjectOld.GetAnnotations()[PrivateKeySecretAnnotation] !=
+					e.ObjectNew.GetAnnotations()[PrivateKeySecretAnnotation]
+		},
+	})
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&core.Secret{}, privateKeyPredicate).
 		Watches(&source.Kind{Type: &core.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.mapToPrivateKeySecret),
 			mappingPredicate).
+		Watches(&source.Kind{Type: &mapi.MachineSet{}},
+			handler.EnqueueRequestsFromMapFunc(r.mapMachineSetToPrivateKeySecret), machineSetPredicate).
+		WithOptions(options).
 		Complete(r)
 }
 
-// isUserDataSecret returns true if the provided object is the userData Secret
+// isUserDataSecret returns true if the provided object is the userData Secret, or the userData Secret generated from
+// the private key secret of a MachineSet
 func isUserDataSecret(obj client.Object) bool {
-	return obj.GetName() == userDataSecret && obj.GetNamespace() == userDataNamespace
-}
-
-// isPrivateKeySecret returns true if the provided object is the private key secret
-func isPrivateKeySecret(obj client.Object, keyNamespace string) bool {
-	return obj.GetName() == secrets.PrivateKeySecret && obj.GetNamespace() == keyNamespace
+	return secrets.PrivateKeySecretName(obj.GetName()) != "" && obj.GetNamespace() == userDataNamespace
 }
 
 // SecretReconciler is used to create a controller which manages Secret objects
@@ -112,8 +142,13 @@ type SecretReconciler struct {
 	client client.Client
 	scheme *runtime.Scheme
 	log    logr.Logger
+	// k8sclientset holds the kube client used to load the operator configuration
+	k8sclientset *kubernetes.Clientset
+	recorder     record.EventRecorder
 	// watchNamespace is the namespace the operator is watching as defined by the operator CSV
 	watchNamespace string
+	// defaultConfig holds the operator configuration used for the settings missing from the configuration ConfigMap
+	defaultConfig operatorconfig.Config
 }
 
 // Reconcile reads that state of the cluster for a Secret object and makes changes based on the state read
@@ -121,7 +156,31 @@ type SecretReconciler struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *SecretReconciler) Reconcile(ctx context.Context, request ctrl.Request) (reconcile.Result, error) {
+	result, err := r.reconcileUserData(ctx, request)
+	if err != nil || !result.IsZero() {
+		return result, err
+	}
+	// The private key age metrics only report the age of the cloud-private-key secret
+	if request.Name != secrets.PrivateKeySecret {
+		return reconcile.Result{}, nil
+	}
+	return r.reconcilePrivateKeyAge(ctx, request.NamespacedName)
+}
+
+// reconcileUserData keeps the userData secret in sync with the private key secret of the given request. The userData
+// secret of the private key secret of MachineSets is deleted once no Windows MachineSet uses the private key secret.
+func (r *SecretReconciler) reconcileUserData(ctx context.Context, request ctrl.Request) (reconcile.Result, error) {
 	log := r.log.WithValues("secret", request.NamespacedName)
+	userDataName := secrets.UserDataSecretName(request.Name)
+	if request.Name != secrets.PrivateKeySecret {
+		used, err := r.isMachineSetPrivateKeySecret(ctx, request.Name)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !used {
+			return reconcile.Result{}, r.deleteUserData(ctx, request.Name)
+		}
+	}
 
 	privateKey, err := secrets.GetPrivateKey(request.NamespacedName, r.client)
 	if err != nil {
@@ -133,48 +192,90 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 		}
 		return reconcile.Result{}, errors.Wrapf(err, "unable to get secret %s", request.NamespacedName)
 	}
-	// Generate expected userData based on the existing private key
-	validUserData, err := secrets.GenerateUserData(privateKey)
+	caKey, err := secrets.GetSSHCAKey(request.NamespacedName, r.client)
 	if err != nil {
-		return reconcile.Result{}, errors.Wrapf(err, "error generating %s secret", userDataSecret)
+		return reconcile.Result{}, errors.Wrapf(err, "unable to get secret %s", request.NamespacedName)
+	}
+	previousKey, err := secrets.GetPreviousPrivateKey(request.NamespacedName, r.client)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "unable to get secret %s", request.NamespacedName)
+	}
+	// Generate expected userData based on the existing private key and SSH certificate authority
+	validUserData, err := secrets.GenerateUserData(privateKey, caKey)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "error generating %s secret", userDataName)
+	}
+	validUserData.Name = userDataName
+	if request.Name != secrets.PrivateKeySecret {
+		// The userData secret records the private key secret it is generated from, so that it is only deleted by WMCO
+		// if it generated it
+		validUserData.Annotations = map[string]string{PrivateKeySecretAnnotation: request.Name}
 	}
 
 	userData := &core.Secret{}
 	// Fetch UserData instance
-	err = r.client.Get(ctx, kubeTypes.NamespacedName{Name: userDataSecret, Namespace: userDataNamespace}, userData)
+	err = r.client.Get(ctx, kubeTypes.NamespacedName{Name: userDataName, Namespace: userDataNamespace}, userData)
 	if err != nil && k8sapierrors.IsNotFound(err) {
 		// Secret is deleted
-		log.Info("secret not found, creating the secret", "name", userDataSecret)
-		err = r.client.Create(ctx, validUserData)
+		log.Info("secret not found, creating the secret", "name", userDataName)
+		_, err = apply.Secret(ctx, r.k8sclientset, validUserData)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
 		// Secret created successfully - don't requeue
 		return reconcile.Result{}, nil
 	} else if err != nil {
-		log.Error(err, "error retrieving the secret", "name", userDataSecret)
+		log.Error(err, "error retrieving the secret", "name", userDataName)
 		return reconcile.Result{}, err
 	} else if string(userData.Data["userData"][:]) == string(validUserData.Data["userData"][:]) {
 		// valid userData secret already exists
 		return reconcile.Result{}, nil
 	} else {
 		// userdata secret data does not match what is expected
-		// Mark nodes configured with the previous private key for deletion
-		signer, err := signer.Create(privateKey)
+		// Mark nodes configured with an outdated private key, or SSH certificate authority, for deletion. Without a
+		// certificate authority, nodes configured with the previous private key are switched over in place instead.
+		keySigner, err := signer.Create(privateKey)
 		if err != nil {
 			return reconcile.Result{}, errors.Wrap(err, "error creating signer from private key")
 		}
+		var caSigner ssh.Signer
+		if caKey != nil {
+			if caSigner, err = signer.Create(caKey); err != nil {
+				return reconcile.Result{}, errors.Wrap(err, "error creating signer from SSH certificate authority")
+			}
+		}
 		nodes := &core.NodeList{}
 		err = r.client.List(ctx, nodes, client.MatchingLabels{core.LabelOSStable: "windows"})
 		if err != nil {
 			return reconcile.Result{}, errors.Wrapf(err, "error getting node list")
 		}
-		expectedPubKeyAnno := nodeconfig.CreatePubKeyHashAnnotation(signer.PublicKey())
+		// Only the nodes configured with the private key secret are affected by its changes
+		nodeSecrets, err := r.nodePrivateKeySecrets(ctx)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		expectedPubKeyAnno := nodeconfig.CreatePubKeyHashAnnotation(signer.TrustedKey(keySigner, caSigner))
+		var previousPubKeyAnno string
+		if previousKey != nil && caSigner == nil {
+			previousSigner, err := signer.Create(previousKey)
+			if err != nil {
+				return reconcile.Result{}, errors.Wrap(err, "error creating signer from previous private key")
+			}
+			previousPubKeyAnno = nodeconfig.CreatePubKeyHashAnnotation(previousSigner.PublicKey())
+		}
 		escapedPubKeyAnnotation := strings.Replace(nodeconfig.PubKeyHashAnnotation, "/", "~1", -1)
 		patchData := fmt.Sprintf(`[{"op":"add","path":"/metadata/annotations/%s","value":""}]`, escapedPubKeyAnnotation)
 		for _, node := range nodes.Items {
+			nodeSecret, found := nodeSecrets[node.GetName()]
+			if !found {
+				nodeSecret = secrets.PrivateKeySecret
+			}
+			if nodeSecret != request.Name {
+				continue
+			}
 			existingPubKeyAnno := node.Annotations[nodeconfig.PubKeyHashAnnotation]
-			if existingPubKeyAnno == expectedPubKeyAnno {
+			if existingPubKeyAnno == expectedPubKeyAnno ||
+				(previousPubKeyAnno != "" && existingPubKeyAnno == previousPubKeyAnno) {
 				continue
 			}
 			node.Annotations[nodeconfig.PubKeyHashAnnotation] = ""
@@ -187,8 +288,8 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 		}
 
 		// Set userdata to expected value
-		log.Info("updating secret", "name", userDataSecret)
-		err = r.client.Update(ctx, validUserData)
+		log.Info("updating secret", "name", userDataName)
+		_, err = apply.Secret(ctx, r.k8sclientset, validUserData)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
@@ -226,9 +327,78 @@ func (r *SecretReconciler) RemoveInvalidAnnotationsFromLinuxNodes(config *rest.C
 	return nil
 }
 
-// mapToPrivateKeySecret is a mapping function that will always return a request for the cloud private key secret
-func (r *SecretReconciler) mapToPrivateKeySecret(_ client.Object) []reconcile.Request {
+// mapToPrivateKeySecret is a mapping function that returns a request for the private key secret the given userData
+// secret is generated from
+func (r *SecretReconciler) mapToPrivateKeySecret(obj client.Object) []reconcile.Request {
 	return []reconcile.Request{
-		{NamespacedName: kubeTypes.NamespacedName{Namespace: r.watchNamespace, Name: secrets.PrivateKeySecret}},
+		{NamespacedName: kubeTypes.NamespacedName{Namespace: r.watchNamespace,
+			Name: secrets.PrivateKeySecretName(obj.GetName())}},
+	}
+}
+
+// mapMachineSetToPrivateKeySecret is a mapping function that returns a request for the private key secret of the
+// given MachineSet
+func (r *SecretReconciler) mapMachineSetToPrivateKeySecret(obj client.Object) []reconcile.Request {
+	secretName := obj.GetAnnotations()[PrivateKeySecretAnnotation]
+	if secretName == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: kubeTypes.NamespacedName{Namespace: r.watchNamespace, Name: secretName}},
+	}
+}
+
+// isMachineSetPrivateKeySecret returns true if a Windows MachineSet is configured with the private key secret with the
+// given name
+func (r *SecretReconciler) isMachineSetPrivateKeySecret(ctx context.Context, secretName string) (bool, error) {
+	machineSets := &mapi.MachineSetList{}
+	if err := r.client.List(ctx, machineSets, client.InNamespace(userDataNamespace)); err != nil {
+		return false, errors.Wrap(err, "error getting MachineSet list")
 	}
+	for i := range machineSets.Items {
+		if isWindowsMachineSet(&machineSets.Items[i]) &&
+			machineSets.Items[i].GetAnnotations()[PrivateKeySecretAnnotation] == secretName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// nodePrivateKeySecrets maps the names of the Windows nodes configured with the private key secret of their
+// MachineSet to the name of the secret
+func (r *SecretReconciler) nodePrivateKeySecrets(ctx context.Context) (map[string]string, error) {
+	machineSets := &mapi.MachineSetList{}
+	if err := r.client.List(ctx, machineSets, client.InNamespace(userDataNamespace)); err != nil {
+		return nil, errors.Wrap(err, "error getting MachineSet list")
+	}
+	machines := &mapi.MachineList{}
+	if err := r.client.List(ctx, machines, client.InNamespace(userDataNamespace),
+		client.MatchingLabels{MachineOSLabel: "Windows"}); err != nil {
+		return nil, errors.Wrap(err, "error getting Machine list")
+	}
+	return nodePrivateKeySecrets(machines.Items, machineSets.Items), nil
+}
+
+// deleteUserData deletes the userData secret generated from the private key secret with the given name, if it exists
+func (r *SecretReconciler) deleteUserData(ctx context.Context, privateKeySecret string) error {
+	name := secrets.UserDataSecretName(privateKeySecret)
+	userData := &core.Secret{}
+	if err := r.client.Get(ctx, kubeTypes.NamespacedName{Namespace: userDataNamespace, Name: name},
+		userData); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "unable to get secret %s", name)
+	}
+	if userData.GetAnnotations()[PrivateKeySecretAnnotation] != privateKeySecret {
+		// The secret was not generated by WMCO
+		return nil
+	}
+	err := r.k8sclientset.CoreV1().Secrets(userDataNamespace).Delete(ctx, name,
+		meta.DeleteOptions{Preconditions: meta.NewUIDPreconditions(string(userData.GetUID()))})
+	if err != nil && !k8sapierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "error deleting secret %s", name)
+	}
+	r.log.Info("deleted secret of unused private key secret", "name", name, "privateKeySecret", privateKeySecret)
+	return nil
 }
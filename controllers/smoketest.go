@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/retry"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+const (
+	// SmokeTestedAnnotation is applied to a node once it passed the smoke test. The value is the operator version
+	// the node was tested with.
+	SmokeTestedAnnotation = "windowsmachineconfig.openshift.io/smoke-tested"
+	// smokeTestPodPrefix is the prefix of the name of the test pod scheduled on the node being tested
+	smokeTestPodPrefix = "windows-smoke-test-"
+	// smokeTestPort is the port the test pod serves HTTP on
+	smokeTestPort = 8080
+	// smokeTestRequeueDelay is the time to wait before checking on the test pod again
+	smokeTestRequeueDelay = 15 * time.Second
+	// smokeTestRetryDelay is the time to wait before testing a node which failed the smoke test again
+	smokeTestRetryDelay = 30 * time.Minute
+	// smokeTestRequestTimeout is the timeout of the request sent to the test pod
+	smokeTestRequestTimeout = 5 * time.Second
+)
+
+// reconcileSmokeTest runs the smoke test of the node of the given configured Machine, if it did not pass it yet: a test
+// pod is scheduled on the node, and must become ready and answer a request sent by WMCO across the pod network. The
+// result is recorded in the SmokeTestPassed condition of the WindowsNode and reported through an event.
+func (r *machineReconciliation) reconcileSmokeTest(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.config.SmokeTest || node.Annotations[SmokeTestedAnnotation] == version.Get() || !isNodeReady(node) {
+		return ctrl.Result{}, nil
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "SmokeTest", "Machine %s node %s would be smoke tested with a test pod",
+			machine.GetName(), node.GetName())
+		return ctrl.Result{}, nil
+	}
+
+	podName := smokeTestPodPrefix + node.GetName()
+	pod, err := r.k8sclientset.CoreV1().Pods(r.watchNamespace).Get(ctx, podName, meta.GetOptions{})
+	if err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "error getting smoke test pod %s", podName)
+		}
+		log.Info("scheduling smoke test pod", "node", node.GetName())
+		if _, err = r.k8sclientset.CoreV1().Pods(r.watchNamespace).Create(ctx,
+			newSmokeTestPod(podName, node.GetName(), r.config.SmokeTestImage), meta.CreateOptions{}); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "error creating smoke test pod %s", podName)
+		}
+		condition := meta.Condition{Type: wmcoapi.SmokeTestPassedCondition, Status: meta.ConditionUnknown,
+			Reason: "Running", Message: "A test pod is running on the node"}
+		if err := r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+			apimeta.SetStatusCondition(&status.Conditions, condition)
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: smokeTestRequeueDelay}, nil
+	}
+
+	testErr := errors.Errorf("test pod %s did not become ready", podName)
+	if isPodReady(pod) {
+		testErr = checkSmokeTestPod(pod)
+	}
+	if testErr != nil && time.Since(pod.GetCreationTimestamp().Time) < retry.Timeout {
+		log.V(1).Info("waiting for smoke test pod", "node", node.GetName(), "status", testErr.Error())
+		return ctrl.Result{RequeueAfter: smokeTestRequeueDelay}, nil
+	}
+
+	// The test pod is not needed anymore, whatever the result
+	if err := r.k8sclientset.CoreV1().Pods(r.watchNamespace).Delete(ctx, podName,
+		meta.DeleteOptions{}); err != nil && !k8sapierrors.IsNotFound(err) {
+		return ctrl.Result{}, errors.Wrapf(err, "error deleting smoke test pod %s", podName)
+	}
+	condition := meta.Condition{Type: wmcoapi.SmokeTestPassedCondition, Status: meta.ConditionTrue, Reason: "Passed",
+		Message: "A test pod ran on the node and was reachable across the pod network"}
+	if testErr != nil {
+		condition.Status = meta.ConditionFalse
+		condition.Reason = "Failed"
+		condition.Message = testErr.Error()
+	}
+	if err := r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		apimeta.SetStatusCondition(&status.Conditions, condition)
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	if testErr != nil {
+		log.Info("smoke test failed", "node", node.GetName(), "error", testErr.Error())
+		r.recorder.Eventf(machine, core.EventTypeWarning, "SmokeTestFailed", "Machine %s node %s failed the smoke "+
+			"test: %v", machine.GetName(), node.GetName(), testErr)
+		return ctrl.Result{RequeueAfter: smokeTestRetryDelay}, nil
+	}
+
+	node.Annotations[SmokeTestedAnnotation] = version.Get()
+	if _, err := r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager}); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "error annotating node %s", node.GetName())
+	}
+	log.Info("smoke test passed", "node", node.GetName())
+	r.recorder.Eventf(machine, core.EventTypeNormal, "SmokeTestPassed", "Machine %s node %s passed the smoke test",
+		machine.GetName(), node.GetName())
+	return ctrl.Result{}, nil
+}
+
+// checkSmokeTestPod sends a request to the given test pod across the pod network, and checks that the pod answers
+// with its name
+func checkSmokeTestPod(pod *core.Pod) error {
+	if pod.Status.PodIP == "" {
+		return errors.Errorf("test pod %s has no IP address", pod.GetName())
+	}
+	url := fmt.Sprintf("http://%s/hostname", net.JoinHostPort(pod.Status.PodIP, fmt.Sprint(smokeTestPort)))
+	client := http.Client{Timeout: smokeTestRequestTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return errors.Wrapf(err, "test pod %s is not reachable", pod.GetName())
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "error reading the answer of test pod %s", pod.GetName())
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != pod.GetName() {
+		return errors.Errorf("unexpected answer of test pod %s: %s %q", pod.GetName(), resp.Status, body)
+	}
+	return nil
+}
+
+// newSmokeTestPod returns a test pod serving the agnhost netexec endpoints, which must run on the given node
+func newSmokeTestPod(name, nodeName, image string) *core.Pod {
+	return &core.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app": "windows-smoke-test"},
+		},
+		Spec: core.PodSpec{
+			Containers: []core.Container{{
+				Name:  "netexec",
+				Image: image,
+				Args:  []string{"netexec", fmt.Sprintf("--http-port=%d", smokeTestPort)},
+				Ports: []core.ContainerPort{{ContainerPort: smokeTestPort}},
+				ReadinessProbe: &core.Probe{
+					Handler: core.Handler{HTTPGet: &core.HTTPGetAction{Path: "/healthz",
+						Port: intstr.FromInt(smokeTestPort)}},
+				},
+			}},
+			NodeSelector: map[string]string{
+				core.LabelHostname: nodeName,
+				core.LabelOSStable: "windows",
+			},
+			Tolerations: []core.Toleration{{
+				Operator: core.TolerationOpExists,
+			}},
+			RestartPolicy: core.RestartPolicyNever,
+		},
+	}
+}
+
+// isPodReady returns true if the given pod has the Ready condition set to true
+func isPodReady(pod *core.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == core.PodReady {
+			return condition.Status == core.ConditionTrue
+		}
+	}
+	return false
+}
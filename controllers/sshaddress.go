@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net"
+	"strings"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// SSHAddressAnnotation is the annotation of a Windows Machine giving the address, an IP address or a host name, its VM
+// is connected to over SSH, overriding the address selected from the addresses of the Machine
+const SSHAddressAnnotation = "windowsmachineconfig.openshift.io/ssh-address"
+
+// getMachineSSHAddress returns the address the VM backing the given Machine is connected to over SSH, and its
+// instance ID
+func (r *machineReconciliation) getMachineSSHAddress(machine *mapi.Machine) (string, string, error) {
+	nodeIP, instanceID, err := GetMachineInstance(machine, r.config.NodeIPCIDRs)
+	if err != nil {
+		return "", "", err
+	}
+	sshAddress, err := r.machineSSHAddress(machine, nodeIP)
+	if err != nil {
+		return "", "", err
+	}
+	return sshAddress, instanceID, nil
+}
+
+// machineSSHAddress returns the address the VM backing the given Machine, with the given node IP, is connected to over
+// SSH, selected with the current operator configuration
+func (r *machineReconciliation) machineSSHAddress(machine *mapi.Machine, nodeIP string) (string, error) {
+	return SelectSSHAddress(machine, nodeIP, r.config.SSHAddressType, r.config.SSHAddressCIDRs)
+}
+
+// SelectSSHAddress returns the address the VM backing the given Machine, with the given node IP, is connected to over
+// SSH: the address of its SSH address annotation, else its address of the given type, InternalIP if empty, within the
+// first of the given CIDRs which contains one. The node IP is returned for internal addresses when no CIDRs are given.
+func SelectSSHAddress(machine *mapi.Machine, nodeIP, addressType string, cidrs []string) (string, error) {
+	if address, present := machine.GetAnnotations()[SSHAddressAnnotation]; present {
+		address = strings.TrimSpace(address)
+		if net.ParseIP(address) == nil && len(validation.IsDNS1123Subdomain(address)) > 0 {
+			return "", errors.Errorf("invalid %s annotation %q of machine %s: expected an IP address or a host name",
+				SSHAddressAnnotation, address, machine.GetName())
+		}
+		return address, nil
+	}
+	if addressType == "" {
+		addressType = string(core.NodeInternalIP)
+	}
+	if addressType == string(core.NodeInternalIP) && len(cidrs) == 0 {
+		return nodeIP, nil
+	}
+	address := selectAddress(machine.Status.Addresses, core.NodeAddressType(addressType), cidrs)
+	if address == "" {
+		if len(cidrs) > 0 {
+			return "", errors.Errorf("no %s address within %s associated with machine %s", addressType,
+				strings.Join(cidrs, ","), machine.GetName())
+		}
+		return "", errors.Errorf("no %s address associated with machine %s", addressType, machine.GetName())
+	}
+	return address, nil
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelectSSHAddress(t *testing.T) {
+	machine := func(annotations map[string]string) *mapi.Machine {
+		return &mapi.Machine{
+			ObjectMeta: meta.ObjectMeta{Name: "winworker", Annotations: annotations},
+			Status: mapi.MachineStatus{Addresses: []core.NodeAddress{
+				{Type: core.NodeHostName, Address: "winworker"},
+				{Type: core.NodeInternalIP, Address: "10.0.1.5"},
+				{Type: core.NodeInternalIP, Address: "172.16.0.5"},
+				{Type: core.NodeExternalIP, Address: "203.0.113.5"},
+			}},
+		}
+	}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		addressType string
+		cidrs       []string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "node IP",
+			expected: "10.0.1.5",
+		},
+		{
+			name:        "internal address",
+			addressType: "InternalIP",
+			expected:    "10.0.1.5",
+		},
+		{
+			name:        "external address",
+			addressType: "ExternalIP",
+			expected:    "203.0.113.5",
+		},
+		{
+			name:     "internal address within CIDRs",
+			cidrs:    []string{"172.16.0.0/16"},
+			expected: "172.16.0.5",
+		},
+		{
+			name:        "no external address within CIDRs",
+			addressType: "ExternalIP",
+			cidrs:       []string{"198.51.100.0/24"},
+			expectedErr: true,
+		},
+		{
+			name:        "annotation",
+			annotations: map[string]string{SSHAddressAnnotation: " winworker.example.com "},
+			addressType: "ExternalIP",
+			expected:    "winworker.example.com",
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{SSHAddressAnnotation: "winworker:22"},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address, err := SelectSSHAddress(machine(test.annotations), "10.0.1.5", test.addressType, test.cidrs)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, address)
+		})
+	}
+}
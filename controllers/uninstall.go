@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// UninstallConfigMap is the name of the ConfigMap, in the operator namespace, whose deletion triggers the
+	// deconfiguration of all Windows nodes. It must be deleted before the operator is uninstalled.
+	UninstallConfigMap = "windows-machine-config-operator-uninstall"
+	// DeconfigureFinalizer is the finalizer which prevents the uninstall ConfigMap from being removed until the Windows
+	// nodes have been deconfigured
+	DeconfigureFinalizer = "windowsmachineconfig.openshift.io/deconfigure"
+	// DeconfiguringAnnotation is applied to a node drained by WMCO to be deconfigured as the operator is being
+	// uninstalled, so that no more than maxUnhealthyCount nodes are deconfigured at once
+	DeconfiguringAnnotation = "windowsmachineconfig.openshift.io/deconfiguring"
+	// uninstallTimeout is the time, since the deletion of the uninstall ConfigMap, after which the Windows nodes which
+	// could not be deconfigured are abandoned, so that the ConfigMap, and the operator namespace, are not held forever
+	// by unreachable instances
+	uninstallTimeout = 30 * time.Minute
+)
+
+func init() {
+	disruptionAnnotations = append(disruptionAnnotations, DeconfiguringAnnotation)
+}
+
+// EnsureUninstallConfigMap creates the uninstall ConfigMap, holding the deconfigure finalizer, if it does not exist
+func (r *WindowsMachineReconciler) EnsureUninstallConfigMap(ctx context.Context) error {
+	cm := &core.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{
+			Name:       UninstallConfigMap,
+			Namespace:  r.watchNamespace,
+			Finalizers: []string{DeconfigureFinalizer},
+		},
+	}
+	_, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Create(ctx, cm, meta.CreateOptions{})
+	if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "error creating %s ConfigMap", UninstallConfigMap)
+	}
+	return nil
+}
+
+// isUninstalling returns true if the uninstall ConfigMap has been deleted, indicating that all Windows nodes must be
+// deconfigured. The ConfigMap is re-created if it was removed without the nodes being deconfigured, such as when its
+// finalizer was removed by hand, and a completed uninstall is cancelled once the ConfigMap is created again.
+func (r *WindowsMachineReconciler) isUninstalling(ctx context.Context) (bool, error) {
+	cm, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Get(ctx, UninstallConfigMap, meta.GetOptions{})
+	if err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return false, errors.Wrapf(err, "error getting %s ConfigMap", UninstallConfigMap)
+		}
+		if r.isUninstalled() {
+			return true, nil
+		}
+		r.log.Info("re-creating missing ConfigMap", "name", UninstallConfigMap)
+		return false, r.EnsureUninstallConfigMap(ctx)
+	}
+	if !cm.GetDeletionTimestamp().IsZero() {
+		return true, nil
+	}
+	// A ConfigMap created by hand is given the finalizer, so that its deletion triggers the uninstall
+	if !controllerutil.ContainsFinalizer(cm, DeconfigureFinalizer) {
+		controllerutil.AddFinalizer(cm, DeconfigureFinalizer)
+		if _, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Update(ctx, cm,
+			meta.UpdateOptions{}); err != nil {
+			return false, errors.Wrapf(err, "error adding finalizer to %s ConfigMap", UninstallConfigMap)
+		}
+	}
+	r.fleet.Lock()
+	defer r.fleet.Unlock()
+	if r.fleet.uninstalled {
+		r.log.Info("uninstall cancelled, the ConfigMap was created again", "name", UninstallConfigMap)
+		r.fleet.uninstalled = false
+	}
+	return false, nil
+}
+
+// isUninstalled returns true once all Windows nodes have been deconfigured as the operator is being uninstalled
+func (r *WindowsMachineReconciler) isUninstalled() bool {
+	r.fleet.Lock()
+	defer r.fleet.Unlock()
+	return r.fleet.uninstalled
+}
+
+// isUninstallConfigMap returns true if the given object is the uninstall ConfigMap
+func (r *WindowsMachineReconciler) isUninstallConfigMap(obj client.Object) bool {
+	return obj.GetName() == UninstallConfigMap && obj.GetNamespace() == r.watchNamespace
+}
+
+// mapToWindowsMachines maps the given object to all Windows Machines
+func (r *WindowsMachineReconciler) mapToWindowsMachines(object client.Object) []reconcile.Request {
+	machines, err := listWindowsMachines(context.TODO(), r.client)
+	if err != nil {
+		r.log.Error(err, "could not get a list of machines")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, machine := range machines {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKey{Namespace: machine.GetNamespace(), Name: machine.GetName()},
+		})
+	}
+	return requests
+}
+
+// reconcileUninstall deconfigures the node of the given Machine, draining it beforehand, with no more than
+// maxUnhealthyCount nodes being deconfigured at once. Once no configured Windows node remains, or once
+// uninstallTimeout elapsed since the uninstall ConfigMap was deleted, the finalizer is removed from the uninstall
+// ConfigMap and WMCO stops configuring Windows Machines.
+func (r *machineReconciliation) reconcileUninstall(ctx context.Context, machine *mapi.Machine) (ctrl.Result, error) {
+	if r.isUninstalled() {
+		return ctrl.Result{}, nil
+	}
+	cm, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Get(ctx, UninstallConfigMap, meta.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			// The uninstall was completed by the reconciliation of another Machine
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "error getting %s ConfigMap", UninstallConfigMap)
+	}
+	remaining := time.Until(cm.GetDeletionTimestamp().Add(uninstallTimeout))
+
+	nodes, err := r.k8sclientset.CoreV1().Nodes().List(ctx, meta.ListOptions{LabelSelector: nodeconfig.WindowsOSLabel})
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "error listing Windows nodes")
+	}
+	var configured []core.Node
+	for _, node := range nodes.Items {
+		if _, present := node.Annotations[nodeconfig.VersionAnnotation]; present {
+			configured = append(configured, node)
+		}
+	}
+	if remaining > 0 {
+		for i := range configured {
+			node := &configured[i]
+			if machine.Status.NodeRef == nil || node.GetName() != machine.Status.NodeRef.Name {
+				continue
+			}
+			if result, err := r.deconfigureNode(ctx, machine, node); err != nil || !result.IsZero() {
+				return result, err
+			}
+			configured = append(configured[:i], configured[i+1:]...)
+			break
+		}
+		if len(configured) > 0 {
+			// Wait for the remaining nodes to be deconfigured, at most until the uninstall times out
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	// The nodes which could not be deconfigured in time are released
+	for i := range configured {
+		node := &configured[i]
+		r.log.Info("abandoning the deconfiguration of node", "node", node.GetName(), "timeout", uninstallTimeout)
+		r.recorder.Eventf(node, core.EventTypeWarning, "MachineDeconfigurationAbandoned",
+			"Node %s could not be deconfigured within %s of the uninstall and is left configured", node.GetName(),
+			uninstallTimeout)
+		if _, present := node.Annotations[DeconfiguringAnnotation]; present {
+			if err := r.finishNodeDisruption(ctx, node.GetName(), DeconfiguringAnnotation); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	controllerutil.RemoveFinalizer(cm, DeconfigureFinalizer)
+	if _, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Update(ctx, cm,
+		meta.UpdateOptions{}); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "error removing finalizer from %s ConfigMap", UninstallConfigMap)
+	}
+	r.fleet.Lock()
+	r.fleet.uninstalled = true
+	r.fleet.Unlock()
+	if len(configured) == 0 {
+		r.log.Info("all Windows nodes deconfigured, the operator can be uninstalled")
+	} else {
+		r.log.Info("uninstall timed out, the Windows nodes which could not be deconfigured are left configured",
+			"timeout", uninstallTimeout, "nodes", len(configured))
+	}
+	return ctrl.Result{}, nil
+}
+
+// deconfigureNode drains the given configured node of the given Machine, once fewer than maxUnhealthyCount other
+// nodes are unavailable, and then deconfigures it. A non-zero result is returned while the node must wait for its turn
+// or to be drained.
+func (r *machineReconciliation) deconfigureNode(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	if _, deconfiguring := node.Annotations[DeconfiguringAnnotation]; !deconfiguring {
+		var result ctrl.Result
+		var err error
+		if node, result, err = r.reserveNodeDisruption(ctx, machine, node.DeepCopy(), DeconfiguringAnnotation,
+			"deconfiguration"); err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+	drained, err := r.drainNode(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to drain node %s", node.GetName())
+	}
+	if !drained {
+		log.Info("waiting for node to be drained", "node", node.GetName())
+		return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+	if err := r.deconfigureMachine(ctx, machine); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// deconfigureMachine removes the configuration done by WMCO from the VM backing the given Machine and deletes the
+// associated node
+func (r *machineReconciliation) deconfigureMachine(ctx context.Context, machine *mapi.Machine) error {
+	sshAddress, instanceID, err := r.getMachineSSHAddress(machine)
+	if err != nil {
+		return err
+	}
+	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, sshAddress, instanceID, machine.GetName(),
+		r.networkCIDRs, r.vxlanPort, r.signer, r.platform, r.config.SSHUser, windows.KubeletSettings{},
+		r.connectionSettings(), nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create new nodeconfig for machine %s", machine.GetName())
+	}
+	defer nc.Close()
+	if err := nc.Deconfigure(ctx); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "MachineDeconfigurationFailure",
+			"Machine %s deconfiguration failure", machine.GetName())
+		return errors.Wrapf(err, "error deconfiguring machine %s", machine.GetName())
+	}
+	r.recorder.Eventf(machine, core.EventTypeNormal, "MachineDeconfigured",
+		"Machine %s deconfigured successfully", machine.GetName())
+	return nil
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+const (
+	// upgradeProgressConfigMap is the name of the ConfigMap, in the operator namespace, in which the progress of the
+	// rollout of the current operator version across the Windows Machines is published
+	upgradeProgressConfigMap = "windows-upgrade-progress"
+	// upgradeProgressPeriod is the interval at which the upgrade progress is published
+	upgradeProgressPeriod = time.Minute
+)
+
+// Rollout states of a Windows Machine, as counted in the upgrade progress
+const (
+	// upgradeStateUpgraded is the state of the Machines whose node was configured by the current operator version
+	upgradeStateUpgraded = "upgraded"
+	// upgradeStateInProgress is the state of the Machines being deleted, provisioned or configured
+	upgradeStateInProgress = "in_progress"
+	// upgradeStatePending is the state of the Machines whose node is yet to be recreated with the current operator
+	// version, including the nodes held by a pinned version or the version skew tolerance
+	upgradeStatePending = "pending"
+	// upgradeStateFailed is the state of the Machines which failed to provision
+	upgradeStateFailed = "failed"
+)
+
+// upgradeStates are the rollout states of Windows Machines counted in the upgrade progress
+var upgradeStates = []string{upgradeStateUpgraded, upgradeStateInProgress, upgradeStatePending, upgradeStateFailed}
+
+// upgradeMachines is the number of Windows Machines in each rollout state
+var upgradeMachines = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "windows_upgrade_machines",
+	Help: "Number of Windows Machines managed by the operator, by state of the rollout of the current operator version",
+}, []string{"state"})
+
+func init() {
+	metrics.Registry.MustRegister(upgradeMachines)
+}
+
+// ReportUpgradeProgress periodically publishes the progress of the rollout of the current operator version across the
+// Windows Machines, in the upgrade progress ConfigMap and metric. It returns once the given context is done.
+func (r *WindowsMachineReconciler) ReportUpgradeProgress(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.reportUpgradeProgress(ctx); err != nil {
+			r.log.Error(err, "unable to report the upgrade progress")
+		}
+	}, upgradeProgressPeriod)
+	return nil
+}
+
+// reportUpgradeProgress counts the Windows Machines in each rollout state and publishes the counts
+func (r *WindowsMachineReconciler) reportUpgradeProgress(ctx context.Context) error {
+	config, err := operatorconfig.Load(ctx, r.k8sclientset, r.watchNamespace, r.defaultConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to load operator configuration")
+	}
+	managed, err := r.getManagedMachines(ctx, config)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for _, m := range managed {
+		counts[upgradeState(m.machine, m.node, config)]++
+	}
+	for _, state := range upgradeStates {
+		upgradeMachines.WithLabelValues(state).Set(float64(counts[state]))
+	}
+	return r.publishUpgradeProgress(ctx, upgradeProgressData(counts, len(managed)))
+}
+
+// upgradeState returns the rollout state of the given Windows Machine, with the given node, managed with the given
+// configuration
+func upgradeState(machine *mapi.Machine, node *core.Node, config *operatorconfig.Config) string {
+	switch {
+	case machine.Status.Phase == nil:
+		return upgradeStateInProgress
+	case *machine.Status.Phase == "Failed":
+		return upgradeStateFailed
+	}
+	switch machineRolloutState(machine, node, config) {
+	case "":
+		if node.Annotations[nodeconfig.VersionAnnotation] == version.Get() {
+			return upgradeStateUpgraded
+		}
+		// The upgrade of the node is held by the pinned version
+		return upgradeStatePending
+	case "upgrading", "pending upgrade":
+		return upgradeStatePending
+	default:
+		return upgradeStateInProgress
+	}
+}
+
+// upgradeProgressData returns the data of the upgrade progress ConfigMap reporting the given counts of Machines by
+// rollout state, out of the given total
+func upgradeProgressData(counts map[string]int, total int) map[string]string {
+	data := map[string]string{
+		"version":  version.Get(),
+		"total":    strconv.Itoa(total),
+		"complete": strconv.FormatBool(counts[upgradeStateUpgraded] == total),
+	}
+	for _, state := range upgradeStates {
+		data[state] = strconv.Itoa(counts[state])
+	}
+	return data
+}
+
+// publishUpgradeProgress writes the given data to the upgrade progress ConfigMap, creating it if needed. The
+// ConfigMap is only updated when the data changes, recording the time of the change.
+func (r *WindowsMachineReconciler) publishUpgradeProgress(ctx context.Context, data map[string]string) error {
+	configMaps := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace)
+	cm, err := configMaps.Get(ctx, upgradeProgressConfigMap, meta.GetOptions{})
+	if err != nil && !k8sapierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "error getting %s ConfigMap", upgradeProgressConfigMap)
+	}
+	if err == nil {
+		current := make(map[string]string)
+		for key, value := range cm.Data {
+			if key != "lastChanged" {
+				current[key] = value
+			}
+		}
+		if reflect.DeepEqual(current, data) {
+			return nil
+		}
+	}
+	data["lastChanged"] = time.Now().UTC().Format(time.RFC3339)
+	if k8sapierrors.IsNotFound(err) {
+		cm = &core.ConfigMap{ObjectMeta: meta.ObjectMeta{Name: upgradeProgressConfigMap, Namespace: r.watchNamespace},
+			Data: data}
+		if _, err := configMaps.Create(ctx, cm, meta.CreateOptions{}); err != nil {
+			return errors.Wrapf(err, "error creating %s ConfigMap", upgradeProgressConfigMap)
+		}
+	} else {
+		cm.Data = data
+		if _, err := configMaps.Update(ctx, cm, meta.UpdateOptions{}); err != nil {
+			return errors.Wrapf(err, "error updating %s ConfigMap", upgradeProgressConfigMap)
+		}
+	}
+	r.log.Info("upgrade progress", "version", data["version"], "total", data["total"],
+		upgradeStateUpgraded, data[upgradeStateUpgraded], upgradeStateInProgress, data[upgradeStateInProgress],
+		upgradeStatePending, data[upgradeStatePending], upgradeStateFailed, data[upgradeStateFailed])
+	return nil
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+func TestUpgradeState(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	nodeWithVersion := func(v string) *core.Node {
+		return &core.Node{ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{nodeconfig.VersionAnnotation: v}}}
+	}
+	tests := []struct {
+		name          string
+		phase         *string
+		node          *core.Node
+		pinnedVersion string
+		expected      string
+	}{
+		{
+			name:     "no phase",
+			expected: upgradeStateInProgress,
+		},
+		{
+			name:     "failed",
+			phase:    ptr("Failed"),
+			expected: upgradeStateFailed,
+		},
+		{
+			name:     "configuring",
+			phase:    ptr("Running"),
+			node:     &core.Node{},
+			expected: upgradeStateInProgress,
+		},
+		{
+			name:     "outdated",
+			phase:    ptr("Running"),
+			node:     nodeWithVersion("old"),
+			expected: upgradeStatePending,
+		},
+		{
+			name:          "pinned",
+			phase:         ptr("Running"),
+			node:          nodeWithVersion("old"),
+			pinnedVersion: "old",
+			expected:      upgradeStatePending,
+		},
+		{
+			name:     "upgraded",
+			phase:    ptr("Running"),
+			node:     nodeWithVersion(version.Get()),
+			expected: upgradeStateUpgraded,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			machine := &mapi.Machine{Status: mapi.MachineStatus{Phase: test.phase}}
+			assert.Equal(t, test.expected, upgradeState(machine, test.node,
+				&operatorconfig.Config{PinnedVersion: test.pinnedVersion}))
+		})
+	}
+}
+
+func TestUpgradeProgressData(t *testing.T) {
+	data := upgradeProgressData(map[string]int{upgradeStateUpgraded: 2, upgradeStatePending: 1}, 3)
+	assert.Equal(t, map[string]string{"version": version.Get(), "total": "3", "complete": "false", "upgraded": "2",
+		"in_progress": "0", "pending": "1", "failed": "0"}, data)
+	assert.Equal(t, "true", upgradeProgressData(map[string]int{upgradeStateUpgraded: 3}, 3)["complete"])
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig/payload"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+// isVersionOutdated returns true if a node configured by the given WMCO version must be recreated with the desired
+// version. When the operator image holds the payload of the pinned version, the nodes configured by any other version
+// are recreated with it. Otherwise, the nodes are recreated with the current operator version unless their version is
+// pinned or within the version skew tolerance.
+func isVersionOutdated(nodeVersion string, config *operatorconfig.Config) bool {
+	if desired := desiredVersion(config); desired != version.Get() {
+		return nodeVersion != desired
+	}
+	return nodeVersion != version.Get() && nodeVersion != config.PinnedVersion &&
+		!withinVersionSkew(nodeVersion, version.Get(), config.VersionSkew)
+}
+
+// desiredVersion returns the WMCO version whose payload the nodes configured with the given configuration run: the
+// pinned version when the operator image holds its payload, else the current operator version
+func desiredVersion(config *operatorconfig.Config) string {
+	if config.PinnedVersion != "" && payload.HasVersion(config.PinnedVersion) {
+		return config.PinnedVersion
+	}
+	return version.Get()
+}
+
+// payloadVersion returns the previous WMCO version whose payload the nodes configured with the given configuration
+// run, empty for the payload of the current operator version
+func payloadVersion(config *operatorconfig.Config) string {
+	if desired := desiredVersion(config); desired != version.Get() {
+		return desired
+	}
+	return ""
+}
+
+// withinVersionSkew returns true if the given node version is the given operator version, or if its major version is
+// older than the major version of the operator version by at most skew major versions. The other versions of the
+// operator major version are not within the skew, so that the releases of a major version are rolled out. Versions
+// whose major version cannot be parsed are never within the skew.
+func withinVersionSkew(nodeVersion, operatorVersion string, skew int) bool {
+	if nodeVersion == operatorVersion {
+		return true
+	}
+	if skew == 0 {
+		return false
+	}
+	nodeMajor, err := majorVersion(nodeVersion)
+	if err != nil {
+		return false
+	}
+	operatorMajor, err := majorVersion(operatorVersion)
+	if err != nil {
+		return false
+	}
+	return nodeMajor < operatorMajor && operatorMajor-nodeMajor <= skew
+}
+
+// majorVersion returns the major version of the given WMCO version, such as 3 for 3.1.0-7c1f7f4
+func majorVersion(v string) (int, error) {
+	major, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(v, "v"), ".", 2)[0])
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid version %q", v)
+	}
+	return major, nil
+}
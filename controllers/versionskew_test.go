@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithinVersionSkew(t *testing.T) {
+	tests := []struct {
+		name            string
+		nodeVersion     string
+		operatorVersion string
+		skew            int
+		expected        bool
+	}{
+		{
+			name:            "no skew tolerated",
+			nodeVersion:     "2.0.1-4f2e3a1",
+			operatorVersion: "3.0.0-7c1f7f4",
+			expected:        false,
+		},
+		{
+			name:            "previous major version",
+			nodeVersion:     "2.0.1-4f2e3a1",
+			operatorVersion: "3.0.0-7c1f7f4",
+			skew:            1,
+			expected:        true,
+		},
+		{
+			name:            "same version",
+			nodeVersion:     "3.1.0-7c1f7f4",
+			operatorVersion: "3.1.0-7c1f7f4",
+			skew:            1,
+			expected:        true,
+		},
+		{
+			name:            "older version of the same major version",
+			nodeVersion:     "3.0.0-4f2e3a1",
+			operatorVersion: "3.1.0-7c1f7f4",
+			skew:            1,
+			expected:        false,
+		},
+		{
+			name:            "beyond skew",
+			nodeVersion:     "1.0.0-4f2e3a1",
+			operatorVersion: "3.0.0-7c1f7f4",
+			skew:            1,
+			expected:        false,
+		},
+		{
+			name:            "newer node version",
+			nodeVersion:     "4.0.0-4f2e3a1",
+			operatorVersion: "3.0.0-7c1f7f4",
+			skew:            1,
+			expected:        false,
+		},
+		{
+			name:            "unparsable node version",
+			nodeVersion:     "old",
+			operatorVersion: "3.0.0-7c1f7f4",
+			skew:            1,
+			expected:        false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, withinVersionSkew(test.nodeVersion, test.operatorVersion, test.skew))
+		})
+	}
+}
@@ -3,14 +3,19 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	oconfig "github.com/openshift/api/config/v1"
+	operator "github.com/openshift/api/operator/v1"
 	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,17 +25,19 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
-	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
-	"github.com/openshift/windows-machine-config-operator/pkg/metrics"
+	"github.com/openshift/windows-machine-config-operator/pkg/maintenance"
 	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
 	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
 	"github.com/openshift/windows-machine-config-operator/pkg/signer"
 	"github.com/openshift/windows-machine-config-operator/pkg/windows"
@@ -38,11 +45,23 @@ import (
 )
 
 const (
-	// maxUnhealthyCount is the maximum number of nodes that are not ready to serve at a given time.
-	// TODO: https://issues.redhat.com/browse/WINC-524
-	maxUnhealthyCount = 1
 	// MachineOSLabel is the label used to identify the Windows Machines.
 	MachineOSLabel = "machine.openshift.io/os-id"
+	// PausedAnnotation can be applied to a Windows Machine or MachineSet to stop WMCO from configuring or deleting the
+	// Machines it applies to
+	PausedAnnotation = "windowsmachineconfig.openshift.io/paused"
+	// machineNodeUIDIndex is the field index of Windows Machines by the UID of their node
+	machineNodeUIDIndex = "status.nodeRef.uid"
+	// machineSetIndex is the field index of Windows Machines by the name of the MachineSet owning them
+	machineSetIndex = "metadata.ownerReferences.machineSet"
+	// machineSetLabel is the label applied by the Machine API to Machines created by a MachineSet
+	machineSetLabel = "machine.openshift.io/cluster-api-machineset"
+	// clusterNetwork is the name of the network.operator object holding the cluster network configuration
+	clusterNetwork = "cluster"
+	// drainRequeueDelay is the time to wait before checking if a node being drained has been emptied
+	drainRequeueDelay = 10 * time.Second
+	// maxMaintenanceWindowDelay is the maximum time a machine with pending disruptive operations is requeued for
+	maxMaintenanceWindowDelay = time.Hour
 )
 
 // WindowsMachineReconciler is used to create a controller which manages Windows Machine objects
@@ -50,29 +69,80 @@ type WindowsMachineReconciler struct {
 	client client.Client
 	log    logr.Logger
 	scheme *runtime.Scheme
+	// apiReader reads objects from the API server, for the objects which are not watched
+	apiReader client.Reader
 	// k8sclientset holds the kube client that we can re-use for all kube objects other than custom resources.
 	k8sclientset *kubernetes.Clientset
-	// clusterServiceCIDR holds the cluster network service CIDR
-	clusterServiceCIDR string
-	// signer is a signer created from the user's private key
-	signer ssh.Signer
-	// vxlanPort is the custom VXLAN port
-	vxlanPort string
+	// networkCIDRs holds the service and pod network CIDRs of the cluster
+	networkCIDRs cluster.CIDRs
 	// recorder to generate events
 	recorder record.EventRecorder
 	// watchNamespace is the namespace the operator is watching as defined by the operator CSV
 	watchNamespace string
-	// prometheusConfig stores information required to configure Prometheus
-	prometheusNodeConfig *metrics.PrometheusNodeConfig
 	// platform indicates the cloud on which OpenShift cluster is running
 	// TODO: Remove this once we figure out how to be provider agnostic. This is specific to proper usage of userData
 	// 		 in vSphere
 	//		 https://bugzilla.redhat.com/show_bug.cgi?id=1876987
 	platform oconfig.PlatformType
+	// fips indicates that the cluster is in FIPS mode, requiring Windows nodes to be configured using FIPS approved
+	// algorithms only
+	fips bool
+	// azureStackHub indicates that the cluster runs on Azure Stack Hub
+	azureStackHub bool
+	// awsRegion is the AWS region of the cluster, empty if it does not run on AWS
+	awsRegion string
+	// awsEC2Endpoint is the custom endpoint of the AWS EC2 service of the cluster
+	awsEC2Endpoint string
+	// namespacedCache is a cache restricted to the operator namespace
+	namespacedCache cache.Cache
+	// defaultConfig holds the operator configuration used for the settings missing from the configuration ConfigMap
+	defaultConfig operatorconfig.Config
+	// fleet is the state shared by the reconciliations of Windows Machines, which may run concurrently
+	fleet *fleetState
+	// signerCache holds the signers created from the private key secrets, by resource version of the secrets
+	signerCache *signerCache
+}
+
+// machineReconciliation is the reconciliation of a single Windows Machine. It holds the state resolved at the start of
+// the reconciliation, so that concurrent reconciliations of Machines of MachineSets with different configuration
+// overrides or private key secrets never share it.
+type machineReconciliation struct {
+	*WindowsMachineReconciler
+	// config is the operator configuration, with the overrides of the MachineSet owning the Machine
+	config *operatorconfig.Config
+	// vxlanPort is the custom VXLAN port, read from the cluster network configuration
+	vxlanPort string
+	// podNetworkMTU is the MTU of the cluster pod network, zero when it is detected by the cluster network operator
+	podNetworkMTU int
+	// signer is a signer created from the user's private key
+	signer ssh.Signer
+	// sshCA is a signer created from the private key of the SSH certificate authority trusted by the instances, nil
+	// when the instances trust the public key of the user's private key
+	sshCA ssh.Signer
+	// previousSigner is a signer created from the private key held by the private key secret before its last rotation,
+	// nil if the secret does not hold one
+	previousSigner ssh.Signer
+	// privateKeySecret is the name of the private key secret the signers are created from, the cloud-private-key
+	// secret unless the MachineSet of the Machine has its own
+	privateKeySecret string
+	// vm is the connection to the VM backing the Machine, established with the signers above by the first check of
+	// the reconciliation which needs it, and closed at the end of the reconciliation
+	vm windows.Windows
+}
+
+// fleetState is the state shared by the reconciliations of Windows Machines
+type fleetState struct {
+	// Mutex serializes the decisions depending on the state of all Windows Machines, such as whether a Machine can be
+	// deleted without exceeding maxUnhealthyCount, with the actions they lead to
+	sync.Mutex
+	// uninstalled indicates that all Windows nodes have been deconfigured as the operator is being uninstalled, until
+	// the uninstall ConfigMap is created again
+	uninstalled bool
 }
 
 // NewWindowsMachineReconciler returns a pointer to a WindowsMachineReconciler
-func NewWindowsMachineReconciler(mgr manager.Manager, clusterConfig cluster.Config, watchNamespace string) (*WindowsMachineReconciler, error) {
+func NewWindowsMachineReconciler(mgr manager.Manager, clusterConfig cluster.Config, watchNamespace string,
+	namespacedCache cache.Cache, defaultConfig operatorconfig.Config) (*WindowsMachineReconciler, error) {
 	// The client provided by the GetClient() method of the manager is a split client that will always hit the API
 	// server when writing. When reading, the client will either use a cache populated by the informers backing the
 	// controllers, or in certain cases read directly from the API server. It will read from the server both for
@@ -84,33 +154,69 @@ func NewWindowsMachineReconciler(mgr manager.Manager, clusterConfig cluster.Conf
 		return nil, errors.Wrap(err, "error creating kubernetes clientset")
 	}
 
-	serviceCIDR, err := clusterConfig.Network().GetServiceCIDR()
-	if err != nil {
-		return nil, errors.Wrap(err, "error getting service CIDR")
-	}
-
-	// Initialize prometheus configuration
-	pc, err := metrics.NewPrometheusNodeConfig(clientset, watchNamespace)
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to initialize Prometheus configuration")
-	}
-
 	return &WindowsMachineReconciler{
-		client:               mgr.GetClient(),
-		log:                  ctrl.Log.WithName("controller").WithName("windowsmachine"),
-		scheme:               mgr.GetScheme(),
-		k8sclientset:         clientset,
-		clusterServiceCIDR:   serviceCIDR,
-		vxlanPort:            clusterConfig.Network().VXLANPort(),
-		recorder:             mgr.GetEventRecorderFor("windowsmachine"),
-		watchNamespace:       watchNamespace,
-		prometheusNodeConfig: pc,
-		platform:             clusterConfig.Platform(),
+		client:          mgr.GetClient(),
+		apiReader:       mgr.GetAPIReader(),
+		log:             ctrl.Log.WithName("controller").WithName("windowsmachine"),
+		scheme:          mgr.GetScheme(),
+		k8sclientset:    clientset,
+		networkCIDRs:    clusterConfig.Network().GetCIDRs(),
+		recorder:        newThrottledRecorder(mgr.GetEventRecorderFor("windowsmachine")),
+		watchNamespace:  watchNamespace,
+		platform:        clusterConfig.Platform(),
+		fips:            clusterConfig.FIPSEnabled(),
+		azureStackHub:   clusterConfig.AzureStackHub(),
+		awsRegion:       clusterConfig.AWSRegion(),
+		awsEC2Endpoint:  clusterConfig.AWSEC2Endpoint(),
+		namespacedCache: namespacedCache,
+		defaultConfig:   defaultConfig,
+		fleet:           &fleetState{},
+		signerCache:     &signerCache{},
 	}, nil
 }
 
-// SetupWithManager sets up a new Secret controller
-func (r *WindowsMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+// SetupWithManager sets up a new Windows Machine controller with the given options
+func (r *WindowsMachineReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
+	// Index the Windows Machines by node and by MachineSet, so that node events are mapped to their Machine and the
+	// Machines of a MachineSet are counted without listing them all
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &mapi.Machine{}, machineNodeUIDIndex,
+		indexMachineByNodeUID); err != nil {
+		return errors.Wrapf(err, "unable to index machines by %s", machineNodeUIDIndex)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &mapi.Machine{}, machineSetIndex,
+		indexMachineByMachineSet); err != nil {
+		return errors.Wrapf(err, "unable to index machines by %s", machineSetIndex)
+	}
+	// Watch the deletion of the private key secrets, so that their cached signers are dropped. The secrets are read
+	// from the cache of the manager, and the cached signers are recreated when their resource version changes.
+	privateKeyPredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return holdsPrivateKey(e.Object, r.watchNamespace)
+		},
+	}
+	// Watch for the cluster network configuration being changed, so that the Machines waiting for the network
+	// prerequisites are configured, and the nodes configured with a previous custom VXLAN port are recreated
+	networkPredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNetwork, ok := e.ObjectOld.(*operator.Network)
+			if !ok || e.ObjectNew.GetName() != clusterNetwork {
+				return false
+			}
+			return !equality.Semantic.DeepEqual(oldNetwork.Spec, e.ObjectNew.(*operator.Network).Spec)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
 	// Watch for the Machine objects with label defined by MachineOSLabel
 	machinePredicate := predicate.Funcs{
 		// We need the create event to account for Machines that are in provisioned state but were created
@@ -121,9 +227,9 @@ func (r *WindowsMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			return r.isValidMachine(e.ObjectNew) && isWindowsMachine(e.ObjectNew.GetLabels())
 		},
-		// ignore delete event for all Machines as WMCO does not react to node getting deleted
+		// The state kept for deleted Machines is removed
 		DeleteFunc: func(e event.DeleteEvent) bool {
-			return false
+			return isWindowsMachine(e.Object.GetLabels())
 		},
 	}
 
@@ -146,19 +252,138 @@ func (r *WindowsMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					e.ObjectOld.GetAnnotations()[nodeconfig.PubKeyHashAnnotation] {
 				return true
 			}
+			// Reboot the node as soon as an administrator requests it
+			if _, requested := e.ObjectNew.GetAnnotations()[RebootRequestedAnnotation]; requested {
+				if _, wasRequested := e.ObjectOld.GetAnnotations()[RebootRequestedAnnotation]; !wasRequested {
+					return true
+				}
+			}
+			// Drain the node as soon as its instance received an interruption notice
+			if _, interrupted := e.ObjectNew.GetAnnotations()[InterruptedAnnotation]; interrupted {
+				if _, wasInterrupted := e.ObjectOld.GetAnnotations()[InterruptedAnnotation]; !wasInterrupted {
+					return true
+				}
+			}
+			// Reconcile the Machine of the node as soon as it is excluded from, or returned to, the management of WMCO
+			reason, excluded := e.ObjectNew.GetAnnotations()[ExcludedAnnotation]
+			oldReason, wasExcluded := e.ObjectOld.GetAnnotations()[ExcludedAnnotation]
+			if excluded != wasExcluded || reason != oldReason {
+				return true
+			}
+			// Configure the node again as soon as an administrator requests it
+			if _, requested := e.ObjectNew.GetAnnotations()[ReconfigureRequestedAnnotation]; requested {
+				if _, wasRequested := e.ObjectOld.GetAnnotations()[ReconfigureRequestedAnnotation]; !wasRequested {
+					return true
+				}
+			}
+			// Keep the WindowsNode status up to date with the node readiness
+			oldNode, ok := e.ObjectOld.(*core.Node)
+			if !ok {
+				return false
+			}
+			return isNodeReady(oldNode) != isNodeReady(e.ObjectNew.(*core.Node))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
 			return false
 		},
+	}
+	// Watch for the paused annotation being changed on Windows MachineSets, so that their Machines are reconciled once
+	// the MachineSet is unpaused, and for changes to the configuration overrides
+	machineSetPredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isWindowsMachineSet(e.ObjectNew) &&
+				(e.ObjectNew.GetAnnotations()[PausedAnnotation] != e.ObjectOld.GetAnnotations()[PausedAnnotation] ||
+					e.ObjectNew.GetAnnotations()[PrivateKeySecretAnnotation] !=
+						e.ObjectOld.GetAnnotations()[PrivateKeySecretAnnotation] ||
+					overridesChanged(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations()))
+		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
 			return false
 		},
 	}
+	// Watch for the uninstall ConfigMap being deleted, so that all Windows Machines are deconfigured, or created again,
+	// cancelling the uninstall, and for the operator configuration being changed
+	configMapPredicate := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isOperatorConfigMap(e.Object, r.watchNamespace) || r.isUninstallConfigMap(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isOperatorConfigMap(e.ObjectNew, r.watchNamespace) ||
+				(r.isUninstallConfigMap(e.ObjectNew) && !e.ObjectNew.GetDeletionTimestamp().IsZero())
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isOperatorConfigMap(e.Object, r.watchNamespace) || r.isUninstallConfigMap(e.Object)
+		},
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&mapi.Machine{}, builder.WithPredicates(machinePredicate)).
-		Watches(&source.Kind{Type: &core.Node{}}, handler.EnqueueRequestsFromMapFunc(r.mapNodeToMachine),
+		// The Machines invalidated together by an event are reconciled at random times within invalidationSpread
+		Watches(&source.Kind{Type: &core.Node{}}, enqueueWithJitter(r.mapNodeToMachine, isInvalidatedNode),
 			builder.WithPredicates(nodePredicate)).
+		Watches(&source.Kind{Type: &mapi.MachineSet{}}, enqueueWithJitter(r.mapMachineSetToMachines, nil),
+			builder.WithPredicates(machineSetPredicate)).
+		Watches(source.NewKindWithCache(&core.ConfigMap{}, r.namespacedCache),
+			enqueueWithJitter(r.mapToWindowsMachines, nil), builder.WithPredicates(configMapPredicate)).
+		Watches(&source.Kind{Type: &core.Secret{}}, r.privateKeyHandler(),
+			builder.WithPredicates(privateKeyPredicate)).
+		Watches(&source.Kind{Type: &operator.Network{}}, enqueueWithJitter(r.mapToWindowsMachines, nil),
+			builder.WithPredicates(networkPredicate)).
+		Watches(&source.Kind{Type: &oconfig.ClusterVersion{}}, enqueueWithJitter(r.mapToWindowsMachines, nil),
+			builder.WithPredicates(clusterUpgradeCompletedPredicate())).
+		WithOptions(options).
 		Complete(r)
 }
 
+// isWindowsMachineSet returns true if the given object is a MachineSet creating Windows Machines
+func isWindowsMachineSet(obj client.Object) bool {
+	machineSet, ok := obj.(*mapi.MachineSet)
+	if !ok {
+		return false
+	}
+	return isWindowsMachine(machineSet.Spec.Template.ObjectMeta.Labels)
+}
+
+// overridesChanged returns true if the configuration override annotations differ between the given annotations
+func overridesChanged(oldAnnotations, newAnnotations map[string]string) bool {
+	for _, annotations := range []map[string]string{oldAnnotations, newAnnotations} {
+		for key := range annotations {
+			if operatorconfig.IsOverrideAnnotation(key) && oldAnnotations[key] != newAnnotations[key] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isInvalidatedNode returns true if the given Windows node was configured by another WMCO version, or with a private
+// key which was rotated, which invalidates all the nodes at once on operator upgrades and private key rotations
+func isInvalidatedNode(node client.Object) bool {
+	annotations := node.GetAnnotations()
+	hash, present := annotations[nodeconfig.PubKeyHashAnnotation]
+	return annotations[nodeconfig.VersionAnnotation] != version.Get() || (present && hash == "")
+}
+
+// mapMachineSetToMachines maps the given MachineSet to the Machines it owns
+func (r *WindowsMachineReconciler) mapMachineSetToMachines(object client.Object) []reconcile.Request {
+	machines := &mapi.MachineList{}
+	err := r.client.List(context.TODO(), machines, client.InNamespace(object.GetNamespace()),
+		client.MatchingLabels{machineSetLabel: object.GetName()})
+	if err != nil {
+		r.log.Error(err, "could not get a list of machines", "machineset", object.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, machine := range machines.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: machine.GetNamespace(), Name: machine.GetName()},
+		})
+	}
+	return requests
+}
+
 // mapNodeToMachine maps the given Windows node to its associated Machine
 func (r *WindowsMachineReconciler) mapNodeToMachine(object client.Object) []reconcile.Request {
 	node := core.Node{}
@@ -172,13 +397,12 @@ func (r *WindowsMachineReconciler) mapNodeToMachine(object client.Object) []reco
 	}
 
 	// Map the Node to the associated Machine through the Node's UID
-	machines := &mapi.MachineList{}
-	err := r.client.List(context.TODO(), machines,
-		client.MatchingLabels(map[string]string{MachineOSLabel: "Windows"}))
+	machines, err := listWindowsMachines(context.TODO(), r.client,
+		client.MatchingFields{machineNodeUIDIndex: string(object.GetUID())})
 	if err != nil {
 		r.log.Error(err, "could not get a list of machines")
 	}
-	for _, machine := range machines.Items {
+	for _, machine := range machines {
 		ok := machine.Status.Phase != nil &&
 			len(machine.Status.Addresses) > 0 &&
 			machine.Status.NodeRef != nil &&
@@ -199,6 +423,28 @@ func (r *WindowsMachineReconciler) mapNodeToMachine(object client.Object) []reco
 	return nil
 }
 
+// indexMachineByNodeUID returns the UID of the node of the given Windows Machine, indexing Machines by node
+func indexMachineByNodeUID(object client.Object) []string {
+	machine, ok := object.(*mapi.Machine)
+	if !ok || !isWindowsMachine(machine.GetLabels()) || machine.Status.NodeRef == nil {
+		return nil
+	}
+	return []string{string(machine.Status.NodeRef.UID)}
+}
+
+// indexMachineByMachineSet returns the name of the MachineSet owning the given Windows Machine, indexing Machines by
+// MachineSet
+func indexMachineByMachineSet(object client.Object) []string {
+	machine, ok := object.(*mapi.Machine)
+	if !ok || !isWindowsMachine(machine.GetLabels()) {
+		return nil
+	}
+	if machineSetName := getMachineSetName(machine); machineSetName != "" {
+		return []string{machineSetName}
+	}
+	return nil
+}
+
 // isWindowsMachine checks if the machine is a Windows machine or not
 func isWindowsMachine(labels map[string]string) bool {
 	if value, ok := labels[MachineOSLabel]; ok {
@@ -242,38 +488,97 @@ func (r *WindowsMachineReconciler) isValidMachine(obj client.Object) bool {
 // Note: The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *WindowsMachineReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	// The signers and the configuration are resolved by each reconciliation, apart from the shared reconciler
+	result, err := (&machineReconciliation{WindowsMachineReconciler: r}).reconcile(ctx, request)
+	return jitterRequeue(result), err
+}
+
+// reconcile reconciles the Windows Machine of the given request
+func (r *machineReconciliation) reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
 	log := r.log.WithValues("windowsmachine", request.NamespacedName)
 	log.V(1).Info("reconciling")
+	// The connection shared by the checks of the reconciliation is closed once they are all done
+	defer r.closeVM()
 
-	// Get the private key that will be used to configure the instance
+	// Get the private key that will be used to configure the instance, and the signer created from it
 	// Doing this before fetching the machine allows us to warn the user better about the missing private key
-	privateKey, err := secrets.GetPrivateKey(kubeTypes.NamespacedName{Namespace: r.watchNamespace,
-		Name: secrets.PrivateKeySecret}, r.client)
-	if err != nil {
-		if k8sapierrors.IsNotFound(err) {
-			// Private key was removed, requeue
-			return ctrl.Result{}, errors.Wrapf(err, "%s does not exist, please create it", secrets.PrivateKeySecret)
-		}
-		return ctrl.Result{}, errors.Wrapf(err, "unable to get secret %s", request.NamespacedName)
+	if err := r.loadSigners(secrets.PrivateKeySecret); err != nil {
+		return ctrl.Result{}, err
 	}
-	// Update the signer with the current privateKey
-	r.signer, err = signer.Create(privateKey)
+	config, err := operatorconfig.Load(ctx, r.k8sclientset, r.watchNamespace, r.defaultConfig)
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "error creating signer")
+		return ctrl.Result{}, errors.Wrap(err, "unable to load operator configuration")
+	}
+	r.config = config
+	network := &operator.Network{}
+	if err := r.client.Get(ctx, kubeTypes.NamespacedName{Name: clusterNetwork}, network); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to get cluster network.operator object")
 	}
+	r.vxlanPort = cluster.VXLANPort(network)
+	r.podNetworkMTU = cluster.PodNetworkMTU(network)
 
 	// Fetch the Machine instance
 	machine := &mapi.Machine{}
 	if err := r.client.Get(ctx, request.NamespacedName, machine); err != nil {
 		if k8sapierrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Owned objects are automatically garbage collected. The state kept for the deleted Machine is removed.
 			// Return and don't requeue
-			return ctrl.Result{}, nil
+			return ctrl.Result{}, r.removeMachineState(ctx, request.Name)
 		}
 		// Error reading the object - requeue the request.
 		return ctrl.Result{}, err
 	}
+	// The Machines of a MachineSet with its own private key secret are configured, and deconfigured, with its keys
+	if err := r.usePrivateKeySecret(ctx, machine); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !machine.GetDeletionTimestamp().IsZero() {
+		// The machine controller drains the node and deletes it once the instance is terminated, the state kept for
+		// the Machine being removed once the Machine is gone
+		return ctrl.Result{}, nil
+	}
+	if err := r.applyMachineSetOverrides(ctx, machine); err != nil {
+		return ctrl.Result{}, err
+	}
+	// Diagnostics are collected even when reconciliation is paused, as they do not change the VM
+	if err := r.reconcileDiagnostics(ctx, machine); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to collect diagnostics of machine %s", machine.GetName())
+	}
+	paused, err := r.isPaused(ctx, machine)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to determine if Machine %s is paused", machine.GetName())
+	}
+	if paused {
+		log.Info("reconciliation paused", "annotation", PausedAnnotation)
+		return ctrl.Result{}, nil
+	}
+	uninstalling, err := r.isUninstalling(ctx)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to determine if the operator is being uninstalled")
+	}
+	if uninstalling {
+		if r.config.DryRun {
+			reportDryRun(r.recorder, log, machine, "MachineDeconfiguration",
+				"Machine %s would be deconfigured as the operator is being uninstalled", machine.GetName())
+			return ctrl.Result{}, nil
+		}
+		log.Info("deconfiguring machine as the operator is being uninstalled")
+		return r.reconcileUninstall(ctx, machine)
+	}
+	if !r.config.ManagesMachine(machine.GetLabels()) {
+		log.V(1).Info("machine not selected", "selector", r.config.MachineSelector)
+		return ctrl.Result{}, nil
+	}
+	// Machines quarantined by an administrator are left untouched until the exclusion is lifted
+	excluded, err := r.reconcileExclusion(ctx, machine)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to determine if Machine %s is excluded", machine.GetName())
+	}
+	if excluded {
+		log.Info("machine excluded from management", "annotation", ExcludedAnnotation)
+		return ctrl.Result{}, nil
+	}
 	// provisionedPhase is the status of the machine when it is in the `Provisioned` state
 	provisionedPhase := "Provisioned"
 	// runningPhase is the status of the machine when it is in the `Running` state, indicating that it is configured into a node
@@ -297,82 +602,287 @@ func (r *WindowsMachineReconciler) Reconcile(ctx context.Context, request ctrl.R
 			return ctrl.Result{}, errors.Wrapf(err, "could not get node associated with machine %s", machine.GetName())
 		}
 
-		if _, present := node.Annotations[nodeconfig.VersionAnnotation]; present {
-			// If either the version annotation doesn't match the current operator version, or the private key used
-			// to configure the machine is out of date, the machine should be deleted
-			if node.Annotations[nodeconfig.VersionAnnotation] != version.Get() ||
-				node.Annotations[nodeconfig.PubKeyHashAnnotation] != nodeconfig.CreatePubKeyHashAnnotation(r.signer.PublicKey()) {
+		// The nodes of spot or preemptible instances are drained as soon as their instance is to be interrupted
+		interruptionResult, interrupted, err := r.reconcileInterruption(ctx, machine, node)
+		if err != nil || interrupted {
+			return interruptionResult, errors.Wrapf(err, "unable to drain interrupted node %s", node.GetName())
+		}
+		// Nodes configured with the previous private key are switched over in place rather than recreated
+		if node, err = r.reconcilePreviousKey(ctx, machine, node); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "unable to switch machine %s over to the current private key",
+				machine.GetName())
+		}
+		if nodeVersion, present := node.Annotations[nodeconfig.VersionAnnotation]; present {
+			// If either the version annotation doesn't match the current operator version, unless the version is
+			// pinned or within the version skew tolerance, or the private key, SSH certificate authority or VXLAN
+			// port used to configure the machine is out of date, the machine should be deleted
+			pubKeyHash := nodeconfig.CreatePubKeyHashAnnotation(signer.TrustedKey(r.signer, r.sshCA))
+			if isVersionOutdated(nodeVersion, r.config) ||
+				node.Annotations[nodeconfig.PubKeyHashAnnotation] != pubKeyHash ||
+				r.isVXLANPortOutdated(node) {
+				delay, err := r.getMaintenanceWindowDelay(ctx, machine)
+				if err != nil {
+					return ctrl.Result{}, errors.Wrap(err, "unable to determine maintenance window")
+				}
+				if delay > 0 {
+					log.Info("machine deletion pending until the next maintenance window", "delay", delay)
+					r.recorder.Eventf(machine, core.EventTypeNormal, "MachineDeletionPending",
+						"Machine %v will be deleted during the next maintenance window", machine.Name)
+					return ctrl.Result{RequeueAfter: delay}, nil
+				}
+				// Concurrent reconciliations must not both pick a canary or exceed maxUnhealthyCount
+				r.fleet.Lock()
+				defer r.fleet.Unlock()
+				if r.config.CanaryUpgrade {
+					state, err := r.getCanaryState(ctx)
+					if err != nil {
+						return ctrl.Result{}, errors.Wrap(err, "unable to determine canary upgrade state")
+					}
+					switch state {
+					case canaryInProgress:
+						log.Info("waiting for canary upgrade to complete")
+						return ctrl.Result{RequeueAfter: canaryRequeueDelay}, nil
+					case canaryFailed:
+						log.Info("canary upgrade failed, machine will not be upgraded")
+						r.recorder.Eventf(machine, core.EventTypeWarning, "CanaryUpgradeFailed",
+							"Machine %v upgrade aborted as the canary node failed verification", machine.Name)
+						return ctrl.Result{RequeueAfter: canaryRequeueDelay}, nil
+					case canaryNotStarted:
+						log.Info("upgrading machine as canary")
+					}
+				}
 				log.Info("deleting machine")
-				deletionAllowed, err := r.isAllowedDeletion(machine)
+				deletionAllowed, err := r.isAllowedDeletion(ctx, machine)
 				if err != nil {
 					return ctrl.Result{}, errors.Wrapf(err, "unable to determine if Machine can be deleted")
 				}
 				if !deletionAllowed {
-					log.Info("machine deletion restricted", "maxUnhealthyCount", maxUnhealthyCount)
+					log.Info("machine deletion restricted", "maxUnhealthyCount", r.config.MaxUnhealthyCount)
 					r.recorder.Eventf(machine, core.EventTypeWarning, "MachineDeletionRestricted",
 						"Machine %v deletion restricted as the maximum unhealthy machines can`t exceed %v count",
-						machine.Name, maxUnhealthyCount)
+						machine.Name, r.config.MaxUnhealthyCount)
 					return ctrl.Result{Requeue: true}, nil
 				}
-				return ctrl.Result{}, r.deleteMachine(machine)
+				if r.config.DryRun {
+					reportDryRun(r.recorder, log, machine, "MachineDeletion",
+						"Machine %s would be deleted to upgrade its node from version %s", machine.GetName(),
+						nodeVersion)
+					return ctrl.Result{}, nil
+				}
+				if err := r.saveNodeMetadata(ctx, machine, node); err != nil {
+					return ctrl.Result{}, errors.Wrapf(err, "unable to save metadata of node %s", node.GetName())
+				}
+				return ctrl.Result{}, r.deleteMachine(ctx, machine)
 			}
-			log.Info("machine has current version", "version", node.Annotations[nodeconfig.VersionAnnotation])
-			// version annotation exists with a valid value, node is fully configured.
-			// configure Prometheus when we have already configured Windows Nodes. This is required to update Endpoints object if
-			// it gets reverted when the operator pod restarts.
-			if err := r.prometheusNodeConfig.Configure(); err != nil {
-				return ctrl.Result{}, errors.Wrap(err, "unable to configure Prometheus")
+			if nodeVersion == desiredVersion(r.config) && nodeVersion != version.Get() {
+				log.Info("machine runs the payload of its pinned version", "version", nodeVersion)
+			} else if nodeVersion == r.config.PinnedVersion && nodeVersion != version.Get() {
+				log.Info("machine upgrade held by pinned version", "version", nodeVersion)
+			} else if nodeVersion != version.Get() {
+				log.Info("machine upgrade pending within version skew tolerance", "version", nodeVersion,
+					"versionSkew", r.config.VersionSkew)
+			} else {
+				log.Info("machine has current version", "version", nodeVersion)
 			}
-			return ctrl.Result{}, nil
+			if err := r.syncWindowsNode(ctx, machine, node); err != nil {
+				return ctrl.Result{}, err
+			}
+			if result, err := r.reconcileReboot(ctx, machine, node); err != nil || !result.IsZero() {
+				return result, errors.Wrapf(err, "unable to reboot node %s", node.GetName())
+			}
+			if result, err := r.reconcileReconfiguration(ctx, machine, node); err != nil || !result.IsZero() {
+				return result, errors.Wrapf(err, "unable to configure node %s again", node.GetName())
+			}
+			if result, err := r.reconcileKubeletSettings(ctx, machine, node); err != nil || !result.IsZero() {
+				return result, errors.Wrapf(err, "unable to reconfigure kubelet on node %s", node.GetName())
+			}
+			if result, err := r.reconcileKubeProxy(ctx, machine, node); err != nil || !result.IsZero() {
+				return result, errors.Wrapf(err, "unable to reconfigure kube-proxy on node %s", node.GetName())
+			}
+			smokeTestResult, err := r.reconcileSmokeTest(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to smoke test node %s", node.GetName())
+			}
+			passwordResult, err := r.reconcilePassword(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to rotate password of node %s", node.GetName())
+			}
+			hnsResult, err := r.reconcileHNSNetworks(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to repair HNS networks of node %s", node.GetName())
+			}
+			clockResult, err := r.reconcileClockSkew(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check clock skew of node %s", node.GetName())
+			}
+			bootstrapResult, err := r.reconcileBootstrapCredentials(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to renew bootstrap credentials of node %s",
+					node.GetName())
+			}
+			certResult, err := r.reconcileKubeletCertificate(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check kubelet client certificate of node %s",
+					node.GetName())
+			}
+			caResult, err := r.reconcileCABundles(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to update CA bundles of node %s", node.GetName())
+			}
+			problemsResult, err := r.reconcileProblems(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to report problems of node %s", node.GetName())
+			}
+			updateResult, err := r.reconcileWindowsUpdates(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to install security updates on node %s", node.GetName())
+			}
+			antivirusResult, err := r.reconcileAntivirus(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check antivirus of node %s", node.GetName())
+			}
+			licenseResult, err := r.reconcileLicense(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check license of node %s", node.GetName())
+			}
+			networkPolicyResult, err := r.reconcileNetworkPolicy(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check network policy enforcement of node %s",
+					node.GetName())
+			}
+			servicesResult, err := r.reconcileKubeProxyServices(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check services against kube-proxy of node %s",
+					node.GetName())
+			}
+			certExpiryResult, err := r.reconcileCertificateExpiry(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check certificate expiry of node %s", node.GetName())
+			}
+			resourceMetricsResult, err := r.reconcileResourceMetrics(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check resource metrics of node %s", node.GetName())
+			}
+			driftResult, err := r.reconcileConfigDrift(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to check configuration drift of node %s",
+					node.GetName())
+			}
+			result, err := r.reconcileHostSettings(ctx, machine, node)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "unable to correct host settings of node %s", node.GetName())
+			}
+			return earliestRequeue(result, smokeTestResult, passwordResult, hnsResult, clockResult,
+				bootstrapResult, certResult, caResult, problemsResult, antivirusResult, updateResult, licenseResult,
+				driftResult, networkPolicyResult, servicesResult, resourceMetricsResult, certExpiryResult), nil
 		}
 	} else if *machine.Status.Phase != provisionedPhase {
 		log.V(1).Info("machine not provisioned", "phase", *machine.Status.Phase)
-		// configure Prometheus when a machine is not in `Running` or `Provisioned` phase. This configuration is
-		// required to update Endpoints object when Windows machines are being deleted.
-		if err := r.prometheusNodeConfig.Configure(); err != nil {
-			return ctrl.Result{}, errors.Wrap(err, "unable to configure Prometheus")
-		}
 		// Machine is not in provisioned or running state, nothing we should do as of now
 		return ctrl.Result{}, nil
 	}
 
 	// validate userData secret
-	if err := r.validateUserData(privateKey); err != nil {
+	if err := validateUserData(ctx, r.client, r.watchNamespace, r.privateKeySecret); err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "UserDataInvalid",
+			"Machine %s cannot be configured until its userData secret is valid: %v", machine.GetName(), err)
 		return ctrl.Result{}, errors.Wrapf(err, "error validating userData secret")
 	}
 
-	// Get the IP address associated with the Windows machine, if not error out to requeue again
-	if len(machine.Status.Addresses) == 0 {
-		return ctrl.Result{}, errors.Errorf("machine %s doesn't have any ip addresses defined",
-			machine.Name)
-	}
-	ipAddress := ""
-	for _, address := range machine.Status.Addresses {
-		if address.Type == core.NodeInternalIP {
-			ipAddress = address.Address
+	if r.fips {
+		if err := validateFIPSKeys(r.signer, r.sshCA); err != nil {
+			// The Machine is configured once the private key is replaced by a FIPS compliant one
+			log.Error(err, "machine cannot be configured in FIPS mode")
+			r.recorder.Eventf(machine, core.EventTypeWarning, "FIPSUnsatisfied",
+				"Machine %s cannot be configured in FIPS mode: %v", machine.Name, err)
+			return ctrl.Result{}, nil
 		}
 	}
-	if len(ipAddress) == 0 {
-		return ctrl.Result{}, errors.Errorf("no internal ip address associated with machine %s",
-			machine.Name)
+
+	// The network prerequisites are checked before configuring the VM, which would otherwise fail once its node has
+	// joined the cluster. The Machine is configured once the cluster network configuration is corrected.
+	networkValid, err := r.checkNetworkPrerequisites(ctx, machine, network)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !networkValid {
+		return ctrl.Result{}, nil
 	}
 
-	// Get the instance ID associated with the Windows machine.
-	providerID := *machine.Spec.ProviderID
-	if len(providerID) == 0 {
-		return ctrl.Result{}, errors.Errorf("empty provider ID associated with machine %s", machine.Name)
+	// Get the IP address and instance ID associated with the Windows machine, if not error out to requeue again
+	ipAddress, instanceID, err := GetMachineInstance(machine, r.config.NodeIPCIDRs)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
-	// Ex: aws:///us-east-1e/i-078285fdadccb2eaa
-	// We always want the last entry which is the instanceID, and the first which is the provider name.
-	providerTokens := strings.Split(providerID, "/")
-	instanceID := providerTokens[len(providerTokens)-1]
-	if len(instanceID) == 0 {
-		return ctrl.Result{}, errors.Errorf("unable to get instance ID from provider ID for machine %s", machine.Name)
+	sshAddress, err := r.machineSSHAddress(machine, ipAddress)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
+	labels, taints, err := getDesiredNodeMetadata(ctx, r.client, machine, r.config.NodeTaints)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get labels and taints for machine %s", machine.Name)
+	}
+
+	if r.config.DryRun {
+		reportDryRun(r.recorder, log, machine, "MachineConfiguration",
+			"Machine %s would be configured as a Windows node", machine.GetName())
+		return ctrl.Result{}, nil
+	}
+	// When many Machines await configuration, such as after a large scale-up, the Machines with the highest priority
+	// are configured first
+	higherPriority, err := r.getHigherPriorityMachine(ctx, machine)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to determine configuration priority of machine %s",
+			machine.GetName())
+	}
+	if higherPriority != "" {
+		log.Info("configuration deferred to machine with higher priority", "machine", higherPriority)
+		return ctrl.Result{RequeueAfter: priorityRequeueDelay}, nil
+	}
+	// The first Machine of a new pool is validated before its configuration, so that a misconfigured pool fails fast
+	preflightPassed, err := r.checkPreflight(ctx, machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !preflightPassed {
+		return ctrl.Result{RequeueAfter: preflightRequeueDelay}, nil
+	}
+	// The VM retrieves artifacts over the network during its configuration, which fails, or leaves pods unable to
+	// start, when disconnected from their sources, which is reported without preventing the configuration
+	if err := r.checkArtifacts(ctx, machine); err != nil {
+		return ctrl.Result{}, err
+	}
+	// On AWS, the instance types unsupported or undersized for Windows nodes are reported, without preventing the
+	// configuration
+	r.checkInstanceType(machine)
+	// On Azure, the network interface configurations known to break the hybrid overlay are reported, without
+	// preventing the configuration
+	if err := r.checkNICs(ctx, machine); err != nil {
+		return ctrl.Result{}, err
+	}
 	log.Info("processing")
 	// Make the Machine a Windows Worker node
-	if err := r.addWorkerNode(ipAddress, instanceID, machine.Name, r.platform); err != nil {
+	progress := newConfigurationProgress(r.recorder, machine)
+	err = r.addWorkerNode(ctx, ipAddress, sshAddress, instanceID, machine, r.platform, labels, taints,
+		progress.report)
+	if isCancellation(err) && ctx.Err() != nil {
+		// The operator is shutting down, the configuration is run again once it restarts
+		log.Info("configuration interrupted", "lastStep", progress.lastStep)
+		return ctrl.Result{}, ctx.Err()
+	}
+	// A failure to record the configuration should not cause the Machine to be configured again
+	if recordErr := r.recordConfiguration(ctx, machine, progress, err); recordErr != nil {
+		log.Error(recordErr, "unable to record configuration")
+	}
+	if isCancellation(err) {
+		// The Machine is being deleted, its VM is about to be terminated and is not reported as failing to configure
+		log.Info("configuration cancelled", "lastStep", progress.lastStep)
+		r.recorder.Eventf(machine, core.EventTypeNormal, "MachineSetupCancelled",
+			"Machine %s configuration cancelled as the Machine is being deleted", machine.Name)
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
 		var authErr *windows.AuthErr
 		if errors.As(err, &authErr) {
 			// SSH authentication errors with the Machine are non recoverable, stemming from a mismatch with the
@@ -380,29 +890,169 @@ func (r *WindowsMachineReconciler) Reconcile(ctx context.Context, request ctrl.R
 			// re-provisioned.
 			r.recorder.Eventf(machine, core.EventTypeWarning, "MachineSetupFailure",
 				"Machine %s authentication failure", machine.Name)
-			return ctrl.Result{}, r.deleteMachine(machine)
+			if r.config.RemediationStrategy == operatorconfig.RemediationNone {
+				log.Info("machine not remediated", "remediationStrategy", r.config.RemediationStrategy)
+				return ctrl.Result{}, err
+			}
+			nodeMaintenance, maintenanceErr := r.getNodeMaintenance(ctx, machine)
+			if maintenanceErr != nil {
+				return ctrl.Result{}, maintenanceErr
+			}
+			if nodeMaintenance != "" {
+				log.Info("machine remediation held while its node is under maintenance", "maintenance",
+					nodeMaintenance)
+				return ctrl.Result{RequeueAfter: nodeMaintenanceDelay}, nil
+			}
+			return ctrl.Result{}, r.deleteMachine(ctx, machine)
+		}
+		var templateErr *windows.TemplateErr
+		if errors.As(err, &templateErr) {
+			// The golden image must be corrected, and the Machine recreated, or its VM corrected in place
+			r.recorder.Eventf(machine, core.EventTypeWarning, "VSphereTemplateInvalid", "Machine %s: %v",
+				machine.Name, templateErr)
 		}
-		r.recorder.Eventf(machine, core.EventTypeWarning, "MachineSetupFailure",
-			"Machine %s configuration failure", machine.Name)
+		r.recorder.Event(machine, core.EventTypeWarning, "MachineSetupFailure", progress.failureMessage())
 		return ctrl.Result{}, err
 	}
 	r.recorder.Eventf(machine, core.EventTypeNormal, "MachineSetup",
 		"Machine %s configured successfully", machine.Name)
-	// configure Prometheus after a Windows machine is configured as a Node.
-	if err := r.prometheusNodeConfig.Configure(); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "unable to configure Prometheus")
+	// Restore the metadata of a node previously deleted by WMCO on the new node. A failure here should not cause the
+	// Machine to be configured again, as the metadata will be restored on the next node configured.
+	if err := r.restoreNodeMetadata(ctx, machine); err != nil {
+		log.Error(err, "unable to restore node metadata")
+	}
+	if err := r.reportIsolationCompatibility(ctx, machine); err != nil {
+		log.Error(err, "unable to report container isolation compatibility")
 	}
 	return ctrl.Result{}, nil
 }
 
+// removeMachineState removes the state kept for the Machine with the given name: its password, its diagnostics, its
+// WindowsNode and its clock skew, bootstrap credentials, kubelet client certificate, configuration drift and SSH
+// failure metrics
+func (r *WindowsMachineReconciler) removeMachineState(ctx context.Context, machineName string) error {
+	if err := r.removePassword(ctx, machineName); err != nil {
+		return err
+	}
+	if err := r.removeDiagnostics(ctx, machineName); err != nil {
+		return err
+	}
+	if err := r.removeWindowsNode(ctx, machineName); err != nil {
+		return err
+	}
+	clockSkewSeconds.DeleteLabelValues(machineName)
+	bootstrapCredentialsExpiry.DeleteLabelValues(machineName)
+	kubeletClientCertExpiry.DeleteLabelValues(machineName)
+	deleteLicenseMetrics(machineName)
+	deleteAntivirusMetrics(machineName)
+	deleteNetworkPolicyMetrics(machineName)
+	deleteResourceMetricsMetrics(machineName)
+	deleteCertificateMetrics(machineName)
+	deleteConfigDrift(machineName)
+	windows.DeleteSSHFailures(machineName)
+	return nil
+}
+
+// hostSettings returns the host settings of the operator configuration, restricted to FIPS approved algorithms if the
+// cluster is in FIPS mode
+func (r *machineReconciliation) hostSettings() windows.HostSettings {
+	settings := r.config.HostSettings()
+	settings.FIPS = r.fips
+	settings.AzureStackHub = r.azureStackHub
+	settings.AWSRegion = r.awsRegion
+	settings.AWSEC2Endpoint = r.awsEC2Endpoint
+	settings.SSHCertificateAuthority = r.sshCA
+	settings.PayloadVersion = payloadVersion(r.config)
+	if settings.OverlayMTU == 0 {
+		settings.OverlayMTU = r.podNetworkMTU
+	}
+	return settings
+}
+
+// connectionSettings returns the host settings which only define how the VMs are connected to, and the payload they
+// run, for operations which do not change the host configuration
+func (r *machineReconciliation) connectionSettings() windows.HostSettings {
+	return windows.HostSettings{SSHAlgorithms: r.config.SSHAlgorithms, FIPS: r.fips, SSHProxy: r.config.SSHProxy,
+		SSHProxyUser: r.config.SSHProxyUser, SSHCertificateAuthority: r.sshCA, Timeouts: r.config.Timeouts,
+		PayloadVersion: payloadVersion(r.config)}
+}
+
+// GetMachineInstance returns the internal IP address and the instance ID of the VM backing the given Machine. When
+// CIDRs are given, the internal IP address within the first CIDR containing one is returned.
+func GetMachineInstance(machine *mapi.Machine, nodeIPCIDRs []string) (string, string, error) {
+	if len(machine.Status.Addresses) == 0 {
+		return "", "", errors.Errorf("machine %s doesn't have any ip addresses defined", machine.Name)
+	}
+	ipAddress := selectNodeIP(machine.Status.Addresses, nodeIPCIDRs)
+	if len(ipAddress) == 0 {
+		if len(nodeIPCIDRs) > 0 {
+			return "", "", errors.Errorf("no internal ip address within %s associated with machine %s",
+				strings.Join(nodeIPCIDRs, ","), machine.Name)
+		}
+		return "", "", errors.Errorf("no internal ip address associated with machine %s", machine.Name)
+	}
+
+	// Get the instance ID associated with the Windows machine.
+	if machine.Spec.ProviderID == nil || len(*machine.Spec.ProviderID) == 0 {
+		return "", "", errors.Errorf("empty provider ID associated with machine %s", machine.Name)
+	}
+	// Ex: aws:///us-east-1e/i-078285fdadccb2eaa
+	// We always want the last entry which is the instanceID, and the first which is the provider name.
+	providerTokens := strings.Split(*machine.Spec.ProviderID, "/")
+	instanceID := providerTokens[len(providerTokens)-1]
+	if len(instanceID) == 0 {
+		return "", "", errors.Errorf("unable to get instance ID from provider ID for machine %s", machine.Name)
+	}
+	return ipAddress, instanceID, nil
+}
+
+// selectNodeIP returns the internal IP address, among the given addresses, within the first of the given CIDRs which
+// contains one. If no CIDRs are given, the last internal IP address is returned. An empty string is returned if no
+// address matches.
+func selectNodeIP(addresses []core.NodeAddress, cidrs []string) string {
+	return selectAddress(addresses, core.NodeInternalIP, cidrs)
+}
+
+// selectAddress returns the IP address of the given type, among the given addresses, within the first of the given
+// CIDRs which contains one. If no CIDRs are given, the last address of the type is returned. An empty string is
+// returned if no address matches.
+func selectAddress(addresses []core.NodeAddress, addressType core.NodeAddressType, cidrs []string) string {
+	var ips []net.IP
+	ipAddress := ""
+	for _, address := range addresses {
+		if address.Type != addressType {
+			continue
+		}
+		ipAddress = address.Address
+		if ip := net.ParseIP(address.Address); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	if len(cidrs) == 0 {
+		return ipAddress
+	}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if ipNet.Contains(ip) {
+				return ip.String()
+			}
+		}
+	}
+	return ""
+}
+
 // deleteMachine deletes the specified Machine
-func (r *WindowsMachineReconciler) deleteMachine(machine *mapi.Machine) error {
+func (r *WindowsMachineReconciler) deleteMachine(ctx context.Context, machine *mapi.Machine) error {
 	if !machine.GetDeletionTimestamp().IsZero() {
 		// Delete already initiated
 		return nil
 	}
 
-	if err := r.client.Delete(context.TODO(), machine); err != nil {
+	if err := r.client.Delete(ctx, machine); err != nil {
 		r.recorder.Eventf(machine, core.EventTypeWarning, "MachineDeletionFailed",
 			"Machine %v deletion failed: %v", machine.Name, err)
 		return err
@@ -413,14 +1063,30 @@ func (r *WindowsMachineReconciler) deleteMachine(machine *mapi.Machine) error {
 	return nil
 }
 
-// addWorkerNode configures the given Windows VM, adding it as a node object to the cluster
-func (r *WindowsMachineReconciler) addWorkerNode(ipAddress, instanceID, machineName string, platform oconfig.PlatformType) error {
-	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, ipAddress, instanceID, machineName, r.clusterServiceCIDR,
-		r.vxlanPort, r.signer, platform)
+// addWorkerNode configures the given Windows VM, with the given node IP and connected to over SSH at the given
+// address, adding it as a node object to the cluster, and reports the configuration steps completed to the given
+// progress function. The configuration is cancelled once the given context is done, or once the given Machine starts
+// being deleted.
+func (r *machineReconciliation) addWorkerNode(ctx context.Context, ipAddress, sshAddress, instanceID string,
+	machine *mapi.Machine, platform oconfig.PlatformType, labels map[string]string, taints []core.Taint,
+	progress nodeconfig.ProgressFunc) error {
+	settings := r.hostSettings()
+	hooks, err := r.configurationHooks(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to configure Windows VM %s", instanceID)
+	}
+	settings.Hooks = hooks
+	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, sshAddress, instanceID, machine.GetName(), r.networkCIDRs,
+		r.vxlanPort, r.signer, platform, r.config.SSHUser, r.config.KubeletSettings(ipAddress), settings,
+		labels, taints)
 	if err != nil {
 		return errors.Wrapf(err, "failed to configure Windows VM %s", instanceID)
 	}
-	if err := nc.Configure(); err != nil {
+	defer nc.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go r.cancelOnDeletion(ctx, machine, cancel)
+	if err := nc.Configure(ctx, progress); err != nil {
 		// TODO: Unwrap to extract correct error
 		return errors.Wrapf(err, "failed to configure Windows VM %s", instanceID)
 	}
@@ -429,18 +1095,29 @@ func (r *WindowsMachineReconciler) addWorkerNode(ipAddress, instanceID, machineN
 	return nil
 }
 
-// validateUserData validates userData secret. It returns error if the secret doesn`t
-// contain expected public key bytes.
-func (r *WindowsMachineReconciler) validateUserData(privateKey []byte) error {
+// validateUserData validates the userData secret generated from the given private key secret of the given namespace.
+// It returns error if the secret doesn`t contain expected public key bytes, which are those of the SSH certificate
+// authority if the private key secret holds one.
+func validateUserData(ctx context.Context, c client.Client, namespace, privateKeySecret string) error {
+	secret := kubeTypes.NamespacedName{Namespace: namespace, Name: privateKeySecret}
+	privateKey, err := secrets.GetPrivateKey(secret, c)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get secret %s", privateKeySecret)
+	}
+	caKey, err := secrets.GetSSHCAKey(secret, c)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get secret %s", privateKeySecret)
+	}
 	userDataSecret := &core.Secret{}
-	err := r.client.Get(context.TODO(), kubeTypes.NamespacedName{Name: "windows-user-data", Namespace: "openshift-machine-api"}, userDataSecret)
-
+	err = c.Get(ctx, kubeTypes.NamespacedName{Name: secrets.UserDataSecretName(privateKeySecret),
+		Namespace: userDataNamespace}, userDataSecret)
 	if err != nil {
-		return errors.Errorf("could not find Windows userData secret in required namespace: %v", err)
+		return errors.Errorf("could not find Windows userData secret %s in required namespace: %v",
+			secrets.UserDataSecretName(privateKeySecret), err)
 	}
 
 	secretData := string(userDataSecret.Data["userData"][:])
-	desiredUserDataSecret, err := secrets.GenerateUserData(privateKey)
+	desiredUserDataSecret, err := secrets.GenerateUserData(privateKey, caKey)
 	if err != nil {
 		return err
 	}
@@ -450,41 +1127,52 @@ func (r *WindowsMachineReconciler) validateUserData(privateKey []byte) error {
 	return nil
 }
 
+// validateFIPSKeys returns an error if the given signer, or the given SSH certificate authority signing its
+// certificates, cannot be used for FIPS compliant SSH authentication
+func validateFIPSKeys(keySigner, ca ssh.Signer) error {
+	if err := signer.ValidateFIPS(keySigner.PublicKey()); err != nil {
+		return err
+	}
+	if ca != nil {
+		if err := signer.ValidateFIPS(ca.PublicKey()); err != nil {
+			return errors.Wrap(err, "invalid SSH certificate authority")
+		}
+	}
+	return nil
+}
+
 // isAllowedDeletion determines if the number of machines after deletion of the given machine doesn`t fall below the
-// minHealthyCount
-func (r *WindowsMachineReconciler) isAllowedDeletion(machine *mapi.Machine) (bool, error) {
-	if len(machine.OwnerReferences) == 0 {
+// minHealthyCount. The MachineSet and its Machines are read from the cache.
+func (r *machineReconciliation) isAllowedDeletion(ctx context.Context, machine *mapi.Machine) (bool, error) {
+	machinesetName := getMachineSetName(machine)
+	if machinesetName == "" {
 		return false, errors.New("Machine has no owner reference")
 	}
-	machinesetName := machine.OwnerReferences[0].Name
 
-	machines := &mapi.MachineList{}
-	err := r.client.List(context.TODO(), machines,
-		client.MatchingLabels(map[string]string{MachineOSLabel: "Windows"}))
+	machines, err := listWindowsMachines(ctx, r.client, client.InNamespace(machine.GetNamespace()),
+		client.MatchingFields{machineSetIndex: machinesetName})
 	if err != nil {
 		return false, errors.Wrap(err, "cannot list Machines")
 	}
 
 	// get Windows MachineSet
 	windowsMachineSet := &mapi.MachineSet{}
-	err = r.client.Get(context.TODO(), types.NamespacedName{Name: machinesetName,
-		Namespace: "openshift-machine-api"}, windowsMachineSet)
+	err = r.client.Get(ctx, types.NamespacedName{Name: machinesetName, Namespace: machine.GetNamespace()},
+		windowsMachineSet)
 	if err != nil {
 		return false, errors.Wrap(err, "cannot get MachineSet")
 	}
 
 	// Allow deletion if there is only one machine in the Windows MachineSet
 	totalWindowsMachineCount := *windowsMachineSet.Spec.Replicas
-	if maxUnhealthyCount == totalWindowsMachineCount {
+	if r.config.MaxUnhealthyCount == totalWindowsMachineCount {
 		return true, nil
 	}
 
 	totalHealthy := 0
-	for _, ma := range machines.Items {
-		// Increment the count if the machine is identified as healthy and is a part of given Windows MachineSet and
-		// on which deletion is not already initiated.
-		if len(machine.OwnerReferences) != 0 && ma.OwnerReferences[0].Name == machinesetName &&
-			r.isWindowsMachineHealthy(&ma) && ma.DeletionTimestamp.IsZero() {
+	for _, ma := range machines {
+		// Increment the count if the machine is identified as healthy and on which deletion is not already initiated.
+		if r.isWindowsMachineHealthy(&ma) && ma.DeletionTimestamp.IsZero() {
 			totalHealthy += 1
 		}
 	}
@@ -493,7 +1181,123 @@ func (r *WindowsMachineReconciler) isAllowedDeletion(machine *mapi.Machine) (boo
 	r.log.Info("unhealthy machine count for machineset", "name", machinesetName, "total", totalWindowsMachineCount,
 		"unhealthy", unhealthyMachineCount)
 
-	return unhealthyMachineCount < maxUnhealthyCount, nil
+	return unhealthyMachineCount < r.config.MaxUnhealthyCount, nil
+}
+
+// getMaintenanceWindowDelay returns the time to wait until disruptive operations are allowed on the given machine.
+// Zero is returned if the operations are allowed now, which is always the case when no maintenance windows are defined,
+// the cluster is not being upgraded and the node of the Machine is not under maintenance.
+func (r *WindowsMachineReconciler) getMaintenanceWindowDelay(ctx context.Context, machine *mapi.Machine) (time.Duration,
+	error) {
+	// Windows nodes are not disrupted on top of the rollout of the control plane and Linux nodes
+	upgrading, err := r.isClusterUpgrading(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if upgrading {
+		r.recorder.Eventf(machine, core.EventTypeNormal, "DisruptionHeldForClusterUpgrade",
+			"Disruptive operations on Machine %s are held until the cluster upgrade completes", machine.GetName())
+		return clusterUpgradeDelay, nil
+	}
+	// Nodes under maintenance declared by administrators are left alone until the maintenance ends
+	held, err := r.isHeldForNodeMaintenance(ctx, machine)
+	if err != nil {
+		return 0, err
+	}
+	if held {
+		return nodeMaintenanceDelay, nil
+	}
+	cm, err := r.k8sclientset.CoreV1().ConfigMaps(r.watchNamespace).Get(ctx, maintenance.ConfigMapName,
+		meta.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "error getting %s ConfigMap", maintenance.ConfigMapName)
+	}
+	windows, err := maintenance.Parse(cm.Data[maintenance.ConfigMapKey])
+	if err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "InvalidMaintenanceWindow",
+			"ConfigMap %s contains invalid maintenance windows: %v", maintenance.ConfigMapName, err)
+		return 0, err
+	}
+	now := time.Now()
+	if maintenance.IsOpen(windows, now) {
+		return 0, nil
+	}
+	// Check again at least once every maxMaintenanceWindowDelay so changes to the windows are picked up
+	next, found := maintenance.NextOpening(windows, now)
+	if !found || next.Sub(now) > maxMaintenanceWindowDelay {
+		return maxMaintenanceWindowDelay, nil
+	}
+	return next.Sub(now), nil
+}
+
+// isPaused returns true if the given Machine, or the MachineSet owning it, has the paused annotation
+func (r *WindowsMachineReconciler) isPaused(ctx context.Context, machine *mapi.Machine) (bool, error) {
+	if _, present := machine.GetAnnotations()[PausedAnnotation]; present {
+		return true, nil
+	}
+	for _, owner := range machine.GetOwnerReferences() {
+		if owner.Kind != "MachineSet" {
+			continue
+		}
+		machineSet := &mapi.MachineSet{}
+		err := r.client.Get(ctx, types.NamespacedName{Namespace: machine.GetNamespace(), Name: owner.Name}, machineSet)
+		if err != nil {
+			if k8sapierrors.IsNotFound(err) {
+				continue
+			}
+			return false, errors.Wrapf(err, "unable to get MachineSet %s", owner.Name)
+		}
+		if _, present := machineSet.GetAnnotations()[PausedAnnotation]; present {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getMachineSet returns the MachineSet owning the given Machine, or nil if the Machine is not owned by a MachineSet
+func (r *WindowsMachineReconciler) getMachineSet(ctx context.Context, machine *mapi.Machine) (*mapi.MachineSet,
+	error) {
+	machineSetName := getMachineSetName(machine)
+	if machineSetName == "" {
+		return nil, nil
+	}
+	machineSet := &mapi.MachineSet{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: machine.GetNamespace(), Name: machineSetName}, machineSet)
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "unable to get MachineSet %s", machineSetName)
+	}
+	return machineSet, nil
+}
+
+// applyMachineSetOverrides applies the settings overridden by the annotations of the MachineSet owning the given
+// Machine to the configuration used for the current reconciliation
+func (r *machineReconciliation) applyMachineSetOverrides(ctx context.Context, machine *mapi.Machine) error {
+	machineSet, err := r.getMachineSet(ctx, machine)
+	if err != nil || machineSet == nil {
+		return err
+	}
+	config, err := operatorconfig.Override(*r.config, machineSet.GetAnnotations())
+	if err != nil {
+		r.recorder.Eventf(machineSet, core.EventTypeWarning, "InvalidConfiguration",
+			"MachineSet %s has invalid configuration overrides: %v", machineSet.GetName(), err)
+		return errors.Wrapf(err, "invalid configuration overrides on MachineSet %s", machineSet.GetName())
+	}
+	r.config = config
+	return nil
+}
+
+// isVXLANPortOutdated returns true if the hybrid overlay of the given node was configured with a VXLAN port other than
+// the current one. The hybrid overlay network of the node is created with the port, so the node must be recreated to
+// apply the change. Nodes configured before the port was recorded are not considered outdated.
+func (r *machineReconciliation) isVXLANPortOutdated(node *core.Node) bool {
+	nodePort, present := node.Annotations[nodeconfig.VXLANPortAnnotation]
+	return present && nodePort != r.vxlanPort
 }
 
 // isWindowsMachineHealthy determines if the given Machine object is healthy. A Windows machine is considered
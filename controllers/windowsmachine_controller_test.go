@@ -7,6 +7,7 @@ import (
 	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	"github.com/stretchr/testify/require"
 	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -76,3 +77,100 @@ func TestIsValidMachine(t *testing.T) {
 	}
 
 }
+
+func TestIndexMachineByNodeUID(t *testing.T) {
+	windowsLabels := map[string]string{MachineOSLabel: "Windows"}
+	testCases := []struct {
+		name    string
+		machine *mapi.Machine
+		want    []string
+	}{
+		{
+			name:    "Windows Machine without node",
+			machine: &mapi.Machine{ObjectMeta: meta.ObjectMeta{Labels: windowsLabels}},
+		},
+		{
+			name: "Windows Machine with node",
+			machine: &mapi.Machine{ObjectMeta: meta.ObjectMeta{Labels: windowsLabels},
+				Status: mapi.MachineStatus{NodeRef: &core.ObjectReference{UID: "node-uid"}}},
+			want: []string{"node-uid"},
+		},
+		{
+			name: "Linux Machine with node",
+			machine: &mapi.Machine{
+				Status: mapi.MachineStatus{NodeRef: &core.ObjectReference{UID: "node-uid"}}},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, indexMachineByNodeUID(test.machine))
+		})
+	}
+}
+
+func TestIndexMachineByMachineSet(t *testing.T) {
+	owners := []meta.OwnerReference{{Kind: "MachineSet", Name: "winworker"}}
+	testCases := []struct {
+		name    string
+		machine *mapi.Machine
+		want    []string
+	}{
+		{
+			name: "Windows Machine owned by a MachineSet",
+			machine: &mapi.Machine{ObjectMeta: meta.ObjectMeta{OwnerReferences: owners,
+				Labels: map[string]string{MachineOSLabel: "Windows"}}},
+			want: []string{"winworker"},
+		},
+		{
+			name:    "Windows Machine without owner",
+			machine: &mapi.Machine{ObjectMeta: meta.ObjectMeta{Labels: map[string]string{MachineOSLabel: "Windows"}}},
+		},
+		{
+			name:    "Linux Machine owned by a MachineSet",
+			machine: &mapi.Machine{ObjectMeta: meta.ObjectMeta{OwnerReferences: owners}},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, indexMachineByMachineSet(test.machine))
+		})
+	}
+}
+
+func TestSelectNodeIP(t *testing.T) {
+	addresses := []core.NodeAddress{
+		{Type: core.NodeHostName, Address: "winworker"},
+		{Type: core.NodeInternalIP, Address: "10.0.1.5"},
+		{Type: core.NodeExternalIP, Address: "192.168.1.5"},
+		{Type: core.NodeInternalIP, Address: "172.16.0.5"},
+	}
+	testCases := []struct {
+		name  string
+		cidrs []string
+		want  string
+	}{
+		{
+			name: "no CIDRs",
+			want: "172.16.0.5",
+		},
+		{
+			name:  "first matching CIDR",
+			cidrs: []string{"10.0.0.0/16", "172.16.0.0/12"},
+			want:  "10.0.1.5",
+		},
+		{
+			name:  "CIDRs in order of preference",
+			cidrs: []string{"172.16.0.0/12", "10.0.0.0/16"},
+			want:  "172.16.0.5",
+		},
+		{
+			name:  "external addresses are ignored",
+			cidrs: []string{"192.168.0.0/16"},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, selectNodeIP(addresses, test.cidrs))
+		})
+	}
+}
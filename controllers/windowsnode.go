@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+
+	operator "github.com/openshift/api/operator/v1"
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+// configurationSteps are the configuration steps in the order they are completed
+var configurationSteps = []windows.ConfigurationStep{windows.StepPayloadTransferred, windows.StepServicesInstalled,
+	nodeconfig.StepNodeJoined, nodeconfig.StepHybridOverlayReady, nodeconfig.StepCNIConfigured}
+
+// maxConfigurationAttempts is the number of most recent configuration attempts kept in the journal of a WindowsNode
+const maxConfigurationAttempts = 10
+
+// updateWindowsNode applies the given changes to the status of the WindowsNode of the given Machine, creating the
+// WindowsNode if it does not exist
+func (r *WindowsMachineReconciler) updateWindowsNode(ctx context.Context, machine *mapi.Machine,
+	update func(status *wmcoapi.WindowsNodeStatus)) error {
+	windowsNode := &wmcoapi.WindowsNode{}
+	// The WindowsNode is read from the API server, as it is not watched
+	err := r.apiReader.Get(ctx, kubeTypes.NamespacedName{Namespace: r.watchNamespace, Name: machine.GetName()},
+		windowsNode)
+	if err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error getting WindowsNode %s", machine.GetName())
+		}
+		windowsNode = &wmcoapi.WindowsNode{ObjectMeta: meta.ObjectMeta{Name: machine.GetName(),
+			Namespace: r.watchNamespace}}
+		if err := r.client.Create(ctx, windowsNode); err != nil {
+			return errors.Wrapf(err, "error creating WindowsNode %s", machine.GetName())
+		}
+	}
+
+	status := windowsNode.Status.DeepCopy()
+	status.MachineName = machine.GetName()
+	if machine.Spec.ProviderID != nil {
+		status.InstanceID = *machine.Spec.ProviderID
+	}
+	update(status)
+	if equality.Semantic.DeepEqual(*status, windowsNode.Status) {
+		return nil
+	}
+	windowsNode.Status = *status
+	if err := r.client.Status().Update(ctx, windowsNode); err != nil {
+		return errors.Wrapf(err, "error updating status of WindowsNode %s", machine.GetName())
+	}
+	return nil
+}
+
+// removeWindowsNode deletes the WindowsNode of the Machine with the given name, if it exists
+func (r *WindowsMachineReconciler) removeWindowsNode(ctx context.Context, machineName string) error {
+	windowsNode := &wmcoapi.WindowsNode{ObjectMeta: meta.ObjectMeta{Name: machineName, Namespace: r.watchNamespace}}
+	if err := r.client.Delete(ctx, windowsNode); err != nil && !k8sapierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "error deleting WindowsNode %s", machineName)
+	}
+	return nil
+}
+
+// recordConfiguration records the outcome of a configuration of the given Machine in its WindowsNode, given its
+// progress and the configuration error, if any. The configuration is added to the journal of the configuration
+// attempts, a cancelled configuration leaving the rest of the status unchanged.
+func (r *machineReconciliation) recordConfiguration(ctx context.Context, machine *mapi.Machine,
+	progress *configurationProgress, configErr error) error {
+	attempt := progress.attempt(configErr)
+	return r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		status.ConfigurationAttempts = appendConfigurationAttempt(status.ConfigurationAttempts, attempt)
+		if attempt.Outcome == wmcoapi.ConfigurationCancelled {
+			return
+		}
+		if configErr == nil {
+			now := meta.Now()
+			status.LastConfigurationTime = &now
+			status.LastError = ""
+			status.Version = desiredVersion(r.config)
+		} else {
+			status.LastError = configErr.Error()
+		}
+		for _, condition := range configurationConditions(progress.lastStep, configErr) {
+			apimeta.SetStatusCondition(&status.Conditions, condition)
+		}
+	})
+}
+
+// appendConfigurationAttempt returns the given journal of configuration attempts with the given attempt appended, the
+// oldest attempts being dropped beyond maxConfigurationAttempts
+func appendConfigurationAttempt(attempts []wmcoapi.ConfigurationAttempt,
+	attempt wmcoapi.ConfigurationAttempt) []wmcoapi.ConfigurationAttempt {
+	attempts = append(attempts, attempt)
+	if len(attempts) > maxConfigurationAttempts {
+		attempts = attempts[len(attempts)-maxConfigurationAttempts:]
+	}
+	return attempts
+}
+
+// checkNetworkPrerequisites records whether the given cluster network meets the network prerequisites of Windows nodes
+// in the WindowsNode of the given Machine, returning false if it does not
+func (r *WindowsMachineReconciler) checkNetworkPrerequisites(ctx context.Context, machine *mapi.Machine,
+	network *operator.Network) (bool, error) {
+	condition := meta.Condition{Type: wmcoapi.NetworkPrerequisitesMetCondition, Status: meta.ConditionTrue,
+		Reason: "Validated", Message: "The cluster network meets the prerequisites of Windows nodes"}
+	prerequisitesErr := cluster.ValidateNetwork(network)
+	if prerequisitesErr != nil {
+		condition.Status = meta.ConditionFalse
+		condition.Reason = "PrerequisitesMissing"
+		condition.Message = prerequisitesErr.Error()
+		r.log.Error(prerequisitesErr, "machine cannot be configured as the network prerequisites are missing",
+			"machine", machine.GetName())
+		r.recorder.Eventf(machine, core.EventTypeWarning, "NetworkPrerequisitesMissing",
+			"Machine %s cannot be configured: %v", machine.Name, prerequisitesErr)
+	}
+	if err := r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		apimeta.SetStatusCondition(&status.Conditions, condition)
+	}); err != nil {
+		return false, err
+	}
+	return prerequisitesErr == nil, nil
+}
+
+// configurationConditions returns the conditions resulting from a configuration, given the last configuration step
+// completed and the configuration error, if any. Conditions which cannot be determined are not returned.
+func configurationConditions(lastStep windows.ConfigurationStep, configErr error) []meta.Condition {
+	completed := 0
+	for i, step := range configurationSteps {
+		if step == lastStep {
+			completed = i + 1
+		}
+	}
+	if completed == 0 {
+		var authErr *windows.AuthErr
+		if errors.As(configErr, &authErr) {
+			return []meta.Condition{{Type: wmcoapi.ReachableCondition, Status: meta.ConditionFalse,
+				Reason: "AuthenticationFailed", Message: configErr.Error()}}
+		}
+		// The configuration failed before anything was done on the instance, which may or may not be reachable
+		return nil
+	}
+	conditions := []meta.Condition{{Type: wmcoapi.ReachableCondition, Status: meta.ConditionTrue,
+		Reason: "Connected", Message: "The instance was reached over SSH"}}
+
+	for _, required := range []struct {
+		conditionType string
+		step          windows.ConfigurationStep
+	}{
+		{wmcoapi.PayloadCurrentCondition, windows.StepPayloadTransferred},
+		{wmcoapi.ServicesRunningCondition, windows.StepServicesInstalled},
+		{wmcoapi.NetworkReadyCondition, nodeconfig.StepCNIConfigured},
+	} {
+		condition := meta.Condition{Type: required.conditionType, Status: meta.ConditionFalse,
+			Reason: "ConfigurationFailed", Message: "The configuration failed before step " + string(required.step)}
+		for _, step := range configurationSteps[:completed] {
+			if step == required.step {
+				condition.Status = meta.ConditionTrue
+				condition.Reason = string(step)
+				condition.Message = "The configuration step " + string(step) + " completed"
+			}
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions
+}
+
+// syncWindowsNode updates the WindowsNode of the given configured Machine with the state of the given node
+func (r *WindowsMachineReconciler) syncWindowsNode(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) error {
+	return r.updateWindowsNode(ctx, machine, func(status *wmcoapi.WindowsNodeStatus) {
+		status.NodeName = node.GetName()
+		status.Version = node.Annotations[nodeconfig.VersionAnnotation]
+		for _, condition := range nodeConditions(node) {
+			apimeta.SetStatusCondition(&status.Conditions, condition)
+		}
+	})
+}
+
+// nodeConditions returns the PayloadCurrent, ServicesRunning and NetworkReady conditions of the given configured node
+func nodeConditions(node *core.Node) []meta.Condition {
+	payload := meta.Condition{Type: wmcoapi.PayloadCurrentCondition, Status: meta.ConditionTrue,
+		Reason: "CurrentVersion", Message: "The node was configured by the current operator version"}
+	if nodeVersion := node.Annotations[nodeconfig.VersionAnnotation]; nodeVersion != version.Get() {
+		payload.Status = meta.ConditionFalse
+		payload.Reason = "OutdatedVersion"
+		payload.Message = "The node was configured by operator version " + nodeVersion
+	}
+
+	services := meta.Condition{Type: wmcoapi.ServicesRunningCondition, Status: meta.ConditionFalse,
+		Reason: "NodeNotReady", Message: "The kubelet does not report the node as ready"}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == core.NodeReady && condition.Status == core.ConditionTrue {
+			services.Status = meta.ConditionTrue
+			services.Reason = "NodeReady"
+			services.Message = "The kubelet reports the node as ready"
+		}
+	}
+
+	network := meta.Condition{Type: wmcoapi.NetworkReadyCondition, Status: meta.ConditionFalse,
+		Reason: "HybridOverlayNotReady", Message: "The hybrid overlay has not set up the node network"}
+	if _, present := node.Annotations[nodeconfig.HybridOverlayMac]; present {
+		network.Status = meta.ConditionTrue
+		network.Reason = "HybridOverlayReady"
+		network.Message = "The hybrid overlay has set up the node network"
+	}
+	return []meta.Condition{payload, services, network}
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"strconv"
+	"testing"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+// conditionStatuses returns the status of each of the given conditions, keyed by condition type
+func conditionStatuses(conditions []meta.Condition) map[string]meta.ConditionStatus {
+	statuses := make(map[string]meta.ConditionStatus)
+	for _, condition := range conditions {
+		statuses[condition.Type] = condition.Status
+	}
+	return statuses
+}
+
+func TestConfigurationConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		lastStep  windows.ConfigurationStep
+		configErr error
+		expected  map[string]meta.ConditionStatus
+	}{
+		{
+			name:      "failed before any step",
+			configErr: errors.New("connection refused"),
+			expected:  map[string]meta.ConditionStatus{},
+		},
+		{
+			name:      "authentication failure",
+			configErr: errors.Wrap(&windows.AuthErr{}, "failed to configure Windows VM"),
+			expected:  map[string]meta.ConditionStatus{wmcoapi.ReachableCondition: meta.ConditionFalse},
+		},
+		{
+			name:      "failed after services were installed",
+			lastStep:  windows.StepServicesInstalled,
+			configErr: errors.New("node not registered"),
+			expected: map[string]meta.ConditionStatus{
+				wmcoapi.ReachableCondition:       meta.ConditionTrue,
+				wmcoapi.PayloadCurrentCondition:  meta.ConditionTrue,
+				wmcoapi.ServicesRunningCondition: meta.ConditionTrue,
+				wmcoapi.NetworkReadyCondition:    meta.ConditionFalse,
+			},
+		},
+		{
+			name:     "configured",
+			lastStep: nodeconfig.StepCNIConfigured,
+			expected: map[string]meta.ConditionStatus{
+				wmcoapi.ReachableCondition:       meta.ConditionTrue,
+				wmcoapi.PayloadCurrentCondition:  meta.ConditionTrue,
+				wmcoapi.ServicesRunningCondition: meta.ConditionTrue,
+				wmcoapi.NetworkReadyCondition:    meta.ConditionTrue,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, conditionStatuses(configurationConditions(test.lastStep, test.configErr)))
+		})
+	}
+}
+
+func TestNodeConditions(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{nodeconfig.VersionAnnotation: version.Get(),
+			nodeconfig.HybridOverlayMac: "00:11:22:33:44:55"}},
+		Status: core.NodeStatus{Conditions: []core.NodeCondition{{Type: core.NodeReady, Status: core.ConditionTrue}}},
+	}
+	assert.Equal(t, map[string]meta.ConditionStatus{
+		wmcoapi.PayloadCurrentCondition:  meta.ConditionTrue,
+		wmcoapi.ServicesRunningCondition: meta.ConditionTrue,
+		wmcoapi.NetworkReadyCondition:    meta.ConditionTrue,
+	}, conditionStatuses(nodeConditions(node)))
+
+	node = &core.Node{ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{
+		nodeconfig.VersionAnnotation: "0.0.1"}}}
+	assert.Equal(t, map[string]meta.ConditionStatus{
+		wmcoapi.PayloadCurrentCondition:  meta.ConditionFalse,
+		wmcoapi.ServicesRunningCondition: meta.ConditionFalse,
+		wmcoapi.NetworkReadyCondition:    meta.ConditionFalse,
+	}, conditionStatuses(nodeConditions(node)))
+}
+
+func TestAppendConfigurationAttempt(t *testing.T) {
+	var attempts []wmcoapi.ConfigurationAttempt
+	for i := 0; i < maxConfigurationAttempts+2; i++ {
+		attempts = appendConfigurationAttempt(attempts, wmcoapi.ConfigurationAttempt{Error: strconv.Itoa(i)})
+	}
+	assert.Len(t, attempts, maxConfigurationAttempts)
+	assert.Equal(t, "2", attempts[0].Error, "the oldest attempts should be dropped")
+	assert.Equal(t, strconv.Itoa(maxConfigurationAttempts+1), attempts[len(attempts)-1].Error)
+}
+
+func TestConfigurationAttempt(t *testing.T) {
+	progress := newConfigurationProgress(record.NewFakeRecorder(10), &mapi.Machine{})
+	progress.report(windows.StepPayloadTransferred, nil)
+	progress.report(windows.StepServicesInstalled, nil)
+
+	attempt := progress.attempt(nil)
+	assert.Equal(t, wmcoapi.ConfigurationSucceeded, attempt.Outcome)
+	assert.Equal(t, version.Get(), attempt.Version)
+	assert.Equal(t, []string{string(windows.StepPayloadTransferred), string(windows.StepServicesInstalled)},
+		[]string{attempt.Steps[0].Name, attempt.Steps[1].Name})
+
+	attempt = progress.attempt(errors.New("connection refused"))
+	assert.Equal(t, wmcoapi.ConfigurationFailed, attempt.Outcome)
+	assert.Equal(t, "connection refused", attempt.Error)
+
+	attempt = progress.attempt(errors.Wrap(windows.ErrCancelled, "error running hostname"))
+	assert.Equal(t, wmcoapi.ConfigurationCancelled, attempt.Outcome)
+	assert.Empty(t, attempt.Error)
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/retry"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// WindowsUpdatingAnnotation is applied to a node cordoned by WMCO while security updates are installed on it. The
+	// node is uncordoned once the updates are installed and the node is ready again.
+	WindowsUpdatingAnnotation = "windowsmachineconfig.openshift.io/windows-updating"
+	// OSBuildAnnotation holds the operating system build of the node, including its update revision, as last checked
+	OSBuildAnnotation = "windowsmachineconfig.openshift.io/os-build"
+	// WindowsUpdateCheckedAnnotation holds the time at which the node was last checked for security updates
+	WindowsUpdateCheckedAnnotation = "windowsmachineconfig.openshift.io/windows-update-checked"
+	// windowsUpdatePeriod is the interval at which Windows nodes are checked for security updates
+	windowsUpdatePeriod = 24 * time.Hour
+	// windowsUpdatePollDelay is the delay after which the installation of the security updates is checked again
+	windowsUpdatePollDelay = time.Minute
+)
+
+func init() {
+	disruptionAnnotations = append(disruptionAnnotations, WindowsUpdatingAnnotation)
+}
+
+// reconcileWindowsUpdates checks the node of the given Machine for security updates once a day, during maintenance
+// windows, and installs them. The installation is rolled out to at most maxUnhealthyCount nodes at a time: each node
+// is cordoned and drained, rebooted if the updates require it, and uncordoned once its services run and it is ready.
+func (r *machineReconciliation) reconcileWindowsUpdates(ctx context.Context, machine *mapi.Machine,
+	node *core.Node) (ctrl.Result, error) {
+	if !r.config.WindowsUpdates {
+		return ctrl.Result{}, nil
+	}
+	log := r.log.WithValues("windowsmachine", machine.GetNamespace()+"/"+machine.GetName())
+	nodeName := node.GetName()
+	if _, updating := node.Annotations[WindowsUpdatingAnnotation]; !updating {
+		if checked, err := time.Parse(time.RFC3339, node.Annotations[WindowsUpdateCheckedAnnotation]); err == nil &&
+			time.Since(checked) < windowsUpdatePeriod {
+			return ctrl.Result{RequeueAfter: windowsUpdatePeriod - time.Since(checked)}, nil
+		}
+		if r.config.DryRun {
+			reportDryRun(r.recorder, log, machine, "WindowsUpdate",
+				"Machine %s node %s would be checked for security updates, and drained and updated if any are "+
+					"available", machine.GetName(), nodeName)
+			return ctrl.Result{}, nil
+		}
+		delay, err := r.getMaintenanceWindowDelay(ctx, machine)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "unable to determine maintenance window")
+		}
+		if delay > 0 {
+			log.V(1).Info("Windows update check pending until the next maintenance window", "delay", delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+
+		nc, err := r.machineVM(machine)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		updates, err := nc.PendingUpdates()
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(updates) == 0 {
+			if _, err := r.recordWindowsUpdateCheck(ctx, nc, nodeName); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: windowsUpdatePeriod}, nil
+		}
+		// The check is only recorded once the updates are installed, so that a node waiting for its disruption is
+		// checked again rather than skipped until the next period
+		log.Info("security updates available", "node", nodeName, "updates", updates)
+		var result ctrl.Result
+		if node, result, err = r.startNodeDisruption(ctx, machine, node.DeepCopy(), WindowsUpdatingAnnotation,
+			"Windows update"); err != nil || !result.IsZero() {
+			return result, err
+		}
+		r.recorder.Eventf(machine, core.EventTypeNormal, "WindowsUpdateStarted",
+			"Machine %s node %s is being drained to install security updates: %s", machine.GetName(), nodeName,
+			strings.Join(updates, ", "))
+	}
+
+	drained, err := r.drainNode(ctx, node)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to drain node %s", nodeName)
+	}
+	if !drained {
+		log.Info("waiting for node to be drained", "node", nodeName)
+		return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+	}
+	nc, err := r.machineVM(machine)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	result, err := nc.InstallUpdates()
+	if err != nil {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "WindowsUpdateFailure",
+			"Machine %s node %s security updates could not be installed: %v", machine.GetName(), nodeName, err)
+		return ctrl.Result{}, err
+	}
+	if result == nil {
+		log.V(1).Info("waiting for security updates to be installed", "node", nodeName)
+		return ctrl.Result{RequeueAfter: windowsUpdatePollDelay}, nil
+	}
+	if len(result.Failed) > 0 {
+		r.recorder.Eventf(machine, core.EventTypeWarning, "WindowsUpdateFailure",
+			"Machine %s node %s security updates failed to install: %s", machine.GetName(), nodeName,
+			strings.Join(result.Failed, ", "))
+	}
+	if result.RebootRequired {
+		if err := nc.Reboot(); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := nc.WaitForServices(); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "services of node %s not running after update", nodeName)
+	}
+	if err := r.waitForNodeReady(ctx, nodeName); err != nil {
+		return ctrl.Result{}, err
+	}
+	// The OS build is read again, as the updates changed it
+	if node, err = r.recordWindowsUpdateCheck(ctx, nc, nodeName); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.finishNodeDisruption(ctx, nodeName, WindowsUpdatingAnnotation); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info("security updates installed", "node", nodeName, "updates", result.Installed,
+		"rebooted", result.RebootRequired)
+	r.recorder.Eventf(machine, core.EventTypeNormal, "WindowsUpdated",
+		"Machine %s node %s updated to OS build %s, installed: %s", machine.GetName(), nodeName,
+		node.Annotations[OSBuildAnnotation], strings.Join(result.Installed, ", "))
+	return ctrl.Result{RequeueAfter: windowsUpdatePeriod}, nil
+}
+
+// machineVM returns the VM backing the given configured Machine, connected to with the current host settings. The
+// connection is established once per reconciliation and shared by all its checks, which must not close it: it is
+// closed by closeVM at the end of the reconciliation.
+func (r *machineReconciliation) machineVM(machine *mapi.Machine) (windows.Windows, error) {
+	if r.vm != nil {
+		return r.vm, nil
+	}
+	sshAddress, instanceID, err := r.getMachineSSHAddress(machine)
+	if err != nil {
+		return nil, err
+	}
+	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, sshAddress, instanceID, machine.GetName(),
+		r.networkCIDRs, r.vxlanPort, r.signer, r.platform, r.config.SSHUser, windows.KubeletSettings{},
+		r.hostSettings(), nil, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create new nodeconfig for machine %s", machine.GetName())
+	}
+	r.vm = nc.Windows
+	return r.vm, nil
+}
+
+// closeVM closes the connection to the VM established by machineVM, if any, so that the next check connects again
+func (r *machineReconciliation) closeVM() {
+	if r.vm == nil {
+		return
+	}
+	if err := r.vm.Close(); err != nil {
+		r.log.V(1).Error(err, "error closing connection", "instance", r.vm.ID())
+	}
+	r.vm = nil
+}
+
+// recordWindowsUpdateCheck records the current time and the OS build of the VM in the annotations of the node with
+// the given name, returning the updated node
+func (r *WindowsMachineReconciler) recordWindowsUpdateCheck(ctx context.Context, vm windows.Windows,
+	nodeName string) (*core.Node, error) {
+	build, err := vm.OSBuild()
+	if err != nil {
+		return nil, err
+	}
+	node, err := r.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get node %s", nodeName)
+	}
+	node.Annotations[OSBuildAnnotation] = build
+	node.Annotations[WindowsUpdateCheckedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	node, err = r.k8sclientset.CoreV1().Nodes().Update(ctx, node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error annotating node %s", nodeName)
+	}
+	return node, nil
+}
+
+// waitForNodeReady waits for the node with the given name to be reported as ready by its kubelet
+func (r *WindowsMachineReconciler) waitForNodeReady(ctx context.Context, nodeName string) error {
+	err := wait.PollImmediate(retry.Interval, retry.Timeout, func() (bool, error) {
+		node, err := r.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+		if err != nil {
+			r.log.V(1).Error(err, "unable to get node", "node", nodeName)
+			return false, nil
+		}
+		return isNodeReady(node), nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "timed out waiting for node %s to be ready", nodeName)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"golang.org/x/crypto/ssh"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/openshift/windows-machine-config-operator/controllers"
+	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
+	"github.com/openshift/windows-machine-config-operator/pkg/signer"
+)
+
+// osInfoCmd prints information about the operating system of a Windows VM and the state of the services WMCO manages
+const osInfoCmd = "\"Get-CimInstance Win32_OperatingSystem | " +
+	"Format-List CSName,Caption,Version,BuildNumber,OSArchitecture,LastBootUpTime; " +
+	"Get-Service -Name sshd,docker,kubelet,kube-proxy,hybrid-overlay-node,windows_exporter " +
+	"-ErrorAction SilentlyContinue | Format-Table -AutoSize Name,Status,StartType\""
+
+// debugSteps are the configuration steps which can be run by the debug subcommand
+var debugSteps = []string{"host-settings", "windows-exporter", "kubelet"}
+
+// runDebug implements the debug subcommand, which tests the SSH connectivity to the VM of a Windows Machine, prints
+// information about its operating system and optionally runs a single configuration step on it. It is meant to be run
+// within the operator pod, and returns the exit code of the subcommand.
+func runDebug(args []string) int {
+	flags := pflag.NewFlagSet("debug", pflag.ContinueOnError)
+	machineName := flags.String("machine", "", "Name of the Windows Machine to debug")
+	machineNamespace := flags.String("machineNamespace", "openshift-machine-api", "Namespace of the Windows Machine")
+	diagnostics := flags.String("diagnostics", "", "Path of a gzipped tarball the diagnostics of the VM are "+
+		"written to, for inclusion in support cases")
+	step := flags.String("step", "", fmt.Sprintf("Configuration step to run on the VM, one of: %v. The node is not "+
+		"drained before the kubelet step restarts kubelet.", debugSteps))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *machineName == "" {
+		fmt.Fprintln(os.Stderr, "the --machine flag is required")
+		flags.PrintDefaults()
+		return 2
+	}
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	if err := debugMachine(context.TODO(), kubeTypes.NamespacedName{Namespace: *machineNamespace,
+		Name: *machineName}, *step, *diagnostics); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// debugMachine connects to the VM of the given Machine, prints information about it, writes its diagnostics to the
+// given path and runs the given configuration step, if any
+func debugMachine(ctx context.Context, machineKey kubeTypes.NamespacedName, step, diagnosticsPath string) error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the config for talking to a Kubernetes API server")
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return errors.Wrap(err, "error creating client")
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return errors.Wrap(err, "error creating kubernetes clientset")
+	}
+	watchNamespace, err := getWatchNamespace("")
+	if err != nil {
+		return err
+	}
+	clusterConfig, err := cluster.NewConfig(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster configuration")
+	}
+	operatorConfig, err := operatorconfig.Load(ctx, clientset, watchNamespace, operatorconfig.Default())
+	if err != nil {
+		return errors.Wrap(err, "unable to load operator configuration")
+	}
+
+	privateKey, err := secrets.GetPrivateKey(kubeTypes.NamespacedName{Namespace: watchNamespace,
+		Name: secrets.PrivateKeySecret}, c)
+	if err != nil {
+		return errors.Wrap(err, "unable to get private key")
+	}
+	keySigner, err := signer.Create(privateKey)
+	if err != nil {
+		return errors.Wrap(err, "error creating signer")
+	}
+	caKey, err := secrets.GetSSHCAKey(kubeTypes.NamespacedName{Namespace: watchNamespace,
+		Name: secrets.PrivateKeySecret}, c)
+	if err != nil {
+		return errors.Wrap(err, "unable to get SSH certificate authority")
+	}
+	var caSigner ssh.Signer
+	if caKey != nil {
+		if caSigner, err = signer.Create(caKey); err != nil {
+			return errors.Wrap(err, "error creating SSH certificate authority signer")
+		}
+	}
+
+	machine := &mapi.Machine{}
+	if err := c.Get(ctx, machineKey, machine); err != nil {
+		return errors.Wrapf(err, "unable to get Machine %s", machineKey)
+	}
+	if err := applyDebugMachineSetOverrides(ctx, c, machine, operatorConfig); err != nil {
+		return err
+	}
+	ipAddress, instanceID, err := controllers.GetMachineInstance(machine, operatorConfig.NodeIPCIDRs)
+	if err != nil {
+		return err
+	}
+	sshAddress, err := controllers.SelectSSHAddress(machine, ipAddress, operatorConfig.SSHAddressType,
+		operatorConfig.SSHAddressCIDRs)
+	if err != nil {
+		return err
+	}
+
+	host := operatorConfig.HostSettings()
+	host.FIPS = clusterConfig.FIPSEnabled()
+	host.AzureStackHub = clusterConfig.AzureStackHub()
+	host.AWSRegion = clusterConfig.AWSRegion()
+	host.AWSEC2Endpoint = clusterConfig.AWSEC2Endpoint()
+	host.SSHCertificateAuthority = caSigner
+	fmt.Printf("connecting to Machine %s, instance %s, at %s\n", machine.GetName(), instanceID, sshAddress)
+	nc, err := nodeconfig.NewNodeConfig(clientset, sshAddress, instanceID, machine.GetName(),
+		clusterConfig.Network().GetCIDRs(), clusterConfig.Network().VXLANPort(), keySigner, clusterConfig.Platform(),
+		operatorConfig.SSHUser, operatorConfig.KubeletSettings(ipAddress), host, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "SSH connection failed")
+	}
+	defer nc.Close()
+	fmt.Println("SSH connection successful")
+
+	out, err := nc.Run(osInfoCmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error getting operating system information with output: %s", out)
+	}
+	fmt.Println(out)
+
+	drift, err := nc.DesiredStateDrift()
+	if err != nil {
+		return errors.Wrap(err, "error comparing the VM with its desired state")
+	}
+	if len(drift) == 0 {
+		fmt.Println("host settings match the desired state")
+	}
+	for _, resource := range drift {
+		fmt.Printf("drifted from the desired state: %s\n", resource)
+	}
+
+	if diagnosticsPath != "" {
+		if err := writeDiagnosticsArchive(diagnosticsPath, machine.GetName(), nc.CollectDiagnostics()); err != nil {
+			return err
+		}
+		fmt.Printf("diagnostics written to %s\n", diagnosticsPath)
+	}
+
+	switch step {
+	case "":
+		return nil
+	case "host-settings":
+		err = nc.EnsureHostSettings()
+	case "windows-exporter":
+		err = nc.ConfigureWindowsExporter()
+	case "kubelet":
+		err = nc.ReconfigureKubelet(ctx)
+	default:
+		return errors.Errorf("unknown step %q, expected one of: %v", step, debugSteps)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "step %s failed", step)
+	}
+	fmt.Printf("step %s completed\n", step)
+	return nil
+}
+
+// applyDebugMachineSetOverrides overrides the given operator configuration with the annotations of the MachineSet
+// owning the given Machine, if any, as the Windows Machine controller does
+func applyDebugMachineSetOverrides(ctx context.Context, c client.Client, machine *mapi.Machine,
+	operatorConfig *operatorconfig.Config) error {
+	for _, owner := range machine.GetOwnerReferences() {
+		if owner.Kind != "MachineSet" {
+			continue
+		}
+		machineSet := &mapi.MachineSet{}
+		if err := c.Get(ctx, kubeTypes.NamespacedName{Namespace: machine.GetNamespace(), Name: owner.Name},
+			machineSet); err != nil {
+			return errors.Wrapf(err, "unable to get MachineSet %s", owner.Name)
+		}
+		overridden, err := operatorconfig.Override(*operatorConfig, machineSet.GetAnnotations())
+		if err != nil {
+			return errors.Wrapf(err, "invalid configuration overrides on MachineSet %s", owner.Name)
+		}
+		*operatorConfig = *overridden
+	}
+	return nil
+}
+
+// writeDiagnosticsArchive writes the given diagnostics of the given Machine to a gzipped tarball at the given path,
+// within a directory named after the Machine
+func writeDiagnosticsArchive(path, machineName string, diagnostics map[string]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "error creating %s", path)
+	}
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	names := make([]string, 0, len(diagnostics))
+	for name := range diagnostics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := &tar.Header{Name: machineName + "/" + name, Mode: 0644, Size: int64(len(diagnostics[name])),
+			ModTime: time.Now()}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return errors.Wrapf(err, "error writing %s to %s", name, path)
+		}
+		if _, err := tarWriter.Write([]byte(diagnostics[name])); err != nil {
+			return errors.Wrapf(err, "error writing %s to %s", name, path)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return errors.Wrapf(err, "error writing %s", path)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return errors.Wrapf(err, "error writing %s", path)
+	}
+	return nil
+}
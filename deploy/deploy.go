@@ -0,0 +1,13 @@
+// Package deploy holds the manifests deploying the operator without OLM
+package deploy
+
+import (
+	// Required to embed the manifests
+	_ "embed"
+)
+
+// Role holds the Role, granted in the operator namespace, and the ClusterRole of the operator, which must grant the
+// same permissions as the CSV. They are the permissions verified by the operator at startup.
+//
+//go:embed role.yaml
+var Role []byte
@@ -0,0 +1,103 @@
+package deploy
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// manifestsDir is the directory holding the manifests of the OLM bundle
+const manifestsDir = "olm-catalog/windows-machine-config-operator/manifests"
+
+// csv holds the permissions of the operator CSV
+type csv struct {
+	Spec struct {
+		Install struct {
+			Spec struct {
+				Permissions []struct {
+					Rules []rbac.PolicyRule `json:"rules"`
+				} `json:"permissions"`
+				ClusterPermissions []struct {
+					Rules []rbac.PolicyRule `json:"rules"`
+				} `json:"clusterPermissions"`
+			} `json:"spec"`
+		} `json:"install"`
+	} `json:"spec"`
+}
+
+// permission is a single verb granted on a resource by a policy rule
+type permission struct {
+	group, resource, verb, name string
+}
+
+// rulePermissions returns the permissions granted by the given policy rules
+func rulePermissions(rules []rbac.PolicyRule) map[permission]bool {
+	permissions := make(map[permission]bool)
+	for _, rule := range rules {
+		names := rule.ResourceNames
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					for _, name := range names {
+						permissions[permission{group, resource, verb, name}] = true
+					}
+				}
+			}
+		}
+	}
+	return permissions
+}
+
+// TestRoleMatchesCSV tests that the Role and ClusterRole deploying the operator without OLM grant the same permissions
+// as the CSV
+func TestRoleMatchesCSV(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join(manifestsDir,
+		"windows-machine-config-operator.clusterserviceversion.yaml"))
+	require.NoError(t, err)
+	var operatorCSV csv
+	require.NoError(t, k8syaml.Unmarshal(data, &operatorCSV))
+	var namespaced, cluster []rbac.PolicyRule
+	for _, p := range operatorCSV.Spec.Install.Spec.Permissions {
+		namespaced = append(namespaced, p.Rules...)
+	}
+	for _, p := range operatorCSV.Spec.Install.Spec.ClusterPermissions {
+		cluster = append(cluster, p.Rules...)
+	}
+
+	roles := make(map[string][]rbac.PolicyRule)
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(Role), 4096)
+	for {
+		var role rbac.ClusterRole
+		if err := decoder.Decode(&role); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		roles[role.Kind] = append(roles[role.Kind], role.Rules...)
+	}
+	assert.Equal(t, rulePermissions(namespaced), rulePermissions(roles["Role"]))
+	assert.Equal(t, rulePermissions(cluster), rulePermissions(roles["ClusterRole"]))
+}
+
+// TestCRDsMatchBundle tests that the CRDs deploying the operator without OLM are the ones of the OLM bundle
+func TestCRDsMatchBundle(t *testing.T) {
+	for _, name := range []string{"windowsmachineconfig.openshift.io_windowsnodes.yaml",
+		"windowsmachineconfig.openshift.io_windowsfleets.yaml"} {
+		expected, err := ioutil.ReadFile(filepath.Join(manifestsDir, name))
+		require.NoError(t, err)
+		actual, err := ioutil.ReadFile(filepath.Join("crds", name))
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), string(actual), name)
+	}
+}
@@ -22,9 +22,13 @@ find_files() {
         -o -wholename './target' \
         -o -wholename './.git' \
         -o -wholename '*/vendor/*' \
+        -o -wholename './cloud-provider-aws' \
+        -o -wholename './cloud-provider-azure' \
+        -o -wholename './cloud-provider-gcp' \
         -o -wholename './containernetworking-plugins' \
         -o -wholename './kubelet' \
         -o -wholename './kube-proxy' \
+        -o -wholename './kube-rbac-proxy' \
         -o -wholename './ovn-kubernetes' \
         -o -wholename './promu' \
         -o -wholename './windows-machine-config-bootstrapper' \
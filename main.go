@@ -4,27 +4,48 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
+	oconfig "github.com/openshift/api/config/v1"
+	operator "github.com/openshift/api/operator/v1"
 	mapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
-	"github.com/operator-framework/operator-lib/leader"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	uberzap "go.uber.org/zap"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	wmcoapi "github.com/openshift/windows-machine-config-operator/api/v1alpha1"
 	"github.com/openshift/windows-machine-config-operator/controllers"
 	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
+	"github.com/openshift/windows-machine-config-operator/pkg/logging"
 	"github.com/openshift/windows-machine-config-operator/pkg/metrics"
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
 	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig/payload"
+	"github.com/openshift/windows-machine-config-operator/pkg/operatorconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/secrets"
 	"github.com/openshift/windows-machine-config-operator/version"
 )
 
+// serviceAccountNamespacePath is the file holding the namespace of the pod, mounted along with its service account token
+const serviceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -33,19 +54,86 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(mapi.AddToScheme(scheme))
+	utilruntime.Must(operator.Install(scheme))
+	utilruntime.Must(oconfig.Install(scheme))
+	utilruntime.Must(wmcoapi.AddToScheme(scheme))
 }
 
 func main() {
+	// The flags define the default operator configuration, which is overridden by the operator configuration
+	// ConfigMap
 	var debugLogging bool
 	flag.BoolVar(&debugLogging, "debugLogging", false, "Log debug messages")
+	var logFormat string
+	flag.StringVar(&logFormat, "logFormat", "",
+		"Format of the logs: json or console. Defaults to console when debugLogging is set, json otherwise")
+	var canaryUpgrade bool
+	flag.BoolVar(&canaryUpgrade, "canaryUpgrade", false,
+		"Upgrade and verify a single Windows node before upgrading the remaining Windows nodes")
+	var nodeTaint string
+	flag.StringVar(&nodeTaint, "windowsNodeTaint", "",
+		"Taint, in the key=value:effect format, applied to all Windows nodes when they are configured. "+
+			"For example: os=Windows:NoSchedule")
+	var controllerConcurrency string
+	flag.StringVar(&controllerConcurrency, "controllerConcurrency", "",
+		"Comma separated number of reconciliations run concurrently by controllers, in the controller=count format. "+
+			"For example: windowsmachine=4,node=2")
+	// The rate limiter flags are empty when the defaults, or the operator configuration, are used
+	var rateLimiterBaseDelay, rateLimiterMaxDelay, rateLimiterQPS, rateLimiterBurst string
+	flag.StringVar(&rateLimiterBaseDelay, "rateLimiterBaseDelay", "",
+		"Delay before a failed reconciliation is retried, doubled at each consecutive failure. Defaults to 5ms")
+	flag.StringVar(&rateLimiterMaxDelay, "rateLimiterMaxDelay", "",
+		"Maximum delay before a failed reconciliation is retried. Defaults to 1000s")
+	flag.StringVar(&rateLimiterQPS, "rateLimiterQPS", "",
+		"Rate, in objects per second, at which each controller requeues objects. Defaults to 10")
+	flag.StringVar(&rateLimiterBurst, "rateLimiterBurst", "",
+		"Number of objects each controller can requeue at once above rateLimiterQPS. Defaults to 100")
+	// The leader election durations default to the controller-runtime ones, so that a replica takes over within
+	// seconds when the leader dies
+	var leaseDuration, renewDeadline, retryPeriod time.Duration
+	flag.DurationVar(&leaseDuration, "leaderElectLeaseDuration", 15*time.Second,
+		"Duration non-leader replicas wait after the last observed renewal before trying to acquire leadership")
+	flag.DurationVar(&renewDeadline, "leaderElectRenewDeadline", 10*time.Second,
+		"Duration the leader retries renewing its leadership before giving it up")
+	flag.DurationVar(&retryPeriod, "leaderElectRetryPeriod", 2*time.Second,
+		"Duration the replicas wait between attempts to acquire or renew leadership")
+
+	// The watch namespace is set by OLM through the WATCH_NAMESPACE environment variable, the flag allows deploying the
+	// operator without OLM
+	var watchNamespaceFlag string
+	flag.StringVar(&watchNamespaceFlag, "watchNamespace", "",
+		"Namespace holding the operator configuration and secrets. Defaults to the WATCH_NAMESPACE environment "+
+			"variable, or to the namespace of the operator pod")
 
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	// The debug subcommand has its own flags, so it is handled before the operator flags are parsed
+	if len(os.Args) > 1 && os.Args[1] == "debug" {
+		os.Exit(runDebug(os.Args[2:]))
+	}
+
 	pflag.Parse()
 
-	opts := zap.Options{Development: debugLogging}
+	// The log levels can be changed at runtime through the operator configuration
+	logLevel := uberzap.InfoLevel
+	if debugLogging {
+		logLevel = uberzap.DebugLevel
+	}
+	logLevels := logging.NewLevels(logLevel)
+	opts := zap.Options{Development: debugLogging, Level: logLevels,
+		ZapOpts: []uberzap.Option{uberzap.WrapCore(logLevels.WrapCore)}}
+	switch logFormat {
+	case "":
+	case "json":
+		zap.JSONEncoder()(&opts)
+	case "console":
+		zap.ConsoleEncoder()(&opts)
+	default:
+		fmt.Printf("invalid logFormat %q: expected json or console\n", logFormat)
+		os.Exit(1)
+	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	// add version subcommand to query the operator version
@@ -60,7 +148,7 @@ func main() {
 			arg := strings.Replace(fg[0], "--", "", -1)
 			if pflag.Lookup(arg) == nil {
 				fmt.Printf("unknown sub-command: %v\n", os.Args[1])
-				fmt.Print("available sub-commands:\n\tversion\n")
+				fmt.Print("available sub-commands:\n\tversion\n\tdebug\n")
 				os.Exit(1)
 			}
 		}
@@ -68,6 +156,41 @@ func main() {
 
 	version.Print()
 
+	defaultConfig := operatorconfig.Default()
+	defaultConfig.CanaryUpgrade = canaryUpgrade
+	if debugLogging {
+		defaultConfig.LogLevel = operatorconfig.LogLevelDebug
+	}
+	if nodeTaint != "" {
+		taint, err := nodeconfig.ParseTaint(nodeTaint)
+		if err != nil {
+			setupLog.Error(err, "invalid windowsNodeTaint flag")
+			os.Exit(1)
+		}
+		defaultConfig.NodeTaints = []core.Taint{*taint}
+	}
+	// The flags matching settings of the operator configuration set their defaults
+	startupFlags := make(map[string]string)
+	for key, value := range map[string]string{
+		operatorconfig.ControllerConcurrencyKey: controllerConcurrency,
+		operatorconfig.RateLimiterBaseDelayKey:  rateLimiterBaseDelay,
+		operatorconfig.RateLimiterMaxDelayKey:   rateLimiterMaxDelay,
+		operatorconfig.RateLimiterQPSKey:        rateLimiterQPS,
+		operatorconfig.RateLimiterBurstKey:      rateLimiterBurst,
+	} {
+		if value != "" {
+			startupFlags[key] = value
+		}
+	}
+	if len(startupFlags) > 0 {
+		config, err := operatorconfig.Parse(startupFlags, defaultConfig)
+		if err != nil {
+			setupLog.Error(err, "invalid flag")
+			os.Exit(1)
+		}
+		defaultConfig = *config
+	}
+
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -87,6 +210,11 @@ func main() {
 		setupLog.Error(err, "failed to validate required cluster configuration")
 		os.Exit(1)
 	}
+	// The operator keeps running with missing network prerequisites, reporting them through the network readiness
+	// check and the WindowsNodes of the Machines waiting to be configured until they are corrected
+	if err := clusterConfig.Network().Validate(); err != nil {
+		setupLog.Error(err, "cluster network does not meet the prerequisites of Windows nodes")
+	}
 
 	// Checking if required files exist before starting the operator
 	requiredFiles := []string{
@@ -108,14 +236,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	ctx := context.TODO()
-	// Become the leader before proceeding
-	err = leader.Become(ctx, "windows-machine-config-operator-lock")
-	if err != nil {
-		setupLog.Error(err, "failed to become a leader within current namespace")
-		os.Exit(1)
-	}
-
 	// Create a new Manager to provide shared dependencies and start components
 	// TODO: https://issues.redhat.com/browse/WINC-599
 	//       The NewCache field is not being set, as the default is a cluster wide scope, which is what we want
@@ -123,9 +243,19 @@ func main() {
 	//       with cluster scoped resources. Once those issues are resolved, it may be worth switching to using that
 	//       cache type.
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: fmt.Sprintf("%s:%d", metrics.Host, metrics.Port),
-		Port:               9443,
+		Scheme:                 scheme,
+		MetricsBindAddress:     fmt.Sprintf("%s:%d", metrics.Host, metrics.Port),
+		Port:                   9443,
+		HealthProbeBindAddress: ":9440",
+		// Only the leader configures Windows Machines. The lease is released when the leader shuts down, so that a
+		// replica takes over without waiting for the lease to expire.
+		LeaderElection:                true,
+		LeaderElectionID:              "windows-machine-config-operator-leader",
+		LeaderElectionResourceLock:    resourcelock.LeasesResourceLock,
+		LeaderElectionReleaseOnCancel: true,
+		LeaseDuration:                 &leaseDuration,
+		RenewDeadline:                 &renewDeadline,
+		RetryPeriod:                   &retryPeriod,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -134,8 +264,8 @@ func main() {
 
 	// Get the watched namespace. This is originally sourced from from the OperatorGroup associated with the CSV.
 	// Because the WMCO CSV only supports the OwnNamespace InstallMode, the watch namespace will always be the namespace
-	// that WMCO is deployed in.
-	watchNamespace, err := getWatchNamespace()
+	// that WMCO is deployed in. Outside of OLM, it is given by the watchNamespace flag or is the namespace of the pod.
+	watchNamespace, err := getWatchNamespace(watchNamespaceFlag)
 	if err != nil {
 		setupLog.Error(err, "failed to get watch namespace")
 		os.Exit(1)
@@ -151,24 +281,92 @@ func main() {
 		os.Exit(1)
 	}
 
+	namespacedCache, err := controllers.NewNamespacedCache(mgr, watchNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to create namespaced cache")
+		os.Exit(1)
+	}
+
+	// The controller concurrency is read from the operator configuration when the operator starts
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		setupLog.Error(err, "failed to create kubernetes clientset")
+		os.Exit(1)
+	}
+	startupConfig, err := operatorconfig.Load(context.TODO(), clientset, watchNamespace, defaultConfig)
+	if err != nil {
+		setupLog.Error(err, "invalid operator configuration, using the default controller options")
+		startupConfig = &defaultConfig
+	}
+
+	// The RBAC and secrets of the operator are not generated from the CSV when it is deployed without OLM, so they are
+	// verified up front. The operator keeps running, so that they can be corrected without redeploying it.
+	verifyDeployment(clientset, watchNamespace)
+
 	// Setup all Controllers
-	winMachineReconciler, err := controllers.NewWindowsMachineReconciler(mgr, clusterConfig, watchNamespace)
+	winMachineReconciler, err := controllers.NewWindowsMachineReconciler(mgr, clusterConfig, watchNamespace,
+		namespacedCache, defaultConfig)
 	if err != nil {
 		setupLog.Error(err, "unable to create Windows Machine reconciler")
 		os.Exit(1)
 	}
-	if err = winMachineReconciler.SetupWithManager(mgr); err != nil {
+	if err = winMachineReconciler.SetupWithManager(mgr, startupConfig.ControllerOptions("windowsmachine")); err != nil {
 		setupLog.Error(err, "unable to create Windows Machine controller")
 		os.Exit(1)
 	}
 
-	secretReconciler := controllers.NewSecretReconciler(mgr, watchNamespace)
-	if err = secretReconciler.SetupWithManager(mgr); err != nil {
+	nodeReconciler, err := controllers.NewNodeReconciler(mgr, clusterConfig.Platform(), watchNamespace, namespacedCache,
+		defaultConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create Node reconciler")
+		os.Exit(1)
+	}
+	if err = nodeReconciler.SetupWithManager(mgr, startupConfig.ControllerOptions("node")); err != nil {
+		setupLog.Error(err, "unable to create Node controller")
+		os.Exit(1)
+	}
+
+	configReconciler, err := controllers.NewConfigReconciler(mgr, watchNamespace, namespacedCache, defaultConfig,
+		logLevels)
+	if err != nil {
+		setupLog.Error(err, "unable to create operator configuration reconciler")
+		os.Exit(1)
+	}
+	if err = configReconciler.SetupWithManager(mgr, startupConfig.ControllerOptions("config")); err != nil {
+		setupLog.Error(err, "unable to create operator configuration controller")
+		os.Exit(1)
+	}
+
+	metricsReconciler, err := controllers.NewMetricsReconciler(mgr, watchNamespace, namespacedCache)
+	if err != nil {
+		setupLog.Error(err, "unable to create metrics reconciler")
+		os.Exit(1)
+	}
+	if err = metricsReconciler.SetupWithManager(mgr, startupConfig.ControllerOptions("metrics")); err != nil {
+		setupLog.Error(err, "unable to create metrics controller")
+		os.Exit(1)
+	}
+
+	secretReconciler, err := controllers.NewSecretReconciler(mgr, watchNamespace, defaultConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create Secret reconciler")
+		os.Exit(1)
+	}
+	if err = secretReconciler.SetupWithManager(mgr, startupConfig.ControllerOptions("secret")); err != nil {
 		setupLog.Error(err, "unable to create Secret controller")
 		os.Exit(1)
 	}
-	if err := secretReconciler.RemoveInvalidAnnotationsFromLinuxNodes(mgr.GetConfig()); err != nil {
-		setupLog.Error(err, "error removing invalid annotations from Linux nodes")
+
+	// The Windows node fleet is reported through the operator metrics, read from the node cache at each scrape
+	if err := crmetrics.Registry.Register(metrics.NewFleetCollector(mgr.GetClient(),
+		clusterConfig.Platform())); err != nil {
+		setupLog.Error(err, "unable to register Windows node fleet metrics")
+		os.Exit(1)
+	}
+	// The hardware inventory of the Windows nodes is reported from their annotations, read at each scrape
+	if err := crmetrics.Registry.Register(metrics.NewInventoryCollector(mgr.GetClient())); err != nil {
+		setupLog.Error(err, "unable to register Windows node hardware inventory metrics")
+		os.Exit(1)
 	}
 
 	metricsConfig, err := metrics.NewConfig(mgr, cfg, watchNamespace)
@@ -177,15 +375,75 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Best effort to delete stale metric resources from previous operator version.
-	// Logs and creates events if stale resource deletion fails.
-	metricsConfig.RemoveStaleResources(ctx)
+	// The cluster resources are set up by the leader only, once it has been elected. An error stops the manager.
+	err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if err := winMachineReconciler.EnsureUninstallConfigMap(ctx); err != nil {
+			return errors.Wrap(err, "unable to create uninstall ConfigMap")
+		}
+		if err := secretReconciler.RemoveInvalidAnnotationsFromLinuxNodes(mgr.GetConfig()); err != nil {
+			setupLog.Error(err, "error removing invalid annotations from Linux nodes")
+		}
+
+		// Best effort to delete stale metric resources from previous operator version.
+		// Logs and creates events if stale resource deletion fails.
+		metricsConfig.RemoveStaleResources(ctx)
+
+		// Configure the metric resources. Prometheus configuration is skipped on clusters where monitoring is
+		// disabled, not installed or restricted, which is reported through the OperatorCondition.
+		if err := metricsConfig.Configure(ctx); err != nil {
+			return errors.Wrap(err, "error setting up metrics")
+		}
+		if err := winMachineReconciler.ReportCondition(ctx, metricsConfig.Condition()); err != nil {
+			setupLog.Error(err, "unable to report the Prometheus configuration condition")
+		}
+		return nil
+	}))
+	if err != nil {
+		setupLog.Error(err, "unable to set up cluster resources")
+		os.Exit(1)
+	}
+
+	// OLM is told not to upgrade the operator while Windows nodes are being rolled out. Only the leader reports it.
+	if err := mgr.Add(manager.RunnableFunc(winMachineReconciler.ReportUpgradeable)); err != nil {
+		setupLog.Error(err, "unable to set up the Upgradeable condition reporting")
+		os.Exit(1)
+	}
+
+	// The spot instances backing Windows nodes are checked for interruption notices by the leader
+	if err := mgr.Add(manager.RunnableFunc(winMachineReconciler.MonitorInterruptions)); err != nil {
+		setupLog.Error(err, "unable to set up the instance interruption monitoring")
+		os.Exit(1)
+	}
+
+	// The progress of the rollout of the operator version across the Windows Machines is published by the leader
+	if err := mgr.Add(manager.RunnableFunc(winMachineReconciler.ReportUpgradeProgress)); err != nil {
+		setupLog.Error(err, "unable to set up the upgrade progress reporting")
+		os.Exit(1)
+	}
+
+	// The health of the Windows Machines is summarized in the status of the WindowsFleet by the leader
+	if err := mgr.Add(manager.RunnableFunc(winMachineReconciler.ReportFleetStatus)); err != nil {
+		setupLog.Error(err, "unable to set up the Windows fleet status reporting")
+		os.Exit(1)
+	}
 
-	// Configure the metric resources
-	if err := metricsConfig.Configure(ctx); err != nil {
-		setupLog.Error(err, "error setting up metrics")
+	// The operator is live as long as the manager is running, but it is only ready to configure Windows Machines once
+	// the private key secret is in place and the caches are synced. The userData secret and the network prerequisites
+	// are reported on the Machines instead, so that a configuration error does not fail the operator install.
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
+	readyChecks := map[string]healthz.Checker{
+		"private-key": controllers.PrivateKeyCheck(mgr.GetClient(), watchNamespace),
+		"caches":      controllers.CacheSyncCheck(mgr.GetCache(), namespacedCache),
+	}
+	for name, check := range readyChecks {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up ready check", "check", name)
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -214,14 +472,43 @@ func checkIfRequiredFilesExist(requiredFiles []string) error {
 	return nil
 }
 
-// getWatchNamespace returns the Namespace the operator should be watching for changes
+// getWatchNamespace returns the Namespace the operator should be watching for changes: the given flag value if set,
+// else the WATCH_NAMESPACE environment variable set by OLM, else the namespace of the operator pod.
 // An empty value means the operator is running with cluster scope.
-func getWatchNamespace() (string, error) {
+func getWatchNamespace(flagValue string) (string, error) {
 	var watchNamespaceEnvVar = "WATCH_NAMESPACE"
 
-	ns, found := os.LookupEnv(watchNamespaceEnvVar)
-	if !found {
-		return "", fmt.Errorf("%s must be set", watchNamespaceEnvVar)
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if ns, found := os.LookupEnv(watchNamespaceEnvVar); found {
+		return ns, nil
+	}
+	ns, err := ioutil.ReadFile(serviceAccountNamespacePath)
+	if err != nil {
+		return "", fmt.Errorf("%s must be set, or the watchNamespace flag given, when not running in a pod",
+			watchNamespaceEnvVar)
+	}
+	setupLog.Info("watch namespace not set, watching the operator namespace", "namespace", string(ns))
+	return strings.TrimSpace(string(ns)), nil
+}
+
+// verifyDeployment logs the permissions required to configure Windows Machines which are not granted to the operator,
+// and the absence of the private key secret from the given namespace
+func verifyDeployment(clientset kubernetes.Interface, watchNamespace string) {
+	missing, err := controllers.MissingPermissions(context.TODO(), clientset, watchNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to verify the permissions of the operator")
+	} else if len(missing) > 0 {
+		setupLog.Error(fmt.Errorf("missing permissions: %s", strings.Join(missing, ", ")),
+			"the operator service account lacks permissions required to configure Windows Machines")
+	}
+	_, err = clientset.CoreV1().Secrets(watchNamespace).Get(context.TODO(), secrets.PrivateKeySecret,
+		meta.GetOptions{})
+	if k8sapierrors.IsNotFound(err) {
+		setupLog.Info("Windows Machines are not configured until the private key secret is created",
+			"secret", secrets.PrivateKeySecret, "namespace", watchNamespace)
+	} else if err != nil {
+		setupLog.Error(err, "unable to get the private key secret", "secret", secrets.PrivateKeySecret)
 	}
-	return ns, nil
 }
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,3 +29,30 @@ func TestCheckIfRequiredFilesExist(t *testing.T) {
 		"Expected error message is absent")
 
 }
+
+// TestWriteDiagnosticsArchive tests that writeDiagnosticsArchive writes each diagnostic to a file named after it, in a
+// directory named after the Machine
+func TestWriteDiagnosticsArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diagnostics.tar.gz")
+	diagnostics := map[string]string{"kubelet.log": "kubelet logs", "services.txt": "services"}
+	require.NoError(t, writeDiagnosticsArchive(path, "machine", diagnostics))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	read := make(map[string]string)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(tarReader)
+		require.NoError(t, err)
+		read[header.Name] = string(content)
+	}
+	assert.Equal(t, map[string]string{"machine/kubelet.log": "kubelet logs", "machine/services.txt": "services"}, read)
+}
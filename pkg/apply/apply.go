@@ -0,0 +1,134 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FieldManager is the field manager of the fields applied by WMCO, so that the fields it owns are tracked apart from
+// the fields owned by other controllers and users
+const FieldManager = "windows-machine-config-operator"
+
+// Options returns the options of the server-side apply patches of WMCO. Fields owned by other field managers are
+// taken over if force is set, else applying a different value to them fails the patch with a Conflict error.
+func Options(force bool) meta.PatchOptions {
+	return meta.PatchOptions{FieldManager: FieldManager, Force: &force}
+}
+
+// Endpoints applies the given Endpoints object, which WMCO fully owns
+func Endpoints(ctx context.Context, clientset kubernetes.Interface, endpoints *core.Endpoints) error {
+	endpoints.TypeMeta = meta.TypeMeta{APIVersion: "v1", Kind: "Endpoints"}
+	data, err := json.Marshal(endpoints)
+	if err != nil {
+		return errors.Wrapf(err, "error encoding Endpoints %s", endpoints.GetName())
+	}
+	_, err = clientset.CoreV1().Endpoints(endpoints.GetNamespace()).Patch(ctx, endpoints.GetName(),
+		kubeTypes.ApplyPatchType, data, Options(true))
+	return errors.Wrapf(err, "error applying Endpoints %s", endpoints.GetName())
+}
+
+// Secret applies the given Secret, which WMCO fully owns, returning the updated Secret. The keys WMCO applied before
+// and which the Secret no longer holds are removed, unless another field manager also owns them.
+func Secret(ctx context.Context, clientset kubernetes.Interface, secret *core.Secret) (*core.Secret, error) {
+	secret.TypeMeta = meta.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error encoding Secret %s", secret.GetName())
+	}
+	applied, err := clientset.CoreV1().Secrets(secret.GetNamespace()).Patch(ctx, secret.GetName(),
+		kubeTypes.ApplyPatchType, data, Options(true))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error applying Secret %s", secret.GetName())
+	}
+	return applied, nil
+}
+
+// SecretAnnotations applies the given annotations to the Secret with the given name, taking them over from other
+// field managers. The other fields of the Secret are left to their owners.
+func SecretAnnotations(ctx context.Context, clientset kubernetes.Interface, name kubeTypes.NamespacedName,
+	annotations map[string]string) error {
+	data, err := json.Marshal(metadataPatch("Secret", name, nil, annotations))
+	if err != nil {
+		return errors.Wrapf(err, "error encoding annotations of Secret %s", name)
+	}
+	_, err = clientset.CoreV1().Secrets(name.Namespace).Patch(ctx, name.Name, kubeTypes.ApplyPatchType, data,
+		Options(true))
+	return errors.Wrapf(err, "error applying annotations of Secret %s", name)
+}
+
+// NodeMetadata applies the given labels and annotations to the node with the given name, returning the updated node.
+// Labels and annotations owned by other field managers are not taken over: applying a different value to them fails
+// with a Conflict error, detected with k8sapierrors.IsConflict.
+func NodeMetadata(ctx context.Context, clientset kubernetes.Interface, name string, labels,
+	annotations map[string]string) (*core.Node, error) {
+	data, err := json.Marshal(metadataPatch("Node", kubeTypes.NamespacedName{Name: name}, labels, annotations))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error encoding metadata of node %s", name)
+	}
+	node, err := clientset.CoreV1().Nodes().Patch(ctx, name, kubeTypes.ApplyPatchType, data, Options(false))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error applying metadata of node %s", name)
+	}
+	return node, nil
+}
+
+// NodeConditions merges the given conditions into the status of the node with the given name, by condition type. The
+// other conditions of the node, owned by the kubelet and other controllers, are left untouched.
+func NodeConditions(ctx context.Context, clientset kubernetes.Interface, name string,
+	conditions []core.NodeCondition) error {
+	data, err := json.Marshal(conditionsPatch(conditions))
+	if err != nil {
+		return errors.Wrapf(err, "error encoding conditions of node %s", name)
+	}
+	_, err = clientset.CoreV1().Nodes().Patch(ctx, name, kubeTypes.StrategicMergePatchType, data,
+		meta.PatchOptions{FieldManager: FieldManager}, "status")
+	return errors.Wrapf(err, "error patching conditions of node %s", name)
+}
+
+// NodeAnnotations merges the given annotations into the annotations of the node with the given name. Unlike
+// NodeMetadata, the annotations WMCO applied before are kept, so that each can be set on its own.
+func NodeAnnotations(ctx context.Context, clientset kubernetes.Interface, name string,
+	annotations map[string]string) error {
+	data, err := json.Marshal(annotationsPatch(annotations))
+	if err != nil {
+		return errors.Wrapf(err, "error encoding annotations of node %s", name)
+	}
+	_, err = clientset.CoreV1().Nodes().Patch(ctx, name, kubeTypes.MergePatchType, data,
+		meta.PatchOptions{FieldManager: FieldManager})
+	return errors.Wrapf(err, "error patching annotations of node %s", name)
+}
+
+// annotationsPatch returns the merge patch of the given annotations, which are merged with the existing ones
+func annotationsPatch(annotations map[string]string) map[string]interface{} {
+	return map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}}
+}
+
+// conditionsPatch returns the strategic merge patch of the given node conditions, which are merged by type with the
+// existing ones
+func conditionsPatch(conditions []core.NodeCondition) map[string]interface{} {
+	return map[string]interface{}{"status": map[string]interface{}{"conditions": conditions}}
+}
+
+// metadataPatch returns the server-side apply patch of the given labels and annotations of the core object of the
+// given kind and name. The patch is built from maps, as the typed objects would also apply the zero values of their
+// other fields.
+func metadataPatch(kind string, name kubeTypes.NamespacedName, labels,
+	annotations map[string]string) map[string]interface{} {
+	metadata := map[string]interface{}{"name": name.Name}
+	if name.Namespace != "" {
+		metadata["namespace"] = name.Namespace
+	}
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	return map[string]interface{}{"apiVersion": "v1", "kind": kind, "metadata": metadata}
+}
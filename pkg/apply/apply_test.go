@@ -0,0 +1,65 @@
+package apply
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeTypes "k8s.io/apimachinery/pkg/types"
+)
+
+func TestMetadataPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		object      kubeTypes.NamespacedName
+		labels      map[string]string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name:        "node",
+			kind:        "Node",
+			object:      kubeTypes.NamespacedName{Name: "node"},
+			labels:      map[string]string{"team": "a"},
+			annotations: map[string]string{"note": "b"},
+			want: `{"apiVersion":"v1","kind":"Node","metadata":{"annotations":{"note":"b"},"labels":{"team":"a"},` +
+				`"name":"node"}}`,
+		},
+		{
+			name:        "secret annotations",
+			kind:        "Secret",
+			object:      kubeTypes.NamespacedName{Namespace: "ns", Name: "secret"},
+			annotations: map[string]string{"note": "b"},
+			want: `{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"note":"b"},"name":"secret",` +
+				`"namespace":"ns"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(metadataPatch(test.kind, test.object, test.labels, test.annotations))
+			require.NoError(t, err)
+			assert.JSONEq(t, test.want, string(data))
+		})
+	}
+}
+
+func TestConditionsPatch(t *testing.T) {
+	now := meta.Unix(0, 0).UTC()
+	data, err := json.Marshal(conditionsPatch([]core.NodeCondition{{Type: "WindowsSystemDiskPressure",
+		Status: core.ConditionFalse, Reason: "SufficientFreeSpace", LastHeartbeatTime: meta.NewTime(now),
+		LastTransitionTime: meta.NewTime(now)}}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":{"conditions":[{"type":"WindowsSystemDiskPressure","status":"False",`+
+		`"reason":"SufficientFreeSpace","lastHeartbeatTime":"1970-01-01T00:00:00Z",`+
+		`"lastTransitionTime":"1970-01-01T00:00:00Z"}]}}`, string(data))
+}
+
+func TestAnnotationsPatch(t *testing.T) {
+	data, err := json.Marshal(annotationsPatch(map[string]string{"checked": "2021-06-01T00:00:00Z"}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"metadata":{"annotations":{"checked":"2021-06-01T00:00:00Z"}}}`, string(data))
+}
@@ -2,17 +2,22 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
 
 	oconfig "github.com/openshift/api/config/v1"
+	operator "github.com/openshift/api/operator/v1"
 	configclient "github.com/openshift/client-go/config/clientset/versioned"
 	operatorv1 "github.com/openshift/client-go/operator/clientset/versioned/typed/operator/v1"
 	"github.com/pkg/errors"
 	"golang.org/x/mod/semver"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
@@ -21,12 +26,18 @@ const (
 	// baseK8sVersion specifies the base k8s version supported by the operator. (For eg. All versions in the format
 	// 1.20.x are supported for baseK8sVersion 1.20)
 	baseK8sVersion = "v1.21"
+	// installConfigMap is the ConfigMap, in the kube-system namespace, holding the install config of the cluster
+	installConfigMap = "cluster-config-v1"
+	// installConfigKey is the key within the installConfigMap which holds the install config
+	installConfigKey = "install-config"
+	// azureStackCloud is the name of the Azure cloud environment of the clusters running on Azure Stack Hub
+	azureStackCloud = "AzureStackCloud"
 )
 
 // Network interface contains methods to interact with cluster network objects
 type Network interface {
 	Validate() error
-	GetServiceCIDR() (string, error)
+	GetCIDRs() CIDRs
 	VXLANPort() string
 }
 
@@ -38,6 +49,15 @@ type Config interface {
 	Platform() oconfig.PlatformType
 	// Network returns network configuration for the OpenShift cluster
 	Network() Network
+	// FIPSEnabled returns true if the cluster was installed in FIPS mode
+	FIPSEnabled() bool
+	// AzureStackHub returns true if the cluster runs on Azure Stack Hub
+	AzureStackHub() bool
+	// AWSRegion returns the AWS region of the cluster, empty if it does not run on AWS
+	AWSRegion() string
+	// AWSEC2Endpoint returns the custom endpoint of the AWS EC2 service of the cluster, empty if it uses the default
+	// endpoint of its region
+	AWSEC2Endpoint() string
 }
 
 // networkType holds information for a required network type
@@ -59,6 +79,14 @@ type config struct {
 	// platform indicates the cloud on which OpenShift cluster is running
 	// TODO: Remove this once we figure out how to be provider agnostic
 	platform oconfig.PlatformType
+	// fips indicates if the cluster was installed in FIPS mode
+	fips bool
+	// azureStackHub indicates if the cluster runs on Azure Stack Hub
+	azureStackHub bool
+	// awsRegion is the AWS region of the cluster
+	awsRegion string
+	// awsEC2Endpoint is the custom endpoint of the AWS EC2 service of the cluster
+	awsEC2Endpoint string
 }
 
 func (c *config) Platform() oconfig.PlatformType {
@@ -69,6 +97,22 @@ func (c *config) Network() Network {
 	return c.network
 }
 
+func (c *config) FIPSEnabled() bool {
+	return c.fips
+}
+
+func (c *config) AzureStackHub() bool {
+	return c.azureStackHub
+}
+
+func (c *config) AWSRegion() string {
+	return c.awsRegion
+}
+
+func (c *config) AWSEC2Endpoint() string {
+	return c.awsEC2Endpoint
+}
+
 // NewConfig returns a Config struct pertaining to the cluster configuration
 func NewConfig(restConfig *rest.Config) (Config, error) {
 	// get OpenShift API config client.
@@ -101,14 +145,80 @@ func NewConfig(restConfig *rest.Config) (Config, error) {
 	if len(platformStatus.Type) == 0 {
 		return nil, errors.New("error getting platform type")
 	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create kubernetes clientset")
+	}
+	fips, err := getFIPSEnabled(clientset)
+	if err != nil {
+		return nil, errors.Wrap(err, "error determining if FIPS mode is enabled")
+	}
+	awsRegion, awsEC2Endpoint := awsEC2Settings(platformStatus)
 	return &config{
 		oclient:        oclient,
 		operatorClient: operatorClient,
 		network:        network,
 		platform:       platformStatus.Type,
+		fips:           fips,
+		azureStackHub:  isAzureStackHub(platformStatus),
+		awsRegion:      awsRegion,
+		awsEC2Endpoint: awsEC2Endpoint,
 	}, nil
 }
 
+// getFIPSEnabled returns true if the install config of the cluster enables FIPS mode. Clusters without an install
+// config ConfigMap are considered not to be in FIPS mode.
+func getFIPSEnabled(clientset kubernetes.Interface) (bool, error) {
+	cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), installConfigMap, meta.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error getting %s ConfigMap", installConfigMap)
+	}
+	return parseFIPSEnabled(cm.Data[installConfigKey])
+}
+
+// parseFIPSEnabled returns the value of the fips field of the given install config
+func parseFIPSEnabled(installConfig string) (bool, error) {
+	if strings.TrimSpace(installConfig) == "" {
+		return false, nil
+	}
+	data, err := yaml.ToJSON([]byte(installConfig))
+	if err != nil {
+		return false, errors.Wrap(err, "error parsing install config")
+	}
+	var fields struct {
+		FIPS bool `json:"fips"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false, errors.Wrap(err, "error parsing install config")
+	}
+	return fields.FIPS, nil
+}
+
+// isAzureStackHub returns true if the given platform status is the one of a cluster running on Azure Stack Hub. The
+// Azure Government, China and Germany clouds are reached through the public Azure endpoints of their environment.
+func isAzureStackHub(status *oconfig.PlatformStatus) bool {
+	return status.Type == oconfig.AzurePlatformType && status.Azure != nil &&
+		status.Azure.CloudName == azureStackCloud
+}
+
+// awsEC2Settings returns the AWS region of the cluster with the given platform status and the custom endpoint of its
+// EC2 service, if any. Both are empty if the cluster does not run on AWS.
+func awsEC2Settings(status *oconfig.PlatformStatus) (string, string) {
+	if status.Type != oconfig.AWSPlatformType || status.AWS == nil {
+		return "", ""
+	}
+	for _, endpoint := range status.AWS.ServiceEndpoints {
+		if endpoint.Name == "ec2" {
+			return status.AWS.Region, endpoint.URL
+		}
+	}
+	return status.AWS.Region, ""
+}
+
 // validateK8sVersion checks for valid k8s version in the cluster. It returns an error for all versions that are not in
 // range of given base version(x.y.z) and x.y+1.z version.
 func (c *config) validateK8sVersion() error {
@@ -135,22 +245,27 @@ func (c *config) validateK8sVersion() error {
 }
 
 // Validate method checks if the cluster configurations are as required. It throws an error if the configuration could not
-// be validated.
+// be validated. The network configuration, which may be corrected while the operator runs, is validated separately.
 func (c *config) Validate() error {
 	err := c.validateK8sVersion()
 	if err != nil {
 		return errors.Wrap(err, "error validating k8s version")
 	}
-	if err = c.network.Validate(); err != nil {
-		return errors.Wrap(err, "error validating network configuration")
-	}
 	return nil
 }
 
+// CIDRs holds the CIDRs of the cluster networks
+type CIDRs struct {
+	// Service holds the CIDRs of the service networks
+	Service []string
+	// Cluster holds the CIDRs of the pod networks
+	Cluster []string
+}
+
 // clusterNetworkCfg struct holds the information for the cluster network
 type clusterNetworkCfg struct {
-	// serviceCIDR holds the value for cluster network service CIDR
-	serviceCIDR string
+	// cidrs holds the CIDRs of the service and pod networks
+	cidrs CIDRs
 	// vxlanPort is the port to be used for VXLAN communication
 	vxlanPort string
 }
@@ -168,10 +283,10 @@ func networkConfigurationFactory(oclient configclient.Interface, operatorClient
 		return nil, errors.Wrap(err, "error getting cluster network type")
 	}
 
-	// retrieve serviceCIDR using cluster config required for cni configurations
-	serviceCIDR, err := getServiceNetworkCIDR(oclient)
-	if err != nil || serviceCIDR == "" {
-		return nil, errors.Wrap(err, "error getting service network CIDR")
+	// retrieve the service and pod network CIDRs using cluster config required for cni configurations
+	cidrs, err := getNetworkCIDRs(oclient)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cluster network CIDRs")
 	}
 
 	// retrieve the VXLAN port using cluster config
@@ -180,7 +295,7 @@ func networkConfigurationFactory(oclient configclient.Interface, operatorClient
 		return nil, errors.Wrap(err, "error getting the custom vxlan port")
 	}
 
-	clusterNetworkCfg, err := NewClusterNetworkCfg(serviceCIDR, vxlanPort)
+	clusterNetworkCfg, err := NewClusterNetworkCfg(cidrs, vxlanPort)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error getting cluster network config")
 	}
@@ -198,21 +313,21 @@ func networkConfigurationFactory(oclient configclient.Interface, operatorClient
 	}
 }
 
-// NewClusterNetworkCfg assigns a serviceCIDR value and returns a pointer to the clusterNetworkCfg struct
-func NewClusterNetworkCfg(serviceCIDR, vxlanPort string) (*clusterNetworkCfg, error) {
-	if serviceCIDR == "" {
-		return nil, errors.Errorf("can't instantiate cluster network config" +
+// NewClusterNetworkCfg assigns the network CIDRs and returns a pointer to the clusterNetworkCfg struct
+func NewClusterNetworkCfg(cidrs CIDRs, vxlanPort string) (*clusterNetworkCfg, error) {
+	if len(cidrs.Service) == 0 {
+		return nil, errors.Errorf("can't instantiate cluster network config " +
 			"with empty service CIDR value")
 	}
 	return &clusterNetworkCfg{
-		serviceCIDR: serviceCIDR,
-		vxlanPort:   vxlanPort,
+		cidrs:     cidrs,
+		vxlanPort: vxlanPort,
 	}, nil
 }
 
-// GetServiceCIDR returns the serviceCIDR string
-func (ovn *ovnKubernetes) GetServiceCIDR() (string, error) {
-	return ovn.clusterNetworkConfig.serviceCIDR, nil
+// GetCIDRs returns the CIDRs of the service and pod networks
+func (ovn *ovnKubernetes) GetCIDRs() CIDRs {
+	return ovn.clusterNetworkConfig.cidrs
 }
 
 // GetVXLANPort gets the VXLAN port to be used for VXLAN tunnel establishment
@@ -228,14 +343,45 @@ func (ovn *ovnKubernetes) Validate() error {
 		return errors.Wrap(err, "error getting cluster network.operator object")
 	}
 
+	return ValidateNetwork(networkCR)
+}
+
+// ValidateNetwork checks that the given cluster network.operator object meets the network prerequisites of Windows
+// nodes: OVNKubernetes networking with hybrid overlay enabled, and hybrid cluster networks not overlapping the cluster
+// and service networks
+func ValidateNetwork(networkCR *operator.Network) error {
 	defaultNetwork := networkCR.Spec.DefaultNetwork
+	if defaultNetwork.Type != "" && defaultNetwork.Type != operator.NetworkTypeOVNKubernetes {
+		return errors.Errorf("%s : network type not supported", defaultNetwork.Type)
+	}
 	if defaultNetwork.OVNKubernetesConfig == nil || defaultNetwork.OVNKubernetesConfig.HybridOverlayConfig == nil {
 		return errors.New("cluster is not configured for OVN hybrid networking")
 	}
 
-	if len(networkCR.Spec.DefaultNetwork.OVNKubernetesConfig.HybridOverlayConfig.HybridClusterNetwork) == 0 {
+	hybridNetworks := defaultNetwork.OVNKubernetesConfig.HybridOverlayConfig.HybridClusterNetwork
+	if len(hybridNetworks) == 0 {
 		return errors.New("invalid OVN hybrid networking configuration")
 	}
+	otherCIDRs := append([]string{}, networkCR.Spec.ServiceNetwork...)
+	for _, clusterNetwork := range networkCR.Spec.ClusterNetwork {
+		otherCIDRs = append(otherCIDRs, clusterNetwork.CIDR)
+	}
+	for _, hybridNetwork := range hybridNetworks {
+		_, hybridNet, err := net.ParseCIDR(hybridNetwork.CIDR)
+		if err != nil {
+			return errors.Wrapf(err, "invalid hybrid cluster network CIDR %s", hybridNetwork.CIDR)
+		}
+		for _, cidr := range otherCIDRs {
+			_, otherNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				// The cluster and service networks are validated by the cluster network operator
+				continue
+			}
+			if hybridNet.Contains(otherNet.IP) || otherNet.Contains(hybridNet.IP) {
+				return errors.Errorf("hybrid cluster network %s overlaps network %s", hybridNetwork.CIDR, cidr)
+			}
+		}
+	}
 	return nil
 }
 
@@ -249,22 +395,31 @@ func getNetworkType(oclient configclient.Interface) (string, error) {
 	return networkCR.Spec.NetworkType, nil
 }
 
-// getServiceNetworkCIDR gets the serviceCIDR using cluster config required for cni configuration
-func getServiceNetworkCIDR(oclient configclient.Interface) (string, error) {
-	// Get the cluster network object so that we can find the service network
+// getNetworkCIDRs gets the CIDRs of all the service and pod networks using cluster config required for cni
+// configuration
+func getNetworkCIDRs(oclient configclient.Interface) (CIDRs, error) {
+	// Get the cluster network object so that we can find the service and pod networks
 	networkCR, err := oclient.ConfigV1().Networks().Get(context.TODO(), "cluster", meta.GetOptions{})
 	if err != nil {
-		return "", errors.Wrap(err, "error getting cluster network object")
+		return CIDRs{}, errors.Wrap(err, "error getting cluster network object")
 	}
 	if len(networkCR.Spec.ServiceNetwork) == 0 {
-		return "", errors.Wrapf(err, "error getting cluster service CIDR,"+
-			"received empty value for service networks")
+		return CIDRs{}, errors.New("error getting cluster service CIDR, received empty value for service networks")
+	}
+	cidrs := CIDRs{}
+	for _, serviceCIDR := range networkCR.Spec.ServiceNetwork {
+		if err := ValidateCIDR(serviceCIDR); err != nil {
+			return CIDRs{}, errors.Wrapf(err, "invalid cluster service CIDR %s", serviceCIDR)
+		}
+		cidrs.Service = append(cidrs.Service, serviceCIDR)
 	}
-	serviceCIDR := networkCR.Spec.ServiceNetwork[0]
-	if ValidateCIDR(serviceCIDR) != nil {
-		return "", errors.Wrapf(err, "invalid cluster service CIDR %s", serviceCIDR)
+	for _, clusterNetwork := range networkCR.Spec.ClusterNetwork {
+		if err := ValidateCIDR(clusterNetwork.CIDR); err != nil {
+			return CIDRs{}, errors.Wrapf(err, "invalid cluster network CIDR %s", clusterNetwork.CIDR)
+		}
+		cidrs.Cluster = append(cidrs.Cluster, clusterNetwork.CIDR)
 	}
-	return serviceCIDR, nil
+	return cidrs, nil
 }
 
 // getVXLANPort gets the VXLAN port to establish tunnel as a string. The return type doesn't matter as we want to pass
@@ -275,14 +430,28 @@ func getVXLANPort(operatorClient operatorv1.OperatorV1Interface) (string, error)
 	if err != nil {
 		return "", errors.Wrap(err, "error getting cluster network object")
 	}
-	var vxlanPort *uint32
+	return VXLANPort(networkCR), nil
+}
+
+// VXLANPort returns the custom VXLAN port set in the hybrid overlay configuration of the given cluster network.operator
+// object, or an empty string if the default port is used
+func VXLANPort(networkCR *operator.Network) string {
 	if networkCR.Spec.DefaultNetwork.OVNKubernetesConfig != nil &&
 		networkCR.Spec.DefaultNetwork.OVNKubernetesConfig.HybridOverlayConfig != nil &&
 		networkCR.Spec.DefaultNetwork.OVNKubernetesConfig.HybridOverlayConfig.HybridOverlayVXLANPort != nil {
-		vxlanPort = networkCR.Spec.DefaultNetwork.OVNKubernetesConfig.HybridOverlayConfig.HybridOverlayVXLANPort
-		return fmt.Sprint(*vxlanPort), nil
+		return fmt.Sprint(*networkCR.Spec.DefaultNetwork.OVNKubernetesConfig.HybridOverlayConfig.HybridOverlayVXLANPort)
+	}
+	return ""
+}
+
+// PodNetworkMTU returns the MTU of the pod network set in the OVNKubernetes configuration of the given cluster
+// network.operator object, or zero if the MTU is detected by the cluster network operator
+func PodNetworkMTU(networkCR *operator.Network) int {
+	if networkCR.Spec.DefaultNetwork.OVNKubernetesConfig != nil &&
+		networkCR.Spec.DefaultNetwork.OVNKubernetesConfig.MTU != nil {
+		return int(*networkCR.Spec.DefaultNetwork.OVNKubernetesConfig.MTU)
 	}
-	return "", nil
+	return 0
 }
 
 // ValidateCIDR uses the parseCIDR from network package to validate the format of the CIDR
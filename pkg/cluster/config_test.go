@@ -91,6 +91,44 @@ func TestNetworkConfigurationValidate(t *testing.T) {
 	}
 }
 
+// TestValidateNetwork tests that ValidateNetwork rejects hybrid cluster networks overlapping the cluster or service
+// networks
+func TestValidateNetwork(t *testing.T) {
+	var tests = []struct {
+		name          string
+		hybridCIDR    string
+		errorExpected bool
+	}{
+		{"distinct hybrid network", "10.132.0.0/14", false},
+		{"hybrid network within cluster network", "10.130.0.0/16", true},
+		{"hybrid network containing service network", "172.16.0.0/12", true},
+		{"invalid hybrid network", "10.132.0.0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := &operatorv1.Network{Spec: operatorv1.NetworkSpec{
+				ClusterNetwork: []operatorv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}},
+				ServiceNetwork: []string{"172.30.0.0/16"},
+				DefaultNetwork: operatorv1.DefaultNetworkDefinition{
+					Type: operatorv1.NetworkTypeOVNKubernetes,
+					OVNKubernetesConfig: &operatorv1.OVNKubernetesConfig{
+						HybridOverlayConfig: &operatorv1.HybridOverlayConfig{
+							HybridClusterNetwork: []operatorv1.ClusterNetworkEntry{{CIDR: tt.hybridCIDR, HostPrefix: 23}},
+						},
+					},
+				},
+			}}
+			err := ValidateNetwork(network)
+			if tt.errorExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 // CreateFakeClients is a helper function to create fake OpenShift API config and operator clients
 func createFakeClients(networkType string) (configclient.Interface, operatorclient.OperatorV1Interface) {
 	fakeOperatorClient := fakeoperatorclient.NewSimpleClientset().OperatorV1()
@@ -188,3 +226,70 @@ func TestGetVXLANPort(t *testing.T) {
 		})
 	}
 }
+
+// TestParseFIPSEnabled tests the parseFIPSEnabled function
+func TestParseFIPSEnabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		installConfig string
+		want          bool
+		wantErr       bool
+	}{
+		{
+			name:          "empty install config",
+			installConfig: "",
+			want:          false,
+		},
+		{
+			name:          "fips not set",
+			installConfig: "apiVersion: v1\nbaseDomain: example.com\n",
+			want:          false,
+		},
+		{
+			name:          "fips enabled",
+			installConfig: "apiVersion: v1\nbaseDomain: example.com\nfips: true\n",
+			want:          true,
+		},
+		{
+			name:          "invalid install config",
+			installConfig: "fips: [",
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFIPSEnabled(tt.installConfig)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsAzureStackHub(t *testing.T) {
+	assert.False(t, isAzureStackHub(&oconfig.PlatformStatus{Type: oconfig.AzurePlatformType}))
+	assert.False(t, isAzureStackHub(&oconfig.PlatformStatus{Type: oconfig.AzurePlatformType,
+		Azure: &oconfig.AzurePlatformStatus{CloudName: oconfig.AzureUSGovernmentCloud}}))
+	assert.True(t, isAzureStackHub(&oconfig.PlatformStatus{Type: oconfig.AzurePlatformType,
+		Azure: &oconfig.AzurePlatformStatus{CloudName: azureStackCloud}}))
+}
+
+func TestAWSEC2Settings(t *testing.T) {
+	region, endpoint := awsEC2Settings(&oconfig.PlatformStatus{Type: oconfig.AzurePlatformType})
+	assert.Empty(t, region)
+	assert.Empty(t, endpoint)
+
+	region, endpoint = awsEC2Settings(&oconfig.PlatformStatus{Type: oconfig.AWSPlatformType,
+		AWS: &oconfig.AWSPlatformStatus{Region: "us-gov-west-1"}})
+	assert.Equal(t, "us-gov-west-1", region)
+	assert.Empty(t, endpoint)
+
+	region, endpoint = awsEC2Settings(&oconfig.PlatformStatus{Type: oconfig.AWSPlatformType,
+		AWS: &oconfig.AWSPlatformStatus{Region: "cn-north-1", ServiceEndpoints: []oconfig.AWSServiceEndpoint{
+			{Name: "s3", URL: "https://s3.example.com"}, {Name: "ec2", URL: "https://ec2.example.com"}}}})
+	assert.Equal(t, "cn-north-1", region)
+	assert.Equal(t, "https://ec2.example.com", endpoint)
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// controllerLoggerPrefix is the prefix of the names of the controller loggers
+const controllerLoggerPrefix = "controller."
+
+// Levels holds the level of the operator logs, along with the levels overriding it for the logs of given controllers.
+// The levels can be changed at runtime.
+type Levels struct {
+	mu sync.RWMutex
+	// level is the level of the logs not overridden
+	level zapcore.Level
+	// controllers holds the levels of the logs of the controllers, by controller name
+	controllers map[string]zapcore.Level
+}
+
+// NewLevels returns Levels logging at the given level
+func NewLevels(level zapcore.Level) *Levels {
+	return &Levels{level: level}
+}
+
+// Set changes the level of the logs, and the levels overriding it for the logs of the given controllers. Returns true
+// if the levels changed.
+func (l *Levels) Set(level zapcore.Level, controllers map[string]zapcore.Level) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	changed := level != l.level || len(controllers) != len(l.controllers)
+	for name, controllerLevel := range controllers {
+		if current, present := l.controllers[name]; !present || current != controllerLevel {
+			changed = true
+		}
+	}
+	l.level = level
+	l.controllers = controllers
+	return changed
+}
+
+// Enabled returns true if logs at the given level are enabled for any logger
+func (l *Levels) Enabled(level zapcore.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.level.Enabled(level) {
+		return true
+	}
+	for _, controllerLevel := range l.controllers {
+		if controllerLevel.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnabledFor returns true if logs at the given level are enabled for the logger with the given name
+func (l *Levels) EnabledFor(loggerName string, level zapcore.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if strings.HasPrefix(loggerName, controllerLoggerPrefix) {
+		name := strings.SplitN(strings.TrimPrefix(loggerName, controllerLoggerPrefix), ".", 2)[0]
+		if controllerLevel, present := l.controllers[name]; present {
+			return controllerLevel.Enabled(level)
+		}
+	}
+	return l.level.Enabled(level)
+}
+
+// WrapCore returns a core writing the entries of the given core which are enabled by the levels
+func (l *Levels) WrapCore(core zapcore.Core) zapcore.Core {
+	return &levelsCore{Core: core, levels: l}
+}
+
+// levelsCore is a core filtering entries by logger name and level
+type levelsCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func (c *levelsCore) Enabled(level zapcore.Level) bool {
+	return c.levels.Enabled(level)
+}
+
+func (c *levelsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelsCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelsCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.EnabledFor(entry.LoggerName, entry.Level) {
+		return checked
+	}
+	return checked.AddCore(entry, c)
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestLevels tests the Levels type
+func TestLevels(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+	assert.True(t, levels.EnabledFor("controller.windowsmachine", zapcore.InfoLevel))
+	assert.False(t, levels.EnabledFor("controller.windowsmachine", zapcore.DebugLevel))
+	assert.False(t, levels.Enabled(zapcore.DebugLevel))
+
+	changed := levels.Set(zapcore.InfoLevel, map[string]zapcore.Level{"windowsmachine": zapcore.DebugLevel})
+	assert.True(t, changed)
+	assert.True(t, levels.Enabled(zapcore.DebugLevel))
+	assert.True(t, levels.EnabledFor("controller.windowsmachine", zapcore.DebugLevel))
+	assert.True(t, levels.EnabledFor("controller.windowsmachine.child", zapcore.DebugLevel))
+	assert.False(t, levels.EnabledFor("controller.node", zapcore.DebugLevel))
+	assert.False(t, levels.EnabledFor("audit", zapcore.DebugLevel))
+
+	changed = levels.Set(zapcore.InfoLevel, map[string]zapcore.Level{"windowsmachine": zapcore.DebugLevel})
+	assert.False(t, changed)
+}
@@ -0,0 +1,190 @@
+package maintenance
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap, in the operator namespace, which defines the maintenance windows
+	ConfigMapName = "windows-maintenance-windows"
+	// ConfigMapKey is the key within the maintenance windows ConfigMap which holds the window specifications
+	ConfigMapKey = "windows"
+	// maxLookahead is how far in the future the start of the next window is searched for
+	maxLookahead = 366 * 24 * time.Hour
+)
+
+// field is a single field of a cron-like schedule, holding the values that the field matches
+type field struct {
+	values map[int]bool
+	// wildcard is true when the field was specified as '*' and therefore matches every value
+	wildcard bool
+}
+
+// fieldBounds contains the valid minimum and maximum values for each schedule field, in order
+var fieldBounds = [][2]int{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week, 0 is Sunday
+}
+
+// Window is a recurring period of time during which disruptive operations are allowed
+type Window struct {
+	// minute, hour, dom, month and dow define when the window starts
+	minute, hour, dom, month, dow field
+	// duration is how long the window stays open after it starts
+	duration time.Duration
+}
+
+// Parse parses the given maintenance window specification. Each non empty line which does not start with '#' defines a
+// window using a cron-like start time followed by the duration of the window, for example
+// `0 2 * * 6 4h` defines a window starting every Saturday at 02:00 UTC and lasting 4 hours. The cron fields support
+// '*', single values, ranges ('1-5'), lists ('1,3,5') and steps ('*/2' or '0-12/3').
+func Parse(spec string) ([]Window, error) {
+	var windows []Window
+	scanner := bufio.NewScanner(strings.NewReader(spec))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		window, err := parseWindow(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid maintenance window %q", line)
+		}
+		windows = append(windows, *window)
+	}
+	return windows, nil
+}
+
+// parseWindow parses a single maintenance window line
+func parseWindow(line string) (*Window, error) {
+	tokens := strings.Fields(line)
+	if len(tokens) != len(fieldBounds)+1 {
+		return nil, errors.Errorf("expected %d fields, found %d", len(fieldBounds)+1, len(tokens))
+	}
+	fields := make([]field, len(fieldBounds))
+	for i, bounds := range fieldBounds {
+		f, err := parseField(tokens[i], bounds[0], bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = *f
+	}
+	duration, err := time.ParseDuration(tokens[len(fieldBounds)])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid window duration")
+	}
+	if duration <= 0 {
+		return nil, errors.New("window duration must be positive")
+	}
+	return &Window{minute: fields[0], hour: fields[1], dom: fields[2], month: fields[3], dow: fields[4],
+		duration: duration}, nil
+}
+
+// parseField parses a single cron field, ensuring all values are within the given bounds
+func parseField(token string, min, max int) (*field, error) {
+	f := &field{values: make(map[int]bool), wildcard: token == "*"}
+	for _, part := range strings.Split(token, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, errors.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, errors.Errorf("invalid value %q", part)
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, errors.Errorf("invalid range %q", part)
+				}
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, errors.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			f.values[v] = true
+		}
+	}
+	return f, nil
+}
+
+// startsAt returns true if the window starts at the minute of the given time
+func (w *Window) startsAt(t time.Time) bool {
+	if !w.minute.values[t.Minute()] || !w.hour.values[t.Hour()] || !w.month.values[int(t.Month())] {
+		return false
+	}
+	// Following cron semantics, when both the day of month and day of week are restricted, either can match
+	domMatch := w.dom.values[t.Day()]
+	dowMatch := w.dow.values[int(t.Weekday())]
+	if !w.dom.wildcard && !w.dow.wildcard {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Contains returns true if the given time falls within the window
+func (w *Window) Contains(t time.Time) bool {
+	t = t.UTC().Truncate(time.Minute)
+	for start := t; t.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.startsAt(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the next time the window starts after the given time
+func (w *Window) next(t time.Time) (time.Time, bool) {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	for end := t.Add(maxLookahead); t.Before(end); t = t.Add(time.Minute) {
+		if w.startsAt(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IsOpen returns true if disruptive operations are allowed at the given time. Operations are always allowed when no
+// windows are defined.
+func IsOpen(windows []Window, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns the earliest time after the given time at which one of the windows opens. False is returned if
+// none of the windows open within the next year.
+func NextOpening(windows []Window, t time.Time) (time.Time, bool) {
+	var earliest time.Time
+	found := false
+	for _, w := range windows {
+		next, ok := w.next(t)
+		if ok && (!found || next.Before(earliest)) {
+			earliest = next
+			found = true
+		}
+	}
+	return earliest, found
+}
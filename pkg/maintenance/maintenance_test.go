@@ -0,0 +1,78 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		count   int
+		wantErr bool
+	}{
+		{
+			name:  "empty spec",
+			spec:  "",
+			count: 0,
+		},
+		{
+			name:  "comments and multiple windows",
+			spec:  "# weekends\n0 2 * * 0,6 4h\n\n30 22 1-7 * * 90m\n",
+			count: 2,
+		},
+		{
+			name:  "steps",
+			spec:  "*/15 0-12/2 * * * 10m",
+			count: 1,
+		},
+		{
+			name:    "missing duration",
+			spec:    "0 2 * * 6",
+			wantErr: true,
+		},
+		{
+			name:    "out of range",
+			spec:    "0 24 * * 6 1h",
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			spec:    "0 2 * * 6 -1h",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			windows, err := Parse(test.spec)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, windows, test.count)
+		})
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	// Saturdays from 02:00 to 06:00 UTC
+	windows, err := Parse("0 2 * * 6 4h")
+	require.NoError(t, err)
+
+	// 2021-05-01 is a Saturday
+	saturday := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	assert.False(t, IsOpen(windows, saturday.Add(time.Hour+59*time.Minute)))
+	assert.True(t, IsOpen(windows, saturday.Add(2*time.Hour)))
+	assert.True(t, IsOpen(windows, saturday.Add(5*time.Hour+59*time.Minute)))
+	assert.False(t, IsOpen(windows, saturday.Add(6*time.Hour)))
+	assert.True(t, IsOpen(nil, saturday), "operations must be allowed when no windows are defined")
+
+	next, found := NextOpening(windows, saturday.Add(6*time.Hour))
+	require.True(t, found)
+	assert.Equal(t, saturday.AddDate(0, 0, 7).Add(2*time.Hour), next)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+)
+
+// windowsNodesDesc describes the number of Windows nodes, labeled with the attributes relevant to the adoption of
+// Windows nodes and to the version skew within the fleet
+var windowsNodesDesc = prometheus.NewDesc("windows_nodes",
+	"Number of Windows nodes, by platform, container runtime, kubelet version and WMCO payload version",
+	[]string{"platform", "container_runtime", "kubelet_version", "payload_version"}, nil)
+
+// fleetKey identifies the Windows nodes sharing the same attributes
+type fleetKey struct {
+	containerRuntime string
+	kubeletVersion   string
+	payloadVersion   string
+}
+
+// FleetCollector is a Prometheus collector reporting the Windows node fleet, counted from the nodes cached by the
+// operator at each scrape so that the metrics never outlive the nodes
+type FleetCollector struct {
+	// reader lists the nodes from the cache of the manager
+	reader client.Reader
+	// platform is the platform the cluster is running on
+	platform oconfig.PlatformType
+}
+
+// NewFleetCollector returns a collector reporting the Windows nodes listed through the given reader, on the given
+// platform
+func NewFleetCollector(reader client.Reader, platform oconfig.PlatformType) *FleetCollector {
+	return &FleetCollector{reader: reader, platform: platform}
+}
+
+// Describe sends the descriptions of the fleet metrics to the given channel
+func (c *FleetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- windowsNodesDesc
+}
+
+// Collect sends the number of Windows nodes, grouped by attributes, to the given channel
+func (c *FleetCollector) Collect(ch chan<- prometheus.Metric) {
+	nodes := &v1.NodeList{}
+	if err := c.reader.List(context.TODO(), nodes, client.MatchingLabels{v1.LabelOSStable: "windows"}); err != nil {
+		log.Error(err, "unable to list Windows nodes for the fleet metrics")
+		ch <- prometheus.NewInvalidMetric(windowsNodesDesc, err)
+		return
+	}
+	for key, count := range countFleet(nodes.Items) {
+		ch <- prometheus.MustNewConstMetric(windowsNodesDesc, prometheus.GaugeValue, float64(count),
+			string(c.platform), key.containerRuntime, key.kubeletVersion, key.payloadVersion)
+	}
+}
+
+// countFleet returns the number of the given nodes sharing each combination of attributes. The payload version is
+// the WMCO version which configured the node, empty while the node is being configured.
+func countFleet(nodes []v1.Node) map[fleetKey]int {
+	fleet := make(map[fleetKey]int)
+	for _, node := range nodes {
+		fleet[fleetKey{
+			containerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+			kubeletVersion:   node.Status.NodeInfo.KubeletVersion,
+			payloadVersion:   node.Annotations[nodeconfig.VersionAnnotation],
+		}]++
+	}
+	return fleet
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+func TestCountFleet(t *testing.T) {
+	node := func(runtime, kubelet, version string) v1.Node {
+		n := v1.Node{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{ContainerRuntimeVersion: runtime,
+			KubeletVersion: kubelet}}}
+		if version != "" {
+			n.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{nodeconfig.VersionAnnotation: version}}
+		}
+		return n
+	}
+	nodes := []v1.Node{
+		node("docker://20.10.7", "v1.21.1", "3.0.0"),
+		node("docker://20.10.7", "v1.21.1", "3.0.0"),
+		node("docker://20.10.7", "v1.20.0", "2.0.0"),
+		node("docker://20.10.7", "v1.21.1", ""),
+	}
+	assert.Equal(t, map[fleetKey]int{
+		{containerRuntime: "docker://20.10.7", kubeletVersion: "v1.21.1", payloadVersion: "3.0.0"}: 2,
+		{containerRuntime: "docker://20.10.7", kubeletVersion: "v1.20.0", payloadVersion: "2.0.0"}: 1,
+		{containerRuntime: "docker://20.10.7", kubeletVersion: "v1.21.1", payloadVersion: ""}:      1,
+	}, countFleet(nodes))
+	assert.Empty(t, countFleet(nil))
+}
+
+func TestNodeInventory(t *testing.T) {
+	node := &v1.Node{}
+	assert.Nil(t, nodeInventory(node))
+
+	node.Annotations = map[string]string{nodeconfig.HardwareInventoryAnnotation: "{\"cpus\":2,\"memoryBytes\":8}"}
+	assert.Equal(t, &windows.Inventory{CPUs: 2, MemoryBytes: 8}, nodeInventory(node))
+
+	node.Annotations[nodeconfig.HardwareInventoryAnnotation] = "invalid"
+	assert.Nil(t, nodeInventory(node))
+}
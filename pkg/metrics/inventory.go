@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+var (
+	// nodeCPUsDesc describes the number of logical processors of each Windows node
+	nodeCPUsDesc = prometheus.NewDesc("windows_node_cpus",
+		"Number of logical processors of the Windows node, by processor model",
+		[]string{"node", "model"}, nil)
+	// nodeMemoryDesc describes the physical memory of each Windows node
+	nodeMemoryDesc = prometheus.NewDesc("windows_node_memory_bytes",
+		"Physical memory of the Windows node", []string{"node"}, nil)
+	// nodeSystemDiskDesc describes the size of the system drive of each Windows node
+	nodeSystemDiskDesc = prometheus.NewDesc("windows_node_system_disk_bytes",
+		"Size of the system drive of the Windows node", []string{"node"}, nil)
+	// nodeNICSpeedDesc describes the link speed of each physical network adapter of each Windows node
+	nodeNICSpeedDesc = prometheus.NewDesc("windows_node_nic_speed_bits_per_second",
+		"Link speed of the physical network adapters of the Windows node", []string{"node", "nic"}, nil)
+)
+
+// InventoryCollector is a Prometheus collector reporting the hardware inventory of the Windows nodes, read from their
+// hardware inventory annotation at each scrape so that the metrics never outlive the nodes
+type InventoryCollector struct {
+	// reader lists the nodes from the cache of the manager
+	reader client.Reader
+}
+
+// NewInventoryCollector returns a collector reporting the hardware inventory of the Windows nodes listed through the
+// given reader
+func NewInventoryCollector(reader client.Reader) *InventoryCollector {
+	return &InventoryCollector{reader: reader}
+}
+
+// Describe sends the descriptions of the hardware inventory metrics to the given channel
+func (c *InventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nodeCPUsDesc
+	ch <- nodeMemoryDesc
+	ch <- nodeSystemDiskDesc
+	ch <- nodeNICSpeedDesc
+}
+
+// Collect sends the hardware inventory of each Windows node which has one to the given channel
+func (c *InventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	nodes := &v1.NodeList{}
+	if err := c.reader.List(context.TODO(), nodes, client.MatchingLabels{v1.LabelOSStable: "windows"}); err != nil {
+		log.Error(err, "unable to list Windows nodes for the hardware inventory metrics")
+		ch <- prometheus.NewInvalidMetric(nodeCPUsDesc, err)
+		return
+	}
+	for _, node := range nodes.Items {
+		inventory := nodeInventory(&node)
+		if inventory == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(nodeCPUsDesc, prometheus.GaugeValue, float64(inventory.CPUs),
+			node.GetName(), inventory.CPUModel)
+		ch <- prometheus.MustNewConstMetric(nodeMemoryDesc, prometheus.GaugeValue, float64(inventory.MemoryBytes),
+			node.GetName())
+		ch <- prometheus.MustNewConstMetric(nodeSystemDiskDesc, prometheus.GaugeValue,
+			float64(inventory.SystemDiskBytes), node.GetName())
+		for _, nic := range inventory.NICs {
+			ch <- prometheus.MustNewConstMetric(nodeNICSpeedDesc, prometheus.GaugeValue,
+				float64(nic.SpeedBitsPerSecond), node.GetName(), nic.Name)
+		}
+	}
+}
+
+// nodeInventory returns the hardware inventory recorded in the annotation of the given node, or nil if the node has
+// none or it is invalid
+func nodeInventory(node *v1.Node) *windows.Inventory {
+	data, present := node.Annotations[nodeconfig.HardwareInventoryAnnotation]
+	if !present {
+		return nil
+	}
+	inventory := &windows.Inventory{}
+	if err := json.Unmarshal([]byte(data), inventory); err != nil {
+		log.V(1).Info("invalid hardware inventory annotation", "node", node.GetName(), "error", err.Error())
+		return nil
+	}
+	return inventory
+}
@@ -2,7 +2,6 @@ package metrics
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 
@@ -11,7 +10,6 @@ import (
 	"k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	k8sclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -19,6 +17,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
 	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
 )
 
@@ -29,8 +28,13 @@ var (
 )
 
 const (
+	// defaultOperatorName is the name of the operator when it is not set in the environment
+	defaultOperatorName = "windows-machine-config-operator"
 	// metricsPortName specifies the portname used for Prometheus monitoring
 	PortName = "metrics"
+	// HTTPPortName is the port name of the nodes configured by previous WMCO versions, which serve their metrics over
+	// plain HTTP on the same port, until they are recreated
+	HTTPPortName = "metrics-http"
 	// Host is the host address used by Windows metrics
 	Host = "0.0.0.0"
 	// Port is the port number on which windows-exporter is exposed.
@@ -38,6 +42,29 @@ const (
 	// WindowsMetricsResource is the name for objects created for Prometheus monitoring
 	// by current operator version. Its name is defined through the bundle manifests
 	WindowsMetricsResource = "windows-exporter"
+	// monitoringGroupVersion is the API group version of the ServiceMonitors of the monitoring stack, through which
+	// Prometheus is pointed to the metrics Endpoints
+	monitoringGroupVersion = "monitoring.coreos.com/v1"
+	// serviceMonitorKind is the kind of the ServiceMonitors
+	serviceMonitorKind = "ServiceMonitor"
+)
+
+// ConditionType is the type of the condition reporting whether Prometheus is configured to scrape the Windows nodes
+const ConditionType = "PrometheusConfigured"
+
+// Reasons of the condition reporting whether Prometheus is configured to scrape the Windows nodes
+const (
+	// ReasonConfigured is the reason of the condition when the metrics Endpoints are set up
+	ReasonConfigured = "AsExpected"
+	// ReasonMonitoringDisabled is the reason of the condition when cluster monitoring is not enabled in the operator
+	// namespace
+	ReasonMonitoringDisabled = "MonitoringDisabled"
+	// ReasonMonitoringNotInstalled is the reason of the condition when the cluster does not serve the ServiceMonitor
+	// API of the monitoring stack
+	ReasonMonitoringNotInstalled = "MonitoringNotInstalled"
+	// ReasonMonitoringRestricted is the reason of the condition when the operator is not allowed to read the operator
+	// namespace or to write the metrics Endpoints
+	ReasonMonitoringRestricted = "MonitoringRestricted"
 )
 
 // PrometheusNodeConfig holds the information required to configure Prometheus, so that it can scrape metrics from the
@@ -59,16 +86,8 @@ type Config struct {
 	namespace string
 	// recorder to generate events
 	recorder record.EventRecorder
-}
-
-// patchEndpoint contains information regarding patching metrics Endpoint
-type patchEndpoint struct {
-	// op defines patch operation to be performed on the Endpoints object
-	Op string `json:"op"`
-	// path defines the location of the patch
-	Path string `json:"path"`
-	// value defines the data to be patched
-	Value []v1.EndpointSubset `json:"value"`
+	// condition reports the outcome of the last configuration
+	condition metav1.Condition
 }
 
 // NewPrometheuopsNodeConfig creates a new instance for prometheusNodeConfig  to be used by the caller.
@@ -100,38 +119,58 @@ func NewConfig(mgr manager.Manager, cfg *rest.Config, namespace string) (*Config
 	}, nil
 }
 
-// syncMetricsEndpoint updates the endpoint object with the new list of IP addresses from the Windows nodes and the
-// metrics port.
-func (pc *PrometheusNodeConfig) syncMetricsEndpoint(nodeEndpointAdressess []v1.EndpointAddress) error {
-	// Update EndpointSubset field with list of Windows Nodes endpoint addresses and required metrics port information
-	// We need to patch the entire endpoint subset field, since addresses and ports both fields are deleted when there
-	// are no Windows nodes.
-	var subsets []v1.EndpointSubset
-	if nodeEndpointAdressess != nil {
-		subsets = []v1.EndpointSubset{{
-			Addresses: nodeEndpointAdressess,
-			Ports: []v1.EndpointPort{{
-				Name:     PortName,
-				Port:     Port,
-				Protocol: v1.ProtocolTCP,
-			}},
-		}}
-	}
-
-	patchData := []patchEndpoint{{
-		Op:    "replace",
-		Path:  "/subsets",
-		Value: subsets,
-	}}
-	// convert patch data to bytes
-	patchDataBytes, err := json.Marshal(patchData)
+// syncMetricsEndpoint updates the endpoint object with the given subsets, holding the IP addresses of the Windows
+// nodes and the metrics port.
+func (pc *PrometheusNodeConfig) syncMetricsEndpoint(subsets []v1.EndpointSubset) error {
+	// The subsets are omitted when there are no Windows nodes, which removes them as WMCO owns them
+	if err := apply.Endpoints(context.TODO(), pc.k8sclientset, metricsEndpoints(pc.namespace, subsets)); err != nil {
+		return errors.Wrap(err, "unable to sync metrics endpoints")
+	}
+	if subsets != nil {
+		return nil
+	}
+	// The subsets are kept when they are also owned by the field manager of a previous WMCO version, which patched the
+	// Endpoints instead of applying them, so they are removed through an update once the last Windows node is gone
+	endpoints, err := pc.k8sclientset.CoreV1().Endpoints(pc.namespace).Get(context.TODO(), WindowsMetricsResource,
+		metav1.GetOptions{})
 	if err != nil {
-		return errors.Wrap(err, "unable to get patch data in bytes")
+		return errors.Wrapf(err, "could not get metrics endpoints %v", WindowsMetricsResource)
 	}
+	if len(endpoints.Subsets) == 0 {
+		return nil
+	}
+	endpoints.Subsets = nil
+	_, err = pc.k8sclientset.CoreV1().Endpoints(pc.namespace).Update(context.TODO(), endpoints,
+		metav1.UpdateOptions{FieldManager: apply.FieldManager})
+	return errors.Wrap(err, "unable to remove metrics endpoints subsets")
+}
+
+// endpointSubsets returns the subsets of the metrics Endpoints holding the given addresses of the nodes serving their
+// metrics through kube-rbac-proxy, on the metrics port, and the given addresses of the nodes serving them over plain
+// HTTP, on the HTTP port. It is nil when there are no nodes.
+func endpointSubsets(addresses, httpAddresses []v1.EndpointAddress) []v1.EndpointSubset {
+	var subsets []v1.EndpointSubset
+	if len(addresses) > 0 {
+		subsets = append(subsets, v1.EndpointSubset{Addresses: addresses,
+			Ports: []v1.EndpointPort{{Name: PortName, Port: Port, Protocol: v1.ProtocolTCP}}})
+	}
+	if len(httpAddresses) > 0 {
+		subsets = append(subsets, v1.EndpointSubset{Addresses: httpAddresses,
+			Ports: []v1.EndpointPort{{Name: HTTPPortName, Port: Port, Protocol: v1.ProtocolTCP}}})
+	}
+	return subsets
+}
 
-	_, err = pc.k8sclientset.CoreV1().Endpoints(pc.namespace).
-		Patch(context.TODO(), WindowsMetricsResource, types.JSONPatchType, patchDataBytes, metav1.PatchOptions{})
-	return errors.Wrap(err, "unable to sync metrics endpoints")
+// metricsEndpoints returns the metrics Endpoints object of the given namespace, with the given subsets
+func metricsEndpoints(namespace string, subsets []v1.EndpointSubset) *v1.Endpoints {
+	return &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      WindowsMetricsResource,
+			Namespace: namespace,
+			Labels:    map[string]string{"name": WindowsMetricsResource},
+		},
+		Subsets: subsets,
+	}
 }
 
 // Configure patches the endpoint object to reflect the current list Windows nodes.
@@ -141,6 +180,11 @@ func (pc *PrometheusNodeConfig) Configure() error {
 		log.Info("install the prometheus-operator to enable Prometheus configuration")
 		return nil
 	}
+	return pc.sync()
+}
+
+// sync updates the Endpoints object with the addresses of the current list of Windows nodes, if they changed
+func (pc *PrometheusNodeConfig) sync() error {
 	// get list of Windows nodes that are in Ready phase
 	nodes, err := pc.k8sclientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: nodeconfig.WindowsOSLabel,
 		FieldSelector: "spec.unschedulable=false"})
@@ -155,28 +199,31 @@ func (pc *PrometheusNodeConfig) Configure() error {
 		return errors.Wrapf(err, "could not get metrics endpoints %v", WindowsMetricsResource)
 	}
 
-	if !isEndpointsValid(nodes, endpoints) {
-		// check if we can get list of endpoint addresses
-		windowsIPList := getNodeEndpointAddresses(nodes)
-		// sync metrics endpoints object with the current list of addresses
-		if err := pc.syncMetricsEndpoint(windowsIPList); err != nil {
-			return errors.Wrap(err, "error updating endpoints object with list of endpoint addresses")
-		}
+	windowsIPList, httpIPList := getNodeEndpointAddresses(nodes)
+	subsets := endpointSubsets(windowsIPList, httpIPList)
+	if isEndpointsValid(subsets, endpoints) {
+		return nil
+	}
+	// sync metrics endpoints object with the current list of addresses
+	if err := pc.syncMetricsEndpoint(subsets); err != nil {
+		return errors.Wrap(err, "error updating endpoints object with list of endpoint addresses")
 	}
-	log.Info("Prometheus configured", "endpoints", WindowsMetricsResource, "port", Port, "name", PortName)
+	log.Info("Prometheus configured", "endpoints", WindowsMetricsResource, "port", Port, "name", PortName,
+		"addresses", len(windowsIPList), "httpAddresses", len(httpIPList))
 	return nil
 }
 
-// getNodeEndpointAddresses returns a list of endpoint addresses according to the given list of Windows nodes
-func getNodeEndpointAddresses(nodes *v1.NodeList) []v1.EndpointAddress {
-	// an empty list to store node IP addresses
-	var nodeIPAddress []v1.EndpointAddress
+// getNodeEndpointAddresses returns a list of endpoint addresses according to the given list of Windows nodes, split
+// between the nodes serving their metrics through kube-rbac-proxy and the nodes configured by previous WMCO versions,
+// which serve them over plain HTTP
+func getNodeEndpointAddresses(nodes *v1.NodeList) ([]v1.EndpointAddress, []v1.EndpointAddress) {
+	// empty lists to store node IP addresses
+	var nodeIPAddress, httpNodeIPAddress []v1.EndpointAddress
 	// loops through nodes
 	for _, node := range nodes.Items {
 		for _, address := range node.Status.Addresses {
 			if address.Type == "InternalIP" && address.Address != "" {
-				// add IP address address to the endpoint address list
-				nodeIPAddress = append(nodeIPAddress, v1.EndpointAddress{
+				endpointAddress := v1.EndpointAddress{
 					IP:       address.Address,
 					Hostname: "",
 					NodeName: nil,
@@ -184,31 +231,54 @@ func getNodeEndpointAddresses(nodes *v1.NodeList) []v1.EndpointAddress {
 						Kind: "Node",
 						Name: node.Name,
 					},
-				})
+				}
+				// add the IP address to the endpoint address list of the scheme of the node
+				if _, present := node.Annotations[nodeconfig.MetricsProxyAnnotation]; present {
+					nodeIPAddress = append(nodeIPAddress, endpointAddress)
+				} else {
+					httpNodeIPAddress = append(httpNodeIPAddress, endpointAddress)
+				}
 				break
 			}
 		}
 	}
-	return nodeIPAddress
+	return nodeIPAddress, httpNodeIPAddress
 }
 
-// isEndpointsValid returns true if Endpoints object has entries for all the Windows nodes in the cluster.
-// It returns false when any one of the Windows nodes is not present in the subset.
-func isEndpointsValid(nodes *v1.NodeList, endpoints *v1.Endpoints) bool {
-	// check if number of entries in endpoints object match number of Ready Windows nodes
-	if len(endpoints.Subsets) == 0 || len(nodes.Items) != len(endpoints.Subsets[0].Addresses) {
+// isEndpointsValid returns true if the Endpoints object holds exactly the given subsets, regardless of the order of
+// their addresses
+func isEndpointsValid(subsets []v1.EndpointSubset, endpoints *v1.Endpoints) bool {
+	if len(endpoints.Subsets) != len(subsets) {
 		return false
 	}
+	for i, subset := range subsets {
+		if !isSubsetValid(subset, endpoints.Subsets[i]) {
+			return false
+		}
+	}
+	return true
+}
 
-	for _, node := range nodes.Items {
-		nodeFound := false
-		for _, address := range endpoints.Subsets[0].Addresses {
-			if address.TargetRef.Name == node.Name {
-				nodeFound = true
-				break
-			}
+// isSubsetValid returns true if the given Endpoints subset holds exactly the addresses and the port of the given
+// desired subset
+func isSubsetValid(desired, subset v1.EndpointSubset) bool {
+	if len(subset.Addresses) != len(desired.Addresses) {
+		return false
+	}
+	ports := subset.Ports
+	if len(ports) != 1 || ports[0].Name != desired.Ports[0].Name || ports[0].Port != desired.Ports[0].Port {
+		return false
+	}
+
+	current := make(map[string]string, len(desired.Addresses))
+	for _, address := range subset.Addresses {
+		if address.TargetRef == nil {
+			return false
 		}
-		if !nodeFound {
+		current[address.TargetRef.Name] = address.IP
+	}
+	for _, address := range desired.Addresses {
+		if ip, present := current[address.TargetRef.Name]; !present || ip != address.IP {
 			return false
 		}
 	}
@@ -217,27 +287,105 @@ func isEndpointsValid(nodes *v1.NodeList, endpoints *v1.Endpoints) bool {
 
 // Configure takes care of all the required configuration steps
 // for Prometheus monitoring like validating monitoring label
-// and creating metrics Endpoints object.
+// and creating metrics Endpoints object. Prometheus configuration is
+// skipped, rather than failing, when cluster monitoring is disabled in the
+// operator namespace, not installed or restricted, as reported by Condition.
 func (c *Config) Configure(ctx context.Context) error {
+	condition, err := c.configure(ctx)
+	if err != nil {
+		return err
+	}
+	c.condition = condition
+	if condition.Status != metav1.ConditionTrue {
+		metricsEnabled = false
+		log.Info("Prometheus configuration skipped", "reason", condition.Reason, "message", condition.Message)
+	}
+	return nil
+}
+
+// Condition returns the condition reporting whether Prometheus was configured to scrape the Windows nodes by the last
+// successful call to Configure
+func (c *Config) Condition() metav1.Condition {
+	return c.condition
+}
+
+// configure validates that cluster monitoring is available and creates the metrics Endpoints, returning the condition
+// describing the outcome. Only unexpected errors are returned.
+func (c *Config) configure(ctx context.Context) (metav1.Condition, error) {
 	// validate if cluster monitoring is enabled in the operator namespace
 	enabled, err := c.validate(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error validating cluster monitoring label")
+		if apierrors.IsForbidden(err) {
+			return restrictedCondition(err), nil
+		}
+		return metav1.Condition{}, errors.Wrap(err, "error validating cluster monitoring label")
 	}
 	// Create Metrics Endpoint object only if monitoring is enabled
 	if !enabled {
-		return nil
+		return metav1.Condition{Type: ConditionType, Status: metav1.ConditionFalse, Reason: ReasonMonitoringDisabled,
+			Message: fmt.Sprintf("the openshift.io/cluster-monitoring=true label is not set on the %s namespace",
+				c.namespace)}, nil
+	}
+	installed, err := c.monitoringInstalled()
+	if err != nil {
+		return metav1.Condition{}, err
+	}
+	if !installed {
+		return metav1.Condition{Type: ConditionType, Status: metav1.ConditionFalse,
+			Reason:  ReasonMonitoringNotInstalled,
+			Message: fmt.Sprintf("the %s API is not served", monitoringGroupVersion)}, nil
 	}
 	// In the case of an operator restart, a previous Endpoint object will be deleted and a new one will
 	// be created to ensure we have a correct spec.
 	err = c.CoreV1().Endpoints(c.namespace).Delete(ctx, WindowsMetricsResource, metav1.DeleteOptions{})
 	if err != nil && !apierrors.IsNotFound(err) {
-		return errors.Wrap(err, "error deleting existing metrics Endpoint")
+		if apierrors.IsForbidden(err) {
+			return restrictedCondition(err), nil
+		}
+		return metav1.Condition{}, errors.Wrap(err, "error deleting existing metrics Endpoint")
 	}
 	if err := c.createEndpoint(); err != nil {
-		return errors.Wrap(err, "error creating metrics Endpoint")
+		if apierrors.IsForbidden(err) {
+			return restrictedCondition(err), nil
+		}
+		return metav1.Condition{}, errors.Wrap(err, "error creating metrics Endpoint")
 	}
-	return nil
+	return metav1.Condition{Type: ConditionType, Status: metav1.ConditionTrue, Reason: ReasonConfigured,
+		Message: fmt.Sprintf("Prometheus scrapes the Windows nodes through the %s Endpoints",
+			WindowsMetricsResource)}, nil
+}
+
+// restrictedCondition returns the condition reporting that Prometheus cannot be configured, as the operator was
+// denied the given access
+func restrictedCondition(err error) metav1.Condition {
+	return metav1.Condition{Type: ConditionType, Status: metav1.ConditionFalse, Reason: ReasonMonitoringRestricted,
+		Message: err.Error()}
+}
+
+// monitoringInstalled returns true if the cluster serves the ServiceMonitor API of the monitoring stack, through which
+// Prometheus is pointed to the metrics Endpoints
+func (c *Config) monitoringInstalled() (bool, error) {
+	resources, err := c.Discovery().ServerResourcesForGroupVersion(monitoringGroupVersion)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error discovering the %s API", monitoringGroupVersion)
+	}
+	return servesServiceMonitors(resources), nil
+}
+
+// servesServiceMonitors returns true if the given API resources include ServiceMonitors
+func servesServiceMonitors(resources *metav1.APIResourceList) bool {
+	if resources == nil {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == serviceMonitorKind {
+			return true
+		}
+	}
+	return false
 }
 
 // validate will verify if cluster monitoring is enabled in the operator namespace.
@@ -265,10 +413,7 @@ func (c *Config) RemoveStaleResources(ctx context.Context) {
 		log.Error(err, "error getting operator namespace")
 	}
 
-	operatorName, err := getOperatorName()
-	if err != nil {
-		log.Error(err, "error getting operator name")
-	}
+	operatorName := getOperatorName()
 
 	// staleResourceName is the metrics object name created for Prometheus monitoring by previous operator versions
 	staleResourceName := operatorName + "-metrics"
@@ -304,33 +449,21 @@ func (c *Config) RemoveStaleResources(ctx context.Context) {
 // We cannot create endpoints as a part of manifests deployment as
 // Endpoints resources are not currently OLM-supported for bundle creation.
 func (c *Config) createEndpoint() error {
-	// create new Endpoint
-	newEndpoint := &v1.Endpoints{
-		TypeMeta: metav1.TypeMeta{
-			Kind: "Endpoints",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      WindowsMetricsResource,
-			Namespace: c.namespace,
-			Labels:    map[string]string{"name": WindowsMetricsResource},
-		},
-		Subsets: nil,
-	}
-	_, err := c.CoreV1().Endpoints(c.namespace).Create(context.TODO(),
-		newEndpoint, metav1.CreateOptions{})
-	if err != nil {
+	// The Endpoints object is applied, so that WMCO owns its fields
+	if err := apply.Endpoints(context.TODO(), c.Clientset, metricsEndpoints(c.namespace, nil)); err != nil {
 		return errors.Wrap(err, "error creating metrics Endpoint")
 	}
 	return nil
 }
 
-// getOperatorName returns the name of the operator
-func getOperatorName() (string, error) {
+// getOperatorName returns the name of the operator, set by OLM in the OPERATOR_NAME environment variable, defaulting to
+// the name it is deployed with outside of OLM
+func getOperatorName() string {
 	var operatorNameEnvVar = "OPERATOR_NAME"
 
 	name, found := os.LookupEnv(operatorNameEnvVar)
-	if !found {
-		return "", fmt.Errorf("%s must be set", operatorNameEnvVar)
+	if !found || name == "" {
+		return defaultOperatorName
 	}
-	return name, nil
+	return name
 }
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsEndpointsValid(t *testing.T) {
+	addresses := []v1.EndpointAddress{
+		{IP: "10.0.0.1", TargetRef: &v1.ObjectReference{Kind: "Node", Name: "node-1"}},
+		{IP: "10.0.0.2", TargetRef: &v1.ObjectReference{Kind: "Node", Name: "node-2"}},
+	}
+	ports := []v1.EndpointPort{{Name: PortName, Port: Port, Protocol: v1.ProtocolTCP}}
+	testCases := []struct {
+		name          string
+		addresses     []v1.EndpointAddress
+		httpAddresses []v1.EndpointAddress
+		subsets       []v1.EndpointSubset
+		want          bool
+	}{
+		{
+			name: "no nodes and no subsets",
+			want: true,
+		},
+		{
+			name:    "no nodes",
+			subsets: []v1.EndpointSubset{{Addresses: addresses, Ports: ports}},
+			want:    false,
+		},
+		{
+			name:      "no subsets",
+			addresses: addresses,
+			want:      false,
+		},
+		{
+			name:      "same addresses in a different order",
+			addresses: addresses,
+			subsets:   []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{addresses[1], addresses[0]}, Ports: ports}},
+			want:      true,
+		},
+		{
+			name:      "missing node",
+			addresses: addresses,
+			subsets:   []v1.EndpointSubset{{Addresses: addresses[:1], Ports: ports}},
+			want:      false,
+		},
+		{
+			name:      "changed node IP",
+			addresses: addresses,
+			subsets: []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{addresses[0],
+				{IP: "10.0.0.3", TargetRef: addresses[1].TargetRef}}, Ports: ports}},
+			want: false,
+		},
+		{
+			name:      "wrong port",
+			addresses: addresses,
+			subsets: []v1.EndpointSubset{{Addresses: addresses,
+				Ports: []v1.EndpointPort{{Name: PortName, Port: 9100}}}},
+			want: false,
+		},
+		{
+			name:          "node configured by a previous WMCO version",
+			addresses:     addresses[:1],
+			httpAddresses: addresses[1:],
+			subsets: []v1.EndpointSubset{{Addresses: addresses[:1], Ports: ports},
+				{Addresses: addresses[1:], Ports: []v1.EndpointPort{{Name: HTTPPortName, Port: Port}}}},
+			want: true,
+		},
+		{
+			name:          "node serving its metrics over HTTPS on the HTTP port",
+			addresses:     addresses[:1],
+			httpAddresses: addresses[1:],
+			subsets:       []v1.EndpointSubset{{Addresses: addresses, Ports: ports}},
+			want:          false,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, isEndpointsValid(endpointSubsets(test.addresses, test.httpAddresses),
+				&v1.Endpoints{Subsets: test.subsets}))
+		})
+	}
+}
+
+func TestServesServiceMonitors(t *testing.T) {
+	assert.False(t, servesServiceMonitors(nil))
+	assert.False(t, servesServiceMonitors(&metav1.APIResourceList{
+		APIResources: []metav1.APIResource{{Name: "prometheusrules", Kind: "PrometheusRule"}}}))
+	assert.True(t, servesServiceMonitors(&metav1.APIResourceList{APIResources: []metav1.APIResource{
+		{Name: "prometheusrules", Kind: "PrometheusRule"}, {Name: "servicemonitors", Kind: "ServiceMonitor"}}}))
+}
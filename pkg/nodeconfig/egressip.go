@@ -0,0 +1,28 @@
+package nodeconfig
+
+import core "k8s.io/api/core/v1"
+
+const (
+	// EgressAssignableLabel marks the nodes OVN-Kubernetes may assign egress IPs to. Windows nodes run the hybrid
+	// overlay rather than OVN, so they cannot host egress IPs.
+	EgressAssignableLabel = "k8s.ovn.org/egress-assignable"
+	// EgressIPCapableLabel is applied to Windows nodes with the value false, telling that they cannot host egress IPs,
+	// so that the nodes labeled egress-assignable can be selected excluding them
+	EgressIPCapableLabel = "windowsmachineconfig.openshift.io/egress-ip-capable"
+)
+
+// IsEgressAssignable returns true if the given node is labeled as a node egress IPs may be assigned to
+func IsEgressAssignable(node *core.Node) bool {
+	_, present := node.Labels[EgressAssignableLabel]
+	return present
+}
+
+// ExcludeFromEgressIP removes the egress-assignable label from the given Windows node, so that OVN-Kubernetes does not
+// assign egress IPs to it. Returns true if the node was changed.
+func ExcludeFromEgressIP(node *core.Node) bool {
+	if !IsEgressAssignable(node) {
+		return false
+	}
+	delete(node.Labels, EgressAssignableLabel)
+	return true
+}
@@ -0,0 +1,36 @@
+package nodeconfig
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// DirectXGPUsLabel is applied to the Windows nodes with DirectX capable GPUs, holding the number of these GPUs, so that
+// workloads requiring a GPU can be scheduled on them
+const DirectXGPUsLabel = "windowsmachineconfig.openshift.io/directx-gpus"
+
+// labelGPUs detects the GPUs of the VM and sets the DirectXGPUsLabel on the node, removing it from nodes without
+// DirectX capable GPUs. GPUs whose display driver does not allow DirectX to be used from containers are logged.
+func (nc *nodeConfig) labelGPUs() error {
+	gpus, err := nc.GPUs()
+	if err != nil {
+		return errors.Wrap(err, "unable to detect GPUs")
+	}
+	count := 0
+	for _, gpu := range gpus {
+		if !gpu.DirectX {
+			nc.log.Info("GPU display driver does not support DirectX in containers, WDDM 2.5 or later is required",
+				"node", nc.node.GetName(), "gpu", gpu.Name, "driverVersion", gpu.DriverVersion)
+			continue
+		}
+		count++
+	}
+	if count == 0 {
+		delete(nc.node.Labels, DirectXGPUsLabel)
+		return nil
+	}
+	nc.log.Info("DirectX capable GPUs detected", "node", nc.node.GetName(), "count", count)
+	nc.node.Labels[DirectXGPUsLabel] = strconv.Itoa(count)
+	return nil
+}
@@ -0,0 +1,54 @@
+package nodeconfig
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows/windowstest"
+)
+
+func TestLabelGPUs(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want map[string]string
+	}{
+		{
+			name: "no GPU",
+			want: map[string]string{},
+		},
+		{
+			name: "DirectX capable GPUs",
+			out:  "27.21.14.5671|NVIDIA Tesla T4\r\n21.21.13.7570|NVIDIA Tesla K80\r\n27.21.14.5671|NVIDIA Tesla T4\r\n",
+			want: map[string]string{DirectXGPUsLabel: "2"},
+		},
+	}
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Every command is answered with the output of the test
+			remote := &windowstest.FakeRemoteHost{Outputs: map[string]string{"": test.out}}
+			win, err := windows.NewWithRemoteHost(remote, nil, "i-1", "machine", "https://api-int.example.com:22623",
+				"", "", windows.KubeletSettings{}, windows.HostSettings{})
+			require.NoError(t, err)
+			nc := newNodeConfig(nil, win, cluster.CIDRs{}, "", key, windows.KubeletSettings{},
+				windows.HostSettings{DetectGPUs: true}, nil, nil)
+			nc.node = &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node",
+				Labels: map[string]string{DirectXGPUsLabel: "1"}}}
+			require.NoError(t, nc.labelGPUs())
+			assert.Equal(t, test.want, nc.node.Labels)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package nodeconfig
+
+// HookChecksumsAnnotation is applied to the Windows nodes whose VM ran hook scripts when it was last configured,
+// holding the comma separated name=checksum pairs of the SHA256 checksums of the scripts
+const HookChecksumsAnnotation = "windowsmachineconfig.openshift.io/configuration-hooks"
+
+// addHookChecksumsAnnotation records the checksums of the hook scripts run on the VM in the annotations of nc.node,
+// removing the annotation when no hooks were run
+func (nc *nodeConfig) addHookChecksumsAnnotation() {
+	if nc.hookChecksums == "" {
+		delete(nc.node.Annotations, HookChecksumsAnnotation)
+		return
+	}
+	nc.node.Annotations[HookChecksumsAnnotation] = nc.hookChecksums
+}
@@ -0,0 +1,27 @@
+package nodeconfig
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// HardwareInventoryAnnotation is applied to the Windows nodes, holding the JSON hardware inventory of their VM: its
+// processors, memory, system drive size and physical network adapters
+const HardwareInventoryAnnotation = "windowsmachineconfig.openshift.io/hardware-inventory"
+
+// annotateInventory collects the hardware inventory of the VM and records it in the HardwareInventoryAnnotation of the
+// node
+func (nc *nodeConfig) annotateInventory() error {
+	inventory, err := nc.Inventory()
+	if err != nil {
+		return errors.Wrap(err, "unable to collect hardware inventory")
+	}
+	data, err := json.Marshal(inventory)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal hardware inventory")
+	}
+	nc.log.V(1).Info("hardware inventory collected", "node", nc.node.GetName(), "inventory", string(data))
+	nc.node.Annotations[HardwareInventoryAnnotation] = string(data)
+	return nil
+}
@@ -0,0 +1,53 @@
+package nodeconfig
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+const (
+	// ProcessIsolationLabel is applied to the Windows nodes, holding true if the containers of the Windows workloads,
+	// as described by the OS builds of their images, can run process-isolated on the node, else false
+	ProcessIsolationLabel = "windowsmachineconfig.openshift.io/process-isolation"
+	// IncompatibleBuildsAnnotation holds the comma separated OS builds of the images of the Windows workloads whose
+	// containers cannot run process-isolated on the node
+	IncompatibleBuildsAnnotation = "windowsmachineconfig.openshift.io/incompatible-workload-builds"
+)
+
+// labelIsolationModes detects the container isolation modes supported by the VM and describes them through the
+// isolation label of the node, recording the workload OS builds the node cannot run process-isolated containers of
+func (nc *nodeConfig) labelIsolationModes() error {
+	modes, err := nc.IsolationModes()
+	if err != nil {
+		return errors.Wrap(err, "unable to detect container isolation modes")
+	}
+	incompatible := incompatibleBuilds(modes, nc.workloadBuilds)
+	nc.node.Labels[ProcessIsolationLabel] = strconv.FormatBool(len(incompatible) == 0)
+	if len(incompatible) == 0 {
+		delete(nc.node.Annotations, IncompatibleBuildsAnnotation)
+		return nil
+	}
+	nc.log.Info("workload images cannot run process-isolated on the node", "node", nc.node.GetName(),
+		"build", modes.Build, "workloadBuilds", incompatible)
+	nc.node.Annotations[IncompatibleBuildsAnnotation] = strings.Join(incompatible, ",")
+	return nil
+}
+
+// incompatibleBuilds returns, in increasing order, the given workload OS builds whose containers cannot run
+// process-isolated on a VM with the given isolation modes
+func incompatibleBuilds(modes *windows.IsolationModes, workloadBuilds []int) []string {
+	builds := append([]int(nil), workloadBuilds...)
+	sort.Ints(builds)
+	var incompatible []string
+	for _, build := range builds {
+		if modes.ContainerIsolation(build) != windows.IsolationProcess {
+			incompatible = append(incompatible, strconv.Itoa(build))
+		}
+	}
+	return incompatible
+}
@@ -0,0 +1,17 @@
+package nodeconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// TestIncompatibleBuilds tests the incompatibleBuilds function
+func TestIncompatibleBuilds(t *testing.T) {
+	modes := &windows.IsolationModes{Build: 17763}
+	assert.Nil(t, incompatibleBuilds(modes, nil))
+	assert.Nil(t, incompatibleBuilds(modes, []int{17763}))
+	assert.Equal(t, []string{"14393", "19041"}, incompatibleBuilds(modes, []int{19041, 17763, 14393}))
+}
@@ -0,0 +1,68 @@
+package nodeconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// KubeletConfigHashAnnotation is the hash of the kubelet settings applied to the node on top of the configuration
+// generated by the bootstrapper. It is not present when no settings are applied.
+const KubeletConfigHashAnnotation = "windowsmachineconfig.openshift.io/kubelet-config-hash"
+
+// CreateKubeletConfigHash returns the value of the kubelet configuration hash annotation for the given settings
+func CreateKubeletConfigHash(settings windows.KubeletSettings) (string, error) {
+	if settings.IsEmpty() {
+		return "", nil
+	}
+	// Maps are encoded with sorted keys, so the encoding of equal settings is identical
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", errors.Wrap(err, "error encoding kubelet settings")
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// ReconfigureKubelet applies the kubelet settings to the already configured Windows VM, restarting kubelet if
+// needed, and updates the kubelet configuration hash annotation of the node. It is cancelled once the given context is
+// done.
+func (nc *nodeConfig) ReconfigureKubelet(ctx context.Context) error {
+	defer windows.CancelOnDone(ctx, nc.Windows)()
+	if err := nc.Windows.ConfigureKubelet(); err != nil {
+		return errors.Wrapf(err, "error configuring kubelet on VM %s", nc.ID())
+	}
+	if err := nc.setNode(ctx); err != nil {
+		return errors.Wrapf(err, "error getting node object for VM %s", nc.ID())
+	}
+	if err := nc.addKubeletConfigHashAnnotation(); err != nil {
+		return err
+	}
+	node, err := nc.k8sclientset.CoreV1().Nodes().Update(ctx, nc.node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager})
+	if err != nil {
+		return errors.Wrapf(err, "error updating annotations of node %s", nc.node.GetName())
+	}
+	nc.node = node
+	return nil
+}
+
+// addKubeletConfigHashAnnotation sets the kubelet configuration hash annotation of nc.node
+func (nc *nodeConfig) addKubeletConfigHashAnnotation() error {
+	hash, err := CreateKubeletConfigHash(nc.kubelet)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		delete(nc.node.Annotations, KubeletConfigHashAnnotation)
+		return nil
+	}
+	nc.node.Annotations[KubeletConfigHashAnnotation] = hash
+	return nil
+}
@@ -0,0 +1,53 @@
+package nodeconfig
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// KubeProxyDSRAnnotation indicates whether Direct Server Return is enabled in the kube-proxy of the node. Nodes
+// configured before the annotation was introduced do not have it, and have DSR disabled.
+const KubeProxyDSRAnnotation = "windowsmachineconfig.openshift.io/kube-proxy-dsr"
+
+// IsKubeProxyDSRCurrent returns true if the kube-proxy of the given node has Direct Server Return enabled or disabled
+// as requested
+func IsKubeProxyDSRCurrent(node *core.Node, dsr bool) bool {
+	current, present := node.Annotations[KubeProxyDSRAnnotation]
+	if !present {
+		current = strconv.FormatBool(false)
+	}
+	return current == strconv.FormatBool(dsr)
+}
+
+// ReconfigureKubeProxy applies the Direct Server Return setting to the kube-proxy of the already configured Windows
+// VM, restarting kube-proxy if needed, and updates the kube-proxy DSR annotation of the node. It is cancelled once the
+// given context is done.
+func (nc *nodeConfig) ReconfigureKubeProxy(ctx context.Context) error {
+	defer windows.CancelOnDone(ctx, nc.Windows)()
+	if err := nc.Windows.ReconfigureKubeProxy(); err != nil {
+		return errors.Wrapf(err, "error configuring kube-proxy on VM %s", nc.ID())
+	}
+	if err := nc.setNode(ctx); err != nil {
+		return errors.Wrapf(err, "error getting node object for VM %s", nc.ID())
+	}
+	nc.addKubeProxyDSRAnnotation()
+	node, err := nc.k8sclientset.CoreV1().Nodes().Update(ctx, nc.node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager})
+	if err != nil {
+		return errors.Wrapf(err, "error updating annotations of node %s", nc.node.GetName())
+	}
+	nc.node = node
+	return nil
+}
+
+// addKubeProxyDSRAnnotation sets the kube-proxy DSR annotation of nc.node
+func (nc *nodeConfig) addKubeProxyDSRAnnotation() {
+	nc.node.Annotations[KubeProxyDSRAnnotation] = strconv.FormatBool(nc.kubeProxyDSR)
+}
@@ -0,0 +1,192 @@
+package nodeconfig
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// ManagedLabelsAnnotation holds the comma separated keys of the node labels applied by WMCO, so that labels which
+	// are no longer desired can be removed
+	ManagedLabelsAnnotation = "windowsmachineconfig.openshift.io/managed-labels"
+	// ManagedTaintsAnnotation holds the comma separated key:effect pairs of the node taints applied by WMCO, so that
+	// taints which are no longer desired can be removed
+	ManagedTaintsAnnotation = "windowsmachineconfig.openshift.io/managed-taints"
+	// ManagedAnnotationsAnnotation holds the comma separated keys of the node annotations applied by WMCO, so that
+	// annotations which are no longer desired can be removed
+	ManagedAnnotationsAnnotation = "windowsmachineconfig.openshift.io/managed-annotations"
+)
+
+// StartupTaint is applied to Windows nodes as soon as they register, and removed once their Kubernetes components are
+// verified running, so that workloads are not scheduled on partially configured nodes
+var StartupTaint = core.Taint{Key: "node.windowsmachineconfig.openshift.io/configuring",
+	Effect: core.TaintEffectNoSchedule}
+
+// CloudProviderUninitializedTaint is applied by kubelet to the nodes it registers with an external cloud provider, and
+// removed by the external cloud controller manager once it initialized the node
+var CloudProviderUninitializedTaint = core.Taint{Key: "node.cloudprovider.kubernetes.io/uninitialized",
+	Effect: core.TaintEffectNoSchedule}
+
+// SyncNodeMetadata ensures the given node has the desired labels and taints. Labels and taints previously applied by
+// this function which are no longer desired are removed, while the ones added by others are left untouched. Returns
+// true if the node was changed.
+func SyncNodeMetadata(node *core.Node, labels map[string]string, taints []core.Taint) bool {
+	changed := false
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+
+	for _, key := range splitAnnotation(node.Annotations[ManagedLabelsAnnotation]) {
+		if _, desired := labels[key]; desired {
+			continue
+		}
+		if _, present := node.Labels[key]; present {
+			delete(node.Labels, key)
+			changed = true
+		}
+	}
+	var labelKeys []string
+	for key, value := range labels {
+		labelKeys = append(labelKeys, key)
+		if current, present := node.Labels[key]; !present || current != value {
+			node.Labels[key] = value
+			changed = true
+		}
+	}
+
+	var desiredTaints []string
+	for _, taint := range taints {
+		desiredTaints = append(desiredTaints, taintKey(taint))
+	}
+	managed := make(map[string]bool)
+	for _, key := range splitAnnotation(node.Annotations[ManagedTaintsAnnotation]) {
+		managed[key] = true
+	}
+	var remainingTaints []core.Taint
+	for _, taint := range node.Spec.Taints {
+		if managed[taintKey(taint)] && !HasTaint(taints, taint) {
+			changed = true
+			continue
+		}
+		remainingTaints = append(remainingTaints, taint)
+	}
+	node.Spec.Taints = remainingTaints
+	for _, taint := range taints {
+		if !HasTaint(node.Spec.Taints, taint) {
+			node.Spec.Taints = append(node.Spec.Taints, taint)
+			changed = true
+		}
+	}
+
+	changed = setAnnotation(node, ManagedLabelsAnnotation, labelKeys) || changed
+	changed = setAnnotation(node, ManagedTaintsAnnotation, desiredTaints) || changed
+	return changed
+}
+
+// SyncNodeAnnotations ensures the given node has the desired annotations. Annotations previously applied by this
+// function which are no longer desired are removed, while the ones added by others are left untouched. Returns true if
+// the node was changed.
+func SyncNodeAnnotations(node *core.Node, annotations map[string]string) bool {
+	changed := false
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	for _, key := range splitAnnotation(node.Annotations[ManagedAnnotationsAnnotation]) {
+		if _, desired := annotations[key]; desired {
+			continue
+		}
+		if _, present := node.Annotations[key]; present {
+			delete(node.Annotations, key)
+			changed = true
+		}
+	}
+	var keys []string
+	for key, value := range annotations {
+		keys = append(keys, key)
+		if current, present := node.Annotations[key]; !present || current != value {
+			node.Annotations[key] = value
+			changed = true
+		}
+	}
+	return setAnnotation(node, ManagedAnnotationsAnnotation, keys) || changed
+}
+
+// setAnnotation sets the given annotation on the node to the sorted, comma separated values, removing the annotation
+// if there are no values. Returns true if the annotation was changed.
+func setAnnotation(node *core.Node, annotation string, values []string) bool {
+	current, present := node.Annotations[annotation]
+	if len(values) == 0 {
+		delete(node.Annotations, annotation)
+		return present
+	}
+	sort.Strings(values)
+	value := strings.Join(values, ",")
+	node.Annotations[annotation] = value
+	return current != value
+}
+
+// splitAnnotation returns the values in the given comma separated annotation value
+func splitAnnotation(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// removeTaint removes the taints with the same key and effect as the given taint from the given node. Returns true if
+// the node was changed.
+func removeTaint(node *core.Node, taint core.Taint) bool {
+	var remainingTaints []core.Taint
+	for _, t := range node.Spec.Taints {
+		if !t.MatchTaint(&taint) {
+			remainingTaints = append(remainingTaints, t)
+		}
+	}
+	changed := len(remainingTaints) != len(node.Spec.Taints)
+	node.Spec.Taints = remainingTaints
+	return changed
+}
+
+// taintKey returns the key:effect pair identifying the given taint
+func taintKey(taint core.Taint) string {
+	return taint.Key + ":" + string(taint.Effect)
+}
+
+// HasTaint returns true if the given taints contain a taint with the same key and effect as the given taint
+func HasTaint(taints []core.Taint, taint core.Taint) bool {
+	for _, t := range taints {
+		if t.MatchTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTaint parses a taint in the `key=value:effect` or `key:effect` format
+func ParseTaint(spec string) (*core.Taint, error) {
+	i := strings.LastIndex(spec, ":")
+	if i == -1 {
+		return nil, errors.Errorf("invalid taint %q: expected key=value:effect", spec)
+	}
+	taint := &core.Taint{Effect: core.TaintEffect(spec[i+1:])}
+	switch taint.Effect {
+	case core.TaintEffectNoSchedule, core.TaintEffectPreferNoSchedule, core.TaintEffectNoExecute:
+	default:
+		return nil, errors.Errorf("invalid taint %q: unsupported effect %q", spec, taint.Effect)
+	}
+	keyValue := strings.SplitN(spec[:i], "=", 2)
+	taint.Key = keyValue[0]
+	if len(keyValue) == 2 {
+		taint.Value = keyValue[1]
+	}
+	if taint.Key == "" {
+		return nil, errors.Errorf("invalid taint %q: empty key", spec)
+	}
+	return taint, nil
+}
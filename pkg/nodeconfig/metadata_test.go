@@ -0,0 +1,129 @@
+package nodeconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestSyncNodeMetadata tests the SyncNodeMetadata function
+func TestSyncNodeMetadata(t *testing.T) {
+	userTaint := core.Taint{Key: "user", Effect: core.TaintEffectNoSchedule}
+	oldTaint := core.Taint{Key: "old", Effect: core.TaintEffectNoSchedule}
+	newTaint := core.Taint{Key: "new", Value: "true", Effect: core.TaintEffectNoExecute}
+
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{
+			Labels: map[string]string{"user": "a", "old": "b", "kept": "c"},
+			Annotations: map[string]string{
+				ManagedLabelsAnnotation: "kept,old",
+				ManagedTaintsAnnotation: "old:NoSchedule",
+			},
+		},
+		Spec: core.NodeSpec{Taints: []core.Taint{userTaint, oldTaint}},
+	}
+	labels := map[string]string{"kept": "d", "new": "e"}
+	taints := []core.Taint{newTaint}
+
+	assert.True(t, SyncNodeMetadata(node, labels, taints))
+	assert.Equal(t, map[string]string{"user": "a", "kept": "d", "new": "e"}, node.Labels)
+	assert.Equal(t, []core.Taint{userTaint, newTaint}, node.Spec.Taints)
+	assert.Equal(t, "kept,new", node.Annotations[ManagedLabelsAnnotation])
+	assert.Equal(t, "new:NoExecute", node.Annotations[ManagedTaintsAnnotation])
+
+	// Syncing again must be a no-op
+	assert.False(t, SyncNodeMetadata(node, labels, taints))
+
+	// Removing all desired metadata removes the tracking annotations
+	assert.True(t, SyncNodeMetadata(node, nil, nil))
+	assert.Equal(t, map[string]string{"user": "a"}, node.Labels)
+	assert.Equal(t, []core.Taint{userTaint}, node.Spec.Taints)
+	assert.NotContains(t, node.Annotations, ManagedLabelsAnnotation)
+	assert.NotContains(t, node.Annotations, ManagedTaintsAnnotation)
+}
+
+// TestSyncNodeAnnotations tests the SyncNodeAnnotations function
+func TestSyncNodeAnnotations(t *testing.T) {
+	node := &core.Node{ObjectMeta: meta.ObjectMeta{Annotations: map[string]string{
+		"user": "a", "old": "b", "kept": "c", ManagedAnnotationsAnnotation: "kept,old"}}}
+	annotations := map[string]string{"kept": "d", "new": "e"}
+
+	assert.True(t, SyncNodeAnnotations(node, annotations))
+	assert.Equal(t, map[string]string{"user": "a", "kept": "d", "new": "e", ManagedAnnotationsAnnotation: "kept,new"},
+		node.Annotations)
+
+	// Syncing again must be a no-op
+	assert.False(t, SyncNodeAnnotations(node, annotations))
+
+	// Removing all desired annotations removes the tracking annotation
+	assert.True(t, SyncNodeAnnotations(node, nil))
+	assert.Equal(t, map[string]string{"user": "a"}, node.Annotations)
+}
+
+// TestRemoveTaint tests the removeTaint function
+func TestRemoveTaint(t *testing.T) {
+	userTaint := core.Taint{Key: StartupTaint.Key, Effect: core.TaintEffectNoExecute}
+	node := &core.Node{Spec: core.NodeSpec{Taints: []core.Taint{userTaint, StartupTaint}}}
+
+	assert.True(t, removeTaint(node, StartupTaint))
+	assert.Equal(t, []core.Taint{userTaint}, node.Spec.Taints)
+	assert.False(t, removeTaint(node, StartupTaint))
+}
+
+// TestParseTaint tests the ParseTaint function
+func TestParseTaint(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    *core.Taint
+		wantErr bool
+	}{
+		{
+			name: "key, value and effect",
+			spec: "os=Windows:NoSchedule",
+			want: &core.Taint{Key: "os", Value: "Windows", Effect: core.TaintEffectNoSchedule},
+		},
+		{
+			name: "key and effect",
+			spec: "example.com/windows:PreferNoSchedule",
+			want: &core.Taint{Key: "example.com/windows", Effect: core.TaintEffectPreferNoSchedule},
+		},
+		{
+			name:    "missing effect",
+			spec:    "os=Windows",
+			wantErr: true,
+		},
+		{
+			name:    "invalid effect",
+			spec:    "os=Windows:NoRun",
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			spec:    "=Windows:NoSchedule",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTaint(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExcludeFromEgressIP(t *testing.T) {
+	node := &core.Node{ObjectMeta: meta.ObjectMeta{Labels: map[string]string{EgressAssignableLabel: "", "user": "a"}}}
+	assert.True(t, IsEgressAssignable(node))
+	assert.True(t, ExcludeFromEgressIP(node))
+	assert.Equal(t, map[string]string{"user": "a"}, node.Labels)
+	assert.False(t, ExcludeFromEgressIP(node))
+}
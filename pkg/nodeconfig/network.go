@@ -76,7 +76,7 @@ func (nw *network) cleanupTempConfig(configFile string) error {
 
 // populateCniConfig populates the CNI config template with necessary information and
 // creates a new file in temp directory to store the modified template
-func (nw *network) populateCniConfig(serviceCIDR string, templatePath string) (string, error) {
+func (nw *network) populateCniConfig(cidrs cluster.CIDRs, templatePath string) (string, error) {
 	if nw.hostSubnet == "" {
 		return "", errors.New("can't populate CNI config with empty hostSubnet")
 	}
@@ -91,7 +91,7 @@ func (nw *network) populateCniConfig(serviceCIDR string, templatePath string) (s
 		return "", errors.Wrap(err, "error converting CNI template into cniCfg struct")
 	}
 
-	if err = populateCfgPolicies(&cniCfg.Policies, serviceCIDR); err != nil {
+	if err = populateCfgPolicies(&cniCfg.Policies, cidrs); err != nil {
 		return "", errors.Wrap(err, "error populating config policies in cniConf struct")
 	}
 
@@ -123,12 +123,22 @@ func (nw *network) populateCniConfig(serviceCIDR string, templatePath string) (s
 	return cniConfigPath.Name(), nil
 }
 
-// populateCfgPolicies populates the policies in cniConf struct with serviceCIDR information
-func populateCfgPolicies(cniCfgPolicies *policies, serviceCIDR string) error {
+// populateCfgPolicies populates the policies in cniConf struct with the service network CIDRs. The traffic to all the
+// service networks is excluded from the outbound NAT, and the route policy is repeated for each service network.
+func populateCfgPolicies(cniCfgPolicies *policies, cidrs cluster.CIDRs) error {
 	if len(*cniCfgPolicies) < 2 || len((*cniCfgPolicies)[0].Value.ExceptionList) == 0 || (*cniCfgPolicies)[1].Value.DestinationPrefix == "" {
 		return errors.Errorf("invalid policy fields in cniConf struct")
 	}
-	(*cniCfgPolicies)[0].Value.ExceptionList[0] = serviceCIDR
-	(*cniCfgPolicies)[1].Value.DestinationPrefix = serviceCIDR
+	if len(cidrs.Service) == 0 {
+		return errors.Errorf("no service CIDR to populate cniConf struct with")
+	}
+	(*cniCfgPolicies)[0].Value.ExceptionList = append([]string{}, cidrs.Service...)
+	routePolicy := (*cniCfgPolicies)[1]
+	populated := append(policies{}, (*cniCfgPolicies)[0])
+	for _, serviceCIDR := range cidrs.Service {
+		routePolicy.Value.DestinationPrefix = serviceCIDR
+		populated = append(populated, routePolicy)
+	}
+	*cniCfgPolicies = append(populated, (*cniCfgPolicies)[2:]...)
 	return nil
 }
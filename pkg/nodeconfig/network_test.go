@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
 )
 
 var tests = []struct {
@@ -29,7 +31,7 @@ var tests = []struct {
 func TestPopulateCfgPoliciesError(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := populateCfgPolicies(tt.policies, tt.serviceCIDR)
+			err := populateCfgPolicies(tt.policies, cluster.CIDRs{Service: []string{tt.serviceCIDR}})
 			if tt.errorMessage == "" {
 				require.Nil(t, err, "Successful check for invalid CNI config template")
 			} else {
@@ -46,12 +48,25 @@ func TestPopulateCfgPoliciesError(t *testing.T) {
 func TestPopulateCfgPoliciesValues(t *testing.T) {
 	policies := mockValidPolicies()
 	serviceCIDR := "10.128.0.0/14"
-	_ = populateCfgPolicies(policies, serviceCIDR)
+	_ = populateCfgPolicies(policies, cluster.CIDRs{Service: []string{serviceCIDR}})
 	if (*policies)[0].Value.ExceptionList[0] != serviceCIDR || (*policies)[1].Value.DestinationPrefix != serviceCIDR {
 		t.Errorf("error populating policies in CNI config")
 	}
 }
 
+// TestPopulateCfgPoliciesMultipleCIDRs tests that populateCfgPolicies excludes all the service networks, and only
+// them, from the outbound NAT, and adds a route policy for each service network
+func TestPopulateCfgPoliciesMultipleCIDRs(t *testing.T) {
+	policies := mockValidPolicies()
+	cidrs := cluster.CIDRs{Service: []string{"172.30.0.0/16", "172.31.0.0/16"},
+		Cluster: []string{"10.128.0.0/14", "10.140.0.0/14"}}
+	require.NoError(t, populateCfgPolicies(policies, cidrs))
+	require.Len(t, *policies, 3)
+	assert.Equal(t, []string{"172.30.0.0/16", "172.31.0.0/16"}, (*policies)[0].Value.ExceptionList)
+	assert.Equal(t, "172.30.0.0/16", (*policies)[1].Value.DestinationPrefix)
+	assert.Equal(t, "172.31.0.0/16", (*policies)[2].Value.DestinationPrefix)
+}
+
 // mockValidPolicies is a helper function to create a set of valid CNI config policies
 // for testing populateCfgPolicies()
 func mockValidPolicies() *policies {
@@ -13,15 +13,17 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
 	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	crclientcfg "sigs.k8s.io/controller-runtime/pkg/client/config"
 
+	"github.com/openshift/windows-machine-config-operator/pkg/apply"
 	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
 	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig/payload"
 	"github.com/openshift/windows-machine-config-operator/pkg/retry"
+	keysigner "github.com/openshift/windows-machine-config-operator/pkg/signer"
 	"github.com/openshift/windows-machine-config-operator/pkg/windows"
 	"github.com/openshift/windows-machine-config-operator/version"
 )
@@ -39,8 +41,34 @@ const (
 	VersionAnnotation = "windowsmachineconfig.openshift.io/version"
 	// PubKeyHashAnnotation corresponds to the public key present on the VM
 	PubKeyHashAnnotation = "windowsmachineconfig.openshift.io/pub-key-hash"
+	// VXLANPortAnnotation indicates the custom VXLAN port the hybrid overlay of the node was configured with, empty
+	// for the default port
+	VXLANPortAnnotation = "windowsmachineconfig.openshift.io/vxlan-port"
+	// MetricsProxyAnnotation indicates the node serves its metrics over HTTPS through kube-rbac-proxy. The nodes
+	// configured by previous WMCO versions serve them over plain HTTP.
+	MetricsProxyAnnotation = "windowsmachineconfig.openshift.io/metrics-proxy"
 )
 
+const (
+	// StepNodeJoined is completed once the node of the VM is registered
+	StepNodeJoined windows.ConfigurationStep = "NodeJoined"
+	// StepHybridOverlayReady is completed once the hybrid overlay is running and has set up the node network
+	StepHybridOverlayReady windows.ConfigurationStep = "HybridOverlayReady"
+	// StepCNIConfigured is completed once kubelet is configured with the CNI configuration
+	StepCNIConfigured windows.ConfigurationStep = "CNIConfigured"
+)
+
+// ProgressFunc is called with each configuration step as it completes, and with the node of the VM once it is
+// registered
+type ProgressFunc func(step windows.ConfigurationStep, node *core.Node)
+
+// report calls the given progress function, if any, with the given step and node
+func (p ProgressFunc) report(step windows.ConfigurationStep, node *core.Node) {
+	if p != nil {
+		p(step, node)
+	}
+}
+
 // nodeConfig holds the information to make the given VM a kubernetes node. As of now, it holds the information
 // related to kubeclient and the windowsVM.
 type nodeConfig struct {
@@ -50,13 +78,36 @@ type nodeConfig struct {
 	windows.Windows
 	// Node holds the information related to node object
 	node *core.Node
+	// nodeName is the name the node is registered with if it overrides the host name of the VM, else empty
+	nodeName string
 	// network holds the network information specific to the node
 	network *network
 	// publicKeyHash is the hash of the public key present on the VM
 	publicKeyHash string
-	// clusterServiceCIDR holds the service CIDR for cluster
-	clusterServiceCIDR string
-	log                logr.Logger
+	// vxlanPort is the custom VXLAN port the hybrid overlay is configured with
+	vxlanPort string
+	// kubeProxyDSR indicates that kube-proxy is configured with Direct Server Return
+	kubeProxyDSR bool
+	// detectGPUs indicates that the node is labeled with the number of DirectX capable GPUs of the VM
+	detectGPUs bool
+	// externalCloudProvider indicates that the node is initialized by the external cloud controller manager
+	externalCloudProvider bool
+	// payloadVersion is the previous WMCO version whose payload the VM is configured with, empty for the current one
+	payloadVersion string
+	// hookChecksums are the checksums of the hook scripts run on the VM, empty when there are none
+	hookChecksums string
+	// workloadBuilds are the OS builds of the images of the Windows workloads, whose containers must be able to run
+	// process-isolated on the node
+	workloadBuilds []int
+	// networkCIDRs holds the service and pod network CIDRs of the cluster
+	networkCIDRs cluster.CIDRs
+	// kubelet holds the kubelet settings applied on top of the configuration generated by the bootstrapper
+	kubelet windows.KubeletSettings
+	// labels are applied to the node when it is registered
+	labels map[string]string
+	// taints are applied to the node when it is registered
+	taints []core.Taint
+	log    logr.Logger
 }
 
 // discoverKubeAPIServerEndpoint discovers the kubernetes api server endpoint
@@ -82,9 +133,14 @@ func discoverKubeAPIServerEndpoint() (string, error) {
 	return host.Status.APIServerInternalURL, nil
 }
 
-// NewNodeConfig creates a new instance of nodeConfig to be used by the caller.
-func NewNodeConfig(clientset *kubernetes.Clientset, ipAddress, instanceID, machineName, clusterServiceCIDR,
-	vxlanPort string, signer ssh.Signer, platform oconfig.PlatformType) (*nodeConfig, error) {
+// NewNodeConfig creates a new instance of nodeConfig to be used by the caller. The platform default user is used for
+// SSH connections when sshUser is empty. The given kubelet settings are applied on top of the kubelet configuration
+// generated by the bootstrapper, and the given host settings are applied before the Kubernetes components are
+// configured.
+func NewNodeConfig(clientset *kubernetes.Clientset, ipAddress, instanceID, machineName string,
+	networkCIDRs cluster.CIDRs, vxlanPort string, signer ssh.Signer, platform oconfig.PlatformType, sshUser string,
+	kubelet windows.KubeletSettings, host windows.HostSettings, labels map[string]string,
+	taints []core.Taint) (*nodeConfig, error) {
 	var err error
 	if nodeConfigCache.workerIgnitionEndPoint == "" {
 		var kubeAPIServerEndpoint string
@@ -100,24 +156,44 @@ func NewNodeConfig(clientset *kubernetes.Clientset, ipAddress, instanceID, machi
 		workerIgnitionEndpoint := "https://" + clusterAddress + ":22623/config/worker"
 		nodeConfigCache.workerIgnitionEndPoint = workerIgnitionEndpoint
 	}
-	if err = cluster.ValidateCIDR(clusterServiceCIDR); err != nil {
-		return nil, errors.Wrap(err, "error receiving valid CIDR value for "+
-			"creating new node config")
+	if len(networkCIDRs.Service) == 0 {
+		return nil, errors.New("error receiving service CIDR value for creating new node config")
+	}
+	for _, cidr := range append(append([]string{}, networkCIDRs.Service...), networkCIDRs.Cluster...) {
+		if err = cluster.ValidateCIDR(cidr); err != nil {
+			return nil, errors.Wrap(err, "error receiving valid CIDR value for "+
+				"creating new node config")
+		}
 	}
 
-	// Update the logger name with the VM's cloud ID. Ideally this should be the Machine name but is not available at
-	// this point.
-	log := ctrl.Log.WithName(fmt.Sprintf("nodeconfig %s", instanceID))
 	win, err := windows.New(ipAddress, instanceID, machineName, nodeConfigCache.workerIgnitionEndPoint, vxlanPort,
-		signer, platform)
+		signer, platform, sshUser, kubelet, host)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "error instantiating Windows instance from VM")
 	}
 
+	// The hash identifies the key trusted by the VM, which is the certificate authority when there is one
+	trustedKey := keysigner.TrustedKey(signer, host.SSHCertificateAuthority)
+	nc := newNodeConfig(clientset, win, networkCIDRs, vxlanPort, trustedKey, kubelet, host, labels, taints)
+	nc.nodeName = windows.NodeNameOverride(platform, machineName)
+	return nc, nil
+}
+
+// newNodeConfig returns the nodeConfig of the given Windows instance, which can be created from a fake remote host
+// with windows.NewWithRemoteHost in unit tests. The given trusted key is the public key trusted by the VM for SSH
+// authentication.
+func newNodeConfig(clientset *kubernetes.Clientset, win windows.Windows, networkCIDRs cluster.CIDRs,
+	vxlanPort string, trustedKey ssh.PublicKey, kubelet windows.KubeletSettings, host windows.HostSettings,
+	labels map[string]string, taints []core.Taint) *nodeConfig {
+	// Update the logger name with the VM's cloud ID. Ideally this should be the Machine name but is not available at
+	// this point.
+	log := ctrl.Log.WithName(fmt.Sprintf("nodeconfig %s", win.ID()))
 	return &nodeConfig{k8sclientset: clientset, Windows: win, network: newNetwork(log),
-		clusterServiceCIDR: clusterServiceCIDR, publicKeyHash: CreatePubKeyHashAnnotation(signer.PublicKey()),
-		log: log}, nil
+		networkCIDRs: networkCIDRs, publicKeyHash: CreatePubKeyHashAnnotation(trustedKey),
+		vxlanPort: vxlanPort, kubeProxyDSR: host.KubeProxyDSR, detectGPUs: host.DetectGPUs,
+		workloadBuilds: host.WorkloadBuilds, externalCloudProvider: host.ExternalCloudProvider,
+		payloadVersion: host.PayloadVersion, hookChecksums: host.Hooks.Checksums(), kubelet: kubelet, labels: labels, taints: taints, log: log}
 }
 
 // getClusterAddr gets the cluster address associated with given kubernetes APIServerEndpoint.
@@ -140,29 +216,86 @@ func getClusterAddr(kubeAPIServerEndpoint string) (string, error) {
 	return hostName, nil
 }
 
-// Configure configures the Windows VM to make it a Windows worker node
-func (nc *nodeConfig) Configure() error {
-	if err := nc.Windows.Configure(); err != nil {
+// Configure configures the Windows VM to make it a Windows worker node, reporting the steps completed to the given
+// progress function, which may be nil. The configuration is cancelled once the given context is done, such as when the
+// Machine of the VM starts being deleted or the operator shuts down.
+func (nc *nodeConfig) Configure(ctx context.Context, progress ProgressFunc) error {
+	defer windows.CancelOnDone(ctx, nc.Windows)()
+	if err := nc.Windows.Configure(ctx, func(step windows.ConfigurationStep) {
+		progress.report(step, nil)
+	}); err != nil {
 		return errors.Wrap(err, "configuring the Windows VM failed")
 	}
 	// populate node object in nodeConfig
-	if err := nc.setNode(); err != nil {
+	if err := nc.setNode(ctx); err != nil {
 		return errors.Wrapf(err, "error getting node object for VM %s", nc.ID())
 	}
+	progress.report(StepNodeJoined, nc.node)
+	// Apply the labels and taints as soon as the node is registered, so that workloads without tolerations are not
+	// scheduled
+	if err := nc.applyNodeMetadata(ctx); err != nil {
+		return errors.Wrapf(err, "error applying labels and taints to node %s", nc.node.GetName())
+	}
+	// kube-rbac-proxy authenticates and authorizes the metrics requests with the credentials of kubelet
+	if err := nc.ConfigureMetricsProxy(); err != nil {
+		return errors.Wrapf(err, "error configuring metrics proxy on node %s", nc.node.GetName())
+	}
 	// Now that basic kubelet configuration is complete, configure networking in the node
-	if err := nc.configureNetwork(); err != nil {
+	if err := nc.configureNetwork(ctx, progress); err != nil {
 		return errors.Wrap(err, "configuring node network failed")
 	}
 
+	if err := nc.verifyComponents(ctx); err != nil {
+		return errors.Wrapf(err, "error verifying the components of node %s", nc.node.GetName())
+	}
+	// All the services now exist, transient crashes are recovered from without waiting for WMCO
+	if err := nc.ConfigureServiceRecovery(); err != nil {
+		return errors.Wrapf(err, "error configuring service recovery on node %s", nc.node.GetName())
+	}
+	// The images are pulled while the node is still tainted, so that the first pods scheduled do not wait for them.
+	// Images which could not be pulled are pulled by kubelet when needed.
+	if err := nc.PullImages(); err != nil {
+		nc.log.Error(err, "unable to pre-pull images", "node", nc.node.GetName())
+	}
+	// The site-specific customization completes the configuration, the node is not configured if it fails
+	if err := nc.RunPostConfigureHook(); err != nil {
+		return errors.Wrapf(err, "error running post-configure hook on node %s", nc.node.GetName())
+	}
+
 	// Now that the node has been fully configured, add the version annotation to signify that the node
 	// was successfully configured by this version of WMCO
 	// populate node object in nodeConfig once more
-	if err := nc.setNode(); err != nil {
+	if err := nc.setNode(ctx); err != nil {
 		return errors.Wrapf(err, "error getting node object for VM %s", nc.ID())
 	}
+	// The components are verified running, workloads can be scheduled on the node
+	removeTaint(nc.node, StartupTaint)
 	nc.addVersionAnnotation()
 	nc.addPubKeyHashAnnotation()
-	node, err := nc.k8sclientset.CoreV1().Nodes().Update(context.TODO(), nc.node, meta.UpdateOptions{})
+	nc.node.Annotations[VXLANPortAnnotation] = nc.vxlanPort
+	nc.node.Annotations[MetricsProxyAnnotation] = "true"
+	nc.addKubeProxyDSRAnnotation()
+	nc.addHookChecksumsAnnotation()
+	if nc.detectGPUs {
+		// GPU workloads are scheduled through the label, its absence leaves the node usable by other workloads
+		if err := nc.labelGPUs(); err != nil {
+			nc.log.Error(err, "unable to label GPUs", "node", nc.node.GetName())
+		}
+	}
+	// The isolation labels are set along with the removal of the startup taint, before workloads are scheduled. Their
+	// absence leaves the node usable by the workloads which do not select them.
+	if err := nc.labelIsolationModes(); err != nil {
+		nc.log.Error(err, "unable to label container isolation modes", "node", nc.node.GetName())
+	}
+	// The inventory is only reported, its absence does not affect the node
+	if err := nc.annotateInventory(); err != nil {
+		nc.log.Error(err, "unable to annotate hardware inventory", "node", nc.node.GetName())
+	}
+	if err := nc.addKubeletConfigHashAnnotation(); err != nil {
+		return err
+	}
+	node, err := nc.k8sclientset.CoreV1().Nodes().Update(ctx, nc.node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager})
 	if err != nil {
 		return errors.Wrap(err, "error updating node labels and annotations")
 	}
@@ -171,12 +304,35 @@ func (nc *nodeConfig) Configure() error {
 	return nil
 }
 
-// configureNetwork configures k8s networking in the node
+// Deconfigure removes the configuration done by WMCO from the Windows VM and deletes the associated node object, so
+// that the VM no longer acts as a worker node
+func (nc *nodeConfig) Deconfigure(ctx context.Context) error {
+	if err := nc.Windows.Deconfigure(ctx); err != nil {
+		return errors.Wrapf(err, "error deconfiguring VM %s", nc.ID())
+	}
+	nodes, err := nc.k8sclientset.CoreV1().Nodes().List(ctx, meta.ListOptions{LabelSelector: WindowsOSLabel})
+	if err != nil {
+		return errors.Wrap(err, "error listing Windows nodes")
+	}
+	for _, node := range nodes.Items {
+		if nc.ID() != getInstanceIDfromProviderID(node.Spec.ProviderID) {
+			continue
+		}
+		err := nc.k8sclientset.CoreV1().Nodes().Delete(ctx, node.GetName(), meta.DeleteOptions{})
+		if err != nil && !k8sapierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error deleting node %s", node.GetName())
+		}
+		nc.log.Info("deleted node", "node", node.GetName())
+	}
+	return nil
+}
+
+// configureNetwork configures k8s networking in the node, reporting the steps completed to the given progress function
 // we are assuming that the WindowsVM and node objects are valid
-func (nc *nodeConfig) configureNetwork() error {
+func (nc *nodeConfig) configureNetwork(ctx context.Context, progress ProgressFunc) error {
 	// Wait until the node object has the hybrid overlay subnet annotation. Otherwise the hybrid-overlay will fail to
 	// start
-	if err := nc.waitForNodeAnnotation(HybridOverlaySubnet); err != nil {
+	if err := nc.waitForNodeAnnotation(ctx, HybridOverlaySubnet); err != nil {
 		return errors.Wrapf(err, "error waiting for %s node annotation for %s", HybridOverlaySubnet,
 			nc.node.GetName())
 	}
@@ -191,15 +347,17 @@ func (nc *nodeConfig) configureNetwork() error {
 
 	// Wait until the node object has the hybrid overlay MAC annotation. This is required for the CNI configuration to
 	// start.
-	if err := nc.waitForNodeAnnotation(HybridOverlayMac); err != nil {
+	if err := nc.waitForNodeAnnotation(ctx, HybridOverlayMac); err != nil {
 		return errors.Wrapf(err, "error waiting for %s node annotation for %s", HybridOverlayMac,
 			nc.node.GetName())
 	}
+	progress.report(StepHybridOverlayReady, nc.node)
 
 	// Configure CNI in the Windows VM
 	if err := nc.configureCNI(); err != nil {
 		return errors.Wrapf(err, "error configuring CNI for %s", nc.node.GetName())
 	}
+	progress.report(StepCNIConfigured, nc.node)
 	// Start the kube-proxy service
 	if err := nc.Windows.ConfigureKubeProxy(nc.node.GetName(), nc.node.Annotations[HybridOverlaySubnet]); err != nil {
 		return errors.Wrapf(err, "error starting kube-proxy for %s", nc.node.GetName())
@@ -207,9 +365,54 @@ func (nc *nodeConfig) configureNetwork() error {
 	return nil
 }
 
-// addVersionAnnotation adds the version annotation to nc.node
+// verifyComponents waits for the Windows services of the node to run, for its HNS networks to be set up by the hybrid
+// overlay and kube-proxy, and for kubelet to report the node as ready with the CNI configuration
+func (nc *nodeConfig) verifyComponents(ctx context.Context) error {
+	if err := nc.Windows.WaitForServices(); err != nil {
+		return err
+	}
+	var problems []string
+	err := retry.PollImmediate(ctx, func() (bool, error) {
+		var err error
+		if problems, err = nc.Windows.CheckHNSNetworks(); err != nil {
+			return false, err
+		}
+		return len(problems) == 0, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "HNS networks not set up: %s", strings.Join(problems, ", "))
+	}
+	nodeName := nc.node.GetName()
+	err = retry.PollImmediate(ctx, func() (bool, error) {
+		node, err := nc.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
+		if err != nil {
+			nc.log.V(1).Error(err, "unable to get node", "node", nodeName)
+			return false, nil
+		}
+		if nc.externalCloudProvider && HasTaint(node.Spec.Taints, CloudProviderUninitializedTaint) {
+			nc.log.V(1).Info("waiting for the cloud controller manager to initialize the node", "node", nodeName)
+			return false, nil
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == core.NodeReady {
+				return condition.Status == core.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	})
+	if nc.externalCloudProvider {
+		return errors.Wrapf(err, "node %s not ready or not initialized by the cloud controller manager", nodeName)
+	}
+	return errors.Wrapf(err, "node %s not ready", nodeName)
+}
+
+// addVersionAnnotation adds the version annotation to nc.node, which is the version of the payload the VM is configured
+// with
 func (nc *nodeConfig) addVersionAnnotation() {
 	nc.node.Annotations[VersionAnnotation] = version.Get()
+	if nc.payloadVersion != "" {
+		nc.node.Annotations[VersionAnnotation] = nc.payloadVersion
+	}
 }
 
 // addPubKeyHashAnnotation adds the public key annotation to nc.node
@@ -217,10 +420,32 @@ func (nc *nodeConfig) addPubKeyHashAnnotation() {
 	nc.node.Annotations[PubKeyHashAnnotation] = nc.publicKeyHash
 }
 
-// setNode identifies the node from the instanceID provided and sets the node object in the nodeconfig.
-func (nc *nodeConfig) setNode() error {
-	err := wait.Poll(retry.Interval, retry.Timeout, func() (bool, error) {
-		nodes, err := nc.k8sclientset.CoreV1().Nodes().List(context.TODO(),
+// applyNodeMetadata applies the desired labels and taints, and the startup taint, to the node
+func (nc *nodeConfig) applyNodeMetadata(ctx context.Context) error {
+	changed := SyncNodeMetadata(nc.node, nc.labels, nc.taints)
+	if !HasTaint(nc.node.Spec.Taints, StartupTaint) {
+		nc.node.Spec.Taints = append(nc.node.Spec.Taints, StartupTaint)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	node, err := nc.k8sclientset.CoreV1().Nodes().Update(ctx, nc.node,
+		meta.UpdateOptions{FieldManager: apply.FieldManager})
+	if err != nil {
+		return err
+	}
+	nc.node = node
+	nc.log.Info("applied labels and taints", "node", node.GetName(), "labels", nc.labels, "taints", nc.taints)
+	return nil
+}
+
+// setNode identifies the node from the instanceID provided and sets the node object in the nodeconfig. If the node
+// name overrides the host name of the VM, the node registered with the host name by the bootstrapper, before kubelet
+// was configured with the node name, is deleted.
+func (nc *nodeConfig) setNode(ctx context.Context) error {
+	err := retry.Poll(ctx, func() (bool, error) {
+		nodes, err := nc.k8sclientset.CoreV1().Nodes().List(ctx,
 			meta.ListOptions{LabelSelector: WindowsOSLabel})
 		if err != nil {
 			nc.log.V(1).Error(err, "node listing failed")
@@ -231,24 +456,43 @@ func (nc *nodeConfig) setNode() error {
 			return false, nil
 		}
 		// get the node with given instance id
-		for _, node := range nodes.Items {
-			if nc.ID() == getInstanceIDfromProviderID(node.Spec.ProviderID) {
-				nc.node = &node
-				return true, nil
+		var found *core.Node
+		var stale []core.Node
+		for i, node := range nodes.Items {
+			if nc.ID() != getInstanceIDfromProviderID(node.Spec.ProviderID) {
+				continue
 			}
+			if nc.nodeName != "" && node.GetName() != nc.nodeName {
+				stale = append(stale, node)
+				continue
+			}
+			found = &nodes.Items[i]
 		}
-		return false, nil
+		if found == nil {
+			return false, nil
+		}
+		for _, node := range stale {
+			nc.log.Info("deleting node registered with the host name", "node", node.GetName(),
+				"expected", nc.nodeName)
+			err := nc.k8sclientset.CoreV1().Nodes().Delete(ctx, node.GetName(), meta.DeleteOptions{})
+			if err != nil && !k8sapierrors.IsNotFound(err) {
+				nc.log.V(1).Error(err, "node deletion failed", "node", node.GetName())
+				return false, nil
+			}
+		}
+		nc.node = found
+		return true, nil
 	})
 	return errors.Wrapf(err, "unable to find node for instanceID %s", nc.ID())
 }
 
 // waitForNodeAnnotation checks if the node object has the given annotation and waits for retry.Interval seconds and
 // returns an error if the annotation does not appear in that time frame.
-func (nc *nodeConfig) waitForNodeAnnotation(annotation string) error {
+func (nc *nodeConfig) waitForNodeAnnotation(ctx context.Context, annotation string) error {
 	nodeName := nc.node.GetName()
 	var found bool
-	err := wait.Poll(retry.Interval, retry.Timeout, func() (bool, error) {
-		node, err := nc.k8sclientset.CoreV1().Nodes().Get(context.TODO(), nodeName, meta.GetOptions{})
+	err := retry.Poll(ctx, func() (bool, error) {
+		node, err := nc.k8sclientset.CoreV1().Nodes().Get(ctx, nodeName, meta.GetOptions{})
 		if err != nil {
 			nc.log.V(1).Error(err, "unable to get associated node object")
 			return false, nil
@@ -275,8 +519,9 @@ func (nc *nodeConfig) configureCNI() error {
 	if err := nc.network.setHostSubnet(nc.node.Annotations[HybridOverlaySubnet]); err != nil {
 		return errors.Wrapf(err, "error populating host subnet in node network")
 	}
-	// populate the CNI config file with the host subnet and the service network CIDR
-	configFile, err := nc.network.populateCniConfig(nc.clusterServiceCIDR, payload.CNIConfigTemplatePath)
+	// populate the CNI config file with the host subnet and the service and pod network CIDRs
+	configFile, err := nc.network.populateCniConfig(nc.networkCIDRs,
+		payload.Path(payload.CNIConfigTemplatePath, nc.payloadVersion))
 	if err != nil {
 		return errors.Wrapf(err, "error populating CNI config file %s", configFile)
 	}
@@ -4,6 +4,9 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -12,6 +15,9 @@ import (
 const (
 	// payloadDirectory is the directory in the operator image where are all the binaries live
 	payloadDirectory = "/payload/"
+	// versionsDirectory is the directory in the operator image holding the payloads of previous WMCO versions, one
+	// directory per version with the same layout as the payload directory
+	versionsDirectory = payloadDirectory + "versions/"
 	// WmcbPath contains the path of the Windows Machine Config Bootstrapper binary. The container image should already
 	// have this binary mounted
 	WmcbPath = payloadDirectory + "wmcb.exe"
@@ -52,6 +58,20 @@ const (
 	// WindowsExporterPath contains the path of the windows_exporter binary. The container image should already have
 	// this binary mounted
 	WindowsExporterPath = payloadDirectory + WindowsExporterName
+	// KubeRBACProxyPath contains the path of the kube-rbac-proxy binary, serving the metrics of windows_exporter to
+	// authorized clients. The container image should already have this binary mounted
+	KubeRBACProxyPath = payloadDirectory + "kube-rbac-proxy.exe"
+	// credentialProvidersDirectory is the directory for storing the kubelet image credential providers
+	credentialProvidersDirectory = "/credential-providers/"
+	// ECRCredentialProviderPath is the path of the kubelet image credential provider of the Amazon ECR registries. The
+	// container image should already have this binary mounted
+	ECRCredentialProviderPath = payloadDirectory + credentialProvidersDirectory + "ecr-credential-provider.exe"
+	// ACRCredentialProviderPath is the path of the kubelet image credential provider of the Azure Container Registry
+	// registries. The container image should already have this binary mounted
+	ACRCredentialProviderPath = payloadDirectory + credentialProvidersDirectory + "acr-credential-provider.exe"
+	// GCPCredentialProviderPath is the path of the kubelet image credential provider of the Google Container Registry
+	// and Artifact Registry registries. The container image should already have this binary mounted
+	GCPCredentialProviderPath = payloadDirectory + credentialProvidersDirectory + "auth-provider-gcp.exe"
 )
 
 // FileInfo contains information about a file
@@ -71,3 +91,21 @@ func NewFileInfo(path string) (*FileInfo, error) {
 		SHA256: fmt.Sprintf("%x", sha256.Sum256(contents)),
 	}, nil
 }
+
+// Path returns the path of the given payload file within the payload of the given previous WMCO version, or the given
+// path if the version is empty
+func Path(path, version string) string {
+	if version == "" {
+		return path
+	}
+	return filepath.Join(versionsDirectory, version, strings.TrimPrefix(path, payloadDirectory))
+}
+
+// HasVersion returns true if the operator image holds the payload of the given previous WMCO version
+func HasVersion(version string) bool {
+	if version == "" || strings.ContainsAny(version, "/\\") || strings.Contains(version, "..") {
+		return false
+	}
+	info, err := os.Stat(versionsDirectory + version)
+	return err == nil && info.IsDir()
+}
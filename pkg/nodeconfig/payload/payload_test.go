@@ -0,0 +1,19 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, KubeletPath, Path(KubeletPath, ""))
+	assert.Equal(t, "/payload/versions/2.0.0/kube-node/kubelet.exe", Path(KubeletPath, "2.0.0"))
+	assert.Equal(t, "/payload/versions/2.0.0/wmcb.exe", Path(WmcbPath, "2.0.0"))
+}
+
+func TestHasVersion(t *testing.T) {
+	for _, version := range []string{"", "../payload", "2.0.0/..", "2.0.0\\kube-node"} {
+		assert.False(t, HasVersion(version), version)
+	}
+}
@@ -0,0 +1,58 @@
+package nodeconfig
+
+import (
+	oconfig "github.com/openshift/api/config/v1"
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// TopologyZoneLabel is the well-known label holding the failure zone of a node, used by topology-aware scheduling
+	// and by CSI drivers to provision volumes reachable from the node
+	TopologyZoneLabel = "topology.kubernetes.io/zone"
+	// TopologyRegionLabel is the well-known label holding the region of a node
+	TopologyRegionLabel = "topology.kubernetes.io/region"
+	// MachineZoneLabel is applied to Machines by the Machine API, holding the zone of the instance
+	MachineZoneLabel = "machine.openshift.io/zone"
+	// MachineRegionLabel is applied to Machines by the Machine API, holding the region of the instance
+	MachineRegionLabel = "machine.openshift.io/region"
+)
+
+// TopologyLabels returns the topology labels of the node of a Machine with the given labels, on the given platform,
+// formatted like the cloud provider integration of the platform sets them. Labels whose value is unknown are omitted.
+func TopologyLabels(platform oconfig.PlatformType, machineLabels map[string]string) map[string]string {
+	topology := make(map[string]string)
+	region := machineLabels[MachineRegionLabel]
+	if region != "" {
+		topology[TopologyRegionLabel] = region
+	}
+	zone := machineLabels[MachineZoneLabel]
+	if platform == oconfig.AzurePlatformType && zone != "" {
+		// The Azure cloud provider prefixes the availability zone number with the region. The zone of instances
+		// outside availability zones is their fault domain, which the Machine does not hold.
+		if region == "" {
+			return topology
+		}
+		zone = region + "-" + zone
+	}
+	if zone != "" {
+		topology[TopologyZoneLabel] = zone
+	}
+	return topology
+}
+
+// AddTopologyLabels sets the given topology labels missing from the given node, leaving the ones set by the cloud
+// provider integration untouched. Returns true if the node was changed.
+func AddTopologyLabels(node *core.Node, topology map[string]string) bool {
+	changed := false
+	for key, value := range topology {
+		if _, present := node.Labels[key]; present {
+			continue
+		}
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		node.Labels[key] = value
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,56 @@
+package nodeconfig
+
+import (
+	"testing"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTopologyLabels(t *testing.T) {
+	tests := []struct {
+		name          string
+		platform      oconfig.PlatformType
+		machineLabels map[string]string
+		want          map[string]string
+	}{
+		{
+			name:          "AWS",
+			platform:      oconfig.AWSPlatformType,
+			machineLabels: map[string]string{MachineRegionLabel: "us-east-1", MachineZoneLabel: "us-east-1a"},
+			want:          map[string]string{TopologyRegionLabel: "us-east-1", TopologyZoneLabel: "us-east-1a"},
+		},
+		{
+			name:          "Azure availability zone",
+			platform:      oconfig.AzurePlatformType,
+			machineLabels: map[string]string{MachineRegionLabel: "eastus", MachineZoneLabel: "2"},
+			want:          map[string]string{TopologyRegionLabel: "eastus", TopologyZoneLabel: "eastus-2"},
+		},
+		{
+			name:          "Azure without availability zone",
+			platform:      oconfig.AzurePlatformType,
+			machineLabels: map[string]string{MachineRegionLabel: "eastus", MachineZoneLabel: ""},
+			want:          map[string]string{TopologyRegionLabel: "eastus"},
+		},
+		{
+			name:     "no topology",
+			platform: oconfig.VSpherePlatformType,
+			want:     map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, TopologyLabels(test.platform, test.machineLabels))
+		})
+	}
+}
+
+func TestAddTopologyLabels(t *testing.T) {
+	node := &core.Node{ObjectMeta: meta.ObjectMeta{Labels: map[string]string{TopologyZoneLabel: "cloud-zone"}}}
+	topology := map[string]string{TopologyRegionLabel: "region", TopologyZoneLabel: "machine-zone"}
+	assert.True(t, AddTopologyLabels(node, topology))
+	assert.Equal(t, map[string]string{TopologyRegionLabel: "region", TopologyZoneLabel: "cloud-zone"}, node.Labels)
+	assert.False(t, AddTopologyLabels(node, topology))
+}
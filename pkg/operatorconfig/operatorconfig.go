@@ -0,0 +1,1540 @@
+package operatorconfig
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	core "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// ConfigMapName is the name of the ConfigMap, in the operator namespace, which holds the operator configuration
+const ConfigMapName = "windows-machine-config-operator-config"
+
+// SSHProxyCredentialsSecretName is the name of the optional Secret, in the operator namespace, holding in its username
+// and password keys the credentials of the SSH proxy
+const SSHProxyCredentialsSecretName = "windows-ssh-proxy-credentials"
+
+// Keys of the operator configuration ConfigMap
+const (
+	// MaxUnhealthyCountKey is the maximum number of Windows Machines per MachineSet which may be unhealthy at the
+	// same time as a result of WMCO deleting Machines
+	MaxUnhealthyCountKey = "maxUnhealthyCount"
+	// RemediationStrategyKey is the action taken on Windows Machines which cannot be configured
+	RemediationStrategyKey = "remediationStrategy"
+	// SSHUserKey is the user used to SSH into Windows VMs, overriding the platform default
+	SSHUserKey = "sshUser"
+	// LogLevelKey is the operator log level
+	LogLevelKey = "logLevel"
+	// ControllerLogLevelsKey is a comma separated list of log levels, in the controller=level format, overriding the
+	// operator log level for the logs of the given controllers
+	ControllerLogLevelsKey = "controllerLogLevels"
+	// ControllerConcurrencyKey is a comma separated list of counts, in the controller=count format, of the
+	// reconciliations each controller runs concurrently. It is read when the operator starts.
+	ControllerConcurrencyKey = "controllerConcurrency"
+	// RateLimiterBaseDelayKey is the delay before a failed reconciliation is retried, doubled at each consecutive
+	// failure of the same object. It is read when the operator starts.
+	RateLimiterBaseDelayKey = "rateLimiterBaseDelay"
+	// RateLimiterMaxDelayKey is the maximum delay before a failed reconciliation is retried. It is read when the
+	// operator starts.
+	RateLimiterMaxDelayKey = "rateLimiterMaxDelay"
+	// RateLimiterQPSKey is the rate, in objects per second, at which each controller requeues objects across all
+	// objects. It is read when the operator starts.
+	RateLimiterQPSKey = "rateLimiterQPS"
+	// RateLimiterBurstKey is the number of objects each controller can requeue at once above RateLimiterQPSKey. It is
+	// read when the operator starts.
+	RateLimiterBurstKey = "rateLimiterBurst"
+	// MachineSelectorKey is a label selector restricting the Windows Machines managed by WMCO
+	MachineSelectorKey = "machineSelector"
+	// CanaryUpgradeKey enables upgrading and verifying a single Windows node before the rest of the fleet
+	CanaryUpgradeKey = "canaryUpgrade"
+	// NodeTaintsKey is a comma separated list of taints, in the key=value:effect format, applied to all Windows nodes
+	NodeTaintsKey = "nodeTaints"
+	// KubeletArgsKey is a whitespace separated list of arguments, in the --name=value format, added to the kubelet
+	// command line of Windows nodes
+	KubeletArgsKey = "kubeletArgs"
+	// KubeletConfigKey holds KubeletConfiguration fields, in YAML or JSON format, merged into the kubelet configuration
+	// file of Windows nodes
+	KubeletConfigKey = "kubeletConfig"
+	// KubeletFeatureGatesKey is a comma separated list of kubelet feature gates, in the Name=true|false format, enabled
+	// or disabled on Windows nodes
+	KubeletFeatureGatesKey = "kubeletFeatureGates"
+	// SystemReservedKey is a comma separated list of resources, in the name=quantity format, reserved for the operating
+	// system on Windows nodes
+	SystemReservedKey = "systemReserved"
+	// KubeReservedKey is a comma separated list of resources, in the name=quantity format, reserved for the Kubernetes
+	// components on Windows nodes
+	KubeReservedKey = "kubeReserved"
+	// EvictionHardKey is a comma separated list of eviction thresholds, in the signal=quantity or signal=percentage%
+	// format, which trigger the immediate eviction of pods on Windows nodes
+	EvictionHardKey = "evictionHard"
+	// EvictionSoftKey is a comma separated list of eviction thresholds, in the same format as EvictionHardKey, which
+	// trigger the eviction of pods once exceeded for the grace period of the signal
+	EvictionSoftKey = "evictionSoft"
+	// EvictionSoftGracePeriodKey is a comma separated list of grace periods, in the signal=duration format, of the soft
+	// eviction thresholds
+	EvictionSoftGracePeriodKey = "evictionSoftGracePeriod"
+	// ContainerLogMaxSizeKey is the maximum size, as a quantity, of a container log file before it is rotated
+	ContainerLogMaxSizeKey = "containerLogMaxSize"
+	// ContainerLogMaxFilesKey is the maximum number of log files kept for a container
+	ContainerLogMaxFilesKey = "containerLogMaxFiles"
+	// KubeletRootDirKey is the directory of Windows nodes in which kubelet stores its files, such as pod volumes
+	KubeletRootDirKey = "kubeletRootDir"
+	// DataDisksKey is a comma separated list of disks, in the number=path format, initialized and mounted on Windows
+	// VMs before they are configured. The path is either a drive letter, such as D:, or an absolute directory path.
+	DataDisksKey = "dataDisks"
+	// PagefileSizeKey defines the pagefile of Windows VMs: Disabled, SystemManaged, or a fixed size as a quantity
+	PagefileSizeKey = "pagefileSize"
+	// PagefilePathKey is the location of the pagefile when its size is fixed
+	PagefilePathKey = "pagefilePath"
+	// OverlayMTUKey is the MTU of the pod network of Windows nodes, overriding the MTU of the cluster pod network
+	OverlayMTUKey = "overlayMTU"
+	// DNSServersKey is a comma separated list of the IP addresses of the DNS servers of Windows nodes, replacing the
+	// servers provided by DHCP
+	DNSServersKey = "dnsServers"
+	// DNSSuffixSearchListKey is a comma separated list of the DNS suffixes appended to unqualified names resolved on
+	// Windows nodes
+	DNSSuffixSearchListKey = "dnsSuffixSearchList"
+	// NTPServersKey is a comma separated list of the host names or IP addresses of the NTP servers the clocks of
+	// Windows nodes are synchronized with
+	NTPServersKey = "ntpServers"
+	// ClockSkewThresholdKey is the offset of the clock of a Windows node from the operator clock above which the skew
+	// is reported
+	ClockSkewThresholdKey = "clockSkewThreshold"
+	// ProblemDetectionKey enables the detection of the Windows specific problems of Windows nodes, reported as node
+	// conditions
+	ProblemDetectionKey = "problemDetection"
+	// HNSNetworkRepairKey enables the repair of the broken HNS networks of Windows nodes, which are drained for it
+	HNSNetworkRepairKey = "hnsNetworkRepair"
+	// ShutdownGracePeriodKey is the time the containers of the pods running on a Windows node are given to terminate
+	// when the node shuts down
+	ShutdownGracePeriodKey = "shutdownGracePeriod"
+	// KubeProxyDSRKey enables Direct Server Return in the kube-proxy of Windows nodes
+	KubeProxyDSRKey = "kubeProxyDSR"
+	// NodeIPCIDRsKey is a comma separated list of CIDRs, in order of preference, within which the internal address of
+	// a Windows Machine is chosen as the node IP, registered by kubelet and used to connect to the VM
+	NodeIPCIDRsKey = "nodeIPCIDRs"
+	// ManageFirewallRulesKey enables the creation and drift correction of the Windows firewall rules required by the
+	// Kubernetes components
+	ManageFirewallRulesKey = "manageFirewallRules"
+	// HardenNodesKey enables the hardening profile of Windows nodes, which disables RDP, the inbound firewall rules
+	// which are not required and unused services. It requires the firewall rules to be managed.
+	HardenNodesKey = "hardenNodes"
+	// CrashDumpsKey enables the configuration of kernel and user-mode crash dumps on Windows nodes, and the reporting
+	// of the dumps written after a crash
+	CrashDumpsKey = "crashDumps"
+	// EventLogsKey enables the periodic export of the critical, error and warning events of the event log channels
+	// relevant to node failures, such as System and Application, to files served by the kubelet logs endpoint
+	EventLogsKey = "eventLogs"
+	// KubeletAuthHardeningKey enforces the webhook authentication and authorization of the requests to the kubelet of
+	// Windows nodes, and disables anonymous access, as on Linux nodes
+	KubeletAuthHardeningKey = "kubeletAuthHardening"
+	// WindowsUpdatesKey enables the installation of the security updates of Windows nodes during maintenance windows,
+	// draining and rebooting the nodes as needed
+	WindowsUpdatesKey = "windowsUpdates"
+	// SmokeTestKey enables the smoke test of newly configured Windows nodes, which runs a test pod on the node and
+	// checks that it is reachable across the pod network
+	SmokeTestKey = "smokeTest"
+	// SmokeTestImageKey is the image of the smoke test pod, which must serve the agnhost netexec endpoints
+	SmokeTestImageKey = "smokeTestImage"
+	// PauseImageKey is the pause image of the pod sandboxes of Windows nodes, overriding the image set by the bootstrapper
+	PauseImageKey = "pauseImage"
+	// NodeLabelsKey is a comma separated list of key=value labels Windows nodes are registered with by kubelet, so
+	// that the labels are present from the instant the nodes register
+	NodeLabelsKey = "nodeLabels"
+	// ExternalCloudProviderKey makes the kubelet of Windows nodes leave their initialization to the external cloud
+	// controller manager of the cluster
+	ExternalCloudProviderKey = "externalCloudProvider"
+	// PrePullImagesKey is a comma separated list of the images pulled on new Windows nodes, along with the pause image,
+	// before workloads can be scheduled on them
+	PrePullImagesKey = "prePullImages"
+	// TransferBandwidthKey is the maximum throughput, as a quantity of bytes per second, of the payload files
+	// transferred to each Windows VM
+	TransferBandwidthKey = "transferBandwidth"
+	// ConnectTimeoutKey is the time allowed to establish the SSH connection to a Windows VM, retrying while the VM
+	// boots
+	ConnectTimeoutKey = "connectTimeout"
+	// CommandTimeoutKey is the time allowed for a command run on a Windows VM to complete
+	CommandTimeoutKey = "commandTimeout"
+	// TransferTimeoutKey is the time allowed for a file to be transferred to a Windows VM
+	TransferTimeoutKey = "transferTimeout"
+	// DetectGPUsKey enables the detection of the DirectX capable GPUs of new Windows nodes, which are labeled with their
+	// number
+	DetectGPUsKey = "detectGPUs"
+	// WorkloadWindowsBuildsKey is a comma separated list of the Windows OS builds the container images of the Windows
+	// workloads are built for, checked against the OS build of new Windows nodes to determine whether the containers
+	// can run process-isolated on them
+	WorkloadWindowsBuildsKey = "workloadWindowsBuilds"
+	// PasswordRotationIntervalKey is the interval at which the password of the user WMCO connects as is rotated on
+	// Windows nodes. The passwords are stored in the CredentialsSecret.
+	PasswordRotationIntervalKey = "passwordRotationInterval"
+	// PrivateKeyMaxAgeKey is the age of the private key secret above which its rotation is reported as due
+	PrivateKeyMaxAgeKey = "privateKeyMaxAge"
+	// SSHCiphersKey is a comma separated list of the ciphers allowed for the SSH connections to Windows VMs
+	SSHCiphersKey = "sshCiphers"
+	// SSHMACsKey is a comma separated list of the MAC algorithms allowed for the SSH connections to Windows VMs
+	SSHMACsKey = "sshMACs"
+	// SSHKeyExchangesKey is a comma separated list of the key exchange algorithms allowed for the SSH connections to
+	// Windows VMs
+	SSHKeyExchangesKey = "sshKeyExchanges"
+	// DryRunKey enables the dry-run mode, in which the actions WMCO would take on Windows instances, Machines and nodes
+	// are reported through events instead of being taken
+	DryRunKey = "dryRun"
+	// PinnedVersionKey is a WMCO version whose Windows nodes are not upgraded
+	PinnedVersionKey = "pinnedVersion"
+	// VersionSkewKey is the number of major versions, 0 or 1, by which the WMCO version a Windows node was configured
+	// with may lag the operator version before the node is recreated
+	VersionSkewKey = "versionSkew"
+	// ConfigurationHooksKey is the name of a ConfigMap, in the operator namespace, holding the PowerShell scripts run on
+	// Windows VMs before and after their configuration
+	ConfigurationHooksKey = "configurationHooks"
+	// SSHProxyKey is the URL of the SOCKS5 or HTTP proxy the operator dials the SSH connections to Windows VMs through
+	SSHProxyKey = "sshProxy"
+	// KubeletCredentialProvidersKey configures the kubelet of Windows nodes to retrieve the credentials of the
+	// registries of the cloud platform from the image credential provider of the platform, authenticating with the
+	// identity of the instance instead of pull secrets
+	KubeletCredentialProvidersKey = "kubeletCredentialProviders"
+	// SSHAddressTypeKey is the type of the Machine address, InternalIP or ExternalIP, the operator connects to
+	// Windows VMs over SSH, for topologies in which the node IP is not routable from the operator
+	SSHAddressTypeKey = "sshAddressType"
+	// SSHAddressCIDRsKey is a comma separated list of CIDRs, in order of preference, within which the address of a
+	// Windows Machine the operator connects to over SSH is chosen, such as the address of a secondary NIC
+	SSHAddressCIDRsKey = "sshAddressCIDRs"
+)
+
+// Annotations which can be applied to Windows MachineSets to override settings for the Machines they own
+const (
+	KubeletArgsAnnotation         = "windowsmachineconfig.openshift.io/kubelet-args"
+	KubeletConfigAnnotation       = "windowsmachineconfig.openshift.io/kubelet-config"
+	DataDisksAnnotation           = "windowsmachineconfig.openshift.io/data-disks"
+	RemediationStrategyAnnotation = "windowsmachineconfig.openshift.io/remediation-strategy"
+	PinnedVersionAnnotation       = "windowsmachineconfig.openshift.io/pinned-version"
+	SSHUserAnnotation             = "windowsmachineconfig.openshift.io/ssh-user"
+	NodeIPCIDRsAnnotation         = "windowsmachineconfig.openshift.io/node-ip-cidrs"
+	PrePullImagesAnnotation       = "windowsmachineconfig.openshift.io/pre-pull-images"
+	PauseImageAnnotation          = "windowsmachineconfig.openshift.io/pause-image"
+	DetectGPUsAnnotation          = "windowsmachineconfig.openshift.io/detect-gpus"
+	TransferBandwidthAnnotation   = "windowsmachineconfig.openshift.io/transfer-bandwidth"
+	NodeLabelsAnnotation          = "windowsmachineconfig.openshift.io/node-labels"
+	SSHAddressTypeAnnotation      = "windowsmachineconfig.openshift.io/ssh-address-type"
+	SSHAddressCIDRsAnnotation     = "windowsmachineconfig.openshift.io/ssh-address-cidrs"
+)
+
+const (
+	// minOverlayMTU is the minimum MTU of IPv4 links
+	minOverlayMTU = 576
+	// maxOverlayMTU is the largest MTU supported by jumbo frames, less the VXLAN overhead
+	maxOverlayMTU = 9166
+)
+
+// windowsPathRegex matches absolute Windows paths which do not contain whitespace
+var windowsPathRegex = regexp.MustCompile(`^[a-zA-Z]:\\\S*$`)
+
+// driveLetterRegex matches drive letters, with or without a trailing backslash
+var driveLetterRegex = regexp.MustCompile(`^[a-zA-Z]:\\?$`)
+
+// imageReferenceRegexp matches image references, made of a repository optionally followed by a tag and a digest, which
+// are safe to pass on a command line
+var imageReferenceRegexp = regexp.MustCompile(`^[a-z0-9][a-zA-Z0-9._/:-]*(@sha256:[a-f0-9]{64})?$`)
+
+// overrideAnnotations maps the MachineSet override annotations to the settings they override
+var overrideAnnotations = map[string]string{
+	KubeletArgsAnnotation:         KubeletArgsKey,
+	KubeletConfigAnnotation:       KubeletConfigKey,
+	DataDisksAnnotation:           DataDisksKey,
+	RemediationStrategyAnnotation: RemediationStrategyKey,
+	PinnedVersionAnnotation:       PinnedVersionKey,
+	SSHUserAnnotation:             SSHUserKey,
+	NodeIPCIDRsAnnotation:         NodeIPCIDRsKey,
+	PrePullImagesAnnotation:       PrePullImagesKey,
+	PauseImageAnnotation:          PauseImageKey,
+	DetectGPUsAnnotation:          DetectGPUsKey,
+	TransferBandwidthAnnotation:   TransferBandwidthKey,
+	NodeLabelsAnnotation:          NodeLabelsKey,
+	SSHAddressTypeAnnotation:      SSHAddressTypeKey,
+	SSHAddressCIDRsAnnotation:     SSHAddressCIDRsKey,
+}
+
+// externalCloudProviderArg is the kubelet argument leaving the initialization of the node to the external cloud
+// controller manager
+const externalCloudProviderArg = "--cloud-provider=external"
+
+// kubeletLabels are the labels within the kubernetes.io and k8s.io domains kubelet can register a node with, along
+// with the labels within the kubeletLabelDomains. Kubelet refuses to start when given other labels within these
+// domains.
+var kubeletLabels = map[string]bool{
+	"kubernetes.io/hostname":                   true,
+	"kubernetes.io/os":                         true,
+	"kubernetes.io/arch":                       true,
+	"beta.kubernetes.io/os":                    true,
+	"beta.kubernetes.io/arch":                  true,
+	"beta.kubernetes.io/instance-type":         true,
+	"node.kubernetes.io/instance-type":         true,
+	"failure-domain.beta.kubernetes.io/region": true,
+	"failure-domain.beta.kubernetes.io/zone":   true,
+	"topology.kubernetes.io/region":            true,
+	"topology.kubernetes.io/zone":              true,
+}
+
+// kubeletLabelDomains are the domains, within the kubernetes.io domain, of the labels kubelet can register a node with
+var kubeletLabelDomains = []string{"kubelet.kubernetes.io", "node.kubernetes.io"}
+
+// RemediationStrategy is the action taken on Windows Machines which cannot be configured
+type RemediationStrategy string
+
+const (
+	// RemediationRecreate deletes the Machine, so that it is recreated by its MachineSet
+	RemediationRecreate RemediationStrategy = "Recreate"
+	// RemediationNone leaves the Machine in place, for it to be investigated by an administrator
+	RemediationNone RemediationStrategy = "None"
+)
+
+// LogLevel is the verbosity of the operator logs
+type LogLevel string
+
+const (
+	// LogLevelNormal logs informational messages and errors
+	LogLevelNormal LogLevel = "Normal"
+	// LogLevelDebug additionally logs debug messages
+	LogLevelDebug LogLevel = "Debug"
+)
+
+// Config holds the operator wide settings
+type Config struct {
+	MaxUnhealthyCount   int32
+	RemediationStrategy RemediationStrategy
+	// SSHUser is empty when the platform default is used
+	SSHUser  string
+	LogLevel LogLevel
+	// MachineSelector is nil when all Windows Machines are managed
+	MachineSelector labels.Selector
+	// ControllerLogLevels maps controller names to the log level overriding LogLevel for their logs
+	ControllerLogLevels map[string]LogLevel
+	// ControllerConcurrency maps controller names to the number of reconciliations they run concurrently
+	ControllerConcurrency map[string]int
+	CanaryUpgrade         bool
+	NodeTaints            []core.Taint
+	KubeletArgs           []string
+	KubeletConfig         map[string]interface{}
+	// KubeletFeatureGates maps the name of kubelet feature gates to whether they are enabled
+	KubeletFeatureGates map[string]bool
+	// SystemReserved and KubeReserved map resource names to the quantity reserved
+	SystemReserved map[string]string
+	KubeReserved   map[string]string
+	// EvictionHard, EvictionSoft and EvictionSoftGracePeriod map eviction signals to their threshold or grace period
+	EvictionHard            map[string]string
+	EvictionSoft            map[string]string
+	EvictionSoftGracePeriod map[string]string
+	// ContainerLogMaxSize is empty and ContainerLogMaxFiles is zero when the kubelet defaults are used
+	ContainerLogMaxSize  string
+	ContainerLogMaxFiles int32
+	// KubeletRootDir is empty when the kubelet default is used
+	KubeletRootDir string
+	// DataDisks are sorted by disk number
+	DataDisks []windows.DataDisk
+	Pagefile  windows.Pagefile
+	// OverlayMTU is zero when the MTU of the cluster pod network is used
+	OverlayMTU int
+	// DNSServers and DNSSuffixSearchList are empty when the DNS settings of Windows nodes are not managed
+	DNSServers          []string
+	DNSSuffixSearchList []string
+	// NTPServers is empty when the time synchronization of Windows nodes is not managed
+	NTPServers []string
+	// ClockSkewThreshold is zero when the clocks of Windows nodes are not checked
+	ClockSkewThreshold time.Duration
+	// ProblemDetection enables the reporting of the Windows specific problems of Windows nodes as node conditions
+	ProblemDetection bool
+	// HNSNetworkRepair enables the repair of the broken HNS networks of Windows nodes, otherwise only reported
+	HNSNetworkRepair bool
+	// ShutdownGracePeriod is zero when no shutdown hook is registered on Windows nodes
+	ShutdownGracePeriod time.Duration
+	// KubeProxyDSR enables Direct Server Return in the kube-proxy of Windows nodes
+	KubeProxyDSR bool
+	// NodeIPCIDRs is empty when the node IP is not chosen by CIDR
+	NodeIPCIDRs []string
+	// ManageFirewallRules enables the creation and drift correction of the firewall rules of Windows nodes
+	ManageFirewallRules bool
+	// HardenNodes enables the hardening profile of Windows nodes
+	HardenNodes bool
+	// CrashDumps enables the configuration and reporting of crash dumps on Windows nodes
+	CrashDumps bool
+	// EventLogs enables the export of the event logs of Windows nodes
+	EventLogs bool
+	// KubeletAuthHardening enforces the webhook authentication and authorization of the kubelet of Windows nodes
+	KubeletAuthHardening bool
+	// WindowsUpdates enables the installation of the security updates of Windows nodes
+	WindowsUpdates bool
+	// SmokeTest enables the smoke test of newly configured Windows nodes, run with the SmokeTestImage
+	SmokeTest      bool
+	SmokeTestImage string
+	// PauseImage is empty when the pause image set by the bootstrapper is used
+	PauseImage string
+	// PrePullImages is empty when only the pause image is pulled on new Windows nodes
+	PrePullImages []string
+	// TransferBandwidth is zero when the file transfers to Windows VMs are not throttled
+	TransferBandwidth int64
+	// Timeouts limit the operations on Windows VMs, the command and transfer timeouts are zero when unlimited
+	Timeouts windows.Timeouts
+	// DetectGPUs enables the labeling of Windows nodes with the number of their DirectX capable GPUs
+	DetectGPUs bool
+	// NodeLabels are the labels Windows nodes are registered with, nil when none are added
+	NodeLabels map[string]string
+	// ExternalCloudProvider is true when the Windows nodes are initialized by an external cloud controller manager
+	ExternalCloudProvider bool
+	// WorkloadBuilds are the OS build numbers of the images of the Windows workloads, empty when not known
+	WorkloadBuilds []int
+	// PasswordRotationInterval is zero when passwords are not rotated
+	PasswordRotationInterval time.Duration
+	// PrivateKeyMaxAge is zero when the age of the private key is not checked
+	PrivateKeyMaxAge time.Duration
+	// SSHAlgorithms lists are empty when the SSH client defaults are used
+	SSHAlgorithms windows.SSHAlgorithms
+	// DryRun reports the actions on Windows instances, Machines and nodes instead of taking them
+	DryRun bool
+	// PinnedVersion is empty when all Windows nodes are upgraded
+	PinnedVersion string
+	// VersionSkew is zero when every Windows node configured by another WMCO version is upgraded
+	VersionSkew int
+	// ConfigurationHooks is empty when no hook scripts are run on Windows VMs
+	ConfigurationHooks string
+	// SSHProxy is empty when the SSH connections to Windows VMs are dialed directly
+	SSHProxy string
+	// SSHProxyUser is nil when the SSH proxy does not require authentication
+	SSHProxyUser *url.Userinfo
+	// KubeletCredentialProviders enables the image credential provider of the platform on Windows nodes
+	KubeletCredentialProviders bool
+	// SSHAddressType is empty when Windows VMs are connected to over SSH through their node IP, unless SSHAddressCIDRs
+	// is set, in which case the internal addresses are considered
+	SSHAddressType string
+	// SSHAddressCIDRs is empty when the SSH address of Windows VMs is not chosen by CIDR
+	SSHAddressCIDRs []string
+	// RateLimiter limits the rate at which the controllers requeue objects
+	RateLimiter RateLimiter
+}
+
+// RateLimiter holds the settings of the rate limiter of the workqueues of the controllers, which delays each object by
+// the longest of its per-object exponential backoff and of the overall token bucket
+type RateLimiter struct {
+	// BaseDelay is the backoff of an object after its first failed reconciliation, doubled at each consecutive failure
+	// up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// QPS is the rate at which the token bucket is refilled, and Burst its size
+	QPS   float64
+	Burst int
+}
+
+// Default returns the configuration used for the settings missing from the ConfigMap
+func Default() Config {
+	return Config{
+		MaxUnhealthyCount:   1,
+		RemediationStrategy: RemediationRecreate,
+		LogLevel:            LogLevelNormal,
+		Pagefile:            windows.Pagefile{Path: `C:\pagefile.sys`},
+		ManageFirewallRules: true,
+		ClockSkewThreshold:  30 * time.Second,
+		Timeouts:            windows.Timeouts{Connect: 10 * time.Minute},
+		ProblemDetection:    true,
+		SmokeTestImage:      "k8s.gcr.io/e2e-test-images/agnhost:2.32",
+		// The rate limiter defaults to the controller-runtime one
+		RateLimiter: RateLimiter{BaseDelay: 5 * time.Millisecond, MaxDelay: 1000 * time.Second, QPS: 10, Burst: 100},
+	}
+}
+
+// Parse returns the configuration defined by the given ConfigMap data. Settings which are not present in the data
+// are taken from the given defaults.
+func Parse(data map[string]string, defaults Config) (*Config, error) {
+	config := defaults
+	if value, present := data[MaxUnhealthyCountKey]; present {
+		count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil || count < 1 {
+			return nil, errors.Errorf("invalid %s %q: expected a positive integer", MaxUnhealthyCountKey, value)
+		}
+		config.MaxUnhealthyCount = int32(count)
+	}
+	if value, present := data[RemediationStrategyKey]; present {
+		config.RemediationStrategy = RemediationStrategy(strings.TrimSpace(value))
+		switch config.RemediationStrategy {
+		case RemediationRecreate, RemediationNone:
+		default:
+			return nil, errors.Errorf("invalid %s %q: expected %s or %s", RemediationStrategyKey, value,
+				RemediationRecreate, RemediationNone)
+		}
+	}
+	if value, present := data[SSHUserKey]; present {
+		config.SSHUser = strings.TrimSpace(value)
+	}
+	if value, present := data[LogLevelKey]; present {
+		config.LogLevel = LogLevel(strings.TrimSpace(value))
+		switch config.LogLevel {
+		case LogLevelNormal, LogLevelDebug:
+		default:
+			return nil, errors.Errorf("invalid %s %q: expected %s or %s", LogLevelKey, value, LogLevelNormal,
+				LogLevelDebug)
+		}
+	}
+	if value, present := data[ControllerLogLevelsKey]; present {
+		levels, err := parseList(value, func(value string) error {
+			switch LogLevel(value) {
+			case LogLevelNormal, LogLevelDebug:
+				return nil
+			default:
+				return errors.Errorf("expected %s or %s", LogLevelNormal, LogLevelDebug)
+			}
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", ControllerLogLevelsKey)
+		}
+		config.ControllerLogLevels = nil
+		if len(levels) > 0 {
+			config.ControllerLogLevels = make(map[string]LogLevel, len(levels))
+			for controller, level := range levels {
+				config.ControllerLogLevels[controller] = LogLevel(level)
+			}
+		}
+	}
+	if value, present := data[ControllerConcurrencyKey]; present {
+		counts, err := parseList(value, func(value string) error {
+			if count, err := strconv.Atoi(value); err != nil || count < 1 {
+				return errors.New("expected a positive integer")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", ControllerConcurrencyKey)
+		}
+		config.ControllerConcurrency = nil
+		if len(counts) > 0 {
+			config.ControllerConcurrency = make(map[string]int, len(counts))
+			for controller, count := range counts {
+				config.ControllerConcurrency[controller], _ = strconv.Atoi(count)
+			}
+		}
+	}
+	for key, delay := range map[string]*time.Duration{
+		RateLimiterBaseDelayKey: &config.RateLimiter.BaseDelay,
+		RateLimiterMaxDelayKey:  &config.RateLimiter.MaxDelay,
+	} {
+		value, present := data[key]
+		if !present {
+			continue
+		}
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || parsed < time.Millisecond {
+			return nil, errors.Errorf("invalid %s %q: expected a duration of at least 1ms", key, value)
+		}
+		*delay = parsed
+	}
+	if config.RateLimiter.MaxDelay < config.RateLimiter.BaseDelay {
+		return nil, errors.Errorf("invalid %s %s: expected at least the %s %s", RateLimiterMaxDelayKey,
+			config.RateLimiter.MaxDelay, RateLimiterBaseDelayKey, config.RateLimiter.BaseDelay)
+	}
+	if value, present := data[RateLimiterQPSKey]; present {
+		qps, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || qps <= 0 {
+			return nil, errors.Errorf("invalid %s %q: expected a positive number", RateLimiterQPSKey, value)
+		}
+		config.RateLimiter.QPS = qps
+	}
+	if value, present := data[RateLimiterBurstKey]; present {
+		burst, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || burst < 1 {
+			return nil, errors.Errorf("invalid %s %q: expected a positive integer", RateLimiterBurstKey, value)
+		}
+		config.RateLimiter.Burst = burst
+	}
+	if value, present := data[MachineSelectorKey]; present {
+		config.MachineSelector = nil
+		if value = strings.TrimSpace(value); value != "" {
+			selector, err := labels.Parse(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s %q", MachineSelectorKey, value)
+			}
+			config.MachineSelector = selector
+		}
+	}
+	if value, present := data[CanaryUpgradeKey]; present {
+		canaryUpgrade, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", CanaryUpgradeKey, value)
+		}
+		config.CanaryUpgrade = canaryUpgrade
+	}
+	if value, present := data[NodeTaintsKey]; present {
+		config.NodeTaints = nil
+		for _, spec := range strings.Split(value, ",") {
+			spec = strings.TrimSpace(spec)
+			if spec == "" {
+				continue
+			}
+			taint, err := nodeconfig.ParseTaint(spec)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", NodeTaintsKey)
+			}
+			config.NodeTaints = append(config.NodeTaints, *taint)
+		}
+	}
+	if value, present := data[KubeletArgsKey]; present {
+		config.KubeletArgs = nil
+		for _, arg := range strings.Fields(value) {
+			if !strings.HasPrefix(arg, "--") || strings.HasPrefix(arg, "--=") || arg == "--" {
+				return nil, errors.Errorf("invalid %s argument %q: expected --name=value", KubeletArgsKey, arg)
+			}
+			config.KubeletArgs = append(config.KubeletArgs, arg)
+		}
+	}
+	if value, present := data[KubeletConfigKey]; present {
+		config.KubeletConfig = nil
+		if strings.TrimSpace(value) != "" {
+			kubeletConfig, err := parseKubeletConfig(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", KubeletConfigKey)
+			}
+			config.KubeletConfig = kubeletConfig
+		}
+	}
+	if value, present := data[KubeletFeatureGatesKey]; present {
+		config.KubeletFeatureGates = nil
+		for _, gate := range strings.Split(value, ",") {
+			gate = strings.TrimSpace(gate)
+			if gate == "" {
+				continue
+			}
+			tokens := strings.SplitN(gate, "=", 2)
+			enabled, err := strconv.ParseBool(tokens[len(tokens)-1])
+			if len(tokens) != 2 || tokens[0] == "" || err != nil {
+				return nil, errors.Errorf("invalid %s %q: expected Name=true or Name=false", KubeletFeatureGatesKey,
+					gate)
+			}
+			if config.KubeletFeatureGates == nil {
+				config.KubeletFeatureGates = make(map[string]bool)
+			}
+			config.KubeletFeatureGates[tokens[0]] = enabled
+		}
+	}
+	for key, reserved := range map[string]*map[string]string{SystemReservedKey: &config.SystemReserved,
+		KubeReservedKey: &config.KubeReserved} {
+		if value, present := data[key]; present {
+			resources, err := parseResourceList(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", key)
+			}
+			*reserved = resources
+		}
+	}
+	for key, thresholds := range map[string]*map[string]string{EvictionHardKey: &config.EvictionHard,
+		EvictionSoftKey: &config.EvictionSoft} {
+		if value, present := data[key]; present {
+			signals, err := parseList(value, validateThreshold)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", key)
+			}
+			*thresholds = signals
+		}
+	}
+	if value, present := data[EvictionSoftGracePeriodKey]; present {
+		gracePeriods, err := parseList(value, func(value string) error {
+			_, err := time.ParseDuration(value)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", EvictionSoftGracePeriodKey)
+		}
+		config.EvictionSoftGracePeriod = gracePeriods
+	}
+	// kubelet fails to start if a soft eviction threshold has no grace period
+	for signal := range config.EvictionSoft {
+		if _, present := config.EvictionSoftGracePeriod[signal]; !present {
+			return nil, errors.Errorf("invalid %s: missing grace period for signal %s", EvictionSoftGracePeriodKey,
+				signal)
+		}
+	}
+	if value, present := data[ContainerLogMaxSizeKey]; present {
+		config.ContainerLogMaxSize = strings.TrimSpace(value)
+		if config.ContainerLogMaxSize != "" {
+			if _, err := resource.ParseQuantity(config.ContainerLogMaxSize); err != nil {
+				return nil, errors.Errorf("invalid %s %q: expected a quantity", ContainerLogMaxSizeKey, value)
+			}
+		}
+	}
+	if value, present := data[ContainerLogMaxFilesKey]; present {
+		// kubelet requires at least two files, the current log file and a rotated one
+		count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil || count < 2 {
+			return nil, errors.Errorf("invalid %s %q: expected an integer greater than 1", ContainerLogMaxFilesKey,
+				value)
+		}
+		config.ContainerLogMaxFiles = int32(count)
+	}
+	if value, present := data[KubeletRootDirKey]; present {
+		config.KubeletRootDir = strings.TrimSpace(value)
+		// The directory is given as a kubelet argument, it cannot contain whitespace
+		if config.KubeletRootDir != "" && !windowsPathRegex.MatchString(config.KubeletRootDir) {
+			return nil, errors.Errorf("invalid %s %q: expected an absolute path without whitespace",
+				KubeletRootDirKey, value)
+		}
+	}
+	if value, present := data[DataDisksKey]; present {
+		dataDisks, err := parseDataDisks(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", DataDisksKey)
+		}
+		config.DataDisks = dataDisks
+	}
+	if value, present := data[PagefileSizeKey]; present {
+		if err := parsePagefileSize(strings.TrimSpace(value), &config.Pagefile); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", PagefileSizeKey)
+		}
+	}
+	if value, present := data[PagefilePathKey]; present {
+		path := strings.TrimSpace(value)
+		if !windowsPathRegex.MatchString(path) {
+			return nil, errors.Errorf("invalid %s %q: expected an absolute path without whitespace", PagefilePathKey,
+				value)
+		}
+		config.Pagefile.Path = path
+	}
+	if value, present := data[OverlayMTUKey]; present {
+		mtu, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || (mtu != 0 && (mtu < minOverlayMTU || mtu > maxOverlayMTU)) {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or an integer between %d and %d", OverlayMTUKey,
+				value, minOverlayMTU, maxOverlayMTU)
+		}
+		config.OverlayMTU = mtu
+	}
+	if value, present := data[DNSServersKey]; present {
+		config.DNSServers = nil
+		for _, server := range strings.Split(value, ",") {
+			if server = strings.TrimSpace(server); server == "" {
+				continue
+			}
+			if net.ParseIP(server) == nil {
+				return nil, errors.Errorf("invalid %s address %q", DNSServersKey, server)
+			}
+			config.DNSServers = append(config.DNSServers, server)
+		}
+	}
+	if value, present := data[DNSSuffixSearchListKey]; present {
+		config.DNSSuffixSearchList = nil
+		for _, suffix := range strings.Split(value, ",") {
+			if suffix = strings.TrimSpace(suffix); suffix == "" {
+				continue
+			}
+			if errs := validation.IsDNS1123Subdomain(strings.ToLower(suffix)); len(errs) > 0 {
+				return nil, errors.Errorf("invalid %s suffix %q: %s", DNSSuffixSearchListKey, suffix,
+					strings.Join(errs, ", "))
+			}
+			config.DNSSuffixSearchList = append(config.DNSSuffixSearchList, suffix)
+		}
+	}
+	if value, present := data[NTPServersKey]; present {
+		config.NTPServers = nil
+		for _, server := range strings.Split(value, ",") {
+			if server = strings.TrimSpace(server); server == "" {
+				continue
+			}
+			if net.ParseIP(server) == nil && len(validation.IsDNS1123Subdomain(strings.ToLower(server))) > 0 {
+				return nil, errors.Errorf("invalid %s server %q: expected a host name or an IP address", NTPServersKey,
+					server)
+			}
+			config.NTPServers = append(config.NTPServers, server)
+		}
+	}
+	if value, present := data[ClockSkewThresholdKey]; present {
+		threshold, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || (threshold != 0 && threshold < time.Second) {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or a duration of at least 1s", ClockSkewThresholdKey,
+				value)
+		}
+		config.ClockSkewThreshold = threshold
+	}
+	if value, present := data[ShutdownGracePeriodKey]; present {
+		gracePeriod, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || gracePeriod < 0 || gracePeriod%time.Second != 0 {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or a whole number of seconds", ShutdownGracePeriodKey,
+				value)
+		}
+		config.ShutdownGracePeriod = gracePeriod
+	}
+	if value, present := data[KubeProxyDSRKey]; present {
+		dsr, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", KubeProxyDSRKey, value)
+		}
+		config.KubeProxyDSR = dsr
+	}
+	if value, present := data[NodeIPCIDRsKey]; present {
+		config.NodeIPCIDRs = nil
+		for _, cidr := range strings.Split(value, ",") {
+			if cidr = strings.TrimSpace(cidr); cidr == "" {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return nil, errors.Errorf("invalid %s CIDR %q", NodeIPCIDRsKey, cidr)
+			}
+			config.NodeIPCIDRs = append(config.NodeIPCIDRs, cidr)
+		}
+	}
+	if value, present := data[ProblemDetectionKey]; present {
+		problemDetection, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", ProblemDetectionKey, value)
+		}
+		config.ProblemDetection = problemDetection
+	}
+	if value, present := data[HNSNetworkRepairKey]; present {
+		hnsNetworkRepair, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", HNSNetworkRepairKey, value)
+		}
+		config.HNSNetworkRepair = hnsNetworkRepair
+	}
+	if value, present := data[ManageFirewallRulesKey]; present {
+		manageFirewallRules, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", ManageFirewallRulesKey, value)
+		}
+		config.ManageFirewallRules = manageFirewallRules
+	}
+	if value, present := data[HardenNodesKey]; present {
+		hardenNodes, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", HardenNodesKey, value)
+		}
+		config.HardenNodes = hardenNodes
+	}
+	if value, present := data[WindowsUpdatesKey]; present {
+		windowsUpdates, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", WindowsUpdatesKey, value)
+		}
+		config.WindowsUpdates = windowsUpdates
+	}
+	if value, present := data[SmokeTestKey]; present {
+		smokeTest, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", SmokeTestKey, value)
+		}
+		config.SmokeTest = smokeTest
+	}
+	if value, present := data[SmokeTestImageKey]; present {
+		if config.SmokeTestImage = strings.TrimSpace(value); config.SmokeTestImage == "" {
+			return nil, errors.Errorf("invalid %s %q: expected an image", SmokeTestImageKey, value)
+		}
+	}
+	if value, present := data[PauseImageKey]; present {
+		config.PauseImage = strings.TrimSpace(value)
+		if config.PauseImage != "" && !imageReferenceRegexp.MatchString(config.PauseImage) {
+			return nil, errors.Errorf("invalid %s %q: expected an image reference", PauseImageKey, value)
+		}
+	}
+	if value, present := data[NodeLabelsKey]; present {
+		nodeLabels, err := parseNodeLabels(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", NodeLabelsKey)
+		}
+		config.NodeLabels = nodeLabels
+	}
+	if value, present := data[ExternalCloudProviderKey]; present {
+		external, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", ExternalCloudProviderKey, value)
+		}
+		config.ExternalCloudProvider = external
+	}
+	if value, present := data[PrePullImagesKey]; present {
+		config.PrePullImages = nil
+		for _, image := range strings.Split(value, ",") {
+			if image = strings.TrimSpace(image); image == "" {
+				continue
+			}
+			if !imageReferenceRegexp.MatchString(image) {
+				return nil, errors.Errorf("invalid %s image %q: expected an image reference", PrePullImagesKey, image)
+			}
+			config.PrePullImages = append(config.PrePullImages, image)
+		}
+	}
+	if value, present := data[TransferBandwidthKey]; present {
+		bandwidth, err := resource.ParseQuantity(strings.TrimSpace(value))
+		if err != nil || (!bandwidth.IsZero() && bandwidth.Value() < 1024*1024) {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or a quantity of at least 1Mi", TransferBandwidthKey,
+				value)
+		}
+		config.TransferBandwidth = bandwidth.Value()
+	}
+	if value, present := data[ConnectTimeoutKey]; present {
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout < time.Minute {
+			return nil, errors.Errorf("invalid %s %q: expected a duration of at least 1m", ConnectTimeoutKey, value)
+		}
+		config.Timeouts.Connect = timeout
+	}
+	for key, timeout := range map[string]*time.Duration{
+		CommandTimeoutKey:  &config.Timeouts.Command,
+		TransferTimeoutKey: &config.Timeouts.Transfer,
+	} {
+		value, present := data[key]
+		if !present {
+			continue
+		}
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || (parsed != 0 && parsed < 10*time.Second) {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or a duration of at least 10s", key, value)
+		}
+		*timeout = parsed
+	}
+	if value, present := data[DetectGPUsKey]; present {
+		detectGPUs, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", DetectGPUsKey, value)
+		}
+		config.DetectGPUs = detectGPUs
+	}
+	if value, present := data[WorkloadWindowsBuildsKey]; present {
+		config.WorkloadBuilds = nil
+		for _, version := range strings.Split(value, ",") {
+			if version = strings.TrimSpace(version); version == "" {
+				continue
+			}
+			build, err := windows.ParseBuild(version)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", WorkloadWindowsBuildsKey)
+			}
+			config.WorkloadBuilds = append(config.WorkloadBuilds, build)
+		}
+	}
+	if value, present := data[PasswordRotationIntervalKey]; present {
+		interval, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || (interval != 0 && interval < time.Hour) {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or a duration of at least 1h",
+				PasswordRotationIntervalKey, value)
+		}
+		config.PasswordRotationInterval = interval
+	}
+	if value, present := data[PrivateKeyMaxAgeKey]; present {
+		maxAge, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || (maxAge != 0 && maxAge < 24*time.Hour) {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or a duration of at least 24h", PrivateKeyMaxAgeKey,
+				value)
+		}
+		config.PrivateKeyMaxAge = maxAge
+	}
+	for key, algorithms := range map[string]struct {
+		list      *[]string
+		supported []string
+	}{
+		SSHCiphersKey:      {&config.SSHAlgorithms.Ciphers, windows.SupportedSSHCiphers},
+		SSHMACsKey:         {&config.SSHAlgorithms.MACs, windows.SupportedSSHMACs},
+		SSHKeyExchangesKey: {&config.SSHAlgorithms.KeyExchanges, windows.SupportedSSHKeyExchanges},
+	} {
+		if value, present := data[key]; present {
+			list, err := parseAlgorithms(value, algorithms.supported)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", key)
+			}
+			*algorithms.list = list
+		}
+	}
+	if value, present := data[CrashDumpsKey]; present {
+		crashDumps, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", CrashDumpsKey, value)
+		}
+		config.CrashDumps = crashDumps
+	}
+	if value, present := data[EventLogsKey]; present {
+		eventLogs, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", EventLogsKey, value)
+		}
+		config.EventLogs = eventLogs
+	}
+	if value, present := data[KubeletAuthHardeningKey]; present {
+		hardening, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", KubeletAuthHardeningKey, value)
+		}
+		config.KubeletAuthHardening = hardening
+	}
+	if err := validateDebuggingHandlers(&config); err != nil {
+		return nil, err
+	}
+	if err := validateKubeletAuth(&config); err != nil {
+		return nil, err
+	}
+	if config.ExternalCloudProvider {
+		for _, arg := range config.KubeletArgs {
+			if strings.HasPrefix(arg, "--cloud-provider=") && arg != externalCloudProviderArg {
+				return nil, errors.Errorf("invalid %s: %s cannot be set when %s is true", KubeletArgsKey, arg,
+					ExternalCloudProviderKey)
+			}
+		}
+	}
+	if config.HardenNodes && !config.ManageFirewallRules {
+		// The hardening profile disables the inbound rules not created by WMCO, which would make the node unreachable
+		return nil, errors.Errorf("%s requires %s to be true", HardenNodesKey, ManageFirewallRulesKey)
+	}
+	if value, present := data[DryRunKey]; present {
+		dryRun, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", DryRunKey, value)
+		}
+		config.DryRun = dryRun
+	}
+	if value, present := data[PinnedVersionKey]; present {
+		config.PinnedVersion = strings.TrimSpace(value)
+	}
+	if value, present := data[VersionSkewKey]; present {
+		skew, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || skew < 0 || skew > 1 {
+			return nil, errors.Errorf("invalid %s %q: expected 0 or 1", VersionSkewKey, value)
+		}
+		config.VersionSkew = skew
+	}
+	if value, present := data[ConfigurationHooksKey]; present {
+		config.ConfigurationHooks = strings.TrimSpace(value)
+		if config.ConfigurationHooks != "" && len(validation.IsDNS1123Subdomain(config.ConfigurationHooks)) > 0 {
+			return nil, errors.Errorf("invalid %s %q: expected a ConfigMap name", ConfigurationHooksKey, value)
+		}
+	}
+	if value, present := data[SSHProxyKey]; present {
+		config.SSHProxy = strings.TrimSpace(value)
+		if config.SSHProxy != "" {
+			if _, err := windows.ParseSSHProxy(config.SSHProxy); err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", SSHProxyKey)
+			}
+		}
+	}
+	if value, present := data[KubeletCredentialProvidersKey]; present {
+		credentialProviders, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("invalid %s %q: expected true or false", KubeletCredentialProvidersKey, value)
+		}
+		config.KubeletCredentialProviders = credentialProviders
+	}
+	if value, present := data[SSHAddressTypeKey]; present {
+		config.SSHAddressType = strings.TrimSpace(value)
+		if config.SSHAddressType != "" && config.SSHAddressType != string(core.NodeInternalIP) &&
+			config.SSHAddressType != string(core.NodeExternalIP) {
+			return nil, errors.Errorf("invalid %s %q: expected %s or %s", SSHAddressTypeKey, value,
+				core.NodeInternalIP, core.NodeExternalIP)
+		}
+	}
+	if value, present := data[SSHAddressCIDRsKey]; present {
+		config.SSHAddressCIDRs = nil
+		for _, cidr := range strings.Split(value, ",") {
+			if cidr = strings.TrimSpace(cidr); cidr == "" {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return nil, errors.Errorf("invalid %s CIDR %q", SSHAddressCIDRsKey, cidr)
+			}
+			config.SSHAddressCIDRs = append(config.SSHAddressCIDRs, cidr)
+		}
+	}
+	return &config, nil
+}
+
+// parseAlgorithms returns the algorithms of the given comma separated list, in order, ensuring they are supported
+func parseAlgorithms(value string, supported []string) ([]string, error) {
+	var algorithms []string
+	for _, algorithm := range strings.Split(value, ",") {
+		algorithm = strings.TrimSpace(algorithm)
+		if algorithm == "" {
+			continue
+		}
+		isSupported := false
+		for _, name := range supported {
+			if algorithm == name {
+				isSupported = true
+				break
+			}
+		}
+		if !isSupported {
+			return nil, errors.Errorf("unsupported algorithm %q, expected one of: %s", algorithm,
+				strings.Join(supported, ", "))
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	return algorithms, nil
+}
+
+// parseKubeletConfig returns the KubeletConfiguration fields defined by the given YAML or JSON object
+func parseKubeletConfig(value string) (map[string]interface{}, error) {
+	data, err := yaml.ToJSON([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+	kubeletConfig := make(map[string]interface{})
+	if err := json.Unmarshal(data, &kubeletConfig); err != nil {
+		return nil, errors.New("expected an object")
+	}
+	// The type of the configuration is defined by the bootstrapper
+	delete(kubeletConfig, "apiVersion")
+	delete(kubeletConfig, "kind")
+	return kubeletConfig, nil
+}
+
+// parseResourceList returns the resources defined by the given comma separated list of name=quantity pairs, or nil if
+// the list is empty
+func parseResourceList(value string) (map[string]string, error) {
+	return parseList(value, func(value string) error {
+		_, err := resource.ParseQuantity(value)
+		return err
+	})
+}
+
+// parseDataDisks returns the data disks defined by the given comma separated list of number=path pairs, sorted by
+// disk number
+func parseDataDisks(value string) ([]windows.DataDisk, error) {
+	paths, err := parseList(value, func(path string) error {
+		if !driveLetterRegex.MatchString(path) && !windowsPathRegex.MatchString(path) {
+			return errors.Errorf("invalid path %s: expected a drive letter or an absolute path without whitespace",
+				path)
+		}
+		if strings.EqualFold(path[:2], "C:") && len(strings.TrimSuffix(path, "\\")) == 2 {
+			return errors.New("the C: drive is used by the operating system")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var dataDisks []windows.DataDisk
+	for number, path := range paths {
+		diskNumber, err := strconv.Atoi(number)
+		// Disk 0 holds the operating system
+		if err != nil || diskNumber < 1 {
+			return nil, errors.Errorf("invalid disk number %s: expected a positive integer", number)
+		}
+		if driveLetterRegex.MatchString(path) {
+			path = strings.ToUpper(path[:2])
+		} else {
+			path = strings.TrimSuffix(path, "\\")
+		}
+		dataDisks = append(dataDisks, windows.DataDisk{Number: diskNumber, Path: path})
+	}
+	sort.Slice(dataDisks, func(i, j int) bool { return dataDisks[i].Number < dataDisks[j].Number })
+	return dataDisks, nil
+}
+
+// parsePagefileSize sets the mode and size of the given pagefile configuration from the given pagefile size setting
+func parsePagefileSize(value string, pagefile *windows.Pagefile) error {
+	switch windows.PagefileMode(value) {
+	case windows.PagefileUnmanaged, windows.PagefileSystemManaged, windows.PagefileDisabled:
+		pagefile.Mode = windows.PagefileMode(value)
+		pagefile.SizeMB = 0
+		return nil
+	}
+	size, err := resource.ParseQuantity(value)
+	if err != nil {
+		return errors.Errorf("expected %s, %s or a quantity", windows.PagefileDisabled, windows.PagefileSystemManaged)
+	}
+	sizeMB := size.Value() / (1024 * 1024)
+	if sizeMB < 1 {
+		return errors.New("the size must be at least 1Mi")
+	}
+	pagefile.Mode = windows.PagefileFixed
+	pagefile.SizeMB = sizeMB
+	return nil
+}
+
+// validateThreshold returns an error if the given eviction threshold is neither a quantity nor a percentage
+func validateThreshold(value string) error {
+	if strings.HasSuffix(value, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percentage < 0 || percentage > 100 {
+			return errors.Errorf("invalid percentage %s", value)
+		}
+		return nil
+	}
+	_, err := resource.ParseQuantity(value)
+	return err
+}
+
+// parseNodeLabels returns the labels of the given comma separated list of key=value pairs, ensuring that kubelet can
+// register a node with them
+func parseNodeLabels(value string) (map[string]string, error) {
+	nodeLabels, err := parseList(value, func(value string) error {
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return errors.New(strings.Join(errs, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key := range nodeLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, errors.Errorf("invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if !isKubeletLabel(key) {
+			return nil, errors.Errorf("label %s cannot be set by kubelet, only labels within the %v domains are "+
+				"allowed within the kubernetes.io and k8s.io domains", key, kubeletLabelDomains)
+		}
+	}
+	return nodeLabels, nil
+}
+
+// isKubeletLabel returns true if kubelet can register a node with the label with the given key
+func isKubeletLabel(key string) bool {
+	if kubeletLabels[key] {
+		return true
+	}
+	i := strings.Index(key, "/")
+	if i == -1 {
+		return true
+	}
+	domain := key[:i]
+	for _, kubeletDomain := range kubeletLabelDomains {
+		if domain == kubeletDomain || strings.HasSuffix(domain, "."+kubeletDomain) {
+			return true
+		}
+	}
+	for _, restricted := range []string{"kubernetes.io", "k8s.io"} {
+		if domain == restricted || strings.HasSuffix(domain, "."+restricted) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseList returns the entries of the given comma separated list of name=value pairs, or nil if the list is empty.
+// Each value is checked with the given validation function.
+func parseList(list string, validate func(string) error) (map[string]string, error) {
+	var entries map[string]string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tokens := strings.SplitN(entry, "=", 2)
+		if len(tokens) != 2 || tokens[0] == "" {
+			return nil, errors.Errorf("invalid entry %q: expected name=value", entry)
+		}
+		if err := validate(tokens[1]); err != nil {
+			return nil, errors.Wrapf(err, "invalid value for %s", tokens[0])
+		}
+		if entries == nil {
+			entries = make(map[string]string)
+		}
+		entries[tokens[0]] = tokens[1]
+	}
+	return entries, nil
+}
+
+// KubeletSettings returns the kubelet settings applied on top of the configuration generated by the bootstrapper, for
+// the node with the given IP address. The kubelet root directory and, when the node IP is chosen by CIDR, the node IP
+// are given as arguments, placed before the other arguments so that they can be overridden by them. The feature
+// gates, reserved resources, eviction thresholds, log rotation and authentication hardening settings are rendered into
+// the corresponding fields of the kubelet configuration, merged with the values defined by the kubeletConfig setting.
+func (c *Config) KubeletSettings(nodeIP string) windows.KubeletSettings {
+	kubeletConfig := make(map[string]interface{}, len(c.KubeletConfig))
+	for key, value := range c.KubeletConfig {
+		kubeletConfig[key] = value
+	}
+	featureGates := make(map[string]interface{}, len(c.KubeletFeatureGates))
+	for name, enabled := range c.KubeletFeatureGates {
+		featureGates[name] = enabled
+	}
+	mergeKubeletConfigField(kubeletConfig, "featureGates", featureGates)
+	for field, entries := range map[string]map[string]string{"systemReserved": c.SystemReserved,
+		"kubeReserved": c.KubeReserved, "evictionHard": c.EvictionHard, "evictionSoft": c.EvictionSoft,
+		"evictionSoftGracePeriod": c.EvictionSoftGracePeriod} {
+		values := make(map[string]interface{}, len(entries))
+		for name, value := range entries {
+			values[name] = value
+		}
+		mergeKubeletConfigField(kubeletConfig, field, values)
+	}
+	if c.ContainerLogMaxSize != "" {
+		kubeletConfig["containerLogMaxSize"] = c.ContainerLogMaxSize
+	}
+	if c.ContainerLogMaxFiles != 0 {
+		// Numbers are decoded as float64 from the kubelet configuration file, they must be of the same type to be
+		// compared with it
+		kubeletConfig["containerLogMaxFiles"] = float64(c.ContainerLogMaxFiles)
+	}
+	if c.KubeletAuthHardening {
+		for _, setting := range kubeletAuthHardeningSettings {
+			setKubeletConfigValue(kubeletConfig, setting.path, setting.value)
+		}
+	}
+	if len(kubeletConfig) == 0 {
+		kubeletConfig = nil
+	}
+	args := c.KubeletArgs
+	if len(c.NodeIPCIDRs) > 0 && nodeIP != "" {
+		args = append([]string{"--node-ip=" + nodeIP}, args...)
+	}
+	if c.KubeletRootDir != "" {
+		args = append([]string{"--root-dir=" + c.KubeletRootDir}, args...)
+	}
+	if c.PauseImage != "" {
+		args = append([]string{"--pod-infra-container-image=" + c.PauseImage}, args...)
+	}
+	if c.ExternalCloudProvider {
+		args = append([]string{externalCloudProviderArg}, args...)
+	}
+	return windows.KubeletSettings{Args: args, Config: kubeletConfig, CredentialProviders: c.KubeletCredentialProviders}
+}
+
+// mergeKubeletConfigField sets the given object field of the kubelet configuration to the given values, merged on top
+// of the values already present. The field is left untouched if no values are given.
+func mergeKubeletConfigField(kubeletConfig map[string]interface{}, field string, values map[string]interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	merged := make(map[string]interface{})
+	if existing, ok := kubeletConfig[field].(map[string]interface{}); ok {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+	kubeletConfig[field] = merged
+}
+
+// kubeletAuthSetting is a nested field of the kubelet configuration set by the authentication hardening
+type kubeletAuthSetting struct {
+	path  []string
+	value interface{}
+}
+
+// kubeletAuthHardeningSettings are the kubelet configuration fields disabling anonymous requests and delegating the
+// authentication of bearer tokens and the authorization of all requests to the API server, as on Linux nodes
+var kubeletAuthHardeningSettings = []kubeletAuthSetting{
+	{path: []string{"authentication", "anonymous", "enabled"}, value: false},
+	{path: []string{"authentication", "webhook", "enabled"}, value: true},
+	{path: []string{"authorization", "mode"}, value: "Webhook"},
+}
+
+// kubeletAuthHardeningArgs are the kubelet arguments which take precedence over the kubelet configuration fields set
+// by the authentication hardening, along with the only value they can have when the hardening is enforced
+var kubeletAuthHardeningArgs = map[string]string{
+	"--anonymous-auth":               "false",
+	"--authentication-token-webhook": "true",
+	"--authorization-mode":           "Webhook",
+}
+
+// setKubeletConfigValue sets the nested field of the kubelet configuration at the given path to the given value. The
+// objects along the path are copied, so that the objects shared with the operator configuration are not modified.
+func setKubeletConfigValue(kubeletConfig map[string]interface{}, path []string, value interface{}) {
+	if len(path) == 1 {
+		kubeletConfig[path[0]] = value
+		return
+	}
+	object := make(map[string]interface{})
+	if existing, ok := kubeletConfig[path[0]].(map[string]interface{}); ok {
+		for key, value := range existing {
+			object[key] = value
+		}
+	}
+	setKubeletConfigValue(object, path[1:], value)
+	kubeletConfig[path[0]] = object
+}
+
+// validateKubeletAuth returns an error if the kubelet settings of the given configuration weaken the authentication or
+// authorization of the kubelet while the authentication hardening is enforced
+func validateKubeletAuth(config *Config) error {
+	if !config.KubeletAuthHardening {
+		return nil
+	}
+	for _, setting := range kubeletAuthHardeningSettings {
+		value, present := kubeletConfigValue(config.KubeletConfig, setting.path)
+		if present && value != setting.value {
+			return errors.Errorf("invalid %s: %s must be %v when %s is true", KubeletConfigKey,
+				strings.Join(setting.path, "."), setting.value, KubeletAuthHardeningKey)
+		}
+	}
+	for _, arg := range config.KubeletArgs {
+		tokens := strings.SplitN(arg, "=", 2)
+		required, ok := kubeletAuthHardeningArgs[tokens[0]]
+		if !ok {
+			continue
+		}
+		// A boolean flag given without a value is true
+		if len(tokens) == 1 {
+			tokens = append(tokens, "true")
+		}
+		if tokens[1] != required {
+			return errors.Errorf("invalid %s: %s must be %s when %s is true", KubeletArgsKey, arg, required,
+				KubeletAuthHardeningKey)
+		}
+	}
+	return nil
+}
+
+// kubeletConfigValue returns the value of the nested field of the kubelet configuration at the given path, and whether
+// it is present
+func kubeletConfigValue(kubeletConfig map[string]interface{}, path []string) (interface{}, bool) {
+	value, present := kubeletConfig[path[0]]
+	if !present || len(path) == 1 {
+		return value, present
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return kubeletConfigValue(object, path[1:])
+}
+
+// validateDebuggingHandlers returns an error if the kubelet settings of the given configuration disable the kubelet
+// debugging handlers, which serve the node logs read by `oc adm node-logs` and must-gather
+func validateDebuggingHandlers(config *Config) error {
+	if enabled, ok := config.KubeletConfig["enableDebuggingHandlers"].(bool); ok && !enabled {
+		return errors.Errorf("invalid %s: enableDebuggingHandlers cannot be disabled as it serves the node logs",
+			KubeletConfigKey)
+	}
+	for _, arg := range config.KubeletArgs {
+		tokens := strings.SplitN(arg, "=", 2)
+		if tokens[0] != "--enable-debugging-handlers" || len(tokens) != 2 {
+			continue
+		}
+		if enabled, err := strconv.ParseBool(tokens[1]); err == nil && !enabled {
+			return errors.Errorf("invalid %s argument %q: the debugging handlers cannot be disabled as they serve "+
+				"the node logs", KubeletArgsKey, arg)
+		}
+	}
+	return nil
+}
+
+// HostSettings returns the operating system settings applied to Windows VMs before the Kubernetes components are
+// configured
+func (c *Config) HostSettings() windows.HostSettings {
+	return windows.HostSettings{DataDisks: c.DataDisks, Pagefile: c.Pagefile, OverlayMTU: c.OverlayMTU,
+		DNSServers: c.DNSServers, DNSSuffixes: c.DNSSuffixSearchList, NTPServers: c.NTPServers,
+		ShutdownGracePeriod: c.ShutdownGracePeriod, KubeProxyDSR: c.KubeProxyDSR,
+		ManageFirewallRules: c.ManageFirewallRules, Harden: c.HardenNodes, CrashDumps: c.CrashDumps,
+		EventLogs: c.EventLogs, SSHAlgorithms: c.SSHAlgorithms, SSHProxy: c.SSHProxy, SSHProxyUser: c.SSHProxyUser,
+		PrePullImages: c.PrePullImages, TransferBandwidth: c.TransferBandwidth, Timeouts: c.Timeouts,
+		DetectGPUs: c.DetectGPUs, WorkloadBuilds: c.WorkloadBuilds, NodeLabels: c.NodeLabels,
+		ExternalCloudProvider: c.ExternalCloudProvider}
+}
+
+// ManagesMachine returns true if the Windows Machine with the given labels is selected by the machine selector
+func (c *Config) ManagesMachine(machineLabels map[string]string) bool {
+	return c.MachineSelector == nil || c.MachineSelector.Matches(labels.Set(machineLabels))
+}
+
+// MaxConcurrentReconciles returns the number of reconciliations the given controller runs concurrently, which
+// defaults to 1
+func (c *Config) MaxConcurrentReconciles(controller string) int {
+	if count, present := c.ControllerConcurrency[controller]; present {
+		return count
+	}
+	return 1
+}
+
+// ControllerOptions returns the options of the given controller, running MaxConcurrentReconciles reconciliations
+// concurrently and requeuing objects through the configured rate limiter
+func (c *Config) ControllerOptions(controllerName string) controller.Options {
+	return controller.Options{
+		MaxConcurrentReconciles: c.MaxConcurrentReconciles(controllerName),
+		RateLimiter: workqueue.NewMaxOfRateLimiter(
+			workqueue.NewItemExponentialFailureRateLimiter(c.RateLimiter.BaseDelay, c.RateLimiter.MaxDelay),
+			&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(c.RateLimiter.QPS), c.RateLimiter.Burst)},
+		),
+	}
+}
+
+// Override returns the given configuration with the settings overridden by the given MachineSet annotations
+func Override(config Config, annotations map[string]string) (*Config, error) {
+	data := make(map[string]string)
+	for annotation, key := range overrideAnnotations {
+		if value, present := annotations[annotation]; present {
+			data[key] = value
+		}
+	}
+	if len(data) == 0 {
+		return &config, nil
+	}
+	return Parse(data, config)
+}
+
+// IsOverrideAnnotation returns true if the given annotation overrides a setting for the Machines of a MachineSet
+func IsOverrideAnnotation(annotation string) bool {
+	_, present := overrideAnnotations[annotation]
+	return present
+}
+
+// Load returns the configuration defined by the operator configuration ConfigMap in the given namespace, falling back
+// to the given defaults for settings which are not present or when the ConfigMap does not exist
+func Load(ctx context.Context, clientset kubernetes.Interface, namespace string, defaults Config) (*Config, error) {
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, ConfigMapName, meta.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return &defaults, nil
+		}
+		return nil, errors.Wrapf(err, "error getting %s ConfigMap", ConfigMapName)
+	}
+	config, err := Parse(cm.Data, defaults)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s ConfigMap", ConfigMapName)
+	}
+	if config.SSHProxy != "" {
+		if config.SSHProxyUser, err = loadSSHProxyUser(ctx, clientset, namespace); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// LoadFromCache returns the configuration defined by the operator configuration ConfigMap in the given namespace, read
+// through the given reader, such as the cache of the operator namespace, falling back to the given defaults like Load.
+// The credentials of the SSH proxy are not loaded, so the configuration must not be used to connect to VMs.
+func LoadFromCache(ctx context.Context, reader client.Reader, namespace string, defaults Config) (*Config, error) {
+	cm := &core.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ConfigMapName}, cm); err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return &defaults, nil
+		}
+		return nil, errors.Wrapf(err, "error getting %s ConfigMap", ConfigMapName)
+	}
+	config, err := Parse(cm.Data, defaults)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s ConfigMap", ConfigMapName)
+	}
+	return config, nil
+}
+
+// loadSSHProxyUser returns the credentials of the SSH proxy held by the SSHProxyCredentialsSecretName Secret in the
+// given namespace, nil when the Secret does not exist
+func loadSSHProxyUser(ctx context.Context, clientset kubernetes.Interface, namespace string) (*url.Userinfo, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, SSHProxyCredentialsSecretName, meta.GetOptions{})
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error getting %s Secret", SSHProxyCredentialsSecretName)
+	}
+	username := string(secret.Data["username"])
+	if username == "" {
+		return nil, errors.Errorf("invalid %s Secret: missing username", SSHProxyCredentialsSecretName)
+	}
+	return url.UserPassword(username, string(secret.Data["password"])), nil
+}
@@ -0,0 +1,638 @@
+package operatorconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows"
+)
+
+// TestParse tests the Parse function
+func TestParse(t *testing.T) {
+	defaults := Default()
+	defaults.CanaryUpgrade = true
+
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			data: map[string]string{},
+			want: defaults,
+		},
+		{
+			name: "all settings",
+			data: map[string]string{
+				MaxUnhealthyCountKey:          "2",
+				RemediationStrategyKey:        "None",
+				SSHUserKey:                    "core",
+				LogLevelKey:                   "Debug",
+				ControllerLogLevelsKey:        "node=Normal",
+				ControllerConcurrencyKey:      "windowsmachine=4, node=2",
+				RateLimiterBaseDelayKey:       "1s",
+				RateLimiterMaxDelayKey:        "5m",
+				RateLimiterQPSKey:             "0.5",
+				RateLimiterBurstKey:           "20",
+				MachineSelectorKey:            "team in (a,b), !legacy",
+				CanaryUpgradeKey:              "false",
+				NodeTaintsKey:                 "os=Windows:NoSchedule, dedicated:NoExecute",
+				KubeletArgsKey:                "--v=4  --feature-gates=A=true",
+				KubeletConfigKey:              "kind: KubeletConfiguration\nmaxPods: 100\nevictionHard:\n  memory.available: 500Mi\n",
+				KubeletFeatureGatesKey:        "A=true, B=false",
+				SystemReservedKey:             "cpu=500m, memory=2Gi",
+				KubeReservedKey:               "memory=1Gi",
+				EvictionHardKey:               "memory.available=500Mi,nodefs.available=10%",
+				EvictionSoftKey:               "memory.available=1Gi",
+				EvictionSoftGracePeriodKey:    "memory.available=1m30s",
+				ContainerLogMaxSizeKey:        "50Mi",
+				ContainerLogMaxFilesKey:       "3",
+				KubeletRootDirKey:             `D:\kubelet`,
+				DataDisksKey:                  `2=C:\data\, 1=d:\`,
+				PagefileSizeKey:               "4Gi",
+				PagefilePathKey:               `D:\pagefile.sys`,
+				OverlayMTUKey:                 "8900",
+				DNSServersKey:                 "10.0.0.2, 10.0.0.3",
+				DNSSuffixSearchListKey:        "cluster.example.com,Example.com",
+				NTPServersKey:                 "time.example.com, 10.0.0.4",
+				ClockSkewThresholdKey:         "1m",
+				ProblemDetectionKey:           "false",
+				HNSNetworkRepairKey:           "true",
+				ShutdownGracePeriodKey:        "90s",
+				KubeProxyDSRKey:               "true",
+				NodeIPCIDRsKey:                "10.0.0.0/16, 192.168.0.0/24",
+				ManageFirewallRulesKey:        "false",
+				CrashDumpsKey:                 "true",
+				EventLogsKey:                  "true",
+				KubeletAuthHardeningKey:       "true",
+				WindowsUpdatesKey:             "true",
+				SmokeTestKey:                  "true",
+				SmokeTestImageKey:             "registry.example.com/agnhost:2.32",
+				PauseImageKey:                 "registry.example.com/pause:3.4.1",
+				NodeLabelsKey:                 "team=a, node.kubernetes.io/pool=gpu",
+				ExternalCloudProviderKey:      "true",
+				PrePullImagesKey:              "mcr.microsoft.com/windows/servercore:ltsc2019,registry.example.com/app:1.0",
+				TransferBandwidthKey:          "10Mi",
+				ConnectTimeoutKey:             "5m",
+				CommandTimeoutKey:             "30m",
+				TransferTimeoutKey:            "0",
+				DetectGPUsKey:                 "true",
+				WorkloadWindowsBuildsKey:      "10.0.17763, 19041",
+				PasswordRotationIntervalKey:   "720h",
+				PrivateKeyMaxAgeKey:           "2160h",
+				SSHCiphersKey:                 "aes256-ctr, aes128-gcm@openssh.com",
+				SSHMACsKey:                    "hmac-sha2-256",
+				SSHKeyExchangesKey:            "curve25519-sha256@libssh.org",
+				DryRunKey:                     "true",
+				PinnedVersionKey:              "2.0.0",
+				VersionSkewKey:                "1",
+				ConfigurationHooksKey:         "windows-hooks",
+				SSHProxyKey:                   "socks5://proxy.example.com:1080",
+				KubeletCredentialProvidersKey: "true",
+				SSHAddressTypeKey:             "ExternalIP",
+				SSHAddressCIDRsKey:            "203.0.113.0/24",
+			},
+			want: Config{
+				MaxUnhealthyCount:     2,
+				RemediationStrategy:   RemediationNone,
+				SSHUser:               "core",
+				LogLevel:              LogLevelDebug,
+				ControllerLogLevels:   map[string]LogLevel{"node": LogLevelNormal},
+				ControllerConcurrency: map[string]int{"windowsmachine": 4, "node": 2},
+				MachineSelector:       mustParseSelector(t, "team in (a,b), !legacy"),
+				CanaryUpgrade:         false,
+				NodeTaints: []core.Taint{
+					{Key: "os", Value: "Windows", Effect: core.TaintEffectNoSchedule},
+					{Key: "dedicated", Effect: core.TaintEffectNoExecute},
+				},
+				KubeletArgs: []string{"--v=4", "--feature-gates=A=true"},
+				KubeletConfig: map[string]interface{}{
+					"maxPods":      float64(100),
+					"evictionHard": map[string]interface{}{"memory.available": "500Mi"},
+				},
+				KubeletFeatureGates:     map[string]bool{"A": true, "B": false},
+				SystemReserved:          map[string]string{"cpu": "500m", "memory": "2Gi"},
+				KubeReserved:            map[string]string{"memory": "1Gi"},
+				EvictionHard:            map[string]string{"memory.available": "500Mi", "nodefs.available": "10%"},
+				EvictionSoft:            map[string]string{"memory.available": "1Gi"},
+				EvictionSoftGracePeriod: map[string]string{"memory.available": "1m30s"},
+				ContainerLogMaxSize:     "50Mi",
+				ContainerLogMaxFiles:    3,
+				KubeletRootDir:          `D:\kubelet`,
+				DataDisks: []windows.DataDisk{
+					{Number: 1, Path: "D:"},
+					{Number: 2, Path: `C:\data`},
+				},
+				Pagefile:                 windows.Pagefile{Mode: windows.PagefileFixed, Path: `D:\pagefile.sys`, SizeMB: 4096},
+				OverlayMTU:               8900,
+				DNSServers:               []string{"10.0.0.2", "10.0.0.3"},
+				DNSSuffixSearchList:      []string{"cluster.example.com", "Example.com"},
+				NTPServers:               []string{"time.example.com", "10.0.0.4"},
+				ClockSkewThreshold:       time.Minute,
+				ProblemDetection:         false,
+				HNSNetworkRepair:         true,
+				ShutdownGracePeriod:      90 * time.Second,
+				KubeProxyDSR:             true,
+				NodeIPCIDRs:              []string{"10.0.0.0/16", "192.168.0.0/24"},
+				ManageFirewallRules:      false,
+				CrashDumps:               true,
+				EventLogs:                true,
+				KubeletAuthHardening:     true,
+				WindowsUpdates:           true,
+				SmokeTest:                true,
+				SmokeTestImage:           "registry.example.com/agnhost:2.32",
+				PauseImage:               "registry.example.com/pause:3.4.1",
+				NodeLabels:               map[string]string{"team": "a", "node.kubernetes.io/pool": "gpu"},
+				ExternalCloudProvider:    true,
+				PrePullImages:            []string{"mcr.microsoft.com/windows/servercore:ltsc2019", "registry.example.com/app:1.0"},
+				TransferBandwidth:        10 * 1024 * 1024,
+				Timeouts:                 windows.Timeouts{Connect: 5 * time.Minute, Command: 30 * time.Minute},
+				DetectGPUs:               true,
+				WorkloadBuilds:           []int{17763, 19041},
+				PasswordRotationInterval: 720 * time.Hour,
+				PrivateKeyMaxAge:         2160 * time.Hour,
+				SSHAlgorithms: windows.SSHAlgorithms{
+					Ciphers:      []string{"aes256-ctr", "aes128-gcm@openssh.com"},
+					MACs:         []string{"hmac-sha2-256"},
+					KeyExchanges: []string{"curve25519-sha256@libssh.org"},
+				},
+				DryRun:                     true,
+				PinnedVersion:              "2.0.0",
+				VersionSkew:                1,
+				ConfigurationHooks:         "windows-hooks",
+				SSHProxy:                   "socks5://proxy.example.com:1080",
+				KubeletCredentialProviders: true,
+				SSHAddressType:             "ExternalIP",
+				SSHAddressCIDRs:            []string{"203.0.113.0/24"},
+				RateLimiter:                RateLimiter{BaseDelay: time.Second, MaxDelay: 5 * time.Minute, QPS: 0.5, Burst: 20},
+			},
+		},
+		{
+			name:    "invalid versionSkew",
+			data:    map[string]string{VersionSkewKey: "2"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid configurationHooks",
+			data:    map[string]string{ConfigurationHooksKey: "Windows_Hooks"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid sshProxy",
+			data:    map[string]string{SSHProxyKey: "ftp://proxy.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid kubeletCredentialProviders",
+			data:    map[string]string{KubeletCredentialProvidersKey: "ecr"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid sshAddressType",
+			data:    map[string]string{SSHAddressTypeKey: "Hostname"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid sshAddressCIDRs",
+			data:    map[string]string{SSHAddressCIDRsKey: "203.0.113.0"},
+			wantErr: true,
+		},
+		{
+			name:    "rateLimiterMaxDelay below rateLimiterBaseDelay",
+			data:    map[string]string{RateLimiterBaseDelayKey: "1m", RateLimiterMaxDelayKey: "30s"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid rateLimiterQPS",
+			data:    map[string]string{RateLimiterQPSKey: "0"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid maxUnhealthyCount",
+			data:    map[string]string{MaxUnhealthyCountKey: "0"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid machineSelector",
+			data:    map[string]string{MachineSelectorKey: "team in a"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid remediationStrategy",
+			data:    map[string]string{RemediationStrategyKey: "Reboot"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid logLevel",
+			data:    map[string]string{LogLevelKey: "Trace"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid canaryUpgrade",
+			data:    map[string]string{CanaryUpgradeKey: "sometimes"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid nodeTaints",
+			data:    map[string]string{NodeTaintsKey: "os=Windows"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid kubeletConfig",
+			data:    map[string]string{KubeletConfigKey: "- maxPods"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid kubeletFeatureGates",
+			data:    map[string]string{KubeletFeatureGatesKey: "A=yes"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid systemReserved",
+			data:    map[string]string{SystemReservedKey: "memory=lots"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid kubeReserved",
+			data:    map[string]string{KubeReservedKey: "memory"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid evictionHard",
+			data:    map[string]string{EvictionHardKey: "nodefs.available=110%"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid evictionSoftGracePeriod",
+			data:    map[string]string{EvictionSoftGracePeriodKey: "memory.available=soon"},
+			wantErr: true,
+		},
+		{
+			name:    "evictionSoft without grace period",
+			data:    map[string]string{EvictionSoftKey: "memory.available=1Gi"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid containerLogMaxSize",
+			data:    map[string]string{ContainerLogMaxSizeKey: "big"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid containerLogMaxFiles",
+			data:    map[string]string{ContainerLogMaxFilesKey: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid kubeletRootDir",
+			data:    map[string]string{KubeletRootDirKey: `D:\kubelet data`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid dataDisks number",
+			data:    map[string]string{DataDisksKey: "0=D:"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid dataDisks path",
+			data:    map[string]string{DataDisksKey: "1=C:"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pagefileSize",
+			data:    map[string]string{PagefileSizeKey: "Auto"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid overlayMTU",
+			data:    map[string]string{OverlayMTUKey: "100"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid dnsServers",
+			data:    map[string]string{DNSServersKey: "10.0.0.2,dns.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid dnsSuffixSearchList",
+			data:    map[string]string{DNSSuffixSearchListKey: "example.com,-invalid"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid ntpServers",
+			data:    map[string]string{NTPServersKey: "time.example.com,time_server"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid clockSkewThreshold",
+			data:    map[string]string{ClockSkewThresholdKey: "100ms"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid shutdownGracePeriod",
+			data:    map[string]string{ShutdownGracePeriodKey: "1.5s"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid smokeTest",
+			data:    map[string]string{SmokeTestKey: "yes please"},
+			wantErr: true,
+		},
+		{
+			name:    "empty smokeTestImage",
+			data:    map[string]string{SmokeTestImageKey: " "},
+			wantErr: true,
+		},
+		{
+			name:    "invalid windowsUpdates",
+			data:    map[string]string{WindowsUpdatesKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid kubeProxyDSR",
+			data:    map[string]string{KubeProxyDSRKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid nodeIPCIDRs",
+			data:    map[string]string{NodeIPCIDRsKey: "10.0.0.0/16,10.1.0.0"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid problemDetection",
+			data:    map[string]string{ProblemDetectionKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid hnsNetworkRepair",
+			data:    map[string]string{HNSNetworkRepairKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid manageFirewallRules",
+			data:    map[string]string{ManageFirewallRulesKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid hardenNodes",
+			data:    map[string]string{HardenNodesKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "kubelet debugging handlers disabled in kubeletConfig",
+			data:    map[string]string{KubeletConfigKey: "enableDebuggingHandlers: false"},
+			wantErr: true,
+		},
+		{
+			name:    "kubelet debugging handlers disabled in kubeletArgs",
+			data:    map[string]string{KubeletArgsKey: "--v=2 --enable-debugging-handlers=false"},
+			wantErr: true,
+		},
+		{
+			name: "kubelet anonymous access enabled with kubeletAuthHardening",
+			data: map[string]string{KubeletAuthHardeningKey: "true",
+				KubeletConfigKey: "authentication:\n  anonymous:\n    enabled: true"},
+			wantErr: true,
+		},
+		{
+			name:    "kubelet authorization mode overridden with kubeletAuthHardening",
+			data:    map[string]string{KubeletAuthHardeningKey: "true", KubeletArgsKey: "--authorization-mode=AlwaysAllow"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid crashDumps",
+			data:    map[string]string{CrashDumpsKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "hardenNodes without managed firewall rules",
+			data:    map[string]string{HardenNodesKey: "true", ManageFirewallRulesKey: "false"},
+			wantErr: true,
+		},
+		{
+			name:    "passwordRotationInterval too short",
+			data:    map[string]string{PasswordRotationIntervalKey: "10m"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pauseImage",
+			data:    map[string]string{PauseImageKey: "registry.example.com/pause:3.4.1 --v=10"},
+			wantErr: true,
+		},
+		{
+			name:    "nodeLabels not allowed by kubelet",
+			data:    map[string]string{NodeLabelsKey: "node-role.kubernetes.io/infra="},
+			wantErr: true,
+		},
+		{
+			name:    "in-tree cloud provider with externalCloudProvider",
+			data:    map[string]string{ExternalCloudProviderKey: "true", KubeletArgsKey: "--cloud-provider=aws"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid nodeLabels value",
+			data:    map[string]string{NodeLabelsKey: "team=a b"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid prePullImages",
+			data:    map[string]string{PrePullImagesKey: "mcr.microsoft.com/pause:3.4.1,image;Restart-Computer"},
+			wantErr: true,
+		},
+		{
+			name:    "transferBandwidth too low",
+			data:    map[string]string{TransferBandwidthKey: "100Ki"},
+			wantErr: true,
+		},
+		{
+			name:    "connectTimeout too low",
+			data:    map[string]string{ConnectTimeoutKey: "30s"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid commandTimeout",
+			data:    map[string]string{CommandTimeoutKey: "5s"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid detectGPUs",
+			data:    map[string]string{DetectGPUsKey: "yes please"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid workloadWindowsBuilds",
+			data:    map[string]string{WorkloadWindowsBuildsKey: "ltsc2019"},
+			wantErr: true,
+		},
+		{
+			name:    "privateKeyMaxAge too short",
+			data:    map[string]string{PrivateKeyMaxAgeKey: "1h"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported sshCiphers",
+			data:    map[string]string{SSHCiphersKey: "aes256-ctr,blowfish-cbc"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid controllerLogLevels",
+			data:    map[string]string{ControllerLogLevelsKey: "node=Trace"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid controllerConcurrency",
+			data:    map[string]string{ControllerConcurrencyKey: "windowsmachine=0"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid dryRun",
+			data:    map[string]string{DryRunKey: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid kubeletArgs",
+			data:    map[string]string{KubeletArgsKey: "--v=4 -v=4"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.data, defaults)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+// TestOverride tests the Override function
+func TestOverride(t *testing.T) {
+	config := Default()
+	config.SSHUser = "core"
+	config.KubeletArgs = []string{"--v=2"}
+
+	got, err := Override(config, map[string]string{
+		RemediationStrategyAnnotation: "None",
+		KubeletArgsAnnotation:         "--v=4",
+		PinnedVersionAnnotation:       "2.0.0",
+		"unrelated":                   "value",
+	})
+	require.NoError(t, err)
+	want := config
+	want.RemediationStrategy = RemediationNone
+	want.KubeletArgs = []string{"--v=4"}
+	want.PinnedVersion = "2.0.0"
+	assert.Equal(t, want, *got)
+
+	got, err = Override(config, nil)
+	require.NoError(t, err)
+	assert.Equal(t, config, *got)
+
+	_, err = Override(config, map[string]string{RemediationStrategyAnnotation: "Reboot"})
+	assert.Error(t, err)
+}
+
+// TestKubeletSettings tests the KubeletSettings method
+func TestKubeletSettings(t *testing.T) {
+	config := Default()
+	config.KubeletArgs = []string{"--v=4"}
+	config.KubeletConfig = map[string]interface{}{
+		"maxPods":      float64(100),
+		"featureGates": map[string]interface{}{"A": false, "C": true},
+	}
+	config.KubeletFeatureGates = map[string]bool{"A": true, "B": false}
+	config.SystemReserved = map[string]string{"memory": "2Gi"}
+	config.ContainerLogMaxFiles = 3
+	config.KubeletRootDir = `D:\kubelet`
+	config.PauseImage = "registry.example.com/pause:3.4.1"
+
+	settings := config.KubeletSettings("10.0.0.5")
+	assert.Equal(t, []string{"--pod-infra-container-image=registry.example.com/pause:3.4.1", `--root-dir=D:\kubelet`,
+		"--v=4"}, settings.Args)
+	assert.Equal(t, map[string]interface{}{
+		"maxPods":              float64(100),
+		"featureGates":         map[string]interface{}{"A": true, "B": false, "C": true},
+		"systemReserved":       map[string]interface{}{"memory": "2Gi"},
+		"containerLogMaxFiles": float64(3),
+	}, settings.Config)
+	// The configuration is not modified
+	assert.Equal(t, false, config.KubeletConfig["featureGates"].(map[string]interface{})["A"])
+}
+
+// TestKubeletSettingsAuthHardening tests that the authentication hardening is merged into the kubelet configuration
+func TestKubeletSettingsAuthHardening(t *testing.T) {
+	config := Default()
+	config.KubeletConfig = map[string]interface{}{
+		"authentication": map[string]interface{}{"webhook": map[string]interface{}{"cacheTTL": "2m"}},
+	}
+	config.KubeletAuthHardening = true
+
+	assert.Equal(t, map[string]interface{}{
+		"authentication": map[string]interface{}{
+			"anonymous": map[string]interface{}{"enabled": false},
+			"webhook":   map[string]interface{}{"cacheTTL": "2m", "enabled": true},
+		},
+		"authorization": map[string]interface{}{"mode": "Webhook"},
+	}, config.KubeletSettings("").Config)
+	// The configuration is not modified
+	assert.Equal(t, map[string]interface{}{"cacheTTL": "2m"},
+		config.KubeletConfig["authentication"].(map[string]interface{})["webhook"])
+}
+
+// TestKubeletSettingsEmpty tests that the default configuration does not change the kubelet configuration
+func TestKubeletSettingsEmpty(t *testing.T) {
+	config := Default()
+	assert.True(t, config.KubeletSettings("").IsEmpty())
+}
+
+// TestKubeletSettingsNodeIP tests that the node IP is only given to kubelet when it is chosen by CIDR
+func TestKubeletSettingsNodeIP(t *testing.T) {
+	config := Default()
+	config.KubeletArgs = []string{"--v=4"}
+	assert.Equal(t, []string{"--v=4"}, config.KubeletSettings("10.0.0.5").Args)
+
+	config.NodeIPCIDRs = []string{"10.0.0.0/16"}
+	assert.Equal(t, []string{"--node-ip=10.0.0.5", "--v=4"}, config.KubeletSettings("10.0.0.5").Args)
+}
+
+// TestManagesMachine tests the ManagesMachine function
+func TestManagesMachine(t *testing.T) {
+	config := Default()
+	assert.True(t, config.ManagesMachine(map[string]string{"team": "c"}))
+
+	config.MachineSelector = mustParseSelector(t, "team in (a,b)")
+	assert.True(t, config.ManagesMachine(map[string]string{"team": "a"}))
+	assert.False(t, config.ManagesMachine(map[string]string{"team": "c"}))
+	assert.False(t, config.ManagesMachine(nil))
+}
+
+func TestControllerOptions(t *testing.T) {
+	config := Default()
+	config.ControllerConcurrency = map[string]int{"windowsmachine": 4}
+	config.RateLimiter = RateLimiter{BaseDelay: time.Second, MaxDelay: 3 * time.Second, QPS: 10, Burst: 100}
+	options := config.ControllerOptions("windowsmachine")
+	assert.Equal(t, 4, options.MaxConcurrentReconciles)
+	// The delay of an object doubles at each failure up to the maximum delay
+	var delays []time.Duration
+	for i := 0; i < 4; i++ {
+		delays = append(delays, options.RateLimiter.When("machine"))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}, delays)
+	assert.Equal(t, 1, config.ControllerOptions("node").MaxConcurrentReconciles)
+}
+
+// mustParseSelector returns the parsed label selector, failing the test if it is invalid
+func mustParseSelector(t *testing.T, selector string) labels.Selector {
+	parsed, err := labels.Parse(selector)
+	require.NoError(t, err)
+	return parsed
+}
@@ -0,0 +1,32 @@
+package retry
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Poll calls the given condition every Interval, starting after Interval, until it returns true or an error, Timeout
+// elapses, or the given context is done. The error of the context is returned once it is done.
+func Poll(ctx context.Context, condition wait.ConditionFunc) error {
+	return poll(ctx, wait.PollUntil, condition)
+}
+
+// PollImmediate calls the given condition immediately and then every Interval, until it returns true or an error,
+// Timeout elapses, or the given context is done. The error of the context is returned once it is done.
+func PollImmediate(ctx context.Context, condition wait.ConditionFunc) error {
+	return poll(ctx, wait.PollImmediateUntil, condition)
+}
+
+// poll polls the given condition with the given polling function, until Timeout elapses or the given context is done
+func poll(ctx context.Context, pollUntil func(time.Duration, wait.ConditionFunc, <-chan struct{}) error,
+	condition wait.ConditionFunc) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	err := pollUntil(Interval, condition, timeoutCtx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package secrets
 
 import (
 	"context"
+	"strings"
 
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
@@ -22,6 +23,12 @@ const (
 	PrivateKeySecret = "cloud-private-key"
 	// PrivateKeySecretKey is the key within the private key secret which holds the private key
 	PrivateKeySecretKey = "private-key.pem"
+	// SSHCAKeySecretKey is the optional key within the private key secret which holds the private key of the SSH
+	// certificate authority trusted by the Windows instances
+	SSHCAKeySecretKey = "ssh-ca-key.pem"
+	// PreviousPrivateKeySecretKey is the optional key within the private key secret which holds the private key it
+	// held before the last rotation, still accepted to reach the instances configured with it
+	PreviousPrivateKeySecretKey = "previous-private-key.pem"
 )
 
 // GetPrivateKey fetches the specified secret and extracts the private key data
@@ -34,26 +41,88 @@ func GetPrivateKey(secret kubeTypes.NamespacedName, c client.Client) ([]byte, er
 	}
 	privateKey, ok := privateKeySecret.Data[PrivateKeySecretKey]
 	if !ok {
-		return []byte{}, errors.New("cloud-private-key missing 'private-key.pem' secret")
+		return []byte{}, errors.Errorf("%s missing '%s' secret", secret.Name, PrivateKeySecretKey)
 	}
 	return privateKey, nil
 }
 
-// GenerateUserData generates the desired value of userdata secret.
-func GenerateUserData(privateKey []byte) (*core.Secret, error) {
+// UserDataSecretName returns the name of the userData secret generated from the private key secret with the given
+// name: the userData secret shared by the Windows MachineSets for the cloud-private-key secret, else a userData secret
+// dedicated to the MachineSets using the private key secret
+func UserDataSecretName(privateKeySecret string) string {
+	if privateKeySecret == PrivateKeySecret {
+		return userDataSecret
+	}
+	return userDataSecret + "-" + privateKeySecret
+}
+
+// PrivateKeySecretName returns the name of the private key secret the userData secret with the given name is
+// generated from, empty if the name is not the name of a userData secret
+func PrivateKeySecretName(userDataSecretName string) string {
+	if userDataSecretName == userDataSecret {
+		return PrivateKeySecret
+	}
+	if !strings.HasPrefix(userDataSecretName, userDataSecret+"-") {
+		return ""
+	}
+	return strings.TrimPrefix(userDataSecretName, userDataSecret+"-")
+}
+
+// GetSSHCAKey fetches the specified secret and extracts the private key of the SSH certificate authority, which is
+// nil if the secret does not hold one
+func GetSSHCAKey(secret kubeTypes.NamespacedName, c client.Client) ([]byte, error) {
+	return getOptionalKey(secret, c, SSHCAKeySecretKey)
+}
+
+// GetPreviousPrivateKey fetches the specified secret and extracts the previous private key, which is nil if the
+// secret does not hold one
+func GetPreviousPrivateKey(secret kubeTypes.NamespacedName, c client.Client) ([]byte, error) {
+	return getOptionalKey(secret, c, PreviousPrivateKeySecretKey)
+}
+
+// getOptionalKey fetches the specified secret and extracts the data of the given key, which is nil if the secret
+// does not hold it
+func getOptionalKey(secret kubeTypes.NamespacedName, c client.Client, key string) ([]byte, error) {
+	privateKeySecret := &core.Secret{}
+	if err := c.Get(context.TODO(), secret, privateKeySecret); err != nil {
+		return nil, err
+	}
+	return privateKeySecret.Data[key], nil
+}
+
+// GenerateUserData generates the desired value of userdata secret. When the private key of an SSH certificate
+// authority is given, the instances trust the certificates it issues rather than the public key of the private key.
+func GenerateUserData(privateKey, caKey []byte) (*core.Secret, error) {
 	keySigner, err := signer.Create(privateKey)
 	if err != nil {
 		return nil, err
 	}
+	if caKey != nil {
+		caSigner, err := signer.Create(caKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid SSH certificate authority")
+		}
+		// The TrustedUserCAKeys option is prepended to sshd_config, as the Match block ending the default
+		// configuration would otherwise scope it to the administrators
+		return generateUserData("trusted_user_ca_keys", ssh.MarshalAuthorizedKey(caSigner.PublicKey()),
+			`			$caConf = @("TrustedUserCAKeys __PROGRAMDATA__/ssh/trusted_user_ca_keys") + (Get-Content -path C:\ProgramData\ssh\sshd_config)
+			$caConf | Set-Content -Path C:\ProgramData\ssh\sshd_config
+`), nil
+	}
 
 	pubKeyBytes := ssh.MarshalAuthorizedKey(keySigner.PublicKey())
 	if pubKeyBytes == nil {
 		return nil, errors.Errorf("failed to retrieve public key using signer")
 	}
+	return generateUserData("administrators_authorized_keys", pubKeyBytes, ""), nil
+}
 
-	// sshd service is started to create the default sshd_config file. This file is modified
-	// for enabling publicKey auth and the service is restarted for the changes to take effect.
-	userDataSecret := &core.Secret{
+// generateUserData generates the userData secret writing the given public key to the given file of the sshd
+// configuration directory, readable by the administrators and SYSTEM only, and adding the given lines to the
+// configuration of sshd. sshd service is started to create the default sshd_config file. This file is modified
+// for enabling publicKey auth and the service is restarted for the changes to take effect.
+func generateUserData(keyFile string, pubKeyBytes []byte, sshdConfig string) *core.Secret {
+	return &core.Secret{
 		ObjectMeta: meta.ObjectMeta{
 			Name:      userDataSecret,
 			Namespace: userDataNamespace,
@@ -70,10 +139,10 @@ func GenerateUserData(privateKey []byte) (*core.Secret, error) {
 			$pubKeyConf | Set-Content -Path C:\ProgramData\ssh\sshd_config
  			$passwordConf = (Get-Content -path C:\ProgramData\ssh\sshd_config) -replace '#PasswordAuthentication yes','PasswordAuthentication yes'
 			$passwordConf | Set-Content -Path C:\ProgramData\ssh\sshd_config
-			$authorizedKeyFilePath = "$env:ProgramData\ssh\administrators_authorized_keys"
+			$authorizedKeyFilePath = "$env:ProgramData\ssh\` + keyFile + `"
 			New-Item -Force $authorizedKeyFilePath
 			echo "` + string(pubKeyBytes[:]) + `"| Out-File $authorizedKeyFilePath -Encoding ascii
-			$acl = Get-Acl C:\ProgramData\ssh\administrators_authorized_keys
+` + sshdConfig + `			$acl = Get-Acl C:\ProgramData\ssh\` + keyFile + `
 			$acl.SetAccessRuleProtection($true, $false)
 			$administratorsRule = New-Object system.security.accesscontrol.filesystemaccessrule("Administrators","FullControl","Allow")
 			$systemRule = New-Object system.security.accesscontrol.filesystemaccessrule("SYSTEM","FullControl","Allow")
@@ -85,6 +154,4 @@ func GenerateUserData(privateKey []byte) (*core.Secret, error) {
 			<persist>true</persist>`),
 		},
 	}
-
-	return userDataSecret, nil
 }
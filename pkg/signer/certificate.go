@@ -0,0 +1,71 @@
+package signer
+
+import (
+	"crypto/rand"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// certificateValidity is the time during which the SSH certificates issued by WMCO can be used to authenticate.
+	// It only needs to cover the establishment of the connections of a reconciliation.
+	certificateValidity = time.Hour
+	// certificateClockSkew is the time the SSH certificates issued by WMCO are backdated by, so that they are
+	// accepted by instances whose clock is behind
+	certificateClockSkew = 5 * time.Minute
+)
+
+// Certify returns a signer authenticating with a short-lived SSH certificate of the public key of the given signer,
+// issued by the given certificate authority to the given user
+func Certify(keySigner, ca ssh.Signer, user string) (ssh.Signer, error) {
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             keySigner.PublicKey(),
+		CertType:        ssh.UserCert,
+		KeyId:           "windows-machine-config-operator",
+		ValidPrincipals: []string{user},
+		ValidAfter:      uint64(now.Add(-certificateClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(certificateValidity).Unix()),
+		Permissions: ssh.Permissions{Extensions: map[string]string{
+			"permit-pty": "",
+		}},
+	}
+	if err := cert.SignCert(rand.Reader, sha2Signer(ca)); err != nil {
+		return nil, errors.Wrap(err, "unable to sign SSH certificate")
+	}
+	certSigner, err := ssh.NewCertSigner(cert, keySigner)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create SSH certificate signer")
+	}
+	return certSigner, nil
+}
+
+// TrustedKey returns the public key trusted by the Windows instances for SSH authentication: the public key of the
+// given certificate authority if there is one, else the public key of the given signer
+func TrustedKey(keySigner, ca ssh.Signer) ssh.PublicKey {
+	if ca != nil {
+		return ca.PublicKey()
+	}
+	return keySigner.PublicKey()
+}
+
+// rsaSHA2Signer signs with SHA-512 on RSA keys, as OpenSSH rejects certificates signed by RSA authorities with SHA-1
+type rsaSHA2Signer struct {
+	ssh.AlgorithmSigner
+}
+
+// Sign signs the given data with the rsa-sha2-512 algorithm
+func (s rsaSHA2Signer) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, ssh.SigAlgoRSASHA2512)
+}
+
+// sha2Signer returns the given signer, signing with SHA-512 if it holds an RSA key
+func sha2Signer(ca ssh.Signer) ssh.Signer {
+	if algorithmSigner, ok := ca.(ssh.AlgorithmSigner); ok && ca.PublicKey().Type() == ssh.KeyAlgoRSA {
+		return rsaSHA2Signer{algorithmSigner}
+	}
+	return ca
+}
@@ -0,0 +1,48 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// TestCertify tests that the certificates issued by Certify are accepted by a certificate checker trusting the
+// certificate authority, for the given user only
+func TestCertify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keySigner, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	rsaCA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaCA, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	for _, caKey := range []interface{}{rsaCA, ecdsaCA} {
+		ca, err := ssh.NewSignerFromKey(caKey)
+		require.NoError(t, err)
+		t.Run(ca.PublicKey().Type(), func(t *testing.T) {
+			certSigner, err := Certify(keySigner, ca, "Administrator")
+			require.NoError(t, err)
+			cert, ok := certSigner.PublicKey().(*ssh.Certificate)
+			require.True(t, ok)
+			if ca.PublicKey().Type() == ssh.KeyAlgoRSA {
+				assert.Equal(t, ssh.SigAlgoRSASHA2512, cert.Signature.Format)
+			}
+
+			checker := &ssh.CertChecker{IsUserAuthority: func(auth ssh.PublicKey) bool {
+				return string(auth.Marshal()) == string(ca.PublicKey().Marshal())
+			}}
+			assert.NoError(t, checker.CheckCert("Administrator", cert))
+			assert.Error(t, checker.CheckCert("capi", cert))
+			assert.Equal(t, ca.PublicKey(), TrustedKey(keySigner, ca))
+		})
+	}
+	assert.Equal(t, keySigner.PublicKey(), TrustedKey(keySigner, nil))
+}
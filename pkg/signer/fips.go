@@ -0,0 +1,18 @@
+package signer
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// ValidateFIPS returns an error if the given public key cannot be used for FIPS compliant SSH authentication. Only
+// ECDSA keys are accepted, as the SSH client signs with SHA-1 when authenticating with RSA keys, and Ed25519 and DSA
+// are not FIPS approved.
+func ValidateFIPS(publicKey ssh.PublicKey) error {
+	switch publicKey.Type() {
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		return nil
+	default:
+		return errors.Errorf("%s keys are not supported in FIPS mode, an ECDSA key is required", publicKey.Type())
+	}
+}
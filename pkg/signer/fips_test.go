@@ -0,0 +1,28 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// TestValidateFIPS tests the ValidateFIPS function
+func TestValidateFIPS(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaPublicKey, err := ssh.NewPublicKey(&ecdsaKey.PublicKey)
+	require.NoError(t, err)
+	assert.NoError(t, ValidateFIPS(ecdsaPublicKey))
+
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ed25519PublicKey, err := ssh.NewPublicKey(ed25519Key)
+	require.NoError(t, err)
+	assert.Error(t, ValidateFIPS(ed25519PublicKey))
+}
@@ -0,0 +1,78 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// antivirusExclusionPaths are the directories of the container runtime and of the Kubernetes components which the
+	// real-time protection of Microsoft Defender Antivirus must exclude, as scanning them slows down the containers
+	antivirusExclusionPaths = []string{"C:\\ProgramData\\docker", strings.TrimSuffix(k8sDir, "\\")}
+	// antivirusExclusionProcesses are the processes of the container runtime and of the Kubernetes components whose
+	// file accesses Microsoft Defender Antivirus must exclude
+	antivirusExclusionProcesses = []string{"dockerd.exe", "kubelet.exe", "kube-proxy.exe", "hybrid-overlay-node.exe"}
+)
+
+// Antivirus describes the status of Microsoft Defender Antivirus on the VM
+type Antivirus struct {
+	// Defender is false when Microsoft Defender Antivirus is not installed or not running, such as when it is
+	// replaced by a third-party antivirus
+	Defender bool
+	// RealTimeProtection is true when the real-time protection is enabled
+	RealTimeProtection bool
+	// SignaturesUpdated is the time at which the antivirus signatures were last updated
+	SignaturesUpdated time.Time
+	// MissingExclusions are the paths and processes which must be excluded from the real-time protection but are not
+	MissingExclusions []string
+}
+
+func (vm *windows) Antivirus() (*Antivirus, error) {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"if (-not (Get-Command Get-MpComputerStatus -ErrorAction SilentlyContinue)) { 'defender False' } else { " +
+		"$status = Get-MpComputerStatus; 'defender ' + $status.AMServiceEnabled; " +
+		"'realtime ' + $status.RealTimeProtectionEnabled; " +
+		"'signatures ' + $status.AntivirusSignatureLastUpdated.ToUniversalTime().ToString('o'); " +
+		"$preference = Get-MpPreference; " +
+		"foreach ($path in @(" + psList(antivirusExclusionPaths) + ")) { " +
+		"if ($preference.ExclusionPath -notcontains $path) { 'missing ' + $path } }; " +
+		"foreach ($process in @(" + psList(antivirusExclusionProcesses) + ")) { " +
+		"if ($preference.ExclusionProcess -notcontains $process) { 'missing ' + $process } } }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting antivirus status with output: %s", out)
+	}
+	return parseAntivirus(out)
+}
+
+// parseAntivirus parses the antivirus status reported one item per line, as "defender <bool>", "realtime <bool>",
+// "signatures <RFC 3339 time>" and "missing <exclusion>" lines
+func parseAntivirus(out string) (*Antivirus, error) {
+	antivirus := &Antivirus{}
+	for _, line := range parseChanges(out) {
+		tokens := strings.SplitN(line, " ", 2)
+		if len(tokens) != 2 {
+			return nil, errors.Errorf("unexpected antivirus output %q", line)
+		}
+		var err error
+		switch tokens[0] {
+		case "defender":
+			antivirus.Defender, err = strconv.ParseBool(tokens[1])
+		case "realtime":
+			antivirus.RealTimeProtection, err = strconv.ParseBool(tokens[1])
+		case "signatures":
+			antivirus.SignaturesUpdated, err = time.Parse(time.RFC3339Nano, tokens[1])
+		case "missing":
+			antivirus.MissingExclusions = append(antivirus.MissingExclusions, tokens[1])
+		default:
+			return nil, errors.Errorf("unexpected antivirus output %q", line)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid antivirus status %q", line)
+		}
+	}
+	return antivirus, nil
+}
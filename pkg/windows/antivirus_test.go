@@ -0,0 +1,26 @@
+package windows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAntivirus tests the parseAntivirus function
+func TestParseAntivirus(t *testing.T) {
+	antivirus, err := parseAntivirus("defender True\r\nrealtime True\r\nsignatures 2021-06-01T08:30:00.0000000Z\r\n" +
+		"missing C:\\k\r\nmissing kubelet.exe\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &Antivirus{Defender: true, RealTimeProtection: true,
+		SignaturesUpdated: time.Date(2021, 6, 1, 8, 30, 0, 0, time.UTC),
+		MissingExclusions: []string{"C:\\k", "kubelet.exe"}}, antivirus)
+
+	antivirus, err = parseAntivirus("defender False\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &Antivirus{}, antivirus)
+
+	_, err = parseAntivirus("defender maybe\r\n")
+	assert.Error(t, err)
+}
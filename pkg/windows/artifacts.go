@@ -0,0 +1,139 @@
+package windows
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	// DefaultPauseImage is the pause image of the pod sandboxes when kubelet is not given one
+	DefaultPauseImage = "mcr.microsoft.com/oss/kubernetes/pause:3.4.1"
+	// dockerHubRegistry is the registry the images whose name does not start with a registry host are pulled from
+	dockerHubRegistry = "registry-1.docker.io"
+	// artifactConnectTimeoutMs is the time, in milliseconds, a connection from the VM to an artifact source is given
+	// to be established
+	artifactConnectTimeoutMs = 5000
+)
+
+// artifactSource is an endpoint the VM retrieves artifacts from while it is configured, or once it runs workloads
+type artifactSource struct {
+	// description names the source and the artifacts retrieved from it
+	description string
+	host        string
+	port        int
+}
+
+// imageRegistry returns the host and port of the registry the given image is pulled from
+func imageRegistry(image string) (string, int) {
+	registry := dockerHubRegistry
+	if slash := strings.Index(image, "/"); slash > 0 {
+		first := image[:slash]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			registry = first
+		}
+	}
+	if host, port, err := net.SplitHostPort(registry); err == nil {
+		if p, err := strconv.Atoi(port); err == nil {
+			return host, p
+		}
+	}
+	return registry, 443
+}
+
+// artifactSources returns the sources of the artifacts the VM retrieves over the network: the worker ignition of the
+// Machine Config Server and the registries of the given images. The payload binaries are transferred by WMCO and are
+// not retrieved by the VM. The sources retrieved through the given proxy, nil if the cluster has none, are replaced by
+// the proxy.
+func (vm *windows) artifactSources(images []string, proxy *httpproxy.Config) ([]artifactSource, error) {
+	ignitionURL, err := url.Parse(vm.workerIgnitionEndpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid ignition endpoint %s", vm.workerIgnitionEndpoint)
+	}
+	ignitionPort, err := strconv.Atoi(ignitionURL.Port())
+	if err != nil {
+		ignitionPort = 443
+	}
+	sources := []artifactSource{{description: "Machine Config Server " + ignitionURL.Host + " (worker ignition)",
+		host: ignitionURL.Hostname(), port: ignitionPort}}
+
+	registryImages := make(map[string][]string)
+	for _, image := range images {
+		host, port := imageRegistry(image)
+		endpoint := net.JoinHostPort(host, strconv.Itoa(port))
+		if !containsString(registryImages[endpoint], image) {
+			registryImages[endpoint] = append(registryImages[endpoint], image)
+		}
+	}
+	var endpoints []string
+	for endpoint := range registryImages {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		host, port, _ := net.SplitHostPort(endpoint)
+		p, _ := strconv.Atoi(port)
+		sources = append(sources, artifactSource{description: "registry " + endpoint + " (" +
+			strings.Join(registryImages[endpoint], ", ") + ")", host: host, port: p})
+	}
+	if proxy == nil {
+		return sources, nil
+	}
+	proxyFunc := proxy.ProxyFunc()
+	for i, source := range sources {
+		proxyURL, err := proxyFunc(&url.URL{Scheme: "https",
+			Host: net.JoinHostPort(source.host, strconv.Itoa(source.port))})
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid cluster proxy")
+		}
+		if proxyURL == nil {
+			continue
+		}
+		port, err := strconv.Atoi(proxyURL.Port())
+		if err != nil {
+			port = 80
+			if proxyURL.Scheme == "https" {
+				port = 443
+			}
+		}
+		sources[i] = artifactSource{description: source.description + " through proxy " + proxyURL.Host,
+			host: proxyURL.Hostname(), port: port}
+	}
+	return sources, nil
+}
+
+func (vm *windows) UnreachableArtifacts(images []string, proxy *httpproxy.Config) ([]string, error) {
+	sources, err := vm.artifactSources(images, proxy)
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	for _, source := range sources {
+		entries = append(entries, "@{d="+psString(source.description)+";h="+psString(source.host)+";p="+
+			strconv.Itoa(source.port)+"}")
+	}
+	// A TCP connection is attempted rather than a request, as the sources may require authentication
+	cmd := "\"foreach ($s in @(" + strings.Join(entries, ",") + ")) { $c = New-Object Net.Sockets.TcpClient; " +
+		"try { if (-not $c.ConnectAsync($s.h, $s.p).Wait(" + strconv.Itoa(artifactConnectTimeoutMs) + ")) { $s.d } } " +
+		"catch { $s.d } finally { $c.Dispose() } }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error checking the artifact sources with output: %s", out)
+	}
+	return parseChanges(out), nil
+}
+
+// containsString returns true if the given list contains the given string
+func containsString(list []string, s string) bool {
+	for _, entry := range list {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
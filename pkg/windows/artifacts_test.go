@@ -0,0 +1,59 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http/httpproxy"
+)
+
+func TestImageRegistry(t *testing.T) {
+	tests := []struct {
+		image string
+		host  string
+		port  int
+	}{
+		{image: "busybox", host: dockerHubRegistry, port: 443},
+		{image: "library/busybox:1.33", host: dockerHubRegistry, port: 443},
+		{image: DefaultPauseImage, host: "mcr.microsoft.com", port: 443},
+		{image: "registry.example.com:5000/pause:3.4.1", host: "registry.example.com", port: 5000},
+		{image: "localhost/pause", host: "localhost", port: 443},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			host, port := imageRegistry(test.image)
+			assert.Equal(t, test.host, host)
+			assert.Equal(t, test.port, port)
+		})
+	}
+}
+
+func TestArtifactSources(t *testing.T) {
+	vm := &windows{workerIgnitionEndpoint: "https://api-int.example.com:22623/config/worker"}
+	sources, err := vm.artifactSources([]string{"registry.example.com/pause:3.4.1", "busybox",
+		"registry.example.com/app:1.0", "registry.example.com/pause:3.4.1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []artifactSource{
+		{description: "Machine Config Server api-int.example.com:22623 (worker ignition)",
+			host: "api-int.example.com", port: 22623},
+		{description: "registry registry-1.docker.io:443 (busybox)", host: dockerHubRegistry, port: 443},
+		{description: "registry registry.example.com:443 (registry.example.com/pause:3.4.1, " +
+			"registry.example.com/app:1.0)", host: "registry.example.com", port: 443},
+	}, sources)
+}
+
+func TestArtifactSourcesThroughProxy(t *testing.T) {
+	vm := &windows{workerIgnitionEndpoint: "https://api-int.example.com:22623/config/worker"}
+	sources, err := vm.artifactSources([]string{"busybox", "registry.example.com/pause:3.4.1"},
+		&httpproxy.Config{HTTPSProxy: "http://proxy.example.com:3128", NoProxy: ".example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []artifactSource{
+		{description: "Machine Config Server api-int.example.com:22623 (worker ignition)",
+			host: "api-int.example.com", port: 22623},
+		{description: "registry registry-1.docker.io:443 (busybox) through proxy proxy.example.com:3128",
+			host: "proxy.example.com", port: 3128},
+		{description: "registry registry.example.com:443 (registry.example.com/pause:3.4.1)",
+			host: "registry.example.com", port: 443},
+	}, sources)
+}
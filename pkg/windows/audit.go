@@ -0,0 +1,37 @@
+package windows
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// auditLog records every command run and file transferred on Windows VMs, allowing the changes made by WMCO to the
+// hosts to be reviewed
+var auditLog = ctrl.Log.WithName("audit")
+
+// audit records the given operation on the VM, started at the given time, along with its outcome
+func (vm *windows) audit(operation, target string, start time.Time, err error) {
+	keysAndValues := []interface{}{"machine", vm.machineName, "instanceID", vm.id, "operation", operation,
+		"target", target, "start", start.UTC().Format(time.RFC3339Nano), "duration", time.Since(start).String(),
+		"exitStatus", exitStatus(err)}
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err.Error())
+	}
+	auditLog.Info(operation, keysAndValues...)
+}
+
+// exitStatus returns the exit status of a remote operation which returned the given error. -1 is returned when the
+// operation failed without an exit status, such as when the connection was lost.
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+	return -1
+}
@@ -0,0 +1,14 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExitStatus tests the exitStatus function
+func TestExitStatus(t *testing.T) {
+	assert.Equal(t, 0, exitStatus(nil))
+	assert.Equal(t, -1, exitStatus(errors.New("connection lost")))
+}
@@ -0,0 +1,46 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// administratorsAuthorizedKeys is the remote location of the public keys authorized to connect as an administrator,
+// written by the userData
+const administratorsAuthorizedKeys = "$env:ProgramData\\ssh\\administrators_authorized_keys"
+
+// AuthorizeKey adds the given public key to the public keys authorized to connect as an administrator, unless it is
+// already authorized. The file is appended to in place, so that the access rules restricting it to the administrators
+// and SYSTEM, required by sshd, are kept.
+func (vm *windows) AuthorizeKey(publicKey ssh.PublicKey) error {
+	key := authorizedKey(publicKey)
+	cmd := "\"$ErrorActionPreference = 'Stop'; $k = '" + key + "'; if (-not (Get-Content -Path " +
+		administratorsAuthorizedKeys + " | Where-Object { $_.Trim().StartsWith($k) })) { Add-Content -Encoding ascii " +
+		"-Path " + administratorsAuthorizedKeys + " -Value $k }\""
+	if out, err := vm.Run(cmd, true); err != nil {
+		return errors.Wrapf(err, "error authorizing public key with output: %s", out)
+	}
+	vm.log.Info("authorized public key", "type", publicKey.Type())
+	return nil
+}
+
+// RevokeKey removes the given public key from the public keys authorized to connect as an administrator, rewriting the
+// file in place to keep its access rules
+func (vm *windows) RevokeKey(publicKey ssh.PublicKey) error {
+	key := authorizedKey(publicKey)
+	cmd := "\"$ErrorActionPreference = 'Stop'; $k = '" + key + "'; $keys = @(Get-Content -Path " +
+		administratorsAuthorizedKeys + " | Where-Object { -not $_.Trim().StartsWith($k) }); Set-Content " +
+		"-Encoding ascii -Path " + administratorsAuthorizedKeys + " -Value $keys\""
+	if out, err := vm.Run(cmd, true); err != nil {
+		return errors.Wrapf(err, "error revoking public key with output: %s", out)
+	}
+	vm.log.Info("revoked public key", "type", publicKey.Type())
+	return nil
+}
+
+// authorizedKey returns the given public key in the authorized_keys format, without a comment
+func authorizedKey(publicKey ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey)))
+}
@@ -0,0 +1,93 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AWS partitions, the groups of AWS regions sharing their endpoints domain and credentials
+const (
+	// awsPartition is the partition of the commercial AWS regions
+	awsPartition = "aws"
+	// awsUSGovPartition is the partition of the AWS GovCloud (US) regions
+	awsUSGovPartition = "aws-us-gov"
+	// awsChinaPartition is the partition of the AWS China regions, whose endpoints are in the amazonaws.com.cn domain
+	awsChinaPartition = "aws-cn"
+)
+
+const (
+	// cloudConfigPath is the location of the cloud provider configuration of kubelet on the VM
+	cloudConfigPath = k8sDir + "cloud.conf"
+	// cloudConfigArg is the kubelet argument giving the location of the cloud provider configuration
+	cloudConfigArg = "--cloud-config="
+)
+
+// awsRegionPartition returns the partition of the given AWS region
+func awsRegionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return awsUSGovPartition
+	case strings.HasPrefix(region, "cn-"):
+		return awsChinaPartition
+	default:
+		return awsPartition
+	}
+}
+
+// awsEC2Endpoint returns the endpoint of the EC2 service in the given AWS region, in the endpoints domain of its
+// partition
+func awsEC2Endpoint(region string) string {
+	domain := "amazonaws.com"
+	if awsRegionPartition(region) == awsChinaPartition {
+		domain = "amazonaws.com.cn"
+	}
+	return "https://ec2." + region + "." + domain
+}
+
+// awsCloudConfig returns the cloud provider configuration pointing the AWS cloud provider of kubelet to the given EC2
+// endpoint, or to the endpoint of the given region if empty, signing its requests for the region
+func awsCloudConfig(region, endpoint string) string {
+	if endpoint == "" {
+		endpoint = awsEC2Endpoint(region)
+	}
+	return "[Global]\n\n" +
+		"[ServiceOverride \"ec2\"]\n" +
+		"Service = ec2\n" +
+		"Region = " + region + "\n" +
+		"URL = " + endpoint + "\n" +
+		"SigningRegion = " + region + "\n"
+}
+
+// configureAWSPartition writes the cloud provider configuration overriding the EC2 endpoint to the VM, and points the
+// kubelet unit of the downloaded worker ignition to it, when the cluster runs in the AWS GovCloud or China partitions
+// or uses a custom EC2 endpoint. The AWS cloud provider of kubelet otherwise derives the EC2 endpoint from the region
+// in the commercial partition, failing to retrieve the addresses of the instance in the regions it does not know.
+func (vm *windows) configureAWSPartition() error {
+	if vm.host.ExternalCloudProvider || vm.host.AWSRegion == "" ||
+		(awsRegionPartition(vm.host.AWSRegion) == awsPartition && vm.host.AWSEC2Endpoint == "") {
+		return nil
+	}
+	config := awsCloudConfig(vm.host.AWSRegion, vm.host.AWSEC2Endpoint)
+	if err := vm.writeFile(cloudConfigPath, []byte(config)); err != nil {
+		return errors.Wrap(err, "error writing the AWS cloud provider configuration")
+	}
+	// The ignition is JSON, in which the backslashes of the Windows path would have to be escaped
+	arg := cloudConfigArg + strings.ReplaceAll(cloudConfigPath, "\\", "/")
+	cmd := "\"$ErrorActionPreference = 'Stop'; $ign = [IO.File]::ReadAllText(" + psString(workerIgnitionPath) + "); " +
+		"if ($ign -match '--cloud-config=[^\\s\\\\]*') { " +
+		"$ign = $ign -replace '--cloud-config=[^\\s\\\\]*', " + psString(arg) + " } " +
+		"elseif ($ign.Contains(" + psString(nodeLabelsArg) + ")) { $ign = $ign.Replace(" + psString(nodeLabelsArg) +
+		", " + psString(arg+" "+nodeLabelsArg) + ") } else { 'missing' }; " +
+		"[IO.File]::WriteAllText(" + psString(workerIgnitionPath) + ", $ign)\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error setting the cloud configuration in the worker ignition with output: %s", out)
+	}
+	if strings.TrimSpace(out) == "missing" {
+		return errors.New("the kubelet unit of the worker ignition has no argument to add the cloud configuration to")
+	}
+	vm.log.Info("configured the AWS cloud provider", "region", vm.host.AWSRegion,
+		"partition", awsRegionPartition(vm.host.AWSRegion), "config", cloudConfigPath)
+	return nil
+}
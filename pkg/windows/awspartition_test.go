@@ -0,0 +1,21 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSRegionPartition(t *testing.T) {
+	assert.Equal(t, awsPartition, awsRegionPartition("us-east-1"))
+	assert.Equal(t, awsUSGovPartition, awsRegionPartition("us-gov-west-1"))
+	assert.Equal(t, awsChinaPartition, awsRegionPartition("cn-northwest-1"))
+}
+
+func TestAWSCloudConfig(t *testing.T) {
+	assert.Equal(t, "[Global]\n\n[ServiceOverride \"ec2\"]\nService = ec2\nRegion = cn-north-1\n"+
+		"URL = https://ec2.cn-north-1.amazonaws.com.cn\nSigningRegion = cn-north-1\n",
+		awsCloudConfig("cn-north-1", ""))
+	assert.Contains(t, awsCloudConfig("us-gov-east-1", ""), "URL = https://ec2.us-gov-east-1.amazonaws.com\n")
+	assert.Contains(t, awsCloudConfig("us-gov-east-1", "https://ec2.example.com"), "URL = https://ec2.example.com\n")
+}
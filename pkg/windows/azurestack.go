@@ -0,0 +1,59 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// azureStackEndpointsIgnitionPath is the path, in the worker ignition, of the Azure Stack Hub endpoints file, which
+	// describes the Azure Resource Manager endpoints of the Azure Stack Hub environment to the in-tree cloud provider
+	azureStackEndpointsIgnitionPath = "/etc/kubernetes/azurestackcloud.json"
+	// azureStackEndpointsPath is the location of the Azure Stack Hub endpoints file on the VM
+	azureStackEndpointsPath = k8sDir + "azurestackcloud.json"
+	// azureEnvironmentFileVariable is the environment variable giving the location of the endpoints file to the cloud
+	// provider of kubelet
+	azureEnvironmentFileVariable = "AZURE_ENVIRONMENT_FILEPATH"
+)
+
+// configureAzureStackHub writes the Azure Stack Hub endpoints file of the worker ignition to the VM and points the
+// kubelet service to it, restarting kubelet when its environment changes, as the in-tree cloud provider can otherwise
+// only reach the endpoints of the public and sovereign Azure clouds. The other variables of the environment of the
+// service are kept.
+func (vm *windows) configureAzureStackHub() error {
+	if !vm.host.AzureStackHub {
+		return nil
+	}
+	files, err := vm.workerIgnitionFiles(azureStackEndpointsIgnitionPath)
+	if err != nil {
+		return errors.Wrap(err, "error reading the Azure Stack Hub endpoints from the worker ignition")
+	}
+	if err := vm.writeFile(azureStackEndpointsPath, files[azureStackEndpointsIgnitionPath]); err != nil {
+		return err
+	}
+	env := azureEnvironmentFileVariable + "=" + azureStackEndpointsPath
+	key := "HKLM:\\SYSTEM\\CurrentControlSet\\Services\\" + kubeletServiceName
+	out, err := vm.Run("\"$ErrorActionPreference = 'Stop'; $current = (Get-ItemProperty -Path "+psString(key)+
+		" -Name Environment -ErrorAction SilentlyContinue).Environment; "+
+		"if (@($current) -notcontains "+psString(env)+") { "+
+		"$kept = @($current | Where-Object { $_ -and $_ -notlike "+psString(azureEnvironmentFileVariable+"=*")+" }); "+
+		"Set-ItemProperty -Path "+psString(key)+" -Name Environment -Type MultiString -Value ($kept + "+
+		psString(env)+"); 'updated' }\"", true)
+	if err != nil {
+		return errors.Wrapf(err, "error setting the environment of the %s service with output: %s",
+			kubeletServiceName, out)
+	}
+	if strings.TrimSpace(out) != "updated" {
+		return nil
+	}
+	vm.log.Info("configured kubelet for Azure Stack Hub", "endpoints", azureStackEndpointsPath)
+	svc := &service{name: kubeletServiceName}
+	if err := vm.ensureServiceNotRunning(svc); err != nil {
+		return errors.Wrapf(err, "error stopping %s service", kubeletServiceName)
+	}
+	if err := vm.startService(svc); err != nil {
+		return errors.Wrapf(err, "error starting %s service", kubeletServiceName)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package windows
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// bootstrapKubeconfigPath is the location of the kubeconfig written by the bootstrapper, used by kubelet to request a
+// new client certificate when its current one expired
+const bootstrapKubeconfigPath = k8sDir + "bootstrap-kubeconfig"
+
+func (vm *windows) BootstrapCredentialsExpiry() (time.Time, error) {
+	out, err := vm.Run("Get-Content -Raw "+bootstrapKubeconfigPath, true)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "error reading %s", bootstrapKubeconfigPath)
+	}
+	return credentialsExpiry([]byte(out))
+}
+
+func (vm *windows) RenewBootstrapCredentials() error {
+	files, err := vm.workerIgnitionFiles(ignitionKubeconfigPath)
+	if err != nil {
+		return err
+	}
+	// kubelet is not restarted, as it only reads the bootstrap kubeconfig once its client certificate expired
+	return vm.writeFile(bootstrapKubeconfigPath, files[ignitionKubeconfigPath])
+}
+
+// credentialsExpiry returns the earliest expiry of the client certificates and tokens of the users of the given
+// kubeconfig. The zero time is returned if none of the credentials expire, as for service account tokens.
+func credentialsExpiry(kubeconfig []byte) (time.Time, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid bootstrap kubeconfig")
+	}
+	var earliest time.Time
+	record := func(expiry time.Time) {
+		if !expiry.IsZero() && (earliest.IsZero() || expiry.Before(earliest)) {
+			earliest = expiry
+		}
+	}
+	for name, user := range config.AuthInfos {
+		if len(user.ClientCertificateData) > 0 {
+			expiry, err := certificateExpiry(user.ClientCertificateData)
+			if err != nil {
+				return time.Time{}, errors.Wrapf(err, "invalid client certificate of user %s", name)
+			}
+			record(expiry)
+		}
+		if user.Token != "" {
+			expiry, err := tokenExpiry(user.Token)
+			if err != nil {
+				return time.Time{}, errors.Wrapf(err, "invalid token of user %s", name)
+			}
+			record(expiry)
+		}
+	}
+	return earliest, nil
+}
+
+// certificateExpiry returns the expiry of the first certificate of the given PEM data
+func certificateExpiry(data []byte) (time.Time, error) {
+	cert, err := parseCertificate(data)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// parseCertificate returns the first certificate of the given PEM data, which may also hold private keys
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM encoded certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse certificate")
+		}
+		return cert, nil
+	}
+}
+
+// tokenExpiry returns the expiry of the given token if it is a JWT with an exp claim, else the zero time, as bootstrap
+// tokens and legacy service account tokens do not expire
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "unable to decode JWT payload")
+	}
+	var claims struct {
+		Expiry int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, errors.Wrap(err, "unable to parse JWT claims")
+	}
+	if claims.Expiry == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(claims.Expiry, 0), nil
+}
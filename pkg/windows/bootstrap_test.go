@@ -0,0 +1,62 @@
+package windows
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCredentialsExpiry tests the credentialsExpiry function
+func TestCredentialsExpiry(t *testing.T) {
+	jwt := func(claims string) string {
+		return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2lnbmF0dXJl"
+	}
+	const subject = `"sub":"system:serviceaccount:openshift-machine-config-operator:node-bootstrapper"`
+	kubeconfig := func(token string) []byte {
+		return []byte("apiVersion: v1\nkind: Config\nusers:\n- name: kubelet\n  user:\n    token: " + token + "\n")
+	}
+	testCases := []struct {
+		name       string
+		kubeconfig []byte
+		expected   time.Time
+		expectErr  bool
+	}{
+		{
+			name:       "expiring token",
+			kubeconfig: kubeconfig(jwt(`{` + subject + `,"exp":1700000000}`)),
+			expected:   time.Unix(1700000000, 0),
+		},
+		{
+			name:       "legacy service account token",
+			kubeconfig: kubeconfig(jwt(`{` + subject + `}`)),
+		},
+		{
+			name:       "bootstrap token",
+			kubeconfig: kubeconfig("abcdef.0123456789abcdef"),
+		},
+		{
+			name:       "invalid claims",
+			kubeconfig: kubeconfig(jwt("not json")),
+			expectErr:  true,
+		},
+		{
+			name:       "invalid kubeconfig",
+			kubeconfig: []byte("users: ["),
+			expectErr:  true,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			expiry, err := credentialsExpiry(test.kubeconfig)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expected.Equal(expiry), "expected %s, got %s", test.expected, expiry)
+		})
+	}
+}
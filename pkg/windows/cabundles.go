@@ -0,0 +1,153 @@
+package windows
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+const (
+	// kubeconfigPath is the location of the kubeconfig of kubelet, also used by kube-proxy and the hybrid overlay
+	kubeconfigPath = k8sDir + "kubeconfig"
+	// kubeletCAPath is the location of the CA bundle kubelet authenticates clients with, such as the API server
+	kubeletCAPath = k8sDir + "kubelet-ca.crt"
+)
+
+// CABundleServices returns the Windows services to restart, in the order they are stopped, for the given CA bundle
+// files of the VM, as returned by CABundleDrift, to be picked up. kubelet only reads its bootstrap kubeconfig when it
+// requests a new client certificate, so the bootstrap kubeconfig is updated without restarting any service.
+func CABundleServices(files []string) []string {
+	var kubelet, kubeconfig bool
+	for _, file := range files {
+		switch file {
+		case kubeletCAPath:
+			kubelet = true
+		case kubeconfigPath:
+			kubeconfig = true
+		}
+	}
+	var services []string
+	if kubeconfig {
+		services = append(services, kubeProxyServiceName, hybridOverlayServiceName)
+	}
+	if kubelet || kubeconfig {
+		services = append(services, kubeletServiceName)
+	}
+	return services
+}
+
+func (vm *windows) CABundleDrift() ([]string, error) {
+	return vm.syncCABundles(false)
+}
+
+func (vm *windows) UpdateCABundles() ([]string, error) {
+	return vm.syncCABundles(true)
+}
+
+// syncCABundles compares the CA bundles of the VM with the ones of the current worker ignition: the CA bundle kubelet
+// authenticates clients with, and the API server CA bundle of the kubeconfigs. Returns the files which differ, updating
+// them if apply is set, in which case the services reading them are restarted.
+func (vm *windows) syncCABundles(apply bool) ([]string, error) {
+	files, err := vm.workerIgnitionFiles(ignitionKubeconfigPath, ignitionKubeletCAPath)
+	if err != nil {
+		return nil, err
+	}
+	desiredCA, err := kubeconfigCA(files[ignitionKubeconfigPath])
+	if err != nil {
+		return nil, err
+	}
+	updates := make(map[string][]byte)
+	kubeletCA, err := vm.Run("Get-Content -Raw "+kubeletCAPath, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", kubeletCAPath)
+	}
+	if !bytes.Equal(bytes.TrimSpace([]byte(kubeletCA)), bytes.TrimSpace(files[ignitionKubeletCAPath])) {
+		updates[kubeletCAPath] = files[ignitionKubeletCAPath]
+	}
+	for _, path := range []string{kubeconfigPath, bootstrapKubeconfigPath} {
+		out, err := vm.Run("Get-Content -Raw "+path, true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", path)
+		}
+		updated, changed, err := setKubeconfigCA([]byte(out), desiredCA)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid kubeconfig %s", path)
+		}
+		if changed {
+			updates[path] = updated
+		}
+	}
+	var drift []string
+	for _, path := range []string{kubeletCAPath, kubeconfigPath, bootstrapKubeconfigPath} {
+		if _, present := updates[path]; present {
+			drift = append(drift, path)
+		}
+	}
+	if !apply || len(drift) == 0 {
+		return drift, nil
+	}
+
+	// The services are stopped while the files they read are replaced, and started again in the reverse order
+	services := CABundleServices(drift)
+	for _, name := range services {
+		if err := vm.ensureServiceNotRunning(&service{name: name}); err != nil {
+			return nil, errors.Wrapf(err, "error stopping %s service", name)
+		}
+	}
+	for _, path := range drift {
+		if err := vm.writeFile(path, updates[path]); err != nil {
+			return nil, err
+		}
+		vm.log.Info("CA bundle updated", "file", path)
+	}
+	for i := len(services) - 1; i >= 0; i-- {
+		if err := vm.startService(&service{name: services[i]}); err != nil {
+			return nil, errors.Wrapf(err, "error starting %s service", services[i])
+		}
+	}
+	return drift, nil
+}
+
+// kubeconfigCA returns the CA bundle of the cluster of the given kubeconfig
+func kubeconfigCA(kubeconfig []byte) ([]byte, error) {
+	config := &clientcmdv1.Config{}
+	if err := yaml.Unmarshal(kubeconfig, config); err != nil {
+		return nil, errors.Wrap(err, "invalid kubeconfig")
+	}
+	for _, cluster := range config.Clusters {
+		if len(cluster.Cluster.CertificateAuthorityData) > 0 {
+			return cluster.Cluster.CertificateAuthorityData, nil
+		}
+	}
+	return nil, errors.New("kubeconfig holds no CA bundle")
+}
+
+// setKubeconfigCA sets the given CA bundle on the clusters of the given kubeconfig. Returns the updated kubeconfig,
+// encoded as JSON which is valid YAML, and true if the CA bundle of any cluster changed.
+func setKubeconfigCA(kubeconfig, ca []byte) ([]byte, bool, error) {
+	config := &clientcmdv1.Config{}
+	if err := yaml.Unmarshal(kubeconfig, config); err != nil {
+		return nil, false, err
+	}
+	changed := false
+	for i := range config.Clusters {
+		cluster := &config.Clusters[i].Cluster
+		if bytes.Equal(cluster.CertificateAuthorityData, ca) {
+			continue
+		}
+		cluster.CertificateAuthorityData = ca
+		cluster.CertificateAuthority = ""
+		changed = true
+	}
+	if !changed {
+		return kubeconfig, false, nil
+	}
+	updated, err := json.Marshal(config)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to encode kubeconfig")
+	}
+	return updated, true, nil
+}
@@ -0,0 +1,44 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSetKubeconfigCA tests the setKubeconfigCA function
+func TestSetKubeconfigCA(t *testing.T) {
+	kubeconfig := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: local
+  cluster:
+    server: https://api-int.example.com:6443
+    certificate-authority-data: b2xkIENB
+users:
+- name: kubelet
+  user:
+    client-certificate: C:\var\lib\kubelet\pki\kubelet-client-current.pem
+`)
+	updated, changed, err := setKubeconfigCA(kubeconfig, []byte("old CA"))
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, kubeconfig, updated)
+
+	updated, changed, err = setKubeconfigCA(kubeconfig, []byte("new CA"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	ca, err := kubeconfigCA(updated)
+	require.NoError(t, err)
+	assert.Equal(t, "new CA", string(ca))
+	assert.Contains(t, string(updated), "kubelet-client-current.pem")
+}
+
+// TestCABundleServices tests the CABundleServices function
+func TestCABundleServices(t *testing.T) {
+	assert.Empty(t, CABundleServices([]string{bootstrapKubeconfigPath}))
+	assert.Equal(t, []string{kubeletServiceName}, CABundleServices([]string{kubeletCAPath, bootstrapKubeconfigPath}))
+	assert.Equal(t, []string{kubeProxyServiceName, hybridOverlayServiceName, kubeletServiceName},
+		CABundleServices([]string{kubeletCAPath, kubeconfigPath}))
+}
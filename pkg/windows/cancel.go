@@ -0,0 +1,51 @@
+package windows
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCancelled is returned by the operations on a VM once they are cancelled
+var ErrCancelled = errors.New("operations on the VM cancelled")
+
+func (vm *windows) Cancel() {
+	if !atomic.CompareAndSwapInt32(&vm.cancelled, 0, 1) {
+		return
+	}
+	vm.log.Info("cancelling operations")
+	// Closing the connection interrupts the command or file transfer in progress
+	if closer, ok := vm.interact.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			vm.log.V(1).Error(err, "error closing connection")
+		}
+	}
+}
+
+func (vm *windows) Close() error {
+	if closer, ok := vm.interact.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// isCancelled returns true once the operations on the VM are cancelled
+func (vm *windows) isCancelled() bool {
+	return atomic.LoadInt32(&vm.cancelled) == 1
+}
+
+// CancelOnDone cancels the operations on the given VM once the given context is done, such as when the operator shuts
+// down, until the returned function is called
+func CancelOnDone(ctx context.Context, vm Windows) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			vm.Cancel()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
@@ -0,0 +1,37 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// externalCloudProviderArg is the kubelet argument making kubelet leave the initialization of the node to the external
+// cloud controller manager of the cluster, tainting the node as uninitialized until it is done
+const externalCloudProviderArg = "--cloud-provider=external"
+
+// useExternalCloudProvider sets the cloud provider of the kubelet unit of the downloaded worker ignition to external,
+// if the external cloud provider is enabled in the host settings, so that the bootstrapper starts kubelet with it and
+// the node is registered uninitialized rather than initialized by the in-tree cloud provider
+func (vm *windows) useExternalCloudProvider() error {
+	if !vm.host.ExternalCloudProvider {
+		return nil
+	}
+	// The argument is added before the node labels when the kubelet unit has no cloud provider
+	cmd := "\"$ErrorActionPreference = 'Stop'; $ign = [IO.File]::ReadAllText(" + psString(workerIgnitionPath) + "); " +
+		"if ($ign -match '--cloud-provider=[A-Za-z-]*') { " +
+		"$ign = $ign -replace '--cloud-provider=[A-Za-z-]*', " + psString(externalCloudProviderArg) + " } " +
+		"elseif ($ign.Contains(" + psString(nodeLabelsArg) + ")) { $ign = $ign.Replace(" + psString(nodeLabelsArg) +
+		", " + psString(externalCloudProviderArg+" "+nodeLabelsArg) + ") } else { 'missing' }; " +
+		"[IO.File]::WriteAllText(" + psString(workerIgnitionPath) + ", $ign)\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error setting the external cloud provider in the worker ignition with output: %s",
+			out)
+	}
+	if strings.TrimSpace(out) == "missing" {
+		return errors.New("the kubelet unit of the worker ignition has no argument to add the cloud provider to")
+	}
+	vm.log.Info("registering node with the external cloud provider")
+	return nil
+}
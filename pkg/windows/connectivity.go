@@ -3,6 +3,8 @@ package windows
 import (
 	"fmt"
 	"io"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +22,34 @@ import (
 // sshPort is the default SSH port
 const sshPort = "22"
 
+// Algorithms supported by the SSH client
+var (
+	SupportedSSHCiphers = []string{"aes128-gcm@openssh.com", "chacha20-poly1305@openssh.com", "aes128-ctr",
+		"aes192-ctr", "aes256-ctr", "aes128-cbc", "3des-cbc", "arcfour256", "arcfour128", "arcfour"}
+	SupportedSSHMACs         = []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256", "hmac-sha1", "hmac-sha1-96"}
+	SupportedSSHKeyExchanges = []string{"curve25519-sha256@libssh.org", "ecdh-sha2-nistp256", "ecdh-sha2-nistp384",
+		"ecdh-sha2-nistp521", "diffie-hellman-group14-sha1", "diffie-hellman-group1-sha1"}
+)
+
+// SSHAlgorithms holds the algorithms, in order of preference, allowed for an SSH connection. The SSH client defaults
+// are used for empty lists.
+type SSHAlgorithms struct {
+	Ciphers      []string
+	MACs         []string
+	KeyExchanges []string
+}
+
+// Timeouts limits the time taken by the operations on a VM. Zero values select the defaults.
+type Timeouts struct {
+	// Connect is the time allowed to establish the SSH connection, retrying while the VM is still booting, which
+	// defaults to retry.Timeout
+	Connect time.Duration
+	// Command is the time allowed for a remote command to complete, unlimited by default
+	Command time.Duration
+	// Transfer is the time allowed for a file to be transferred, unlimited by default
+	Transfer time.Duration
+}
+
 // AuthErr occurs when our authentication into the VM is rejected
 type AuthErr struct {
 	err string
@@ -34,16 +64,7 @@ func newAuthErr(err error) *AuthErr {
 	return &AuthErr{err: err.Error()}
 }
 
-type connectivity interface {
-	// run executes the given command on the remote system
-	run(cmd string) (string, error)
-	// transfer copies the file from the local disk to the remote VM directory, creating the remote directory if needed
-	transfer(filePath, remoteDir string) error
-	// init initialises the connectivity medium
-	init() error
-}
-
-// sshConnectivity encapsulates the information needed to connect to the Windows VM over ssh
+// sshConnectivity is the RemoteHost connecting to the Windows VM over ssh
 type sshConnectivity struct {
 	// username is the user to connect to the VM
 	username string
@@ -51,27 +72,44 @@ type sshConnectivity struct {
 	ipAddress string
 	// signer is used for authenticating against the VM
 	signer ssh.Signer
+	// algorithms restricts the algorithms used for the SSH connection
+	algorithms SSHAlgorithms
+	// fips restricts the SSH connection to FIPS approved algorithms
+	fips bool
+	// proxy is the URL of the proxy the SSH connection is dialed through, holding its credentials, nil when dialed
+	// directly
+	proxy *url.URL
+	// transferBandwidth is the maximum throughput of file transfers in bytes per second, unlimited when zero
+	transferBandwidth int64
+	// timeouts limits the time taken to connect, run commands and transfer files
+	timeouts Timeouts
 	// sshClient is the client used to access the Windows VM via ssh
 	sshClient *ssh.Client
 	log       logr.Logger
 }
 
 // newSshConnectivity returns an instance of sshConnectivity
-func newSshConnectivity(username, ipAddress string, signer ssh.Signer, logger logr.Logger) (connectivity, error) {
+func newSshConnectivity(username, ipAddress string, signer ssh.Signer, algorithms SSHAlgorithms, fips bool,
+	proxy *url.URL, transferBandwidth int64, timeouts Timeouts, logger logr.Logger) (RemoteHost, error) {
 	c := &sshConnectivity{
-		username:  username,
-		ipAddress: ipAddress,
-		signer:    signer,
-		log:       logger,
+		username:          username,
+		ipAddress:         ipAddress,
+		signer:            signer,
+		algorithms:        algorithms,
+		fips:              fips,
+		proxy:             proxy,
+		transferBandwidth: transferBandwidth,
+		timeouts:          timeouts,
+		log:               logger,
 	}
-	if err := c.init(); err != nil {
+	if err := c.Init(); err != nil {
 		return nil, errors.Wrap(err, "error instantiating SSH client")
 	}
 	return c, nil
 }
 
-// init initialises the key based SSH client
-func (c *sshConnectivity) init() error {
+// Init initialises the key based SSH client
+func (c *sshConnectivity) Init() error {
 	if c.username == "" || c.ipAddress == "" || c.signer == nil {
 		return fmt.Errorf("incomplete sshConnectivity information: %v", c)
 	}
@@ -83,11 +121,31 @@ func (c *sshConnectivity) init() error {
 		},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 	}
+	config.Ciphers = c.algorithms.Ciphers
+	config.MACs = c.algorithms.MACs
+	config.KeyExchanges = c.algorithms.KeyExchanges
+	if c.fips {
+		var err error
+		if config.Ciphers, err = restrictAlgorithms(config.Ciphers, fipsCiphers); err != nil {
+			return errors.Wrap(err, "no FIPS approved SSH cipher allowed")
+		}
+		if config.MACs, err = restrictAlgorithms(config.MACs, fipsMACs); err != nil {
+			return errors.Wrap(err, "no FIPS approved SSH MAC algorithm allowed")
+		}
+		if config.KeyExchanges, err = restrictAlgorithms(config.KeyExchanges, fipsKeyExchanges); err != nil {
+			return errors.Wrap(err, "no FIPS approved SSH key exchange algorithm allowed")
+		}
+		config.HostKeyAlgorithms = fipsHostKeyAlgorithms
+	}
 	var err error
 	var sshClient *ssh.Client
+	connectTimeout := c.timeouts.Connect
+	if connectTimeout == 0 {
+		connectTimeout = retry.Timeout
+	}
 	// Retry if we are unable to create a client as the VM could still be executing the steps in its user data
-	err = wait.PollImmediate(time.Minute, retry.Timeout, func() (bool, error) {
-		sshClient, err = ssh.Dial("tcp", c.ipAddress+":"+sshPort, config)
+	err = wait.PollImmediate(time.Minute, connectTimeout, func() (bool, error) {
+		sshClient, err = dialSSH(c.proxy, net.JoinHostPort(c.ipAddress, sshPort), config)
 		if err == nil {
 			return true, nil
 		}
@@ -101,14 +159,19 @@ func (c *sshConnectivity) init() error {
 	if err != nil {
 		return errors.Wrapf(err, "unable to connect to Windows VM %s", c.ipAddress)
 	}
+	// The client replaced when reinitializing, such as after a reboot, is closed so that its connection is not leaked
+	if c.sshClient != nil {
+		c.sshClient.Close()
+	}
 	c.sshClient = sshClient
 	return nil
 }
 
-// run instantiates a new SSH session and runs the command on the VM and returns the combined stdout and stderr output
-func (c *sshConnectivity) run(cmd string) (string, error) {
+// RunCommand instantiates a new SSH session and runs the command on the VM and returns the combined stdout and stderr
+// output
+func (c *sshConnectivity) RunCommand(cmd string) (string, error) {
 	if c.sshClient == nil {
-		return "", errors.New("run cannot be called with nil SSH client")
+		return "", errors.New("RunCommand cannot be called with nil SSH client")
 	}
 
 	session, err := c.sshClient.NewSession()
@@ -123,17 +186,55 @@ func (c *sshConnectivity) run(cmd string) (string, error) {
 		}
 	}()
 
-	out, err := session.CombinedOutput(cmd)
+	var out []byte
+	// Closing the session makes the command return, and the SSH server terminate the remote process
+	err = withTimeout(c.timeouts.Command, func() error {
+		var err error
+		out, err = session.CombinedOutput(cmd)
+		return err
+	}, func() { session.Close() })
 	if err != nil {
 		return string(out), err
 	}
 	return string(out), nil
 }
 
-// transfer uses FTP to copy the file from the local disk to the remote VM directory, creating the directory if needed
-func (c *sshConnectivity) transfer(filePath, remoteDir string) error {
+// withTimeout runs the given operation, calling the given abort function if the operation does not complete within
+// the given timeout, which must make the operation return. The operation is not limited when the timeout is zero.
+func withTimeout(timeout time.Duration, operation func() error, abort func()) error {
+	if timeout == 0 {
+		return operation()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- operation()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		abort()
+		// The result of the aborted operation is discarded
+		<-done
+		return errors.Errorf("timed out after %s", timeout)
+	}
+}
+
+// Close closes the SSH client, interrupting the commands and file transfers in progress
+func (c *sshConnectivity) Close() error {
+	if c.sshClient == nil {
+		return nil
+	}
+	return c.sshClient.Close()
+}
+
+// CopyFile uses FTP to copy the file from the local disk to the remote VM directory, creating the directory if needed.
+// The throughput of the copy is limited to the transfer bandwidth.
+func (c *sshConnectivity) CopyFile(filePath, remoteDir string) error {
 	if c.sshClient == nil {
-		return errors.New("transfer cannot be called with nil SSH client")
+		return errors.New("CopyFile cannot be called with nil SSH client")
 	}
 
 	ftp, err := sftp.NewClient(c.sshClient)
@@ -166,7 +267,11 @@ func (c *sshConnectivity) transfer(filePath, remoteDir string) error {
 		return errors.Wrapf(err, "error initializing %s file on Windows VM", remoteFile)
 	}
 
-	_, err = io.Copy(dstFile, f)
+	// Closing the SFTP client makes the copy return
+	err = withTimeout(c.timeouts.Transfer, func() error {
+		_, err := io.Copy(dstFile, newThrottledReader(f, c.transferBandwidth))
+		return err
+	}, func() { ftp.Close() })
 	if err != nil {
 		return errors.Wrapf(err, "error copying %s to the Windows VM", filePath)
 	}
@@ -0,0 +1,23 @@
+package windows
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout(t *testing.T) {
+	operationErr := fmt.Errorf("failed")
+	assert.Equal(t, operationErr, withTimeout(0, func() error { return operationErr }, func() {}))
+	assert.Equal(t, operationErr, withTimeout(time.Minute, func() error { return operationErr }, func() {}))
+
+	// The operation only returns once aborted
+	abort := make(chan struct{})
+	err := withTimeout(10*time.Millisecond, func() error {
+		<-abort
+		return operationErr
+	}, func() { close(abort) })
+	assert.EqualError(t, err, "timed out after 10ms")
+}
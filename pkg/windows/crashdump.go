@@ -0,0 +1,80 @@
+package windows
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// crashDumpDir is the remote directory the crash dumps are written to. It is within the log directory so that the
+	// dumps can be retrieved through the kubelet logs endpoint.
+	crashDumpDir = logDir + "crash-dumps"
+	// kernelDumpFile is the remote location of the kernel memory dump written when the VM crashes
+	kernelDumpFile = crashDumpDir + "\\MEMORY.DMP"
+	// userDumpCount is the number of user-mode dumps kept, the oldest dumps being deleted first
+	userDumpCount = 10
+)
+
+// CrashDump is a crash dump found on the Windows VM
+type CrashDump struct {
+	// Name is the file name of the dump, relative to the crash dump directory
+	Name string
+	// Time is the time at which the dump was written
+	Time time.Time
+}
+
+// String returns the name and time of the dump, in a form which identifies the dump even if a file with the same name
+// is written again
+func (d CrashDump) String() string {
+	return d.Name + "@" + d.Time.UTC().Format(time.RFC3339)
+}
+
+// crashDumpRegistryValues returns the registry values configuring Windows to write a kernel memory dump and to
+// restart when the VM crashes, and to write user-mode dumps when a process crashes, in the crash dump directory. The
+// kernel dump settings take effect once the VM restarts.
+func crashDumpRegistryValues() []registryValue {
+	crashControl := "HKLM:\\System\\CurrentControlSet\\Control\\CrashControl"
+	localDumps := "HKLM:\\SOFTWARE\\Microsoft\\Windows\\Windows Error Reporting\\LocalDumps"
+	return []registryValue{
+		{key: crashControl, name: "CrashDumpEnabled", value: 2},
+		{key: crashControl, name: "AutoReboot", value: 1},
+		{key: crashControl, name: "Overwrite", value: 1},
+		{key: crashControl, name: "DumpFile", value: kernelDumpFile},
+		{key: localDumps, name: "DumpFolder", value: crashDumpDir},
+		{key: localDumps, name: "DumpType", value: 1},
+		{key: localDumps, name: "DumpCount", value: userDumpCount},
+	}
+}
+
+func (vm *windows) CrashDumps() ([]CrashDump, error) {
+	cmd := "\"Get-ChildItem -Path " + crashDumpDir + " -Filter *.dmp -File -ErrorAction SilentlyContinue | " +
+		"ForEach-Object { $_.Name + ' ' + $_.LastWriteTimeUtc.ToString('o') }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing crash dumps with output: %s", out)
+	}
+	return parseCrashDumps(out)
+}
+
+// parseCrashDumps parses the crash dumps listed one per line, as a file name followed by a round-trip formatted time
+func parseCrashDumps(out string) ([]CrashDump, error) {
+	var dumps []CrashDump
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		separator := strings.LastIndex(line, " ")
+		if separator < 0 {
+			return nil, errors.Errorf("invalid crash dump %q", line)
+		}
+		dumpTime, err := time.Parse(time.RFC3339Nano, line[separator+1:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid crash dump time %q", line)
+		}
+		dumps = append(dumps, CrashDump{Name: line[:separator], Time: dumpTime})
+	}
+	return dumps, nil
+}
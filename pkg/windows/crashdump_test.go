@@ -0,0 +1,30 @@
+package windows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseCrashDumps tests the parseCrashDumps function
+func TestParseCrashDumps(t *testing.T) {
+	dumps, err := parseCrashDumps("MEMORY.DMP 2021-03-04T05:06:07.1234567Z\r\n" +
+		"kubelet.exe.1234.dmp 2021-03-05T00:00:00.0000000Z\r\n\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, []CrashDump{
+		{Name: "MEMORY.DMP", Time: time.Date(2021, 3, 4, 5, 6, 7, 123456700, time.UTC)},
+		{Name: "kubelet.exe.1234.dmp", Time: time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)},
+	}, dumps)
+	assert.Equal(t, "MEMORY.DMP@2021-03-04T05:06:07Z", dumps[0].String())
+
+	dumps, err = parseCrashDumps("")
+	require.NoError(t, err)
+	assert.Empty(t, dumps)
+
+	_, err = parseCrashDumps("MEMORY.DMP")
+	assert.Error(t, err)
+	_, err = parseCrashDumps("MEMORY.DMP yesterday")
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package windows
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig/payload"
+)
+
+const (
+	// credentialProviderDir is the directory of the kubelet image credential providers on the VM
+	credentialProviderDir = k8sDir + "credential-providers\\"
+	// credentialProviderConfigPath is the location of the kubelet image credential provider configuration on the VM
+	credentialProviderConfigPath = k8sDir + "credential-provider-config.json"
+	// credentialProvidersFeatureGate is the kubelet feature gate enabling the image credential providers
+	credentialProvidersFeatureGate = "KubeletCredentialProviders"
+	// credentialProviderAPIVersion is the version of the configuration and exec APIs of the kubelet image credential
+	// providers supported by kubelet
+	credentialProviderAPIVersion = "v1alpha1"
+)
+
+// credentialProvider describes the kubelet image credential provider of a platform
+type credentialProvider struct {
+	// path is the location of the provider binary in the payload
+	path string
+	// matchImages are the patterns of the images whose credentials are retrieved from the provider
+	matchImages []string
+	// args are the arguments the provider is run with
+	args []string
+}
+
+// credentialProviders are the kubelet image credential providers of the platforms whose registries authenticate the
+// VMs with their instance identity
+var credentialProviders = map[oconfig.PlatformType]credentialProvider{
+	oconfig.AWSPlatformType: {
+		path: payload.ECRCredentialProviderPath,
+		matchImages: []string{"*.dkr.ecr.*.amazonaws.com", "*.dkr.ecr.*.amazonaws.com.cn",
+			"*.dkr.ecr-fips.*.amazonaws.com"},
+	},
+	oconfig.AzurePlatformType: {
+		path:        payload.ACRCredentialProviderPath,
+		matchImages: []string{"*.azurecr.io", "*.azurecr.cn", "*.azurecr.de", "*.azurecr.us"},
+		// The provider authenticates with the identity defined by the cloud provider configuration of kubelet
+		args: []string{cloudConfigPath},
+	},
+	oconfig.GCPPlatformType: {
+		path:        payload.GCPCredentialProviderPath,
+		matchImages: []string{"container.cloud.google.com", "gcr.io", "*.gcr.io", "*.pkg.dev"},
+		args:        []string{"get-credentials"},
+	},
+}
+
+// name returns the name of the provider, which is the name of its binary, including its extension, within the
+// provider directory
+func (p credentialProvider) name() string {
+	return filepath.Base(p.path)
+}
+
+// credentialProviderConfig returns the kubelet CredentialProviderConfig running the given provider
+func credentialProviderConfig(provider credentialProvider) ([]byte, error) {
+	type execProvider struct {
+		Name                 string   `json:"name"`
+		MatchImages          []string `json:"matchImages"`
+		DefaultCacheDuration string   `json:"defaultCacheDuration"`
+		APIVersion           string   `json:"apiVersion"`
+		Args                 []string `json:"args,omitempty"`
+	}
+	config := struct {
+		APIVersion string         `json:"apiVersion"`
+		Kind       string         `json:"kind"`
+		Providers  []execProvider `json:"providers"`
+	}{
+		APIVersion: "kubelet.config.k8s.io/" + credentialProviderAPIVersion,
+		Kind:       "CredentialProviderConfig",
+		Providers: []execProvider{{Name: provider.name(), MatchImages: provider.matchImages,
+			DefaultCacheDuration: "12h", Args: provider.args,
+			APIVersion: "credentialprovider.kubelet.k8s.io/" + credentialProviderAPIVersion}},
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error encoding the credential provider configuration")
+	}
+	return data, nil
+}
+
+// credentialProvider returns the image credential provider kubelet is configured with, nil if the credential
+// providers are not enabled or if the platform of the VM has none
+func (vm *windows) credentialProvider() *credentialProvider {
+	if !vm.kubelet.CredentialProviders {
+		return nil
+	}
+	provider, found := credentialProviders[vm.platform]
+	if !found {
+		return nil
+	}
+	return &provider
+}
+
+// credentialProviderArgs returns the kubelet arguments pointing to the image credential provider configuration and
+// to the directory of the provider binaries
+func credentialProviderArgs() []string {
+	return []string{"--image-credential-provider-config=" + credentialProviderConfigPath,
+		"--image-credential-provider-bin-dir=" + strings.TrimSuffix(credentialProviderDir, "\\")}
+}
+
+// configureCredentialProvider copies the binary of the image credential provider of the platform to the VM and writes
+// its configuration, when the credential providers are enabled
+func (vm *windows) configureCredentialProvider() error {
+	provider := vm.credentialProvider()
+	if provider == nil {
+		return nil
+	}
+	file, err := payload.NewFileInfo(payload.Path(provider.path, vm.host.PayloadVersion))
+	if err != nil {
+		return errors.Wrapf(err, "could not create FileInfo object for file %s", provider.path)
+	}
+	if err := vm.EnsureFile(file, credentialProviderDir); err != nil {
+		return errors.Wrapf(err, "error copying %s to the Windows VM", provider.name())
+	}
+	config, err := credentialProviderConfig(*provider)
+	if err != nil {
+		return err
+	}
+	return vm.writeFile(credentialProviderConfigPath, config)
+}
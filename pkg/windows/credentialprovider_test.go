@@ -0,0 +1,35 @@
+package windows
+
+import (
+	"testing"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialProviderConfig(t *testing.T) {
+	config, err := credentialProviderConfig(credentialProviders[oconfig.GCPPlatformType])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"apiVersion": "kubelet.config.k8s.io/v1alpha1", "kind": "CredentialProviderConfig",
+		"providers": [{"name": "auth-provider-gcp.exe",
+			"matchImages": ["container.cloud.google.com", "gcr.io", "*.gcr.io", "*.pkg.dev"],
+			"defaultCacheDuration": "12h", "apiVersion": "credentialprovider.kubelet.k8s.io/v1alpha1",
+			"args": ["get-credentials"]}]}`, string(config))
+}
+
+func TestKubeletCredentialProvider(t *testing.T) {
+	vm := &windows{platform: oconfig.AWSPlatformType,
+		kubelet: KubeletSettings{Args: []string{"--v=4"}, CredentialProviders: true,
+			Config: map[string]interface{}{"featureGates": map[string]interface{}{"A": true}}}}
+	assert.Equal(t, []string{"--image-credential-provider-config=C:\\k\\credential-provider-config.json",
+		"--image-credential-provider-bin-dir=C:\\k\\credential-providers", "--v=4"}, vm.kubeletArgs())
+	assert.Equal(t, map[string]interface{}{
+		"featureGates": map[string]interface{}{"A": true, credentialProvidersFeatureGate: true},
+	}, vm.kubeletConfig())
+
+	// The platform has no credential provider
+	vm.platform = oconfig.VSpherePlatformType
+	assert.Equal(t, []string{"--v=4"}, vm.kubeletArgs())
+	assert.Equal(t, vm.kubelet.Config, vm.kubeletConfig())
+}
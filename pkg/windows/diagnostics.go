@@ -0,0 +1,53 @@
+package windows
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// kubeletLogDir is the remote kubelet log directory, configured by the bootstrapper
+	kubeletLogDir = logDir + "kubelet\\"
+	// diagnosticsLogLines is the number of lines collected from the end of the most recent log file of each Kubernetes
+	// component, keeping the diagnostics of a VM within the size limit of a ConfigMap
+	diagnosticsLogLines = 300
+)
+
+// diagnosticsCommands are the PowerShell commands run to collect the diagnostics of a VM, keyed by the name of the
+// file their output is stored in
+var diagnosticsCommands = map[string]string{
+	"kubelet.log":        tailLatestLogCmd(kubeletLogDir),
+	"kube-proxy.log":     tailLatestLogCmd(kubeProxyLogDir),
+	"hybrid-overlay.log": tailLatestLogCmd(hybridOverlayLogDir),
+	"services.txt": "Get-Service -Name " + kubeletServiceName + "," + kubeProxyServiceName + "," +
+		hybridOverlayServiceName + "," + windowsExporterServiceName + ",docker,sshd -ErrorAction SilentlyContinue | " +
+		"Format-Table -AutoSize Name,Status,StartType | Out-String -Width 200",
+	"hns.txt": "Import-Module -DisableNameChecking " + hnsPSModule + "; " +
+		"Get-HnsNetwork | ConvertTo-Json -Depth 5; Get-HnsEndpoint | ConvertTo-Json -Depth 5; " +
+		"Get-HnsPolicyList | ConvertTo-Json -Depth 5",
+	"events.txt": eventLogDiagnosticsCmd(),
+	"network.txt": "ipconfig /all; Get-NetRoute | Format-Table -AutoSize | Out-String -Width 200; " +
+		"Get-NetFirewallProfile | Format-Table -AutoSize Name,Enabled | Out-String -Width 200",
+}
+
+// tailLatestLogCmd returns the PowerShell command printing the last lines of the most recently written log file in
+// the given directory
+func tailLatestLogCmd(dir string) string {
+	return "Get-ChildItem -Path " + dir + " -File | Sort-Object LastWriteTime -Descending | Select-Object -First 1 | " +
+		"Get-Content -Tail " + strconv.Itoa(diagnosticsLogLines)
+}
+
+// CollectDiagnostics gathers the recent logs of the Kubernetes components, the recent events of the event log, the HNS
+// state, the status of the services and the network configuration of the VM, keyed by file name. A diagnostic which
+// cannot be collected holds the error instead, so that a partially broken VM still yields the rest of its diagnostics.
+func (vm *windows) CollectDiagnostics() map[string]string {
+	diagnostics := make(map[string]string, len(diagnosticsCommands))
+	for name, cmd := range diagnosticsCommands {
+		out, err := vm.Run("\""+cmd+"\"", true)
+		if err != nil {
+			out = fmt.Sprintf("%s\nerror collecting %s: %v", out, name, err)
+		}
+		diagnostics[name] = out
+	}
+	return diagnostics
+}
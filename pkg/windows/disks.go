@@ -0,0 +1,54 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DataDisk is a disk attached to the Windows VM, which is initialized and mounted before the VM is configured
+type DataDisk struct {
+	// Number is the number of the disk, as reported by Get-Disk
+	Number int
+	// Path is either a drive letter, such as D:, or the absolute path of a directory on which the disk is mounted
+	Path string
+}
+
+// isDriveLetter returns true if the disk is mounted as a drive letter rather than on a directory
+func (d DataDisk) isDriveLetter() bool {
+	return len(d.Path) == 2 && d.Path[1] == ':'
+}
+
+// accessPath returns the access path of the disk partition, as reported by Get-Partition
+func (d DataDisk) accessPath() string {
+	return strings.TrimSuffix(d.Path, "\\") + "\\"
+}
+
+// initializeDataDisks brings the data disks online, initializes and formats those which are not initialized yet and
+// mounts them on their path. Disks which are already initialized are never formatted, so that their data is kept.
+func (vm *windows) initializeDataDisks() error {
+	for _, disk := range vm.host.DataDisks {
+		number := strconv.Itoa(disk.Number)
+		mountCmd := "New-Item -ItemType Directory -Force -Path '" + disk.Path + "' | Out-Null; " +
+			"Add-PartitionAccessPath -InputObject $partition -AccessPath '" + disk.accessPath() + "'"
+		if disk.isDriveLetter() {
+			mountCmd = "Set-Partition -InputObject $partition -NewDriveLetter " + disk.Path[:1]
+		}
+		cmd := "\"$ErrorActionPreference = 'Stop'; " +
+			"$disk = Get-Disk -Number " + number + "; " +
+			"if ($disk.IsOffline) { Set-Disk -Number " + number + " -IsOffline $false }; " +
+			"if ($disk.IsReadOnly) { Set-Disk -Number " + number + " -IsReadOnly $false }; " +
+			"if ($disk.PartitionStyle -eq 'RAW') { " +
+			"Initialize-Disk -Number " + number + " -PartitionStyle GPT; " +
+			"New-Partition -DiskNumber " + number + " -UseMaximumSize | " +
+			"Format-Volume -FileSystem NTFS -Confirm:$false | Out-Null }; " +
+			"$partition = Get-Partition -DiskNumber " + number + " | where { $_.Type -eq 'Basic' } | select -Last 1; " +
+			"if (-not ($partition.AccessPaths -contains '" + disk.accessPath() + "')) { " + mountCmd + " }\""
+		if out, err := vm.Run(cmd, true); err != nil {
+			return errors.Wrapf(err, "error initializing disk %d with output: %s", disk.Number, out)
+		}
+		vm.log.Info("initialized data disk", "disk", disk.Number, "path", disk.Path)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ensureDNS sets the DNS servers of the network interfaces with a default gateway, and the DNS suffix search list of
+// the VM, if they are defined in the host settings. The interfaces are looked up each time, as the hybrid overlay
+// moves the IP configuration of the network adapter to a virtual adapter.
+func (vm *windows) ensureDNS() error {
+	cmd := "\"$ErrorActionPreference = 'Stop'; "
+	if len(vm.host.DNSServers) > 0 {
+		servers := strings.Join(vm.host.DNSServers, ",")
+		cmd += "foreach ($cfg in @(Get-NetIPConfiguration | where { $_.IPv4DefaultGateway })) { " +
+			"$current = (Get-DnsClientServerAddress -InterfaceIndex $cfg.InterfaceIndex -AddressFamily IPv4)." +
+			"ServerAddresses -join ','; " +
+			"if ($current -ne '" + servers + "') { " +
+			"Set-DnsClientServerAddress -InterfaceIndex $cfg.InterfaceIndex -ServerAddresses " +
+			psArray(vm.host.DNSServers) + "; 'set DNS servers of ' + $cfg.InterfaceAlias + ' to " + servers + "' } }; "
+	}
+	if len(vm.host.DNSSuffixes) > 0 {
+		suffixes := strings.Join(vm.host.DNSSuffixes, ",")
+		cmd += "if (((Get-DnsClientGlobalSetting).SuffixSearchList -join ',') -ne '" + suffixes + "') { " +
+			"Set-DnsClientGlobalSetting -SuffixSearchList " + psArray(vm.host.DNSSuffixes) + "; " +
+			"'set DNS suffix search list to " + suffixes + "' }; "
+	}
+	out, err := vm.Run(cmd+"\"", true)
+	if err != nil {
+		return errors.Wrapf(err, "error configuring DNS with output: %s", out)
+	}
+	for _, change := range strings.Split(strings.TrimSpace(out), "\n") {
+		if change = strings.TrimSpace(change); change != "" {
+			vm.log.Info("DNS", "change", change)
+		}
+	}
+	return nil
+}
+
+// psArray returns the PowerShell array literal of the given strings, which must not contain single quotes
+func psArray(values []string) string {
+	return "@('" + strings.Join(values, "','") + "')"
+}
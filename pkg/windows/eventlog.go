@@ -0,0 +1,118 @@
+package windows
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// eventLogDir is the remote directory the exported event logs are written to, served by the kubelet logs endpoint
+	eventLogDir = logDir + "event-logs\\"
+	// eventLogScript is the remote location of the script exporting the new events of the event log channels
+	eventLogScript = k8sDir + "export-event-logs.ps1"
+	// eventLogTaskName is the name of the scheduled task running the event log export script
+	eventLogTaskName = "wmco-event-log-export"
+	// eventLogExportInterval is the interval at which the new events are exported
+	eventLogExportInterval = 5 * time.Minute
+	// eventLogMaxBytes is the size above which an exported event log is rotated, a single rotated file being kept
+	eventLogMaxBytes = 10 * 1024 * 1024
+	// eventLogLookBack is the age of the oldest events collected, by the first export of a channel and in the
+	// diagnostics
+	eventLogLookBack = 24 * time.Hour
+	// eventLogDiagnosticsMaxEvents is the number of most recent events collected in the diagnostics, keeping the
+	// diagnostics of a VM within the size limit of a ConfigMap
+	eventLogDiagnosticsMaxEvents = 200
+	// eventLogLevels filters the critical, error and warning events
+	eventLogLevels = "(Level=1 or Level=2 or Level=3)"
+)
+
+// eventLogChannels are the event log channels collected from the VM, in which the failures of the operating system,
+// of the container runtime, of Hyper-V containers and of the Host Network Service are logged
+var eventLogChannels = []string{"System", "Application", "Microsoft-Windows-Hyper-V-Compute-Admin",
+	"Microsoft-Windows-Host-Network-Service-Admin"}
+
+// eventLogScriptContents returns the script appending the critical, error and warning events logged in each channel
+// since its last export to <channel>.log in the event log directory, one JSON object per line. The record ID of the
+// last exported event of each channel is kept in <channel>.bookmark, the first export of a channel going back
+// eventLogLookBack. Channels which do not exist on the VM are skipped.
+func eventLogScriptContents() string {
+	lookBack := strconv.FormatInt(eventLogLookBack.Milliseconds(), 10)
+	return "$ErrorActionPreference = 'Stop'\r\n" +
+		"New-Item -ItemType Directory -Force -Path " + psString(eventLogDir) + " | Out-Null\r\n" +
+		"foreach ($channel in @(" + psList(eventLogChannels) + ")) {\r\n" +
+		"  if (-not (Get-WinEvent -ListLog $channel -ErrorAction SilentlyContinue)) { continue }\r\n" +
+		"  $log = " + psString(eventLogDir) + " + $channel + '.log'\r\n" +
+		"  $bookmark = " + psString(eventLogDir) + " + $channel + '.bookmark'\r\n" +
+		"  $filter = 'TimeCreated[timediff(@SystemTime) <= " + lookBack + "]'\r\n" +
+		"  if (Test-Path $bookmark) { $filter = 'EventRecordID > ' + [long](Get-Content $bookmark) }\r\n" +
+		"  $events = @(Get-WinEvent -LogName $channel -FilterXPath ('*[System[" + eventLogLevels +
+		" and ' + $filter + ']]') -ErrorAction SilentlyContinue | Sort-Object RecordId)\r\n" +
+		"  if ($events.Count -eq 0) { continue }\r\n" +
+		"  if ((Test-Path $log) -and (Get-Item $log).Length -gt " + strconv.Itoa(eventLogMaxBytes) + ") { " +
+		"Move-Item -Force -Path $log -Destination ($log + '.1') }\r\n" +
+		"  $events | ForEach-Object { @{time=$_.TimeCreated.ToUniversalTime().ToString('o'); channel=$channel; " +
+		"provider=$_.ProviderName; id=$_.Id; level=$_.LevelDisplayName; recordId=$_.RecordId; " +
+		"message=$_.Message} | ConvertTo-Json -Compress } | Add-Content -Encoding UTF8 -Path $log\r\n" +
+		"  Set-Content -Path $bookmark -Value $events[-1].RecordId\r\n" +
+		"}\r\n"
+}
+
+// eventLogDiagnosticsCmd returns the PowerShell command printing the most recent critical, error and warning events of
+// the event log channels, within eventLogLookBack
+func eventLogDiagnosticsCmd() string {
+	return "Get-WinEvent -FilterHashtable @{LogName=" + psList(eventLogChannels) + "; Level=1,2,3; " +
+		"StartTime=(Get-Date).AddHours(-" + strconv.Itoa(int(eventLogLookBack.Hours())) + ")} " +
+		"-MaxEvents " + strconv.Itoa(eventLogDiagnosticsMaxEvents) + " -ErrorAction SilentlyContinue | " +
+		"Format-List TimeCreated,LogName,ProviderName,Id,LevelDisplayName,Message | Out-String -Width 300"
+}
+
+// ensureEventLogExport writes the event log export script and registers the scheduled task running it every
+// eventLogExportInterval, so that the event logs can be read through the kubelet logs endpoint and collected along with
+// the other node logs
+func (vm *windows) ensureEventLogExport() error {
+	script := base64.StdEncoding.EncodeToString([]byte(eventLogScriptContents()))
+	interval := strconv.Itoa(int(eventLogExportInterval.Minutes()))
+	args := "-NoProfile -NonInteractive -ExecutionPolicy Bypass -File " + eventLogScript
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"if (-not (Test-Path " + eventLogScript + ") -or [Convert]::ToBase64String([IO.File]::ReadAllBytes('" +
+		eventLogScript + "')) -ne '" + script + "') { [IO.File]::WriteAllBytes('" + eventLogScript +
+		"', [Convert]::FromBase64String('" + script + "')); 'wrote event log export script' }; " +
+		"$task = Get-ScheduledTask -TaskName " + psString(eventLogTaskName) + " -ErrorAction SilentlyContinue; " +
+		"if (-not $task -or $task.Actions[0].Arguments -ne " + psString(args) + " -or " +
+		"$task.Triggers[0].Repetition.Interval -ne 'PT" + interval + "M') { " +
+		"$action = New-ScheduledTaskAction -Execute powershell.exe -Argument " + psString(args) + "; " +
+		"$trigger = New-ScheduledTaskTrigger -Once -At (Get-Date) -RepetitionInterval (New-TimeSpan -Minutes " +
+		interval + "); " +
+		"$settings = New-ScheduledTaskSettingsSet -MultipleInstances IgnoreNew -AllowStartIfOnBatteries " +
+		"-DontStopIfGoingOnBatteries; " +
+		"Register-ScheduledTask -Force -TaskName " + psString(eventLogTaskName) + " -Action $action " +
+		"-Trigger $trigger -Settings $settings -User SYSTEM -RunLevel Highest | Out-Null; " +
+		"'registered ' + " + psString(eventLogTaskName) + " }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error configuring event log export with output: %s", out)
+	}
+	for _, change := range parseChanges(out) {
+		vm.log.Info("event log export", "change", change)
+	}
+	return nil
+}
+
+// removeEventLogExport unregisters the event log export scheduled task, if it exists. The exported event logs are
+// left in place.
+func (vm *windows) removeEventLogExport() error {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"Get-ScheduledTask -TaskName " + psString(eventLogTaskName) + " -ErrorAction SilentlyContinue | " +
+		"foreach { Unregister-ScheduledTask -TaskName $_.TaskName -Confirm:$false; 'unregistered ' + $_.TaskName }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error removing %s scheduled task with output: %s", eventLogTaskName, out)
+	}
+	for _, change := range parseChanges(out) {
+		vm.log.Info("event log export", "change", change)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// fipsKeyExchanges are the FIPS approved SSH key exchange algorithms
+	fipsKeyExchanges = []string{"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521"}
+	// fipsCiphers are the FIPS approved SSH ciphers
+	fipsCiphers = []string{"aes128-gcm@openssh.com", "aes256-ctr", "aes192-ctr", "aes128-ctr"}
+	// fipsMACs are the FIPS approved SSH MAC algorithms
+	fipsMACs = []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256"}
+	// fipsHostKeyAlgorithms are the FIPS approved SSH host key algorithms
+	fipsHostKeyAlgorithms = []string{ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521}
+)
+
+// restrictAlgorithms returns the given allowed algorithms which are approved, or all approved algorithms if no
+// algorithm is given. An error is returned if none of the given algorithms is approved.
+func restrictAlgorithms(allowed, approved []string) ([]string, error) {
+	if len(allowed) == 0 {
+		return approved, nil
+	}
+	var restricted []string
+	for _, algorithm := range allowed {
+		for _, approvedAlgorithm := range approved {
+			if algorithm == approvedAlgorithm {
+				restricted = append(restricted, algorithm)
+				break
+			}
+		}
+	}
+	if len(restricted) == 0 {
+		return nil, errors.Errorf("allowed %v, approved %v", allowed, approved)
+	}
+	return restricted, nil
+}
+
+// configureFIPSPolicy enables the Windows FIPS algorithm policy if the cluster is in FIPS mode, restarting the VM if
+// it changed for all services to take it into account
+func (vm *windows) configureFIPSPolicy() error {
+	if !vm.host.FIPS {
+		return nil
+	}
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"$key = 'HKLM:\\System\\CurrentControlSet\\Control\\Lsa\\FipsAlgorithmPolicy'; " +
+		"if ((Get-ItemProperty -Path $key).Enabled -ne 1) { " +
+		"Set-ItemProperty -Path $key -Name Enabled -Value 1 -Type DWord; $true } else { $false }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error enabling FIPS algorithm policy with output: %s", out)
+	}
+	if strings.TrimSpace(out) != "True" {
+		return nil
+	}
+
+	vm.log.Info("FIPS algorithm policy enabled, restarting VM")
+	if out, err := vm.Run("Restart-Computer -Force", true); err != nil {
+		return errors.Wrapf(err, "error restarting VM with output: %s", out)
+	}
+	// Reinitialize the SSH connection given the VM restarted
+	if err := vm.Reinitialize(); err != nil {
+		return errors.Wrap(err, "error reinitializing VM after enabling FIPS algorithm policy")
+	}
+	return nil
+}
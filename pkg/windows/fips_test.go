@@ -0,0 +1,46 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRestrictAlgorithms tests the restrictAlgorithms function
+func TestRestrictAlgorithms(t *testing.T) {
+	approved := []string{"a", "b", "c"}
+	tests := []struct {
+		name    string
+		allowed []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "no allowed algorithms",
+			allowed: nil,
+			want:    approved,
+		},
+		{
+			name:    "allowed algorithms order kept",
+			allowed: []string{"x", "c", "a"},
+			want:    []string{"c", "a"},
+		},
+		{
+			name:    "no approved algorithm allowed",
+			allowed: []string{"x", "y"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := restrictAlgorithms(tt.allowed, approved)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package windows
+
+const (
+	// firewallRulePrefix is the prefix of the name of the firewall rules managed by WMCO
+	firewallRulePrefix = "WMCO-"
+	// kubeletPort is the port on which kubelet serves its API
+	kubeletPort = "10250"
+	// metricsPort is the port on which kube-rbac-proxy serves the metrics of windows_exporter, it must match
+	// metrics.Port
+	metricsPort = "9182"
+	// defaultVXLANPort is the VXLAN port used by the hybrid overlay when no custom port is configured
+	defaultVXLANPort = "4789"
+	// nodePortRange is the default Kubernetes NodePort service range
+	nodePortRange = "30000-32767"
+)
+
+// firewallRule is an inbound rule of the Windows firewall allowing traffic to a local port
+type firewallRule struct {
+	name     string
+	protocol string
+	port     string
+}
+
+// firewallRules returns the inbound firewall rules required by the Kubernetes components on the VM, which are part of
+// its desired state when the firewall rules are managed
+func (vm *windows) firewallRules() []firewallRule {
+	vxlanPort := vm.vxlanPort
+	if vxlanPort == "" {
+		vxlanPort = defaultVXLANPort
+	}
+	return []firewallRule{
+		{name: "kubelet", protocol: "TCP", port: kubeletPort},
+		{name: "windows-exporter", protocol: "TCP", port: metricsPort},
+		{name: "vxlan", protocol: "UDP", port: vxlanPort},
+		{name: "nodeport-tcp", protocol: "TCP", port: nodePortRange},
+		{name: "nodeport-udp", protocol: "UDP", port: nodePortRange},
+	}
+}
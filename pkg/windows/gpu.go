@@ -0,0 +1,54 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// minDirectXDriverMajorVersion is the major version of the display drivers implementing WDDM 2.5, the first version
+// allowing DirectX to be used from process-isolated Windows containers
+const minDirectXDriverMajorVersion = 25
+
+// GPU is a display adapter of the VM
+type GPU struct {
+	// Name is the name of the adapter
+	Name string
+	// DriverVersion is the version of the display driver of the adapter
+	DriverVersion string
+	// DirectX is true when the driver allows the adapter to be used by DirectX from containers
+	DirectX bool
+}
+
+func (vm *windows) GPUs() ([]GPU, error) {
+	// The emulated adapters of the hypervisors and the fallback driver of Windows are not GPUs
+	out, err := vm.Run("\"$ErrorActionPreference = 'Stop'; Get-CimInstance -ClassName Win32_VideoController | "+
+		"Where-Object { $_.Name -notmatch '^Microsoft (Basic Display Adapter|Hyper-V Video)' } | "+
+		"ForEach-Object { $_.DriverVersion + '|' + $_.Name }\"", true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing display adapters with output: %s", out)
+	}
+	return parseGPUs(out)
+}
+
+// parseGPUs parses the display adapters reported one per line as "<driver version>|<name>"
+func parseGPUs(out string) ([]GPU, error) {
+	var gpus []GPU
+	for _, line := range parseChanges(out) {
+		tokens := strings.SplitN(line, "|", 2)
+		if len(tokens) != 2 {
+			return nil, errors.Errorf("unexpected display adapter output %q", line)
+		}
+		gpus = append(gpus, GPU{Name: tokens[1], DriverVersion: tokens[0],
+			DirectX: directXCapable(tokens[0])})
+	}
+	return gpus, nil
+}
+
+// directXCapable returns true if the display driver with the given version implements WDDM 2.5 or later. The major
+// version of Windows display drivers identifies the WDDM version they implement.
+func directXCapable(driverVersion string) bool {
+	major, err := strconv.Atoi(strings.SplitN(driverVersion, ".", 2)[0])
+	return err == nil && major >= minDirectXDriverMajorVersion
+}
@@ -0,0 +1,30 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGPUs(t *testing.T) {
+	gpus, err := parseGPUs("27.21.14.5671|NVIDIA Tesla T4\r\n23.21.13.8813|NVIDIA Tesla M60\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, []GPU{
+		{Name: "NVIDIA Tesla T4", DriverVersion: "27.21.14.5671", DirectX: true},
+		{Name: "NVIDIA Tesla M60", DriverVersion: "23.21.13.8813", DirectX: false},
+	}, gpus)
+
+	gpus, err = parseGPUs("\r\n")
+	require.NoError(t, err)
+	assert.Empty(t, gpus)
+
+	_, err = parseGPUs("NVIDIA Tesla T4\r\n")
+	assert.Error(t, err)
+}
+
+func TestDirectXCapable(t *testing.T) {
+	assert.True(t, directXCapable("25.21.14.1634"))
+	assert.False(t, directXCapable("24.21.13.9882"))
+	assert.False(t, directXCapable(""))
+}
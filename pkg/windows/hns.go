@@ -0,0 +1,89 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func (vm *windows) CheckHNSNetworks() ([]string, error) {
+	// The HNS networks are recreated by the hybrid overlay once it runs again, a stopped service is left to the
+	// recovery actions of the Windows Service Control Manager rather than being treated as corrupted networks
+	for _, serviceName := range []string{hybridOverlayServiceName, kubeProxyServiceName} {
+		running, err := vm.isRunning(serviceName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to check if %s Windows service is running", serviceName)
+		}
+		if !running {
+			vm.log.V(1).Info("HNS networks not checked as service is not running", "service", serviceName)
+			return nil, nil
+		}
+	}
+	cmdLine, err := vm.getServiceCmdLine(kubeProxyServiceName)
+	if err != nil {
+		return nil, err
+	}
+	sourceVIP := argValue(cmdLine, "--source-vip")
+
+	// The hybrid overlay programs a remote subnet route policy on the overlay network for each Linux node, and
+	// kube-proxy relies on the endpoint of its source VIP. Both are lost when HNS recreates the network.
+	cmd := "\"$ErrorActionPreference = 'Stop'; $nets = Get-HnsNetwork; " +
+		"foreach ($name in @('" + BaseOVNKubeOverlayNetwork + "', '" + OVNKubeOverlayNetwork + "')) { " +
+		"if (-not ($nets | where { $_.Name -eq $name })) { 'HNS network ' + $name + ' is missing' } }; " +
+		"$net = $nets | where { $_.Name -eq '" + OVNKubeOverlayNetwork + "' }; " +
+		"if ($net) { " +
+		"if (-not ($net.Policies | where { $_.Type -eq 'RemoteSubnetRoute' })) { " +
+		"'HNS network " + OVNKubeOverlayNetwork + " has no remote subnet route policies' }; " +
+		"if ('" + sourceVIP + "' -and -not (Get-HnsEndpoint | " +
+		"where { $_.VirtualNetwork -eq $net.Id -and $_.IPAddress -eq '" + sourceVIP + "' })) { " +
+		"'HNS endpoint of the kube-proxy source VIP " + sourceVIP + " is missing' } }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error checking HNS networks with output: %s", out)
+	}
+	var problems []string
+	for _, problem := range strings.Split(strings.TrimSpace(out), "\n") {
+		if problem = strings.TrimSpace(problem); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems, nil
+}
+
+func (vm *windows) RepairHNSNetworks(nodeName, hostSubnet string) error {
+	vm.log.Info("recreating HNS networks")
+	// kube-proxy is recreated once the network is, as its source VIP endpoint is removed along with the network
+	if err := vm.ensureServiceNotRunning(&service{name: kubeProxyServiceName}); err != nil {
+		return errors.Wrapf(err, "error stopping %s service", kubeProxyServiceName)
+	}
+	if err := vm.ensureServiceIsDeleted(kubeProxyServiceName); err != nil {
+		return errors.Wrapf(err, "error deleting %s service", kubeProxyServiceName)
+	}
+	if err := vm.ensureServiceNotRunning(&service{name: hybridOverlayServiceName}); err != nil {
+		return errors.Wrapf(err, "error stopping %s service", hybridOverlayServiceName)
+	}
+	// The overlay network is removed so that the hybrid overlay recreates it along with its policies. The base network
+	// is kept, as removing it disrupts the connectivity of the VM, and is only recreated if it is missing.
+	cmd := "\"Get-HnsNetwork | where { $_.Name -eq '" + OVNKubeOverlayNetwork + "' } | Remove-HnsNetwork\""
+	if out, err := vm.Run(cmd, true); err != nil {
+		return errors.Wrapf(err, "error removing HNS network %s with output: %s", OVNKubeOverlayNetwork, out)
+	}
+	if err := vm.ConfigureHybridOverlay(nodeName); err != nil {
+		return errors.Wrap(err, "error restarting hybrid overlay")
+	}
+	if err := vm.ConfigureKubeProxy(nodeName, hostSubnet); err != nil {
+		return errors.Wrap(err, "error recreating kube-proxy")
+	}
+	return nil
+}
+
+// argValue returns the value of the argument with the given name, in the --name=value format, of the given command
+// line. An empty string is returned if the argument is not present.
+func argValue(cmdLine, name string) string {
+	for _, token := range strings.Fields(cmdLine) {
+		if parts := strings.SplitN(token, "=", 2); len(parts) == 2 && parts[0] == name {
+			return strings.Trim(parts[1], "\"")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestArgValue tests the argValue function
+func TestArgValue(t *testing.T) {
+	cmdLine := `c:\k\kube-proxy.exe --windows-service --source-vip=10.132.0.3 --enable-dsr=false`
+	assert.Equal(t, "10.132.0.3", argValue(cmdLine, "--source-vip"))
+	assert.Equal(t, "false", argValue(cmdLine, "--enable-dsr"))
+	assert.Equal(t, "", argValue(cmdLine, "--windows-service"))
+	assert.Equal(t, "", argValue(cmdLine, "--cluster-cidr"))
+}
@@ -0,0 +1,99 @@
+package windows
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig/payload"
+)
+
+const (
+	// hooksDir is the remote directory the hook scripts are transferred to before being run
+	hooksDir = k8sDir + "hooks"
+	// PreConfigureHook is the name of the hook script run before the VM is configured
+	PreConfigureHook = "pre-configure.ps1"
+	// PostConfigureHook is the name of the hook script run once the node of the VM is configured
+	PostConfigureHook = "post-configure.ps1"
+	// HookChecksumSuffix is appended to the name of a hook script to get the name of the ConfigMap key holding the
+	// expected SHA256 checksum of the script
+	HookChecksumSuffix = ".sha256"
+)
+
+// ConfigurationHooks holds the PowerShell hook scripts run on the VM around its configuration, along with their
+// expected SHA256 checksums, empty scripts being skipped
+type ConfigurationHooks struct {
+	// PreConfigure is run before the VM is configured
+	PreConfigure string
+	// PreConfigureSHA256 is the hex encoded SHA256 checksum PreConfigure must have once transferred to the VM
+	PreConfigureSHA256 string
+	// PostConfigure is run once the node of the VM is configured, before workloads can be scheduled on it
+	PostConfigure string
+	// PostConfigureSHA256 is the hex encoded SHA256 checksum PostConfigure must have once transferred to the VM
+	PostConfigureSHA256 string
+}
+
+// HookChecksum returns the hex encoded SHA256 checksum of the given hook script
+func HookChecksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// Checksums returns the checksums of the hook scripts, as a comma separated list of name=checksum pairs, empty when
+// there are no hooks
+func (h ConfigurationHooks) Checksums() string {
+	var checksums []string
+	if h.PreConfigure != "" {
+		checksums = append(checksums, PreConfigureHook+"="+HookChecksum(h.PreConfigure))
+	}
+	if h.PostConfigure != "" {
+		checksums = append(checksums, PostConfigureHook+"="+HookChecksum(h.PostConfigure))
+	}
+	return strings.Join(checksums, ",")
+}
+
+func (vm *windows) RunPostConfigureHook() error {
+	return vm.runHook(PostConfigureHook, vm.host.Hooks.PostConfigure, vm.host.Hooks.PostConfigureSHA256)
+}
+
+// runHook transfers the given hook script to the hooks directory, verifies that its checksum on the VM is the given
+// expected one, and runs it, failing if the script throws or exits with a non-zero code. The script is removed from
+// the VM once run, and is never part of the commands run, so that the secrets it may hold are neither logged nor
+// audited. An empty script is not run.
+func (vm *windows) runHook(name, script, checksum string) error {
+	if script == "" {
+		return nil
+	}
+	localDir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		return errors.Wrap(err, "error creating local hooks directory")
+	}
+	defer os.RemoveAll(localDir)
+	localPath := filepath.Join(localDir, name)
+	if err := ioutil.WriteFile(localPath, []byte(script), 0600); err != nil {
+		return errors.Wrapf(err, "error writing hook %s", name)
+	}
+	if err := vm.EnsureFile(&payload.FileInfo{Path: localPath, SHA256: checksum}, hooksDir); err != nil {
+		return errors.Wrapf(err, "error transferring hook %s", name)
+	}
+
+	path := hooksDir + "\\" + name
+	cmd := "\"$ErrorActionPreference = 'Stop'; try { " +
+		"if ((Get-FileHash -Algorithm SHA256 -Path " + psString(path) + ").Hash -ne '" + checksum + "') { " +
+		"throw 'checksum mismatch of hook " + name + "' }; " +
+		"& powershell.exe -NoProfile -NonInteractive -ExecutionPolicy Bypass -File " + psString(path) + "; " +
+		"if ($LASTEXITCODE -ne 0) { throw ('hook " + name + " exited with code ' + $LASTEXITCODE) } " +
+		"} finally { Remove-Item -Force -ErrorAction SilentlyContinue -Path " + psString(path) + " }\""
+	vm.log.Info("running hook", "hook", name, "checksum", checksum)
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error running hook %s with output: %s", name, out)
+	}
+	vm.log.V(1).Info("hook completed", "hook", name, "output", out)
+	return nil
+}
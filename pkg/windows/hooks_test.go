@@ -0,0 +1,16 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHookChecksums tests the Checksums method
+func TestHookChecksums(t *testing.T) {
+	assert.Equal(t, "", ConfigurationHooks{}.Checksums())
+	assert.Equal(t, "post-configure.ps1=ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb",
+		ConfigurationHooks{PostConfigure: "a"}.Checksums())
+	assert.Equal(t, "pre-configure.ps1="+HookChecksum("b")+",post-configure.ps1="+HookChecksum("a"),
+		ConfigurationHooks{PreConfigure: "b", PostConfigure: "a"}.Checksums())
+}
@@ -0,0 +1,239 @@
+package windows
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// HostSettings holds the operating system and networking settings applied to the Windows VM, mostly before the
+// Kubernetes components are configured
+type HostSettings struct {
+	// DataDisks are initialized and mounted on the VM
+	DataDisks []DataDisk
+	// Pagefile defines how the pagefile of the VM is managed
+	Pagefile Pagefile
+	// OverlayMTU is the MTU of the pod interfaces of the hybrid overlay network, zero when the MTU is derived from the
+	// network adapter of the VM
+	OverlayMTU int
+	// DNSServers are the DNS servers of the network interfaces of the VM, left as provided by DHCP when empty
+	DNSServers []string
+	// DNSSuffixes is the DNS suffix search list of the VM, left as configured in the Windows image when empty
+	DNSSuffixes []string
+	// NTPServers are the servers the clock of the VM is synchronized with, left as configured in the Windows image when
+	// empty
+	NTPServers []string
+	// ShutdownGracePeriod is the time the containers of the pods are given to terminate when the VM shuts down, zero
+	// when no shutdown hook is registered
+	ShutdownGracePeriod time.Duration
+	// KubeProxyDSR enables Direct Server Return in kube-proxy, along with the WinDSR feature gate
+	KubeProxyDSR bool
+	// ManageFirewallRules enables the creation and drift correction of the firewall rules required by the Kubernetes
+	// components
+	ManageFirewallRules bool
+	// Harden enables the hardening profile, which disables RDP, unneeded inbound firewall rules and unused services
+	Harden bool
+	// CrashDumps enables kernel and user-mode crash dumps, written to a directory they can be retrieved from
+	CrashDumps bool
+	// EventLogs enables the periodic export of the critical, error and warning events of the event log channels
+	// relevant to node failures, to files served by the kubelet logs endpoint
+	EventLogs bool
+	// SSHAlgorithms restricts the algorithms used for the SSH connection to the VM
+	SSHAlgorithms SSHAlgorithms
+	// SSHProxy is the URL of the SOCKS5 or HTTP proxy the SSH connection to the VM is dialed through, empty when the
+	// VM is dialed directly
+	SSHProxy string
+	// SSHProxyUser holds the credentials of the SSH proxy, nil when it does not require authentication
+	SSHProxyUser *url.Userinfo
+	// FIPS restricts the SSH connection to FIPS approved algorithms and enables the Windows FIPS algorithm policy
+	FIPS bool
+	// SSHCertificateAuthority issues the short-lived certificate the SSH connection to the VM is authenticated with,
+	// nil when the connection is authenticated with the key alone
+	SSHCertificateAuthority ssh.Signer
+	// PrePullImages are pulled, along with the pause image, once the VM is configured and before workloads can be
+	// scheduled on its node
+	PrePullImages []string
+	// Timeouts limits the time taken to connect to the VM, run commands and transfer files
+	Timeouts Timeouts
+	// TransferBandwidth is the maximum throughput, in bytes per second, of the files transferred to the VM, unlimited
+	// when zero
+	TransferBandwidth int64
+	// DetectGPUs enables the detection of the DirectX capable GPUs of the VM once it is configured
+	DetectGPUs bool
+	// WorkloadBuilds are the OS build numbers of the container images of the Windows workloads, checked against the
+	// OS build of the VM to determine whether the containers can run process-isolated
+	WorkloadBuilds []int
+	// NodeLabels are the labels kubelet registers the node with, added to the labels of the worker ignition
+	NodeLabels map[string]string
+	// ExternalCloudProvider makes kubelet register the node uninitialized, for the external cloud controller manager
+	// of the cluster to initialize it
+	ExternalCloudProvider bool
+	// PayloadVersion is the previous WMCO version whose payload, held by the operator image, is transferred to the VM,
+	// empty for the payload of the current version
+	PayloadVersion string
+	// AzureStackHub configures kubelet with the Azure Resource Manager endpoints of the Azure Stack Hub environment
+	// the VM runs in, and disables the checks relying on Azure metadata endpoints which Azure Stack Hub lacks
+	AzureStackHub bool
+	// AWSRegion is the AWS region of the cluster, used to point the AWS cloud provider of kubelet to the EC2 endpoint
+	// of the partition of the region
+	AWSRegion string
+	// AWSEC2Endpoint is the custom endpoint of the AWS EC2 service of the cluster, overriding the endpoint of the
+	// region
+	AWSEC2Endpoint string
+	// Hooks are the hook scripts run on the VM before and after its configuration
+	Hooks ConfigurationHooks
+}
+
+// unusedServices are the Windows services not needed by Windows nodes, which are disabled by the hardening profile
+var unusedServices = []string{"Spooler", "TermService", "RemoteRegistry", "MapsBroker", "lfsvc", "XblAuthManager",
+	"XblGameSave", "WMPNetworkSvc"}
+
+func (vm *windows) EnsureHostSettings() error {
+	m, err := vm.desiredState(false)
+	if err != nil {
+		return err
+	}
+	if err := vm.applyManifest(m); err != nil {
+		return err
+	}
+	return vm.ensureImperativeHostSettings()
+}
+
+// ensureImperativeHostSettings applies the host settings which are not part of the desired state manifest, as they
+// depend on the state of the VM, such as the firewall rules disabled by the hardening profile, or are applied through
+// dedicated tools
+func (vm *windows) ensureImperativeHostSettings() error {
+	if vm.host.Harden {
+		if err := vm.ensureHardened(); err != nil {
+			return errors.Wrap(err, "error applying hardening profile")
+		}
+	}
+	if vm.host.OverlayMTU > 0 {
+		if err := vm.ensureOverlayMTU(); err != nil {
+			return err
+		}
+	}
+	if len(vm.host.DNSServers) > 0 || len(vm.host.DNSSuffixes) > 0 {
+		if err := vm.ensureDNS(); err != nil {
+			return err
+		}
+	}
+	if len(vm.host.NTPServers) > 0 {
+		if err := vm.ensureTimeSync(); err != nil {
+			return err
+		}
+	}
+	if vm.host.ShutdownGracePeriod > 0 {
+		if err := vm.ensureShutdownHook(); err != nil {
+			return err
+		}
+	}
+	if vm.host.EventLogs {
+		if err := vm.ensureEventLogExport(); err != nil {
+			return err
+		}
+	} else if err := vm.removeEventLogExport(); err != nil {
+		return err
+	}
+	// The recovery actions of services recreated since the node was configured, such as kube-proxy, are restored
+	return vm.ConfigureServiceRecovery()
+}
+
+// ensureHardened disables the inbound firewall rules which are not required by the Kubernetes components, SSH or core
+// networking. It relies on the firewall rules managed by WMCO to keep the node reachable. RDP and the unused services
+// are disabled through the desired state manifest.
+func (vm *windows) ensureHardened() error {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"foreach ($rule in @(Get-NetFirewallRule -Direction Inbound -Action Allow -Enabled True)) { " +
+		"if ($rule.Name -like '" + firewallRulePrefix + "*' -or $rule.DisplayGroup -eq 'Core Networking' -or " +
+		"($rule | Get-NetFirewallPortFilter).LocalPort -eq '22') { continue }; " +
+		"Disable-NetFirewallRule -Name $rule.Name; 'disabled firewall rule ' + $rule.Name }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error hardening VM with output: %s", out)
+	}
+	for _, change := range strings.Split(strings.TrimSpace(out), "\n") {
+		if change = strings.TrimSpace(change); change != "" {
+			vm.log.Info("hardening", "change", change)
+		}
+	}
+	return nil
+}
+
+// PagefileMode defines how the pagefile of the Windows VM is managed
+type PagefileMode string
+
+const (
+	// PagefileUnmanaged leaves the pagefile as configured in the Windows image
+	PagefileUnmanaged PagefileMode = ""
+	// PagefileSystemManaged lets Windows manage the size and location of the pagefile
+	PagefileSystemManaged PagefileMode = "SystemManaged"
+	// PagefileDisabled removes all pagefiles
+	PagefileDisabled PagefileMode = "Disabled"
+	// PagefileFixed configures a single pagefile with a fixed size
+	PagefileFixed PagefileMode = "Fixed"
+)
+
+// Pagefile holds the pagefile configuration of the Windows VM
+type Pagefile struct {
+	Mode PagefileMode
+	// Path is the location of the pagefile when the mode is PagefileFixed
+	Path string
+	// SizeMB is the size of the pagefile, in megabytes, when the mode is PagefileFixed
+	SizeMB int64
+}
+
+// configurePagefile applies the pagefile configuration, restarting the VM if it changed for the change to take effect
+func (vm *windows) configurePagefile() error {
+	pagefile := vm.host.Pagefile
+	var cmd string
+	switch pagefile.Mode {
+	case PagefileUnmanaged:
+		return nil
+	case PagefileSystemManaged:
+		cmd = "if (-not $cs.AutomaticManagedPagefile) { " +
+			"Set-CimInstance -InputObject $cs -Property @{AutomaticManagedPagefile=$true}; $changed = $true }; "
+	case PagefileDisabled:
+		cmd = "if ($cs.AutomaticManagedPagefile) { " +
+			"Set-CimInstance -InputObject $cs -Property @{AutomaticManagedPagefile=$false}; $changed = $true }; " +
+			"foreach ($pf in @(Get-CimInstance Win32_PageFileSetting)) { Remove-CimInstance -InputObject $pf; " +
+			"$changed = $true }; "
+	case PagefileFixed:
+		size := strconv.FormatInt(pagefile.SizeMB, 10)
+		cmd = "if ($cs.AutomaticManagedPagefile) { " +
+			"Set-CimInstance -InputObject $cs -Property @{AutomaticManagedPagefile=$false}; $changed = $true }; " +
+			"foreach ($pf in @(Get-CimInstance Win32_PageFileSetting)) { if ($pf.Name -ne '" + pagefile.Path + "') { " +
+			"Remove-CimInstance -InputObject $pf; $changed = $true } }; " +
+			"$pf = Get-CimInstance Win32_PageFileSetting | where { $_.Name -eq '" + pagefile.Path + "' }; " +
+			"if (-not $pf) { $pf = New-CimInstance -ClassName Win32_PageFileSetting -Property @{Name='" +
+			pagefile.Path + "'}; $changed = $true }; " +
+			"if ($pf.InitialSize -ne " + size + " -or $pf.MaximumSize -ne " + size + ") { " +
+			"Set-CimInstance -InputObject $pf -Property @{InitialSize=[uint32]" + size + "; MaximumSize=[uint32]" +
+			size + "}; $changed = $true }; "
+	default:
+		return errors.Errorf("unknown pagefile mode %s", pagefile.Mode)
+	}
+	cmd = "\"$ErrorActionPreference = 'Stop'; $changed = $false; $cs = Get-CimInstance Win32_ComputerSystem; " +
+		cmd + "$changed\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error configuring pagefile with output: %s", out)
+	}
+	if strings.TrimSpace(out) != "True" {
+		return nil
+	}
+
+	vm.log.Info("pagefile configuration changed, restarting VM", "mode", pagefile.Mode)
+	if out, err := vm.Run("Restart-Computer -Force", true); err != nil {
+		return errors.Wrapf(err, "error restarting VM with output: %s", out)
+	}
+	// Reinitialize the SSH connection given the VM restarted
+	if err := vm.Reinitialize(); err != nil {
+		return errors.Wrap(err, "error reinitializing VM after configuring pagefile")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package windows
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	oconfig "github.com/openshift/api/config/v1"
+)
+
+const (
+	// maxHostNameLength is the maximum length of the computer name of a Windows VM, limited by NetBIOS
+	maxHostNameLength = 15
+	// hostNameHashLength is the number of characters of the hash of the Machine name ending a truncated host name
+	hostNameHashLength = 5
+)
+
+// HostName returns the computer name of the VM of the Machine with the given name: the Machine name if it fits within
+// the NetBIOS limit of 15 characters, else its first characters followed by a hyphen and a hash of the full name, so
+// that the Machines of a MachineSet, which share a prefix, are given distinct host names
+func HostName(machineName string) string {
+	if len(machineName) <= maxHostNameLength {
+		return machineName
+	}
+	sum := sha256.Sum256([]byte(machineName))
+	prefix := strings.TrimRight(machineName[:maxHostNameLength-hostNameHashLength-1], "-.")
+	return prefix + "-" + hex.EncodeToString(sum[:])[:hostNameHashLength]
+}
+
+// NodeNameOverride returns the name the node of the Machine with the given name must be registered with, overriding
+// the host name of its VM, or an empty string if the node is registered with the host name. On vSphere, where WMCO
+// sets the host name of the VM, the node must be named after the Machine, which is the name of the vSphere VM, so a
+// truncated host name is overridden.
+func NodeNameOverride(platform oconfig.PlatformType, machineName string) string {
+	if platform != oconfig.VSpherePlatformType || HostName(machineName) == machineName {
+		return ""
+	}
+	return machineName
+}
@@ -0,0 +1,46 @@
+package windows
+
+import (
+	"testing"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostName(t *testing.T) {
+	tests := []struct {
+		name        string
+		machineName string
+		expected    string
+	}{
+		{
+			name:        "short name",
+			machineName: "winworker-abcde",
+			expected:    "winworker-abcde",
+		},
+		{
+			name:        "long name",
+			machineName: "cluster-windows-worker-abcde",
+			expected:    "cluster-w-fa4eb",
+		},
+		{
+			name:        "long name with hyphen at truncation",
+			machineName: "cluster1-windows-worker-abcde",
+			expected:    "cluster1-fc6d6",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hostName := HostName(test.machineName)
+			assert.LessOrEqual(t, len(hostName), maxHostNameLength)
+			assert.Equal(t, test.expected, hostName)
+		})
+	}
+}
+
+func TestNodeNameOverride(t *testing.T) {
+	assert.Equal(t, "", NodeNameOverride(oconfig.VSpherePlatformType, "winworker-abcde"))
+	assert.Equal(t, "", NodeNameOverride(oconfig.AWSPlatformType, "cluster-windows-worker-abcde"))
+	assert.Equal(t, "cluster-windows-worker-abcde",
+		NodeNameOverride(oconfig.VSpherePlatformType, "cluster-windows-worker-abcde"))
+}
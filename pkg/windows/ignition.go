@@ -0,0 +1,137 @@
+package windows
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// workerIgnitionPath is the location the worker ignition is downloaded to on the VM, for the bootstrapper
+	workerIgnitionPath = winTemp + "worker.ign"
+	// ignitionKubeconfigPath is the path, in the worker ignition, of the bootstrap kubeconfig
+	ignitionKubeconfigPath = "/etc/kubernetes/kubeconfig"
+	// ignitionKubeletCAPath is the path, in the worker ignition, of the CA bundle kubelet authenticates clients with
+	ignitionKubeletCAPath = "/etc/kubernetes/kubelet-ca.crt"
+	// workerIgnitionCacheTTL is the time for which the worker ignition downloaded by a VM is reused for all VMs. The
+	// worker ignition only changes when the cluster CAs rotate or the bootstrap credentials are renewed, which the
+	// checks using it detect well within their periods.
+	workerIgnitionCacheTTL = 30 * time.Minute
+)
+
+// workerIgnitionCache holds the worker ignition last downloaded by any VM, so that the checks of the configured VMs
+// do not each download it
+var workerIgnitionCache struct {
+	sync.Mutex
+	// ignition is the worker ignition, nil until downloaded
+	ignition []byte
+	// downloaded is the time at which the worker ignition was downloaded
+	downloaded time.Time
+}
+
+// ignitionConfig is the part of the worker ignition holding the files it writes
+type ignitionConfig struct {
+	Storage struct {
+		Files []struct {
+			Path     string `json:"path"`
+			Contents struct {
+				Compression string `json:"compression"`
+				Source      string `json:"source"`
+			} `json:"contents"`
+		} `json:"files"`
+	} `json:"storage"`
+}
+
+// workerIgnitionFiles returns the contents of the files the current worker ignition writes at the given paths
+func (vm *windows) workerIgnitionFiles(paths ...string) (map[string][]byte, error) {
+	ignition, err := vm.workerIgnition()
+	if err != nil {
+		return nil, err
+	}
+	return parseIgnitionFiles(ignition, paths...)
+}
+
+// workerIgnition returns the current worker ignition, downloading it onto the VM unless it was downloaded by any VM
+// within workerIgnitionCacheTTL
+func (vm *windows) workerIgnition() ([]byte, error) {
+	workerIgnitionCache.Lock()
+	defer workerIgnitionCache.Unlock()
+	if workerIgnitionCache.ignition != nil && time.Since(workerIgnitionCache.downloaded) < workerIgnitionCacheTTL {
+		return workerIgnitionCache.ignition, nil
+	}
+	if err := vm.initializeBootstrapperFiles(); err != nil {
+		return nil, errors.Wrap(err, "error downloading the worker ignition")
+	}
+	out, err := vm.Run("Get-Content -Raw "+workerIgnitionPath, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", workerIgnitionPath)
+	}
+	workerIgnitionCache.ignition = []byte(out)
+	workerIgnitionCache.downloaded = time.Now()
+	return workerIgnitionCache.ignition, nil
+}
+
+// parseIgnitionFiles returns the contents of the files the given ignition writes at the given paths
+func parseIgnitionFiles(ignition []byte, paths ...string) (map[string][]byte, error) {
+	config := &ignitionConfig{}
+	if err := json.Unmarshal(ignition, config); err != nil {
+		return nil, errors.Wrap(err, "invalid ignition")
+	}
+	files := make(map[string][]byte)
+	for _, path := range paths {
+		for _, file := range config.Storage.Files {
+			if file.Path != path {
+				continue
+			}
+			contents, err := decodeDataURL(file.Contents.Source)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid contents of ignition file %s", path)
+			}
+			if file.Contents.Compression == "gzip" {
+				reader, err := gzip.NewReader(bytes.NewReader(contents))
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid compressed contents of ignition file %s", path)
+				}
+				if contents, err = ioutil.ReadAll(reader); err != nil {
+					return nil, errors.Wrapf(err, "invalid compressed contents of ignition file %s", path)
+				}
+			} else if file.Contents.Compression != "" {
+				return nil, errors.Errorf("unsupported compression %q of ignition file %s",
+					file.Contents.Compression, path)
+			}
+			files[path] = contents
+		}
+		if _, found := files[path]; !found {
+			return nil, errors.Errorf("ignition file %s not found", path)
+		}
+	}
+	return files, nil
+}
+
+// decodeDataURL returns the data of the given data URL, either base64 or percent encoded
+func decodeDataURL(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "data:") {
+		return nil, errors.Errorf("unsupported source %q", source)
+	}
+	comma := strings.Index(source, ",")
+	if comma < 0 {
+		return nil, errors.New("data URL without data")
+	}
+	header, data := source[len("data:"):comma], source[comma+1:]
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(decoded), nil
+}
@@ -0,0 +1,51 @@
+package windows
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseIgnitionFiles tests the parseIgnitionFiles function
+func TestParseIgnitionFiles(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte("compressed CA"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	ignition := []byte(`{"ignition":{"version":"3.1.0"},"storage":{"files":[
+		{"path":"/etc/kubernetes/kubeconfig","contents":{"source":"data:,apiVersion%3A%20v1"}},
+		{"path":"/etc/kubernetes/kubelet-ca.crt","contents":{"source":"data:;base64,` +
+		base64.StdEncoding.EncodeToString([]byte("CA")) + `"}},
+		{"path":"/etc/kubernetes/ca.crt","contents":{"compression":"gzip","source":"data:;base64,` +
+		base64.StdEncoding.EncodeToString(compressed.Bytes()) + `"}},
+		{"path":"/etc/kubernetes/remote","contents":{"source":"https://example.com/file"}}]}}`)
+
+	testCases := []struct {
+		name      string
+		path      string
+		expected  string
+		expectErr bool
+	}{
+		{name: "percent encoded", path: "/etc/kubernetes/kubeconfig", expected: "apiVersion: v1"},
+		{name: "base64 encoded", path: "/etc/kubernetes/kubelet-ca.crt", expected: "CA"},
+		{name: "compressed", path: "/etc/kubernetes/ca.crt", expected: "compressed CA"},
+		{name: "remote source", path: "/etc/kubernetes/remote", expectErr: true},
+		{name: "missing file", path: "/etc/kubernetes/missing", expectErr: true},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := parseIgnitionFiles(ignition, test.path)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(files[test.path]))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package windows
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pauseImageArg is the kubelet argument setting the pause image of the pod sandboxes
+const pauseImageArg = "--pod-infra-container-image"
+
+// pauseImage returns the pause image kubelet runs the pod sandboxes with
+func (vm *windows) pauseImage() (string, error) {
+	cmdLine, err := vm.getKubeletCmdLine()
+	if err != nil {
+		return "", err
+	}
+	if image := argValue(cmdLine, pauseImageArg); image != "" {
+		return image, nil
+	}
+	return DefaultPauseImage, nil
+}
+
+func (vm *windows) PullImages() error {
+	pauseImage, err := vm.pauseImage()
+	if err != nil {
+		return errors.Wrap(err, "unable to determine the pause image")
+	}
+	images := []string{pauseImage}
+	for _, image := range vm.host.PrePullImages {
+		if image != pauseImage {
+			images = append(images, image)
+		}
+	}
+	// Every image is attempted, so that an unavailable image does not prevent the others from being pulled
+	var failed []string
+	for _, image := range images {
+		start := time.Now()
+		if out, err := vm.Run("docker pull "+image, false); err != nil {
+			vm.log.Error(err, "unable to pull image", "image", image, "output", out)
+			failed = append(failed, image)
+			continue
+		}
+		vm.log.Info("pulled image", "image", image, "duration", time.Since(start).Round(time.Second))
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("unable to pull images %v", failed)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package windows
+
+import (
+	"strings"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	// awsInterruptionCmd prints the action and time of the spot instance interruption notice of an AWS instance,
+	// retrieved from the instance metadata service with an IMDSv2 token. The instance action is not found until the
+	// instance is interrupted.
+	awsInterruptionCmd = "\"$t = Invoke-RestMethod -Method Put -Uri http://169.254.169.254/latest/api/token " +
+		"-Headers @{'X-aws-ec2-metadata-token-ttl-seconds'='60'} -TimeoutSec 5; " +
+		"try { $a = Invoke-RestMethod -Uri http://169.254.169.254/latest/meta-data/spot/instance-action " +
+		"-Headers @{'X-aws-ec2-metadata-token'=$t} -TimeoutSec 5; $a.action + ' at ' + $a.time } " +
+		"catch { if ($_.Exception.Response.StatusCode.value__ -ne 404) { throw } }\""
+	// azureInterruptionCmd prints the type and time of the scheduled events of an Azure instance removing it, retrieved
+	// from the instance metadata service
+	azureInterruptionCmd = "\"$e = Invoke-RestMethod " +
+		"-Uri 'http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01' -Headers @{Metadata='true'} " +
+		"-TimeoutSec 5; foreach ($ev in $e.Events) { if (@('Preempt','Terminate') -contains $ev.EventType) { " +
+		"$ev.EventType + ' at ' + $ev.NotBefore } }\""
+)
+
+func (vm *windows) InterruptionNotice() (string, error) {
+	var cmd string
+	switch vm.platform {
+	case oconfig.AWSPlatformType:
+		cmd = awsInterruptionCmd
+	case oconfig.AzurePlatformType:
+		cmd = azureInterruptionCmd
+	default:
+		return "", nil
+	}
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return "", errors.Wrapf(err, "error checking for an interruption notice with output: %s", out)
+	}
+	return strings.Join(parseChanges(out), "; "), nil
+}
@@ -0,0 +1,87 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Inventory describes the hardware of the VM
+type Inventory struct {
+	// CPUModel is the name of the processors of the VM
+	CPUModel string `json:"cpuModel"`
+	// CPUs is the number of logical processors of the VM
+	CPUs int `json:"cpus"`
+	// MemoryBytes is the physical memory of the VM
+	MemoryBytes int64 `json:"memoryBytes"`
+	// SystemDiskBytes is the size of the system drive of the VM
+	SystemDiskBytes int64 `json:"systemDiskBytes"`
+	// NICs are the physical network adapters of the VM
+	NICs []NIC `json:"nics,omitempty"`
+}
+
+// NIC is a physical network adapter of the VM
+type NIC struct {
+	// Name is the description of the adapter
+	Name string `json:"name"`
+	// SpeedBitsPerSecond is the link speed of the adapter
+	SpeedBitsPerSecond int64 `json:"speedBitsPerSecond"`
+}
+
+func (vm *windows) Inventory() (*Inventory, error) {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"$cpu = @(Get-CimInstance -ClassName Win32_Processor); " +
+		"'cpu ' + ($cpu | Measure-Object -Property NumberOfLogicalProcessors -Sum).Sum + '|' + $cpu[0].Name; " +
+		"'memory ' + (Get-CimInstance -ClassName Win32_ComputerSystem).TotalPhysicalMemory; " +
+		"$disk = Get-PSDrive -Name $env:SystemDrive.TrimEnd(':'); 'disk ' + ($disk.Used + $disk.Free); " +
+		"Get-NetAdapter -Physical | ForEach-Object { 'nic ' + $_.Speed + '|' + $_.InterfaceDescription }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error collecting hardware inventory with output: %s", out)
+	}
+	return parseInventory(out)
+}
+
+// parseInventory parses the hardware inventory reported one item per line, as "cpu <count>|<model>",
+// "memory <bytes>", "disk <bytes>" and "nic <speed>|<name>" lines
+func parseInventory(out string) (*Inventory, error) {
+	inventory := &Inventory{}
+	for _, line := range parseChanges(out) {
+		tokens := strings.SplitN(line, " ", 2)
+		if len(tokens) != 2 {
+			return nil, errors.Errorf("unexpected hardware inventory output %q", line)
+		}
+		var err error
+		switch tokens[0] {
+		case "cpu":
+			fields := strings.SplitN(tokens[1], "|", 2)
+			if len(fields) != 2 {
+				return nil, errors.Errorf("unexpected processor output %q", line)
+			}
+			inventory.CPUModel = strings.TrimSpace(fields[1])
+			inventory.CPUs, err = strconv.Atoi(fields[0])
+		case "memory":
+			inventory.MemoryBytes, err = strconv.ParseInt(tokens[1], 10, 64)
+		case "disk":
+			inventory.SystemDiskBytes, err = strconv.ParseInt(tokens[1], 10, 64)
+		case "nic":
+			fields := strings.SplitN(tokens[1], "|", 2)
+			if len(fields) != 2 {
+				return nil, errors.Errorf("unexpected network adapter output %q", line)
+			}
+			nic := NIC{Name: fields[1]}
+			nic.SpeedBitsPerSecond, err = strconv.ParseInt(fields[0], 10, 64)
+			inventory.NICs = append(inventory.NICs, nic)
+		default:
+			return nil, errors.Errorf("unexpected hardware inventory output %q", line)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid hardware inventory item %q", line)
+		}
+	}
+	if inventory.CPUs == 0 || inventory.MemoryBytes == 0 {
+		return nil, errors.Errorf("incomplete hardware inventory output %q", out)
+	}
+	return inventory, nil
+}
@@ -0,0 +1,23 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseInventory tests the parseInventory function
+func TestParseInventory(t *testing.T) {
+	inventory, err := parseInventory("cpu 4|Intel(R) Xeon(R) Platinum 8259CL CPU @ 2.50GHz\r\n" +
+		"memory 17179398144\r\ndisk 128847958016\r\nnic 25000000000|Amazon Elastic Network Adapter\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &Inventory{CPUModel: "Intel(R) Xeon(R) Platinum 8259CL CPU @ 2.50GHz", CPUs: 4,
+		MemoryBytes: 17179398144, SystemDiskBytes: 128847958016,
+		NICs: []NIC{{Name: "Amazon Elastic Network Adapter", SpeedBitsPerSecond: 25000000000}}}, inventory)
+
+	_, err = parseInventory("memory 17179398144\r\n")
+	assert.Error(t, err)
+	_, err = parseInventory("cpu 4|Xeon\r\nmemory many\r\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IsolationProcess is the isolation mode running the container as a process sharing the kernel of the VM, which
+// requires the container image to be built for the same OS build as the VM. Hyper-V isolation, which runs the
+// containers of images built for older OS builds in a utility VM, is not supported by the Docker runtime of the nodes.
+const IsolationProcess = "process"
+
+// IsolationModes describes the container isolation modes supported by the VM
+type IsolationModes struct {
+	// Build is the OS build number of the VM, such as 17763 for Windows Server 2019
+	Build int
+}
+
+func (vm *windows) IsolationModes() (*IsolationModes, error) {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"(Get-ItemProperty 'HKLM:\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion').CurrentBuildNumber\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting container isolation modes with output: %s", out)
+	}
+	return parseIsolationModes(out)
+}
+
+// parseIsolationModes parses the OS build number of the VM, reported on a single line
+func parseIsolationModes(out string) (*IsolationModes, error) {
+	lines := parseChanges(out)
+	if len(lines) != 1 {
+		return nil, errors.Errorf("unexpected isolation modes output %q", out)
+	}
+	build, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid OS build number %q", lines[0])
+	}
+	return &IsolationModes{Build: build}, nil
+}
+
+// ContainerIsolation returns the isolation mode the containers of images built for the given OS build number can run
+// with on the VM: process isolation when the builds match. An empty string is returned when the containers cannot run
+// on the VM.
+func (m *IsolationModes) ContainerIsolation(imageBuild int) string {
+	if imageBuild == m.Build {
+		return IsolationProcess
+	}
+	return ""
+}
+
+// ParseBuild returns the build number of the given Windows version, given either as a build number, such as 17763, or
+// as a major.minor.build version, such as 10.0.17763, as found in the os.version of Windows container images
+func ParseBuild(version string) (int, error) {
+	tokens := strings.Split(strings.TrimSpace(version), ".")
+	if len(tokens) != 1 && len(tokens) != 3 && len(tokens) != 4 {
+		return 0, errors.Errorf("invalid Windows version %q: expected a build number or major.minor.build", version)
+	}
+	token := tokens[0]
+	if len(tokens) > 1 {
+		token = tokens[2]
+	}
+	build, err := strconv.Atoi(token)
+	if err != nil || build <= 0 {
+		return 0, errors.Errorf("invalid Windows version %q: expected a build number or major.minor.build", version)
+	}
+	return build, nil
+}
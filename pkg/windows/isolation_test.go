@@ -0,0 +1,41 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseIsolationModes tests the parseIsolationModes function
+func TestParseIsolationModes(t *testing.T) {
+	modes, err := parseIsolationModes("17763\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &IsolationModes{Build: 17763}, modes)
+
+	_, err = parseIsolationModes("17763\r\nTrue\r\n")
+	assert.Error(t, err)
+	_, err = parseIsolationModes("")
+	assert.Error(t, err)
+}
+
+// TestContainerIsolation tests the ContainerIsolation method
+func TestContainerIsolation(t *testing.T) {
+	modes := &IsolationModes{Build: 19041}
+	assert.Equal(t, IsolationProcess, modes.ContainerIsolation(19041))
+	assert.Equal(t, "", modes.ContainerIsolation(17763))
+	assert.Equal(t, "", modes.ContainerIsolation(20348))
+}
+
+// TestParseBuild tests the ParseBuild function
+func TestParseBuild(t *testing.T) {
+	for version, expected := range map[string]int{"17763": 17763, "10.0.17763": 17763, "10.0.17763.1879": 17763} {
+		build, err := ParseBuild(version)
+		require.NoError(t, err, version)
+		assert.Equal(t, expected, build, version)
+	}
+	for _, version := range []string{"", "ltsc2019", "10.0", "10.0.x"} {
+		_, err := ParseBuild(version)
+		assert.Error(t, err, version)
+	}
+}
@@ -0,0 +1,233 @@
+package windows
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// kubeletConfigPath is the location of the kubelet configuration file generated by the bootstrapper
+	kubeletConfigPath = k8sDir + "kubelet.conf"
+	// bootstrapKubeletConfigPath is the location of the copy of the kubelet configuration file, as generated by the
+	// bootstrapper
+	bootstrapKubeletConfigPath = k8sDir + "kubelet.conf.bootstrap"
+	// bootstrapKubeletCmdLinePath is the location of the kubelet service command line, as configured by the
+	// bootstrapper
+	bootstrapKubeletCmdLinePath = k8sDir + "kubelet.cmdline.bootstrap"
+)
+
+// KubeletSettings holds the kubelet settings applied on top of the configuration generated by the bootstrapper
+type KubeletSettings struct {
+	// Args are added to the kubelet command line, replacing the value of arguments with the same name
+	Args []string `json:"args,omitempty"`
+	// Config holds KubeletConfiguration fields merged into the kubelet configuration file
+	Config map[string]interface{} `json:"config,omitempty"`
+	// CredentialProviders configures kubelet to retrieve the credentials of the registries of the platform, on which
+	// the VM authenticates with its instance identity, from the image credential provider of the platform
+	CredentialProviders bool `json:"credentialProviders,omitempty"`
+}
+
+// IsEmpty returns true if the settings do not change the configuration generated by the bootstrapper
+func (k KubeletSettings) IsEmpty() bool {
+	return len(k.Args) == 0 && len(k.Config) == 0 && !k.CredentialProviders
+}
+
+// saveBootstrapKubeletConfig saves the kubelet configuration file and command line generated by the bootstrapper, so
+// that the kubelet settings can be applied again, or removed, without running the bootstrapper
+func (vm *windows) saveBootstrapKubeletConfig() error {
+	if out, err := vm.Run("Copy-Item -Force "+kubeletConfigPath+" "+bootstrapKubeletConfigPath, true); err != nil {
+		return errors.Wrapf(err, "error copying %s with output: %s", kubeletConfigPath, out)
+	}
+	cmdLine, err := vm.getKubeletCmdLine()
+	if err != nil {
+		return err
+	}
+	return vm.writeFile(bootstrapKubeletCmdLinePath, []byte(cmdLine))
+}
+
+func (vm *windows) ConfigureKubelet() error {
+	configSaved, err := vm.FileExists(bootstrapKubeletCmdLinePath)
+	if err != nil {
+		return err
+	}
+	if err := vm.configureCredentialProvider(); err != nil {
+		return errors.Wrap(err, "error configuring the kubelet image credential provider")
+	}
+	args := vm.kubeletArgs()
+	kubeletConfig := vm.kubeletConfig()
+	if !configSaved {
+		if len(args) == 0 && len(kubeletConfig) == 0 {
+			return nil
+		}
+		// The node was configured before the bootstrapper configuration was saved, so it has not been modified
+		if err := vm.saveBootstrapKubeletConfig(); err != nil {
+			return errors.Wrap(err, "error saving the kubelet configuration generated by the bootstrapper")
+		}
+	}
+
+	bootstrapCmdLine, err := vm.Run("Get-Content -Raw "+bootstrapKubeletCmdLinePath, true)
+	if err != nil {
+		return errors.Wrapf(err, "error reading %s", bootstrapKubeletCmdLinePath)
+	}
+	cmdLine, err := vm.getKubeletCmdLine()
+	if err != nil {
+		return err
+	}
+	desiredCmdLine := mergeArgs(strings.TrimSpace(bootstrapCmdLine), args)
+	updateCmdLine := desiredCmdLine != strings.Join(strings.Fields(cmdLine), " ")
+
+	bootstrapConfig, err := vm.readKubeletConfig(bootstrapKubeletConfigPath)
+	if err != nil {
+		return err
+	}
+	config, err := vm.readKubeletConfig(kubeletConfigPath)
+	if err != nil {
+		return err
+	}
+	desiredConfig := mergeConfig(bootstrapConfig, kubeletConfig)
+	updateConfig := !reflect.DeepEqual(desiredConfig, config)
+
+	if !updateCmdLine && !updateConfig {
+		return nil
+	}
+	vm.log.Info("updating kubelet configuration", "args", vm.kubelet.Args, "config", vm.kubelet.Config)
+	svc := &service{name: kubeletServiceName}
+	if err := vm.ensureServiceNotRunning(svc); err != nil {
+		return errors.Wrapf(err, "error stopping %s service", kubeletServiceName)
+	}
+	if updateConfig {
+		data, err := json.Marshal(desiredConfig)
+		if err != nil {
+			return errors.Wrap(err, "error encoding kubelet configuration")
+		}
+		if err := vm.writeFile(kubeletConfigPath, data); err != nil {
+			return err
+		}
+	}
+	if updateCmdLine {
+		if err := vm.services.SetCmdLine(kubeletServiceName, desiredCmdLine); err != nil {
+			return err
+		}
+	}
+	if err := vm.startService(svc); err != nil {
+		return errors.Wrapf(err, "error starting %s service", kubeletServiceName)
+	}
+	return nil
+}
+
+func (vm *windows) RestartKubelet() error {
+	svc := &service{name: kubeletServiceName}
+	if err := vm.ensureServiceNotRunning(svc); err != nil {
+		return errors.Wrapf(err, "error stopping %s service", kubeletServiceName)
+	}
+	if err := vm.startService(svc); err != nil {
+		return errors.Wrapf(err, "error starting %s service", kubeletServiceName)
+	}
+	return nil
+}
+
+// kubeletArgs returns the arguments added to the kubelet command line: the arguments of the kubelet settings, preceded
+// by the name of the node if it must not be the host name of the VM, and by the location of the image credential
+// provider configuration if kubelet runs a credential provider
+func (vm *windows) kubeletArgs() []string {
+	var args []string
+	if nodeName := NodeNameOverride(vm.platform, vm.machineName); nodeName != "" {
+		args = append(args, "--hostname-override="+nodeName)
+	}
+	if vm.credentialProvider() != nil {
+		args = append(args, credentialProviderArgs()...)
+	}
+	if len(args) == 0 {
+		return vm.kubelet.Args
+	}
+	return append(args, vm.kubelet.Args...)
+}
+
+// kubeletConfig returns the fields merged into the kubelet configuration file: the configuration of the kubelet
+// settings, with the feature gate enabling the image credential providers if kubelet runs a credential provider
+func (vm *windows) kubeletConfig() map[string]interface{} {
+	if vm.credentialProvider() == nil {
+		return vm.kubelet.Config
+	}
+	featureGates := map[string]interface{}{
+		"featureGates": map[string]interface{}{credentialProvidersFeatureGate: true},
+	}
+	return mergeConfig(vm.kubelet.Config, featureGates)
+}
+
+// getKubeletCmdLine returns the command line of the kubelet service
+func (vm *windows) getKubeletCmdLine() (string, error) {
+	return vm.getServiceCmdLine(kubeletServiceName)
+}
+
+// getServiceCmdLine returns the command line of the given service
+func (vm *windows) getServiceCmdLine(serviceName string) (string, error) {
+	return vm.services.CmdLine(serviceName)
+}
+
+// readKubeletConfig returns the contents of the given kubelet configuration file, in either YAML or JSON format
+func (vm *windows) readKubeletConfig(path string) (map[string]interface{}, error) {
+	out, err := vm.Run("Get-Content -Raw "+path, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", path)
+	}
+	data, err := yaml.ToJSON([]byte(out))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error decoding %s", path)
+	}
+	config := make(map[string]interface{})
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrapf(err, "error decoding %s", path)
+	}
+	return config, nil
+}
+
+// writeFile writes the given data to the file at the given path on the Windows VM, replacing its contents
+func (vm *windows) writeFile(path string, data []byte) error {
+	cmd := "[IO.File]::WriteAllBytes('" + path + "', [Convert]::FromBase64String('" +
+		base64.StdEncoding.EncodeToString(data) + "'))"
+	if out, err := vm.Run(cmd, true); err != nil {
+		return errors.Wrapf(err, "error writing %s with output: %s", path, out)
+	}
+	return nil
+}
+
+// mergeArgs returns the given command line with the given arguments, in the --name=value format, appended. Arguments
+// of the command line with the same name are removed.
+func mergeArgs(cmdLine string, args []string) string {
+	names := make(map[string]bool)
+	for _, arg := range args {
+		names[strings.SplitN(arg, "=", 2)[0]] = true
+	}
+	var merged []string
+	for _, token := range strings.Fields(cmdLine) {
+		if !names[strings.SplitN(token, "=", 2)[0]] {
+			merged = append(merged, token)
+		}
+	}
+	return strings.Join(append(merged, args...), " ")
+}
+
+// mergeConfig returns a copy of the given base configuration with the given overlay merged into it. Nested objects
+// are merged recursively, other values of the overlay replace the values of the base configuration.
+func mergeConfig(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		baseObject, baseIsObject := merged[key].(map[string]interface{})
+		overlayObject, overlayIsObject := value.(map[string]interface{})
+		if baseIsObject && overlayIsObject {
+			merged[key] = mergeConfig(baseObject, overlayObject)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
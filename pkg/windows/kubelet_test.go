@@ -0,0 +1,38 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMergeArgs tests the mergeArgs function
+func TestMergeArgs(t *testing.T) {
+	cmdLine := `C:\k\kubelet.exe --config=C:\k\kubelet.conf  --windows-service --v=3`
+	assert.Equal(t, `C:\k\kubelet.exe --config=C:\k\kubelet.conf --windows-service --v=3`, mergeArgs(cmdLine, nil))
+	assert.Equal(t, `C:\k\kubelet.exe --config=C:\k\kubelet.conf --windows-service --v=5 --max-pods=50`,
+		mergeArgs(cmdLine, []string{"--v=5", "--max-pods=50"}))
+	assert.Equal(t, `C:\k\kubelet.exe --config=C:\k\kubelet.conf --v=3 --windows-service`,
+		mergeArgs(cmdLine, []string{"--windows-service"}))
+}
+
+// TestMergeConfig tests the mergeConfig function
+func TestMergeConfig(t *testing.T) {
+	base := map[string]interface{}{
+		"kind":         "KubeletConfiguration",
+		"maxPods":      float64(250),
+		"evictionHard": map[string]interface{}{"memory.available": "100Mi", "nodefs.available": "10%"},
+	}
+	overlay := map[string]interface{}{
+		"maxPods":      float64(100),
+		"evictionHard": map[string]interface{}{"memory.available": "500Mi"},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"kind":         "KubeletConfiguration",
+		"maxPods":      float64(100),
+		"evictionHard": map[string]interface{}{"memory.available": "500Mi", "nodefs.available": "10%"},
+	}, mergeConfig(base, overlay))
+	// The base configuration is not modified
+	assert.Equal(t, float64(250), base["maxPods"])
+	assert.Equal(t, "100Mi", base["evictionHard"].(map[string]interface{})["memory.available"])
+}
@@ -0,0 +1,116 @@
+package windows
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// kubeletCertDir is the directory in which kubelet stores its certificates
+	kubeletCertDir = "C:\\var\\lib\\kubelet\\pki\\"
+	// kubeletClientCertPath is the location of the current client certificate of kubelet, along with its private key
+	kubeletClientCertPath = kubeletCertDir + "kubelet-client-current.pem"
+)
+
+// Certificates of the Kubernetes components of a Windows VM
+const (
+	// CertificateKubeletClient is the client certificate kubelet authenticates to the API server with
+	CertificateKubeletClient = "kubelet-client"
+	// CertificateKubeletServing is the serving certificate of kubelet, also used by kube-rbac-proxy to serve metrics
+	CertificateKubeletServing = "kubelet-serving"
+	// CertificateKubeletCA is the CA bundle written by WMCO, which kubelet authenticates clients with
+	CertificateKubeletCA = "kubelet-ca"
+)
+
+// certificatePaths are the locations of the certificates of the Kubernetes components of the VM, by Certificate name
+var certificatePaths = map[string]string{
+	CertificateKubeletClient:  kubeletClientCertPath,
+	CertificateKubeletServing: kubeletServingCertPath,
+	CertificateKubeletCA:      kubeletCAPath,
+}
+
+// CertificateValidity is the validity period of a certificate
+type CertificateValidity struct {
+	// NotBefore is the time from which the certificate is valid
+	NotBefore time.Time
+	// NotAfter is the time at which the certificate expires
+	NotAfter time.Time
+}
+
+func (vm *windows) KubeletClientCertificate() (*CertificateValidity, error) {
+	out, err := vm.Run("Get-Content -Raw "+kubeletClientCertPath, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", kubeletClientCertPath)
+	}
+	cert, err := parseCertificate([]byte(out))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid kubelet client certificate %s", kubeletClientCertPath)
+	}
+	return &CertificateValidity{NotBefore: cert.NotBefore, NotAfter: cert.NotAfter}, nil
+}
+
+func (vm *windows) RenewKubeletClientCertificate() error {
+	svc := &service{name: kubeletServiceName}
+	if err := vm.ensureServiceNotRunning(svc); err != nil {
+		return errors.Wrapf(err, "error stopping %s service", kubeletServiceName)
+	}
+	// Without a client certificate, kubelet requests a new one with the credentials of the bootstrap kubeconfig
+	if out, err := vm.Run("Remove-Item -Force -ErrorAction SilentlyContinue "+kubeletCertDir+"kubelet-client-*.pem",
+		true); err != nil {
+		return errors.Wrapf(err, "error removing the kubelet client certificates with output: %s", out)
+	}
+	if err := vm.startService(svc); err != nil {
+		return errors.Wrapf(err, "error starting %s service", kubeletServiceName)
+	}
+	return nil
+}
+
+func (vm *windows) Certificates() (map[string]*CertificateValidity, error) {
+	validities := make(map[string]*CertificateValidity, len(certificatePaths))
+	for name, path := range certificatePaths {
+		out, err := vm.Run("\"if (Test-Path "+psString(path)+") { Get-Content -Raw "+psString(path)+" }\"", true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", path)
+		}
+		if strings.TrimSpace(out) == "" {
+			continue
+		}
+		validity, err := firstExpiringCertificate([]byte(out))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid certificate %s", path)
+		}
+		validities[name] = validity
+	}
+	return validities, nil
+}
+
+// firstExpiringCertificate returns the validity period of the first expiring certificate of the given PEM data, which
+// may also hold private keys
+func firstExpiringCertificate(data []byte) (*CertificateValidity, error) {
+	var first *CertificateValidity
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse certificate")
+		}
+		if first == nil || cert.NotAfter.Before(first.NotAfter) {
+			first = &CertificateValidity{NotBefore: cert.NotBefore, NotAfter: cert.NotAfter}
+		}
+	}
+	if first == nil {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return first, nil
+}
@@ -0,0 +1,41 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// kubeProxyDSRArgs returns the kube-proxy arguments enabling or disabling Direct Server Return, along with the WinDSR
+// feature gate it requires
+func kubeProxyDSRArgs(dsr bool) []string {
+	featureGates := "--feature-gates=WinOverlay=true,IPv6DualStack=false"
+	if dsr {
+		featureGates += ",WinDSR=true"
+	}
+	return []string{featureGates, "--enable-dsr=" + strconv.FormatBool(dsr)}
+}
+
+func (vm *windows) ReconfigureKubeProxy() error {
+	cmdLine, err := vm.getServiceCmdLine(kubeProxyServiceName)
+	if err != nil {
+		return err
+	}
+	desiredCmdLine := mergeArgs(cmdLine, kubeProxyDSRArgs(vm.host.KubeProxyDSR))
+	if desiredCmdLine == strings.Join(strings.Fields(cmdLine), " ") {
+		return nil
+	}
+	vm.log.Info("updating kube-proxy configuration", "dsr", vm.host.KubeProxyDSR)
+	svc := &service{name: kubeProxyServiceName}
+	if err := vm.ensureServiceNotRunning(svc); err != nil {
+		return errors.Wrapf(err, "error stopping %s service", kubeProxyServiceName)
+	}
+	if err := vm.services.SetCmdLine(kubeProxyServiceName, desiredCmdLine); err != nil {
+		return err
+	}
+	if err := vm.startService(svc); err != nil {
+		return errors.Wrapf(err, "error starting %s service", kubeProxyServiceName)
+	}
+	return nil
+}
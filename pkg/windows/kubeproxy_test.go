@@ -0,0 +1,18 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubeProxyDSRArgs(t *testing.T) {
+	cmdLine := `c:\k\kube-proxy.exe --windows-service --v=4 --proxy-mode=kernelspace ` +
+		`--feature-gates=WinOverlay=true --source-vip=10.132.0.2 --enable-dsr=false --feature-gates=IPv6DualStack=false`
+	assert.Equal(t, `c:\k\kube-proxy.exe --windows-service --v=4 --proxy-mode=kernelspace --source-vip=10.132.0.2 `+
+		`--feature-gates=WinOverlay=true,IPv6DualStack=false,WinDSR=true --enable-dsr=true`,
+		mergeArgs(cmdLine, kubeProxyDSRArgs(true)))
+	assert.Equal(t, `c:\k\kube-proxy.exe --windows-service --v=4 --proxy-mode=kernelspace --source-vip=10.132.0.2 `+
+		`--feature-gates=WinOverlay=true,IPv6DualStack=false --enable-dsr=false`,
+		mergeArgs(cmdLine, kubeProxyDSRArgs(false)))
+}
@@ -0,0 +1,69 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// licenseStatuses are the names of the license statuses of the SoftwareLicensingProduct class, indexed by value
+var licenseStatuses = []string{"Unlicensed", "Licensed", "OOBGrace", "OOTGrace", "NonGenuineGrace", "Notification",
+	"ExtendedGrace"}
+
+// licensed is the license status of an activated Windows installation
+const licensed = 1
+
+// License describes the activation status of the Windows installation of the VM
+type License struct {
+	// Status is the license status of the installation, as reported by the SoftwareLicensingProduct class
+	Status int
+	// Description is the description of the license, such as a volume or time based evaluation license
+	Description string
+	// Remaining is the time remaining before the grace period or the time based license of the installation expires,
+	// zero if it does not expire
+	Remaining time.Duration
+}
+
+func (vm *windows) License() (*License, error) {
+	out, err := vm.Run("\"$ErrorActionPreference = 'Stop'; Get-CimInstance -ClassName SoftwareLicensingProduct | "+
+		"Where-Object { $_.PartialProductKey -and $_.Name -like 'Windows*' } | Select-Object -First 1 | "+
+		"ForEach-Object { '' + $_.LicenseStatus + '|' + $_.GracePeriodRemaining + '|' + $_.Description }\"", true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting license status with output: %s", out)
+	}
+	return parseLicense(out)
+}
+
+// parseLicense parses the license status reported as "<status>|<grace period remaining in minutes>|<description>",
+// an installation without product key being unlicensed
+func parseLicense(out string) (*License, error) {
+	lines := parseChanges(out)
+	if len(lines) == 0 {
+		return &License{Description: "no Windows product key installed"}, nil
+	}
+	tokens := strings.SplitN(lines[0], "|", 3)
+	if len(tokens) != 3 {
+		return nil, errors.Errorf("unexpected license output %q", out)
+	}
+	status, err := strconv.Atoi(tokens[0])
+	if err != nil || status < 0 || status >= len(licenseStatuses) {
+		return nil, errors.Errorf("invalid license status %q", tokens[0])
+	}
+	minutes, err := strconv.ParseInt(tokens[1], 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid grace period %q", tokens[1])
+	}
+	return &License{Status: status, Description: tokens[2], Remaining: time.Duration(minutes) * time.Minute}, nil
+}
+
+// Activated returns true if the Windows installation is activated
+func (l *License) Activated() bool {
+	return l.Status == licensed
+}
+
+// StatusName returns the name of the license status, such as Licensed or OOBGrace
+func (l *License) StatusName() string {
+	return licenseStatuses[l.Status]
+}
@@ -0,0 +1,29 @@
+package windows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseLicense tests the parseLicense function
+func TestParseLicense(t *testing.T) {
+	license, err := parseLicense("1|86400|Windows(R) Operating System, TIMEBASED_EVAL channel\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &License{Status: 1, Description: "Windows(R) Operating System, TIMEBASED_EVAL channel",
+		Remaining: 60 * 24 * time.Hour}, license)
+	assert.True(t, license.Activated())
+	assert.Equal(t, "Licensed", license.StatusName())
+
+	license, err = parseLicense("")
+	require.NoError(t, err)
+	assert.False(t, license.Activated())
+	assert.Equal(t, "Unlicensed", license.StatusName())
+
+	_, err = parseLicense("9|0|Windows")
+	assert.Error(t, err)
+	_, err = parseLicense("1|Windows")
+	assert.Error(t, err)
+}
@@ -0,0 +1,245 @@
+package windows
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig/payload"
+)
+
+// manifest is the desired state of the resources WMCO manages on the Windows VM. It is rendered from the host settings
+// and applied by scripts comparing each resource with its state on the VM, which only change the resources which
+// drifted and report one line per change, so that applying an unchanged manifest leaves the VM untouched.
+type manifest struct {
+	// directories are created if missing
+	directories []string
+	// files are transferred from the payload of the operator if missing or with a different content
+	files []manifestFile
+	// registryValues are set if missing or with a different value
+	registryValues []registryValue
+	// disabledServices are stopped and disabled if they exist
+	disabledServices []string
+	// firewallRules are created, or corrected if they were modified
+	firewallRules []firewallRule
+}
+
+// manifestFile is a payload file transferred to a remote directory
+type manifestFile struct {
+	file      *payload.FileInfo
+	remoteDir string
+}
+
+// remotePath returns the location of the file on the VM
+func (f manifestFile) remotePath() string {
+	return strings.TrimSuffix(f.remoteDir, "\\") + "\\" + filepath.Base(f.file.Path)
+}
+
+// registryValue is a value of a registry key, set as a string or, for integers, as a DWORD
+type registryValue struct {
+	key   string
+	name  string
+	value interface{}
+}
+
+// desiredState renders the manifest of the VM from its host settings. The payload files, and the directories of the
+// Kubernetes components, are only included if withPayload is set, as they cannot be replaced while the node runs.
+func (vm *windows) desiredState(withPayload bool) (*manifest, error) {
+	m := &manifest{}
+	if withPayload {
+		m.directories = []string{k8sDir, remoteDir, cniDir, cniConfDir, logDir, kubeProxyLogDir, hybridOverlayLogDir}
+		filesToTransfer, err := getFilesToTransfer(vm.host.PayloadVersion)
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting list of files to transfer")
+		}
+		for file, remoteDir := range filesToTransfer {
+			m.files = append(m.files, manifestFile{file: file, remoteDir: remoteDir})
+		}
+		// Sort the files so that the rendered manifest does not depend on the iteration order of the map
+		sort.Slice(m.files, func(i, j int) bool { return m.files[i].remotePath() < m.files[j].remotePath() })
+	}
+	if vm.host.ManageFirewallRules {
+		m.firewallRules = vm.firewallRules()
+	}
+	if vm.host.Harden {
+		m.registryValues = append(m.registryValues, registryValue{
+			key: "HKLM:\\System\\CurrentControlSet\\Control\\Terminal Server", name: "fDenyTSConnections", value: 1})
+		m.disabledServices = unusedServices
+	}
+	if vm.host.CrashDumps {
+		m.directories = append(m.directories, crashDumpDir)
+		m.registryValues = append(m.registryValues, crashDumpRegistryValues()...)
+	}
+	if vm.host.EventLogs {
+		m.directories = append(m.directories, eventLogDir)
+	}
+	return m, nil
+}
+
+// scripts returns the PowerShell commands comparing the VM with the manifest, one per kind of resource so that each
+// command stays within the command line length limit, in the order they must be run. Each command reports the drifted
+// resources as "<kind> <name>" lines, and corrects them if apply is set. Files are only reported, as they are
+// transferred over SFTP.
+func (m *manifest) scripts(apply bool) []string {
+	var bodies []string
+	if len(m.directories) > 0 {
+		bodies = append(bodies, "foreach ($path in @("+psList(m.directories)+")) { "+
+			"if (-not (Test-Path -PathType Container -Path $path)) { "+
+			"if ($apply) { New-Item -ItemType Directory -Force -Path $path | Out-Null }; 'directory ' + $path } }")
+	}
+	if len(m.files) > 0 {
+		var files []string
+		for _, f := range m.files {
+			files = append(files, "@{p="+psString(f.remotePath())+";h="+psString(f.file.SHA256)+"}")
+		}
+		bodies = append(bodies, "foreach ($f in @("+strings.Join(files, ",")+")) { "+
+			"if ((Get-FileHash -Algorithm SHA256 -Path $f.p -ErrorAction SilentlyContinue).Hash -ne $f.h) { "+
+			"'file ' + $f.p } }")
+	}
+	if len(m.registryValues) > 0 {
+		var values []string
+		for _, v := range m.registryValues {
+			values = append(values, "@{k="+psString(v.key)+";n="+psString(v.name)+";v="+psValue(v.value)+"}")
+		}
+		bodies = append(bodies, "foreach ($v in @("+strings.Join(values, ",")+")) { "+
+			"if ((Get-ItemProperty -Path $v.k -ErrorAction SilentlyContinue).($v.n) -ne $v.v) { if ($apply) { "+
+			"if (-not (Test-Path -Path $v.k)) { New-Item -Force -Path $v.k | Out-Null }; "+
+			"Set-ItemProperty -Path $v.k -Name $v.n -Value $v.v }; 'registry ' + $v.k + '\\' + $v.n } }")
+	}
+	if len(m.disabledServices) > 0 {
+		bodies = append(bodies, "foreach ($svc in @(Get-Service -Name "+strings.Join(m.disabledServices, ",")+
+			" -ErrorAction SilentlyContinue)) { if ($svc.StartType -eq 'Disabled') { continue }; if ($apply) { "+
+			"Stop-Service -Name $svc.Name -Force; Set-Service -Name $svc.Name -StartupType Disabled }; "+
+			"'service ' + $svc.Name }")
+	}
+	if len(m.firewallRules) > 0 {
+		var rules []string
+		for _, r := range m.firewallRules {
+			rules = append(rules, "@{n="+psString(firewallRulePrefix+r.name)+";p="+psString(r.protocol)+
+				";l="+psString(r.port)+"}")
+		}
+		settings := "-Direction Inbound -Action Allow -Protocol $r.p -LocalPort $r.l"
+		bodies = append(bodies, "foreach ($r in @("+strings.Join(rules, ",")+")) { "+
+			"$rule = Get-NetFirewallRule -Name $r.n -ErrorAction SilentlyContinue; if ($rule) { "+
+			"$filter = $rule | Get-NetFirewallPortFilter; if ($rule.Enabled -eq 'True' -and $rule.Action -eq 'Allow' "+
+			"-and $rule.Direction -eq 'Inbound' -and $filter.Protocol -eq $r.p -and $filter.LocalPort -eq $r.l) { "+
+			"continue } }; if ($apply) { if ($rule) { Set-NetFirewallRule -Name $r.n -Enabled True "+settings+
+			" } else { New-NetFirewallRule -Name $r.n -DisplayName $r.n "+settings+" | Out-Null } }; "+
+			"'firewall ' + $r.n }")
+	}
+	scripts := make([]string, len(bodies))
+	for i, body := range bodies {
+		scripts[i] = "\"$ErrorActionPreference = 'Stop'; $apply = $" + strconv.FormatBool(apply) + "; " + body + "\""
+	}
+	return scripts
+}
+
+// psString returns the given string as a PowerShell single-quoted string literal
+func psString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// psList returns the given strings as a comma separated list of PowerShell string literals
+func psList(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = psString(s)
+	}
+	return strings.Join(quoted, ",")
+}
+
+// psValue returns the given registry value as a PowerShell literal
+func psValue(value interface{}) string {
+	if i, ok := value.(int); ok {
+		return strconv.Itoa(i)
+	}
+	return psString(value.(string))
+}
+
+// parseChanges returns the changes reported one per line by the manifest scripts
+func parseChanges(out string) []string {
+	var changes []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			changes = append(changes, line)
+		}
+	}
+	return changes
+}
+
+// diffManifest returns the resources of the VM which drifted from the given manifest, correcting them if apply is set.
+// Drifted files are transferred once reported.
+func (vm *windows) diffManifest(m *manifest, apply bool) ([]string, error) {
+	var drift []string
+	for _, script := range m.scripts(apply) {
+		out, err := vm.Run(script, true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error applying desired state with output: %s", out)
+		}
+		drift = append(drift, parseChanges(out)...)
+	}
+	if !apply {
+		return drift, nil
+	}
+	for _, change := range drift {
+		if !strings.HasPrefix(change, "file ") {
+			continue
+		}
+		for _, f := range m.files {
+			if "file "+f.remotePath() != change {
+				continue
+			}
+			vm.log.V(1).Info("copy", "local file", f.file.Path, "remote dir", f.remoteDir)
+			start := time.Now()
+			err := vm.interact.CopyFile(f.file.Path, f.remoteDir)
+			vm.audit("transfer", f.remotePath(), start, err)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to transfer %s to remote dir %s", f.file.Path, f.remoteDir)
+			}
+		}
+	}
+	return drift, nil
+}
+
+// applyManifest brings the VM to the state of the given manifest, logging each change made
+func (vm *windows) applyManifest(m *manifest) error {
+	changes, err := vm.diffManifest(m, true)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		vm.log.Info("desired state applied", "change", change)
+	}
+	return nil
+}
+
+func (vm *windows) DesiredStateDrift() ([]string, error) {
+	m, err := vm.desiredState(false)
+	if err != nil {
+		return nil, err
+	}
+	return vm.diffManifest(m, false)
+}
+
+func (vm *windows) ConfigurationDrift(withPayload bool) ([]string, error) {
+	m, err := vm.desiredState(withPayload)
+	if err != nil {
+		return nil, err
+	}
+	drift, err := vm.diffManifest(m, false)
+	if err != nil {
+		return nil, err
+	}
+	// Services which do not exist are not reported, as not all the required services are created on every platform
+	cmd := "\"foreach ($svc in @(Get-Service -Name " + strings.Join(requiredServices, ",") +
+		" -ErrorAction SilentlyContinue)) { if ($svc.Status -ne 'Running') { 'stopped ' + $svc.Name } }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error checking the state of the required services with output: %s", out)
+	}
+	return append(drift, parseChanges(out)...), nil
+}
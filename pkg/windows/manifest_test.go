@@ -0,0 +1,38 @@
+package windows
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestManifestScripts tests that a script is rendered per kind of resource of the manifest, in order
+func TestManifestScripts(t *testing.T) {
+	assert.Empty(t, (&manifest{}).scripts(true))
+
+	m := &manifest{
+		directories:      []string{"C:\\k\\"},
+		registryValues:   []registryValue{{key: "HKLM:\\Key", name: "Name", value: "it's"}},
+		disabledServices: []string{"Spooler"},
+		firewallRules:    []firewallRule{{name: "kubelet", protocol: "TCP", port: kubeletPort}},
+	}
+	scripts := m.scripts(false)
+	require.Len(t, scripts, 4)
+	for _, script := range scripts {
+		assert.True(t, strings.HasPrefix(script, "\"$ErrorActionPreference = 'Stop'; $apply = $false; "))
+	}
+	assert.Contains(t, scripts[0], "@('C:\\k\\')")
+	assert.Contains(t, scripts[1], "@{k='HKLM:\\Key';n='Name';v='it''s'}")
+	assert.Contains(t, scripts[2], "Get-Service -Name Spooler ")
+	assert.Contains(t, scripts[3], "@{n='WMCO-kubelet';p='TCP';l='10250'}")
+	assert.True(t, strings.HasPrefix(m.scripts(true)[0], "\"$ErrorActionPreference = 'Stop'; $apply = $true; "))
+}
+
+// TestParseChanges tests the parseChanges function
+func TestParseChanges(t *testing.T) {
+	assert.Empty(t, parseChanges("\r\n"))
+	assert.Equal(t, []string{"directory C:\\k\\", "firewall WMCO-vxlan"},
+		parseChanges("directory C:\\k\\\r\n\r\nfirewall WMCO-vxlan\r\n"))
+}
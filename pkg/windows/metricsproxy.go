@@ -0,0 +1,69 @@
+package windows
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// kubeRBACProxyPath is the location of the kube-rbac-proxy executable
+	kubeRBACProxyPath = k8sDir + "kube-rbac-proxy.exe"
+	// kubeRBACProxyTaskName is the name of the scheduled task running kube-rbac-proxy. kube-rbac-proxy cannot run as a
+	// Windows service, so it is run at startup by the Task Scheduler, which restarts it when it exits.
+	kubeRBACProxyTaskName = "kube-rbac-proxy"
+	// windowsExporterAddress is the loopback address on which windows_exporter serves its metrics to kube-rbac-proxy
+	windowsExporterAddress = "127.0.0.1:9183"
+	// kubeletServingCertPath is the location of the current serving certificate of kubelet, along with its private
+	// key, used by kube-rbac-proxy so that the metrics are served with a certificate signed by the kubelet serving CA
+	kubeletServingCertPath = kubeletCertDir + "kubelet-server-current.pem"
+)
+
+// kubeRBACProxyArgs are the arguments of kube-rbac-proxy. The bearer tokens of the clients are authenticated, and their
+// access to the /metrics non-resource URL authorized, through the API server with the credentials of kubelet.
+var kubeRBACProxyArgs = strings.Join([]string{
+	"--secure-listen-address=0.0.0.0:" + metricsPort,
+	"--upstream=http://" + windowsExporterAddress + "/",
+	"--allow-paths=/metrics",
+	"--kubeconfig=" + kubeconfigPath,
+	"--tls-cert-file=" + kubeletServingCertPath,
+	"--tls-private-key-file=" + kubeletServingCertPath,
+}, " ")
+
+func (vm *windows) ConfigureMetricsProxy() error {
+	// The task is registered again if its arguments changed, and started if it is not running, such as when kubelet
+	// had no serving certificate yet the last time it was started
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"$task = Get-ScheduledTask -TaskName " + psString(kubeRBACProxyTaskName) + " -ErrorAction SilentlyContinue; " +
+		"if (-not $task -or $task.Actions[0].Arguments -ne " + psString(kubeRBACProxyArgs) + ") { " +
+		"$action = New-ScheduledTaskAction -Execute " + psString(kubeRBACProxyPath) + " -Argument " +
+		psString(kubeRBACProxyArgs) + "; " +
+		"$settings = New-ScheduledTaskSettingsSet -RestartCount 9999 -RestartInterval (New-TimeSpan -Minutes 1) " +
+		"-ExecutionTimeLimit (New-TimeSpan -Seconds 0) -AllowStartIfOnBatteries -DontStopIfGoingOnBatteries; " +
+		"if ($task) { Stop-ScheduledTask -TaskName " + psString(kubeRBACProxyTaskName) + " }; " +
+		"Register-ScheduledTask -Force -TaskName " + psString(kubeRBACProxyTaskName) + " -Action $action " +
+		"-Settings $settings -Trigger (New-ScheduledTaskTrigger -AtStartup) -User SYSTEM -RunLevel Highest | " +
+		"Out-Null; 'registered' }; " +
+		"if ((Get-ScheduledTask -TaskName " + psString(kubeRBACProxyTaskName) + ").State -ne 'Running') { " +
+		"Start-ScheduledTask -TaskName " + psString(kubeRBACProxyTaskName) + "; 'started' }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error configuring %s scheduled task with output: %s", kubeRBACProxyTaskName, out)
+	}
+	for _, change := range parseChanges(out) {
+		vm.log.Info("configure", "task", kubeRBACProxyTaskName, "change", change)
+	}
+	return nil
+}
+
+// removeMetricsProxy stops and unregisters the kube-rbac-proxy scheduled task, if it exists
+func (vm *windows) removeMetricsProxy() error {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"Get-ScheduledTask -TaskName " + psString(kubeRBACProxyTaskName) + " -ErrorAction SilentlyContinue | " +
+		"foreach { Stop-ScheduledTask -TaskName $_.TaskName; " +
+		"Unregister-ScheduledTask -TaskName $_.TaskName -Confirm:$false }\""
+	if out, err := vm.Run(cmd, true); err != nil {
+		return errors.Wrapf(err, "error removing %s scheduled task with output: %s", kubeRBACProxyTaskName, out)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// vxlanOverhead is the number of bytes added to the packets of the pod network by the VXLAN encapsulation
+const vxlanOverhead = 50
+
+// ensureOverlayMTU sets the MTU of the host interface of the hybrid overlay network, from which HNS derives the MTU of
+// the pod interfaces by subtracting the VXLAN overhead. It does nothing until the hybrid overlay has created the
+// network. The MTU applies to the pods created afterwards.
+func (vm *windows) ensureOverlayMTU() error {
+	mtu := strconv.Itoa(vm.host.OverlayMTU + vxlanOverhead)
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"$net = Get-HnsNetwork | where { $_.Name -eq '" + BaseOVNKubeOverlayNetwork + "' }; " +
+		"if ($net -eq $null) { exit }; " +
+		"$alias = 'vEthernet (' + $net.NetworkAdapterName + ')'; " +
+		"if ((Get-NetIPInterface -InterfaceAlias $alias -AddressFamily IPv4).NlMtu -ne " + mtu + ") { " +
+		"Set-NetIPInterface -InterfaceAlias $alias -AddressFamily IPv4 -NlMtuBytes " + mtu + "; " +
+		"'set MTU of ' + $alias + ' to " + mtu + "' }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error setting overlay MTU with output: %s", out)
+	}
+	if change := strings.TrimSpace(out); change != "" {
+		vm.log.Info("overlay MTU", "change", change)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package windows
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// minNetworkPolicyBuild is the OS build of Windows Server 2019, from which HNS supports the ACL policies network
+// policies are enforced with on the endpoints of pods
+const minNetworkPolicyBuild = 17763
+
+// NetworkPolicyStatus describes whether network policies can be, and are, enforced on the pods of the VM
+type NetworkPolicyStatus struct {
+	// Build is the OS build number of the VM
+	Build int
+	// OverlayNetwork is true when the HNS overlay network the pod endpoints are attached to exists
+	OverlayNetwork bool
+	// PodEndpoints is the number of HNS endpoints of pods on the overlay network
+	PodEndpoints int
+	// EnforcedEndpoints is the number of HNS endpoints of pods with ACL policies
+	EnforcedEndpoints int
+}
+
+func (vm *windows) NetworkPolicyStatus() (*NetworkPolicyStatus, error) {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"'build ' + (Get-ItemProperty 'HKLM:\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion').CurrentBuildNumber; " +
+		"$net = Get-HnsNetwork | where { $_.Name -eq " + psString(OVNKubeOverlayNetwork) + " }; " +
+		"'overlay ' + [bool]$net; " +
+		"if ($net) { $endpoints = @(Get-HnsEndpoint | where { $_.VirtualNetwork -eq $net.Id -and " +
+		"$_.Name -ne " + psString(sourceVIPEndpointName) + " }); " +
+		"'endpoints ' + $endpoints.Count + ' ' + @($endpoints | where { @($_.Policies | " +
+		"where { $_.Type -eq 'ACL' }).Count -gt 0 }).Count }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting network policy status with output: %s", out)
+	}
+	return parseNetworkPolicyStatus(out)
+}
+
+// parseNetworkPolicyStatus parses the network policy status reported as "build <number>", "overlay <bool>" and, when
+// the overlay network exists, "endpoints <pod endpoints> <endpoints with ACL policies>" lines
+func parseNetworkPolicyStatus(out string) (*NetworkPolicyStatus, error) {
+	status := &NetworkPolicyStatus{}
+	var err error
+	for _, line := range parseChanges(out) {
+		tokens := strings.Fields(line)
+		switch {
+		case len(tokens) == 2 && tokens[0] == "build":
+			status.Build, err = strconv.Atoi(tokens[1])
+		case len(tokens) == 2 && tokens[0] == "overlay":
+			status.OverlayNetwork, err = strconv.ParseBool(tokens[1])
+		case len(tokens) == 3 && tokens[0] == "endpoints":
+			if status.PodEndpoints, err = strconv.Atoi(tokens[1]); err == nil {
+				status.EnforcedEndpoints, err = strconv.Atoi(tokens[2])
+			}
+		default:
+			return nil, errors.Errorf("unexpected network policy status line %q", line)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid network policy status line %q", line)
+		}
+	}
+	if status.Build == 0 {
+		return nil, errors.Errorf("unexpected network policy status output %q", out)
+	}
+	return status, nil
+}
+
+// ACLPoliciesSupported returns true if the OS build of the VM supports the HNS ACL policies network policies are
+// enforced with
+func (s *NetworkPolicyStatus) ACLPoliciesSupported() bool {
+	return s.Build >= minNetworkPolicyBuild
+}
+
+// Gaps returns the reasons network policies are not enforced on the pods of the VM: its OS build predates the HNS
+// ACL policies, the overlay network is missing, or pod endpoints have no ACL policies programmed
+func (s *NetworkPolicyStatus) Gaps() []string {
+	var gaps []string
+	if !s.ACLPoliciesSupported() {
+		gaps = append(gaps, fmt.Sprintf("OS build %d predates the HNS ACL policies network policies are enforced "+
+			"with, available from build %d", s.Build, minNetworkPolicyBuild))
+	}
+	if !s.OverlayNetwork {
+		gaps = append(gaps, "HNS network "+OVNKubeOverlayNetwork+" is missing")
+	} else if s.EnforcedEndpoints < s.PodEndpoints {
+		gaps = append(gaps, fmt.Sprintf("%d of %d pod endpoints have no HNS ACL policies, the hybrid overlay does "+
+			"not program network policies", s.PodEndpoints-s.EnforcedEndpoints, s.PodEndpoints))
+	}
+	return gaps
+}
@@ -0,0 +1,29 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetworkPolicyStatus(t *testing.T) {
+	status, err := parseNetworkPolicyStatus("build 17763\r\noverlay True\r\nendpoints 3 0\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &NetworkPolicyStatus{Build: 17763, OverlayNetwork: true, PodEndpoints: 3}, status)
+	assert.Equal(t, []string{"3 of 3 pod endpoints have no HNS ACL policies, the hybrid overlay does not program " +
+		"network policies"}, status.Gaps())
+
+	status, err = parseNetworkPolicyStatus("build 17134\r\noverlay False\r\n")
+	require.NoError(t, err)
+	assert.Len(t, status.Gaps(), 2)
+
+	status, err = parseNetworkPolicyStatus("build 20348\r\noverlay True\r\nendpoints 2 2\r\n")
+	require.NoError(t, err)
+	assert.Empty(t, status.Gaps())
+
+	_, err = parseNetworkPolicyStatus("overlay True\r\n")
+	assert.Error(t, err)
+	_, err = parseNetworkPolicyStatus("build 17763\r\nendpoints x 0\r\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package windows
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// azureAgentServices are the services of the Azure VM agent, which runs the VM extensions
+var azureAgentServices = []string{"WindowsAzureGuestAgent", "RdAgent"}
+
+// listNICsCmd lists the network adapters of the VM and the state of the Azure VM agent services, one
+// "adapter|<description>|<status>" or "service|<name>|<status>" line each
+var listNICsCmd = "\"Get-NetAdapter | ForEach-Object { 'adapter|' + $_.InterfaceDescription + '|' + $_.Status }; " +
+	"foreach ($name in @(" + psList(azureAgentServices) + ")) { " +
+	"$s = Get-Service -Name $name -ErrorAction SilentlyContinue; " +
+	"if ($s) { 'service|' + $name + '|' + $s.Status } else { 'service|' + $name + '|Missing' } }\""
+
+func (vm *windows) NetworkInterfaceProblems() ([]string, error) {
+	out, err := vm.Run(listNICsCmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing network adapters with output: %s", out)
+	}
+	return nicProblems(out), nil
+}
+
+// nicProblems returns the problems found in the given output of listNICsCmd: accelerated networking, whose SR-IOV
+// virtual function adapters are known to break the hybrid overlay on Windows, several connected network interfaces,
+// of which the hybrid overlay only uses one, and a stopped Azure VM agent, without which VM extensions do not run
+func nicProblems(out string) []string {
+	var problems []string
+	connected := 0
+	for _, line := range parseChanges(out) {
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			continue
+		}
+		name, status := fields[1], fields[2]
+		switch fields[0] {
+		case "adapter":
+			description := strings.ToLower(name)
+			if strings.Contains(description, "virtual function") ||
+				strings.Contains(description, "microsoft azure network adapter") {
+				problems = append(problems, fmt.Sprintf("accelerated networking is enabled, adapter %s is known "+
+					"to break the hybrid overlay", name))
+			} else if strings.Contains(description, "hyper-v network adapter") && status == "Up" {
+				connected++
+			}
+		case "service":
+			if status != "Running" {
+				problems = append(problems, fmt.Sprintf("Azure VM agent service %s is %s, VM extensions do not run",
+					name, strings.ToLower(status)))
+			}
+		}
+	}
+	if connected > 1 {
+		problems = append(problems, fmt.Sprintf("%d network interfaces are connected, the hybrid overlay only uses "+
+			"one of them", connected))
+	}
+	return problems
+}
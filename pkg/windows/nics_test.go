@@ -0,0 +1,44 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNICProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		expected []string
+	}{
+		{
+			name: "valid",
+			out: "adapter|Microsoft Hyper-V Network Adapter|Up\r\nservice|WindowsAzureGuestAgent|Running\r\n" +
+				"service|RdAgent|Running\r\n",
+			expected: nil,
+		},
+		{
+			name: "accelerated networking",
+			out: "adapter|Microsoft Hyper-V Network Adapter|Up\r\n" +
+				"adapter|Mellanox ConnectX-4 Lx Virtual Function Ethernet Adapter|Up\r\n" +
+				"service|WindowsAzureGuestAgent|Running\r\nservice|RdAgent|Running\r\n",
+			expected: []string{"accelerated networking is enabled, adapter Mellanox ConnectX-4 Lx Virtual Function " +
+				"Ethernet Adapter is known to break the hybrid overlay"},
+		},
+		{
+			name: "several interfaces and stopped agent",
+			out: "adapter|Microsoft Hyper-V Network Adapter|Up\r\nadapter|Microsoft Hyper-V Network Adapter #2|Up\r\n" +
+				"adapter|Microsoft Hyper-V Network Adapter #3|Disconnected\r\n" +
+				"service|WindowsAzureGuestAgent|Stopped\r\nservice|RdAgent|Missing\r\n",
+			expected: []string{"Azure VM agent service WindowsAzureGuestAgent is stopped, VM extensions do not run",
+				"Azure VM agent service RdAgent is missing, VM extensions do not run",
+				"2 network interfaces are connected, the hybrid overlay only uses one of them"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, nicProblems(test.out))
+		})
+	}
+}
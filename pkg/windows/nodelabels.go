@@ -0,0 +1,45 @@
+package windows
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// nodeLabelsArg is the argument of the kubelet unit of the worker ignition giving the labels kubelet registers the node
+// with, which the bootstrapper passes on to the kubelet of the Windows node
+const nodeLabelsArg = "--node-labels="
+
+// nodeLabelsValue returns the given labels as the value of the kubelet --node-labels argument, sorted by key
+func nodeLabelsValue(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// addRegistrationLabels adds the node labels of the host settings to the labels of the kubelet unit of the downloaded
+// worker ignition, so that the bootstrapper starts kubelet with them and the node is registered with the labels
+func (vm *windows) addRegistrationLabels() error {
+	if len(vm.host.NodeLabels) == 0 {
+		return nil
+	}
+	labels := nodeLabelsValue(vm.host.NodeLabels)
+	cmd := "\"$ErrorActionPreference = 'Stop'; $ign = [IO.File]::ReadAllText(" + psString(workerIgnitionPath) + "); " +
+		"if (-not $ign.Contains(" + psString(nodeLabelsArg) + ")) { 'missing' } else { " +
+		"[IO.File]::WriteAllText(" + psString(workerIgnitionPath) + ", $ign.Replace(" + psString(nodeLabelsArg) +
+		", " + psString(nodeLabelsArg+labels+",") + ")) }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error adding node labels to the worker ignition with output: %s", out)
+	}
+	if strings.TrimSpace(out) == "missing" {
+		return errors.Errorf("the kubelet unit of the worker ignition has no %s argument to add the node labels to",
+			strings.TrimSuffix(nodeLabelsArg, "="))
+	}
+	vm.log.Info("registering node with labels", "labels", labels)
+	return nil
+}
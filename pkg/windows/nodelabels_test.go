@@ -0,0 +1,13 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNodeLabelsValue tests the nodeLabelsValue function
+func TestNodeLabelsValue(t *testing.T) {
+	assert.Equal(t, "node.kubernetes.io/pool=gpu,team=a",
+		nodeLabelsValue(map[string]string{"team": "a", "node.kubernetes.io/pool": "gpu"}))
+}
@@ -0,0 +1,25 @@
+package windows
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SetPassword sets the password of the user WMCO connects as. The command is run without being logged, as it holds
+// the password.
+func (vm *windows) SetPassword(password string) error {
+	encoded := base64.StdEncoding.EncodeToString([]byte(password))
+	cmd := remotePowerShellCmdPrefix + "\"$ErrorActionPreference = 'Stop'; " +
+		"$password = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String('" + encoded + "')); " +
+		"Set-LocalUser -Name $env:USERNAME -Password (ConvertTo-SecureString -String $password -AsPlainText -Force)\""
+	start := time.Now()
+	out, err := vm.interact.RunCommand(cmd)
+	vm.audit("run", "Set-LocalUser -Name $env:USERNAME -Password <redacted>", start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error setting password with output: %s", out)
+	}
+	vm.log.Info("password set")
+	return nil
+}
@@ -0,0 +1,77 @@
+package windows
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// minPreflightBuild is the oldest OS build supported for Windows nodes, Windows Server 2019
+	minPreflightBuild = 17763
+	// minPreflightFreeBytes is the free space required on the system drive for the payload, the container images and
+	// the logs of a Windows node
+	minPreflightFreeBytes = 10 * 1024 * 1024 * 1024
+)
+
+// Preflight describes the properties of the VM validated before configuring the first instance of a pool
+type Preflight struct {
+	// Build is the OS build number of the VM
+	Build int
+	// FreeBytes is the free space on the system drive of the VM
+	FreeBytes int64
+	// Containers is true when the Containers feature is installed
+	Containers bool
+}
+
+func (vm *windows) Preflight() (*Preflight, error) {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"(Get-ItemProperty 'HKLM:\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion').CurrentBuildNumber; " +
+		"(Get-PSDrive -Name $env:SystemDrive.TrimEnd(':')).Free; " +
+		"(Get-WindowsFeature -Name Containers).Installed\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error running pre-flight checks with output: %s", out)
+	}
+	return parsePreflight(out)
+}
+
+// parsePreflight parses the OS build number, the free space on the system drive and whether the Containers feature
+// is installed, reported on three lines
+func parsePreflight(out string) (*Preflight, error) {
+	lines := parseChanges(out)
+	if len(lines) != 3 {
+		return nil, errors.Errorf("unexpected pre-flight output %q", out)
+	}
+	build, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid OS build number %q", lines[0])
+	}
+	free, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid free disk space %q", lines[1])
+	}
+	containers, err := strconv.ParseBool(lines[2])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Containers feature installation state %q", lines[2])
+	}
+	return &Preflight{Build: build, FreeBytes: free, Containers: containers}, nil
+}
+
+// Problems returns the reasons for which the VM cannot be configured as a Windows node, if any
+func (p *Preflight) Problems() []string {
+	var problems []string
+	if p.Build < minPreflightBuild {
+		problems = append(problems, fmt.Sprintf("OS build %d is older than the minimum supported build %d", p.Build,
+			minPreflightBuild))
+	}
+	if p.FreeBytes < minPreflightFreeBytes {
+		problems = append(problems, fmt.Sprintf("%d MiB free on the system drive, %d MiB required",
+			p.FreeBytes/(1024*1024), minPreflightFreeBytes/(1024*1024)))
+	}
+	if !p.Containers {
+		problems = append(problems, "the Containers feature is not installed")
+	}
+	return problems
+}
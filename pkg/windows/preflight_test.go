@@ -0,0 +1,27 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParsePreflight tests the parsePreflight function
+func TestParsePreflight(t *testing.T) {
+	preflight, err := parsePreflight("17763\r\n21474836480\r\nTrue\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &Preflight{Build: 17763, FreeBytes: 21474836480, Containers: true}, preflight)
+
+	_, err = parsePreflight("17763\r\nTrue\r\n")
+	assert.Error(t, err)
+}
+
+// TestPreflightProblems tests the Problems method
+func TestPreflightProblems(t *testing.T) {
+	preflight := &Preflight{Build: 17763, FreeBytes: minPreflightFreeBytes, Containers: true}
+	assert.Empty(t, preflight.Problems())
+
+	preflight = &Preflight{Build: 14393, FreeBytes: 1024 * 1024 * 1024}
+	assert.Len(t, preflight.Problems(), 3)
+}
@@ -0,0 +1,70 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// monitoredServices are the services whose unexpected terminations are reported: the services created by WMCO and the
+// container runtime
+var monitoredServices = append([]string{containerRuntimeServiceName}, requiredServices...)
+
+// Problems are the Windows specific problems detected on the VM, which the kubelet does not report
+type Problems struct {
+	// SystemDiskFreePercent is the percentage of the system drive which is free
+	SystemDiskFreePercent int
+	// ServiceCrashes is the number of unexpected terminations of each monitored service which terminated within the
+	// look-back window
+	ServiceCrashes map[string]int
+}
+
+func (vm *windows) DetectProblems(window time.Duration) (*Problems, error) {
+	// The Service Control Manager logs event 7031 or 7034 when a service terminates unexpectedly, the first property
+	// of the event being the name of the service
+	cmd := "\"$ErrorActionPreference = 'Stop'; $d = Get-PSDrive -Name C; " +
+		"'disk ' + [int](100 * $d.Free / ($d.Used + $d.Free)); " +
+		"Get-WinEvent -FilterHashtable @{LogName='System'; ProviderName='Service Control Manager'; Id=7031,7034; " +
+		"StartTime=(Get-Date).AddSeconds(-" + strconv.Itoa(int(window.Seconds())) + ")} " +
+		"-ErrorAction SilentlyContinue | ForEach-Object { 'crash ' + $_.Properties[0].Value }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error detecting problems with output: %s", out)
+	}
+	return parseProblems(out)
+}
+
+// parseProblems parses the free percentage of the system drive, reported as a "disk <percent>" line, and the
+// terminated services, reported as one "crash <service>" line per termination. Only the terminations of the monitored
+// services are counted.
+func parseProblems(out string) (*Problems, error) {
+	problems := &Problems{ServiceCrashes: make(map[string]int)}
+	diskReported := false
+	for _, line := range parseChanges(out) {
+		tokens := strings.SplitN(line, " ", 2)
+		if len(tokens) != 2 {
+			return nil, errors.Errorf("unexpected problem detection output %q", line)
+		}
+		switch tokens[0] {
+		case "disk":
+			percent, err := strconv.Atoi(tokens[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid free percentage of the system drive %q", tokens[1])
+			}
+			problems.SystemDiskFreePercent = percent
+			diskReported = true
+		case "crash":
+			if containsString(monitoredServices, tokens[1]) {
+				problems.ServiceCrashes[tokens[1]]++
+			}
+		default:
+			return nil, errors.Errorf("unexpected problem detection output %q", line)
+		}
+	}
+	if !diskReported {
+		return nil, errors.New("free percentage of the system drive not reported")
+	}
+	return problems, nil
+}
@@ -0,0 +1,25 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProblems(t *testing.T) {
+	problems, err := parseProblems("disk 42\r\ncrash kubelet\r\ncrash Spooler\r\ncrash kubelet\r\ncrash docker\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &Problems{SystemDiskFreePercent: 42,
+		ServiceCrashes: map[string]int{"kubelet": 2, "docker": 1}}, problems)
+
+	problems, err = parseProblems("disk 7\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, 7, problems.SystemDiskFreePercent)
+	assert.Empty(t, problems.ServiceCrashes)
+
+	_, err = parseProblems("crash kubelet\r\n")
+	assert.Error(t, err)
+	_, err = parseProblems("disk full\r\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,21 @@
+package windows
+
+// ConfigurationStep is a phase of the configuration of a Windows VM as a node, reported once it completes
+type ConfigurationStep string
+
+const (
+	// StepPayloadTransferred is completed once the Kubernetes component binaries and scripts are on the VM
+	StepPayloadTransferred ConfigurationStep = "PayloadTransferred"
+	// StepServicesInstalled is completed once the Windows exporter and kubelet services are installed and running
+	StepServicesInstalled ConfigurationStep = "ServicesInstalled"
+)
+
+// ProgressFunc is called with each configuration step as it completes
+type ProgressFunc func(ConfigurationStep)
+
+// report calls the given progress function, if any, with the given step
+func (p ProgressFunc) report(step ConfigurationStep) {
+	if p != nil {
+		p(step)
+	}
+}
@@ -0,0 +1,48 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// containerRuntimeServiceName is the name of the Windows service of the container runtime
+	containerRuntimeServiceName = "docker"
+	// serviceRecoveryResetPeriod is the time without failure after which the Service Control Manager resets the
+	// failure count of a service, starting the backoff over
+	serviceRecoveryResetPeriod = 24 * time.Hour
+)
+
+// serviceRestartDelays are the delays after which the Service Control Manager restarts a failed service, for its
+// first, second and subsequent failures
+var serviceRestartDelays = []time.Duration{10 * time.Second, 30 * time.Second, time.Minute}
+
+// recoveryActions returns the value of the actions argument of sc.exe failure restarting a service after each of the
+// given delays
+func recoveryActions(delays []time.Duration) string {
+	actions := make([]string, len(delays))
+	for i, delay := range delays {
+		actions[i] = "restart/" + strconv.FormatInt(delay.Milliseconds(), 10)
+	}
+	return strings.Join(actions, "/")
+}
+
+func (vm *windows) ConfigureServiceRecovery() error {
+	services := append([]string{containerRuntimeServiceName}, requiredServices...)
+	// The failure flag applies the actions to services stopping with a non-zero exit code, not only to crashes
+	cmd := "\"$ErrorActionPreference = 'Stop'; foreach ($svc in @(Get-Service -Name " + strings.Join(services, ",") +
+		" -ErrorAction SilentlyContinue)) { " +
+		"sc.exe failure $svc.Name reset= " + strconv.Itoa(int(serviceRecoveryResetPeriod.Seconds())) +
+		" actions= " + recoveryActions(serviceRestartDelays) + " | Out-Null; " +
+		"if ($LASTEXITCODE -ne 0) { throw ('unable to set recovery actions of ' + $svc.Name) }; " +
+		"sc.exe failureflag $svc.Name 1 | Out-Null; " +
+		"if ($LASTEXITCODE -ne 0) { throw ('unable to set failure flag of ' + $svc.Name) } }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error setting service recovery actions with output: %s", out)
+	}
+	return nil
+}
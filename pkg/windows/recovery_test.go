@@ -0,0 +1,13 @@
+package windows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoveryActions(t *testing.T) {
+	assert.Equal(t, "restart/10000/restart/30000/restart/60000", recoveryActions(serviceRestartDelays))
+	assert.Equal(t, "restart/500", recoveryActions([]time.Duration{500 * time.Millisecond}))
+}
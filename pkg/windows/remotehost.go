@@ -0,0 +1,12 @@
+package windows
+
+// RemoteHost is the transport through which a Windows VM is configured. The SSH implementation is used by New, other
+// transports, or fakes in unit tests, can be given to NewWithRemoteHost.
+type RemoteHost interface {
+	// Init establishes the connection to the VM, and is called again to re-establish it once the VM rebooted
+	Init() error
+	// RunCommand executes the given command on the VM and returns its combined stdout and stderr output
+	RunCommand(cmd string) (string, error)
+	// CopyFile copies the file from the local disk to the given directory of the VM, creating the directory if needed
+	CopyFile(filePath, remoteDir string) error
+}
@@ -0,0 +1,74 @@
+package windows
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/windows/windowstest"
+)
+
+func TestEnsureServiceIsRunning(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote *windowstest.FakeRemoteHost
+		want   []string
+	}{
+		{
+			name: "missing service",
+			remote: &windowstest.FakeRemoteHost{Errors: map[string]error{
+				serviceQueryCmd + "kube-proxy": fmt.Errorf("Process exited with %s", serviceNotFound)}},
+			want: []string{serviceQueryCmd + "kube-proxy",
+				"sc.exe create kube-proxy binPath=\"C:\\k\\kube-proxy.exe --v=2 start=auto",
+				"sc.exe query kube-proxy", "sc.exe start kube-proxy"},
+		},
+		{
+			name:   "running service",
+			remote: &windowstest.FakeRemoteHost{Outputs: map[string]string{"sc.exe query": "STATE : 4 RUNNING"}},
+			want:   []string{serviceQueryCmd + "kube-proxy", "sc.exe query kube-proxy"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm, err := NewWithRemoteHost(test.remote, nil, "i-1", "machine", "https://api-int.example.com:22623",
+				"", "", KubeletSettings{}, HostSettings{})
+			require.NoError(t, err)
+			svc, err := newService("C:\\k\\kube-proxy.exe", "kube-proxy", "--v=2")
+			require.NoError(t, err)
+			require.NoError(t, vm.(*windows).ensureServiceIsRunning(svc))
+			assert.Equal(t, test.want, test.remote.Commands)
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	remote := &windowstest.FakeRemoteHost{}
+	vm, err := NewWithRemoteHost(remote, nil, "i-1", "machine", "https://api-int.example.com:22623", "", "",
+		KubeletSettings{}, HostSettings{})
+	require.NoError(t, err)
+	_, err = vm.Run("hostname", true)
+	require.NoError(t, err)
+	vm.Cancel()
+	_, err = vm.Run("hostname", true)
+	assert.True(t, errors.Is(err, ErrCancelled))
+	assert.True(t, errors.Is(vm.Reinitialize(), ErrCancelled))
+	assert.Len(t, remote.Commands, 1, "no command should be run once cancelled")
+}
+
+func TestCancelOnDone(t *testing.T) {
+	vm, err := NewWithRemoteHost(&windowstest.FakeRemoteHost{}, nil, "i-1", "machine",
+		"https://api-int.example.com:22623", "", "", KubeletSettings{}, HostSettings{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer CancelOnDone(ctx, vm)()
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := vm.Run("hostname", true)
+		return errors.Is(err, ErrCancelled)
+	}, time.Second, 10*time.Millisecond)
+}
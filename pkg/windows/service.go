@@ -1,6 +1,10 @@
 package windows
 
-import "github.com/pkg/errors"
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
 
 // service struct contains the service information
 type service struct {
@@ -23,3 +27,98 @@ func newService(binaryPath, name, args string) (*service, error) {
 		args:       args,
 	}, nil
 }
+
+// ServiceManager manages the Windows services of a VM
+type ServiceManager interface {
+	// Exists returns true if the given service exists
+	Exists(name string) (bool, error)
+	// IsRunning returns true if the given service is running
+	IsRunning(name string) (bool, error)
+	// Create creates the given service, started automatically, running the given binary with the given arguments
+	Create(name, binaryPath, args string) error
+	// CmdLine returns the command line of the given service
+	CmdLine(name string) (string, error)
+	// SetCmdLine replaces the command line of the given service, which must not be running
+	SetCmdLine(name, cmdLine string) error
+	// Start starts the given service
+	Start(name string) error
+	// Stop initiates the stop of the given service, returning before the service is stopped
+	Stop(name string) error
+	// Delete deletes the given service, which must not be running
+	Delete(name string) error
+}
+
+// scServiceManager is the ServiceManager running the sc.exe commands through the given run function
+type scServiceManager struct {
+	// run runs the given command on the VM, as a PowerShell command if psCmd is set
+	run func(cmd string, psCmd bool) (string, error)
+}
+
+func (m *scServiceManager) Exists(name string) (bool, error) {
+	_, err := m.run(serviceQueryCmd+name, false)
+	if err != nil {
+		if strings.Contains(err.Error(), serviceNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *scServiceManager) IsRunning(name string) (bool, error) {
+	out, err := m.run("sc.exe query "+name, false)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(out, "RUNNING"), nil
+}
+
+func (m *scServiceManager) Create(name, binaryPath, args string) error {
+	if _, err := m.run("sc.exe create "+name+" binPath=\""+binaryPath+" "+args+" start=auto", false); err != nil {
+		return errors.Wrapf(err, "failed to create service %s", name)
+	}
+	return nil
+}
+
+func (m *scServiceManager) CmdLine(name string) (string, error) {
+	out, err := m.run(serviceQueryCmd+name, false)
+	if err != nil {
+		return "", errors.Wrapf(err, "error querying %s service", name)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "BINARY_PATH_NAME") {
+			return strings.TrimSpace(line[strings.Index(line, ":")+1:]), nil
+		}
+	}
+	return "", errors.Errorf("unable to find the %s service command line in output: %s", name, out)
+}
+
+func (m *scServiceManager) SetCmdLine(name, cmdLine string) error {
+	configCmd := "sc.exe config " + name + " binPath= \"" + strings.ReplaceAll(cmdLine, "\"", "\\\"") + "\""
+	if out, err := m.run(configCmd, false); err != nil {
+		return errors.Wrapf(err, "error updating %s service with output: %s", name, out)
+	}
+	return nil
+}
+
+func (m *scServiceManager) Start(name string) error {
+	if out, err := m.run("sc.exe start "+name, false); err != nil {
+		return errors.Wrapf(err, "failed to start %s service with output: %s", name, out)
+	}
+	return nil
+}
+
+func (m *scServiceManager) Stop(name string) error {
+	if out, err := m.run("sc.exe stop "+name, false); err != nil {
+		return errors.Wrapf(err, "failed to stop %s service with output: %s", name, out)
+	}
+	return nil
+}
+
+func (m *scServiceManager) Delete(name string) error {
+	if out, err := m.run("sc.exe delete "+name, false); err != nil {
+		return errors.Wrapf(err, "failed to delete %s service with output: %s", name, out)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package windows
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// shutdownScript is the remote location of the script run by Windows when the VM shuts down
+	shutdownScript = k8sDir + "shutdown.ps1"
+	// shutdownLog is the remote location of the transcript of the shutdown script
+	shutdownLog = logDir + "shutdown.log"
+	// shutdownScriptsKeys are the registry keys holding the shutdown scripts of the local Group Policy, and the state
+	// of the scripts last applied by the Group Policy client
+	shutdownScriptsKeys = "@('HKLM:\\SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\Group Policy\\Scripts\\Shutdown', " +
+		"'HKLM:\\SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\Group Policy\\State\\Machine\\Scripts\\Shutdown')"
+	// gpoScriptWaitMargin is the time the Group Policy client waits for the shutdown script on top of the grace period
+	gpoScriptWaitMargin = time.Minute
+)
+
+// shutdownScriptContents returns the shutdown script giving the containers of the pods running on the VM the given
+// grace period to terminate. kubelet is stopped first so that it does not restart the stopped containers.
+func shutdownScriptContents(gracePeriod time.Duration) string {
+	return "Start-Transcript -Append -Path " + shutdownLog + "\r\n" +
+		"Stop-Service -Force kubelet\r\n" +
+		"$containers = @(docker ps --quiet --filter label=io.kubernetes.pod.name)\r\n" +
+		"if ($containers.Count -gt 0) { docker stop --time " + strconv.Itoa(int(gracePeriod.Seconds())) +
+		" $containers }\r\n" +
+		"Stop-Transcript\r\n"
+}
+
+// ensureShutdownHook registers the shutdown script as a shutdown script of the local Group Policy, so that pods are
+// given the shutdown grace period of the host settings to terminate when the VM is stopped, and lets the Group Policy
+// client wait for the script for that long
+func (vm *windows) ensureShutdownHook() error {
+	script := base64.StdEncoding.EncodeToString([]byte(shutdownScriptContents(vm.host.ShutdownGracePeriod)))
+	maxWait := strconv.Itoa(int((vm.host.ShutdownGracePeriod + gpoScriptWaitMargin).Seconds()))
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"if (-not (Test-Path " + shutdownScript + ") -or [Convert]::ToBase64String([IO.File]::ReadAllBytes('" +
+		shutdownScript + "')) -ne '" + script + "') { [IO.File]::WriteAllBytes('" + shutdownScript +
+		"', [Convert]::FromBase64String('" + script + "')); 'wrote shutdown script' }; " +
+		"foreach ($root in " + shutdownScriptsKeys + ") { " +
+		"if (-not (Test-Path $root)) { New-Item -Force -Path $root | Out-Null }; " +
+		"$gpo = Get-ChildItem $root | where { (Get-ItemProperty $_.PSPath).'GPO-ID' -eq 'LocalGPO' } | " +
+		"select -First 1; " +
+		"if (-not $gpo) { $gpo = New-Item -Path ($root + '\\' + @(Get-ChildItem $root).Count); " +
+		"$props = @{'GPO-ID'='LocalGPO'; 'SOM-ID'='Local'; 'FileSysPath'='C:\\Windows\\System32\\GroupPolicy\\Machine'; " +
+		"'DisplayName'='Local Group Policy'; 'GPOName'='Local Group Policy'}; " +
+		"foreach ($name in $props.Keys) { New-ItemProperty -Path $gpo.PSPath -Name $name -Value $props[$name] | " +
+		"Out-Null }; " +
+		"New-ItemProperty -Path $gpo.PSPath -Name PSScriptOrder -Value 1 -PropertyType DWord | Out-Null }; " +
+		"if (-not (Get-ChildItem $gpo.PSPath | where { (Get-ItemProperty $_.PSPath).Script -eq '" + shutdownScript +
+		"' })) { $entry = New-Item -Path ($gpo.PSPath + '\\' + @(Get-ChildItem $gpo.PSPath).Count); " +
+		"New-ItemProperty -Path $entry.PSPath -Name Script -Value '" + shutdownScript + "' | Out-Null; " +
+		"New-ItemProperty -Path $entry.PSPath -Name Parameters -Value '' | Out-Null; " +
+		"New-ItemProperty -Path $entry.PSPath -Name IsPowershell -Value 1 -PropertyType DWord | Out-Null; " +
+		"New-ItemProperty -Path $entry.PSPath -Name ExecTime -Value 0 -PropertyType QWord | Out-Null; " +
+		"'registered shutdown script in ' + $root } }; " +
+		"$system = 'HKLM:\\SOFTWARE\\Policies\\Microsoft\\Windows\\System'; " +
+		"if (-not (Test-Path $system)) { New-Item -Force -Path $system | Out-Null }; " +
+		"if ((Get-ItemProperty -Path $system).MaxGPOScriptWait -ne " + maxWait + ") { " +
+		"Set-ItemProperty -Path $system -Name MaxGPOScriptWait -Value " + maxWait + " -Type DWord; " +
+		"'set MaxGPOScriptWait to " + maxWait + "' }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error configuring shutdown hook with output: %s", out)
+	}
+	for _, change := range strings.Split(strings.TrimSpace(out), "\n") {
+		if change = strings.TrimSpace(change); change != "" {
+			vm.log.Info("shutdown hook", "change", change)
+		}
+	}
+	return nil
+}
+
+// removeShutdownHook unregisters the shutdown script from the local Group Policy
+func (vm *windows) removeShutdownHook() error {
+	cmd := "\"$ErrorActionPreference = 'Stop'; foreach ($root in " + shutdownScriptsKeys + ") { " +
+		"Get-ChildItem -Recurse $root -ErrorAction SilentlyContinue | " +
+		"where { (Get-ItemProperty $_.PSPath).Script -eq '" + shutdownScript + "' } | Remove-Item -Force }\""
+	if out, err := vm.Run(cmd, true); err != nil {
+		return errors.Wrapf(err, "error removing shutdown hook with output: %s", out)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package windows
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownScriptContents(t *testing.T) {
+	script := shutdownScriptContents(90 * time.Second)
+	assert.Contains(t, script, "docker stop --time 90 $containers")
+	assert.Less(t, strings.Index(script, "Stop-Service -Force kubelet"), strings.Index(script, "docker stop"),
+		"kubelet must be stopped before the containers")
+}
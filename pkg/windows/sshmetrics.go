@@ -0,0 +1,70 @@
+package windows
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// sshFailureDial is the type of the SSH failures in which the VM could not be connected to
+	sshFailureDial = "dial"
+	// sshFailureAuth is the type of the SSH failures in which the VM rejected the credentials of WMCO
+	sshFailureAuth = "auth"
+	// sshFailureCommand is the type of the SSH failures in which a command run on the VM failed
+	sshFailureCommand = "command"
+)
+
+var (
+	// sshFailures is the number of SSH failures of each Windows Machine, by type, so that flapping instances and
+	// images with a broken SSH server are visible on dashboards
+	sshFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "windows_machine_ssh_failures_total",
+		Help: "Number of SSH failures of the Windows Machine, by type: dial, auth or command",
+	}, []string{"machine", "instance", "type"})
+	// sshFailureInstances are the instances of each Machine for which SSH failures were counted, so that the series
+	// of a Machine can be deleted along with it
+	sshFailureInstances = make(map[string]map[string]bool)
+	// sshFailureMutex guards sshFailureInstances
+	sshFailureMutex sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(sshFailures)
+}
+
+// countSSHFailure counts an SSH failure of the given type on the given instance of the given Machine
+func countSSHFailure(machineName, instanceID, failureType string) {
+	sshFailureMutex.Lock()
+	defer sshFailureMutex.Unlock()
+	if sshFailureInstances[machineName] == nil {
+		sshFailureInstances[machineName] = make(map[string]bool)
+	}
+	sshFailureInstances[machineName][instanceID] = true
+	sshFailures.WithLabelValues(machineName, instanceID, failureType).Inc()
+}
+
+// countConnectionFailure counts the failure of an SSH connection with the given error on the given instance of the
+// given Machine, as an authentication failure if the credentials were rejected, else as a dial failure
+func countConnectionFailure(machineName, instanceID string, err error) {
+	var authErr *AuthErr
+	if errors.As(err, &authErr) {
+		countSSHFailure(machineName, instanceID, sshFailureAuth)
+		return
+	}
+	countSSHFailure(machineName, instanceID, sshFailureDial)
+}
+
+// DeleteSSHFailures deletes the SSH failure counters of the Machine with the given name
+func DeleteSSHFailures(machineName string) {
+	sshFailureMutex.Lock()
+	defer sshFailureMutex.Unlock()
+	for instanceID := range sshFailureInstances[machineName] {
+		for _, failureType := range []string{sshFailureDial, sshFailureAuth, sshFailureCommand} {
+			sshFailures.DeleteLabelValues(machineName, instanceID, failureType)
+		}
+	}
+	delete(sshFailureInstances, machineName)
+}
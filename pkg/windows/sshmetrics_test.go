@@ -0,0 +1,28 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCountConnectionFailure tests the countConnectionFailure and DeleteSSHFailures functions
+func TestCountConnectionFailure(t *testing.T) {
+	collected := func() int {
+		ch := make(chan prometheus.Metric, 10)
+		sshFailures.Collect(ch)
+		return len(ch)
+	}
+	countConnectionFailure("machine", "i-1", errors.Wrap(newAuthErr(errors.New("no key")), "unable to connect"))
+	countConnectionFailure("machine", "i-1", errors.New("connection refused"))
+	countConnectionFailure("other", "i-2", errors.New("connection refused"))
+	assert.Equal(t, 3, collected())
+	assert.Equal(t, map[string]bool{"i-1": true}, sshFailureInstances["machine"])
+
+	DeleteSSHFailures("machine")
+	assert.Equal(t, 1, collected())
+	assert.NotContains(t, sshFailureInstances, "machine")
+	DeleteSSHFailures("other")
+}
@@ -0,0 +1,174 @@
+package windows
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+)
+
+// Schemes of the proxies the SSH connections to Windows VMs can be dialed through
+const (
+	// SSHProxySOCKS5 dials through a SOCKS5 proxy
+	SSHProxySOCKS5 = "socks5"
+	// SSHProxyHTTP dials through an HTTP proxy supporting the CONNECT method
+	SSHProxyHTTP = "http"
+	// SSHProxyHTTPS dials through an HTTP proxy supporting the CONNECT method, reached over TLS
+	SSHProxyHTTPS = "https"
+)
+
+// sshProxyDialTimeout is the time allowed to connect to the proxy and for the proxy to connect to the VM
+const sshProxyDialTimeout = 30 * time.Second
+
+// httpProxyDefaultPorts are the ports of the HTTP proxies whose URL does not give one, by scheme. The SOCKS5 proxies
+// default to port 1080.
+var httpProxyDefaultPorts = map[string]string{SSHProxyHTTP: "80", SSHProxyHTTPS: "443"}
+
+func init() {
+	// SOCKS5 is supported by the proxy package, which is extended with the HTTP CONNECT method
+	proxy.RegisterDialerType(SSHProxyHTTP, newHTTPConnectDialer)
+	proxy.RegisterDialerType(SSHProxyHTTPS, newHTTPConnectDialer)
+}
+
+// ParseSSHProxy returns the URL of the given proxy, given as a socks5://, http:// or https:// URL with an optional
+// port, such as socks5://proxy.example.com:1080. The credentials of the proxy are not part of the URL, which is held
+// by the operator ConfigMap, but are given separately.
+func ParseSSHProxy(sshProxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(sshProxy)
+	if err != nil || (proxyURL.Scheme != SSHProxySOCKS5 && httpProxyDefaultPorts[proxyURL.Scheme] == "") ||
+		proxyURL.Hostname() == "" || (proxyURL.Path != "" && proxyURL.Path != "/") || proxyURL.RawQuery != "" {
+		return nil, errors.New("expected a socks5://, http:// or https:// URL")
+	}
+	// The URL is not logged in the errors, as it could hold credentials
+	if proxyURL.User != nil {
+		return nil, errors.New("the URL must not hold the credentials of the proxy")
+	}
+	return proxyURL, nil
+}
+
+// dialSSH returns an SSH client connected to the given address, directly or through the proxy with the given URL,
+// holding its credentials, if not nil
+func dialSSH(proxyURL *url.URL, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if proxyURL == nil {
+		return ssh.Dial("tcp", address, config)
+	}
+	dialer, err := proxy.FromURL(proxyURL, &net.Dialer{Timeout: sshProxyDialTimeout})
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid SSH proxy %s", proxyURL.Host)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.Errorf("SSH proxy %s does not support contexts", proxyURL.Host)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sshProxyDialTimeout)
+	defer cancel()
+	// The credentials of the proxy are left out of the errors, which are logged
+	conn, err := contextDialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to connect to %s through SSH proxy %s", address, proxyURL.Host)
+	}
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(clientConn, channels, requests), nil
+}
+
+// httpConnectDialer dials connections tunneled through an HTTP proxy with the CONNECT method
+type httpConnectDialer struct {
+	// proxyURL is the URL of the proxy, holding its credentials if it requires authentication
+	proxyURL *url.URL
+	// forward dials the connections to the proxy
+	forward proxy.ContextDialer
+}
+
+// newHTTPConnectDialer returns a dialer tunneling the connections through the HTTP proxy with the given URL, reached
+// through the given dialer
+func newHTTPConnectDialer(proxyURL *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	contextDialer, ok := forward.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("the dialer of the HTTP proxy does not support contexts")
+	}
+	return &httpConnectDialer{proxyURL: proxyURL, forward: contextDialer}, nil
+}
+
+func (d *httpConnectDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the proxy and asks it to tunnel the connection to the given address, within the deadline
+// of the given context
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	port := d.proxyURL.Port()
+	if port == "" {
+		port = httpProxyDefaultPorts[d.proxyURL.Scheme]
+	}
+	conn, err := d.forward.DialContext(ctx, network, net.JoinHostPort(d.proxyURL.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	if d.proxyURL.Scheme == SSHProxyHTTPS {
+		conn = tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	tunnel, err := httpConnect(conn, d.proxyURL.User, address)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// bufferedConn is a connection whose reads go through a buffered reader holding data already read from it
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// httpConnect asks the HTTP proxy the given connection is established with to tunnel it to the given address, with
+// the CONNECT method, authenticating with the given credentials if not nil. The returned connection must be used in
+// place of the given one, as the reply of the proxy may have been read along with the first bytes of the tunnel.
+func httpConnect(conn net.Conn, user *url.Userinfo, address string) (net.Conn, error) {
+	request := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: address}, Host: address,
+		Header: make(http.Header)}
+	if user != nil {
+		password, _ := user.Password()
+		request.Header.Set("Proxy-Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := request.Write(conn); err != nil {
+		return nil, errors.Wrap(err, "error sending CONNECT request")
+	}
+	reader := bufio.NewReader(conn)
+	// The body of the response is not read, as it is the tunnel once the request succeeds
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading CONNECT response")
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("proxy refused the connection: %s", response.Status)
+	}
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
@@ -0,0 +1,122 @@
+package windows
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// vmToolsServiceName is the Windows service of VMware Tools
+	vmToolsServiceName = "VMTools"
+	// sshdServiceName is the Windows service of the OpenSSH server
+	sshdServiceName = "sshd"
+	// vmToolsPath is the location of the VMware Tools daemon, whose file version is the VMware Tools version
+	vmToolsPath = "C:\\Program Files\\VMware\\VMware Tools\\vmtoolsd.exe"
+	// vmToolsConfPath is the location of the VMware Tools configuration file
+	vmToolsConfPath = "C:\\ProgramData\\VMware\\VMware Tools\\tools.conf"
+)
+
+// minVMToolsVersion is the minimum VMware Tools version reporting the IP address of the VM to vCenter
+var minVMToolsVersion = []int{11, 0, 6}
+
+// TemplateErr occurs when the vSphere VM was cloned from a golden image missing some of its prerequisites
+type TemplateErr struct {
+	missing []string
+}
+
+func (e *TemplateErr) Error() string {
+	return fmt.Sprintf("VM template is missing vSphere prerequisites: %s", strings.Join(e.missing, "; "))
+}
+
+// checkTemplateCmd reports the state of the prerequisites of the vSphere golden image, one
+// "service|<name>|<status>|<start type>", "version|<VMware Tools version>" or "excludeNics|<set or unset>" line each
+var checkTemplateCmd = "\"foreach ($name in @(" + psList([]string{vmToolsServiceName, sshdServiceName}) + ")) { " +
+	"$s = Get-Service -Name $name -ErrorAction SilentlyContinue; " +
+	"if ($s) { 'service|' + $name + '|' + $s.Status + '|' + $s.StartType } else { 'service|' + $name + '|Missing|' } }; " +
+	"$exe = Get-Item -Path " + psString(vmToolsPath) + " -ErrorAction SilentlyContinue; " +
+	"if ($exe) { 'version|' + $exe.VersionInfo.FileVersion }; " +
+	"$conf = Get-Content -Raw -Path " + psString(vmToolsConfPath) + " -ErrorAction SilentlyContinue; " +
+	"if ($conf -match '(?m)^\\s*exclude-nics\\s*=\\s*$') { 'excludeNics|set' } else { 'excludeNics|unset' }\""
+
+// validateVSphereTemplate returns a TemplateErr if the VM is missing prerequisites of the vSphere golden image, which
+// would otherwise cause less explicit failures later in the configuration
+func (vm *windows) validateVSphereTemplate() error {
+	out, err := vm.Run(checkTemplateCmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error checking the vSphere template prerequisites with output: %s", out)
+	}
+	if missing := missingTemplatePrerequisites(out); len(missing) > 0 {
+		return &TemplateErr{missing: missing}
+	}
+	return nil
+}
+
+// missingTemplatePrerequisites returns the prerequisites of the vSphere golden image missing according to the given
+// output of checkTemplateCmd
+func missingTemplatePrerequisites(out string) []string {
+	var missing []string
+	versionFound := false
+	for _, line := range parseChanges(out) {
+		fields := strings.Split(line, "|")
+		switch {
+		case fields[0] == "service" && len(fields) == 4:
+			name, status, startType := fields[1], fields[2], fields[3]
+			if status == "Missing" {
+				missing = append(missing, "service "+name+" is not installed")
+				continue
+			}
+			// sshd only needs to start on boot, as WMCO is connected through it
+			if name == vmToolsServiceName && status != "Running" {
+				missing = append(missing, "service "+name+" is "+strings.ToLower(status))
+			}
+			if startType != "Automatic" {
+				missing = append(missing, "service "+name+" does not start on boot")
+			}
+		case fields[0] == "version" && len(fields) == 2:
+			versionFound = true
+			if !versionAtLeast(fields[1], minVMToolsVersion) {
+				missing = append(missing, fmt.Sprintf("VMware Tools version %s is older than %s", fields[1],
+					joinVersion(minVMToolsVersion)))
+			}
+		case fields[0] == "excludeNics" && len(fields) == 2:
+			if fields[1] != "set" {
+				missing = append(missing, "tools.conf does not have an empty exclude-nics entry")
+			}
+		}
+	}
+	if !versionFound {
+		missing = append(missing, "VMware Tools is not installed")
+	}
+	return missing
+}
+
+// versionAtLeast returns true if the given dotted version is at least the given minimum version
+func versionAtLeast(version string, min []int) bool {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	for i, minPart := range min {
+		// Missing parts are zero
+		part := 0
+		if i < len(parts) {
+			var err error
+			if part, err = strconv.Atoi(parts[i]); err != nil {
+				return false
+			}
+		}
+		if part != minPart {
+			return part > minPart
+		}
+	}
+	return true
+}
+
+// joinVersion returns the given version in the dotted format
+func joinVersion(version []int) string {
+	parts := make([]string, len(version))
+	for i, part := range version {
+		parts[i] = strconv.Itoa(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,50 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingTemplatePrerequisites(t *testing.T) {
+	tests := []struct {
+		name     string
+		out      string
+		expected []string
+	}{
+		{
+			name: "valid",
+			out: "service|VMTools|Running|Automatic\r\nservice|sshd|Running|Automatic\r\nversion|11.2.5.26209\r\n" +
+				"excludeNics|set\r\n",
+			expected: nil,
+		},
+		{
+			name: "VMware Tools not installed",
+			out:  "service|VMTools|Missing|\r\nservice|sshd|Running|Automatic\r\nexcludeNics|unset\r\n",
+			expected: []string{"service VMTools is not installed", "tools.conf does not have an empty exclude-nics entry",
+				"VMware Tools is not installed"},
+		},
+		{
+			name: "misconfigured",
+			out: "service|VMTools|Stopped|Manual\r\nservice|sshd|Running|Manual\r\nversion|10.3.10.12406962\r\n" +
+				"excludeNics|set\r\n",
+			expected: []string{"service VMTools is stopped", "service VMTools does not start on boot",
+				"service sshd does not start on boot", "VMware Tools version 10.3.10.12406962 is older than 11.0.6"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, missingTemplatePrerequisites(test.out))
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	assert.True(t, versionAtLeast("11.0.6", []int{11, 0, 6}))
+	assert.True(t, versionAtLeast("11.0.6.19689", []int{11, 0, 6}))
+	assert.True(t, versionAtLeast("12.0.0", []int{11, 0, 6}))
+	assert.True(t, versionAtLeast("11.1", []int{11, 0, 6}))
+	assert.False(t, versionAtLeast("11.0.5.9999", []int{11, 0, 6}))
+	assert.False(t, versionAtLeast("11", []int{11, 0, 6}))
+	assert.False(t, versionAtLeast("", []int{11, 0, 6}))
+}
@@ -0,0 +1,56 @@
+package windows
+
+import (
+	"io"
+	"time"
+)
+
+// throttleInterval is the interval over which the bandwidth of a throttled transfer is averaged. Each read is limited
+// to the bytes allowed within this interval, so that the transfer never bursts much above the bandwidth.
+const throttleInterval = 100 * time.Millisecond
+
+// throttledReader is a reader whose throughput is limited to a number of bytes per second
+type throttledReader struct {
+	reader io.Reader
+	// bytesPerSecond is the maximum throughput of the reader
+	bytesPerSecond int64
+	// start is the time of the first read
+	start time.Time
+	// read is the number of bytes read so far
+	read int64
+	// sleep waits for the given duration, replaced in tests
+	sleep func(time.Duration)
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// newThrottledReader returns a reader limiting the throughput of the given reader to the given number of bytes per
+// second, or the given reader itself if the bandwidth is not limited
+func newThrottledReader(reader io.Reader, bytesPerSecond int64) io.Reader {
+	if bytesPerSecond <= 0 {
+		return reader
+	}
+	return &throttledReader{reader: reader, bytesPerSecond: bytesPerSecond, sleep: time.Sleep, now: time.Now}
+}
+
+// Read reads from the underlying reader, waiting as long as needed to keep the average throughput since the first
+// read within the bandwidth
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = t.now()
+	}
+	chunk := t.bytesPerSecond * int64(throttleInterval) / int64(time.Second)
+	if chunk < 1 {
+		chunk = 1
+	}
+	if int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+	n, err := t.reader.Read(p)
+	t.read += int64(n)
+	expected := time.Duration(t.read * int64(time.Second) / t.bytesPerSecond)
+	if elapsed := t.now().Sub(t.start); elapsed < expected {
+		t.sleep(expected - elapsed)
+	}
+	return n, err
+}
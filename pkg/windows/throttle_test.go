@@ -0,0 +1,30 @@
+package windows
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 5000)
+	assert.Equal(t, bytes.NewReader(data), newThrottledReader(bytes.NewReader(data), 0))
+
+	// The clock only advances when the reader sleeps, so the total sleep is the time the transfer must take
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	reader := newThrottledReader(bytes.NewReader(data), 1000).(*throttledReader)
+	reader.now = func() time.Time { return now }
+	reader.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+	read, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, read)
+	assert.Equal(t, 5*time.Second, slept)
+}
@@ -0,0 +1,66 @@
+package windows
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// w32timeParameters is the registry key holding the configuration of the Windows Time service
+const w32timeParameters = "HKLM:\\SYSTEM\\CurrentControlSet\\Services\\W32Time\\Parameters"
+
+// ensureTimeSync configures the Windows Time service to synchronize the clock of the VM with the NTP servers of the
+// host settings, and ensures it is started automatically. The service is restarted and the clock resynchronized when
+// the servers change.
+func (vm *windows) ensureTimeSync() error {
+	peers := ntpPeerList(vm.host.NTPServers)
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"if ((Get-Service w32time).StartType -ne 'Automatic') { Set-Service w32time -StartupType Automatic; " +
+		"'set w32time startup type to Automatic' }; " +
+		"if ((Get-Service w32time).Status -ne 'Running') { Start-Service w32time; 'started w32time' }; " +
+		"$params = Get-ItemProperty -Path " + w32timeParameters + "; " +
+		"if ($params.NtpServer -ne '" + peers + "' -or $params.Type -ne 'NTP') { " +
+		"w32tm /config /manualpeerlist:'" + peers + "' /syncfromflags:manual /update | Out-Null; " +
+		"Restart-Service w32time; w32tm /resync /force | Out-Null; 'set NTP servers to " + peers + "' }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return errors.Wrapf(err, "error configuring time synchronization with output: %s", out)
+	}
+	for _, change := range strings.Split(strings.TrimSpace(out), "\n") {
+		if change = strings.TrimSpace(change); change != "" {
+			vm.log.Info("time synchronization", "change", change)
+		}
+	}
+	return nil
+}
+
+// ntpPeerList returns the w32time peer list of the given NTP servers, polled at the interval configured in the
+// Windows Time service rather than the interval negotiated with the servers
+func ntpPeerList(servers []string) string {
+	peers := make([]string, 0, len(servers))
+	for _, server := range servers {
+		peers = append(peers, server+",0x8")
+	}
+	return strings.Join(peers, " ")
+}
+
+func (vm *windows) ClockSkew() (time.Duration, error) {
+	before := time.Now()
+	out, err := vm.Run("\"[DateTime]::UtcNow.ToString('o')\"", true)
+	after := time.Now()
+	if err != nil {
+		return 0, errors.Wrapf(err, "error reading the time of the VM with output: %s", out)
+	}
+	remote, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(out))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid time %q", strings.TrimSpace(out))
+	}
+	return clockSkew(before, after, remote), nil
+}
+
+// clockSkew returns the offset of the given remote time from the midpoint of the local times measured before and
+// after it was read, which compensates for the latency of the remote command
+func clockSkew(before, after, remote time.Time) time.Duration {
+	return remote.Sub(before.Add(after.Sub(before) / 2))
+}
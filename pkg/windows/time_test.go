@@ -0,0 +1,22 @@
+package windows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNTPPeerList tests the ntpPeerList function
+func TestNTPPeerList(t *testing.T) {
+	assert.Equal(t, "time.example.com,0x8 10.0.0.4,0x8", ntpPeerList([]string{"time.example.com", "10.0.0.4"}))
+}
+
+// TestClockSkew tests the clockSkew function
+func TestClockSkew(t *testing.T) {
+	before := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	after := before.Add(2 * time.Second)
+	assert.Equal(t, time.Duration(0), clockSkew(before, after, before.Add(time.Second)))
+	assert.Equal(t, time.Minute, clockSkew(before, after, before.Add(time.Minute+time.Second)))
+	assert.Equal(t, -time.Minute, clockSkew(before, after, before.Add(-time.Minute+time.Second)))
+}
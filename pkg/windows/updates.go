@@ -0,0 +1,214 @@
+package windows
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/retry"
+)
+
+const (
+	// securityUpdatesCriteria is the Windows Update search criteria matching the security updates not yet installed
+	securityUpdatesCriteria = "IsInstalled=0 and IsHidden=0 and Type='Software' and " +
+		"CategoryIDs contains '0FA1201D-4330-4FA8-8AE9-B877473B6441'"
+	// windowsUpdateTask is the name of the scheduled task installing the updates. The Windows Update API cannot
+	// download or install updates from a remote session, so the updates are installed by a task running as SYSTEM.
+	windowsUpdateTask = "wmco-windows-update"
+	// windowsUpdateScript is the remote location of the script run by the Windows Update task
+	windowsUpdateScript = k8sDir + "windows-update.ps1"
+	// windowsUpdateResult is the remote location of the result written by the Windows Update task once it completes
+	windowsUpdateResult = k8sDir + "windows-update-result.txt"
+)
+
+// windowsUpdateScriptContents installs the pending security updates, writing one line per update, prefixed by
+// installed or failed, and whether a reboot is required to the result file. The result is moved into place once
+// complete so that a partial result is never read.
+var windowsUpdateScriptContents = "$ErrorActionPreference = 'Stop'\r\n" +
+	"$result = '" + windowsUpdateResult + "'\r\n" +
+	"try {\r\n" +
+	"  $session = New-Object -ComObject Microsoft.Update.Session\r\n" +
+	"  $search = $session.CreateUpdateSearcher().Search(\"" + securityUpdatesCriteria + "\")\r\n" +
+	"  $updates = New-Object -ComObject Microsoft.Update.UpdateColl\r\n" +
+	"  foreach ($update in $search.Updates) { if (-not $update.EulaAccepted) { $update.AcceptEula() }; " +
+	"[void]$updates.Add($update) }\r\n" +
+	"  $lines = @()\r\n" +
+	"  if ($updates.Count -gt 0) {\r\n" +
+	"    $downloader = $session.CreateUpdateDownloader(); $downloader.Updates = $updates\r\n" +
+	"    [void]$downloader.Download()\r\n" +
+	"    $installer = $session.CreateUpdateInstaller(); $installer.Updates = $updates\r\n" +
+	"    $installed = $installer.Install()\r\n" +
+	"    for ($i = 0; $i -lt $updates.Count; $i++) {\r\n" +
+	"      if (@(2, 3) -contains $installed.GetUpdateResult($i).ResultCode) { $lines += 'installed ' + " +
+	"$updates.Item($i).Title } else { $lines += 'failed ' + $updates.Item($i).Title }\r\n" +
+	"    }\r\n" +
+	"  }\r\n" +
+	"  $lines += 'reboot ' + (New-Object -ComObject Microsoft.Update.SystemInfo).RebootRequired\r\n" +
+	"} catch {\r\n" +
+	"  $lines = @('error ' + $_)\r\n" +
+	"}\r\n" +
+	"Set-Content -Path \"$result.tmp\" -Value $lines\r\n" +
+	"Move-Item -Force -Path \"$result.tmp\" -Destination $result\r\n"
+
+// UpdateResult is the outcome of the installation of the pending security updates
+type UpdateResult struct {
+	// Installed and Failed are the titles of the updates installed and of the updates which failed to install
+	Installed []string
+	Failed    []string
+	// RebootRequired is true if the VM must be rebooted for the updates to take effect
+	RebootRequired bool
+}
+
+func (vm *windows) PendingUpdates() ([]string, error) {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"(New-Object -ComObject Microsoft.Update.Session).CreateUpdateSearcher().Search('" +
+		strings.ReplaceAll(securityUpdatesCriteria, "'", "''") + "').Updates | ForEach-Object { $_.Title }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error searching for security updates with output: %s", out)
+	}
+	var updates []string
+	for _, update := range strings.Split(strings.TrimSpace(out), "\n") {
+		if update = strings.TrimSpace(update); update != "" {
+			updates = append(updates, update)
+		}
+	}
+	return updates, nil
+}
+
+func (vm *windows) InstallUpdates() (*UpdateResult, error) {
+	cmd := "\"if (Test-Path " + windowsUpdateResult + ") { 'result'; Get-Content " + windowsUpdateResult + "; " +
+		"Remove-Item -Force " + windowsUpdateResult + "; " +
+		"Unregister-ScheduledTask -TaskName " + windowsUpdateTask + " -Confirm:$false -ErrorAction SilentlyContinue; " +
+		"exit }; " +
+		"if ((Get-ScheduledTask -TaskName " + windowsUpdateTask + " -ErrorAction SilentlyContinue).State -eq " +
+		"'Running') { 'running' }\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error checking Windows Update task with output: %s", out)
+	}
+	out = strings.TrimSpace(out)
+	if out == "running" {
+		return nil, nil
+	}
+	if strings.HasPrefix(out, "result") {
+		return parseUpdateResult(strings.TrimPrefix(out, "result"))
+	}
+
+	vm.log.Info("installing security updates")
+	if err := vm.writeFile(windowsUpdateScript, []byte(windowsUpdateScriptContents)); err != nil {
+		return nil, err
+	}
+	cmd = "\"$ErrorActionPreference = 'Stop'; " +
+		"$action = New-ScheduledTaskAction -Execute powershell.exe -Argument '-NoProfile -ExecutionPolicy Bypass " +
+		"-File " + windowsUpdateScript + "'; " +
+		"Register-ScheduledTask -TaskName " + windowsUpdateTask + " -Action $action -User 'NT AUTHORITY\\SYSTEM' " +
+		"-RunLevel Highest -Force | Out-Null; Start-ScheduledTask -TaskName " + windowsUpdateTask + "\""
+	if out, err := vm.Run(cmd, true); err != nil {
+		return nil, errors.Wrapf(err, "error starting Windows Update task with output: %s", out)
+	}
+	return nil, nil
+}
+
+// parseUpdateResult parses the result written by the Windows Update task
+func parseUpdateResult(out string) (*UpdateResult, error) {
+	result := &UpdateResult{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		tokens := strings.SplitN(line, " ", 2)
+		if len(tokens) != 2 {
+			return nil, errors.Errorf("invalid Windows Update result %q", line)
+		}
+		switch tokens[0] {
+		case "installed":
+			result.Installed = append(result.Installed, tokens[1])
+		case "failed":
+			result.Failed = append(result.Failed, tokens[1])
+		case "reboot":
+			rebootRequired, err := strconv.ParseBool(tokens[1])
+			if err != nil {
+				return nil, errors.Errorf("invalid Windows Update result %q", line)
+			}
+			result.RebootRequired = rebootRequired
+		case "error":
+			return nil, errors.Errorf("error installing security updates: %s", tokens[1])
+		default:
+			return nil, errors.Errorf("invalid Windows Update result %q", line)
+		}
+	}
+	return result, nil
+}
+
+func (vm *windows) Reboot() error {
+	bootTime, err := vm.lastBootTime()
+	if err != nil {
+		return err
+	}
+	vm.log.Info("rebooting VM")
+	if out, err := vm.Run("Restart-Computer -Force", true); err != nil {
+		return errors.Wrapf(err, "error restarting VM with output: %s", out)
+	}
+	// The SSH server may still accept connections while the VM shuts down, so the VM is only considered rebooted once
+	// its boot time changed
+	err = wait.PollImmediate(retry.Interval, retry.Timeout, func() (bool, error) {
+		if err := vm.Reinitialize(); err != nil {
+			if errors.Is(err, ErrCancelled) {
+				return false, err
+			}
+			vm.log.V(1).Error(err, "unable to reconnect to VM")
+			return false, nil
+		}
+		current, err := vm.lastBootTime()
+		if err != nil {
+			vm.log.V(1).Error(err, "unable to get boot time")
+			return false, nil
+		}
+		return current != bootTime, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error waiting for VM to reboot")
+	}
+	return nil
+}
+
+// lastBootTime returns the time at which the VM last booted
+func (vm *windows) lastBootTime() (string, error) {
+	out, err := vm.Run("\"(Get-CimInstance Win32_OperatingSystem).LastBootUpTime.ToUniversalTime().ToString('o')\"",
+		true)
+	if err != nil {
+		return "", errors.Wrapf(err, "error getting boot time with output: %s", out)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (vm *windows) WaitForServices() error {
+	for _, serviceName := range requiredServices {
+		exists, err := vm.serviceExists(serviceName)
+		if err != nil {
+			return errors.Wrapf(err, "error checking if %s Windows service exists", serviceName)
+		}
+		if !exists {
+			continue
+		}
+		if err := vm.waitForServiceToRun(serviceName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (vm *windows) OSBuild() (string, error) {
+	cmd := "\"$v = Get-ItemProperty 'HKLM:\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion'; " +
+		"'{0}.{1}.{2}.{3}' -f $v.CurrentMajorVersionNumber, $v.CurrentMinorVersionNumber, $v.CurrentBuildNumber, " +
+		"$v.UBR\""
+	out, err := vm.Run(cmd, true)
+	if err != nil {
+		return "", errors.Wrapf(err, "error getting OS build with output: %s", out)
+	}
+	return strings.TrimSpace(out), nil
+}
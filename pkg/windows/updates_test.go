@@ -0,0 +1,32 @@
+package windows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseUpdateResult tests the parseUpdateResult function
+func TestParseUpdateResult(t *testing.T) {
+	result, err := parseUpdateResult("installed 2021-04 Cumulative Update for Windows Server 2019 (KB5001342)\r\n" +
+		"failed Security Update for Windows Server 2019 (KB4535680)\r\n" +
+		"reboot True\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &UpdateResult{
+		Installed:      []string{"2021-04 Cumulative Update for Windows Server 2019 (KB5001342)"},
+		Failed:         []string{"Security Update for Windows Server 2019 (KB4535680)"},
+		RebootRequired: true,
+	}, result)
+
+	result, err = parseUpdateResult("reboot False")
+	require.NoError(t, err)
+	assert.Equal(t, &UpdateResult{}, result)
+
+	_, err = parseUpdateResult("error Exception from HRESULT: 0x80240024")
+	assert.Error(t, err)
+	_, err = parseUpdateResult("reboot maybe")
+	assert.Error(t, err)
+	_, err = parseUpdateResult("skipped KB4535680")
+	assert.Error(t, err)
+}
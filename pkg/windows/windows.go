@@ -1,20 +1,25 @@
 package windows
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-logr/logr"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	oconfig "github.com/openshift/api/config/v1"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/http/httpproxy"
 	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/openshift/windows-machine-config-operator/pkg/nodeconfig/payload"
 	"github.com/openshift/windows-machine-config-operator/pkg/retry"
+	keysigner "github.com/openshift/windows-machine-config-operator/pkg/signer"
 )
 
 const (
@@ -32,6 +37,9 @@ const (
 	logDir = "C:\\var\\log\\"
 	// kubeProxyLogDir is the remote kube-proxy log directory
 	kubeProxyLogDir = logDir + "kube-proxy\\"
+	// kubeProxyLogFile is the remote kube-proxy log file. A log file with a fixed name, rather than klog's per process
+	// log files, allows the log to be read through the kubelet logs endpoint like the other component logs.
+	kubeProxyLogFile = kubeProxyLogDir + "kube-proxy.log"
 	// hybridOverlayLogDir is the remote hybrid-overlay log directory
 	hybridOverlayLogDir = logDir + "hybrid-overlay\\"
 	// cniDir is the directory for storing CNI binaries
@@ -54,15 +62,17 @@ const (
 	BaseOVNKubeOverlayNetwork = "BaseOVNKubernetesHybridOverlayNetwork"
 	// OVNKubeOverlayNetwork is the name of the OVN HNS Overlay network
 	OVNKubeOverlayNetwork = "OVNKubernetesHybridOverlayNetwork"
+	// sourceVIPEndpointName is the name of the HNS endpoint of the kube-proxy source VIP on the overlay network
+	sourceVIPEndpointName = "VIPEndpoint"
 	// kubeProxyServiceName is the name of the kube-proxy Windows service
 	kubeProxyServiceName = "kube-proxy"
 	// kubeletServiceName is the name of the kubelet Windows service
 	kubeletServiceName = "kubelet"
 	// windowsExporterServiceName is the name of the windows_exporter Windows service
 	windowsExporterServiceName = "windows_exporter"
-	// windowsExporterServiceArgs specifies metrics for the windows_exporter service to collect
-	// and expose metrics at endpoint with default port :9182 and default URL path /metrics
-	windowsExporterServiceArgs = "--collectors.enabled " +
+	// windowsExporterServiceArgs specifies metrics for the windows_exporter service to collect and expose at the
+	// default URL path /metrics, listening on the loopback interface only as the metrics are served by kube-rbac-proxy
+	windowsExporterServiceArgs = "--telemetry.addr " + windowsExporterAddress + " --collectors.enabled " +
 		"cpu,cs,logical_disk,net,os,service,system,textfile,container,memory\""
 	// remotePowerShellCmdPrefix holds the PowerShell prefix that needs to be prefixed  for every remote PowerShell
 	// command executed on the remote Windows VM
@@ -75,13 +85,26 @@ const (
 	serviceNotFound = "status 1060"
 )
 
-// filesToTransfer is a map of what files should be copied to the Windows VM and where they should be copied to
-var filesToTransfer map[*payload.FileInfo]string
+// requiredServices are the Windows services created by WMCO on the Windows VM. The order matters due to service
+// dependencies.
+var requiredServices = []string{windowsExporterServiceName, kubeProxyServiceName, hybridOverlayServiceName,
+	kubeletServiceName}
+
+var (
+	// filesToTransfer maps each payload version, empty for the current one, to the files which should be copied to the
+	// Windows VM and where they should be copied to
+	filesToTransfer = make(map[string]map[*payload.FileInfo]string)
+	// filesToTransferMutex guards filesToTransfer
+	filesToTransferMutex sync.Mutex
+)
 
-// getFilesToTransfer returns the properly populated filesToTransfer map
-func getFilesToTransfer() (map[*payload.FileInfo]string, error) {
-	if filesToTransfer != nil {
-		return filesToTransfer, nil
+// getFilesToTransfer returns the properly populated filesToTransfer map of the given payload version, empty for the
+// payload of the current version
+func getFilesToTransfer(payloadVersion string) (map[*payload.FileInfo]string, error) {
+	filesToTransferMutex.Lock()
+	defer filesToTransferMutex.Unlock()
+	if files, present := filesToTransfer[payloadVersion]; present {
+		return files, nil
 	}
 	srcDestPairs := map[string]string{
 		payload.IgnoreWgetPowerShellPath: remoteDir,
@@ -89,6 +112,7 @@ func getFilesToTransfer() (map[*payload.FileInfo]string, error) {
 		payload.HybridOverlayPath:        k8sDir,
 		payload.HNSPSModule:              remoteDir,
 		payload.WindowsExporterPath:      k8sDir,
+		payload.KubeRBACProxyPath:        k8sDir,
 		payload.FlannelCNIPluginPath:     cniDir,
 		payload.WinBridgeCNIPlugin:       cniDir,
 		payload.HostLocalCNIPlugin:       cniDir,
@@ -98,14 +122,15 @@ func getFilesToTransfer() (map[*payload.FileInfo]string, error) {
 	}
 	files := make(map[*payload.FileInfo]string)
 	for src, dest := range srcDestPairs {
+		src = payload.Path(src, payloadVersion)
 		f, err := payload.NewFileInfo(src)
 		if err != nil {
 			return nil, errors.Wrapf(err, "could not create FileInfo object for file %s", src)
 		}
 		files[f] = dest
 	}
-	filesToTransfer = files
-	return filesToTransfer, nil
+	filesToTransfer[payloadVersion] = files
+	return files, nil
 }
 
 // Windows contains all the  methods needed to configure a Windows VM to become a worker node
@@ -125,16 +150,137 @@ type Windows interface {
 	Run(string, bool) (string, error)
 	// Reinitialize re-initializes the Windows VM's SSH client
 	Reinitialize() error
-	// Configure prepares the Windows VM for the bootstrapper and then runs it
-	Configure() error
+	// Cancel interrupts the command or file transfer in progress on the VM, and makes the following ones fail with
+	// ErrCancelled, so that the configuration of a VM about to be terminated is aborted. It is safe to call
+	// concurrently with the other methods.
+	Cancel()
+	// Close closes the connection to the VM, which must not be used afterwards
+	Close() error
+	// Configure prepares the Windows VM for the bootstrapper and then runs it, reporting the steps completed to the
+	// given progress function, which may be nil. The configuration is cancelled once the given context is done.
+	Configure(context.Context, ProgressFunc) error
 	// ConfigureCNI ensures that the CNI configuration in done on the node
 	ConfigureCNI(string) error
 	// ConfigureHybridOverlay ensures that the hybrid overlay is running on the node
 	ConfigureHybridOverlay(string) error
 	// ConfigureWindowsExporter ensures that the Windows metrics exporter is running on the node
 	ConfigureWindowsExporter() error
+	// ConfigureMetricsProxy runs kube-rbac-proxy in front of windows_exporter, so that the metrics of the Windows VM
+	// are only served to clients authorized to get them. It requires kubelet to have joined the cluster.
+	ConfigureMetricsProxy() error
 	// ConfigureKubeProxy ensures that the kube-proxy service is running
 	ConfigureKubeProxy(string, string) error
+	// ReconfigureKubeProxy applies the Direct Server Return setting to the kube-proxy service, restarting it if its
+	// command line changed
+	ReconfigureKubeProxy() error
+	// CheckHNSNetworks returns the problems breaking the pod networking of the VM: missing OVN HNS networks, remote
+	// subnet route policies or kube-proxy source VIP endpoint. No problems are returned if the pod networking is
+	// healthy, or if the hybrid-overlay or kube-proxy services are not running.
+	CheckHNSNetworks() ([]string, error)
+	// RepairHNSNetworks recreates the OVN overlay HNS network, restarting the hybrid-overlay, and recreates the
+	// kube-proxy service with a new source VIP, given the node name and host subnet
+	RepairHNSNetworks(string, string) error
+	// EnsureHostSettings creates or corrects the firewall rules required by the Kubernetes components, applies the
+	// hardening profile, configures crash dumps, time synchronization, the shutdown hook and the event log export and
+	// sets the overlay MTU and DNS settings, if they are enabled in the host settings
+	EnsureHostSettings() error
+	// DesiredStateDrift returns the resources of the VM managed through its host settings, such as registry values,
+	// services and firewall rules, which drifted from their desired state, without correcting them
+	DesiredStateDrift() ([]string, error)
+	// ConfigurationDrift returns the resources of the VM which drifted from their desired state, including the payload
+	// files if withPayload is set, reported as "file <path>" lines, and the required services which are not running,
+	// reported as "stopped <service>" lines, without correcting them
+	ConfigurationDrift(withPayload bool) ([]string, error)
+	// UnreachableArtifacts returns the sources of the artifacts retrieved by the VM over the network, the Machine
+	// Config Server and the registries of the given images, which cannot be reached from the VM, directly or through
+	// the given cluster proxy when it is not nil
+	UnreachableArtifacts([]string, *httpproxy.Config) ([]string, error)
+	// NetworkInterfaceProblems returns the problems of the network interface configuration of the Azure VM known to
+	// break the hybrid overlay or the VM extensions, such as accelerated networking
+	NetworkInterfaceProblems() ([]string, error)
+	// DetectProblems returns the Windows specific problems of the VM, counting the unexpected service terminations
+	// within the given look-back window
+	DetectProblems(time.Duration) (*Problems, error)
+	// GPUs returns the display adapters of the VM, other than the emulated adapters of the hypervisor
+	GPUs() ([]GPU, error)
+	// IsolationModes returns the OS build of the VM, which the containers it runs process-isolated must be built for
+	IsolationModes() (*IsolationModes, error)
+	// Preflight returns the OS build of the VM, the free space on its system drive and whether the Containers feature
+	// is installed, which are validated before configuring the first instance of a pool
+	Preflight() (*Preflight, error)
+	// Inventory returns the processors, memory, system drive size and physical network adapters of the VM
+	Inventory() (*Inventory, error)
+	// License returns the activation status of the Windows installation of the VM
+	License() (*License, error)
+	// Antivirus returns the status of Microsoft Defender Antivirus on the VM, and the paths and processes of the
+	// container runtime and Kubernetes components missing from its exclusions
+	Antivirus() (*Antivirus, error)
+	// NetworkPolicyStatus returns whether network policies can be, and are, enforced on the pods of the VM
+	NetworkPolicyStatus() (*NetworkPolicyStatus, error)
+	// RunPostConfigureHook runs the post-configure hook script, if any, on the VM once its node is configured
+	RunPostConfigureHook() error
+	// ConfigureServiceRecovery registers recovery actions with the Service Control Manager for the services created by
+	// WMCO and the container runtime, restarting them with a backoff when they crash or exit with an error. Services
+	// which do not exist yet are skipped.
+	ConfigureServiceRecovery() error
+	// PullImages pulls the pause image of the pod sandboxes and the images to pre-pull into the container runtime,
+	// returning an error listing the images which could not be pulled
+	PullImages() error
+	// ClockSkew returns the offset of the clock of the Windows VM from the local clock, positive when the VM is ahead
+	ClockSkew() (time.Duration, error)
+	// InterruptionNotice returns the interruption notice received by the spot or preemptible instance of the VM from
+	// the metadata service of its cloud, on AWS and Azure, or an empty string if there is none
+	InterruptionNotice() (string, error)
+	// BootstrapCredentialsExpiry returns the earliest expiry of the credentials of the bootstrap kubeconfig of the
+	// Windows VM, or the zero time if they do not expire
+	BootstrapCredentialsExpiry() (time.Time, error)
+	// RenewBootstrapCredentials replaces the bootstrap kubeconfig of the Windows VM with the one of the current worker
+	// ignition
+	RenewBootstrapCredentials() error
+	// KubeletClientCertificate returns the validity period of the current client certificate of kubelet
+	KubeletClientCertificate() (*CertificateValidity, error)
+	// RenewKubeletClientCertificate restarts kubelet without its client certificates, so that it requests a new one
+	RenewKubeletClientCertificate() error
+	// Certificates returns the validity period of the certificates of the Kubernetes components of the VM, keyed by
+	// the Certificate names, omitting the ones which do not exist yet. The validity period of a CA bundle is the one of
+	// its first expiring certificate.
+	Certificates() (map[string]*CertificateValidity, error)
+	// CABundleDrift returns the files of the Windows VM whose CA bundles differ from the ones of the current worker
+	// ignition
+	CABundleDrift() ([]string, error)
+	// UpdateCABundles updates the CA bundles of the Windows VM which differ from the ones of the current worker
+	// ignition, restarting the services given by CABundleServices, and returns the updated files
+	UpdateCABundles() ([]string, error)
+	// PendingUpdates returns the titles of the security updates available to the Windows VM and not yet installed
+	PendingUpdates() ([]string, error)
+	// InstallUpdates installs the pending security updates in the background. It starts the installation if it is
+	// not running, and returns nil until the installation completes, after which the result is returned once.
+	InstallUpdates() (*UpdateResult, error)
+	// Reboot restarts the Windows VM and waits until it is reachable again
+	Reboot() error
+	// WaitForServices waits for the Windows services configured by WMCO to run, such as after a reboot
+	WaitForServices() error
+	// OSBuild returns the build of the operating system of the Windows VM, including its update revision
+	OSBuild() (string, error)
+	// CrashDumps returns the crash dumps written on the Windows VM
+	CrashDumps() ([]CrashDump, error)
+	// SetPassword sets the password of the user used to connect to the VM
+	SetPassword(string) error
+	// AuthorizeKey adds the given public key to the public keys authorized to connect to the VM as an administrator
+	AuthorizeKey(ssh.PublicKey) error
+	// RevokeKey removes the given public key from the public keys authorized to connect to the VM as an administrator
+	RevokeKey(ssh.PublicKey) error
+	// ConfigureKubelet applies the kubelet settings on top of the configuration generated by the bootstrapper,
+	// restarting kubelet if its configuration changed
+	ConfigureKubelet() error
+	// RestartKubelet restarts kubelet, such as to recover the collection of the resource statistics of the node
+	RestartKubelet() error
+	// Deconfigure removes the Windows services and files created by WMCO from the Windows VM, and is cancelled once the
+	// given context is done
+	Deconfigure(context.Context) error
+	// CollectDiagnostics gathers the logs of the Kubernetes components, the HNS state, the status of the services and
+	// the network configuration of the Windows VM, keyed by file name
+	CollectDiagnostics() map[string]string
 }
 
 // windows implements the Windows interface
@@ -149,7 +295,9 @@ type windows struct {
 	// signer is used for authenticating against the VM
 	signer ssh.Signer
 	// interact is used to connect to and interact with the VM
-	interact connectivity
+	interact RemoteHost
+	// services manages the Windows services of the VM
+	services ServiceManager
 	// vxlanPort is the custom VXLAN port
 	vxlanPort string
 	// platform indicates the cloud on which OpenShift cluster is running
@@ -157,47 +305,100 @@ type windows struct {
 	// 		 in vSphere
 	//		https://bugzilla.redhat.com/show_bug.cgi?id=1876987
 	platform oconfig.PlatformType
+	// machineName is the name of the Machine of the Windows VM
+	machineName string
 	// hostName is the name of the Windows VM that we need to configure in vSphere clusters. This is currently not set
 	// in case of vSphere VMs. In case of Linux, ignition was handling it. As we don't have an equivalent of ignition
-	// in Windows, we are setting this in WMCO currently
+	// in Windows, we are setting this in WMCO currently. It is derived from the Machine name by HostName.
 	// TODO: Remove this once we figure out how to do this via guestInfo in vSphere
 	// 		https://bugzilla.redhat.com/show_bug.cgi?id=1876987
 	hostName string
-	log      logr.Logger
-}
-
-// New returns a new Windows instance constructed from the given WindowsVM
+	// kubelet holds the kubelet settings applied on top of the configuration generated by the bootstrapper
+	kubelet KubeletSettings
+	// host holds the operating system settings applied before the Kubernetes components are configured
+	host HostSettings
+	// cancelled is set to 1 once the operations on the VM are cancelled
+	cancelled int32
+	log       logr.Logger
+}
+
+// New returns a new Windows instance constructed from the given WindowsVM. The platform default user is used for SSH
+// connections when sshUser is empty. The given kubelet settings are applied on top of the kubelet configuration
+// generated by the bootstrapper, and the given host settings are applied before the Kubernetes components are
+// configured.
 func New(ipAddress, instanceID, machineName, workerIgnitionEndpoint, vxlanPort string, signer ssh.Signer,
-	platform oconfig.PlatformType) (Windows, error) {
+	platform oconfig.PlatformType, sshUser string, kubelet KubeletSettings, host HostSettings) (Windows, error) {
 	if workerIgnitionEndpoint == "" {
 		return nil, errors.New("cannot use empty ignition endpoint")
 	}
 	// TODO: This should be changed so that the "core" user is used on all platforms for SSH connections.
 	// https://issues.redhat.com/browse/WINC-430
-	var adminUser string
-	if platform == oconfig.AzurePlatformType {
-		adminUser = "capi"
-	} else {
-		adminUser = "Administrator"
+	adminUser := sshUser
+	if adminUser == "" {
+		if platform == oconfig.AzurePlatformType {
+			adminUser = "capi"
+		} else {
+			adminUser = "Administrator"
+		}
+	}
+
+	if host.SSHCertificateAuthority != nil {
+		var err error
+		if signer, err = keysigner.Certify(signer, host.SSHCertificateAuthority, adminUser); err != nil {
+			return nil, errors.Wrapf(err, "unable to issue SSH certificate for VM %s", instanceID)
+		}
 	}
 
 	log := ctrl.Log.WithName(fmt.Sprintf("VM %s", instanceID))
+	var sshProxy *url.URL
+	if host.SSHProxy != "" {
+		var err error
+		if sshProxy, err = ParseSSHProxy(host.SSHProxy); err != nil {
+			return nil, errors.Wrap(err, "invalid SSH proxy")
+		}
+		sshProxy.User = host.SSHProxyUser
+	}
 	log.V(1).Info("initializing SSH connection", "user", adminUser)
-	conn, err := newSshConnectivity(adminUser, ipAddress, signer, log)
+	conn, err := newSshConnectivity(adminUser, ipAddress, signer, host.SSHAlgorithms, host.FIPS,
+		sshProxy, host.TransferBandwidth, host.Timeouts, log)
 	if err != nil {
+		countConnectionFailure(machineName, instanceID, err)
 		return nil, errors.Wrapf(err, "unable to setup VM %s sshConnectivity", instanceID)
 	}
+	return NewWithRemoteHost(conn, nil, instanceID, machineName, workerIgnitionEndpoint, vxlanPort, platform, kubelet,
+		host)
+}
 
-	return &windows{
-			id:                     instanceID,
-			interact:               conn,
-			workerIgnitionEndpoint: workerIgnitionEndpoint,
-			vxlanPort:              vxlanPort,
-			platform:               platform,
-			hostName:               machineName,
-			log:                    log,
-		},
-		nil
+// NewWithRemoteHost returns a new Windows instance configured through the given remote host, which must be
+// initialized. The Windows services are managed through the given service manager, or with sc.exe commands run on the
+// remote host if it is nil. The given kubelet settings are applied on top of the kubelet configuration generated by
+// the bootstrapper, and the given host settings are applied before the Kubernetes components are configured.
+func NewWithRemoteHost(remote RemoteHost, services ServiceManager, instanceID, machineName, workerIgnitionEndpoint,
+	vxlanPort string, platform oconfig.PlatformType, kubelet KubeletSettings, host HostSettings) (Windows, error) {
+	if remote == nil {
+		return nil, errors.New("cannot use nil remote host")
+	}
+	if workerIgnitionEndpoint == "" {
+		return nil, errors.New("cannot use empty ignition endpoint")
+	}
+	vm := &windows{
+		id:                     instanceID,
+		interact:               remote,
+		services:               services,
+		workerIgnitionEndpoint: workerIgnitionEndpoint,
+		vxlanPort:              vxlanPort,
+		platform:               platform,
+		machineName:            machineName,
+		hostName:               HostName(machineName),
+		kubelet:                kubelet,
+		host:                   host,
+		log:                    ctrl.Log.WithName(fmt.Sprintf("VM %s", instanceID)),
+	}
+	if vm.services == nil {
+		// The commands are run through the VM, so that they are logged and audited as any other command
+		vm.services = &scServiceManager{run: vm.Run}
+	}
+	return vm, nil
 }
 
 // Interface methods
@@ -225,8 +426,17 @@ func (vm *windows) EnsureFile(file *payload.FileInfo, remoteDir string) error {
 		}
 	}
 
+	if vm.isCancelled() {
+		return errors.Wrapf(ErrCancelled, "unable to transfer %s", file.Path)
+	}
 	vm.log.V(1).Info("copy", "local file", file.Path, "remote dir", remoteDir)
-	if err := vm.interact.transfer(file.Path, remoteDir); err != nil {
+	start := time.Now()
+	err = vm.interact.CopyFile(file.Path, remoteDir)
+	vm.audit("transfer", remotePath, start, err)
+	if err != nil && vm.isCancelled() {
+		return errors.Wrapf(ErrCancelled, "unable to transfer %s to remote dir %s", file.Path, remoteDir)
+	}
+	if err != nil {
 		return errors.Wrapf(err, "unable to transfer %s to remote dir %s", file.Path, remoteDir)
 	}
 	return nil
@@ -244,12 +454,22 @@ func (vm *windows) Run(cmd string, psCmd bool) (string, error) {
 	if psCmd {
 		cmd = remotePowerShellCmdPrefix + cmd
 	}
+	if vm.isCancelled() {
+		return "", errors.Wrapf(ErrCancelled, "unable to run %s", cmd)
+	}
 
-	out, err := vm.interact.run(cmd)
+	start := time.Now()
+	out, err := vm.interact.RunCommand(cmd)
+	vm.audit("run", cmd, start, err)
+	if err != nil && vm.isCancelled() {
+		// The command was interrupted by the cancellation, which is not a failure of the connection
+		return out, errors.Wrapf(ErrCancelled, "error running %s", cmd)
+	}
 	if err != nil {
 		// Hack to not print the error log for "sc.exe qc" returning 1060 for non existent services.
 		if !(strings.HasPrefix(cmd, serviceQueryCmd) && strings.HasSuffix(err.Error(), serviceNotFound)) {
 			vm.log.Error(err, "error running", "cmd", cmd, "out", out)
+			countSSHFailure(vm.machineName, vm.id, sshFailureCommand)
 		}
 		return out, errors.Wrapf(err, "error running %s", cmd)
 	}
@@ -258,7 +478,11 @@ func (vm *windows) Run(cmd string, psCmd bool) (string, error) {
 }
 
 func (vm *windows) Reinitialize() error {
-	if err := vm.interact.init(); err != nil {
+	if vm.isCancelled() {
+		return errors.Wrap(ErrCancelled, "unable to reinitialize ssh client")
+	}
+	if err := vm.interact.Init(); err != nil {
+		countConnectionFailure(vm.machineName, vm.id, err)
 		return fmt.Errorf("failed to reinitialize ssh client: %v", err)
 	}
 	return nil
@@ -266,40 +490,114 @@ func (vm *windows) Reinitialize() error {
 
 // ensureRequiredServicesStopped ensures that all services that are needed to configure a VM are stopped
 func (vm *windows) ensureRequiredServicesStopped() error {
-	// This slice order matters due to service dependencies
-	requiredSVCs := []string{windowsExporterServiceName, kubeProxyServiceName, hybridOverlayServiceName,
-		kubeletServiceName}
-	for _, svcName := range requiredSVCs {
+	for _, svcName := range requiredServices {
 		svc := &service{name: svcName}
 		if err := vm.ensureServiceNotRunning(svc); err != nil {
-			return errors.Wrap(err, "could not stop service %d")
+			return errors.Wrapf(err, "could not stop service %s", svcName)
 		}
 	}
 	return nil
 }
 
-func (vm *windows) Configure() error {
+func (vm *windows) Configure(ctx context.Context, progress ProgressFunc) error {
+	defer CancelOnDone(ctx, vm)()
 	vm.log.Info("configuring")
+	if err := vm.runHook(PreConfigureHook, vm.host.Hooks.PreConfigure, vm.host.Hooks.PreConfigureSHA256); err != nil {
+		return err
+	}
 	if err := vm.ensureRequiredServicesStopped(); err != nil {
 		return errors.Wrap(err, "unable to stop required services")
 	}
-	// Set the hostName of the Windows VM in case of vSphere
+	// Set the hostName of the Windows VM in case of vSphere, once the VM is known to have been cloned from a valid
+	// golden image
 	if vm.platform == oconfig.VSpherePlatformType {
+		if err := vm.validateVSphereTemplate(); err != nil {
+			return err
+		}
 		if err := vm.ensureHostName(); err != nil {
 			return err
 		}
 	}
-	if err := vm.createDirectories(); err != nil {
-		return errors.Wrap(err, "error creating directories on Windows VM")
+	// Data disks may hold directories used by the Kubernetes components, they must be mounted first
+	if err := vm.initializeDataDisks(); err != nil {
+		return errors.Wrap(err, "error initializing data disks on Windows VM")
+	}
+	// The pagefile may be placed on a data disk
+	if err := vm.configurePagefile(); err != nil {
+		return errors.Wrap(err, "error configuring pagefile on Windows VM")
 	}
-	if err := vm.transferFiles(); err != nil {
-		return errors.Wrap(err, "error transferring files to Windows VM")
+	if err := vm.configureFIPSPolicy(); err != nil {
+		return errors.Wrap(err, "error configuring FIPS algorithm policy on Windows VM")
 	}
+	// The directories, payload files, registry values, services and firewall rules managed by WMCO are applied as a
+	// manifest, only changing what drifted from it
+	m, err := vm.desiredState(true)
+	if err != nil {
+		return err
+	}
+	if err := vm.applyManifest(m); err != nil {
+		return errors.Wrap(err, "error applying desired state to Windows VM")
+	}
+	if err := vm.ensureImperativeHostSettings(); err != nil {
+		return errors.Wrap(err, "error configuring host settings on Windows VM")
+	}
+	progress.report(StepPayloadTransferred)
 	if err := vm.ConfigureWindowsExporter(); err != nil {
 		return errors.Wrapf(err, "error configuring Windows exporter on the Windows VM %s", vm.ID())
 	}
 
-	return vm.runBootstrapper()
+	if err := vm.runBootstrapper(); err != nil {
+		return err
+	}
+	if err := vm.configureAzureStackHub(); err != nil {
+		return errors.Wrap(err, "error configuring Azure Stack Hub endpoints on Windows VM")
+	}
+	if err := vm.saveBootstrapKubeletConfig(); err != nil {
+		return errors.Wrap(err, "error saving the kubelet configuration generated by the bootstrapper")
+	}
+	if err := vm.ConfigureKubelet(); err != nil {
+		return err
+	}
+	progress.report(StepServicesInstalled)
+	return nil
+}
+
+func (vm *windows) Deconfigure(ctx context.Context) error {
+	defer CancelOnDone(ctx, vm)()
+	vm.log.Info("deconfiguring")
+	if err := vm.ensureRequiredServicesStopped(); err != nil {
+		return errors.Wrap(err, "unable to stop required services")
+	}
+	for _, svcName := range requiredServices {
+		if err := vm.ensureServiceIsDeleted(svcName); err != nil {
+			return errors.Wrapf(err, "unable to delete %s Windows service", svcName)
+		}
+	}
+	if err := vm.removeShutdownHook(); err != nil {
+		return err
+	}
+	if err := vm.removeMetricsProxy(); err != nil {
+		return err
+	}
+	if err := vm.removeEventLogExport(); err != nil {
+		return err
+	}
+	return vm.removeFiles()
+}
+
+// removeFiles removes the files WMCO created on the VM: the Kubernetes directory, which WMCO owns, and the files it
+// transferred to, or downloaded into, the shared temporary directories, whose other files are left in place. The remote
+// temporary directory is only removed if no other file remains in it.
+func (vm *windows) removeFiles() error {
+	cmd := "\"$ErrorActionPreference = 'Stop'; " +
+		"foreach ($path in " + psList([]string{k8sDir, wgetIgnoreCertCmd, hnsPSModule, workerIgnitionPath}) + ") { " +
+		"if (Test-Path $path) { Remove-Item -Recurse -Force -Path $path } }; " +
+		"if ((Test-Path " + psString(remoteDir) + ") -and -not (Get-ChildItem -Force " + psString(remoteDir) + ")) { " +
+		"Remove-Item -Force -Path " + psString(remoteDir) + " }\""
+	if out, err := vm.Run(cmd, true); err != nil {
+		return errors.Wrapf(err, "unable to remove files with output: %s", out)
+	}
+	return nil
 }
 
 // Start Windows metrics exporter service, only if the file is present on the VM
@@ -355,6 +653,18 @@ func (vm *windows) ConfigureHybridOverlay(nodeName string) error {
 	if err = vm.waitForHNSNetworks(); err != nil {
 		return errors.Wrap(err, "error waiting for OVN HNS networks to be created")
 	}
+	// The overlay MTU is set before any pod is created on the node
+	if vm.host.OverlayMTU > 0 {
+		if err := vm.ensureOverlayMTU(); err != nil {
+			return err
+		}
+	}
+	// The DNS servers are set again on the virtual adapter the IP configuration was moved to
+	if len(vm.host.DNSServers) > 0 {
+		if err := vm.ensureDNS(); err != nil {
+			return err
+		}
+	}
 
 	vm.log.Info("configured", "service", hybridOverlayServiceName, "args", hybridOverlayServiceArgs)
 	return nil
@@ -390,11 +700,11 @@ func (vm *windows) ConfigureKubeProxy(nodeName, hostSubnet string) error {
 		return errors.Wrap(err, "error getting source VIP")
 	}
 
-	kubeProxyServiceArgs := "--windows-service --v=4 --proxy-mode=kernelspace --feature-gates=WinOverlay=true " +
+	kubeProxyServiceArgs := "--windows-service --v=4 --proxy-mode=kernelspace " +
 		"--hostname-override=" + nodeName + " --kubeconfig=c:\\k\\kubeconfig " +
-		"--cluster-cidr=" + hostSubnet + " --log-dir=" + kubeProxyLogDir + " --logtostderr=false " +
-		"--network-name=OVNKubernetesHybridOverlayNetwork --source-vip=" + sVIP +
-		" --enable-dsr=false --feature-gates=IPv6DualStack=false\" depend= " + hybridOverlayServiceName
+		"--cluster-cidr=" + hostSubnet + " --log-file=" + kubeProxyLogFile + " --logtostderr=false " +
+		"--network-name=OVNKubernetesHybridOverlayNetwork --source-vip=" + sVIP + " " +
+		strings.Join(kubeProxyDSRArgs(vm.host.KubeProxyDSR), " ") + "\" depend= " + hybridOverlayServiceName
 
 	kubeProxyService, err := newService(kubeProxyPath, kubeProxyServiceName, kubeProxyServiceArgs)
 	if err != nil {
@@ -410,7 +720,7 @@ func (vm *windows) ConfigureKubeProxy(nodeName, hostSubnet string) error {
 
 // Interface helper methods
 
-// ensureHostName ensures hostname of the Windows VM matches the machine name
+// ensureHostName ensures hostname of the Windows VM matches the machine name, truncated to the NetBIOS limit
 func (vm *windows) ensureHostName() error {
 	hostNameChangedNeeded, err := vm.isHostNameChangeNeeded()
 	if err != nil {
@@ -430,10 +740,10 @@ func (vm *windows) isHostNameChangeNeeded() (bool, error) {
 	if err != nil {
 		return false, errors.Wrapf(err, "error getting the host name for %s with stdout %s", vm.ID(), out)
 	}
-	return !strings.Contains(out, vm.hostName), nil
+	return !strings.EqualFold(strings.TrimSpace(out), vm.hostName), nil
 }
 
-// changeHostName changes the hostName of the Windows VM to match with the machine name
+// changeHostName changes the hostName of the Windows VM to match with the machine name, truncated to the NetBIOS limit
 func (vm *windows) changeHostName() error {
 	changeHostNameCommand := "Rename-Computer -NewName " + vm.hostName + " -Force -Restart"
 	out, err := vm.Run(changeHostNameCommand, true)
@@ -448,48 +758,23 @@ func (vm *windows) changeHostName() error {
 	return nil
 }
 
-// createDirectories creates directories required for configuring the Windows node on the VM
-func (vm *windows) createDirectories() error {
-	directoriesToCreate := []string{
-		k8sDir,
-		remoteDir,
-		cniDir,
-		cniConfDir,
-		logDir,
-		kubeProxyLogDir,
-		hybridOverlayLogDir,
-	}
-	for _, dir := range directoriesToCreate {
-		if _, err := vm.Run(mkdirCmd(dir), false); err != nil {
-			return errors.Wrapf(err, "unable to create remote directory %s", dir)
-		}
-	}
-	return nil
-}
-
-// transferFiles copies various files required for configuring the Windows node, to the VM.
-func (vm *windows) transferFiles() error {
-	vm.log.Info("transferring files")
-	filesToTransfer, err := getFilesToTransfer()
-	if err != nil {
-		return errors.Wrapf(err, "error getting list of files to transfer")
-	}
-	for src, dest := range filesToTransfer {
-		if err := vm.EnsureFile(src, dest); err != nil {
-			return errors.Wrapf(err, "error copying %s to %s ", src.Path, dest)
-		}
-	}
-	return nil
-}
-
 // runBootstrapper copies the bootstrapper and runs the code on the remote Windows VM
 func (vm *windows) runBootstrapper() error {
 	err := vm.initializeBootstrapperFiles()
 	if err != nil {
 		return errors.Wrap(err, "error initializing bootstrapper files")
 	}
-	wmcbInitializeCmd := k8sDir + "\\wmcb.exe initialize-kubelet --ignition-file " + winTemp +
-		"worker.ign --kubelet-path " + k8sDir + "kubelet.exe"
+	if err := vm.addRegistrationLabels(); err != nil {
+		return err
+	}
+	if err := vm.useExternalCloudProvider(); err != nil {
+		return err
+	}
+	if err := vm.configureAWSPartition(); err != nil {
+		return err
+	}
+	wmcbInitializeCmd := k8sDir + "\\wmcb.exe initialize-kubelet --ignition-file " + workerIgnitionPath +
+		" --kubelet-path " + k8sDir + "kubelet.exe"
 
 	out, err := vm.Run(wmcbInitializeCmd, true)
 	vm.log.Info("configured kubelet", "cmd", wmcbInitializeCmd, "output", out)
@@ -505,7 +790,7 @@ func (vm *windows) initializeBootstrapperFiles() error {
 	ignitionAcceptHeaderSpec := "application/vnd.coreos.ignition+json`;version=3.1.0"
 	// Download the worker ignition to C:\Windows\Temp\ using the script that ignores the server cert
 	ignitionFileDownloadCmd := wgetIgnoreCertCmd + " -server " + vm.workerIgnitionEndpoint + " -output " +
-		winTemp + "worker.ign" + " -acceptHeader " + ignitionAcceptHeaderSpec
+		workerIgnitionPath + " -acceptHeader " + ignitionAcceptHeaderSpec
 	_, err := vm.Run(ignitionFileDownloadCmd, true)
 	if err != nil {
 		return errors.Wrap(err, "unable to download worker.ign")
@@ -536,12 +821,7 @@ func (vm *windows) createService(svc *service) error {
 	if svc == nil {
 		return errors.New("service object should not be nil")
 	}
-	svcCreateCmd := "sc.exe create " + svc.name + " binPath=\"" + svc.binaryPath + " " + svc.args + " start=auto"
-	_, err := vm.Run(svcCreateCmd, false)
-	if err != nil {
-		return errors.Wrapf(err, "failed to create service %s", svc.name)
-	}
-	return nil
+	return vm.services.Create(svc.name, svc.binaryPath, svc.args)
 }
 
 // ensureServiceNotRunning stops a service if it exists and is running
@@ -573,20 +853,34 @@ func (vm *windows) ensureServiceNotRunning(svc *service) error {
 
 }
 
+// ensureServiceIsDeleted deletes the given service if it exists. The service must not be running.
+func (vm *windows) ensureServiceIsDeleted(serviceName string) error {
+	exists, err := vm.serviceExists(serviceName)
+	if err != nil {
+		return errors.Wrap(err, "error checking if service exists")
+	}
+	if !exists {
+		return nil
+	}
+	return vm.services.Delete(serviceName)
+}
+
 // stopService stops the service that was already running
 func (vm *windows) stopService(svc *service) error {
 	if svc == nil {
 		return errors.New("service object should not be nil")
 	}
 	// Success here means that the stop has initiated, not necessarily completed
-	out, err := vm.Run("sc.exe stop "+svc.name, false)
-	if err != nil {
-		return errors.Wrapf(err, "failed to stop %s service with output: %s", svc.name, out)
+	if err := vm.services.Stop(svc.name); err != nil {
+		return err
 	}
 
 	// Wait until the service has stopped
-	err = wait.Poll(retry.Interval, retry.Timeout, func() (bool, error) {
+	err := wait.Poll(retry.Interval, retry.Timeout, func() (bool, error) {
 		serviceRunning, err := vm.isRunning(svc.name)
+		if errors.Is(err, ErrCancelled) {
+			return false, err
+		}
 		if err != nil {
 			vm.log.V(1).Error(err, "unable to check if Windows service is running", "service", svc.name)
 			return false, nil
@@ -602,23 +896,12 @@ func (vm *windows) stopService(svc *service) error {
 
 // serviceExists checks if the given service exists on Windows VM
 func (vm *windows) serviceExists(serviceName string) (bool, error) {
-	_, err := vm.Run(serviceQueryCmd+serviceName, false)
-	if err != nil {
-		if strings.Contains(err.Error(), serviceNotFound) {
-			return false, nil
-		}
-		return false, err
-	}
-	return true, nil
+	return vm.services.Exists(serviceName)
 }
 
 // isRunning checks the status of given service
 func (vm *windows) isRunning(serviceName string) (bool, error) {
-	out, err := vm.Run("sc.exe query "+serviceName, false)
-	if err != nil {
-		return false, err
-	}
-	return strings.Contains(out, "RUNNING"), nil
+	return vm.services.IsRunning(serviceName)
 }
 
 // startService starts a previously created Windows service
@@ -633,11 +916,7 @@ func (vm *windows) startService(svc *service) error {
 	if serviceRunning {
 		return nil
 	}
-	out, err := vm.Run("sc.exe start "+svc.name, false)
-	if err != nil {
-		return errors.Wrapf(err, "failed to start %s service with output: %s", svc.name, out)
-	}
-	return nil
+	return vm.services.Start(svc.name)
 }
 
 // waitForHNSNetworks waits for the OVN overlay HNS networks to be created until the timeout is reached
@@ -646,6 +925,9 @@ func (vm *windows) waitForHNSNetworks() error {
 	var err error
 	for retries := 0; retries < retry.Count; retries++ {
 		out, err = vm.Run("Get-HnsNetwork", true)
+		if errors.Is(err, ErrCancelled) {
+			return err
+		}
 		if err != nil {
 			// retry
 			continue
@@ -686,7 +968,7 @@ func (vm *windows) waitForServiceToRun(serviceName string) error {
 func (vm *windows) getSourceVIP() (string, error) {
 	cmd := "\"Import-Module -DisableNameChecking " + hnsPSModule + "; " +
 		"$net = (Get-HnsNetwork | where { $_.Name -eq 'OVNKubernetesHybridOverlayNetwork' }); " +
-		"$endpoint = New-HnsEndpoint -NetworkId $net.ID -Name VIPEndpoint; " +
+		"$endpoint = New-HnsEndpoint -NetworkId $net.ID -Name " + sourceVIPEndpointName + "; " +
 		"Attach-HNSHostEndpoint -EndpointID $endpoint.ID -CompartmentID 1; " +
 		"(Get-NetIPConfiguration -AllCompartments -All -Detailed | " +
 		"where { $_.NetAdapter.LinkLayerAddress -eq $endpoint.MacAddress }).IPV4Address.IPAddress.Trim()\""
@@ -718,8 +1000,3 @@ func (vm *windows) newFileInfo(path string) (*payload.FileInfo, error) {
 }
 
 // Generic helper methods
-
-// mkdirCmd returns the Windows command to create a directory if it does not exists
-func mkdirCmd(dirName string) string {
-	return "if not exist " + dirName + " mkdir " + dirName
-}
@@ -0,0 +1,37 @@
+// Package windowstest provides utilities for testing the configuration of Windows VMs
+package windowstest
+
+import (
+	"strings"
+)
+
+// FakeRemoteHost is a windows.RemoteHost which records the commands run, answering them with the error or the output
+// of the first matching command prefix, the empty prefix matching every command
+type FakeRemoteHost struct {
+	Outputs  map[string]string
+	Errors   map[string]error
+	Commands []string
+}
+
+func (h *FakeRemoteHost) Init() error {
+	return nil
+}
+
+func (h *FakeRemoteHost) RunCommand(cmd string) (string, error) {
+	h.Commands = append(h.Commands, cmd)
+	for prefix, err := range h.Errors {
+		if strings.HasPrefix(cmd, prefix) {
+			return "", err
+		}
+	}
+	for prefix, out := range h.Outputs {
+		if strings.HasPrefix(cmd, prefix) {
+			return out, nil
+		}
+	}
+	return "", nil
+}
+
+func (h *FakeRemoteHost) CopyFile(string, string) error {
+	return nil
+}